@@ -3,11 +3,13 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -16,17 +18,25 @@ import (
 
 	"github.com/home-renovators/ingestion-pipeline/internal/ai"
 	"github.com/home-renovators/ingestion-pipeline/internal/auth"
+	"github.com/home-renovators/ingestion-pipeline/internal/encryption"
+	"github.com/home-renovators/ingestion-pipeline/internal/migrations"
+	"github.com/home-renovators/ingestion-pipeline/internal/repository"
 	"github.com/home-renovators/ingestion-pipeline/internal/spanner"
+	pkgai "github.com/home-renovators/ingestion-pipeline/pkg/ai"
 	"github.com/home-renovators/ingestion-pipeline/pkg/config"
 	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+	"github.com/home-renovators/ingestion-pipeline/pkg/scheduler"
+	"github.com/home-renovators/ingestion-pipeline/pkg/spam"
 )
 
 type AIAnalysisService struct {
 	config       *config.Config
 	authService  *auth.AuthService
-	spannerRepo  *spanner.Repository
+	repo         repository.Repository
 	aiService    *ai.Service
 	pubsubClient *pubsub.Client
+	scheduler    *scheduler.Scheduler
+	spamScorer   *spam.Scorer
 }
 
 type AnalysisRequest struct {
@@ -35,8 +45,17 @@ type AnalysisRequest struct {
 	CallID        string              `json:"call_id"`
 	Transcription string              `json:"transcription"`
 	CallDetails   models.CallDetails  `json:"call_details"`
-	AnalysisType  string              `json:"analysis_type"` // content_analysis, spam_detection, sentiment_analysis
-	Priority      string              `json:"priority,omitempty"` // high, normal, low
+	AnalysisType  string              `json:"analysis_type"` // combined_analysis (default), content_analysis, spam_detection, sentiment_analysis, call_summary, entity_extraction
+	Priority      string              `json:"priority,omitempty"` // low, normal, high or urgent; hot leads default to urgent
+	Language      string              `json:"language,omitempty"` // detected transcript language, e.g. "es-US"
+	TemplateVersion int               `json:"template_version,omitempty"` // prompt template version, defaults to the tenant's pinned or latest
+
+	// Set by the audio service when the call was screened as voicemail, IVR or no speech
+	CallClassification string `json:"call_classification,omitempty"`
+	AnalysisMode       string `json:"analysis_mode,omitempty"` // full, summary, skip
+
+	// Set by the audio service when the recording matched a known robocall or an earlier call
+	Fingerprint *models.FingerprintMatch `json:"fingerprint,omitempty"`
 }
 
 type AnalysisResponse struct {
@@ -45,6 +64,16 @@ type AnalysisResponse struct {
 	AnalysisID       string                 `json:"analysis_id,omitempty"`
 	CallAnalysis     *models.CallAnalysis   `json:"call_analysis,omitempty"`
 	SpamLikelihood   *float64               `json:"spam_likelihood,omitempty"`
+	SpamResult       *pkgai.SpamAnalysisResult      `json:"spam_result,omitempty"`
+	SpamVerdict      *spam.Verdict          `json:"spam_verdict,omitempty"` // how the spam likelihood was reached
+	SentimentResult  *pkgai.SentimentAnalysisResult `json:"sentiment_result,omitempty"`
+	IntentResult     *pkgai.IntentClassificationResult `json:"intent_result,omitempty"`
+	LeadScoreResult  *pkgai.LeadScoreResult `json:"lead_score_result,omitempty"`
+	Summary          *models.CallSummary    `json:"summary,omitempty"`
+	Entities         *models.CallEntities   `json:"entities,omitempty"`
+	Usage            pkgai.TokenUsage       `json:"usage"`
+	TemplateID       string                 `json:"template_id,omitempty"`
+	TemplateVersion  int                    `json:"template_version,omitempty"`
 	ProcessingTimeMs int64                  `json:"processing_time_ms"`
 	Error            string                 `json:"error,omitempty"`
 }
@@ -114,6 +143,22 @@ func initializeServices(ctx context.Context, cfg *config.Config) (*AIAnalysisSer
 		return nil, fmt.Errorf("failed to initialize spanner repository: %w", err)
 	}
 
+	// Refuse to run against a database that hasn't been migrated to this build's schema
+	if cfg.SchemaCheck {
+		if err := migrations.Verify(ctx, spannerRepo); err != nil {
+			return nil, fmt.Errorf("failed to verify database schema: %w", err)
+		}
+	}
+
+	// Processing logs hold transcripts, so they are encrypted like the transcripts themselves
+	envelope, err := encryption.NewEnvelopeFromConfig(ctx, cfg, spannerRepo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize encryption: %w", err)
+	}
+	if envelope != nil {
+		spannerRepo.SetEnvelope(envelope)
+	}
+
 	// Initialize authentication service
 	authService := auth.NewAuthService(cfg, spannerRepo)
 
@@ -132,15 +177,31 @@ func initializeServices(ctx context.Context, cfg *config.Config) (*AIAnalysisSer
 	return &AIAnalysisService{
 		config:       cfg,
 		authService:  authService,
-		spannerRepo:  spannerRepo,
+		repo:         spannerRepo,
 		aiService:    aiService,
 		pubsubClient: pubsubClient,
+		scheduler: scheduler.New(&scheduler.Config{
+			MaxConcurrent: cfg.AnalysisConcurrency,
+			MaxPerTenant:  cfg.TenantConcurrency,
+			Weights:       scheduler.DefaultConfig().Weights,
+		}),
+		spamScorer: newSpamScorer(cfg),
 	}, nil
 }
 
+// newSpamScorer creates the spam scorer with the configured known-spam list and repeat window
+func newSpamScorer(cfg *config.Config) *spam.Scorer {
+	spamConfig := spam.DefaultConfig()
+	spamConfig.KnownSpamNumbers = cfg.SpamKnownNumbers
+	if cfg.SpamRepeatWindowHours > 0 {
+		spamConfig.RepeatWindow = time.Duration(cfg.SpamRepeatWindowHours) * time.Hour
+	}
+	return spam.NewScorer(spamConfig)
+}
+
 func (s *AIAnalysisService) cleanup() {
-	if s.spannerRepo != nil {
-		s.spannerRepo.Close()
+	if s.repo != nil {
+		s.repo.Close()
 	}
 	if s.aiService != nil {
 		s.aiService.Close()
@@ -161,8 +222,14 @@ func (s *AIAnalysisService) setupRoutes(router *gin.Engine) {
 		api.POST("/analysis/content", s.handleContentAnalysis)
 		api.POST("/analysis/spam-detection", s.handleSpamDetection)
 		api.POST("/analysis/sentiment", s.handleSentimentAnalysis)
+		api.POST("/analysis/combined", s.handleCombinedAnalysis)
+		api.POST("/analysis/summary", s.handleCallSummary)
+		api.POST("/analysis/entities", s.handleEntityExtraction)
 		api.POST("/analysis/batch", s.handleBatchAnalysis)
 		api.GET("/analysis/status/:analysis_id", s.handleGetAnalysisStatus)
+
+		// Prompt template endpoints
+		api.POST("/prompts/preview", s.handlePromptPreview)
 	}
 }
 
@@ -171,7 +238,7 @@ func (s *AIAnalysisService) healthCheck(c *gin.Context) {
 		"status":    "healthy",
 		"service":   "ai-analysis-service",
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
-		"ai_model":  s.config.VertexAIModel,
+		"ai_model":  s.aiService.Model(),
 	})
 }
 
@@ -247,6 +314,78 @@ func (s *AIAnalysisService) handleSentimentAnalysis(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// handleCombinedAnalysis runs every analysis of a call in a single model call
+func (s *AIAnalysisService) handleCombinedAnalysis(c *gin.Context) {
+	ctx := c.Request.Context()
+	startTime := time.Now()
+
+	var req AnalysisRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	req.AnalysisType = string(pkgai.AnalysisTypeCombined)
+
+	result, err := s.processAnalysis(ctx, &req)
+	if err != nil {
+		log.Printf("Failed to process combined analysis: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Analysis failed"})
+		return
+	}
+
+	result.ProcessingTimeMs = time.Since(startTime).Milliseconds()
+	c.JSON(http.StatusOK, result)
+}
+
+// handleCallSummary summarizes a call with action items for sales follow-up
+func (s *AIAnalysisService) handleCallSummary(c *gin.Context) {
+	ctx := c.Request.Context()
+	startTime := time.Now()
+
+	var req AnalysisRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	req.AnalysisType = string(pkgai.AnalysisTypeSummary)
+
+	result, err := s.processAnalysis(ctx, &req)
+	if err != nil {
+		log.Printf("Failed to process call summary: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Call summary failed"})
+		return
+	}
+
+	result.ProcessingTimeMs = time.Since(startTime).Milliseconds()
+	c.JSON(http.StatusOK, result)
+}
+
+// handleEntityExtraction extracts the contact details a caller gave during a call
+func (s *AIAnalysisService) handleEntityExtraction(c *gin.Context) {
+	ctx := c.Request.Context()
+	startTime := time.Now()
+
+	var req AnalysisRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	req.AnalysisType = string(pkgai.AnalysisTypeEntities)
+
+	result, err := s.processAnalysis(ctx, &req)
+	if err != nil {
+		log.Printf("Failed to process entity extraction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Entity extraction failed"})
+		return
+	}
+
+	result.ProcessingTimeMs = time.Since(startTime).Milliseconds()
+	c.JSON(http.StatusOK, result)
+}
+
 func (s *AIAnalysisService) handleBatchAnalysis(c *gin.Context) {
 	ctx := c.Request.Context()
 
@@ -297,10 +436,14 @@ func (s *AIAnalysisService) handleGetAnalysisStatus(c *gin.Context) {
 	}
 
 	// Get analysis status from database
-	processingLog, err := s.spannerRepo.GetAIProcessingLog(ctx, tenantID, analysisID)
+	processingLog, err := s.repo.GetAIProcessingLog(ctx, tenantID, analysisID)
 	if err != nil {
 		log.Printf("Failed to get analysis status: %v", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Analysis not found"})
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Analysis not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get analysis status"})
 		return
 	}
 
@@ -316,23 +459,97 @@ func (s *AIAnalysisService) handleGetAnalysisStatus(c *gin.Context) {
 	})
 }
 
+// handlePromptPreview renders the prompt an analysis request would send, without calling the model
+func (s *AIAnalysisService) handlePromptPreview(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req AnalysisRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.TenantID == "" || req.AnalysisType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing tenant_id or analysis_type"})
+		return
+	}
+
+	analysisType := pkgai.AnalysisType(req.AnalysisType)
+	versions := pkgai.DefaultPromptLibrary().Versions(req.AnalysisType)
+	if len(versions) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("No prompt template for analysis type %s", req.AnalysisType)})
+		return
+	}
+
+	input := s.analysisInput(ctx, &req)
+	rendered, err := s.aiService.RenderPrompt(analysisType, input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tenant_id":          req.TenantID,
+		"analysis_type":      req.AnalysisType,
+		"template_id":        rendered.TemplateID,
+		"template_version":   rendered.TemplateVersion,
+		"available_versions": versions,
+		"prompt":             rendered.Text,
+		"response_schema":    pkgai.TenantResponseSchema(analysisType, input.PromptConfig),
+	})
+}
+
 func (s *AIAnalysisService) processAnalysis(ctx context.Context, req *AnalysisRequest) (*AnalysisResponse, error) {
+	// The pipeline names no analysis type and gets every analysis from one model call
+	if req.AnalysisType == "" {
+		req.AnalysisType = string(pkgai.AnalysisTypeCombined)
+	}
+	// Summary mode only pays for the content analysis
+	if models.AnalysisMode(req.AnalysisMode) == models.AnalysisModeSummary && req.AnalysisType == string(pkgai.AnalysisTypeCombined) {
+		req.AnalysisType = "content_analysis"
+	}
+
 	log.Printf("Processing %s for request %s, call %s", req.AnalysisType, req.RequestID, req.CallID)
 
 	startTime := time.Now()
 	var result *AnalysisResponse
 
+	// Screened calls only get the analysis their tenant policy allows
+	if analysisSkipped(req) {
+		log.Printf("Skipping %s for call %s classified as %s (mode %s)", req.AnalysisType, req.CallID, req.CallClassification, req.AnalysisMode)
+		return &AnalysisResponse{
+			Status:    "skipped",
+			RequestID: req.RequestID,
+		}, nil
+	}
+
+	// Wait for a Gemini slot by priority and tenant share
+	release, err := s.scheduler.Acquire(ctx, req.TenantID, analysisPriority(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule analysis: %w", err)
+	}
+	defer release()
+
+	input := s.analysisInput(ctx, req)
 	switch req.AnalysisType {
 	case "content_analysis":
-		result = s.processContentAnalysis(ctx, req)
+		result = s.processContentAnalysis(ctx, req, input)
 	case "spam_detection":
-		result = s.processSpamDetection(ctx, req)
+		result = s.processSpamDetection(ctx, req, input)
 	case "sentiment_analysis":
-		result = s.processSentimentAnalysis(ctx, req)
+		result = s.processSentimentAnalysis(ctx, req, input)
+	case string(pkgai.AnalysisTypeCombined):
+		result = s.processCombinedAnalysis(ctx, req, input)
+	case string(pkgai.AnalysisTypeSummary):
+		result = s.processCallSummary(ctx, req, input)
+	case string(pkgai.AnalysisTypeEntities):
+		result = s.processEntityExtraction(ctx, req, input)
 	default:
 		return nil, fmt.Errorf("unsupported analysis type: %s", req.AnalysisType)
 	}
 
+	// Free the slot before bookkeeping so queued analyses can start
+	release()
+
 	if result.Error != "" {
 		return result, fmt.Errorf("analysis failed: %s", result.Error)
 	}
@@ -349,13 +566,22 @@ func (s *AIAnalysisService) processAnalysis(ctx context.Context, req *AnalysisRe
 		UpdatedAt:      time.Now().UTC(),
 	}
 
-	if err := s.spannerRepo.CreateAIProcessingLog(ctx, processingLog); err != nil {
+	if err := s.repo.CreateAIProcessingLog(ctx, processingLog); err != nil {
 		log.Printf("Failed to create AI processing log: %v", err)
 		// Continue processing even if logging fails
 	}
 
 	result.AnalysisID = processingLog.LogID
 
+	if err := s.storeRequestAnalysis(ctx, req, result); err != nil {
+		log.Printf("Failed to store analysis on request %s: %v", req.RequestID, err)
+		// Continue processing even if the request can't be updated
+	}
+	if err := s.storeRequestEntities(ctx, req, result); err != nil {
+		log.Printf("Failed to store extracted entities on request %s: %v", req.RequestID, err)
+		// Continue processing even if the request can't be updated
+	}
+
 	// Publish analysis completed event
 	if err := s.publishAnalysisCompletedEvent(ctx, req, result); err != nil {
 		log.Printf("Failed to publish analysis completed event: %v", err)
@@ -365,95 +591,353 @@ func (s *AIAnalysisService) processAnalysis(ctx context.Context, req *AnalysisRe
 	return result, nil
 }
 
-func (s *AIAnalysisService) processContentAnalysis(ctx context.Context, req *AnalysisRequest) *AnalysisResponse {
-	analysis, err := s.aiService.AnalyzeCallContent(ctx, req.Transcription, req.CallDetails)
+// analysisSkipped reports whether the request's analysis mode rules out this analysis type
+func analysisSkipped(req *AnalysisRequest) bool {
+	switch models.AnalysisMode(req.AnalysisMode) {
+	case models.AnalysisModeSkip:
+		return true
+	case models.AnalysisModeSummary:
+		// Summary mode keeps the content analysis and summary and drops the extra Gemini calls
+		return req.AnalysisType != "content_analysis" && req.AnalysisType != string(pkgai.AnalysisTypeSummary)
+	default:
+		return false
+	}
+}
+
+// analysisPriority returns the priority a request asks for or, when it asks for none, urgent for a
+// hot lead so it is analyzed ahead of backfill
+func analysisPriority(req *AnalysisRequest) scheduler.Priority {
+	if req.Priority == "" && req.CallDetails.HotLead() {
+		return scheduler.PriorityUrgent
+	}
+	return scheduler.ParsePriority(req.Priority)
+}
+
+// analysisInput builds the AI input for a request, tailored with its tenant's prompt configuration
+func (s *AIAnalysisService) analysisInput(ctx context.Context, req *AnalysisRequest) *ai.AnalysisInput {
+	return &ai.AnalysisInput{
+		Transcription:   req.Transcription,
+		Language:        req.Language,
+		CallDetails:     req.CallDetails,
+		PromptConfig:    s.loadPromptConfig(ctx, req.TenantID),
+		TemplateVersion: req.TemplateVersion,
+		CallerHistory:   s.loadCallerHistory(ctx, req),
+	}
+}
+
+// loadCallerHistory returns the caller's earlier calls to the tenant for the analyses that relate
+// a call to them, or nil if there are none or they are unavailable
+func (s *AIAnalysisService) loadCallerHistory(ctx context.Context, req *AnalysisRequest) []models.PriorInteraction {
+	switch req.AnalysisType {
+	case "content_analysis", string(pkgai.AnalysisTypeCombined):
+	default:
+		return nil
+	}
+	if s.config.CallerHistoryLimit <= 0 || req.CallDetails.CustomerPhoneNumber == "" {
+		return nil
+	}
+
+	// One more than the limit, in case this call's own request is among them
+	requests, err := s.repo.ListRequestsByCallerPhone(ctx, req.TenantID, req.CallDetails.CustomerPhoneNumber, s.config.CallerHistoryLimit+1)
 	if err != nil {
-		return &AnalysisResponse{
-			Status:    "failed",
-			RequestID: req.RequestID,
-			Error:     err.Error(),
+		log.Printf("Failed to load caller history for request %s: %v", req.RequestID, err)
+		return nil
+	}
+
+	var earlier []*models.Request
+	var requestIDs []string
+	for _, request := range requests {
+		if request.RequestID != req.RequestID && len(earlier) < s.config.CallerHistoryLimit {
+			earlier = append(earlier, request)
+			requestIDs = append(requestIDs, strings.ToLower(request.RequestID))
 		}
 	}
+	if len(earlier) == 0 {
+		return nil
+	}
 
-	return &AnalysisResponse{
-		Status:       "completed",
-		RequestID:    req.RequestID,
-		CallAnalysis: analysis,
+	integrations, err := s.repo.FindCRMIntegrations(ctx, req.TenantID, requestIDs)
+	if err != nil {
+		// The history is still useful without CRM stages
+		log.Printf("Failed to load CRM integrations for caller history of request %s: %v", req.RequestID, err)
+	}
+	return pkgai.NewPriorInteractions(earlier, integrations)
+}
+
+// loadPromptConfig returns the tenant's prompt customization, or nil for the defaults if unavailable
+func (s *AIAnalysisService) loadPromptConfig(ctx context.Context, tenantID string) *models.AnalysisPromptConfig {
+	workflowConfig := s.loadWorkflowConfig(ctx, tenantID)
+	if workflowConfig == nil {
+		return nil
 	}
+	return &workflowConfig.Analysis
 }
 
-func (s *AIAnalysisService) processSpamDetection(ctx context.Context, req *AnalysisRequest) *AnalysisResponse {
-	spamLikelihood, err := s.aiService.DetectSpam(ctx, req.Transcription, req.CallDetails)
+// loadWorkflowConfig returns the tenant's workflow configuration, or nil if unavailable
+func (s *AIAnalysisService) loadWorkflowConfig(ctx context.Context, tenantID string) *models.WorkflowConfig {
+	office, err := s.repo.GetOfficeByTenantID(ctx, tenantID)
 	if err != nil {
-		return &AnalysisResponse{
-			Status:    "failed",
-			RequestID: req.RequestID,
-			Error:     err.Error(),
+		if !errors.Is(err, repository.ErrNotFound) {
+			log.Printf("Failed to load tenant configuration: %v", err)
 		}
+		return nil
+	}
+	if office.WorkflowConfig == "" {
+		return nil
+	}
+
+	var workflowConfig models.WorkflowConfig
+	if err := json.Unmarshal([]byte(office.WorkflowConfig), &workflowConfig); err != nil {
+		log.Printf("Failed to parse workflow config: %v", err)
+		return nil
+	}
+
+	return &workflowConfig
+}
+
+// failedAnalysis reports a failed analysis with what the model call cost
+func failedAnalysis(req *AnalysisRequest, metadata pkgai.AnalysisMetadata, err error) *AnalysisResponse {
+	return &AnalysisResponse{
+		Status:          "failed",
+		RequestID:       req.RequestID,
+		Usage:           usageOf(metadata),
+		TemplateID:      metadata.TemplateID,
+		TemplateVersion: metadata.TemplateVersion,
+		Error:           err.Error(),
 	}
+}
 
+// completedAnalysis starts the response of a successful analysis
+func completedAnalysis(req *AnalysisRequest, metadata pkgai.AnalysisMetadata) *AnalysisResponse {
 	return &AnalysisResponse{
-		Status:         "completed",
-		RequestID:      req.RequestID,
-		SpamLikelihood: &spamLikelihood,
+		Status:          "completed",
+		RequestID:       req.RequestID,
+		Usage:           usageOf(metadata),
+		TemplateID:      metadata.TemplateID,
+		TemplateVersion: metadata.TemplateVersion,
 	}
 }
 
-func (s *AIAnalysisService) processSentimentAnalysis(ctx context.Context, req *AnalysisRequest) *AnalysisResponse {
-	// Create a focused sentiment analysis prompt
-	prompt := fmt.Sprintf(`
-Analyze the sentiment of this call transcription:
+func usageOf(metadata pkgai.AnalysisMetadata) pkgai.TokenUsage {
+	return pkgai.TokenUsage{InputTokens: metadata.InputTokens, OutputTokens: metadata.OutputTokens}
+}
 
-TRANSCRIPT: %s
+func (s *AIAnalysisService) processContentAnalysis(ctx context.Context, req *AnalysisRequest, input *ai.AnalysisInput) *AnalysisResponse {
+	analysis, metadata, err := s.aiService.AnalyzeContent(ctx, input)
+	if err != nil {
+		return failedAnalysis(req, metadata, err)
+	}
 
-Return ONLY a JSON object with:
-{
-  "sentiment": "positive|neutral|negative",
-  "confidence": 0.0-1.0,
-  "emotional_tone": "string describing emotional tone",
-  "customer_satisfaction": "high|medium|low"
+	result := completedAnalysis(req, metadata)
+	result.CallAnalysis = analysis
+	return result
 }
-`, req.Transcription)
 
-	// Use the AI service with a custom prompt for sentiment analysis
-	analysis, err := s.aiService.AnalyzeCallContent(ctx, prompt, req.CallDetails)
+func (s *AIAnalysisService) processSpamDetection(ctx context.Context, req *AnalysisRequest, input *ai.AnalysisInput) *AnalysisResponse {
+	spamResult, metadata, err := s.aiService.AnalyzeSpam(ctx, input)
 	if err != nil {
-		return &AnalysisResponse{
-			Status:    "failed",
-			RequestID: req.RequestID,
-			Error:     err.Error(),
-		}
+		return failedAnalysis(req, metadata, err)
 	}
 
-	return &AnalysisResponse{
-		Status:       "completed",
-		RequestID:    req.RequestID,
-		CallAnalysis: analysis,
+	result := completedAnalysis(req, metadata)
+	result.SpamVerdict = s.scoreSpam(ctx, req, spamResult)
+	result.SpamLikelihood = &result.SpamVerdict.Likelihood
+	result.SpamResult = spamResult
+	return result
+}
+
+func (s *AIAnalysisService) processCombinedAnalysis(ctx context.Context, req *AnalysisRequest, input *ai.AnalysisInput) *AnalysisResponse {
+	combined, metadata, err := s.aiService.AnalyzeCombined(ctx, input)
+	if err != nil {
+		return failedAnalysis(req, metadata, err)
 	}
+
+	result := completedAnalysis(req, metadata)
+	result.CallAnalysis = &combined.CallAnalysis
+	result.SpamVerdict = s.scoreSpam(ctx, req, &combined.Spam)
+	result.SpamLikelihood = &result.SpamVerdict.Likelihood
+	result.SpamResult = &combined.Spam
+	result.SentimentResult = &combined.Sentiment
+	result.IntentResult = &combined.Intent
+	result.LeadScoreResult = &combined.LeadScore
+	result.Summary = &combined.Summary
+	result.Entities = &combined.Entities
+	return result
 }
 
-func (s *AIAnalysisService) calculateOutputTokens(result *AnalysisResponse) int64 {
+func (s *AIAnalysisService) processCallSummary(ctx context.Context, req *AnalysisRequest, input *ai.AnalysisInput) *AnalysisResponse {
+	summary, metadata, err := s.aiService.AnalyzeSummary(ctx, input)
+	if err != nil {
+		return failedAnalysis(req, metadata, err)
+	}
+
+	result := completedAnalysis(req, metadata)
+	result.Summary = summary
+	return result
+}
+
+func (s *AIAnalysisService) processEntityExtraction(ctx context.Context, req *AnalysisRequest, input *ai.AnalysisInput) *AnalysisResponse {
+	entities, metadata, err := s.aiService.AnalyzeEntities(ctx, input)
+	if err != nil {
+		return failedAnalysis(req, metadata, err)
+	}
+
+	result := completedAnalysis(req, metadata)
+	result.Entities = entities
+	return result
+}
+
+// storeRequestAnalysis keeps the call analysis and summary on the request, merged with what
+// earlier analyses stored, so they travel with it to the CRM. Requests that were never stored
+// are left alone.
+func (s *AIAnalysisService) storeRequestAnalysis(ctx context.Context, req *AnalysisRequest, result *AnalysisResponse) error {
+	if req.RequestID == "" || (result.CallAnalysis == nil && result.Summary == nil && result.SpamLikelihood == nil) {
+		return nil
+	}
+
+	request, err := s.repo.GetRequest(ctx, req.TenantID, req.RequestID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get request: %w", err)
+	}
+
+	var analysis models.CallAnalysis
+	if request.AIAnalysis != nil && *request.AIAnalysis != "" {
+		if err := json.Unmarshal([]byte(*request.AIAnalysis), &analysis); err != nil {
+			return fmt.Errorf("failed to parse stored analysis: %w", err)
+		}
+	}
+
+	leadScore, spamLikelihood := request.LeadScore, request.SpamLikelihood
 	if result.CallAnalysis != nil {
-		data, _ := json.Marshal(result.CallAnalysis)
-		return int64(len(data))
+		// A content analysis replaces the stored one but keeps an earlier summary
+		summary := analysis.Summary
+		analysis = *result.CallAnalysis
+		if analysis.Summary == nil {
+			analysis.Summary = summary
+		}
+		leadScore = &analysis.LeadScore
+	}
+	if result.Summary != nil {
+		analysis.Summary = result.Summary
 	}
 	if result.SpamLikelihood != nil {
-		return 10 // Estimate for spam likelihood response
+		// The CRM service holds back leads by the stored spam likelihood
+		spamLikelihood = result.SpamLikelihood
+	}
+
+	var aiAnalysis string
+	if result.CallAnalysis == nil && result.Summary == nil {
+		if request.AIAnalysis != nil {
+			aiAnalysis = *request.AIAnalysis
+		}
+	} else {
+		data, err := json.Marshal(analysis)
+		if err != nil {
+			return fmt.Errorf("failed to marshal analysis: %w", err)
+		}
+		aiAnalysis = string(data)
+	}
+
+	return s.repo.UpdateRequestAnalysis(ctx, req.TenantID, req.RequestID, aiAnalysis, leadScore, spamLikelihood)
+}
+
+// storeRequestEntities keeps the contact details extracted from the call on the request
+func (s *AIAnalysisService) storeRequestEntities(ctx context.Context, req *AnalysisRequest, result *AnalysisResponse) error {
+	if req.RequestID == "" || result.Entities == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(result.Entities)
+	if err != nil {
+		return fmt.Errorf("failed to marshal entities: %w", err)
+	}
+
+	err = s.repo.UpdateRequestExtraction(ctx, req.TenantID, req.RequestID, string(data))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
 	}
-	return 0
+	return err
+}
+
+// scoreSpam blends the LLM's spam assessment with what CallRail, the caller's recent calls and
+// the recording's fingerprint say about the call, under the tenant's weights and threshold
+func (s *AIAnalysisService) scoreSpam(ctx context.Context, req *AnalysisRequest, spamResult *pkgai.SpamAnalysisResult) *spam.Verdict {
+	signals := &spam.Signals{
+		Fingerprint: req.Fingerprint,
+		LLM: &spam.LLMVerdict{
+			Likelihood: spamResult.SpamLikelihood,
+			Confidence: spamResult.Confidence,
+			Reasoning:  spamResult.Reasoning,
+		},
+	}
+
+	// Call signals are only known when the request carries CallRail's call details
+	if details := req.CallDetails; details.ID != "" {
+		duration := time.Duration(details.Duration) * time.Second
+		signals.Duration = &duration
+		signals.Answered = &details.Answered
+		signals.CallerPhone = &details.CustomerPhoneNumber
+
+		activity, err := s.repo.GetCallerActivity(ctx, details.CustomerPhoneNumber, time.Now().Add(-s.spamScorer.RepeatWindow()))
+		if err != nil {
+			log.Printf("Failed to load caller activity for call %s: %v", req.CallID, err)
+		} else {
+			signals.CallerActivity = activity
+		}
+	}
+
+	var policy models.SpamDetectionConfig
+	if workflowConfig := s.loadWorkflowConfig(ctx, req.TenantID); workflowConfig != nil {
+		policy = workflowConfig.Validation.SpamDetection
+	}
+	return s.spamScorer.WithWeights(policy.Weights).Score(signals, policy)
+}
+
+func (s *AIAnalysisService) processSentimentAnalysis(ctx context.Context, req *AnalysisRequest, input *ai.AnalysisInput) *AnalysisResponse {
+	sentiment, metadata, err := s.aiService.AnalyzeSentiment(ctx, input)
+	if err != nil {
+		return failedAnalysis(req, metadata, err)
+	}
+
+	result := completedAnalysis(req, metadata)
+	result.SentimentResult = sentiment
+	return result
+}
+
+func (s *AIAnalysisService) calculateOutputTokens(result *AnalysisResponse) int64 {
+	return result.Usage.OutputTokens
 }
 
 func (s *AIAnalysisService) extractConfidenceScore(result *AnalysisResponse) float64 {
 	if result.CallAnalysis != nil {
 		return 0.85 // Default confidence for successful analysis
 	}
-	if result.SpamLikelihood != nil {
-		return 0.90 // Spam detection typically has high confidence
+	if result.SpamResult != nil {
+		return result.SpamResult.Confidence
+	}
+	if result.SentimentResult != nil {
+		return result.SentimentResult.Confidence
 	}
 	return 0.0
 }
 
 func (s *AIAnalysisService) extractResultData(result *AnalysisResponse) interface{} {
+	if result.CallAnalysis != nil && result.SpamLikelihood != nil {
+		// A combined analysis carries every result
+		return map[string]interface{}{
+			"call_analysis":     result.CallAnalysis,
+			"spam_likelihood":   *result.SpamLikelihood,
+			"spam_result":       result.SpamResult,
+			"sentiment_result":  result.SentimentResult,
+			"intent_result":     result.IntentResult,
+			"lead_score_result": result.LeadScoreResult,
+			"summary":           result.Summary,
+			"entities":          result.Entities,
+		}
+	}
 	if result.CallAnalysis != nil {
 		return result.CallAnalysis
 	}
@@ -462,10 +946,23 @@ func (s *AIAnalysisService) extractResultData(result *AnalysisResponse) interfac
 			"spam_likelihood": *result.SpamLikelihood,
 		}
 	}
+	if result.SentimentResult != nil {
+		return result.SentimentResult
+	}
+	if result.Summary != nil {
+		return result.Summary
+	}
+	if result.Entities != nil {
+		return result.Entities
+	}
 	return nil
 }
 
 func (s *AIAnalysisService) publishAnalysisCompletedEvent(ctx context.Context, req *AnalysisRequest, result *AnalysisResponse) error {
+	if s.pubsubClient == nil {
+		return nil // events are only published when Pub/Sub is configured
+	}
+
 	topic := s.pubsubClient.Topic("analysis-completed")
 
 	event := map[string]interface{}{
@@ -478,6 +975,10 @@ func (s *AIAnalysisService) publishAnalysisCompletedEvent(ctx context.Context, r
 		"result":        s.extractResultData(result),
 		"timestamp":     time.Now().Unix(),
 	}
+	if result.SpamVerdict != nil {
+		// Downstream CRM pushes skip calls the tenant's spam threshold suppresses
+		event["crm_suppressed"] = result.SpamVerdict.Suppressed
+	}
 
 	data, err := json.Marshal(event)
 	if err != nil {
@@ -532,7 +1033,11 @@ func (s *AIAnalysisService) startPubSubListener(ctx context.Context) {
 func (s *AIAnalysisService) serializeProcessingData(result *AnalysisResponse, duration time.Duration) string {
 	data := map[string]interface{}{
 		"processing_time_ms": duration.Milliseconds(),
-		"model_used":         s.config.VertexAIModel,
+		"model_used":         s.aiService.Model(),
+		"input_tokens":       result.Usage.InputTokens,
+		"output_tokens":      result.Usage.OutputTokens,
+		"template_id":        result.TemplateID,
+		"template_version":   result.TemplateVersion,
 		"result":             result,
 	}
 
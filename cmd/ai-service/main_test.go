@@ -0,0 +1,523 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/internal/ai"
+	"github.com/home-renovators/ingestion-pipeline/internal/auth"
+	"github.com/home-renovators/ingestion-pipeline/internal/repository"
+	pkgai "github.com/home-renovators/ingestion-pipeline/pkg/ai"
+	"github.com/home-renovators/ingestion-pipeline/pkg/config"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+	"github.com/home-renovators/ingestion-pipeline/pkg/scheduler"
+	"github.com/home-renovators/ingestion-pipeline/pkg/spam"
+)
+
+// newTestAIService wires the AI analysis service to an in-memory store and a fake LLM, without Pub/Sub
+func newTestAIService(t *testing.T) (*repository.MemoryStore, *pkgai.FakeProvider, *gin.Engine) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	store := repository.NewMemoryStore()
+	llm := pkgai.NewFakeProvider()
+	cfg := config.DefaultConfig()
+	s := &AIAnalysisService{
+		config:      cfg,
+		authService: auth.NewAuthService(cfg, store),
+		repo:        store,
+		aiService:   ai.NewServiceWithProvider(cfg, llm),
+		scheduler:   scheduler.New(scheduler.DefaultConfig()),
+		spamScorer:  spam.NewScorer(nil),
+	}
+
+	router := gin.New()
+	s.setupRoutes(router)
+	return store, llm, router
+}
+
+// postJSON sends a JSON body to the router
+func postJSON(router *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAnalysisStatusIsScopedToTenant(t *testing.T) {
+	store, _, router := newTestAIService(t)
+	now := time.Now().UTC()
+	require.NoError(t, store.CreateAIProcessingLog(context.Background(), &models.AIProcessingLog{
+		LogID:          "proc_1",
+		TenantID:       "tenant_a",
+		RequestID:      "req_1",
+		AnalysisType:   "content_analysis",
+		Status:         "completed",
+		ProcessingData: `{"lead_score":80}`,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/analysis/status/proc_1?tenant_id=tenant_a", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var status map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, "req_1", status["request_id"])
+	assert.Equal(t, "completed", status["status"])
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/analysis/status/proc_1?tenant_id=tenant_b", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSkippedAnalysisIsNotLogged(t *testing.T) {
+	store, llm, router := newTestAIService(t)
+
+	w := postJSON(router, "/api/v1/analysis/content", `{"request_id":"req_1","tenant_id":"tenant_a","call_id":"CAL1","analysis_mode":"skip"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"skipped"`)
+
+	logs, err := store.ListAIProcessingLogsByRequest(context.Background(), "tenant_a", "req_1")
+	require.NoError(t, err)
+	assert.Empty(t, logs)
+	assert.Empty(t, llm.Calls(), "a skipped analysis never reaches the model")
+}
+
+func TestContentAnalysisRecordsTokenUsage(t *testing.T) {
+	store, llm, router := newTestAIService(t)
+	llm.SetDefault(pkgai.FakeResponse{
+		Text: `{"intent":"quote_request","project_type":"bathroom","timeline":"1-3_months","budget_indicator":"medium",
+			"sentiment":"positive","lead_score":72,"urgency":"medium","appointment_requested":true,
+			"follow_up_required":true,"key_details":["two bathrooms"]}`,
+		Usage: pkgai.TokenUsage{InputTokens: 640, OutputTokens: 85},
+	})
+
+	w := postJSON(router, "/api/v1/analysis/content",
+		`{"request_id":"req_1","tenant_id":"tenant_a","call_id":"CAL1","transcription":"We want to redo two bathrooms."}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp AnalysisResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.CallAnalysis)
+	assert.Equal(t, "bathroom", resp.CallAnalysis.ProjectType)
+	assert.Equal(t, int64(640), resp.Usage.InputTokens)
+
+	calls := llm.Calls()
+	require.Len(t, calls, 1)
+	assert.Contains(t, calls[0].Prompt, "We want to redo two bathrooms.")
+	require.NotNil(t, calls[0].ResponseSchema)
+
+	logs, err := store.ListAIProcessingLogsByRequest(context.Background(), "tenant_a", "req_1")
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Contains(t, logs[0].ProcessingData, `"input_tokens":640`)
+	assert.Contains(t, logs[0].ProcessingData, `"output_tokens":85`)
+}
+
+func TestFailedAnalysisIsReportedAndNotLogged(t *testing.T) {
+	store, llm, router := newTestAIService(t)
+	llm.SetDefault(pkgai.FakeResponse{Error: "RESOURCE_EXHAUSTED: quota exceeded"})
+
+	w := postJSON(router, "/api/v1/analysis/sentiment",
+		`{"request_id":"req_1","tenant_id":"tenant_a","call_id":"CAL1","transcription":"hello"}`)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	logs, err := store.ListAIProcessingLogsByRequest(context.Background(), "tenant_a", "req_1")
+	require.NoError(t, err)
+	assert.Empty(t, logs)
+}
+
+func TestContentAnalysisRelatesCallToCallerHistory(t *testing.T) {
+	store, llm, router := newTestAIService(t)
+	ctx := context.Background()
+	earlier := time.Date(2026, 9, 14, 15, 0, 0, 0, time.UTC)
+	kitchenAnalysis := `{"intent":"quote_request","project_type":"kitchen","summary":{"summary":"Wants a quote for new kitchen cabinets."}}`
+	for _, req := range []*models.Request{
+		{RequestID: "req_1", TenantID: "tenant_a", Data: `{"customer_phone_number":"+15125550142"}`, CreatedAt: earlier.AddDate(0, -1, 0)},
+		{RequestID: "req_2", TenantID: "tenant_a", Data: `{"customer_phone_number":"(512) 555-0142"}`, AIAnalysis: &kitchenAnalysis, CreatedAt: earlier},
+		{RequestID: "req_other", TenantID: "tenant_b", Data: `{"customer_phone_number":"+15125550142"}`, CreatedAt: earlier},
+		{RequestID: "req_3", TenantID: "tenant_a", Data: `{"customer_phone_number":"+15125550142"}`, CreatedAt: time.Now()},
+	} {
+		req.Source, req.Status, req.UpdatedAt = "callrail", "completed", req.CreatedAt
+		require.NoError(t, store.CreateRequest(ctx, req))
+	}
+	require.NoError(t, store.CreateCRMIntegration(ctx, &models.CRMIntegration{
+		IntegrationID: "int_1", TenantID: "tenant_a", CRMType: "hubspot", Status: "completed",
+		Config:    `{"request_id":"req_2","lead_data":{"lead_status":"quote sent"}}`,
+		CreatedAt: earlier, UpdatedAt: earlier,
+	}))
+
+	llm.SetDefault(pkgai.FakeResponse{Text: `{"intent":"follow_up","project_type":"kitchen","timeline":"1-3_months",
+		"budget_indicator":"medium","sentiment":"positive","lead_score":80,"urgency":"medium","appointment_requested":false,
+		"follow_up_required":true,"key_details":["asking about the cabinet quote"],
+		"continuity":{"relationship":"follow_up","related_request_id":"req_2","reasoning":"asks about the quote from last time"}}`})
+	w := postJSON(router, "/api/v1/analysis/content",
+		`{"request_id":"req_3","tenant_id":"tenant_a","call_id":"CAL3","transcription":"Did you finish the cabinet quote?",
+		"call_details":{"id":"CAL3","customer_phone_number":"+15125550142"}}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	prompt := llm.Calls()[0].Prompt
+	assert.Contains(t, prompt, "- 2026-09-14 [req_2]: quote_request, kitchen, CRM stage: quote sent\n  Wants a quote for new kitchen cabinets.")
+	assert.Contains(t, prompt, "- 2026-08-14 [req_1]: not analyzed")
+	assert.Less(t, strings.Index(prompt, "[req_2]"), strings.Index(prompt, "[req_1]"), "newest first")
+	assert.NotContains(t, prompt, "req_other", "another business's calls stay out")
+	assert.NotContains(t, prompt, "[req_3]", "the call itself is not history")
+
+	var resp AnalysisResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.CallAnalysis.Continuity)
+	assert.Equal(t, models.CallRelationshipFollowUp, resp.CallAnalysis.Continuity.Relationship)
+	assert.Equal(t, "req_2", resp.CallAnalysis.Continuity.RelatedRequestID)
+
+	request, err := store.GetRequest(ctx, "tenant_a", "req_3")
+	require.NoError(t, err)
+	assert.Contains(t, *request.AIAnalysis, `"relationship":"follow_up"`)
+}
+
+func TestSpamDetectionRepairsUnusableAnswer(t *testing.T) {
+	_, llm, router := newTestAIService(t)
+	llm.SetDefault(pkgai.FakeResponse{
+		Text:  "Sure! ```json\n{\"spam_likelihood\": \"high\"}\n```",
+		Usage: pkgai.TokenUsage{InputTokens: 200, OutputTokens: 20},
+	})
+
+	transcript := "Press one to hear about your car warranty."
+	w := postJSON(router, "/api/v1/analysis/spam-detection",
+		`{"request_id":"req_1","tenant_id":"tenant_a","call_id":"CAL1","transcription":"`+transcript+`"}`)
+	require.Equal(t, http.StatusInternalServerError, w.Code, "the repaired answer is still unusable")
+	require.Len(t, llm.Calls(), 2)
+
+	repairPrompt := llm.Calls()[1].Prompt
+	assert.Contains(t, repairPrompt, transcript)
+	assert.Contains(t, repairPrompt, `spam_likelihood "high" is not a number`)
+
+	llm.Respond(repairPrompt, pkgai.FakeResponse{
+		Text:  `{"spam_likelihood":95,"confidence":0.9,"indicators":["robocall"],"reasoning":"recorded pitch"}`,
+		Usage: pkgai.TokenUsage{InputTokens: 400, OutputTokens: 30},
+	})
+	w = postJSON(router, "/api/v1/analysis/spam-detection",
+		`{"request_id":"req_1","tenant_id":"tenant_a","call_id":"CAL1","transcription":"`+transcript+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp AnalysisResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.SpamResult)
+	assert.Equal(t, 95.0, resp.SpamResult.SpamLikelihood)
+	assert.Equal(t, pkgai.TokenUsage{InputTokens: 600, OutputTokens: 50}, resp.Usage, "usage covers the repair call")
+}
+
+func TestSpamVerdictBlendsCallerReputationAndSuppressesAboveThreshold(t *testing.T) {
+	store, llm, router := newTestAIService(t)
+	ctx := context.Background()
+
+	// The same number called three other businesses this morning
+	for i, tenantID := range []string{"tenant_b", "tenant_c", "tenant_d"} {
+		require.NoError(t, store.CreateRequest(ctx, &models.Request{
+			RequestID: fmt.Sprintf("req_%d", i), TenantID: tenantID, Source: "callrail", Status: "pending",
+			Data:      `{"customer_phone_number":"+1 (512) 555-0142"}`,
+			CreatedAt: time.Now().Add(-time.Hour), UpdatedAt: time.Now(),
+		}))
+	}
+	workflowConfig, err := json.Marshal(models.WorkflowConfig{
+		Validation: models.ValidationConfig{
+			SpamDetection: models.SpamDetectionConfig{Enabled: true, ConfidenceThreshold: 45},
+		},
+	})
+	require.NoError(t, err)
+	store.PutOffice(&models.Office{TenantID: "tenant_a", OfficeID: "office_a", WorkflowConfig: string(workflowConfig), Status: "active"})
+
+	llm.SetDefault(pkgai.FakeResponse{Text: `{"spam_likelihood":40,"confidence":1,"indicators":["vague"],"reasoning":"unclear intent"}`})
+	w := postJSON(router, "/api/v1/analysis/spam-detection",
+		`{"request_id":"req_1","tenant_id":"tenant_a","call_id":"CAL1","transcription":"Hello? Is the owner there?",
+		"call_details":{"id":"CAL1","customer_phone_number":"+15125550142","duration":8,"answered":true}}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp AnalysisResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.SpamVerdict)
+	require.NotNil(t, resp.SpamLikelihood)
+	assert.Equal(t, resp.SpamVerdict.Likelihood, *resp.SpamLikelihood)
+	assert.Equal(t, 40.0, resp.SpamResult.SpamLikelihood, "the LLM's own answer is kept")
+	assert.Greater(t, *resp.SpamLikelihood, 40.0, "raised by the short call and the repeat caller")
+	assert.True(t, resp.SpamVerdict.Suppressed)
+	assert.Contains(t, resp.SpamVerdict.Explanation, "caller reached 3 businesses within 24 hours")
+	assert.Contains(t, resp.SpamVerdict.Explanation, "Kept out of the CRM")
+}
+
+// putRooferOffice gives tenant_roof a roofing prompt configuration
+func putRooferOffice(t *testing.T, store *repository.MemoryStore) {
+	t.Helper()
+	workflowConfig, err := json.Marshal(models.WorkflowConfig{
+		Analysis: models.AnalysisPromptConfig{
+			BusinessType:    "roofing company",
+			ProjectTypes:    []string{"Roof Replacement", "roof repair", "gutters"},
+			ScoringCriteria: []string{"Active leaks score highest", "Insurance claims in progress"},
+		},
+	})
+	require.NoError(t, err)
+	store.PutOffice(&models.Office{
+		TenantID:       "tenant_roof",
+		OfficeID:       "office_roof",
+		WorkflowConfig: string(workflowConfig),
+		Status:         "active",
+	})
+}
+
+func TestPromptPreviewRendersTenantTemplate(t *testing.T) {
+	store, llm, router := newTestAIService(t)
+	putRooferOffice(t, store)
+
+	w := postJSON(router, "/api/v1/prompts/preview",
+		`{"tenant_id":"tenant_roof","analysis_type":"content_analysis","transcription":"Water is coming through the ceiling.",
+		"call_details":{"customer_name":"Sam Lee","duration":95}}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var preview struct {
+		TemplateID        string        `json:"template_id"`
+		TemplateVersion   int           `json:"template_version"`
+		AvailableVersions []int         `json:"available_versions"`
+		Prompt            string        `json:"prompt"`
+		ResponseSchema    *pkgai.Schema `json:"response_schema"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &preview))
+	assert.Equal(t, "content_analysis", preview.TemplateID)
+	assert.Equal(t, 1, preview.TemplateVersion)
+	assert.Equal(t, []int{1}, preview.AvailableVersions)
+	assert.Contains(t, preview.Prompt, "for a roofing company")
+	assert.Contains(t, preview.Prompt, `"project_type": "roof_replacement|roof_repair|gutters|other"`)
+	assert.Contains(t, preview.Prompt, "- Active leaks score highest")
+	assert.Contains(t, preview.Prompt, "- Customer Name: Sam Lee")
+	assert.NotContains(t, preview.Prompt, "kitchen")
+	assert.Equal(t, []string{"roof_replacement", "roof_repair", "gutters", "other"}, preview.ResponseSchema.Properties["project_type"].Enum)
+	assert.Empty(t, llm.Calls(), "previews never call the model")
+
+	w = postJSON(router, "/api/v1/prompts/preview", `{"tenant_id":"tenant_roof","analysis_type":"content_analysis","template_version":7}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = postJSON(router, "/api/v1/prompts/preview", `{"tenant_id":"tenant_roof","analysis_type":"horoscope"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestContentAnalysisRecordsPromptTemplate(t *testing.T) {
+	store, llm, router := newTestAIService(t)
+	putRooferOffice(t, store)
+	llm.SetDefault(pkgai.FakeResponse{
+		Text: `{"intent":"quote_request","project_type":"Roof Repair","timeline":"immediate","budget_indicator":"unknown",
+			"sentiment":"negative","lead_score":88,"urgency":"high","appointment_requested":true,
+			"follow_up_required":true,"key_details":["active leak"]}`,
+	})
+
+	w := postJSON(router, "/api/v1/analysis/content",
+		`{"request_id":"req_1","tenant_id":"tenant_roof","call_id":"CAL1","transcription":"Water is coming through the ceiling."}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp AnalysisResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.CallAnalysis)
+	assert.Equal(t, "roof_repair", resp.CallAnalysis.ProjectType, "validated against the tenant's taxonomy")
+	assert.Equal(t, "content_analysis", resp.TemplateID)
+	assert.Equal(t, 1, resp.TemplateVersion)
+	assert.Contains(t, llm.Calls()[0].Prompt, "roofing company")
+
+	logs, err := store.ListAIProcessingLogsByRequest(context.Background(), "tenant_roof", "req_1")
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Contains(t, logs[0].ProcessingData, `"template_id":"content_analysis"`)
+	assert.Contains(t, logs[0].ProcessingData, `"template_version":1`)
+}
+
+// combinedAnswer is a combined analysis answer for a roof leak call
+const combinedAnswer = `{
+	"call_analysis":{"intent":"quote_request","project_type":"Roof Repair","timeline":"immediate","budget_indicator":"unknown",
+		"sentiment":"neutral","lead_score":80,"urgency":"high","appointment_requested":true,"follow_up_required":true,
+		"key_details":["active leak"]},
+	"spam":{"spam_likelihood":3,"confidence":0.95,"indicators":[],"reasoning":"homeowner describing damage"},
+	"sentiment":{"sentiment":"negative","confidence":0.8,"emotional_tone":"worried","customer_satisfaction":"medium","key_emotions":["stress"]},
+	"intent":{"primary_intent":"appointment_booking","confidence":0.9,"secondary_intents":["quote_request"],"reasoning":"wants a visit"},
+	"lead_score":{"lead_score":88,"confidence":0.85,"scoring_factors":{"project_complexity":6,"buying_readiness":9,
+		"timeline_urgency":10,"budget_capability":6,"engagement_quality":8},"reasoning":"urgent repair"},
+	"summary":{"summary":"Caller has water coming through an upstairs ceiling after a storm and wants someone out today.",
+		"action_items":["Dispatch a crew for a leak inspection today"],"commitments":["Rep promised a call back within the hour"],
+		"open_questions":["Does the warranty cover storm damage?"]},
+	"entities":{"customer_name":{"value":"Dana Whitfield","confidence":0.9,"quote":"this is Dana Whitfield"},
+		"email":null,"address":null,"callback_time":null}
+}`
+
+func TestCombinedAnalysisAnswersEverythingInOneCall(t *testing.T) {
+	store, llm, router := newTestAIService(t)
+	putRooferOffice(t, store)
+	llm.SetDefault(pkgai.FakeResponse{Text: combinedAnswer, Usage: pkgai.TokenUsage{InputTokens: 900, OutputTokens: 310}})
+
+	w := postJSON(router, "/api/v1/analysis/combined",
+		`{"request_id":"req_1","tenant_id":"tenant_roof","call_id":"CAL1","transcription":"Hi, this is Dana Whitfield. Water is coming through the ceiling."}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp AnalysisResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.CallAnalysis)
+	require.NotNil(t, resp.SpamResult)
+	require.NotNil(t, resp.SentimentResult)
+	require.NotNil(t, resp.IntentResult)
+	require.NotNil(t, resp.LeadScoreResult)
+	assert.Equal(t, "roof_repair", resp.CallAnalysis.ProjectType, "validated against the tenant's taxonomy")
+	assert.Equal(t, 88, resp.CallAnalysis.LeadScore, "reconciled with the lead score section")
+	assert.Equal(t, "negative", resp.CallAnalysis.Sentiment, "reconciled with the sentiment section")
+	assert.Equal(t, "appointment_booking", resp.CallAnalysis.Intent)
+	require.NotNil(t, resp.SpamLikelihood)
+	assert.Equal(t, 3.0, *resp.SpamLikelihood)
+	assert.Equal(t, 9, resp.LeadScoreResult.ScoringFactors.BuyingReadiness)
+	assert.Equal(t, "combined_analysis", resp.TemplateID)
+	require.NotNil(t, resp.Entities)
+	require.NotNil(t, resp.Entities.CustomerName)
+	assert.Equal(t, "Dana Whitfield", resp.Entities.CustomerName.Value)
+	assert.Equal(t, &models.TranscriptSpan{Start: 4, End: 26}, resp.Entities.CustomerName.Span)
+
+	calls := llm.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, pkgai.CombinedMaxOutputTokens, calls[0].MaxOutputTokens)
+	assert.Contains(t, calls[0].Prompt, "roofing company")
+
+	logs, err := store.ListAIProcessingLogsByRequest(context.Background(), "tenant_roof", "req_1")
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "combined_analysis", logs[0].AnalysisType)
+	assert.Contains(t, logs[0].ProcessingData, `"output_tokens":310`)
+}
+
+func TestPipelineRequestsDefaultToCombinedAnalysis(t *testing.T) {
+	store, llm, router := newTestAIService(t)
+	putRooferOffice(t, store)
+	llm.SetDefault(pkgai.FakeResponse{Text: combinedAnswer})
+
+	w := postJSON(router, "/api/v1/analysis/batch",
+		`{"requests":[{"request_id":"req_1","tenant_id":"tenant_roof","call_id":"CAL1","transcription":"Hi, this is Dana Whitfield. Water is coming through the ceiling."}]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Results []AnalysisResponse `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Results, 1)
+	assert.Equal(t, "completed", body.Results[0].Status)
+	assert.NotNil(t, body.Results[0].SentimentResult)
+	assert.Len(t, llm.Calls(), 1)
+}
+
+func TestSummaryModeRunsCombinedAnalysisAsContentOnly(t *testing.T) {
+	_, llm, router := newTestAIService(t)
+	llm.SetDefault(pkgai.FakeResponse{
+		Text: `{"intent":"follow_up","project_type":"other","timeline":"unknown","budget_indicator":"unknown",
+			"sentiment":"neutral","lead_score":40,"urgency":"low","appointment_requested":false,
+			"follow_up_required":true,"key_details":["left a voicemail"]}`,
+	})
+
+	w := postJSON(router, "/api/v1/analysis/combined",
+		`{"request_id":"req_1","tenant_id":"tenant_a","call_id":"CAL1","transcription":"Please call me back.","analysis_mode":"summary"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp AnalysisResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.CallAnalysis)
+	assert.Nil(t, resp.SentimentResult)
+	assert.Equal(t, "content_analysis", resp.TemplateID)
+}
+
+func TestAnalysesAreStoredOnTheRequest(t *testing.T) {
+	store, llm, router := newTestAIService(t)
+	ctx := context.Background()
+	require.NoError(t, store.CreateRequest(ctx, &models.Request{
+		RequestID: "req_1", TenantID: "tenant_a", Source: "callrail", Status: "pending",
+		AINormalized: "{}", AIExtracted: "{}", CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+
+	llm.SetDefault(pkgai.FakeResponse{Text: `{"summary":"Caller wants two bathrooms redone.",
+		"action_items":["Send quote for 2 bathrooms"],"commitments":[],"open_questions":["Is June realistic?"]}`})
+	w := postJSON(router, "/api/v1/analysis/summary",
+		`{"request_id":"req_1","tenant_id":"tenant_a","call_id":"CAL1","transcription":"We want to redo two bathrooms by June."}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp AnalysisResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Summary)
+	assert.Equal(t, []string{"Is June realistic?"}, resp.Summary.OpenQuestions)
+
+	// A later content analysis replaces the analysis but keeps the summary
+	llm.SetDefault(pkgai.FakeResponse{
+		Text: `{"intent":"quote_request","project_type":"bathroom","timeline":"1-3_months","budget_indicator":"medium",
+			"sentiment":"positive","lead_score":72,"urgency":"medium","appointment_requested":true,
+			"follow_up_required":true,"key_details":["two bathrooms"]}`,
+	})
+	w = postJSON(router, "/api/v1/analysis/content",
+		`{"request_id":"req_1","tenant_id":"tenant_a","call_id":"CAL1","transcription":"We want to redo two bathrooms by June."}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	request, err := store.GetRequest(ctx, "tenant_a", "req_1")
+	require.NoError(t, err)
+	require.NotNil(t, request.AIAnalysis)
+	var stored models.CallAnalysis
+	require.NoError(t, json.Unmarshal([]byte(*request.AIAnalysis), &stored))
+	assert.Equal(t, "bathroom", stored.ProjectType)
+	require.NotNil(t, stored.Summary)
+	assert.Equal(t, "Caller wants two bathrooms redone.", stored.Summary.Summary)
+	require.NotNil(t, request.LeadScore)
+	assert.Equal(t, 72, *request.LeadScore)
+}
+
+func TestExtractedEntitiesAreStoredOnTheRequest(t *testing.T) {
+	store, llm, router := newTestAIService(t)
+	ctx := context.Background()
+	require.NoError(t, store.CreateRequest(ctx, &models.Request{
+		RequestID: "req_1", TenantID: "tenant_a", Source: "callrail", Status: "pending",
+		AINormalized: "{}", AIExtracted: "{}", CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+
+	llm.SetDefault(pkgai.FakeResponse{Text: `{
+		"customer_name":{"value":"dana whitfield","confidence":0.9,"quote":"Dana Whitfield"},
+		"email":{"value":"Dana.W@Example.com","confidence":0.8,"quote":"dana dot w at example dot com"},
+		"address":null,
+		"callback_time":{"value":"after 5pm","confidence":0.7,"quote":"any time after five"}}`})
+	w := postJSON(router, "/api/v1/analysis/entities",
+		`{"request_id":"req_1","tenant_id":"tenant_a","call_id":"CAL1",
+		"transcription":"This is Dana Whitfield, email dana dot w at example dot com. Call me back in the evening."}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp AnalysisResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Entities)
+	assert.Equal(t, "entity_extraction", resp.TemplateID)
+
+	request, err := store.GetRequest(ctx, "tenant_a", "req_1")
+	require.NoError(t, err)
+	var stored models.CallEntities
+	require.NoError(t, json.Unmarshal([]byte(request.AIExtracted), &stored))
+	require.NotNil(t, stored.CustomerName)
+	assert.Equal(t, "Dana Whitfield", stored.CustomerName.Value)
+	require.NotNil(t, stored.Email)
+	assert.Equal(t, "dana.w@example.com", stored.Email.Value)
+	require.NotNil(t, stored.Email.Span)
+	assert.Nil(t, stored.Address)
+
+	// The caller never said "after five", so the callback time is kept but not trusted
+	require.NotNil(t, stored.CallbackTime)
+	assert.Nil(t, stored.CallbackTime.Span)
+	assert.Equal(t, pkgai.UnverifiedEntityConfidence, stored.CallbackTime.Confidence)
+}
+
+func TestHotLeadsAreAnalyzedUrgently(t *testing.T) {
+	hot := models.CallDetails{ID: "CAL1", FirstCall: true, Answered: true}
+	assert.Equal(t, scheduler.PriorityUrgent, analysisPriority(&AnalysisRequest{CallDetails: hot}))
+	assert.Equal(t, scheduler.PriorityNormal, analysisPriority(&AnalysisRequest{CallDetails: models.CallDetails{ID: "CAL2", Answered: true}}))
+	assert.Equal(t, scheduler.PriorityNormal, analysisPriority(&AnalysisRequest{}))
+	assert.Equal(t, scheduler.PriorityLow, analysisPriority(&AnalysisRequest{CallDetails: hot, Priority: "low"}))
+}
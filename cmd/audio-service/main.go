@@ -1,34 +1,51 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"cloud.google.com/go/pubsub"
 
-	"github.com/home-renovators/ingestion-pipeline/internal/ai"
 	"github.com/home-renovators/ingestion-pipeline/internal/auth"
+	"github.com/home-renovators/ingestion-pipeline/internal/encryption"
+	"github.com/home-renovators/ingestion-pipeline/internal/migrations"
+	"github.com/home-renovators/ingestion-pipeline/internal/repository"
 	"github.com/home-renovators/ingestion-pipeline/internal/spanner"
 	"github.com/home-renovators/ingestion-pipeline/internal/storage"
+	"github.com/home-renovators/ingestion-pipeline/pkg/audio"
 	"github.com/home-renovators/ingestion-pipeline/pkg/config"
+	"github.com/home-renovators/ingestion-pipeline/pkg/crm"
+	"github.com/home-renovators/ingestion-pipeline/pkg/export"
 	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+	"github.com/home-renovators/ingestion-pipeline/pkg/privacy"
+	"github.com/home-renovators/ingestion-pipeline/pkg/retention"
 )
 
 type AudioService struct {
 	config         *config.Config
 	authService    *auth.AuthService
-	spannerRepo    *spanner.Repository
+	repo           repository.Store
 	storageService *storage.Service
-	aiService      *ai.Service
+	transcriber    *audio.TranscriptionService
+	jobManager     *audio.JobManager
 	pubsubClient   *pubsub.Client
+	classifier     *audio.CallClassifier
+	decoder        *audio.Decoder
+	fingerprints   *audio.FingerprintMatcher
+	envelope       *encryption.Envelope // nil when encryption is disabled
+	purger         *retention.Purger
+	dataSubjects   *privacy.Service
 }
 
 type AudioProcessingRequest struct {
@@ -37,7 +54,13 @@ type AudioProcessingRequest struct {
 	CallID        string `json:"call_id"`
 	StorageURL    string `json:"storage_url"`
 	RequestID     string `json:"request_id"`
-	Priority      string `json:"priority,omitempty"` // high, normal, low
+	Priority      string `json:"priority,omitempty"` // low, normal, high or urgent; hot leads default to urgent
+
+	// Call metadata used to screen voicemails and silent recordings
+	Answered      *bool  `json:"answered,omitempty"`
+	CallDuration  int    `json:"call_duration,omitempty"` // seconds
+	CustomerName  string `json:"customer_name,omitempty"`
+	CustomerPhone string `json:"customer_phone,omitempty"`
 }
 
 type AudioProcessingResponse struct {
@@ -45,6 +68,9 @@ type AudioProcessingResponse struct {
 	RecordingID      string                      `json:"recording_id"`
 	TranscriptionID  string                      `json:"transcription_id,omitempty"`
 	Transcription    *models.TranscriptionResult `json:"transcription,omitempty"`
+	Classification   *audio.CallClassification   `json:"classification,omitempty"`
+	Fingerprint      *models.FingerprintMatch    `json:"fingerprint,omitempty"`
+	AnalysisMode     models.AnalysisMode         `json:"analysis_mode,omitempty"`
 	ProcessingTimeMs int64                       `json:"processing_time_ms"`
 	Error            string                      `json:"error,omitempty"`
 }
@@ -74,7 +100,12 @@ func main() {
 	service.setupRoutes(router)
 
 	// Start background workers
+	go service.resumeTranscriptionJobs(ctx)
 	go service.startPubSubListener(ctx)
+	go service.runTranscriptionJobCleanup(ctx)
+	if cfg.RetentionPurgeEnabled {
+		go service.runRetentionPurge(ctx)
+	}
 
 	// Start server
 	server := &http.Server{
@@ -110,6 +141,13 @@ func initializeServices(ctx context.Context, cfg *config.Config) (*AudioService,
 		return nil, fmt.Errorf("failed to initialize spanner repository: %w", err)
 	}
 
+	// Refuse to run against a database that hasn't been migrated to this build's schema
+	if cfg.SchemaCheck {
+		if err := migrations.Verify(ctx, spannerRepo); err != nil {
+			return nil, fmt.Errorf("failed to verify database schema: %w", err)
+		}
+	}
+
 	// Initialize authentication service
 	authService := auth.NewAuthService(cfg, spannerRepo)
 
@@ -119,10 +157,30 @@ func initializeServices(ctx context.Context, cfg *config.Config) (*AudioService,
 		return nil, fmt.Errorf("failed to initialize storage service: %w", err)
 	}
 
-	// Initialize AI service
-	aiService, err := ai.NewService(ctx, cfg)
+	// Encrypt recordings and transcripts with per-tenant data keys
+	envelope, err := encryption.NewEnvelopeFromConfig(ctx, cfg, spannerRepo)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize AI service: %w", err)
+		return nil, fmt.Errorf("failed to initialize encryption: %w", err)
+	}
+	if envelope != nil {
+		spannerRepo.SetEnvelope(envelope)
+		storageService.SetEnvelope(envelope)
+	}
+
+	// Initialize transcription service
+	transcriptionConfig := audio.DefaultTranscriptionConfig()
+	transcriptionConfig.ProjectID = cfg.SpeechToTextProject
+	transcriptionConfig.Location = cfg.SpeechToTextLocation
+	transcriptionConfig.Model = cfg.SpeechToTextModel
+	transcriptionConfig.LanguageCode = cfg.SpeechLanguage
+	transcriptionConfig.EnableDiarization = cfg.EnableDiarization
+	transcriptionConfig.Scheduling.MaxConcurrent = cfg.TranscriptionConcurrency
+	transcriptionConfig.Scheduling.MaxPerTenant = cfg.TenantConcurrency
+	transcriptionConfig = transcriptionConfig.WithLanguages(append([]string{cfg.SpeechLanguage}, cfg.SpeechAlternativeLanguages...))
+
+	transcriber, err := audio.NewTranscriptionService(ctx, transcriptionConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize transcription service: %w", err)
 	}
 
 	// Initialize Pub/Sub client
@@ -131,25 +189,41 @@ func initializeServices(ctx context.Context, cfg *config.Config) (*AudioService,
 		return nil, fmt.Errorf("failed to initialize Pub/Sub client: %w", err)
 	}
 
+	// Recordings are decoded locally for screening, fingerprinting and audio redaction
+	decoderConfig := audio.DefaultDecoderConfig()
+	decoderConfig.FFmpegPath = cfg.FFmpegPath
+
+	// Purge call data past each tenant's retention period
+	purgeConfig := retention.DefaultConfig()
+	purgeConfig.DefaultDays = cfg.RetentionDays
+	purgeConfig.BatchSize = cfg.RetentionPurgeBatchSize
+
 	return &AudioService{
 		config:         cfg,
 		authService:    authService,
-		spannerRepo:    spannerRepo,
+		repo:           spannerRepo,
 		storageService: storageService,
-		aiService:      aiService,
+		transcriber:    transcriber,
+		jobManager:     audio.NewJobManager(spannerRepo),
 		pubsubClient:   pubsubClient,
+		classifier:     audio.NewCallClassifier(nil),
+		decoder:        audio.NewDecoder(decoderConfig),
+		fingerprints:   audio.NewFingerprintMatcher(spannerRepo, nil),
+		envelope:       envelope,
+		purger:         retention.NewPurger(spannerRepo, storageService, purgeConfig),
+		dataSubjects:   privacy.NewService(spannerRepo, storageService, &crmEraser{repo: spannerRepo, manager: crm.SetupDefaultProviders()}),
 	}, nil
 }
 
 func (s *AudioService) cleanup() {
-	if s.spannerRepo != nil {
-		s.spannerRepo.Close()
+	if s.repo != nil {
+		s.repo.Close()
 	}
 	if s.storageService != nil {
 		s.storageService.Close()
 	}
-	if s.aiService != nil {
-		s.aiService.Close()
+	if s.transcriber != nil {
+		s.transcriber.Close()
 	}
 	if s.pubsubClient != nil {
 		s.pubsubClient.Close()
@@ -160,13 +234,37 @@ func (s *AudioService) setupRoutes(router *gin.Engine) {
 	// Health check
 	router.GET("/health", s.healthCheck)
 
+	// Signed URLs of the local storage backend are served by the service itself
+	if local, ok := s.storageService.Store().(*storage.LocalStore); ok {
+		router.Any("/blobs/*key", gin.WrapH(http.StripPrefix("/blobs", local.Handler())))
+	}
+
 	// API routes
 	api := router.Group("/api/v1")
 	{
 		// Audio processing endpoints
 		api.POST("/audio/transcribe", s.handleTranscribeAudio)
 		api.GET("/audio/status/:recording_id", s.handleGetTranscriptionStatus)
+		api.GET("/audio/transcript/:recording_id", s.handleExportTranscript)
 		api.POST("/audio/process-batch", s.handleBatchProcess)
+		api.POST("/audio/robocalls/:recording_id", s.handleMarkRobocall)
+
+		// Tenant data key management
+		api.GET("/tenants/:tenant_id/keys", s.handleListTenantKeys)
+		api.POST("/tenants/:tenant_id/keys/rotate", s.handleRotateTenantKey)
+		api.POST("/tenants/:tenant_id/keys/shred", s.handleShredTenant)
+
+		// Retention and legal holds
+		api.GET("/tenants/:tenant_id/legal-holds", s.handleListLegalHolds)
+		api.POST("/tenants/:tenant_id/legal-holds", s.handleCreateLegalHold)
+		api.DELETE("/tenants/:tenant_id/legal-holds/:hold_id", s.handleReleaseLegalHold)
+		api.POST("/tenants/:tenant_id/retention/purge", s.handlePurgeTenant)
+
+		// Data subject (GDPR/CCPA) requests
+		api.POST("/tenants/:tenant_id/data-subjects/search", s.handleSearchDataSubject)
+		api.POST("/tenants/:tenant_id/data-subjects/export", s.handleExportDataSubject)
+		api.POST("/tenants/:tenant_id/data-subjects/delete", s.handleDeleteDataSubject)
+		api.GET("/tenants/:tenant_id/data-subjects/log", s.handleListDataSubjectLog)
 	}
 }
 
@@ -217,19 +315,525 @@ func (s *AudioService) handleGetTranscriptionStatus(c *gin.Context) {
 	}
 
 	// Get recording status from database
-	recording, err := s.spannerRepo.GetCallRecording(ctx, tenantID, recordingID)
+	recording, err := s.repo.GetCallRecording(ctx, tenantID, recordingID)
 	if err != nil {
 		log.Printf("Failed to get recording status: %v", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Recording not found"})
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Recording not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get recording status"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	status := gin.H{
 		"recording_id":         recording.RecordingID,
 		"tenant_id":           recording.TenantID,
 		"call_id":             recording.CallID,
 		"transcription_status": recording.TranscriptionStatus,
 		"created_at":          recording.CreatedAt,
+	}
+
+	// Add live progress from the transcription job when one exists
+	job, err := s.jobManager.FindLatestJob(ctx, tenantID, recording.CallID)
+	if err != nil && !errors.Is(err, audio.ErrJobNotFound) {
+		log.Printf("Failed to get transcription job: %v", err)
+	}
+	if job != nil {
+		status["job"] = gin.H{
+			"job_id":         job.JobID,
+			"status":         job.Status.Status,
+			"progress":       job.Status.Progress,
+			"operation_name": job.Status.OperationName,
+			"started_at":     job.Status.StartTime,
+			"updated_at":     job.Status.UpdatedAt,
+			"ended_at":       job.Status.EndTime,
+			"error":          job.Status.Error,
+		}
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+func (s *AudioService) handleExportTranscript(c *gin.Context) {
+	ctx := c.Request.Context()
+	recordingID := c.Param("recording_id")
+	tenantID := c.Query("tenant_id")
+	format := export.Format(c.DefaultQuery("format", string(export.FormatJSON)))
+
+	if recordingID == "" || tenantID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing recording_id or tenant_id"})
+		return
+	}
+
+	transcription, err := s.repo.GetCallRecordingTranscription(ctx, tenantID, recordingID)
+	if err != nil {
+		log.Printf("Failed to get transcription for export: %v", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transcript not found"})
+		return
+	}
+
+	// Agent and customer labels can be swapped for outbound calls, e.g. ?speaker_1=Customer
+	opts := export.DefaultOptions()
+	opts.SpeakerLabels = make(map[int]string)
+	for speaker := 1; speaker <= 6; speaker++ {
+		if label := c.Query(fmt.Sprintf("speaker_%d", speaker)); label != "" {
+			opts.SpeakerLabels[speaker] = label
+		}
+	}
+
+	body, contentType, err := export.Render(transcription, format, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("download") == "true" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", recordingID+"."+string(format)))
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// handleMarkRobocall adds the fingerprint of one of the tenant's recordings to the robocall list
+// shared by all tenants
+func (s *AudioService) handleMarkRobocall(c *gin.Context) {
+	ctx := c.Request.Context()
+	recordingID := c.Param("recording_id")
+	tenantID := c.Query("tenant_id")
+
+	if tenantID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing tenant_id"})
+		return
+	}
+
+	var body struct {
+		Label string `json:"label"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.Label == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing label"})
+		return
+	}
+
+	robocall, err := s.fingerprints.MarkRobocall(ctx, tenantID, recordingID, body.Label)
+	if errors.Is(err, audio.ErrFingerprintNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recording has no fingerprint"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to mark robocall: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark robocall"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"fingerprint_id": robocall.FingerprintID,
+		"label":          robocall.Label,
+		"duration_ms":    robocall.DurationMs,
+	})
+}
+
+func (s *AudioService) handleListTenantKeys(c *gin.Context) {
+	if s.envelope == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Encryption is not enabled"})
+		return
+	}
+
+	keys, err := s.envelope.TenantKeys(c.Request.Context(), c.Param("tenant_id"))
+	if err != nil {
+		log.Printf("Failed to list tenant keys: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tenant keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+func (s *AudioService) handleRotateTenantKey(c *gin.Context) {
+	if s.envelope == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Encryption is not enabled"})
+		return
+	}
+	tenantID := c.Param("tenant_id")
+
+	key, err := s.envelope.RotateTenantKey(c.Request.Context(), tenantID)
+	if errors.Is(err, encryption.ErrTenantShredded) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Tenant keys have been destroyed"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to rotate key for tenant %s: %v", tenantID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate tenant key"})
+		return
+	}
+
+	log.Printf("Rotated data key for tenant %s to version %d", tenantID, key.KeyVersion)
+	c.JSON(http.StatusOK, key)
+}
+
+// handleShredTenant destroys a tenant's data keys when the tenant is offboarded. Everything
+// encrypted for the tenant becomes unreadable, so the tenant ID must be repeated in the body.
+func (s *AudioService) handleShredTenant(c *gin.Context) {
+	if s.envelope == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Encryption is not enabled"})
+		return
+	}
+	tenantID := c.Param("tenant_id")
+
+	var body struct {
+		ConfirmTenantID string `json:"confirm_tenant_id"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.ConfirmTenantID != tenantID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "confirm_tenant_id must match the tenant being shredded"})
+		return
+	}
+
+	// Shredding destroys held data as surely as deleting it
+	holds, err := s.repo.ListLegalHolds(c.Request.Context(), tenantID)
+	if err != nil {
+		log.Printf("Failed to check legal holds for tenant %s: %v", tenantID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check legal holds"})
+		return
+	}
+	if len(holds) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Tenant has data on legal hold", "legal_holds": len(holds)})
+		return
+	}
+
+	if err := s.envelope.ShredTenant(c.Request.Context(), tenantID); err != nil {
+		log.Printf("Failed to shred tenant %s: %v", tenantID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to destroy tenant keys"})
+		return
+	}
+
+	log.Printf("Destroyed all data keys for tenant %s", tenantID)
+	c.JSON(http.StatusOK, gin.H{"tenant_id": tenantID, "status": "shredded"})
+}
+
+func (s *AudioService) handleListLegalHolds(c *gin.Context) {
+	holds, err := s.repo.ListLegalHolds(c.Request.Context(), c.Param("tenant_id"))
+	if err != nil {
+		log.Printf("Failed to list legal holds: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list legal holds"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"legal_holds": holds})
+}
+
+// handleCreateLegalHold places a call, or the whole tenant when no call_id is given, on legal hold
+func (s *AudioService) handleCreateLegalHold(c *gin.Context) {
+	var body struct {
+		CallID    string `json:"call_id"`
+		Reason    string `json:"reason"`
+		CreatedBy string `json:"created_by"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+
+	hold := &models.LegalHold{
+		HoldID:    models.NewHoldID(),
+		TenantID:  c.Param("tenant_id"),
+		CallID:    body.CallID,
+		Reason:    body.Reason,
+		CreatedBy: body.CreatedBy,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.repo.CreateLegalHold(c.Request.Context(), hold); err != nil {
+		log.Printf("Failed to create legal hold: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create legal hold"})
+		return
+	}
+
+	log.Printf("Placed legal hold %s on tenant %s call %q", hold.HoldID, hold.TenantID, hold.CallID)
+	c.JSON(http.StatusCreated, hold)
+}
+
+func (s *AudioService) handleReleaseLegalHold(c *gin.Context) {
+	tenantID, holdID := c.Param("tenant_id"), c.Param("hold_id")
+	if err := s.repo.ReleaseLegalHold(c.Request.Context(), tenantID, holdID); err != nil {
+		log.Printf("Failed to release legal hold %s: %v", holdID, err)
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Legal hold not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release legal hold"})
+		return
+	}
+
+	log.Printf("Released legal hold %s on tenant %s", holdID, tenantID)
+	c.JSON(http.StatusOK, gin.H{"hold_id": holdID, "status": "released"})
+}
+
+// handlePurgeTenant runs the retention purge for one tenant, or reports what it would purge
+// with dry_run=true
+func (s *AudioService) handlePurgeTenant(c *gin.Context) {
+	ctx := c.Request.Context()
+	tenantID := c.Param("tenant_id")
+
+	policies, err := s.retentionPolicies(ctx)
+	if err != nil {
+		log.Printf("Failed to load retention policies: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load retention policies"})
+		return
+	}
+
+	purger := s.purger
+	if c.Query("dry_run") == "true" {
+		purger = purger.WithDryRun()
+	}
+
+	report, err := purger.PurgeTenant(ctx, tenantID, policies[tenantID])
+	if err != nil {
+		log.Printf("Retention purge failed for tenant %s: %v", tenantID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Retention purge failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// runTranscriptionJobCleanup hourly deletes transcription jobs, and the results kept on them,
+// that finished longer ago than a redelivered call could still arrive
+func (s *AudioService) runTranscriptionJobCleanup(ctx context.Context) {
+	retention := time.Duration(s.config.TranscriptionJobRetentionHours) * time.Hour
+	if retention <= 0 {
+		retention = 7 * 24 * time.Hour
+	}
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		cleaned, err := s.jobManager.CleanupCompletedJobs(ctx, retention)
+		if err != nil {
+			log.Printf("Failed to clean up transcription jobs: %v", err)
+		} else if cleaned > 0 {
+			log.Printf("Cleaned up %d finished transcription jobs", cleaned)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runRetentionPurge purges every tenant's expired data on the configured interval
+func (s *AudioService) runRetentionPurge(ctx context.Context) {
+	interval := time.Duration(s.config.RetentionPurgeIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.purgeAllTenants(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeAllTenants runs the retention purge for each tenant with an active office
+func (s *AudioService) purgeAllTenants(ctx context.Context) {
+	policies, err := s.retentionPolicies(ctx)
+	if err != nil {
+		log.Printf("Failed to load retention policies: %v", err)
+		return
+	}
+
+	for tenantID, policy := range policies {
+		report, err := s.purger.PurgeTenant(ctx, tenantID, policy)
+		if err != nil {
+			log.Printf("Retention purge failed for tenant %s: %v", tenantID, err)
+			continue
+		}
+		if report.Held {
+			log.Printf("Skipped retention purge for tenant %s: tenant is on legal hold", tenantID)
+			continue
+		}
+		for class, classReport := range report.Classes {
+			if classReport.Purged > 0 || classReport.Held > 0 {
+				log.Printf("Purged %s of %d calls for tenant %s (%d held, %d objects, %d rows)",
+					class, classReport.Purged, tenantID, classReport.Held, classReport.ObjectsDeleted, classReport.RowsAffected)
+			}
+		}
+		for _, purgeErr := range report.Errors {
+			log.Printf("Retention purge error for tenant %s: %s", tenantID, purgeErr)
+		}
+	}
+}
+
+// retentionPolicies returns each tenant's retention policy, merged across its offices
+func (s *AudioService) retentionPolicies(ctx context.Context) (map[string]models.RetentionConfig, error) {
+	offices, err := s.repo.ListActiveOffices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byTenant := make(map[string][]models.RetentionConfig)
+	for _, office := range offices {
+		var workflowConfig models.WorkflowConfig
+		if office.WorkflowConfig != "" {
+			if err := json.Unmarshal([]byte(office.WorkflowConfig), &workflowConfig); err != nil {
+				log.Printf("Ignoring invalid workflow config of office %s: %v", office.OfficeID, err)
+			}
+		}
+		byTenant[office.TenantID] = append(byTenant[office.TenantID], workflowConfig.Retention)
+	}
+
+	policies := make(map[string]models.RetentionConfig, len(byTenant))
+	for tenantID, tenantPolicies := range byTenant {
+		policies[tenantID] = retention.MergePolicies(tenantPolicies, s.config.RetentionDays)
+	}
+	return policies, nil
+}
+
+// DataSubjectRequest identifies the consumer of a data subject request
+type DataSubjectRequest struct {
+	Phone       string `json:"phone"`
+	Email       string `json:"email"`
+	RequestedBy string `json:"requested_by"`
+	Confirm     bool   `json:"confirm"` // required for deletion
+}
+
+// bindDataSubject reads a data subject request, responding with 400 if it names no one
+func bindDataSubject(c *gin.Context) (*DataSubjectRequest, privacy.Subject, bool) {
+	var req DataSubjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return nil, privacy.Subject{}, false
+	}
+
+	subject := privacy.Subject{Phone: req.Phone, Email: req.Email}
+	if !subject.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A valid phone or email is required"})
+		return nil, privacy.Subject{}, false
+	}
+	return &req, subject, true
+}
+
+// handleSearchDataSubject lists everything held on a consumer without changing anything
+func (s *AudioService) handleSearchDataSubject(c *gin.Context) {
+	_, subject, ok := bindDataSubject(c)
+	if !ok {
+		return
+	}
+	tenantID := c.Param("tenant_id")
+
+	artifacts, err := s.dataSubjects.Find(c.Request.Context(), tenantID, subject)
+	if err != nil {
+		log.Printf("Failed to search data subject for tenant %s: %v", tenantID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search data subject"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"subject_hash": subject.Hash(tenantID),
+		"counts":       artifacts.Counts(),
+		"call_ids":     artifacts.CallIDs(),
+		"objects":      artifacts.Objects,
+	})
+}
+
+// handleExportDataSubject downloads a zip bundle of everything held on a consumer
+func (s *AudioService) handleExportDataSubject(c *gin.Context) {
+	req, subject, ok := bindDataSubject(c)
+	if !ok {
+		return
+	}
+	tenantID := c.Param("tenant_id")
+
+	// Build the bundle in memory so a failure can still be reported as an error response
+	var bundle bytes.Buffer
+	record, err := s.dataSubjects.Export(c.Request.Context(), tenantID, subject, req.RequestedBy, &bundle)
+	if err != nil {
+		log.Printf("Failed to export data subject for tenant %s: %v", tenantID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export data subject"})
+		return
+	}
+
+	log.Printf("Exported data subject %s for tenant %s (%s)", record.SubjectHash, tenantID, record.LogID)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", record.LogID+".zip"))
+	c.Data(http.StatusOK, "application/zip", bundle.Bytes())
+}
+
+// handleDeleteDataSubject erases everything held on a consumer, including their CRM lead where the
+// CRM supports deletion. The compliance log records the outcome.
+func (s *AudioService) handleDeleteDataSubject(c *gin.Context) {
+	req, subject, ok := bindDataSubject(c)
+	if !ok {
+		return
+	}
+	if !req.Confirm {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "confirm must be true to delete a data subject"})
+		return
+	}
+	tenantID := c.Param("tenant_id")
+
+	record, err := s.dataSubjects.Delete(c.Request.Context(), tenantID, subject, req.RequestedBy)
+	if errors.Is(err, privacy.ErrTenantOnHold) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Tenant is on legal hold", "log": record})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete data subject for tenant %s: %v", tenantID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete data subject", "log": record})
+		return
+	}
+
+	log.Printf("Deleted data subject %s for tenant %s: %s (%s)", record.SubjectHash, tenantID, record.Status, record.LogID)
+	c.JSON(http.StatusOK, record)
+}
+
+func (s *AudioService) handleListDataSubjectLog(c *gin.Context) {
+	tenantID := c.Param("tenant_id")
+
+	subjectHash := c.Query("subject_hash")
+	if phone, email := c.Query("phone"), c.Query("email"); phone != "" || email != "" {
+		subjectHash = privacy.Subject{Phone: phone, Email: email}.Hash(tenantID)
+	}
+
+	logs, err := s.repo.ListDataSubjectLogs(c.Request.Context(), tenantID, subjectHash)
+	if err != nil {
+		log.Printf("Failed to list data subject log: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list data subject log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"log": logs})
+}
+
+// crmEraser deletes leads with the CRM configured in the tenant's workflow config
+type crmEraser struct {
+	repo    repository.OfficeRepository
+	manager *crm.Manager
+}
+
+func (e *crmEraser) DeleteLead(ctx context.Context, tenantID, provider, leadID string) error {
+	office, err := e.repo.GetOfficeByTenantID(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+
+	var workflowConfig models.WorkflowConfig
+	if err := json.Unmarshal([]byte(office.WorkflowConfig), &workflowConfig); err != nil {
+		return fmt.Errorf("failed to parse workflow config: %w", err)
+	}
+	if workflowConfig.CRMIntegration.Provider != provider || workflowConfig.CRMIntegration.APIKey == "" {
+		return fmt.Errorf("no %s credentials configured for tenant", provider)
+	}
+
+	return e.manager.DeleteLead(ctx, provider, leadID, &crm.Config{
+		Provider:     provider,
+		APIKey:       workflowConfig.CRMIntegration.APIKey,
+		FieldMapping: workflowConfig.CRMIntegration.FieldMapping,
 	})
 }
 
@@ -277,32 +881,199 @@ func (s *AudioService) processAudioTranscription(ctx context.Context, req *Audio
 
 	// Update transcription status to processing
 	if req.RecordingID != "" {
-		if err := s.spannerRepo.UpdateCallRecordingStatus(ctx, req.RecordingID, "processing"); err != nil {
+		if err := s.repo.UpdateCallRecordingStatus(ctx, req.TenantID, req.RecordingID, "processing"); err != nil {
 			log.Printf("Failed to update recording status to processing: %v", err)
 		}
 	}
 
-	// Transcribe audio using AI service
-	transcription, err := s.aiService.TranscribeAudio(ctx, req.StorageURL)
-	if err != nil {
-		// Update status to failed
+	// Screen the call before spending on transcription
+	phoneConfig := s.loadPhoneProcessingConfig(ctx, req.TenantID)
+	recording := s.loadRecording(ctx, req)
+	classification := s.classifyRecording(req, recording.pcm)
+	fingerprint := s.checkFingerprint(ctx, req, recording.pcm)
+	decision := audio.Decide(classification, phoneConfig.CallScreening)
+
+	if !decision.Transcribe {
+		log.Printf("Skipping transcription for call %s: classified as %s", req.CallID, classification.Class)
 		if req.RecordingID != "" {
-			s.spannerRepo.UpdateCallRecordingStatus(ctx, req.RecordingID, "failed")
+			if err := s.repo.UpdateCallRecordingStatus(ctx, req.TenantID, req.RecordingID, "skipped"); err != nil {
+				log.Printf("Failed to update recording status to skipped: %v", err)
+			}
+		}
+		if req.RequestID != "" {
+			if err := s.repo.UpdateRequestClassification(ctx, req.TenantID, req.RequestID, string(classification.Class), "screened"); err != nil {
+				log.Printf("Failed to record call classification: %v", err)
+			}
+		}
+		// Screened-out calls still leave a lead so the caller can be called back
+		if err := s.createVoicemailLead(ctx, req, classification, nil); err != nil {
+			log.Printf("Failed to create voicemail lead: %v", err)
+		}
+		if err := s.publishTranscriptionCompletedEvent(ctx, req, nil, classification, fingerprint, decision.AnalysisMode); err != nil {
+			log.Printf("Failed to publish transcription completed event: %v", err)
+		}
+
+		return &AudioProcessingResponse{
+			Status:         "skipped",
+			RecordingID:    req.RecordingID,
+			Classification: classification,
+			Fingerprint:    fingerprint,
+			AnalysisMode:   decision.AnalysisMode,
+		}, nil
+	}
+
+	// Transcribe audio, resuming an in-flight job for this call if there is one
+	transcription, err := s.transcribe(ctx, req, phoneConfig.Languages, classification, recording)
+	if err != nil {
+		// A job taken over by another instance is finished there
+		if req.RecordingID != "" && !errors.Is(err, audio.ErrJobClaimLost) {
+			s.repo.UpdateCallRecordingStatus(ctx, req.TenantID, req.RecordingID, "failed")
 		}
 		return nil, fmt.Errorf("transcription failed: %w", err)
 	}
 
+	return s.completeTranscription(ctx, req, transcription, classification, fingerprint, phoneConfig, recording)
+}
+
+// transcribe runs the recording through Speech-to-Text under a persisted transcription job
+func (s *AudioService) transcribe(ctx context.Context, req *AudioProcessingRequest, languages []string, classification *audio.CallClassification, recording *recordingAudio) (*models.TranscriptionResult, error) {
+	priority := s.transcriptionPriority(ctx, req)
+
+	// Poor transcripts are re-transcribed with an alternate model and the better result kept
+	response, err := s.transcriber.TranscribeWithQuality(ctx, s.jobManager, &audio.TranscriptionRequest{
+		AudioURI:     req.StorageURL,
+		AudioContent: s.inlineAudio(recording),
+		Encrypted:    s.storageService.Encrypted(),
+		// Long recordings, and encrypted ones too big to send inline, are transcribed in chunks
+		// from the decoded audio
+		PCM: recording.pcm,
+		CallID:   req.CallID,
+		TenantID: req.TenantID,
+		Priority: priority,
+		// Tenants serving Spanish-speaking callers list both languages for automatic identification
+		Languages: languages,
+		// Silent and very short recordings aren't worth re-transcribing when the transcript is poor
+		AudioDuration: time.Duration(classification.TotalDuration * float64(time.Second)),
+		NoSpeech:      classification.Class == models.CallClassNoSpeech,
+		// Kept on the job so a restarted service can finish the pipeline; caller details are
+		// reloaded from the request instead so job records hold no PII
+		Metadata: jobMetadata(req),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Result, nil
+}
+
+// transcriptionPriority returns the priority a call asks for or, when it asks for none, urgent for
+// a hot lead so it is transcribed ahead of backfill
+func (s *AudioService) transcriptionPriority(ctx context.Context, req *AudioProcessingRequest) audio.TranscriptionPriority {
+	if req.Priority != "" {
+		return audio.TranscriptionPriority(req.Priority)
+	}
+	if req.RequestID == "" {
+		return audio.PriorityNormal
+	}
+
+	request, err := s.repo.GetRequest(ctx, req.TenantID, req.RequestID)
+	if err != nil {
+		log.Printf("Failed to load request %s to prioritize transcription: %v", req.RequestID, err)
+		return audio.PriorityNormal
+	}
+	var call models.CallDetails
+	if err := json.Unmarshal([]byte(request.Data), &call); err == nil && call.HotLead() {
+		return audio.PriorityUrgent
+	}
+	return audio.PriorityNormal
+}
+
+// inlineAudio returns the decrypted recording to send Speech-to-Text inline. Speech-to-Text
+// can't read encrypted recordings from storage; others are read from storage and nil is returned.
+func (s *AudioService) inlineAudio(recording *recordingAudio) []byte {
+	if !s.storageService.Encrypted() {
+		return nil
+	}
+	return recording.data
+}
+
+// restoreAudio restores the audio of a resumed transcription, which isn't stored on the job, so
+// quality retries can send it again
+func (s *AudioService) restoreAudio(req *AudioProcessingRequest, transcription *audio.TranscriptionRequest, recording *recordingAudio) {
+	transcription.PCM = recording.pcm
+	if !transcription.Encrypted && !s.storageService.Encrypted() {
+		return
+	}
+
+	if recording.data == nil {
+		log.Printf("Failed to reload recording of call %s for transcription retries: %v", req.CallID, recording.err)
+		return
+	}
+	transcription.Encrypted = true
+	transcription.AudioContent = recording.data
+}
+
+// jobMetadata is what a restarted service needs to finish the pipeline for a transcription job.
+// Caller details are reloaded from the request instead so job records hold no PII.
+func jobMetadata(req *AudioProcessingRequest) map[string]string {
+	metadata := map[string]string{
+		"recording_id": req.RecordingID,
+		"request_id":   req.RequestID,
+	}
+	if req.Answered != nil {
+		metadata["answered"] = strconv.FormatBool(*req.Answered)
+	}
+	if req.CallDuration > 0 {
+		metadata["call_duration"] = strconv.Itoa(req.CallDuration)
+	}
+	return metadata
+}
+
+// resumedRequest rebuilds the processing request of a transcription job picked up after a restart
+func resumedRequest(job *audio.TranscriptionJob) *AudioProcessingRequest {
+	req := &AudioProcessingRequest{
+		TenantID:    job.Request.TenantID,
+		CallID:      job.Request.CallID,
+		StorageURL:  job.Request.AudioURI,
+		RecordingID: job.Request.Metadata["recording_id"],
+		RequestID:   job.Request.Metadata["request_id"],
+	}
+	if answered, err := strconv.ParseBool(job.Request.Metadata["answered"]); err == nil {
+		req.Answered = &answered
+	}
+	if duration, err := strconv.Atoi(job.Request.Metadata["call_duration"]); err == nil {
+		req.CallDuration = duration
+	}
+	return req
+}
+
+// completeTranscription stores a finished transcription and hands the call on to analysis
+func (s *AudioService) completeTranscription(ctx context.Context, req *AudioProcessingRequest, transcription *models.TranscriptionResult, classification *audio.CallClassification, fingerprint *models.FingerprintMatch, phoneConfig models.PhoneProcessingConfig, recording *recordingAudio) (*AudioProcessingResponse, error) {
+	// Remove PII before the transcript is stored, logged or sent on to Gemini and CRMs
+	transcription, err := s.redactTranscription(ctx, req, transcription, phoneConfig.Redaction, recording)
+	if err != nil {
+		if req.RecordingID != "" {
+			s.repo.UpdateCallRecordingStatus(ctx, req.TenantID, req.RecordingID, "failed")
+		}
+		return nil, fmt.Errorf("redaction failed: %w", err)
+	}
+
+	// Only the redacted transcript is kept on the call's jobs for redeliveries
+	if err := s.jobManager.StoreResult(ctx, req.TenantID, req.CallID, transcription); err != nil {
+		log.Printf("Failed to store transcription result on jobs for call %s: %v", req.CallID, err)
+	}
+
 	// Serialize transcription result
 	transcriptionJSON, _ := json.Marshal(transcription)
 
 	// Update transcription status to completed
 	if req.RecordingID != "" {
-		if err := s.spannerRepo.UpdateCallRecordingStatus(ctx, req.RecordingID, "completed"); err != nil {
+		if err := s.repo.UpdateCallRecordingStatus(ctx, req.TenantID, req.RecordingID, "completed"); err != nil {
 			log.Printf("Failed to update recording status to completed: %v", err)
 		}
 
 		// Store transcription completion time
-		if err := s.spannerRepo.UpdateCallRecordingTranscription(ctx, req.RecordingID, string(transcriptionJSON)); err != nil {
+		if err := s.repo.UpdateCallRecordingTranscription(ctx, req.TenantID, req.RecordingID, string(transcriptionJSON)); err != nil {
 			log.Printf("Failed to update transcription completion time: %v", err)
 		}
 	}
@@ -319,13 +1090,29 @@ func (s *AudioService) processAudioTranscription(ctx context.Context, req *Audio
 		UpdatedAt:      time.Now().UTC(),
 	}
 
-	if err := s.spannerRepo.CreateAIProcessingLog(ctx, processingLog); err != nil {
+	if err := s.repo.CreateAIProcessingLog(ctx, processingLog); err != nil {
 		log.Printf("Failed to create AI processing log: %v", err)
 		// Continue processing even if logging fails
 	}
 
+	// Refine the classification with what was actually said
+	classification = s.classifier.ClassifyTranscript(classification, transcription.Transcript, transcription.SpeakerCount)
+	decision := audio.Decide(classification, phoneConfig.CallScreening)
+
+	if req.RequestID != "" {
+		if err := s.repo.UpdateRequestClassification(ctx, req.TenantID, req.RequestID, string(classification.Class), "transcribed"); err != nil {
+			log.Printf("Failed to record call classification: %v", err)
+		}
+	}
+
+	if classification.Class == models.CallClassVoicemail || classification.Class == models.CallClassIVROnly {
+		if err := s.createVoicemailLead(ctx, req, classification, transcription); err != nil {
+			log.Printf("Failed to create voicemail lead: %v", err)
+		}
+	}
+
 	// Publish transcription completed event
-	if err := s.publishTranscriptionCompletedEvent(ctx, req, transcription); err != nil {
+	if err := s.publishTranscriptionCompletedEvent(ctx, req, transcription, classification, fingerprint, decision.AnalysisMode); err != nil {
 		log.Printf("Failed to publish transcription completed event: %v", err)
 		// Continue processing even if event publishing fails
 	}
@@ -335,20 +1122,180 @@ func (s *AudioService) processAudioTranscription(ctx context.Context, req *Audio
 		RecordingID:     req.RecordingID,
 		TranscriptionID: processingLog.LogID,
 		Transcription:   transcription,
+		Classification:  classification,
+		Fingerprint:     fingerprint,
+		AnalysisMode:    decision.AnalysisMode,
 	}, nil
 }
 
-func (s *AudioService) publishTranscriptionCompletedEvent(ctx context.Context, req *AudioProcessingRequest, transcription *models.TranscriptionResult) error {
+// loadPhoneProcessingConfig returns the tenant's phone processing policy, or a disabled policy if unavailable
+func (s *AudioService) loadPhoneProcessingConfig(ctx context.Context, tenantID string) models.PhoneProcessingConfig {
+	office, err := s.repo.GetOfficeByTenantID(ctx, tenantID)
+	if err != nil {
+		log.Printf("Failed to load tenant configuration for phone processing: %v", err)
+		return models.PhoneProcessingConfig{}
+	}
+
+	var workflowConfig models.WorkflowConfig
+	if err := json.Unmarshal([]byte(office.WorkflowConfig), &workflowConfig); err != nil {
+		log.Printf("Failed to parse workflow config for phone processing: %v", err)
+		return models.PhoneProcessingConfig{}
+	}
+
+	return workflowConfig.CommunicationDetection.PhoneProcessing
+}
+
+// redactTranscription applies the tenant's redaction policy to a transcription and, when enabled,
+// stores a copy of the recording with the redacted words bleeped
+func (s *AudioService) redactTranscription(ctx context.Context, req *AudioProcessingRequest, transcription *models.TranscriptionResult, policy models.RedactionConfig, recording *recordingAudio) (*models.TranscriptionResult, error) {
+	if !policy.Enabled || transcription == nil {
+		return transcription, nil
+	}
+
+	// No entity recognizer is configured yet, so names are only caught by the address and email patterns
+	redactor := audio.NewRedactor(policy, nil)
+	result, err := redactor.RedactTranscription(ctx, transcription)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only counts are logged; the matched text must never reach the logs
+	if len(result.Entities) > 0 {
+		log.Printf("Redacted PII from call %s: %v", req.CallID, result.Counts())
+	}
+
+	// Playback switches to the redacted copy once it's stored, so the call fails rather than
+	// leave only the unredacted original to serve
+	if policy.RedactAudio && len(result.AudioRanges) > 0 {
+		if err := s.storeRedactedAudio(ctx, req, recording, result.AudioRanges); err != nil {
+			return nil, fmt.Errorf("failed to store redacted audio: %w", err)
+		}
+	}
+
+	return result.Transcription, nil
+}
+
+// storeRedactedAudio bleeps the given ranges in the recording and stores the redacted copy as WAV.
+// The original recording is kept but only the redacted copy is served for playback.
+func (s *AudioService) storeRedactedAudio(ctx context.Context, req *AudioProcessingRequest, recording *recordingAudio, ranges []audio.TimeRange) error {
+	if recording.pcm == nil {
+		return recording.err
+	}
+
+	bleeped := audio.BleepAudio(recording.pcm, ranges, 150*time.Millisecond)
+	if _, err := s.storageService.StoreRedactedAudioFile(ctx, req.TenantID, req.CallID, audio.EncodeWAV(bleeped)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// recordingAudio is a call's recording, downloaded, decrypted and decoded once for every step of
+// the pipeline
+type recordingAudio struct {
+	data []byte          // the recording as stored, decrypted; nil if it couldn't be loaded
+	pcm  *audio.PCMAudio // nil if it couldn't be loaded or decoded locally
+	err  error           // why data or pcm is missing
+}
+
+// loadRecording loads and decodes the stored recording. Failures are logged and leave the audio
+// missing: screening falls back to call metadata, and steps that need the audio report err.
+func (s *AudioService) loadRecording(ctx context.Context, req *AudioProcessingRequest) *recordingAudio {
+	data, err := s.storageService.GetAudioFile(ctx, req.TenantID, req.CallID)
+	if err != nil {
+		log.Printf("Failed to load recording for call %s: %v", req.CallID, err)
+		return &recordingAudio{err: fmt.Errorf("failed to load recording: %w", err)}
+	}
+	pcm, err := s.decoder.Decode(ctx, data)
+	if err != nil {
+		log.Printf("Failed to decode recording for call %s: %v", req.CallID, err)
+		return &recordingAudio{data: data, err: fmt.Errorf("failed to decode recording: %w", err)}
+	}
+	return &recordingAudio{data: data, pcm: pcm}
+}
+
+// classifyRecording classifies the recording from its audio when it could be decoded locally,
+// falling back to CallRail metadata otherwise
+func (s *AudioService) classifyRecording(req *AudioProcessingRequest, pcm *audio.PCMAudio) *audio.CallClassification {
+	return s.classifier.ClassifyAudio(pcm, req.Answered, time.Duration(req.CallDuration)*time.Second)
+}
+
+// checkFingerprint fingerprints the recording, stores it and checks it against the tenant's recent
+// recordings and known robocalls. It returns nil when the audio could not be fingerprinted.
+func (s *AudioService) checkFingerprint(ctx context.Context, req *AudioProcessingRequest, pcm *audio.PCMAudio) *models.FingerprintMatch {
+	if pcm == nil || req.RecordingID == "" {
+		return nil
+	}
+
+	fp := s.fingerprints.Fingerprint(pcm, req.TenantID, req.RecordingID, req.CallID)
+	if fp == nil {
+		return nil
+	}
+
+	match, err := s.fingerprints.Check(ctx, fp)
+	if err != nil {
+		log.Printf("Failed to check fingerprint for call %s: %v", req.CallID, err)
+	}
+	if err := s.fingerprints.Register(ctx, fp); err != nil {
+		log.Printf("Failed to store fingerprint for call %s: %v", req.CallID, err)
+	}
+
+	if match != nil && match.Duplicate {
+		log.Printf("Call %s duplicates call %s (similarity %.2f, exact %t)", req.CallID, match.DuplicateCallID, match.DuplicateSimilarity, match.Exact)
+	}
+	if match != nil && match.Robocall {
+		log.Printf("Call %s matches known robocall %q (similarity %.2f)", req.CallID, match.RobocallLabel, match.RobocallSimilarity)
+	}
+	return match
+}
+
+// createVoicemailLead stores the short lead record kept for voicemails, IVR-only calls and calls
+// screened out before transcription, which have no transcription
+func (s *AudioService) createVoicemailLead(ctx context.Context, req *AudioProcessingRequest, classification *audio.CallClassification, transcription *models.TranscriptionResult) error {
+	var transcript string
+	if transcription != nil {
+		transcript = transcription.Transcript
+	}
+
+	lead := audio.BuildVoicemailLead(classification, transcript, req.CustomerName, req.CustomerPhone)
+	lead.RequestID = req.RequestID
+	lead.CallID = req.CallID
+
+	leadJSON, err := json.Marshal(lead)
+	if err != nil {
+		return fmt.Errorf("failed to marshal voicemail lead: %w", err)
+	}
+
+	return s.repo.CreateAIProcessingLog(ctx, &models.AIProcessingLog{
+		LogID:          models.NewProcessingID(),
+		TenantID:       req.TenantID,
+		RequestID:      req.RequestID,
+		AnalysisType:   "voicemail_lead",
+		Status:         "completed",
+		ProcessingData: string(leadJSON),
+		CreatedAt:      time.Now().UTC(),
+		UpdatedAt:      time.Now().UTC(),
+	})
+}
+
+func (s *AudioService) publishTranscriptionCompletedEvent(ctx context.Context, req *AudioProcessingRequest, transcription *models.TranscriptionResult, classification *audio.CallClassification, fingerprint *models.FingerprintMatch, analysisMode models.AnalysisMode) error {
+	if s.pubsubClient == nil {
+		return nil // events are only published when Pub/Sub is configured
+	}
+
 	topic := s.pubsubClient.Topic("transcription-completed")
 
 	event := map[string]interface{}{
-		"event_type":    "transcription.completed",
-		"tenant_id":     req.TenantID,
-		"call_id":       req.CallID,
-		"recording_id":  req.RecordingID,
-		"request_id":    req.RequestID,
-		"transcription": transcription,
-		"timestamp":     time.Now().Unix(),
+		"event_type":     "transcription.completed",
+		"tenant_id":      req.TenantID,
+		"call_id":        req.CallID,
+		"recording_id":   req.RecordingID,
+		"request_id":     req.RequestID,
+		"transcription":  transcription,
+		"language":       transcriptionLanguage(transcription),
+		"classification": classification,
+		"fingerprint":    fingerprint,
+		"analysis_mode":  analysisMode,
+		"timestamp":      time.Now().Unix(),
 	}
 
 	data, err := json.Marshal(event)
@@ -362,6 +1309,7 @@ func (s *AudioService) publishTranscriptionCompletedEvent(ctx context.Context, r
 			"event_type": "transcription.completed",
 			"tenant_id":  req.TenantID,
 			"call_id":    req.CallID,
+			"call_class": string(classification.Class),
 		},
 	})
 
@@ -369,6 +1317,72 @@ func (s *AudioService) publishTranscriptionCompletedEvent(ctx context.Context, r
 	return err
 }
 
+// transcriptionLanguage returns the detected language of a transcription, if there is one
+func transcriptionLanguage(transcription *models.TranscriptionResult) string {
+	if transcription == nil {
+		return ""
+	}
+	return transcription.Language
+}
+
+// restoreCaller fills in the caller details of a resumed transcription from its stored request
+func (s *AudioService) restoreCaller(ctx context.Context, req *AudioProcessingRequest) {
+	if req.RequestID == "" {
+		return
+	}
+
+	request, err := s.repo.GetRequest(ctx, req.TenantID, req.RequestID)
+	if err != nil {
+		log.Printf("Failed to load request %s for resumed transcription: %v", req.RequestID, err)
+		return
+	}
+
+	var call models.CallDetails
+	if err := json.Unmarshal([]byte(request.Data), &call); err != nil {
+		log.Printf("Failed to parse call details of request %s: %v", req.RequestID, err)
+		return
+	}
+	req.CustomerName = call.CustomerName
+	req.CustomerPhone = call.CustomerPhoneNumber
+}
+
+// resumeTranscriptionJobs picks up Speech-to-Text operations left running by a previous instance
+func (s *AudioService) resumeTranscriptionJobs(ctx context.Context) {
+	resumed, err := s.transcriber.ResumeJobs(ctx, s.jobManager, func(job *audio.TranscriptionJob, response *audio.TranscriptionResponse, err error) {
+		req := resumedRequest(job)
+		s.restoreCaller(ctx, req)
+
+		if err != nil {
+			log.Printf("Resumed transcription job %s failed: %v", job.JobID, err)
+			if req.RecordingID != "" && ctx.Err() == nil && !errors.Is(err, audio.ErrJobClaimLost) {
+				s.repo.UpdateCallRecordingStatus(ctx, req.TenantID, req.RecordingID, "failed")
+			}
+			return
+		}
+
+		recording := s.loadRecording(ctx, req)
+		if response.Request != nil {
+			s.restoreAudio(req, response.Request, recording)
+		}
+		response = s.transcriber.ImproveTranscription(ctx, s.jobManager, response)
+
+		phoneConfig := s.loadPhoneProcessingConfig(ctx, req.TenantID)
+		classification := s.classifyRecording(req, recording.pcm)
+		fingerprint := s.checkFingerprint(ctx, req, recording.pcm)
+		if _, err := s.completeTranscription(ctx, req, response.Result, classification, fingerprint, phoneConfig, recording); err != nil {
+			log.Printf("Failed to complete resumed transcription job %s: %v", job.JobID, err)
+		}
+	})
+	if err != nil {
+		log.Printf("Failed to resume transcription jobs: %v", err)
+		return
+	}
+
+	if resumed > 0 {
+		log.Printf("Resumed %d in-flight transcription jobs", resumed)
+	}
+}
+
 func (s *AudioService) startPubSubListener(ctx context.Context) {
 	sub := s.pubsubClient.Subscription("audio-processing-requests")
 
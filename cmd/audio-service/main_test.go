@@ -0,0 +1,456 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/internal/auth"
+	"github.com/home-renovators/ingestion-pipeline/internal/encryption"
+	"github.com/home-renovators/ingestion-pipeline/internal/repository"
+	"github.com/home-renovators/ingestion-pipeline/internal/storage"
+	"github.com/home-renovators/ingestion-pipeline/pkg/audio"
+	"github.com/home-renovators/ingestion-pipeline/pkg/config"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+	"github.com/home-renovators/ingestion-pipeline/pkg/privacy"
+	"github.com/home-renovators/ingestion-pipeline/pkg/retention"
+)
+
+// newTestAudioService wires the audio service to an in-memory store and local blob storage
+func newTestAudioService(t *testing.T) (*AudioService, *repository.MemoryStore, *gin.Engine) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	store := repository.NewMemoryStore()
+	blobs, err := storage.NewLocalStore(t.TempDir(), "http://localhost/blobs", "test-signing-key")
+	require.NoError(t, err)
+	storageService := storage.NewServiceWithStore(blobs)
+
+	cfg := config.DefaultConfig()
+	s := &AudioService{
+		config:         cfg,
+		authService:    auth.NewAuthService(cfg, store),
+		repo:           store,
+		storageService: storageService,
+		jobManager:     audio.NewJobManager(store),
+		classifier:     audio.NewCallClassifier(nil),
+		decoder:        audio.NewDecoder(nil),
+		fingerprints:   audio.NewFingerprintMatcher(store, nil),
+		purger:         retention.NewPurger(store, storageService, retention.DefaultConfig()),
+		dataSubjects:   privacy.NewService(store, storageService, &crmEraser{repo: store}),
+	}
+
+	router := gin.New()
+	s.setupRoutes(router)
+	return s, store, router
+}
+
+// decodeMP3As makes the service decode every compressed recording as pcm, through a script
+// standing in for ffmpeg
+func decodeMP3As(t *testing.T, s *AudioService, pcm *audio.PCMAudio) {
+	t.Helper()
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "decoded.raw")
+	require.NoError(t, os.WriteFile(raw, pcm.LinearBytes(), 0o644))
+	script := filepath.Join(dir, "ffmpeg")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\ncat > /dev/null\ncat "+raw+"\n"), 0o755))
+	s.decoder = audio.NewDecoder(&audio.DecoderConfig{FFmpegPath: script, SampleRate: pcm.SampleRate})
+}
+
+// sweep is a few seconds of changing tones, enough audio to fingerprint
+func sweep(d time.Duration) *audio.PCMAudio {
+	samples := make([]int16, int(d.Seconds()*8000))
+	for i := range samples {
+		freq := 400 + float64(i/1600%8)*250
+		samples[i] = int16(8000 * math.Sin(2*math.Pi*freq*float64(i)/8000))
+	}
+	return &audio.PCMAudio{Samples: samples, SampleRate: 8000}
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestTranscriptionStatusIsScopedToTenant(t *testing.T) {
+	_, store, router := newTestAudioService(t)
+	require.NoError(t, store.CreateCallRecording(context.Background(), &models.CallRecording{
+		RecordingID:         "rec_1",
+		TenantID:            "tenant_a",
+		CallID:              "CAL1",
+		StorageURL:          "gs://bucket/tenant_a/CAL1.mp3",
+		TranscriptionStatus: "completed",
+		CreatedAt:           time.Now().UTC(),
+	}))
+
+	w := serve(router, http.MethodGet, "/api/v1/audio/status/rec_1?tenant_id=tenant_a", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var status map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, "completed", status["transcription_status"])
+
+	w = serve(router, http.MethodGet, "/api/v1/audio/status/rec_1?tenant_id=tenant_b", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestLegalHoldLifecycle(t *testing.T) {
+	_, _, router := newTestAudioService(t)
+
+	w := serve(router, http.MethodPost, "/api/v1/tenants/tenant_a/legal-holds", `{"call_id":"CAL1","reason":"litigation"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var hold models.LegalHold
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &hold))
+
+	w = serve(router, http.MethodGet, "/api/v1/tenants/tenant_a/legal-holds", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), hold.HoldID)
+
+	w = serve(router, http.MethodDelete, "/api/v1/tenants/tenant_b/legal-holds/"+hold.HoldID, "")
+	assert.Equal(t, http.StatusNotFound, w.Code, "another tenant can't release the hold")
+
+	w = serve(router, http.MethodDelete, "/api/v1/tenants/tenant_a/legal-holds/"+hold.HoldID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(router, http.MethodDelete, "/api/v1/tenants/tenant_a/legal-holds/"+hold.HoldID, "")
+	assert.Equal(t, http.StatusNotFound, w.Code, "a released hold can't be released again")
+}
+
+func TestDataSubjectSearchIsScopedToTenant(t *testing.T) {
+	_, store, router := newTestAudioService(t)
+	callID := "CAL1"
+	require.NoError(t, store.CreateRequest(context.Background(), &models.Request{
+		RequestID:    "req_1",
+		TenantID:     "tenant_a",
+		Source:       "callrail",
+		RequestType:  "call",
+		Status:       "processed",
+		Data:         `{"customer_phone_number":"+1 (555) 123-4567"}`,
+		AINormalized: "{}",
+		AIExtracted:  "{}",
+		CallID:       &callID,
+		CreatedAt:    time.Now().UTC(),
+		UpdatedAt:    time.Now().UTC(),
+	}))
+
+	w := serve(router, http.MethodPost, "/api/v1/tenants/tenant_a/data-subjects/search", `{"phone":"555-123-4567"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var found struct {
+		Counts  models.DataSubjectCounts `json:"counts"`
+		CallIDs []string                 `json:"call_ids"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &found))
+	assert.Equal(t, 1, found.Counts.Requests)
+	assert.Equal(t, []string{"CAL1"}, found.CallIDs)
+
+	w = serve(router, http.MethodPost, "/api/v1/tenants/tenant_b/data-subjects/search", `{"phone":"555-123-4567"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "CAL1")
+}
+
+func TestResumedTranscriptionRestoresCallerFromRequest(t *testing.T) {
+	s, store, _ := newTestAudioService(t)
+	require.NoError(t, store.CreateRequest(context.Background(), &models.Request{
+		RequestID:    "req_1",
+		TenantID:     "tenant_a",
+		Source:       "callrail",
+		RequestType:  "call",
+		Status:       "processed",
+		Data:         `{"customer_name":"Dana Whitfield","customer_phone_number":"+15125550142"}`,
+		AINormalized: "{}",
+		AIExtracted:  "{}",
+		CreatedAt:    time.Now().UTC(),
+		UpdatedAt:    time.Now().UTC(),
+	}))
+
+	req := &AudioProcessingRequest{TenantID: "tenant_a", RequestID: "req_1"}
+	s.restoreCaller(context.Background(), req)
+	assert.Equal(t, "Dana Whitfield", req.CustomerName)
+	assert.Equal(t, "+15125550142", req.CustomerPhone)
+
+	other := &AudioProcessingRequest{TenantID: "tenant_b", RequestID: "req_1"}
+	s.restoreCaller(context.Background(), other)
+	assert.Empty(t, other.CustomerName, "another tenant's request is never read")
+}
+
+func TestHotLeadsAreTranscribedUrgently(t *testing.T) {
+	ctx := context.Background()
+	s, store, _ := newTestAudioService(t)
+	for id, data := range map[string]string{
+		"req_new":       `{"first_call":true,"answered":true}`,
+		"req_returning": `{"first_call":false,"answered":true}`,
+		"req_missed":    `{"first_call":true,"answered":false}`,
+	} {
+		require.NoError(t, store.CreateRequest(ctx, &models.Request{
+			RequestID: id, TenantID: "tenant_a", Source: "callrail", RequestType: "call", Status: "processed",
+			Data: data, AINormalized: "{}", AIExtracted: "{}", CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC(),
+		}))
+	}
+
+	assert.Equal(t, audio.PriorityUrgent, s.transcriptionPriority(ctx, &AudioProcessingRequest{TenantID: "tenant_a", RequestID: "req_new"}))
+	assert.Equal(t, audio.PriorityNormal, s.transcriptionPriority(ctx, &AudioProcessingRequest{TenantID: "tenant_a", RequestID: "req_returning"}))
+	assert.Equal(t, audio.PriorityNormal, s.transcriptionPriority(ctx, &AudioProcessingRequest{TenantID: "tenant_a", RequestID: "req_missed"}))
+	assert.Equal(t, audio.PriorityNormal, s.transcriptionPriority(ctx, &AudioProcessingRequest{TenantID: "tenant_b", RequestID: "req_new"}))
+	// A requested priority wins, so a backfill of old first calls stays low
+	assert.Equal(t, audio.PriorityLow, s.transcriptionPriority(ctx, &AudioProcessingRequest{TenantID: "tenant_a", RequestID: "req_new", Priority: "low"}))
+}
+
+func TestScreenedOutCallLeavesVoicemailLead(t *testing.T) {
+	s, store, _ := newTestAudioService(t)
+	ctx := context.Background()
+	store.PutOffice(&models.Office{
+		TenantID:       "tenant_a",
+		OfficeID:       "office_1",
+		Status:         "active",
+		WorkflowConfig: `{"communication_detection":{"phone_processing":{"call_screening":{"enabled":true,"skip_transcription_for_no_speech":true}}}}`,
+	})
+	silence := &audio.PCMAudio{Samples: make([]int16, 16000*8), SampleRate: 16000}
+	_, err := s.storageService.StoreAudioFile(ctx, "tenant_a", "CAL1", audio.EncodeWAV(silence))
+	require.NoError(t, err)
+
+	answered := false
+	response, err := s.processAudioTranscription(ctx, &AudioProcessingRequest{
+		TenantID:      "tenant_a",
+		CallID:        "CAL1",
+		RequestID:     "req_1",
+		Answered:      &answered,
+		CallDuration:  8,
+		CustomerPhone: "+15125550142",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "skipped", response.Status)
+	assert.Equal(t, models.CallClassNoSpeech, response.Classification.Class)
+
+	logs, err := store.ListAIProcessingLogsByRequest(ctx, "tenant_a", "req_1")
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "voicemail_lead", logs[0].AnalysisType)
+	var lead models.VoicemailLead
+	require.NoError(t, json.Unmarshal([]byte(logs[0].ProcessingData), &lead))
+	assert.Equal(t, "+15125550142", lead.CallerPhone)
+	assert.Equal(t, models.CallClassNoSpeech, lead.Classification)
+	assert.Empty(t, lead.Message)
+}
+
+func TestResumedTranscriptionKeepsCallMetadata(t *testing.T) {
+	answered := false
+	job := &audio.TranscriptionJob{Request: &audio.TranscriptionRequest{
+		TenantID: "tenant_a",
+		CallID:   "CAL1",
+		AudioURI: "gs://bucket/tenant_a/calls/CAL1.mp3",
+		Metadata: jobMetadata(&AudioProcessingRequest{RecordingID: "rec_1", RequestID: "req_1", Answered: &answered, CallDuration: 42}),
+	}}
+
+	req := resumedRequest(job)
+	assert.Equal(t, "rec_1", req.RecordingID)
+	assert.Equal(t, "req_1", req.RequestID)
+	require.NotNil(t, req.Answered)
+	assert.False(t, *req.Answered, "an unanswered call is still screened as one after a restart")
+	assert.Equal(t, 42, req.CallDuration)
+
+	job.Request.Metadata = jobMetadata(&AudioProcessingRequest{RecordingID: "rec_2"})
+	req = resumedRequest(job)
+	assert.Nil(t, req.Answered, "calls without CallRail metadata stay unknown")
+	assert.Zero(t, req.CallDuration)
+}
+
+func TestMP3RecordingIsFingerprintedAndReportableByItsTenant(t *testing.T) {
+	s, store, router := newTestAudioService(t)
+	ctx := context.Background()
+	decodeMP3As(t, s, sweep(6*time.Second))
+	_, err := s.storageService.StoreAudioFile(ctx, "tenant_a", "CAL1", []byte("ID3\x04\x00\x00\x00\x00\x00\x00\xff\xfb"))
+	require.NoError(t, err)
+
+	req := &AudioProcessingRequest{TenantID: "tenant_a", CallID: "CAL1", RecordingID: "rec_1"}
+	s.checkFingerprint(ctx, req, s.loadRecording(ctx, req).pcm)
+	fp, err := store.GetRecordingFingerprint(ctx, "rec_1")
+	require.NoError(t, err)
+	assert.Equal(t, "tenant_a", fp.TenantID)
+
+	w := serve(router, http.MethodPost, "/api/v1/audio/robocalls/rec_1", `{"label":"extended warranty"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serve(router, http.MethodPost, "/api/v1/audio/robocalls/rec_1?tenant_id=tenant_b", `{"label":"extended warranty"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code, "another tenant can't report the recording")
+	w = serve(router, http.MethodPost, "/api/v1/audio/robocalls/rec_1?tenant_id=tenant_a", `{"label":"extended warranty"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	robocalls, err := store.ListRobocallFingerprints(ctx)
+	require.NoError(t, err)
+	require.Len(t, robocalls, 1)
+	assert.Equal(t, "rec_1", robocalls[0].SourceRecordingID)
+}
+
+// countingBlobs counts the recordings read from a blob store
+type countingBlobs struct {
+	storage.BlobStore
+
+	mu    sync.Mutex
+	reads int
+}
+
+func (c *countingBlobs) Get(ctx context.Context, key string) ([]byte, error) {
+	if strings.HasSuffix(key, ".mp3") {
+		c.mu.Lock()
+		c.reads++
+		c.mu.Unlock()
+	}
+	return c.BlobStore.Get(ctx, key)
+}
+
+func TestRecordingIsLoadedOnceForScreeningAndRedaction(t *testing.T) {
+	s, store, _ := newTestAudioService(t)
+	ctx := context.Background()
+	local, err := storage.NewLocalStore(t.TempDir(), "http://localhost/blobs", "test-signing-key")
+	require.NoError(t, err)
+	blobs := &countingBlobs{BlobStore: local}
+	s.storageService = storage.NewServiceWithStore(blobs)
+	decodeMP3As(t, s, sweep(6*time.Second))
+	_, err = s.storageService.StoreAudioFile(ctx, "tenant_a", "CAL1", []byte("ID3\x04\x00\x00\x00\x00\x00\x00\xff\xfb"))
+	require.NoError(t, err)
+
+	req := &AudioProcessingRequest{TenantID: "tenant_a", CallID: "CAL1", RecordingID: "rec_1"}
+	recording := s.loadRecording(ctx, req)
+	classification := s.classifyRecording(req, recording.pcm)
+	fingerprint := s.checkFingerprint(ctx, req, recording.pcm)
+	phoneConfig := models.PhoneProcessingConfig{Redaction: models.RedactionConfig{Enabled: true, RedactAudio: true}}
+	_, err = s.completeTranscription(ctx, req, cardNumberCall(), classification, fingerprint, phoneConfig, recording)
+	require.NoError(t, err)
+
+	_, err = store.GetRecordingFingerprint(ctx, "rec_1")
+	require.NoError(t, err)
+	_, err = s.storageService.ReadObject(ctx, "tenant_a", storage.RedactedRecordingKey("tenant_a", "CAL1"))
+	require.NoError(t, err, "the redacted copy is stored")
+	assert.Equal(t, 1, blobs.reads, "the recording is downloaded once")
+}
+
+// cardNumberCall is a transcription in which the caller reads out a card number from 1.5s on
+func cardNumberCall() *models.TranscriptionResult {
+	words := strings.Fields("my card is 4111 1111 1111 1111")
+	transcription := &models.TranscriptionResult{Transcript: strings.Join(words, " ")}
+	for i, w := range words {
+		start := time.Duration(i) * 500 * time.Millisecond
+		transcription.WordDetails = append(transcription.WordDetails, models.WordDetail{
+			Word: w, StartTime: models.Offset(start), EndTime: models.Offset(start + 400*time.Millisecond), Confidence: 0.95,
+		})
+	}
+	return transcription
+}
+
+func TestRedactedMP3RecordingIsBleepedAndServedForPlayback(t *testing.T) {
+	s, _, _ := newTestAudioService(t)
+	ctx := context.Background()
+	original := sweep(6 * time.Second)
+	decodeMP3As(t, s, original)
+	_, err := s.storageService.StoreAudioFile(ctx, "tenant_a", "CAL1", []byte("ID3\x04\x00\x00\x00\x00\x00\x00\xff\xfb"))
+	require.NoError(t, err)
+
+	req := &AudioProcessingRequest{TenantID: "tenant_a", CallID: "CAL1"}
+	redacted, err := s.redactTranscription(ctx, req, cardNumberCall(), models.RedactionConfig{Enabled: true, RedactAudio: true}, s.loadRecording(ctx, req))
+	require.NoError(t, err)
+	assert.Equal(t, "my card is [CARD_NUMBER]", redacted.Transcript)
+
+	data, err := s.storageService.ReadObject(ctx, "tenant_a", storage.RedactedRecordingKey("tenant_a", "CAL1"))
+	require.NoError(t, err)
+	bleeped, err := audio.DecodeWAV(data)
+	require.NoError(t, err)
+	require.Len(t, bleeped.Samples, len(original.Samples))
+	assert.Equal(t, original.Slice(0, time.Second).Samples, bleeped.Slice(0, time.Second).Samples, "audio before the card number is untouched")
+	assert.NotEqual(t, original.Slice(2*time.Second, 3*time.Second).Samples, bleeped.Slice(2*time.Second, 3*time.Second).Samples, "the card number is bleeped")
+
+	// The original is kept, but playback gets the redacted copy
+	_, err = s.storageService.GetAudioFile(ctx, "tenant_a", "CAL1")
+	require.NoError(t, err)
+	signed, err := s.storageService.GenerateSignedURL(ctx, "tenant_a", "CAL1", time.Minute)
+	require.NoError(t, err)
+	assert.Contains(t, signed, "/tenant_a/calls/CAL1.redacted.wav")
+}
+
+func TestTranscriptionJobKeepsOnlyRedactedResult(t *testing.T) {
+	s, _, _ := newTestAudioService(t)
+	ctx := context.Background()
+	job, err := s.jobManager.CreateJob(ctx, &audio.TranscriptionRequest{TenantID: "tenant_a", CallID: "CAL1"})
+	require.NoError(t, err)
+	require.NoError(t, s.jobManager.CompleteJob(ctx, job.JobID, &audio.TranscriptionResponse{Success: true, Result: cardNumberCall()}))
+
+	completed, err := s.jobManager.GetJob(ctx, job.JobID)
+	require.NoError(t, err)
+	assert.Nil(t, completed.Result, "nothing is kept before redaction")
+
+	req := &AudioProcessingRequest{TenantID: "tenant_a", CallID: "CAL1"}
+	phoneConfig := models.PhoneProcessingConfig{Redaction: models.RedactionConfig{Enabled: true}}
+	_, err = s.completeTranscription(ctx, req, cardNumberCall(), &audio.CallClassification{Class: models.CallClassConversation}, nil, phoneConfig, &recordingAudio{})
+	require.NoError(t, err)
+
+	completed, err = s.jobManager.GetJob(ctx, job.JobID)
+	require.NoError(t, err)
+	require.NotNil(t, completed.Result)
+	assert.Equal(t, "my card is [CARD_NUMBER]", completed.Result.Transcript)
+	for _, w := range completed.Result.WordDetails {
+		assert.NotEqual(t, "4111", w.Word)
+	}
+}
+
+func TestAudioRedactionFailsClosedWhenRecordingCantBeDecoded(t *testing.T) {
+	s, store, _ := newTestAudioService(t)
+	ctx := context.Background()
+	s.decoder = audio.NewDecoder(&audio.DecoderConfig{}) // WAV only
+	_, err := s.storageService.StoreAudioFile(ctx, "tenant_a", "CAL1", []byte("ID3\x04\x00\x00\x00\x00\x00\x00\xff\xfb"))
+	require.NoError(t, err)
+	require.NoError(t, store.CreateCallRecording(ctx, &models.CallRecording{
+		RecordingID: "rec_1", TenantID: "tenant_a", CallID: "CAL1", TranscriptionStatus: "processing", CreatedAt: time.Now().UTC(),
+	}))
+
+	req := &AudioProcessingRequest{TenantID: "tenant_a", CallID: "CAL1", RecordingID: "rec_1"}
+	phoneConfig := models.PhoneProcessingConfig{Redaction: models.RedactionConfig{Enabled: true, RedactAudio: true}}
+	_, err = s.completeTranscription(ctx, req, cardNumberCall(), &audio.CallClassification{Class: models.CallClassConversation}, nil, phoneConfig, s.loadRecording(ctx, req))
+	assert.ErrorIs(t, err, audio.ErrUnsupportedFormat)
+
+	recording, err := store.GetCallRecording(ctx, "tenant_a", "rec_1")
+	require.NoError(t, err)
+	assert.Equal(t, "failed", recording.TranscriptionStatus)
+}
+
+func TestResumedEncryptedTranscriptionReloadsAudioForRetries(t *testing.T) {
+	s, store, _ := newTestAudioService(t)
+	ctx := context.Background()
+	_, err := s.storageService.StoreAudioFile(ctx, "tenant_a", "CAL1", []byte("plain mp3"))
+	require.NoError(t, err)
+	pcm := sweep(5 * time.Second)
+
+	// Recordings Speech-to-Text can read from storage only get the decoded audio back
+	unencrypted := &audio.TranscriptionRequest{TenantID: "tenant_a", CallID: "CAL1"}
+	s.restoreAudio(&AudioProcessingRequest{TenantID: "tenant_a", CallID: "CAL1"}, unencrypted, &recordingAudio{data: []byte("plain mp3"), pcm: pcm})
+	assert.Same(t, pcm, unencrypted.PCM)
+	assert.Nil(t, unencrypted.AudioContent)
+
+	wrapper, err := encryption.NewLocalKeyWrapper(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32)))
+	require.NoError(t, err)
+	s.storageService.SetEnvelope(encryption.NewEnvelope(store, wrapper, nil))
+	_, err = s.storageService.StoreAudioFile(ctx, "tenant_a", "CAL2", []byte("secret mp3"))
+	require.NoError(t, err)
+
+	// A job read back from the store has lost its audio, which is never persisted
+	encrypted := &audio.TranscriptionRequest{TenantID: "tenant_a", CallID: "CAL2", Encrypted: true}
+	req := &AudioProcessingRequest{TenantID: "tenant_a", CallID: "CAL2"}
+	recording := s.loadRecording(ctx, req)
+	recording.pcm = pcm
+	s.restoreAudio(req, encrypted, recording)
+	assert.Equal(t, []byte("secret mp3"), encrypted.AudioContent)
+	assert.Same(t, pcm, encrypted.PCM)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -15,15 +16,20 @@ import (
 	"cloud.google.com/go/pubsub"
 
 	"github.com/home-renovators/ingestion-pipeline/internal/auth"
+	"github.com/home-renovators/ingestion-pipeline/internal/encryption"
+	"github.com/home-renovators/ingestion-pipeline/internal/migrations"
+	"github.com/home-renovators/ingestion-pipeline/internal/repository"
 	"github.com/home-renovators/ingestion-pipeline/internal/spanner"
 	"github.com/home-renovators/ingestion-pipeline/pkg/config"
+	"github.com/home-renovators/ingestion-pipeline/pkg/crm"
 	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+	"github.com/home-renovators/ingestion-pipeline/pkg/spam"
 )
 
 type CRMService struct {
 	config       *config.Config
 	authService  *auth.AuthService
-	spannerRepo  *spanner.Repository
+	repo         repository.Repository
 	pubsubClient *pubsub.Client
 	crmClients   map[string]CRMClient // provider -> client
 }
@@ -57,6 +63,7 @@ type LeadData struct {
 	CustomerCity       string                 `json:"customer_city,omitempty"`
 	CustomerState      string                 `json:"customer_state,omitempty"`
 	CustomerZip        string                 `json:"customer_zip,omitempty"`
+	CallbackTime       string                 `json:"callback_time,omitempty"`
 	ProjectType        string                 `json:"project_type"`
 	ProjectDescription string                 `json:"project_description,omitempty"`
 	LeadScore          int                    `json:"lead_score"`
@@ -66,6 +73,10 @@ type LeadData struct {
 	Urgency            string                 `json:"urgency,omitempty"`
 	Timeline           string                 `json:"timeline,omitempty"`
 	BudgetIndicator    string                 `json:"budget_indicator,omitempty"`
+	Summary            string                 `json:"summary,omitempty"`
+	ActionItems        []string               `json:"action_items,omitempty"`
+	Commitments        []string               `json:"commitments,omitempty"`
+	OpenQuestions      []string               `json:"open_questions,omitempty"`
 	Notes              string                 `json:"notes,omitempty"`
 	Tags               []string               `json:"tags,omitempty"`
 	CustomFields       map[string]interface{} `json:"custom_fields,omitempty"`
@@ -162,6 +173,22 @@ func initializeServices(ctx context.Context, cfg *config.Config) (*CRMService, e
 		return nil, fmt.Errorf("failed to initialize spanner repository: %w", err)
 	}
 
+	// Refuse to run against a database that hasn't been migrated to this build's schema
+	if cfg.SchemaCheck {
+		if err := migrations.Verify(ctx, spannerRepo); err != nil {
+			return nil, fmt.Errorf("failed to verify database schema: %w", err)
+		}
+	}
+
+	// Requests are read to check their spam likelihood, so their encrypted columns must be readable
+	envelope, err := encryption.NewEnvelopeFromConfig(ctx, cfg, spannerRepo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize encryption: %w", err)
+	}
+	if envelope != nil {
+		spannerRepo.SetEnvelope(envelope)
+	}
+
 	// Initialize authentication service
 	authService := auth.NewAuthService(cfg, spannerRepo)
 
@@ -182,15 +209,15 @@ func initializeServices(ctx context.Context, cfg *config.Config) (*CRMService, e
 	return &CRMService{
 		config:       cfg,
 		authService:  authService,
-		spannerRepo:  spannerRepo,
+		repo:         spannerRepo,
 		pubsubClient: pubsubClient,
 		crmClients:   crmClients,
 	}, nil
 }
 
 func (s *CRMService) cleanup() {
-	if s.spannerRepo != nil {
-		s.spannerRepo.Close()
+	if s.repo != nil {
+		s.repo.Close()
 	}
 	if s.pubsubClient != nil {
 		s.pubsubClient.Close()
@@ -285,7 +312,7 @@ func (s *CRMService) handleGetLead(c *gin.Context) {
 	}
 
 	// Get CRM configuration for tenant
-	office, err := s.spannerRepo.GetOfficeByTenantID(ctx, tenantID)
+	office, err := s.repo.GetOfficeByTenantID(ctx, tenantID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Tenant not found"})
 		return
@@ -401,10 +428,14 @@ func (s *CRMService) handleGetIntegrationStatus(c *gin.Context) {
 	}
 
 	// Get integration status from database
-	integration, err := s.spannerRepo.GetCRMIntegration(ctx, tenantID, integrationID)
+	integration, err := s.repo.GetCRMIntegration(ctx, tenantID, integrationID)
 	if err != nil {
 		log.Printf("Failed to get integration status: %v", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Integration not found"})
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Integration not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get integration status"})
 		return
 	}
 
@@ -423,7 +454,7 @@ func (s *CRMService) processCRMIntegration(ctx context.Context, req *CRMIntegrat
 	log.Printf("Processing CRM integration for request %s, action %s, provider %s", req.RequestID, req.Action, req.CRMProvider)
 
 	// Get CRM configuration for tenant
-	office, err := s.spannerRepo.GetOfficeByTenantID(ctx, req.TenantID)
+	office, err := s.repo.GetOfficeByTenantID(ctx, req.TenantID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tenant configuration: %w", err)
 	}
@@ -433,6 +464,28 @@ func (s *CRMService) processCRMIntegration(ctx context.Context, req *CRMIntegrat
 		return nil, fmt.Errorf("invalid CRM configuration: %w", err)
 	}
 
+	request, err := s.loadRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Leads from calls at or above the tenant's spam threshold are kept out of the CRM
+	if req.Action == "create" {
+		if likelihood, suppressed := suppressedAsSpam(request, office.WorkflowConfig); suppressed {
+			log.Printf("Suppressed CRM lead for request %s: spam likelihood %.1f", req.RequestID, likelihood)
+			return &CRMIntegrationResponse{
+				Status:    "suppressed",
+				RequestID: req.RequestID,
+				Error:     fmt.Sprintf("spam likelihood %.1f is at or above the tenant's threshold", likelihood),
+			}, nil
+		}
+	}
+
+	if req.LeadData != nil {
+		applyCallEntities(req.LeadData, request)
+		applyCallSummary(req.LeadData, request)
+	}
+
 	// Get CRM client
 	client, exists := s.crmClients[req.CRMProvider]
 	if !exists {
@@ -462,7 +515,7 @@ func (s *CRMService) processCRMIntegration(ctx context.Context, req *CRMIntegrat
 		UpdatedAt:     time.Now().UTC(),
 	}
 
-	if err := s.spannerRepo.CreateCRMIntegration(ctx, integration); err != nil {
+	if err := s.repo.CreateCRMIntegration(ctx, integration); err != nil {
 		log.Printf("Failed to create CRM integration record: %v", err)
 		// Continue processing even if record creation fails
 	}
@@ -500,7 +553,7 @@ func (s *CRMService) processCRMIntegration(ctx context.Context, req *CRMIntegrat
 	}
 	integration.UpdatedAt = time.Now().UTC()
 
-	if updateErr := s.spannerRepo.UpdateCRMIntegration(ctx, integration); updateErr != nil {
+	if updateErr := s.repo.UpdateCRMIntegration(ctx, integration); updateErr != nil {
 		log.Printf("Failed to update CRM integration record: %v", updateErr)
 	}
 
@@ -527,6 +580,99 @@ func (s *CRMService) processCRMIntegration(ctx context.Context, req *CRMIntegrat
 	}, nil
 }
 
+// loadRequest returns the stored request an integration is for, or nil when there is none. A
+// request that can't be read fails the integration rather than skipping the spam check.
+func (s *CRMService) loadRequest(ctx context.Context, req *CRMIntegrationRequest) (*models.Request, error) {
+	if req.RequestID == "" {
+		return nil, nil
+	}
+
+	request, err := s.repo.GetRequest(ctx, req.TenantID, req.RequestID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load request %s: %w", req.RequestID, err)
+	}
+	return request, nil
+}
+
+// applyCallEntities merges the contact details the caller gave during the call, as extracted and
+// stored on the request, into the lead
+func applyCallEntities(lead *LeadData, request *models.Request) {
+	if request == nil || request.AIExtracted == "" {
+		return
+	}
+
+	var entities models.CallEntities
+	if err := json.Unmarshal([]byte(request.AIExtracted), &entities); err != nil {
+		return
+	}
+
+	merged := &crm.Lead{
+		FullName:     lead.CustomerName,
+		Email:        lead.CustomerEmail,
+		Address:      lead.CustomerAddress,
+		City:         lead.CustomerCity,
+		State:        lead.CustomerState,
+		ZipCode:      lead.CustomerZip,
+		CallbackTime: lead.CallbackTime,
+		CustomFields: lead.CustomFields,
+	}
+	merged.ApplyEntities(&entities)
+
+	lead.CustomerName = merged.FullName
+	lead.CustomerEmail = merged.Email
+	lead.CustomerAddress = merged.Address
+	lead.CustomerCity = merged.City
+	lead.CustomerState = merged.State
+	lead.CustomerZip = merged.ZipCode
+	lead.CallbackTime = merged.CallbackTime
+	lead.CustomFields = merged.CustomFields
+}
+
+// applyCallSummary copies the call summary stored with the request's AI analysis onto a lead that
+// doesn't have one yet. None of this service's CRM clients have fields for the summary, so it is
+// also put ahead of the notes for the rep to read first.
+func applyCallSummary(lead *LeadData, request *models.Request) {
+	if lead.Summary != "" || request == nil || request.AIAnalysis == nil {
+		return
+	}
+
+	var analysis models.CallAnalysis
+	if err := json.Unmarshal([]byte(*request.AIAnalysis), &analysis); err != nil || analysis.Summary == nil {
+		return
+	}
+
+	summarized := &crm.Lead{}
+	summarized.ApplySummary(analysis.Summary)
+	lead.Summary = summarized.Summary
+	lead.ActionItems = summarized.ActionItems
+	lead.Commitments = summarized.Commitments
+	lead.OpenQuestions = summarized.OpenQuestions
+
+	notes := summarized.SummaryNotes()
+	if lead.Notes != "" {
+		notes += "\n" + lead.Notes
+	}
+	lead.Notes = notes
+}
+
+// suppressedAsSpam reports whether the request's spam likelihood reaches the tenant's spam threshold
+func suppressedAsSpam(request *models.Request, workflowConfigJSON string) (float64, bool) {
+	if request == nil || request.SpamLikelihood == nil {
+		return 0, false
+	}
+
+	var workflowConfig models.WorkflowConfig
+	if err := json.Unmarshal([]byte(workflowConfigJSON), &workflowConfig); err != nil {
+		return 0, false
+	}
+
+	likelihood := *request.SpamLikelihood
+	return likelihood, spam.Suppresses(workflowConfig.Validation.SpamDetection, likelihood)
+}
+
 func (s *CRMService) parseCRMConfig(workflowConfigJSON, provider string) (*CRMConfig, error) {
 	var workflowConfig models.WorkflowConfig
 	if err := json.Unmarshal([]byte(workflowConfigJSON), &workflowConfig); err != nil {
@@ -549,6 +695,10 @@ func (s *CRMService) parseCRMConfig(workflowConfigJSON, provider string) (*CRMCo
 }
 
 func (s *CRMService) publishIntegrationCompletedEvent(ctx context.Context, req *CRMIntegrationRequest, response *CRMResponse) error {
+	if s.pubsubClient == nil {
+		return nil // events are only published when Pub/Sub is configured
+	}
+
 	topic := s.pubsubClient.Topic("crm-integration-completed")
 
 	event := map[string]interface{}{
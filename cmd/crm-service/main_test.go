@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/internal/auth"
+	"github.com/home-renovators/ingestion-pipeline/internal/encryption"
+	"github.com/home-renovators/ingestion-pipeline/internal/repository"
+	"github.com/home-renovators/ingestion-pipeline/pkg/config"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// newTestCRMService wires the CRM service to an in-memory store
+func newTestCRMService(t *testing.T) (*CRMService, *repository.MemoryStore, *gin.Engine) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	store := repository.NewMemoryStore()
+	cfg := config.DefaultConfig()
+	s := &CRMService{
+		config:      cfg,
+		authService: auth.NewAuthService(cfg, store),
+		repo:        store,
+		crmClients:  make(map[string]CRMClient),
+	}
+
+	router := gin.New()
+	s.setupRoutes(router)
+	return s, store, router
+}
+
+// capturingClient is a CRMClient that keeps the leads it is asked to create
+type capturingClient struct {
+	created []*LeadData
+}
+
+func (c *capturingClient) CreateLead(ctx context.Context, lead *LeadData, config *CRMConfig) (*CRMResponse, error) {
+	c.created = append(c.created, lead)
+	return &CRMResponse{Success: true, LeadID: "lead_1", ExternalID: "ext_1"}, nil
+}
+
+func (c *capturingClient) UpdateLead(ctx context.Context, leadID string, lead *LeadData, config *CRMConfig) (*CRMResponse, error) {
+	return &CRMResponse{Success: true, LeadID: leadID}, nil
+}
+
+func (c *capturingClient) GetLead(ctx context.Context, leadID string, config *CRMConfig) (*LeadData, error) {
+	return &LeadData{ID: leadID}, nil
+}
+
+func (c *capturingClient) TestConnection(ctx context.Context, config *CRMConfig) error {
+	return nil
+}
+
+func TestIntegrationStatusIsScopedToTenant(t *testing.T) {
+	_, store, router := newTestCRMService(t)
+	now := time.Now().UTC()
+	require.NoError(t, store.CreateCRMIntegration(context.Background(), &models.CRMIntegration{
+		IntegrationID: "int_1",
+		TenantID:      "tenant_a",
+		CRMType:       "hubspot",
+		Config:        `{"lead_id":"123"}`,
+		Status:        "completed",
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/crm/status/int_1?tenant_id=tenant_a", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var status map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, "hubspot", status["crm_type"])
+	assert.Equal(t, "completed", status["status"])
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/crm/status/int_1?tenant_id=tenant_b", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetLeadRequiresActiveOffice(t *testing.T) {
+	_, store, router := newTestCRMService(t)
+	store.PutOffice(&models.Office{
+		TenantID: "tenant_a",
+		OfficeID: "office_1",
+		Status:   "inactive",
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/crm/get-lead/123?tenant_id=tenant_a&provider=hubspot", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCreateLeadIsSuppressedAboveSpamThreshold(t *testing.T) {
+	_, store, router := newTestCRMService(t)
+	ctx := context.Background()
+	workflowConfig, err := json.Marshal(models.WorkflowConfig{
+		CRMIntegration: models.CRMIntegrationConfig{Enabled: true, Provider: "hubspot"},
+		Validation: models.ValidationConfig{
+			SpamDetection: models.SpamDetectionConfig{Enabled: true, ConfidenceThreshold: 80},
+		},
+	})
+	require.NoError(t, err)
+	store.PutOffice(&models.Office{TenantID: "tenant_a", OfficeID: "office_1", WorkflowConfig: string(workflowConfig), Status: "active"})
+
+	spamLikelihood := 92.5
+	require.NoError(t, store.CreateRequest(ctx, &models.Request{
+		RequestID: "req_1", TenantID: "tenant_a", Source: "callrail", Status: "completed",
+		SpamLikelihood: &spamLikelihood, CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/crm/create-lead", strings.NewReader(
+		`{"tenant_id":"tenant_a","request_id":"req_1","crm_provider":"hubspot","action":"create","lead_data":{"customer_name":"Robo Caller"}}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp CRMIntegrationResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "suppressed", resp.Status)
+	assert.Empty(t, resp.IntegrationID, "nothing was pushed")
+
+	// Below the threshold the lead goes on to the CRM, here failing for want of a HubSpot client
+	spamLikelihood = 79.9
+	require.NoError(t, store.UpdateRequestAnalysis(ctx, "tenant_a", "req_1", "{}", nil, &spamLikelihood))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/crm/create-lead", strings.NewReader(
+		`{"tenant_id":"tenant_a","request_id":"req_1","crm_provider":"hubspot","action":"create","lead_data":{"customer_name":"Robo Caller"}}`)))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestCreateLeadIsSuppressedForEncryptedRequest(t *testing.T) {
+	_, store, router := newTestCRMService(t)
+	ctx := context.Background()
+	wrapper, err := encryption.NewLocalKeyWrapper(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32)))
+	require.NoError(t, err)
+	envelope := encryption.NewEnvelope(store, wrapper, nil)
+	store.SetEnvelope(envelope)
+
+	workflowConfig, err := json.Marshal(models.WorkflowConfig{
+		CRMIntegration: models.CRMIntegrationConfig{Enabled: true, Provider: "hubspot"},
+		Validation: models.ValidationConfig{
+			SpamDetection: models.SpamDetectionConfig{Enabled: true, ConfidenceThreshold: 80},
+		},
+	})
+	require.NoError(t, err)
+	store.PutOffice(&models.Office{TenantID: "tenant_a", OfficeID: "office_1", WorkflowConfig: string(workflowConfig), Status: "active"})
+
+	spamLikelihood := 92.5
+	analysis := `{"intent":"spam"}`
+	require.NoError(t, store.CreateRequest(ctx, &models.Request{
+		RequestID: "req_1", TenantID: "tenant_a", Source: "callrail", Status: "completed",
+		AIAnalysis: &analysis, SpamLikelihood: &spamLikelihood, CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+
+	createLead := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/crm/create-lead", strings.NewReader(
+			`{"tenant_id":"tenant_a","request_id":"req_1","crm_provider":"hubspot","action":"create","lead_data":{"customer_name":"Robo Caller"}}`)))
+		return w
+	}
+
+	w := createLead()
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp CRMIntegrationResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "suppressed", resp.Status)
+
+	// Without the keys the sealed row can't be read, and the lead is held back rather than pushed unchecked
+	store.SetEnvelope(nil)
+	w = createLead()
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestCreateLeadCarriesStoredCallSummary(t *testing.T) {
+	s, store, router := newTestCRMService(t)
+	ctx := context.Background()
+	client := &capturingClient{}
+	s.crmClients["hubspot"] = client
+
+	workflowConfig, err := json.Marshal(models.WorkflowConfig{
+		CRMIntegration: models.CRMIntegrationConfig{Enabled: true, Provider: "hubspot"},
+	})
+	require.NoError(t, err)
+	store.PutOffice(&models.Office{TenantID: "tenant_a", OfficeID: "office_1", WorkflowConfig: string(workflowConfig), Status: "active"})
+	require.NoError(t, store.CreateRequest(ctx, &models.Request{
+		RequestID: "req_1", TenantID: "tenant_a", Source: "callrail", Status: "completed",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+
+	analysis, err := json.Marshal(models.CallAnalysis{
+		Intent: "new_project",
+		Summary: &models.CallSummary{
+			Summary:     "Caller wants a quote to rebuild a rotting deck.",
+			ActionItems: []string{"Call back Tuesday morning"},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, store.UpdateRequestAnalysis(ctx, "tenant_a", "req_1", string(analysis), nil, nil))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/crm/create-lead", strings.NewReader(
+		`{"tenant_id":"tenant_a","request_id":"req_1","crm_provider":"hubspot","action":"create","lead_data":{"customer_name":"Dana Whitfield","notes":"Found us on Google"}}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.Len(t, client.created, 1)
+	lead := client.created[0]
+	assert.Equal(t, "Caller wants a quote to rebuild a rotting deck.", lead.Summary)
+	assert.Equal(t, []string{"Call back Tuesday morning"}, lead.ActionItems)
+	assert.Equal(t, "Summary: Caller wants a quote to rebuild a rotting deck.\nAction Items:\n- Call back Tuesday morning\nFound us on Google", lead.Notes, "the summary leads the rep's notes")
+
+	integrations, err := store.FindCRMIntegrations(ctx, "tenant_a", []string{"rotting deck"})
+	require.NoError(t, err)
+	assert.Len(t, integrations, 1, "the integration record keeps the lead as sent")
+}
+
+func TestCreateLeadMergesStoredCallEntities(t *testing.T) {
+	s, store, router := newTestCRMService(t)
+	ctx := context.Background()
+	wrapper, err := encryption.NewLocalKeyWrapper(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32)))
+	require.NoError(t, err)
+	store.SetEnvelope(encryption.NewEnvelope(store, wrapper, nil))
+	client := &capturingClient{}
+	s.crmClients["hubspot"] = client
+
+	workflowConfig, err := json.Marshal(models.WorkflowConfig{
+		CRMIntegration: models.CRMIntegrationConfig{Enabled: true, Provider: "hubspot"},
+	})
+	require.NoError(t, err)
+	store.PutOffice(&models.Office{TenantID: "tenant_a", OfficeID: "office_1", WorkflowConfig: string(workflowConfig), Status: "active"})
+	require.NoError(t, store.CreateRequest(ctx, &models.Request{
+		RequestID: "req_1", TenantID: "tenant_a", Source: "callrail", Status: "completed",
+		AIExtracted: "{}", CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+	require.NoError(t, store.UpdateRequestExtraction(ctx, "tenant_a", "req_1", `{
+		"customer_name":{"value":"Dana Whitfield","confidence":0.9,"quote":"this is Dana Whitfield"},
+		"email":{"value":"dana@example.com","confidence":0.85,"quote":"dana at example dot com"},
+		"address":{"street":"12 Oak Lane","city":"Round Rock","state":"TX","zip_code":"78664","confidence":0.8,"quote":"12 Oak Lane in Round Rock"},
+		"callback_time":{"value":"weekdays after 5pm","confidence":0.3,"quote":"maybe after five"}}`))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/crm/create-lead", strings.NewReader(
+		`{"tenant_id":"tenant_a","request_id":"req_1","crm_provider":"hubspot","action":"create","lead_data":{"customer_name":"WIRELESS CALLER","customer_city":"Austin"}}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.Len(t, client.created, 1)
+	lead := client.created[0]
+	assert.Equal(t, "Dana Whitfield", lead.CustomerName)
+	assert.Equal(t, "WIRELESS CALLER", lead.CustomFields["caller_id_name"])
+	assert.Equal(t, "dana@example.com", lead.CustomerEmail)
+	assert.Equal(t, "12 Oak Lane", lead.CustomerAddress)
+	assert.Equal(t, "Round Rock", lead.CustomerCity)
+	assert.Equal(t, "78664", lead.CustomerZip)
+	assert.Empty(t, lead.CallbackTime, "low-confidence entities are left out")
+}
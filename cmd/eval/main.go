@@ -0,0 +1,208 @@
+// Command eval scores analysis quality against a labeled JSONL dataset of call transcripts,
+// for one prompt and model variant or for a baseline and a candidate side by side. It exits
+// non-zero when the candidate regresses by more than -max-regression on any metric.
+//
+//	eval -dataset calls.jsonl
+//	eval -dataset calls.jsonl -template 1 -candidate-template 2
+//	eval -dataset calls.jsonl -candidate-model gemini-2.5-pro
+//	eval -dataset calls.jsonl -candidate-combined
+//	eval -dataset calls.jsonl -baseline-report main.json -template 2
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/ai"
+	"github.com/home-renovators/ingestion-pipeline/pkg/config"
+	"github.com/home-renovators/ingestion-pipeline/pkg/eval"
+)
+
+type options struct {
+	dataset           string
+	model             string
+	template          int
+	candidateModel    string
+	candidateTemplate int
+	combined          bool
+	candidateCombined bool
+	baselineReport    string
+	maxRegression     float64
+	out               string
+}
+
+func main() {
+	cfg := config.DefaultConfig()
+	runner := eval.DefaultRunner()
+	var opts options
+
+	model := cfg.VertexAIModel
+	if cfg.LLMModel != "" {
+		model = cfg.LLMModel
+	}
+
+	flag.StringVar(&opts.dataset, "dataset", "", "labeled JSONL dataset (required)")
+	flag.StringVar(&cfg.LLMProvider, "provider", cfg.LLMProvider, "LLM provider: vertex, openai or fake")
+	flag.StringVar(&cfg.LLMBaseURL, "base-url", cfg.LLMBaseURL, "OpenAI-compatible API root for the openai provider")
+	flag.StringVar(&cfg.LLMFakeScript, "fake-script", cfg.LLMFakeScript, "scripted answers for the fake provider")
+	flag.StringVar(&opts.model, "model", model, "baseline model")
+	flag.IntVar(&opts.template, "template", 0, "baseline prompt template version, 0 for the latest")
+	flag.StringVar(&opts.candidateModel, "candidate-model", "", "candidate model, defaults to the baseline model")
+	flag.IntVar(&opts.candidateTemplate, "candidate-template", 0, "candidate prompt template version")
+	flag.BoolVar(&opts.combined, "combined", false, "baseline runs one combined analysis per call instead of separate ones")
+	flag.BoolVar(&opts.candidateCombined, "candidate-combined", false, "candidate runs one combined analysis per call")
+	flag.StringVar(&opts.baselineReport, "baseline-report", "", "JSON report of an earlier run to compare against instead of running a baseline")
+	flag.Float64Var(&opts.maxRegression, "max-regression", 0.02, "largest allowed drop of any metric, e.g. 0.02 for two points")
+	flag.StringVar(&opts.out, "out", "", "write the JSON report or comparison to this file")
+	flag.IntVar(&runner.Concurrency, "concurrency", runner.Concurrency, "analyses in flight at once")
+	flag.Float64Var(&runner.SpamThreshold, "spam-threshold", runner.SpamThreshold, "spam likelihood at which a call counts as spam")
+	timeout := flag.Duration("timeout", time.Hour, "overall timeout")
+	flag.Parse()
+
+	if opts.dataset == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	regressed, err := run(ctx, cfg, runner, &opts)
+	if err != nil {
+		log.Fatalf("Evaluation failed: %v", err)
+	}
+	if regressed {
+		os.Exit(1)
+	}
+}
+
+// run evaluates the requested variants and reports whether the candidate regressed
+func run(ctx context.Context, cfg *config.Config, runner *eval.Runner, opts *options) (bool, error) {
+	examples, err := eval.LoadDatasetFile(opts.dataset)
+	if err != nil {
+		return false, err
+	}
+
+	llm, err := ai.NewProvider(ctx, &ai.ProviderConfig{
+		Provider:   cfg.LLMProvider,
+		ProjectID:  cfg.VertexAIProject,
+		Location:   cfg.VertexAILocation,
+		BaseURL:    cfg.LLMBaseURL,
+		APIKey:     cfg.LLMAPIKey,
+		FakeScript: cfg.LLMFakeScript,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to create LLM provider: %w", err)
+	}
+	defer llm.Close()
+
+	evaluate := func(variant eval.Variant) (*eval.Report, error) {
+		analysisConfig := ai.DefaultAnalysisConfig()
+		analysisConfig.Model = variant.Model
+		service := ai.NewAnalysisServiceWithProvider(llm, analysisConfig)
+
+		log.Printf("Evaluating %s over %d examples", variant.Name, len(examples))
+		return runner.Run(ctx, service, variant, examples)
+	}
+
+	baselineVariant := eval.Variant{Name: "baseline", Model: opts.model, TemplateVersion: opts.template, Combined: opts.combined}
+	comparing := opts.candidateModel != "" || opts.candidateTemplate != 0 || opts.candidateCombined
+
+	var baseline *eval.Report
+	switch {
+	case opts.baselineReport != "":
+		if baseline, err = loadReport(opts.baselineReport); err != nil {
+			return false, err
+		}
+	case comparing:
+		if baseline, err = evaluate(baselineVariant); err != nil {
+			return false, err
+		}
+	default:
+		// A single variant: report its metrics without a comparison
+		report, err := evaluate(baselineVariant)
+		if err != nil {
+			return false, err
+		}
+		if err := eval.WriteReport(os.Stdout, report); err != nil {
+			return false, err
+		}
+		return false, writeJSON(opts.out, report)
+	}
+
+	candidateVariant := baselineVariant
+	candidateVariant.Name = "candidate"
+	if opts.candidateModel != "" {
+		candidateVariant.Model = opts.candidateModel
+	}
+	if opts.candidateTemplate != 0 {
+		candidateVariant.TemplateVersion = opts.candidateTemplate
+	}
+	if opts.candidateCombined {
+		candidateVariant.Combined = true
+	}
+	candidate, err := evaluate(candidateVariant)
+	if err != nil {
+		return false, err
+	}
+
+	for _, report := range []*eval.Report{baseline, candidate} {
+		if err := eval.WriteReport(os.Stdout, report); err != nil {
+			return false, err
+		}
+		fmt.Println()
+	}
+
+	comparison := eval.Compare(baseline, candidate, opts.maxRegression)
+	if err := eval.WriteComparison(os.Stdout, comparison); err != nil {
+		return false, err
+	}
+	if err := writeJSON(opts.out, comparison); err != nil {
+		return false, err
+	}
+
+	regressions := comparison.Regressions()
+	for _, row := range regressions {
+		log.Printf("Regression: %s dropped from %.3f to %.3f", row.Metric, row.Baseline, row.Candidate)
+	}
+	return len(regressions) > 0, nil
+}
+
+// loadReport reads a JSON report written by an earlier run with -out
+func loadReport(path string) (*eval.Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline report: %w", err)
+	}
+
+	var report eval.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline report: %w", err)
+	}
+	if len(report.Fields) == 0 {
+		return nil, fmt.Errorf("baseline report %s has no metrics; pass a single-variant report", path)
+	}
+
+	return &report, nil
+}
+
+func writeJSON(path string, v interface{}) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	return nil
+}
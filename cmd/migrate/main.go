@@ -0,0 +1,112 @@
+// Command migrate applies the schema migrations shipped in internal/migrations
+// to Spanner, or to the Spanner emulator when SPANNER_EMULATOR_HOST is set.
+//
+//	migrate [flags] up            apply pending migrations
+//	migrate [flags] status        show the database version and pending migrations
+//	migrate [flags] baseline N    mark migrations 1..N applied on a pre-existing database
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/home-renovators/ingestion-pipeline/internal/migrations"
+	"github.com/home-renovators/ingestion-pipeline/pkg/config"
+)
+
+func main() {
+	cfg := config.DefaultConfig()
+
+	flag.StringVar(&cfg.ProjectID, "project", cfg.ProjectID, "GCP project ID")
+	flag.StringVar(&cfg.SpannerInstance, "instance", cfg.SpannerInstance, "Spanner instance ID")
+	flag.StringVar(&cfg.SpannerDatabase, "database", cfg.SpannerDatabase, "Spanner database ID")
+	create := flag.Bool("create", false, "create the database if it doesn't exist (emulator and new environments)")
+	timeout := flag.Duration("timeout", 30*time.Minute, "overall timeout, schema changes on large tables can be slow")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: migrate [flags] up | status | baseline N\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err := run(ctx, cfg.SpannerDatabasePath(), *create, flag.Args()); err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+}
+
+func run(ctx context.Context, databasePath string, create bool, args []string) error {
+	migrator, err := migrations.NewMigrator(ctx, databasePath)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	if create {
+		created, err := migrator.EnsureDatabase(ctx)
+		if err != nil {
+			return err
+		}
+		if created {
+			log.Printf("Created database %s", databasePath)
+		}
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			log.Printf("Applied %04d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			log.Printf("Database %s is up to date", databasePath)
+		}
+		return nil
+
+	case "status":
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			return err
+		}
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("database: %s\nversion:  %d\n", databasePath, version)
+		for _, migration := range pending {
+			fmt.Printf("pending:  %04d_%s\n", migration.Version, migration.Name)
+		}
+		return nil
+
+	case "baseline":
+		if len(args) != 2 {
+			return fmt.Errorf("baseline requires the version the database already matches")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid baseline version %q: %w", args[1], err)
+		}
+		if err := migrator.Baseline(ctx, version); err != nil {
+			return err
+		}
+		log.Printf("Marked migrations 1..%d as applied", version)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown command %q, expected up, status or baseline", args[0])
+	}
+}
@@ -2,25 +2,23 @@ package ai
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	speech "cloud.google.com/go/speech/apiv1"
 	"cloud.google.com/go/speech/apiv1/speechpb"
-	aiplatform "cloud.google.com/go/aiplatform/apiv1"
-	"cloud.google.com/go/aiplatform/apiv1/aiplatformpb"
 	"google.golang.org/protobuf/types/known/durationpb"
-	"google.golang.org/protobuf/types/known/structpb"
 
+	pkgai "github.com/home-renovators/ingestion-pipeline/pkg/ai"
 	"github.com/home-renovators/ingestion-pipeline/pkg/config"
 	"github.com/home-renovators/ingestion-pipeline/pkg/models"
 )
 
-// Service handles AI operations including Speech-to-Text and Vertex AI
+// Service handles AI operations including Speech-to-Text and LLM analysis
 type Service struct {
 	speechClient *speech.Client
-	aiClient     *aiplatform.PredictionClient
+	llm          pkgai.LLMProvider
 	config       *config.Config
 }
 
@@ -31,25 +29,50 @@ func NewService(ctx context.Context, cfg *config.Config) (*Service, error) {
 		return nil, fmt.Errorf("failed to create speech client: %w", err)
 	}
 
-	aiClient, err := aiplatform.NewPredictionClient(ctx)
+	llm, err := pkgai.NewProvider(ctx, &pkgai.ProviderConfig{
+		Provider:   cfg.LLMProvider,
+		ProjectID:  cfg.VertexAIProject,
+		Location:   cfg.VertexAILocation,
+		BaseURL:    cfg.LLMBaseURL,
+		APIKey:     cfg.LLMAPIKey,
+		FakeScript: cfg.LLMFakeScript,
+	})
 	if err != nil {
 		speechClient.Close()
-		return nil, fmt.Errorf("failed to create AI platform client: %w", err)
+		return nil, fmt.Errorf("failed to create LLM provider: %w", err)
 	}
 
 	return &Service{
 		speechClient: speechClient,
-		aiClient:     aiClient,
+		llm:          llm,
 		config:       cfg,
 	}, nil
 }
 
+// NewServiceWithProvider creates an analysis-only AI service using the given LLM provider
+func NewServiceWithProvider(cfg *config.Config, llm pkgai.LLMProvider) *Service {
+	return &Service{
+		llm:    llm,
+		config: cfg,
+	}
+}
+
 // Close closes the AI service clients
 func (s *Service) Close() error {
-	if err := s.speechClient.Close(); err != nil {
-		return err
+	if s.speechClient != nil {
+		if err := s.speechClient.Close(); err != nil {
+			return err
+		}
+	}
+	return s.llm.Close()
+}
+
+// Model returns the name of the model analyses run on
+func (s *Service) Model() string {
+	if s.config.LLMModel != "" {
+		return s.config.LLMModel
 	}
-	return s.aiClient.Close()
+	return s.config.VertexAIModel
 }
 
 // TranscribeAudio transcribes audio using Speech-to-Text API with Chirp 3
@@ -82,6 +105,9 @@ func (s *Service) TranscribeAudio(ctx context.Context, audioFileURL string) (*mo
 	}
 
 	// Start long-running recognition
+	if s.speechClient == nil {
+		return nil, fmt.Errorf("speech-to-text is not configured")
+	}
 	op, err := s.speechClient.LongRunningRecognize(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start transcription: %w", err)
@@ -131,8 +157,8 @@ func (s *Service) processTranscriptionResults(resp *speechpb.LongRunningRecogniz
 					// Speaker changed, save current segment
 					result.SpeakerDiarization = append(result.SpeakerDiarization, models.SpeakerSegment{
 						Speaker:   int(currentSpeaker),
-						StartTime: formatDuration(segmentStart),
-						EndTime:   formatDuration(word.StartTime),
+						StartTime: toOffset(segmentStart),
+						EndTime:   toOffset(word.StartTime),
 						Text:      strings.TrimSpace(segmentText.String()),
 					})
 
@@ -148,9 +174,10 @@ func (s *Service) processTranscriptionResults(resp *speechpb.LongRunningRecogniz
 				// Add word details
 				result.WordDetails = append(result.WordDetails, models.WordDetail{
 					Word:       word.Word,
-					StartTime:  formatDuration(word.StartTime),
-					EndTime:    formatDuration(word.EndTime),
+					StartTime:  toOffset(word.StartTime),
+					EndTime:    toOffset(word.EndTime),
 					Confidence: word.Confidence,
+					Speaker:    int(word.SpeakerTag),
 				})
 			}
 
@@ -159,8 +186,8 @@ func (s *Service) processTranscriptionResults(resp *speechpb.LongRunningRecogniz
 				lastWord := alt.Words[len(alt.Words)-1]
 				result.SpeakerDiarization = append(result.SpeakerDiarization, models.SpeakerSegment{
 					Speaker:   int(currentSpeaker),
-					StartTime: formatDuration(segmentStart),
-					EndTime:   formatDuration(lastWord.EndTime),
+					StartTime: toOffset(segmentStart),
+					EndTime:   toOffset(lastWord.EndTime),
 					Text:      strings.TrimSpace(segmentText.String()),
 				})
 			}
@@ -182,238 +209,155 @@ func (s *Service) processTranscriptionResults(resp *speechpb.LongRunningRecogniz
 	return result
 }
 
+// AnalysisInput is a transcript to analyze and what its prompt is tailored with
+type AnalysisInput struct {
+	Transcription   string
+	Language        string // transcript language, e.g. "es-US"
+	CallDetails     models.CallDetails
+	PromptConfig    *models.AnalysisPromptConfig // the tenant's prompt customization, nil for the defaults
+	TemplateVersion int                          // overrides the tenant's pinned template version
+	CallerHistory   []models.PriorInteraction    // the caller's earlier calls to the tenant, newest first
+}
+
 // AnalyzeCallContent analyzes call content using Gemini 2.5 Flash
 func (s *Service) AnalyzeCallContent(ctx context.Context, transcription string, callDetails models.CallDetails) (*models.CallAnalysis, error) {
-	prompt := s.buildAnalysisPrompt(transcription, callDetails)
-
-	// Prepare the request for Vertex AI
-	endpoint := fmt.Sprintf("projects/%s/locations/%s/publishers/google/models/%s",
-		s.config.VertexAIProject, s.config.VertexAILocation, s.config.VertexAIModel)
-
-	// Create the prediction request
-	instances, err := s.createGeminiInstances(prompt)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create instances: %w", err)
-	}
+	analysis, _, err := s.AnalyzeContent(ctx, &AnalysisInput{Transcription: transcription, CallDetails: callDetails})
+	return analysis, err
+}
 
-	parameters, err := s.createGeminiParameters()
+// AnalyzeContent analyzes a transcript in its source language, returning English fields
+func (s *Service) AnalyzeContent(ctx context.Context, in *AnalysisInput) (*models.CallAnalysis, pkgai.AnalysisMetadata, error) {
+	var analysis models.CallAnalysis
+	metadata, err := s.generateJSON(ctx, pkgai.AnalysisTypeContent, in, &analysis)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create parameters: %w", err)
+		return nil, metadata, fmt.Errorf("content analysis failed: %w", err)
 	}
+	pkgai.ResolveContinuity(&analysis, in.CallerHistory)
 
-	req := &aiplatformpb.PredictRequest{
-		Endpoint:   endpoint,
-		Instances:  instances,
-		Parameters: parameters,
-	}
+	return &analysis, metadata, nil
+}
 
-	// Make the prediction
-	resp, err := s.aiClient.Predict(ctx, req)
+// DetectSpam analyzes content for spam likelihood
+func (s *Service) DetectSpam(ctx context.Context, transcription string, callDetails models.CallDetails) (float64, error) {
+	result, _, err := s.AnalyzeSpam(ctx, &AnalysisInput{Transcription: transcription, CallDetails: callDetails})
 	if err != nil {
-		return nil, fmt.Errorf("prediction failed: %w", err)
+		return 0, err
 	}
+	return result.SpamLikelihood, nil
+}
 
-	// Parse the response
-	analysis, err := s.parseGeminiResponse(resp)
+// AnalyzeSpam analyzes a transcript in its source language for spam likelihood
+func (s *Service) AnalyzeSpam(ctx context.Context, in *AnalysisInput) (*pkgai.SpamAnalysisResult, pkgai.AnalysisMetadata, error) {
+	var result pkgai.SpamAnalysisResult
+	metadata, err := s.generateJSON(ctx, pkgai.AnalysisTypeSpam, in, &result)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+		return nil, metadata, fmt.Errorf("spam detection failed: %w", err)
 	}
 
-	return analysis, nil
-}
-
-// buildAnalysisPrompt creates the analysis prompt for Gemini
-func (s *Service) buildAnalysisPrompt(transcription string, callDetails models.CallDetails) string {
-	return fmt.Sprintf(`
-Analyze this phone call transcription for a home remodeling company:
-
-TRANSCRIPT: %s
-
-CALL METADATA:
-- Customer Name: %s
-- Customer Phone: %s
-- Customer Location: %s, %s
-- Call Duration: %d seconds
-- Source: %s
-- Tags: %s
-- Lead Status: %s
-
-Extract the following information in JSON format:
-{
-  "intent": "quote_request|information_seeking|appointment_booking|complaint|follow_up|other",
-  "project_type": "kitchen|bathroom|whole_home|addition|flooring|roofing|windows|doors|other",
-  "timeline": "immediate|1-3_months|3-6_months|6+_months|unknown",
-  "budget_indicator": "high|medium|low|unknown",
-  "sentiment": "positive|neutral|negative",
-  "lead_score": 1-100,
-  "urgency": "high|medium|low",
-  "appointment_requested": true|false,
-  "follow_up_required": true|false,
-  "key_details": ["detail1", "detail2", "detail3"]
+	return &result, metadata, nil
 }
 
-Consider these factors for lead scoring:
-- Project type complexity (kitchen/bathroom = higher score)
-- Customer engagement level
-- Timeline urgency
-- Budget indicators
-- Location within service area
-- Quality of conversation
-
-Respond with ONLY the JSON object, no additional text.`,
-		transcription,
-		callDetails.CustomerName,
-		callDetails.CustomerPhoneNumber,
-		callDetails.CustomerCity,
-		callDetails.CustomerState,
-		callDetails.Duration,
-		callDetails.Source,
-		strings.Join(callDetails.Tags, ", "),
-		callDetails.LeadStatus)
-}
-
-// createGeminiInstances creates instances for Gemini prediction
-func (s *Service) createGeminiInstances(prompt string) ([]*structpb.Value, error) {
-	instance := map[string]interface{}{
-		"inputs": prompt,
-		"parameters": map[string]interface{}{
-			"temperature":     0.2,
-			"maxOutputTokens": 1024,
-			"topP":            0.8,
-			"topK":            40,
-		},
-	}
-
-	instanceValue, err := structpb.NewValue(instance)
+// AnalyzeSentiment analyzes the sentiment of a transcript in its source language
+func (s *Service) AnalyzeSentiment(ctx context.Context, in *AnalysisInput) (*pkgai.SentimentAnalysisResult, pkgai.AnalysisMetadata, error) {
+	var result pkgai.SentimentAnalysisResult
+	metadata, err := s.generateJSON(ctx, pkgai.AnalysisTypeSentiment, in, &result)
 	if err != nil {
-		return nil, err
+		return nil, metadata, fmt.Errorf("sentiment analysis failed: %w", err)
 	}
 
-	return []*structpb.Value{instanceValue}, nil
+	return &result, metadata, nil
 }
 
-// createGeminiParameters creates parameters for Gemini prediction
-func (s *Service) createGeminiParameters() (*structpb.Value, error) {
-	parameters := map[string]interface{}{
-		"temperature":     0.2,
-		"maxOutputTokens": 1024,
-		"topP":            0.8,
-		"topK":            40,
+// AnalyzeSummary summarizes a transcript with action items, commitments and open questions
+// for sales follow-up
+func (s *Service) AnalyzeSummary(ctx context.Context, in *AnalysisInput) (*models.CallSummary, pkgai.AnalysisMetadata, error) {
+	var summary models.CallSummary
+	metadata, err := s.generateJSON(ctx, pkgai.AnalysisTypeSummary, in, &summary)
+	if err != nil {
+		return nil, metadata, fmt.Errorf("call summary failed: %w", err)
 	}
 
-	return structpb.NewValue(parameters)
+	return &summary, metadata, nil
 }
 
-// parseGeminiResponse parses the Gemini response into CallAnalysis
-func (s *Service) parseGeminiResponse(resp *aiplatformpb.PredictResponse) (*models.CallAnalysis, error) {
-	if len(resp.Predictions) == 0 {
-		return nil, fmt.Errorf("no predictions returned")
-	}
-
-	prediction := resp.Predictions[0]
-
-	// Extract the content from the prediction
-	predictionMap := prediction.GetStructValue().AsMap()
-
-	content, ok := predictionMap["content"]
-	if !ok {
-		return nil, fmt.Errorf("no content in prediction")
-	}
-
-	contentStr, ok := content.(string)
-	if !ok {
-		return nil, fmt.Errorf("content is not a string")
-	}
-
-	// Parse the JSON response
-	var analysis models.CallAnalysis
-	if err := json.Unmarshal([]byte(contentStr), &analysis); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+// AnalyzeEntities extracts the contact details a caller gave, each located in the transcript
+func (s *Service) AnalyzeEntities(ctx context.Context, in *AnalysisInput) (*models.CallEntities, pkgai.AnalysisMetadata, error) {
+	var entities models.CallEntities
+	metadata, err := s.generateJSON(ctx, pkgai.AnalysisTypeEntities, in, &entities)
+	if err != nil {
+		return nil, metadata, fmt.Errorf("entity extraction failed: %w", err)
 	}
+	pkgai.ResolveEntities(&entities, in.Transcription)
 
-	return &analysis, nil
+	return &entities, metadata, nil
 }
 
-// DetectSpam analyzes content for spam likelihood
-func (s *Service) DetectSpam(ctx context.Context, transcription string, callDetails models.CallDetails) (float64, error) {
-	prompt := fmt.Sprintf(`
-Analyze this phone call for spam likelihood:
-
-TRANSCRIPT: %s
-CALLER: %s
-PHONE: %s
-DURATION: %d seconds
-
-Evaluate for spam indicators:
-- Robotic or scripted speech patterns
-- Generic sales pitches
-- Suspicious caller behavior
-- Short call duration with generic content
-- Known spam phone patterns
-
-Return ONLY a number between 0-100 representing spam likelihood percentage.
-`, transcription, callDetails.CustomerName, callDetails.CustomerPhoneNumber, callDetails.Duration)
-
-	// Use similar Gemini prediction logic
-	endpoint := fmt.Sprintf("projects/%s/locations/%s/publishers/google/models/%s",
-		s.config.VertexAIProject, s.config.VertexAILocation, s.config.VertexAIModel)
-
-	instances, err := s.createGeminiInstances(prompt)
-	if err != nil {
-		return 0, fmt.Errorf("failed to create instances: %w", err)
-	}
-
-	parameters, err := s.createGeminiParameters()
+// AnalyzeCombined runs the content, spam, sentiment, intent, lead score, summary and entity
+// extraction analyses of a transcript in a single model call
+func (s *Service) AnalyzeCombined(ctx context.Context, in *AnalysisInput) (*pkgai.CombinedAnalysisResult, pkgai.AnalysisMetadata, error) {
+	var result pkgai.CombinedAnalysisResult
+	metadata, err := s.generateJSON(ctx, pkgai.AnalysisTypeCombined, in, &result)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create parameters: %w", err)
+		return nil, metadata, fmt.Errorf("combined analysis failed: %w", err)
 	}
+	result.Reconcile()
+	pkgai.ResolveEntities(&result.Entities, in.Transcription)
+	pkgai.ResolveContinuity(&result.CallAnalysis, in.CallerHistory)
 
-	req := &aiplatformpb.PredictRequest{
-		Endpoint:   endpoint,
-		Instances:  instances,
-		Parameters: parameters,
-	}
+	return &result, metadata, nil
+}
 
-	resp, err := s.aiClient.Predict(ctx, req)
+// RenderPrompt renders the prompt an analysis type would send for the input
+func (s *Service) RenderPrompt(analysisType pkgai.AnalysisType, in *AnalysisInput) (*pkgai.RenderedPrompt, error) {
+	rendered, err := pkgai.DefaultPromptLibrary().RenderWithHistory(analysisType, in.TemplateVersion, in.PromptConfig, in.Transcription, &in.CallDetails, in.CallerHistory)
 	if err != nil {
-		return 0, fmt.Errorf("spam detection failed: %w", err)
-	}
-
-	// Parse spam likelihood from response
-	if len(resp.Predictions) == 0 {
-		return 0, fmt.Errorf("no spam predictions returned")
-	}
-
-	prediction := resp.Predictions[0]
-	predictionMap := prediction.GetStructValue().AsMap()
-
-	content, ok := predictionMap["content"]
-	if !ok {
-		return 0, fmt.Errorf("no content in spam prediction")
+		return nil, fmt.Errorf("failed to build %s prompt: %w", analysisType, err)
 	}
+	rendered.Text += pkgai.LanguageInstruction(in.Language)
 
-	contentStr, ok := content.(string)
-	if !ok {
-		return 0, fmt.Errorf("spam content is not a string")
-	}
+	return rendered, nil
+}
 
-	var spamLikelihood float64
-	if _, err := fmt.Sscanf(strings.TrimSpace(contentStr), "%f", &spamLikelihood); err != nil {
-		return 0, fmt.Errorf("failed to parse spam likelihood: %w", err)
-	}
+// generateJSON renders the analysis type's prompt, asks the model for its JSON result and
+// decodes it into out, repairing an unusable answer once
+func (s *Service) generateJSON(ctx context.Context, analysisType pkgai.AnalysisType, in *AnalysisInput, out interface{}) (pkgai.AnalysisMetadata, error) {
+	startTime := time.Now()
+	metadata := pkgai.AnalysisMetadata{ModelUsed: s.Model()}
 
-	return spamLikelihood, nil
+	prompt, err := s.RenderPrompt(analysisType, in)
+	if err != nil {
+		return metadata, err
+	}
+	metadata.PromptTemplate = prompt.Text
+	metadata.TemplateID = prompt.TemplateID
+	metadata.TemplateVersion = prompt.TemplateVersion
+
+	maxOutputTokens := int32(1024)
+	if analysisType == pkgai.AnalysisTypeCombined {
+		maxOutputTokens = pkgai.CombinedMaxOutputTokens
+	}
+
+	structured, err := pkgai.GenerateJSON(ctx, s.llm, &pkgai.GenerateRequest{
+		Model:           s.Model(),
+		Prompt:          prompt.Text,
+		Temperature:     0.2,
+		MaxOutputTokens: maxOutputTokens,
+		TopP:            0.8,
+		TopK:            40,
+		ResponseSchema:  pkgai.TenantResponseSchema(analysisType, in.PromptConfig),
+	}, out)
+	metadata.InputTokens = structured.Usage.InputTokens
+	metadata.OutputTokens = structured.Usage.OutputTokens
+	metadata.Repaired = structured.Repaired
+	metadata.ProcessingTime = time.Since(startTime)
+
+	return metadata, err
 }
 
-// formatDuration formats a protobuf duration to string
-func formatDuration(duration *durationpb.Duration) string {
+// toOffset converts a protobuf duration to a transcript offset
+func toOffset(duration *durationpb.Duration) models.Offset {
 	if duration == nil {
-		return "0.0s"
+		return 0
 	}
-
-	seconds := duration.Seconds
-	nanos := duration.Nanos
-
-	totalSeconds := float64(seconds) + float64(nanos)/1e9
-	return fmt.Sprintf("%.1fs", totalSeconds)
-}
\ No newline at end of file
+	return models.Offset(duration.AsDuration())
+}
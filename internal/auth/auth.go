@@ -8,7 +8,7 @@ import (
 	"errors"
 	"fmt"
 
-	"github.com/home-renovators/ingestion-pipeline/internal/spanner"
+	"github.com/home-renovators/ingestion-pipeline/internal/repository"
 	"github.com/home-renovators/ingestion-pipeline/pkg/config"
 	"github.com/home-renovators/ingestion-pipeline/pkg/models"
 )
@@ -22,15 +22,15 @@ var (
 // AuthService handles authentication and authorization
 type AuthService struct {
 	config       *config.Config
-	spannerRepo  *spanner.Repository
+	offices      repository.OfficeRepository
 	webhookSecret string
 }
 
 // NewAuthService creates a new authentication service
-func NewAuthService(cfg *config.Config, spannerRepo *spanner.Repository) *AuthService {
+func NewAuthService(cfg *config.Config, offices repository.OfficeRepository) *AuthService {
 	return &AuthService{
 		config:      cfg,
-		spannerRepo: spannerRepo,
+		offices:     offices,
 		webhookSecret: cfg.CallRailWebhookSecret,
 	}
 }
@@ -66,7 +66,7 @@ func (a *AuthService) AuthenticateTenant(ctx context.Context, tenantID, callRail
 	}
 
 	// Query office by CallRail company ID and tenant ID
-	office, err := a.spannerRepo.GetOfficeByCallRailCompanyID(ctx, callRailCompanyID, tenantID)
+	office, err := a.offices.GetOfficeByCallRailCompanyID(ctx, callRailCompanyID, tenantID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get office: %w", err)
 	}
@@ -93,7 +93,7 @@ func (a *AuthService) ValidateAPIAccess(ctx context.Context, tenantID string, op
 	}
 
 	// Check if tenant exists and is active
-	exists, err := a.spannerRepo.TenantExists(ctx, tenantID)
+	exists, err := a.offices.TenantExists(ctx, tenantID)
 	if err != nil {
 		return fmt.Errorf("failed to check tenant existence: %w", err)
 	}
@@ -0,0 +1,490 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/config"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// Ciphertext formats. Blobs start with blobMagic, the key version and the nonce; fields are the
+// blob format base64-encoded behind fieldPrefix. Data without the marker is legacy plaintext and
+// is returned unchanged on decrypt.
+const (
+	blobMagic   = "HRE1"
+	fieldPrefix = "enc:v1:"
+	dataKeySize = 32
+	headerSize  = len(blobMagic) + 4
+)
+
+// FieldPrefix starts every encrypted column value, for queries that need to find them
+const FieldPrefix = fieldPrefix
+
+// Purposes bind a ciphertext to what it protects, so a value can't be moved to another column
+const (
+	PurposeAudio          = "audio"
+	PurposeTranscription  = "transcription_data"
+	PurposeAIAnalysis     = "ai_analysis"
+	PurposeAIExtracted    = "ai_extracted"
+	PurposeProcessingData = "processing_data"
+)
+
+var (
+	// ErrTenantShredded is returned for a tenant whose data keys have been destroyed
+	ErrTenantShredded = errors.New("tenant data keys have been destroyed")
+	// ErrKeyNotFound is returned when a ciphertext names a key version the tenant doesn't have
+	ErrKeyNotFound = errors.New("data key version not found")
+)
+
+// Config contains envelope encryption settings
+type Config struct {
+	// CacheTTL bounds how long unwrapped keys are kept in memory. It is also how long another
+	// instance can keep decrypting after a tenant is shredded.
+	CacheTTL time.Duration `json:"cache_ttl"`
+}
+
+// DefaultConfig returns the default envelope encryption settings
+func DefaultConfig() *Config {
+	return &Config{
+		CacheTTL: 10 * time.Minute,
+	}
+}
+
+// Envelope encrypts tenant data with per-tenant data keys, which are themselves wrapped by a key
+// encryption key and stored in a KeyStore. A tenant's first key is created on first use.
+type Envelope struct {
+	store   KeyStore
+	wrapper KeyWrapper
+	config  *Config
+	now     func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]*tenantKeys
+}
+
+// tenantKeys is the cached key state of one tenant
+type tenantKeys struct {
+	records  map[int64]*models.TenantDataKey
+	aeads    map[int64]cipher.AEAD
+	active   int64
+	loadedAt time.Time
+}
+
+// NewEnvelope creates an envelope encryptor
+func NewEnvelope(store KeyStore, wrapper KeyWrapper, config *Config) *Envelope {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Envelope{
+		store:   store,
+		wrapper: wrapper,
+		config:  config,
+		now:     time.Now,
+		cache:   make(map[string]*tenantKeys),
+	}
+}
+
+// NewEnvelopeFromConfig creates the envelope encryptor configured for a service, or nil when
+// encryption is disabled
+func NewEnvelopeFromConfig(ctx context.Context, cfg *config.Config, store KeyStore) (*Envelope, error) {
+	if !cfg.EncryptionEnabled {
+		return nil, nil
+	}
+
+	wrapper, err := NewKeyWrapper(ctx, cfg.EncryptionKMSKey, cfg.EncryptionLocalMasterKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key wrapper: %w", err)
+	}
+
+	envelopeConfig := DefaultConfig()
+	if cfg.EncryptionKeyCacheSeconds > 0 {
+		envelopeConfig.CacheTTL = time.Duration(cfg.EncryptionKeyCacheSeconds) * time.Second
+	}
+	return NewEnvelope(store, wrapper, envelopeConfig), nil
+}
+
+// IsEncrypted reports whether data is in the blob ciphertext format
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(blobMagic))
+}
+
+// IsEncryptedField reports whether a string is in the field ciphertext format
+func IsEncryptedField(value string) bool {
+	return strings.HasPrefix(value, fieldPrefix)
+}
+
+// Encrypt encrypts data with the tenant's active key
+func (e *Envelope) Encrypt(ctx context.Context, tenantID, purpose string, plaintext []byte) ([]byte, error) {
+	version, aead, err := e.activeKey(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, headerSize+aead.NonceSize(), headerSize+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	copy(out, blobMagic)
+	binary.BigEndian.PutUint32(out[len(blobMagic):], uint32(version))
+	nonce := out[headerSize:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return aead.Seal(out, nonce, plaintext, additionalData(tenantID, purpose)), nil
+}
+
+// Decrypt decrypts data written by Encrypt. Legacy plaintext is returned unchanged.
+func (e *Envelope) Decrypt(ctx context.Context, tenantID, purpose string, data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	if len(data) < headerSize {
+		return nil, fmt.Errorf("failed to decrypt: ciphertext too short")
+	}
+
+	version := int64(binary.BigEndian.Uint32(data[len(blobMagic):]))
+	aead, err := e.key(ctx, tenantID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	body := data[headerSize:]
+	if len(body) < aead.NonceSize() {
+		return nil, fmt.Errorf("failed to decrypt: ciphertext too short")
+	}
+	plaintext, err := aead.Open(nil, body[:aead.NonceSize()], body[aead.NonceSize():], additionalData(tenantID, purpose))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+// EncryptField encrypts a string column value
+func (e *Envelope) EncryptField(ctx context.Context, tenantID, purpose, value string) (string, error) {
+	ciphertext, err := e.Encrypt(ctx, tenantID, purpose, []byte(value))
+	if err != nil {
+		return "", err
+	}
+	return fieldPrefix + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// DecryptField decrypts a string column value. Legacy plaintext is returned unchanged.
+func (e *Envelope) DecryptField(ctx context.Context, tenantID, purpose, value string) (string, error) {
+	if !IsEncryptedField(value) {
+		return value, nil
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, fieldPrefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode encrypted field: %w", err)
+	}
+	plaintext, err := e.Decrypt(ctx, tenantID, purpose, ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// TenantKeys lists a tenant's key versions
+func (e *Envelope) TenantKeys(ctx context.Context, tenantID string) ([]*models.TenantDataKey, error) {
+	keys, err := e.store.ListTenantKeys(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RotateTenantKey creates a new active key version for a tenant. Earlier versions are retired but
+// kept, so existing data still decrypts; new data uses the new version.
+func (e *Envelope) RotateTenantKey(ctx context.Context, tenantID string) (*models.TenantDataKey, error) {
+	defer e.evict(tenantID)
+
+	keys, err := e.store.ListTenantKeys(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant keys: %w", err)
+	}
+	if shredded(keys) {
+		return nil, ErrTenantShredded
+	}
+
+	var updated []*models.TenantDataKey
+	for _, key := range keys {
+		if key.State == models.DataKeyStateActive {
+			key.State = models.DataKeyStateRetired
+			updated = append(updated, key)
+		}
+	}
+
+	created, err := e.newKey(ctx, tenantID, nextVersion(keys))
+	if err != nil {
+		return nil, err
+	}
+	if err := e.store.SaveTenantKeys(ctx, []*models.TenantDataKey{created}, updated); err != nil {
+		return nil, fmt.Errorf("failed to save rotated key: %w", err)
+	}
+
+	return created, nil
+}
+
+// RewrapTenantKeys re-wraps a tenant's live key versions with the current key encryption key, e.g.
+// after the KMS key has been rotated. The data itself is not re-encrypted.
+func (e *Envelope) RewrapTenantKeys(ctx context.Context, tenantID string) (int, error) {
+	defer e.evict(tenantID)
+
+	keys, err := e.store.ListTenantKeys(ctx, tenantID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list tenant keys: %w", err)
+	}
+
+	var updated []*models.TenantDataKey
+	for _, key := range keys {
+		if key.State == models.DataKeyStateDestroyed {
+			continue
+		}
+		raw, err := e.unwrap(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		wrapped, err := e.wrapper.WrapKey(ctx, tenantID, raw)
+		if err != nil {
+			return 0, err
+		}
+		key.WrappedKey = wrapped
+		key.KEKName = e.wrapper.Name()
+		updated = append(updated, key)
+	}
+
+	if err := e.store.SaveTenantKeys(ctx, nil, updated); err != nil {
+		return 0, fmt.Errorf("failed to save rewrapped keys: %w", err)
+	}
+	return len(updated), nil
+}
+
+// ShredTenant destroys every data key of a tenant, making all of its encrypted recordings and
+// columns permanently unreadable. Later writes for the tenant are refused.
+func (e *Envelope) ShredTenant(ctx context.Context, tenantID string) error {
+	defer e.evict(tenantID)
+
+	keys, err := e.store.ListTenantKeys(ctx, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to list tenant keys: %w", err)
+	}
+
+	now := e.now().UTC()
+	var created, updated []*models.TenantDataKey
+	for _, key := range keys {
+		if key.State == models.DataKeyStateDestroyed {
+			continue
+		}
+		key.State = models.DataKeyStateDestroyed
+		key.WrappedKey = nil
+		key.DestroyedAt = &now
+		updated = append(updated, key)
+	}
+	if len(keys) == 0 {
+		// Leave a tombstone so a later write can't quietly create a fresh key
+		created = append(created, &models.TenantDataKey{
+			TenantID:    tenantID,
+			KeyVersion:  1,
+			KEKName:     e.wrapper.Name(),
+			State:       models.DataKeyStateDestroyed,
+			CreatedAt:   now,
+			DestroyedAt: &now,
+		})
+	}
+
+	if err := e.store.SaveTenantKeys(ctx, created, updated); err != nil {
+		return fmt.Errorf("failed to destroy tenant keys: %w", err)
+	}
+	return nil
+}
+
+// activeKey returns the tenant's active key version, creating the first one if needed
+func (e *Envelope) activeKey(ctx context.Context, tenantID string) (int64, cipher.AEAD, error) {
+	tk, err := e.load(ctx, tenantID, false)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if tk.active == 0 {
+		if len(tk.records) > 0 && shreddedRecords(tk.records) {
+			return 0, nil, ErrTenantShredded
+		}
+		created, err := e.newKey(ctx, tenantID, 1)
+		if err != nil {
+			return 0, nil, err
+		}
+		// Another instance may have created the first key concurrently; use whichever won
+		if err := e.store.SaveTenantKeys(ctx, []*models.TenantDataKey{created}, nil); err != nil && !errors.Is(err, ErrKeyExists) {
+			return 0, nil, fmt.Errorf("failed to save data key: %w", err)
+		}
+		if tk, err = e.load(ctx, tenantID, true); err != nil {
+			return 0, nil, err
+		}
+		if tk.active == 0 {
+			return 0, nil, fmt.Errorf("tenant %s has no active data key", tenantID)
+		}
+	}
+
+	aead, err := e.key(ctx, tenantID, tk.active)
+	if err != nil {
+		return 0, nil, err
+	}
+	return tk.active, aead, nil
+}
+
+// key returns the cipher for a key version, reloading once in case it was created elsewhere
+func (e *Envelope) key(ctx context.Context, tenantID string, version int64) (cipher.AEAD, error) {
+	tk, err := e.load(ctx, tenantID, false)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := tk.records[version]; !ok {
+		if tk, err = e.load(ctx, tenantID, true); err != nil {
+			return nil, err
+		}
+	}
+
+	e.mu.Lock()
+	aead, ok := tk.aeads[version]
+	record := tk.records[version]
+	e.mu.Unlock()
+	if ok {
+		return aead, nil
+	}
+	if record == nil {
+		return nil, fmt.Errorf("%w: tenant %s version %d", ErrKeyNotFound, tenantID, version)
+	}
+	if record.State == models.DataKeyStateDestroyed {
+		return nil, ErrTenantShredded
+	}
+
+	raw, err := e.unwrap(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+	aead, err = newGCM(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	tk.aeads[version] = aead
+	e.mu.Unlock()
+	return aead, nil
+}
+
+// load returns the cached key state of a tenant, reading the store when it is stale or forced
+func (e *Envelope) load(ctx context.Context, tenantID string, force bool) (*tenantKeys, error) {
+	e.mu.Lock()
+	tk, ok := e.cache[tenantID]
+	e.mu.Unlock()
+	if ok && !force && e.now().Sub(tk.loadedAt) < e.config.CacheTTL {
+		return tk, nil
+	}
+
+	keys, err := e.store.ListTenantKeys(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tenant keys: %w", err)
+	}
+
+	tk = &tenantKeys{
+		records:  make(map[int64]*models.TenantDataKey, len(keys)),
+		aeads:    make(map[int64]cipher.AEAD),
+		loadedAt: e.now(),
+	}
+	for _, key := range keys {
+		tk.records[key.KeyVersion] = key
+		if key.State == models.DataKeyStateActive && key.KeyVersion > tk.active {
+			tk.active = key.KeyVersion
+		}
+	}
+
+	e.mu.Lock()
+	e.cache[tenantID] = tk
+	e.mu.Unlock()
+	return tk, nil
+}
+
+// newKey generates and wraps a new data key version
+func (e *Envelope) newKey(ctx context.Context, tenantID string, version int64) (*models.TenantDataKey, error) {
+	raw := make([]byte, dataKeySize)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	wrapped, err := e.wrapper.WrapKey(ctx, tenantID, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TenantDataKey{
+		TenantID:   tenantID,
+		KeyVersion: version,
+		WrappedKey: wrapped,
+		KEKName:    e.wrapper.Name(),
+		State:      models.DataKeyStateActive,
+		CreatedAt:  e.now().UTC(),
+	}, nil
+}
+
+// unwrap unwraps a stored key, checking it was wrapped by the configured key encryption key
+func (e *Envelope) unwrap(ctx context.Context, key *models.TenantDataKey) ([]byte, error) {
+	if key.KEKName != "" && key.KEKName != e.wrapper.Name() {
+		return nil, fmt.Errorf("data key %s/%d was wrapped by %s, not the configured %s",
+			key.TenantID, key.KeyVersion, key.KEKName, e.wrapper.Name())
+	}
+	return e.wrapper.UnwrapKey(ctx, key.TenantID, key.WrappedKey)
+}
+
+// evict drops a tenant's cached keys
+func (e *Envelope) evict(tenantID string) {
+	e.mu.Lock()
+	delete(e.cache, tenantID)
+	e.mu.Unlock()
+}
+
+// additionalData binds a ciphertext to its tenant and purpose
+func additionalData(tenantID, purpose string) []byte {
+	return []byte(tenantID + "\x00" + purpose)
+}
+
+// nextVersion returns the version after the newest existing one
+func nextVersion(keys []*models.TenantDataKey) int64 {
+	var max int64
+	for _, key := range keys {
+		if key.KeyVersion > max {
+			max = key.KeyVersion
+		}
+	}
+	return max + 1
+}
+
+// shredded reports whether every key version of a tenant has been destroyed
+func shredded(keys []*models.TenantDataKey) bool {
+	if len(keys) == 0 {
+		return false
+	}
+	for _, key := range keys {
+		if key.State != models.DataKeyStateDestroyed {
+			return false
+		}
+	}
+	return true
+}
+
+// shreddedRecords is shredded for the cached key map
+func shreddedRecords(records map[int64]*models.TenantDataKey) bool {
+	keys := make([]*models.TenantDataKey, 0, len(records))
+	for _, key := range records {
+		keys = append(keys, key)
+	}
+	return shredded(keys)
+}
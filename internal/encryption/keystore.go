@@ -0,0 +1,70 @@
+package encryption
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// ErrKeyExists is returned when a data key version already exists, e.g. when two instances create a
+// tenant's first key at the same time
+var ErrKeyExists = errors.New("data key version already exists")
+
+// KeyStore persists wrapped tenant data keys
+type KeyStore interface {
+	// ListTenantKeys returns every key version of a tenant, oldest first
+	ListTenantKeys(ctx context.Context, tenantID string) ([]*models.TenantDataKey, error)
+	// SaveTenantKeys inserts the created keys and updates the changed ones in one transaction,
+	// returning ErrKeyExists if a created version already exists
+	SaveTenantKeys(ctx context.Context, created, updated []*models.TenantDataKey) error
+}
+
+// MemoryKeyStore is an in-process KeyStore for tests and single-instance development
+type MemoryKeyStore struct {
+	mu   sync.Mutex
+	keys map[string]map[int64]models.TenantDataKey
+}
+
+// NewMemoryKeyStore creates an empty in-memory key store
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{keys: make(map[string]map[int64]models.TenantDataKey)}
+}
+
+// ListTenantKeys returns copies of a tenant's key versions, oldest first
+func (s *MemoryKeyStore) ListTenantKeys(ctx context.Context, tenantID string) ([]*models.TenantDataKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []*models.TenantDataKey
+	for _, key := range s.keys[tenantID] {
+		k := key
+		k.WrappedKey = append([]byte(nil), key.WrappedKey...)
+		keys = append(keys, &k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].KeyVersion < keys[j].KeyVersion })
+	return keys, nil
+}
+
+// SaveTenantKeys stores key versions atomically
+func (s *MemoryKeyStore) SaveTenantKeys(ctx context.Context, created, updated []*models.TenantDataKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range created {
+		if _, ok := s.keys[key.TenantID][key.KeyVersion]; ok {
+			return ErrKeyExists
+		}
+	}
+	for _, key := range append(append([]*models.TenantDataKey(nil), created...), updated...) {
+		if s.keys[key.TenantID] == nil {
+			s.keys[key.TenantID] = make(map[int64]models.TenantDataKey)
+		}
+		k := *key
+		k.WrappedKey = append([]byte(nil), key.WrappedKey...)
+		s.keys[key.TenantID][key.KeyVersion] = k
+	}
+	return nil
+}
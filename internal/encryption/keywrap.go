@@ -0,0 +1,162 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"google.golang.org/api/cloudkms/v1"
+)
+
+// KeyWrapper wraps and unwraps tenant data keys with a key encryption key
+type KeyWrapper interface {
+	// Name identifies the key encryption key, recorded with every wrapped key
+	Name() string
+	WrapKey(ctx context.Context, tenantID string, key []byte) ([]byte, error)
+	UnwrapKey(ctx context.Context, tenantID string, wrapped []byte) ([]byte, error)
+}
+
+// KMSKeyWrapper wraps data keys with a Cloud KMS symmetric key. KMS picks the primary key version
+// when wrapping and the right version when unwrapping, so KMS key rotation needs no changes here.
+type KMSKeyWrapper struct {
+	service *cloudkms.Service
+	keyName string // projects/*/locations/*/keyRings/*/cryptoKeys/*
+}
+
+// NewKMSKeyWrapper creates a key wrapper for a Cloud KMS key
+func NewKMSKeyWrapper(ctx context.Context, keyName string) (*KMSKeyWrapper, error) {
+	service, err := cloudkms.NewService(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kms client: %w", err)
+	}
+
+	return &KMSKeyWrapper{
+		service: service,
+		keyName: keyName,
+	}, nil
+}
+
+// Name returns the KMS key resource name
+func (k *KMSKeyWrapper) Name() string {
+	return k.keyName
+}
+
+// WrapKey encrypts a data key with the KMS key, bound to the tenant
+func (k *KMSKeyWrapper) WrapKey(ctx context.Context, tenantID string, key []byte) ([]byte, error) {
+	resp, err := k.service.Projects.Locations.KeyRings.CryptoKeys.Encrypt(k.keyName, &cloudkms.EncryptRequest{
+		Plaintext:                   base64.StdEncoding.EncodeToString(key),
+		AdditionalAuthenticatedData: base64.StdEncoding.EncodeToString([]byte(tenantID)),
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(resp.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode wrapped data key: %w", err)
+	}
+	return wrapped, nil
+}
+
+// UnwrapKey decrypts a data key with the KMS key
+func (k *KMSKeyWrapper) UnwrapKey(ctx context.Context, tenantID string, wrapped []byte) ([]byte, error) {
+	resp, err := k.service.Projects.Locations.KeyRings.CryptoKeys.Decrypt(k.keyName, &cloudkms.DecryptRequest{
+		Ciphertext:                  base64.StdEncoding.EncodeToString(wrapped),
+		AdditionalAuthenticatedData: base64.StdEncoding.EncodeToString([]byte(tenantID)),
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(resp.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode data key: %w", err)
+	}
+	return key, nil
+}
+
+// LocalKeyWrapper wraps data keys with a master key held in configuration. It is meant for
+// development and on-prem installs without KMS.
+type LocalKeyWrapper struct {
+	aead cipher.AEAD
+	name string
+}
+
+// NewLocalKeyWrapper creates a key wrapper from a base64-encoded 32-byte master key
+func NewLocalKeyWrapper(masterKey string) (*LocalKeyWrapper, error) {
+	key, err := base64.StdEncoding.DecodeString(masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode master key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("master key must be 32 bytes, got %d", len(key))
+	}
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Name the key by a fingerprint so wrapped keys record which master key to use
+	sum := sha256.Sum256(key)
+	return &LocalKeyWrapper{
+		aead: aead,
+		name: "local:" + hex.EncodeToString(sum[:8]),
+	}, nil
+}
+
+// Name returns a fingerprint of the master key
+func (l *LocalKeyWrapper) Name() string {
+	return l.name
+}
+
+// WrapKey encrypts a data key with the master key, bound to the tenant
+func (l *LocalKeyWrapper) WrapKey(ctx context.Context, tenantID string, key []byte) ([]byte, error) {
+	nonce := make([]byte, l.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return l.aead.Seal(nonce, nonce, key, []byte(tenantID)), nil
+}
+
+// UnwrapKey decrypts a data key with the master key
+func (l *LocalKeyWrapper) UnwrapKey(ctx context.Context, tenantID string, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < l.aead.NonceSize() {
+		return nil, fmt.Errorf("failed to unwrap data key: wrapped key too short")
+	}
+	nonce, sealed := wrapped[:l.aead.NonceSize()], wrapped[l.aead.NonceSize():]
+	key, err := l.aead.Open(nil, nonce, sealed, []byte(tenantID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return key, nil
+}
+
+// newGCM creates an AES-GCM cipher for a 256-bit key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcm: %w", err)
+	}
+	return aead, nil
+}
+
+// NewKeyWrapper returns a KMS key wrapper when a KMS key is configured and a local one otherwise
+func NewKeyWrapper(ctx context.Context, kmsKeyName, localMasterKey string) (KeyWrapper, error) {
+	if kmsKeyName != "" {
+		return NewKMSKeyWrapper(ctx, kmsKeyName)
+	}
+	if localMasterKey != "" {
+		return NewLocalKeyWrapper(localMasterKey)
+	}
+	return nil, fmt.Errorf("encryption requires a KMS key or a local master key")
+}
@@ -0,0 +1,143 @@
+// Package migrations holds the versioned Spanner schema shipped with the code.
+//
+// Each file in sql/ is one migration named NNNN_description.sql. Versions start
+// at 1 and increase by one; a migration is never edited once released, schema
+// changes are added as a new file instead.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed sql/*.sql
+var embedded embed.FS
+
+// ErrSchemaOutdated is returned when the database is behind the schema the code expects
+var ErrSchemaOutdated = errors.New("database schema is outdated")
+
+// Migration is one versioned set of DDL statements
+type Migration struct {
+	Version    int
+	Name       string
+	Statements []string
+}
+
+// VersionReader reports the schema version a database has been migrated to
+type VersionReader interface {
+	SchemaVersion(ctx context.Context) (int, error)
+}
+
+// All returns the migrations shipped with the code, in version order
+func All() ([]Migration, error) {
+	sub, err := fs.Sub(embedded, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedded migrations: %w", err)
+	}
+	return Load(sub)
+}
+
+// Latest returns the version of the newest migration shipped with the code
+func Latest() (int, error) {
+	all, err := All()
+	if err != nil {
+		return 0, err
+	}
+	if len(all) == 0 {
+		return 0, nil
+	}
+	return all[len(all)-1].Version, nil
+}
+
+// Load reads the *.sql migrations at the root of fsys, in version order
+func Load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	var migrations []Migration
+	for _, file := range files {
+		version, name, err := parseFileName(file)
+		if err != nil {
+			return nil, err
+		}
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+		statements := splitStatements(string(content))
+		if len(statements) == 0 {
+			return nil, fmt.Errorf("migration %s has no statements", file)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, Statements: statements})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must run 1..n without gaps or duplicates, found %04d_%s at position %d", m.Version, m.Name, i+1)
+		}
+	}
+
+	return migrations, nil
+}
+
+// Verify refuses a database whose schema is older than the code's latest migration.
+// A newer schema is accepted so a rollback of the code doesn't need a schema rollback.
+func Verify(ctx context.Context, reader VersionReader) error {
+	latest, err := Latest()
+	if err != nil {
+		return err
+	}
+
+	current, err := reader.SchemaVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if current < latest {
+		return fmt.Errorf("%w: database is at version %d but the code requires %d, run the migrate command", ErrSchemaOutdated, current, latest)
+	}
+
+	return nil
+}
+
+// parseFileName splits NNNN_description.sql into its version and description
+func parseFileName(file string) (int, string, error) {
+	base := strings.TrimSuffix(path.Base(file), ".sql")
+	prefix, name, ok := strings.Cut(base, "_")
+	if !ok || name == "" {
+		return 0, "", fmt.Errorf("migration %s must be named NNNN_description.sql", file)
+	}
+	version, err := strconv.Atoi(prefix)
+	if err != nil || version <= 0 {
+		return 0, "", fmt.Errorf("migration %s must start with a positive version number", file)
+	}
+	return version, name, nil
+}
+
+// splitStatements strips -- comments and splits a file into its ;-terminated statements
+func splitStatements(content string) []string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "--"); i >= 0 {
+			line = line[:i]
+		}
+		lines = append(lines, strings.TrimRight(line, " \t\r"))
+	}
+
+	var statements []string
+	for _, statement := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if statement = strings.TrimSpace(statement); statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
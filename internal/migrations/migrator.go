@@ -0,0 +1,253 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// schemaMigrationsDDL creates the table recording which migrations have been applied
+const schemaMigrationsDDL = `CREATE TABLE schema_migrations (
+  version INT64 NOT NULL,
+  name STRING(MAX) NOT NULL,
+  applied_at TIMESTAMP NOT NULL OPTIONS (allow_commit_timestamp = true),
+) PRIMARY KEY (version)`
+
+// Migrator applies pending migrations to a Spanner database or the Spanner emulator
+type Migrator struct {
+	admin      *database.DatabaseAdminClient
+	client     *spanner.Client
+	database   string
+	migrations []Migration
+}
+
+// NewMigrator creates a migrator for the database at projects/*/instances/*/databases/*.
+// Both clients honor SPANNER_EMULATOR_HOST.
+func NewMigrator(ctx context.Context, databasePath string) (*Migrator, error) {
+	migrations, err := All()
+	if err != nil {
+		return nil, err
+	}
+
+	admin, err := database.NewDatabaseAdminClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create database admin client: %w", err)
+	}
+
+	client, err := spanner.NewClient(ctx, databasePath)
+	if err != nil {
+		admin.Close()
+		return nil, fmt.Errorf("failed to create spanner client: %w", err)
+	}
+
+	return &Migrator{
+		admin:      admin,
+		client:     client,
+		database:   databasePath,
+		migrations: migrations,
+	}, nil
+}
+
+// Close closes the admin and data clients
+func (m *Migrator) Close() {
+	m.client.Close()
+	m.admin.Close()
+}
+
+// EnsureDatabase creates the database if it doesn't exist yet, typically on the emulator
+func (m *Migrator) EnsureDatabase(ctx context.Context) (bool, error) {
+	_, err := m.admin.GetDatabase(ctx, &databasepb.GetDatabaseRequest{Name: m.database})
+	if err == nil {
+		return false, nil
+	}
+	if status.Code(err) != codes.NotFound {
+		return false, fmt.Errorf("failed to get database: %w", err)
+	}
+
+	instance, name, ok := strings.Cut(m.database, "/databases/")
+	if !ok {
+		return false, fmt.Errorf("invalid database path %q", m.database)
+	}
+	op, err := m.admin.CreateDatabase(ctx, &databasepb.CreateDatabaseRequest{
+		Parent:          instance,
+		CreateStatement: "CREATE DATABASE `" + name + "`",
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to create database: %w", err)
+	}
+	if _, err := op.Wait(ctx); err != nil {
+		return false, fmt.Errorf("failed to create database: %w", err)
+	}
+
+	return true, nil
+}
+
+// Version returns the latest applied migration version, 0 for an unmigrated database
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	return ReadVersion(ctx, m.client)
+}
+
+// Pending returns the migrations newer than the database's version
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	current, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return PendingAfter(m.migrations, current), nil
+}
+
+// Up applies every pending migration in order and returns the ones applied
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	if err := m.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range pending {
+		if err := m.updateDDL(ctx, migration.Statements); err != nil {
+			return applied, fmt.Errorf("failed to apply migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		if err := m.record(ctx, migration); err != nil {
+			return applied, err
+		}
+		applied = append(applied, migration)
+	}
+
+	return applied, nil
+}
+
+// Baseline marks migrations up to version as applied without running them,
+// for databases whose schema was created before migrations were tracked
+func (m *Migrator) Baseline(ctx context.Context, version int) error {
+	if version < 1 || version > len(m.migrations) {
+		return fmt.Errorf("baseline version must be between 1 and %d", len(m.migrations))
+	}
+	if err := m.ensureMigrationsTable(ctx); err != nil {
+		return err
+	}
+
+	current, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if current != 0 {
+		return fmt.Errorf("database is already at version %d, baseline only applies to untracked databases", current)
+	}
+
+	for _, migration := range m.migrations[:version] {
+		if err := m.record(ctx, migration); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureMigrationsTable creates schema_migrations on first use
+func (m *Migrator) ensureMigrationsTable(ctx context.Context) error {
+	exists, err := migrationsTableExists(ctx, m.client)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	if err := m.updateDDL(ctx, []string{schemaMigrationsDDL}); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// updateDDL runs DDL statements and waits for the schema change to finish
+func (m *Migrator) updateDDL(ctx context.Context, statements []string) error {
+	op, err := m.admin.UpdateDatabaseDdl(ctx, &databasepb.UpdateDatabaseDdlRequest{
+		Database:   m.database,
+		Statements: statements,
+	})
+	if err != nil {
+		return err
+	}
+	return op.Wait(ctx)
+}
+
+// record marks a migration as applied
+func (m *Migrator) record(ctx context.Context, migration Migration) error {
+	_, err := m.client.Apply(ctx, []*spanner.Mutation{
+		spanner.Insert("schema_migrations",
+			[]string{"version", "name", "applied_at"},
+			[]interface{}{int64(migration.Version), migration.Name, spanner.CommitTimestamp},
+		),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// ReadVersion returns the latest migration version recorded in a database, 0 when none is
+func ReadVersion(ctx context.Context, client *spanner.Client) (int, error) {
+	exists, err := migrationsTableExists(ctx, client)
+	if err != nil || !exists {
+		return 0, err
+	}
+
+	iter := client.Single().Query(ctx, spanner.Statement{
+		SQL: `SELECT IFNULL(MAX(version), 0) FROM schema_migrations`,
+	})
+	defer iter.Stop()
+
+	row, err := iter.Next()
+	if err != nil {
+		return 0, fmt.Errorf("failed to query schema version: %w", err)
+	}
+
+	var version int64
+	if err := row.Columns(&version); err != nil {
+		return 0, fmt.Errorf("failed to scan schema version: %w", err)
+	}
+
+	return int(version), nil
+}
+
+// migrationsTableExists checks the information schema for schema_migrations
+func migrationsTableExists(ctx context.Context, client *spanner.Client) (bool, error) {
+	iter := client.Single().Query(ctx, spanner.Statement{
+		SQL: `SELECT COUNT(*) FROM information_schema.tables
+		      WHERE table_catalog = '' AND table_schema = '' AND table_name = 'schema_migrations'`,
+	})
+	defer iter.Stop()
+
+	row, err := iter.Next()
+	if err != nil {
+		return false, fmt.Errorf("failed to query information schema: %w", err)
+	}
+
+	var count int64
+	if err := row.Columns(&count); err != nil {
+		return false, fmt.Errorf("failed to scan information schema count: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// PendingAfter returns the migrations newer than version
+func PendingAfter(migrations []Migration, version int) []Migration {
+	var pending []Migration
+	for _, migration := range migrations {
+		if migration.Version > version {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
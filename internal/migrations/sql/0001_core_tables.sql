@@ -0,0 +1,90 @@
+-- Tenants, their offices and the requests ingested for them
+
+CREATE TABLE tenants (
+  tenant_id STRING(64) NOT NULL,
+  status STRING(32) NOT NULL,
+  created_at TIMESTAMP,
+  updated_at TIMESTAMP,
+) PRIMARY KEY (tenant_id);
+
+CREATE TABLE offices (
+  tenant_id STRING(64) NOT NULL,
+  office_id STRING(64) NOT NULL,
+  callrail_company_id STRING(64) NOT NULL,
+  callrail_api_key STRING(MAX) NOT NULL,
+  workflow_config STRING(MAX),
+  status STRING(32) NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+) PRIMARY KEY (tenant_id, office_id);
+
+CREATE INDEX offices_by_callrail_company ON offices(callrail_company_id, tenant_id);
+
+CREATE TABLE requests (
+  request_id STRING(64) NOT NULL,
+  tenant_id STRING(64) NOT NULL,
+  source STRING(32) NOT NULL,
+  request_type STRING(32) NOT NULL,
+  status STRING(32) NOT NULL,
+  data STRING(MAX) NOT NULL,
+  ai_normalized STRING(MAX) NOT NULL,
+  ai_extracted STRING(MAX) NOT NULL,
+  call_id STRING(64),
+  recording_url STRING(MAX),
+  transcription_data STRING(MAX),
+  ai_analysis STRING(MAX),
+  lead_score INT64,
+  communication_mode STRING(32),
+  spam_likelihood FLOAT64,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+) PRIMARY KEY (request_id);
+
+CREATE INDEX requests_by_tenant_created ON requests(tenant_id, created_at DESC);
+
+CREATE INDEX requests_by_call ON requests(call_id);
+
+CREATE TABLE call_recordings (
+  recording_id STRING(64) NOT NULL,
+  tenant_id STRING(64) NOT NULL,
+  call_id STRING(64) NOT NULL,
+  storage_url STRING(MAX) NOT NULL,
+  transcription_status STRING(32) NOT NULL,
+  transcription_data STRING(MAX),
+  created_at TIMESTAMP NOT NULL,
+) PRIMARY KEY (recording_id);
+
+CREATE INDEX call_recordings_by_tenant_created ON call_recordings(tenant_id, created_at);
+
+CREATE TABLE webhook_events (
+  event_id STRING(64) NOT NULL,
+  webhook_source STRING(32) NOT NULL,
+  call_id STRING(64),
+  processing_status STRING(32) NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+) PRIMARY KEY (event_id);
+
+CREATE TABLE ai_processing_logs (
+  log_id STRING(64) NOT NULL,
+  tenant_id STRING(64) NOT NULL,
+  request_id STRING(64) NOT NULL,
+  analysis_type STRING(64) NOT NULL,
+  status STRING(32) NOT NULL,
+  processing_data STRING(MAX),
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+) PRIMARY KEY (log_id);
+
+CREATE INDEX ai_processing_logs_by_request ON ai_processing_logs(tenant_id, request_id);
+
+CREATE TABLE crm_integrations (
+  integration_id STRING(64) NOT NULL,
+  tenant_id STRING(64) NOT NULL,
+  crm_type STRING(32) NOT NULL,
+  config STRING(MAX),
+  status STRING(32) NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+) PRIMARY KEY (integration_id);
+
+CREATE INDEX crm_integrations_by_tenant ON crm_integrations(tenant_id);
@@ -0,0 +1,21 @@
+-- Persistent transcription jobs so restarts and other replicas can resume them
+
+CREATE TABLE transcription_jobs (
+  job_id STRING(64) NOT NULL,
+  tenant_id STRING(64) NOT NULL,
+  call_id STRING(64) NOT NULL,
+  status STRING(32) NOT NULL,
+  progress FLOAT64,
+  operation_name STRING(MAX),
+  job_data STRING(MAX) NOT NULL,
+  result_data STRING(MAX),
+  claimed_by STRING(64),
+  claim_expires_at TIMESTAMP,
+  started_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  ended_at TIMESTAMP,
+) PRIMARY KEY (job_id);
+
+CREATE INDEX transcription_jobs_by_tenant_call ON transcription_jobs(tenant_id, call_id);
+
+CREATE INDEX transcription_jobs_by_status ON transcription_jobs(status, started_at);
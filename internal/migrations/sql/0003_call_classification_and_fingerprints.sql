@@ -0,0 +1,30 @@
+-- Call classification and acoustic fingerprints for duplicate and robocall detection
+
+ALTER TABLE requests ADD COLUMN call_classification STRING(32);
+
+CREATE TABLE recording_fingerprints (
+  recording_id STRING(64) NOT NULL,
+  tenant_id STRING(64) NOT NULL,
+  call_id STRING(64) NOT NULL,
+  digest STRING(64) NOT NULL,
+  fingerprint STRING(MAX) NOT NULL,
+  hop_ms INT64 NOT NULL,
+  duration_ms INT64 NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+) PRIMARY KEY (recording_id);
+
+CREATE INDEX recording_fingerprints_by_tenant_created ON recording_fingerprints(tenant_id, created_at DESC);
+
+CREATE INDEX recording_fingerprints_by_digest ON recording_fingerprints(digest);
+
+CREATE TABLE robocall_fingerprints (
+  fingerprint_id STRING(64) NOT NULL,
+  label STRING(MAX) NOT NULL,
+  digest STRING(64) NOT NULL,
+  fingerprint STRING(MAX) NOT NULL,
+  hop_ms INT64 NOT NULL,
+  duration_ms INT64 NOT NULL,
+  source_tenant_id STRING(64),
+  source_recording_id STRING(64),
+  created_at TIMESTAMP NOT NULL,
+) PRIMARY KEY (fingerprint_id);
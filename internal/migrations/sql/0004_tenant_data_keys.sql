@@ -0,0 +1,11 @@
+-- Per-tenant data keys, wrapped by the KMS key encryption key
+
+CREATE TABLE tenant_data_keys (
+  tenant_id STRING(64) NOT NULL,
+  key_version INT64 NOT NULL,
+  wrapped_key BYTES(MAX),
+  kek_name STRING(MAX) NOT NULL,
+  state STRING(32) NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  destroyed_at TIMESTAMP,
+) PRIMARY KEY (tenant_id, key_version);
@@ -0,0 +1,31 @@
+-- Retention purges, legal holds and the purge audit trail
+
+ALTER TABLE call_recordings ADD COLUMN audio_purged_at TIMESTAMP;
+
+CREATE TABLE legal_holds (
+  hold_id STRING(64) NOT NULL,
+  tenant_id STRING(64) NOT NULL,
+  call_id STRING(64),
+  reason STRING(MAX) NOT NULL,
+  created_by STRING(MAX),
+  created_at TIMESTAMP NOT NULL,
+  released_at TIMESTAMP,
+) PRIMARY KEY (hold_id);
+
+CREATE INDEX legal_holds_by_tenant ON legal_holds(tenant_id, created_at);
+
+CREATE TABLE purge_audit_log (
+  audit_id STRING(64) NOT NULL,
+  tenant_id STRING(64) NOT NULL,
+  call_id STRING(64),
+  request_id STRING(64),
+  recording_id STRING(64),
+  data_class STRING(32) NOT NULL,
+  retention_days INT64 NOT NULL,
+  cutoff TIMESTAMP NOT NULL,
+  objects_deleted INT64 NOT NULL,
+  rows_affected INT64 NOT NULL,
+  purged_at TIMESTAMP NOT NULL,
+) PRIMARY KEY (audit_id);
+
+CREATE INDEX purge_audit_log_by_tenant ON purge_audit_log(tenant_id, purged_at DESC);
@@ -0,0 +1,19 @@
+-- Data subject export and deletion requests; subjects are stored only as hashes
+
+CREATE TABLE data_subject_log (
+  log_id STRING(64) NOT NULL,
+  tenant_id STRING(64) NOT NULL,
+  request_type STRING(32) NOT NULL,
+  subject_hash STRING(64) NOT NULL,
+  requested_by STRING(MAX),
+  status STRING(32) NOT NULL,
+  counts STRING(MAX),
+  retained_calls ARRAY<STRING(64)>,
+  crm_erasures STRING(MAX),
+  verified BOOL NOT NULL,
+  error STRING(MAX),
+  created_at TIMESTAMP NOT NULL,
+  completed_at TIMESTAMP NOT NULL,
+) PRIMARY KEY (log_id);
+
+CREATE INDEX data_subject_log_by_tenant ON data_subject_log(tenant_id, created_at DESC);
@@ -0,0 +1,6 @@
+-- Normalized caller phone numbers, to count a caller's requests across tenants for spam scoring.
+-- Requests created before this migration have no caller phone and are not counted.
+
+ALTER TABLE requests ADD COLUMN caller_phone STRING(32);
+
+CREATE INDEX requests_by_caller_phone ON requests(caller_phone, created_at DESC);
@@ -0,0 +1,1194 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/home-renovators/ingestion-pipeline/internal/encryption"
+	"github.com/home-renovators/ingestion-pipeline/pkg/audio"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+	"github.com/home-renovators/ingestion-pipeline/pkg/privacy"
+	"github.com/home-renovators/ingestion-pipeline/pkg/retention"
+)
+
+// MemoryStore is an in-process Store for tests and local development. It keeps the semantics of
+// the Spanner implementation: reads and updates are scoped to the tenant, inserts of an existing
+// key fail, missing rows wrap ErrNotFound and lists come back in the same order.
+type MemoryStore struct {
+	*audio.MemoryJobStore
+	*encryption.MemoryKeyStore
+
+	mu              sync.RWMutex
+	tenants         map[string]string // tenant ID to status
+	offices         map[string]models.Office
+	requests        map[string]models.Request
+	callerPhones    map[string]string // the caller_phone column, by request ID
+	recordings      map[string]models.CallRecording
+	audioPurgedAt   map[string]time.Time // by recording ID
+	webhookEvents   map[string]models.WebhookEvent
+	processingLogs  map[string]models.AIProcessingLog
+	integrations    map[string]models.CRMIntegration
+	fingerprints    map[string]models.RecordingFingerprint
+	robocalls       map[string]models.RobocallFingerprint
+	legalHolds      map[string]models.LegalHold
+	purgeAudits     []models.PurgeAuditRecord
+	dataSubjectLogs map[string]models.DataSubjectLog
+	envelope        *encryption.Envelope
+	now             func() time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		MemoryJobStore:  audio.NewMemoryJobStore(),
+		MemoryKeyStore:  encryption.NewMemoryKeyStore(),
+		tenants:         make(map[string]string),
+		offices:         make(map[string]models.Office),
+		requests:        make(map[string]models.Request),
+		callerPhones:    make(map[string]string),
+		recordings:      make(map[string]models.CallRecording),
+		audioPurgedAt:   make(map[string]time.Time),
+		webhookEvents:   make(map[string]models.WebhookEvent),
+		processingLogs:  make(map[string]models.AIProcessingLog),
+		integrations:    make(map[string]models.CRMIntegration),
+		fingerprints:    make(map[string]models.RecordingFingerprint),
+		robocalls:       make(map[string]models.RobocallFingerprint),
+		legalHolds:      make(map[string]models.LegalHold),
+		dataSubjectLogs: make(map[string]models.DataSubjectLog),
+		now:             time.Now,
+	}
+}
+
+// WithClock sets the clock used for update timestamps
+func (s *MemoryStore) WithClock(now func() time.Time) *MemoryStore {
+	s.now = now
+	return s
+}
+
+// SetEnvelope encrypts the sensitive request columns with per-tenant data keys, as the Spanner
+// repository does
+func (s *MemoryStore) SetEnvelope(envelope *encryption.Envelope) {
+	s.envelope = envelope
+}
+
+// Close does nothing; it satisfies Repository
+func (s *MemoryStore) Close() {}
+
+// PutTenant adds or replaces a tenant row
+func (s *MemoryStore) PutTenant(tenantID, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tenants[tenantID] = status
+}
+
+// PutOffice adds or replaces an office, along with an active tenant row if the tenant is new
+func (s *MemoryStore) PutOffice(office *models.Office) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offices[office.TenantID+"/"+office.OfficeID] = *office
+	if _, ok := s.tenants[office.TenantID]; !ok {
+		s.tenants[office.TenantID] = "active"
+	}
+}
+
+// PurgeAudits returns the purge audit records of a tenant in the order they were written
+func (s *MemoryStore) PurgeAudits(tenantID string) []models.PurgeAuditRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var audits []models.PurgeAuditRecord
+	for _, audit := range s.purgeAudits {
+		if audit.TenantID == tenantID {
+			audits = append(audits, audit)
+		}
+	}
+	return audits
+}
+
+// GetOfficeByCallRailCompanyID returns the active office of a CallRail company, or nil if there is none
+func (s *MemoryStore) GetOfficeByCallRailCompanyID(ctx context.Context, callRailCompanyID, tenantID string) (*models.Office, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, office := range s.sortedOffices() {
+		if office.TenantID == tenantID && office.CallRailCompanyID == callRailCompanyID && office.Status == "active" {
+			return &office, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetOfficeByTenantID returns the active office of a tenant
+func (s *MemoryStore) GetOfficeByTenantID(ctx context.Context, tenantID string) (*models.Office, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, office := range s.sortedOffices() {
+		if office.TenantID == tenantID && office.Status == "active" {
+			return &office, nil
+		}
+	}
+	return nil, fmt.Errorf("office for tenant %s %w", tenantID, ErrNotFound)
+}
+
+// ListActiveOffices lists the active offices of every tenant, ordered by tenant
+func (s *MemoryStore) ListActiveOffices(ctx context.Context) ([]*models.Office, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var offices []*models.Office
+	for _, office := range s.sortedOffices() {
+		if office.Status == "active" {
+			o := office
+			offices = append(offices, &o)
+		}
+	}
+	return offices, nil
+}
+
+// sortedOffices returns every office ordered by tenant and office ID
+func (s *MemoryStore) sortedOffices() []models.Office {
+	offices := make([]models.Office, 0, len(s.offices))
+	for _, office := range s.offices {
+		offices = append(offices, office)
+	}
+	sort.Slice(offices, func(i, j int) bool {
+		if offices[i].TenantID != offices[j].TenantID {
+			return offices[i].TenantID < offices[j].TenantID
+		}
+		return offices[i].OfficeID < offices[j].OfficeID
+	})
+	return offices
+}
+
+// TenantExists reports whether a tenant exists and is active
+func (s *MemoryStore) TenantExists(ctx context.Context, tenantID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tenants[tenantID] == "active", nil
+}
+
+// CreateRequest inserts a request
+func (s *MemoryStore) CreateRequest(ctx context.Context, req *models.Request) error {
+	stored, err := s.sealRequest(ctx, *req)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.requests[req.RequestID]; ok {
+		return fmt.Errorf("failed to create request: request %s already exists", req.RequestID)
+	}
+	s.requests[req.RequestID] = stored
+	s.setCallerPhone(req)
+	return nil
+}
+
+// CreateRequestWithRecording inserts a request and its call recording, or neither
+func (s *MemoryStore) CreateRequestWithRecording(ctx context.Context, req *models.Request, recording *models.CallRecording) error {
+	storedRequest, err := s.sealRequest(ctx, *req)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.requests[req.RequestID]; ok {
+		return fmt.Errorf("failed to create request with recording: request %s already exists", req.RequestID)
+	}
+	if recording != nil {
+		if _, ok := s.recordings[recording.RecordingID]; ok {
+			return fmt.Errorf("failed to create request with recording: call recording %s already exists", recording.RecordingID)
+		}
+		stored := *recording
+		stored.TranscriptionData = nil // not written on insert
+		s.recordings[recording.RecordingID] = stored
+	}
+	s.requests[req.RequestID] = storedRequest
+	s.setCallerPhone(req)
+	return nil
+}
+
+// GetRequest returns a tenant's request
+func (s *MemoryStore) GetRequest(ctx context.Context, tenantID, requestID string) (*models.Request, error) {
+	s.mu.RLock()
+	req, ok := s.requests[requestID]
+	s.mu.RUnlock()
+
+	if !ok || req.TenantID != tenantID {
+		return nil, fmt.Errorf("request %w", ErrNotFound)
+	}
+	return s.openRequest(ctx, req)
+}
+
+// GetRequestByCallID returns the earliest request of a call, or nil if there is none
+func (s *MemoryStore) GetRequestByCallID(ctx context.Context, callID string) (*models.Request, error) {
+	s.mu.RLock()
+	sorted := s.sortedRequests()
+	s.mu.RUnlock()
+
+	for _, req := range sorted {
+		if req.CallID != nil && *req.CallID == callID {
+			return s.openRequest(ctx, req)
+		}
+	}
+	return nil, nil
+}
+
+// GetRequestsByTenant returns a page of a tenant's requests, newest first
+func (s *MemoryStore) GetRequestsByTenant(ctx context.Context, tenantID string, limit int, offset int) ([]*models.Request, error) {
+	s.mu.RLock()
+	sorted := s.sortedRequests()
+	s.mu.RUnlock()
+
+	var requests []*models.Request
+	for i := len(sorted) - 1; i >= 0; i-- {
+		if sorted[i].TenantID != tenantID {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		if len(requests) >= limit {
+			break
+		}
+		req, err := s.openRequest(ctx, sorted[i])
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	return requests, nil
+}
+
+// UpdateRequestClassification records how a tenant's call was classified and the resulting status
+func (s *MemoryStore) UpdateRequestClassification(ctx context.Context, tenantID, requestID, classification, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	req, ok := s.requests[requestID]
+	if !ok || req.TenantID != tenantID {
+		return fmt.Errorf("failed to update request classification: request %w", ErrNotFound)
+	}
+	req.CallClassification = &classification
+	req.Status = status
+	req.UpdatedAt = s.now().UTC()
+	s.requests[requestID] = req
+	return nil
+}
+
+// UpdateRequestAnalysis stores a request's AI analysis with its lead score and spam likelihood
+func (s *MemoryStore) UpdateRequestAnalysis(ctx context.Context, tenantID, requestID, aiAnalysis string, leadScore *int, spamLikelihood *float64) error {
+	data, err := s.sealField(ctx, tenantID, encryption.PurposeAIAnalysis, &aiAnalysis)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	req, ok := s.requests[requestID]
+	if !ok || req.TenantID != tenantID {
+		return fmt.Errorf("failed to update request analysis: request %w", ErrNotFound)
+	}
+	req.AIAnalysis = data
+	req.LeadScore = clonePtr(leadScore)
+	req.SpamLikelihood = clonePtr(spamLikelihood)
+	req.UpdatedAt = s.now().UTC()
+	s.requests[requestID] = req
+	return nil
+}
+
+// UpdateRequestExtraction stores the contact details extracted from a request's call
+func (s *MemoryStore) UpdateRequestExtraction(ctx context.Context, tenantID, requestID, aiExtracted string) error {
+	aiExtracted, err := s.sealExtracted(ctx, tenantID, aiExtracted)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	req, ok := s.requests[requestID]
+	if !ok || req.TenantID != tenantID {
+		return fmt.Errorf("failed to update request extraction: request %w", ErrNotFound)
+	}
+	req.AIExtracted = aiExtracted
+	req.UpdatedAt = s.now().UTC()
+	s.requests[requestID] = req
+	return nil
+}
+
+// GetCallerActivity counts the requests from a phone number created since a time, across every tenant
+func (s *MemoryStore) GetCallerActivity(ctx context.Context, phone string, since time.Time) (*models.CallerActivity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	activity := &models.CallerActivity{}
+	phone = privacy.NormalizePhone(phone)
+	if phone == "" {
+		return activity, nil
+	}
+
+	tenants := make(map[string]bool)
+	for _, req := range s.requests {
+		if !req.CreatedAt.Before(since) && s.callerPhones[req.RequestID] == phone {
+			activity.Requests++
+			tenants[req.TenantID] = true
+		}
+	}
+	activity.Tenants = len(tenants)
+	return activity, nil
+}
+
+// ListRequestsByCallerPhone lists a tenant's most recent requests from a phone number, newest first
+func (s *MemoryStore) ListRequestsByCallerPhone(ctx context.Context, tenantID, phone string, limit int) ([]*models.Request, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	phone = privacy.NormalizePhone(phone)
+	if phone == "" {
+		return nil, nil
+	}
+
+	var requests []*models.Request
+	sorted := s.sortedRequests()
+	for i := len(sorted) - 1; i >= 0 && (limit <= 0 || len(requests) < limit); i-- {
+		if sorted[i].TenantID != tenantID || s.callerPhones[sorted[i].RequestID] != phone {
+			continue
+		}
+		req, err := s.openRequest(ctx, sorted[i])
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	return requests, nil
+}
+
+// GetRequestCountsByTenant counts a tenant's requests created since a time by communication mode
+func (s *MemoryStore) GetRequestCountsByTenant(ctx context.Context, tenantID string, since time.Time) (map[string]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[string]int64)
+	for _, req := range s.requests {
+		if req.TenantID == tenantID && !req.CreatedAt.Before(since) {
+			counts[req.CommunicationMode]++
+		}
+	}
+	return counts, nil
+}
+
+// GetAverageLeadScoreByTenant averages the scored requests of a tenant created since a time
+func (s *MemoryStore) GetAverageLeadScoreByTenant(ctx context.Context, tenantID string, since time.Time) (float64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var total, count int
+	for _, req := range s.requests {
+		if req.TenantID == tenantID && req.LeadScore != nil && !req.CreatedAt.Before(since) {
+			total += *req.LeadScore
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	return float64(total) / float64(count), nil
+}
+
+// sortedRequests returns every request, oldest first
+func (s *MemoryStore) sortedRequests() []models.Request {
+	requests := make([]models.Request, 0, len(s.requests))
+	for _, req := range s.requests {
+		requests = append(requests, cloneRequest(req))
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		if !requests[i].CreatedAt.Equal(requests[j].CreatedAt) {
+			return requests[i].CreatedAt.Before(requests[j].CreatedAt)
+		}
+		return requests[i].RequestID < requests[j].RequestID
+	})
+	return requests
+}
+
+// CreateCallRecording inserts a call recording
+func (s *MemoryStore) CreateCallRecording(ctx context.Context, recording *models.CallRecording) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.recordings[recording.RecordingID]; ok {
+		return fmt.Errorf("failed to create call recording: call recording %s already exists", recording.RecordingID)
+	}
+	stored := *recording
+	stored.TranscriptionData = nil // not written on insert
+	s.recordings[recording.RecordingID] = stored
+	return nil
+}
+
+// GetCallRecording returns a tenant's call recording, without its transcription
+func (s *MemoryStore) GetCallRecording(ctx context.Context, tenantID, recordingID string) (*models.CallRecording, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	recording, ok := s.recordings[recordingID]
+	if !ok || recording.TenantID != tenantID {
+		return nil, fmt.Errorf("call recording %w", ErrNotFound)
+	}
+	recording.TranscriptionData = nil
+	return &recording, nil
+}
+
+// UpdateCallRecordingStatus updates the transcription status of a tenant's call recording
+func (s *MemoryStore) UpdateCallRecordingStatus(ctx context.Context, tenantID, recordingID, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recording, ok := s.recordings[recordingID]
+	if !ok || recording.TenantID != tenantID {
+		return fmt.Errorf("failed to update call recording status: call recording %w", ErrNotFound)
+	}
+	recording.TranscriptionStatus = status
+	s.recordings[recordingID] = recording
+	return nil
+}
+
+// GetCallRecordingTranscription returns the stored transcription of a tenant's call recording
+func (s *MemoryStore) GetCallRecordingTranscription(ctx context.Context, tenantID, recordingID string) (*models.TranscriptionResult, error) {
+	s.mu.RLock()
+	recording, ok := s.recordings[recordingID]
+	s.mu.RUnlock()
+
+	if !ok || recording.TenantID != tenantID {
+		return nil, fmt.Errorf("call recording %w", ErrNotFound)
+	}
+	if recording.TranscriptionData == nil || *recording.TranscriptionData == "" {
+		return nil, fmt.Errorf("call recording has no transcription")
+	}
+
+	var result models.TranscriptionResult
+	if err := json.Unmarshal([]byte(*recording.TranscriptionData), &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transcription: %w", err)
+	}
+	return &result, nil
+}
+
+// UpdateCallRecordingTranscription stores the transcription of a tenant's call recording
+func (s *MemoryStore) UpdateCallRecordingTranscription(ctx context.Context, tenantID, recordingID string, transcriptionData string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recording, ok := s.recordings[recordingID]
+	if !ok || recording.TenantID != tenantID {
+		return fmt.Errorf("failed to update call recording transcription: call recording %w", ErrNotFound)
+	}
+	recording.TranscriptionData = &transcriptionData
+	s.recordings[recordingID] = recording
+	return nil
+}
+
+// CreateWebhookEvent inserts a webhook event
+func (s *MemoryStore) CreateWebhookEvent(ctx context.Context, event *models.WebhookEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.webhookEvents[event.EventID]; ok {
+		return fmt.Errorf("failed to create webhook event: webhook event %s already exists", event.EventID)
+	}
+	s.webhookEvents[event.EventID] = *event
+	return nil
+}
+
+// UpdateWebhookEventStatus updates the processing status of a webhook event
+func (s *MemoryStore) UpdateWebhookEventStatus(ctx context.Context, eventID, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.webhookEvents[eventID]
+	if !ok {
+		return fmt.Errorf("webhook event %w", ErrNotFound)
+	}
+	event.ProcessingStatus = status
+	s.webhookEvents[eventID] = event
+	return nil
+}
+
+// CreateAIProcessingLog inserts an AI processing log
+func (s *MemoryStore) CreateAIProcessingLog(ctx context.Context, log *models.AIProcessingLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.processingLogs[log.LogID]; ok {
+		return fmt.Errorf("failed to create AI processing log: log %s already exists", log.LogID)
+	}
+	s.processingLogs[log.LogID] = *log
+	return nil
+}
+
+// GetAIProcessingLog returns a tenant's AI processing log
+func (s *MemoryStore) GetAIProcessingLog(ctx context.Context, tenantID, logID string) (*models.AIProcessingLog, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	log, ok := s.processingLogs[logID]
+	if !ok || log.TenantID != tenantID {
+		return nil, fmt.Errorf("AI processing log %w", ErrNotFound)
+	}
+	return &log, nil
+}
+
+// CreateCRMIntegration inserts a CRM integration record
+func (s *MemoryStore) CreateCRMIntegration(ctx context.Context, integration *models.CRMIntegration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.integrations[integration.IntegrationID]; ok {
+		return fmt.Errorf("failed to create CRM integration: integration %s already exists", integration.IntegrationID)
+	}
+	s.integrations[integration.IntegrationID] = *integration
+	return nil
+}
+
+// GetCRMIntegration returns a tenant's CRM integration record
+func (s *MemoryStore) GetCRMIntegration(ctx context.Context, tenantID, integrationID string) (*models.CRMIntegration, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	integration, ok := s.integrations[integrationID]
+	if !ok || integration.TenantID != tenantID {
+		return nil, fmt.Errorf("CRM integration %w", ErrNotFound)
+	}
+	return &integration, nil
+}
+
+// UpdateCRMIntegration updates a CRM integration record, keeping its creation time
+func (s *MemoryStore) UpdateCRMIntegration(ctx context.Context, integration *models.CRMIntegration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.integrations[integration.IntegrationID]
+	if !ok {
+		return fmt.Errorf("CRM integration %w", ErrNotFound)
+	}
+	updated := *integration
+	updated.CreatedAt = existing.CreatedAt
+	s.integrations[integration.IntegrationID] = updated
+	return nil
+}
+
+// SaveRecordingFingerprint inserts or updates the fingerprint of a recording
+func (s *MemoryStore) SaveRecordingFingerprint(ctx context.Context, fp *models.RecordingFingerprint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fingerprints[fp.RecordingID] = *fp
+	return nil
+}
+
+// GetRecordingFingerprint returns the fingerprint of a recording
+func (s *MemoryStore) GetRecordingFingerprint(ctx context.Context, recordingID string) (*models.RecordingFingerprint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fp, ok := s.fingerprints[recordingID]
+	if !ok {
+		return nil, audio.ErrFingerprintNotFound
+	}
+	return &fp, nil
+}
+
+// ListRecordingFingerprints lists the recording fingerprints matching the filter
+func (s *MemoryStore) ListRecordingFingerprints(ctx context.Context, filter audio.FingerprintFilter) ([]*models.RecordingFingerprint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var fps []*models.RecordingFingerprint
+	for _, fp := range s.fingerprints {
+		if filter.Matches(&fp) {
+			f := fp
+			fps = append(fps, &f)
+		}
+	}
+	sort.Slice(fps, func(i, j int) bool { return fps[i].RecordingID < fps[j].RecordingID })
+	return fps, nil
+}
+
+// SaveRobocallFingerprint adds a fingerprint to the global robocall list
+func (s *MemoryStore) SaveRobocallFingerprint(ctx context.Context, fp *models.RobocallFingerprint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.robocalls[fp.FingerprintID] = *fp
+	return nil
+}
+
+// ListRobocallFingerprints lists every robocall fingerprint in key order
+func (s *MemoryStore) ListRobocallFingerprints(ctx context.Context) ([]*models.RobocallFingerprint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var fps []*models.RobocallFingerprint
+	for _, fp := range s.robocalls {
+		f := fp
+		fps = append(fps, &f)
+	}
+	sort.Slice(fps, func(i, j int) bool { return fps[i].FingerprintID < fps[j].FingerprintID })
+	return fps, nil
+}
+
+// CreateLegalHold inserts a legal hold
+func (s *MemoryStore) CreateLegalHold(ctx context.Context, hold *models.LegalHold) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.legalHolds[hold.HoldID]; ok {
+		return fmt.Errorf("failed to create legal hold: hold %s already exists", hold.HoldID)
+	}
+	stored := *hold
+	stored.ReleasedAt = nil
+	s.legalHolds[hold.HoldID] = stored
+	return nil
+}
+
+// ListLegalHolds lists a tenant's unreleased legal holds, oldest first
+func (s *MemoryStore) ListLegalHolds(ctx context.Context, tenantID string) ([]*models.LegalHold, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var holds []*models.LegalHold
+	for _, hold := range s.legalHolds {
+		if hold.TenantID == tenantID && hold.Active() {
+			h := hold
+			holds = append(holds, &h)
+		}
+	}
+	sort.Slice(holds, func(i, j int) bool { return holds[i].CreatedAt.Before(holds[j].CreatedAt) })
+	return holds, nil
+}
+
+// ReleaseLegalHold releases a tenant's active legal hold
+func (s *MemoryStore) ReleaseLegalHold(ctx context.Context, tenantID, holdID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hold, ok := s.legalHolds[holdID]
+	if !ok || hold.TenantID != tenantID || !hold.Active() {
+		return fmt.Errorf("failed to release legal hold: legal hold %w", ErrNotFound)
+	}
+	releasedAt := s.now().UTC()
+	hold.ReleasedAt = &releasedAt
+	s.legalHolds[holdID] = hold
+	return nil
+}
+
+// ListPurgeCandidates lists up to limit calls of a tenant holding data of the class created before
+// the cutoff, oldest first
+func (s *MemoryStore) ListPurgeCandidates(ctx context.Context, tenantID string, class models.DataClass, before time.Time, limit int) ([]retention.Candidate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var candidates []retention.Candidate
+	switch class {
+	case models.DataClassAudio:
+		for _, recording := range s.tenantRecordings(tenantID) {
+			if _, purged := s.audioPurgedAt[recording.RecordingID]; !purged && recording.CreatedAt.Before(before) {
+				candidates = append(candidates, recordingCandidate(recording, ""))
+			}
+		}
+	case models.DataClassTranscript:
+		for _, recording := range s.tenantRecordings(tenantID) {
+			if !recording.CreatedAt.Before(before) {
+				continue
+			}
+			req := s.callRequest(tenantID, recording.CallID)
+			requestID := ""
+			if req != nil {
+				requestID = req.RequestID
+			}
+			jobs, err := s.ListTranscriptionJobs(ctx, audio.JobFilter{TenantID: tenantID, CallID: recording.CallID})
+			if err != nil {
+				return nil, err
+			}
+			if recording.TranscriptionData != nil || (req != nil && req.TranscriptionData != nil) || len(jobs) > 0 {
+				candidates = append(candidates, recordingCandidate(recording, requestID))
+			}
+		}
+	case models.DataClassAIOutput, models.DataClassWebhook:
+		for _, req := range s.sortedRequests() {
+			if req.TenantID != tenantID || !req.CreatedAt.Before(before) {
+				continue
+			}
+			holdsData := req.Data != "{}"
+			if class == models.DataClassAIOutput {
+				holdsData = req.AIAnalysis != nil || req.AIExtracted != "{}" || req.AINormalized != "{}"
+			}
+			if holdsData {
+				candidates = append(candidates, retention.Candidate{
+					TenantID:  tenantID,
+					CallID:    stringValue(req.CallID),
+					RequestID: req.RequestID,
+					CreatedAt: req.CreatedAt,
+				})
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown data class %q", class)
+	}
+
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, nil
+}
+
+// PurgeCallData removes a call's data of the class and records the audit, counting every row the
+// Spanner statements would match
+func (s *MemoryStore) PurgeCallData(ctx context.Context, candidate retention.Candidate, class models.DataClass, audit *models.PurgeAuditRecord) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows int64
+	switch class {
+	case models.DataClassAudio:
+		if recording, ok := s.recordings[candidate.RecordingID]; ok && recording.TenantID == candidate.TenantID {
+			s.audioPurgedAt[recording.RecordingID] = s.now().UTC()
+			rows++
+		}
+	case models.DataClassTranscript:
+		var requestIDs []string
+		for id, recording := range s.recordings {
+			if recording.TenantID == candidate.TenantID && recording.CallID == candidate.CallID {
+				recording.TranscriptionData = nil
+				s.recordings[id] = recording
+				rows++
+			}
+		}
+		for id, req := range s.requests {
+			if req.TenantID == candidate.TenantID && stringValue(req.CallID) == candidate.CallID {
+				req.TranscriptionData = nil
+				s.requests[id] = req
+				requestIDs = append(requestIDs, id)
+				rows++
+			}
+		}
+		deleted, err := s.deleteCallJobs(ctx, candidate.TenantID, []string{candidate.CallID})
+		if err != nil {
+			return 0, fmt.Errorf("failed to purge %s data: %w", class, err)
+		}
+		rows += deleted
+		rows += s.deleteProcessingLogs(candidate.TenantID, requestIDs, func(analysisType string) bool {
+			return analysisType == "transcription"
+		})
+	case models.DataClassAIOutput:
+		if req, ok := s.requests[candidate.RequestID]; ok && req.TenantID == candidate.TenantID {
+			req.AIAnalysis = nil
+			req.AIExtracted = "{}"
+			req.AINormalized = "{}"
+			s.requests[req.RequestID] = req
+			rows++
+		}
+		rows += s.deleteProcessingLogs(candidate.TenantID, []string{candidate.RequestID}, func(analysisType string) bool {
+			return analysisType != "transcription"
+		})
+	case models.DataClassWebhook:
+		if req, ok := s.requests[candidate.RequestID]; ok && req.TenantID == candidate.TenantID {
+			req.Data = "{}"
+			s.requests[req.RequestID] = req
+			delete(s.callerPhones, req.RequestID)
+			rows++
+		}
+		if candidate.CallID != "" {
+			for id, event := range s.webhookEvents {
+				if stringValue(event.CallID) == candidate.CallID {
+					delete(s.webhookEvents, id)
+					rows++
+				}
+			}
+		}
+	default:
+		return 0, fmt.Errorf("unknown data class %q", class)
+	}
+
+	audit.RowsAffected = rows
+	s.purgeAudits = append(s.purgeAudits, *audit)
+	return rows, nil
+}
+
+// tenantRecordings returns a tenant's call recordings, oldest first
+func (s *MemoryStore) tenantRecordings(tenantID string) []models.CallRecording {
+	var recordings []models.CallRecording
+	for _, recording := range s.recordings {
+		if recording.TenantID == tenantID {
+			recordings = append(recordings, recording)
+		}
+	}
+	sort.Slice(recordings, func(i, j int) bool {
+		if !recordings[i].CreatedAt.Equal(recordings[j].CreatedAt) {
+			return recordings[i].CreatedAt.Before(recordings[j].CreatedAt)
+		}
+		return recordings[i].RecordingID < recordings[j].RecordingID
+	})
+	return recordings
+}
+
+// callRequest returns the earliest request of a tenant's call, or nil
+func (s *MemoryStore) callRequest(tenantID, callID string) *models.Request {
+	for _, req := range s.sortedRequests() {
+		if req.TenantID == tenantID && stringValue(req.CallID) == callID {
+			return &req
+		}
+	}
+	return nil
+}
+
+// recordingCandidate returns the purge candidate of a recording
+func recordingCandidate(recording models.CallRecording, requestID string) retention.Candidate {
+	return retention.Candidate{
+		TenantID:    recording.TenantID,
+		CallID:      recording.CallID,
+		RequestID:   requestID,
+		RecordingID: recording.RecordingID,
+		CreatedAt:   recording.CreatedAt,
+	}
+}
+
+// deleteCallJobs deletes the transcription jobs of a tenant's calls
+func (s *MemoryStore) deleteCallJobs(ctx context.Context, tenantID string, callIDs []string) (int64, error) {
+	var deleted int64
+	for _, callID := range callIDs {
+		jobs, err := s.ListTranscriptionJobs(ctx, audio.JobFilter{TenantID: tenantID, CallID: callID})
+		if err != nil {
+			return deleted, err
+		}
+		for _, job := range jobs {
+			if err := s.DeleteTranscriptionJob(ctx, job.JobID); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// deleteProcessingLogs deletes a tenant's AI processing logs of the requests whose analysis type matches
+func (s *MemoryStore) deleteProcessingLogs(tenantID string, requestIDs []string, match func(analysisType string) bool) int64 {
+	var deleted int64
+	for id, log := range s.processingLogs {
+		if log.TenantID == tenantID && containsString(requestIDs, log.RequestID) && match(log.AnalysisType) {
+			delete(s.processingLogs, id)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// FindRequests lists a tenant's requests whose raw or extracted data contains any of the terms
+func (s *MemoryStore) FindRequests(ctx context.Context, tenantID string, terms []string) ([]*models.Request, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var requests []*models.Request
+	for _, stored := range s.sortedRequests() {
+		if stored.TenantID != tenantID {
+			continue
+		}
+		req, err := s.openRequest(ctx, stored)
+		if err != nil {
+			return nil, err
+		}
+		if containsAnyTerm(terms, req.Data, req.AIExtracted, req.AINormalized) {
+			requests = append(requests, req)
+		}
+	}
+	return requests, nil
+}
+
+// FindCRMIntegrations lists a tenant's CRM integration records whose config contains any of the terms
+func (s *MemoryStore) FindCRMIntegrations(ctx context.Context, tenantID string, terms []string) ([]*models.CRMIntegration, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var integrations []*models.CRMIntegration
+	for _, integration := range s.integrations {
+		if integration.TenantID == tenantID && containsAnyTerm(terms, integration.Config) {
+			i := integration
+			integrations = append(integrations, &i)
+		}
+	}
+	sort.Slice(integrations, func(i, j int) bool { return integrations[i].IntegrationID < integrations[j].IntegrationID })
+	return integrations, nil
+}
+
+// ListCallRecordingsByCall lists the recordings of a tenant's call, including their transcriptions
+func (s *MemoryStore) ListCallRecordingsByCall(ctx context.Context, tenantID, callID string) ([]*models.CallRecording, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var recordings []*models.CallRecording
+	for _, recording := range s.tenantRecordings(tenantID) {
+		if recording.CallID == callID {
+			r := recording
+			recordings = append(recordings, &r)
+		}
+	}
+	return recordings, nil
+}
+
+// ListAIProcessingLogsByRequest lists the AI processing logs of a tenant's request, oldest first
+func (s *MemoryStore) ListAIProcessingLogsByRequest(ctx context.Context, tenantID, requestID string) ([]*models.AIProcessingLog, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var logs []*models.AIProcessingLog
+	for _, log := range s.processingLogs {
+		if log.TenantID == tenantID && log.RequestID == requestID {
+			l := log
+			logs = append(logs, &l)
+		}
+	}
+	sort.Slice(logs, func(i, j int) bool { return logs[i].CreatedAt.Before(logs[j].CreatedAt) })
+	return logs, nil
+}
+
+// ListWebhookEventsByCall lists the webhook events received for a call
+func (s *MemoryStore) ListWebhookEventsByCall(ctx context.Context, callID string) ([]*models.WebhookEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []*models.WebhookEvent
+	for _, event := range s.webhookEvents {
+		if stringValue(event.CallID) == callID {
+			e := event
+			events = append(events, &e)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].EventID < events[j].EventID })
+	return events, nil
+}
+
+// DeleteSubjectRows deletes a data subject's rows, and the jobs and fingerprints of their calls
+func (s *MemoryStore) DeleteSubjectRows(ctx context.Context, tenantID string, artifacts *privacy.Artifacts) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows int64
+	for _, req := range artifacts.Requests {
+		if existing, ok := s.requests[req.RequestID]; ok && existing.TenantID == tenantID {
+			delete(s.requests, req.RequestID)
+			delete(s.callerPhones, req.RequestID)
+			rows++
+		}
+	}
+	for _, recording := range artifacts.CallRecordings {
+		if existing, ok := s.recordings[recording.RecordingID]; ok && existing.TenantID == tenantID {
+			delete(s.recordings, recording.RecordingID)
+			delete(s.audioPurgedAt, recording.RecordingID)
+			rows++
+		}
+	}
+	for _, log := range artifacts.ProcessingLogs {
+		if existing, ok := s.processingLogs[log.LogID]; ok && existing.TenantID == tenantID {
+			delete(s.processingLogs, log.LogID)
+			rows++
+		}
+	}
+	for _, integration := range artifacts.CRMIntegrations {
+		if existing, ok := s.integrations[integration.IntegrationID]; ok && existing.TenantID == tenantID {
+			delete(s.integrations, integration.IntegrationID)
+			rows++
+		}
+	}
+	for _, event := range artifacts.WebhookEvents {
+		if _, ok := s.webhookEvents[event.EventID]; ok {
+			delete(s.webhookEvents, event.EventID)
+			rows++
+		}
+	}
+
+	callIDs := artifacts.CallIDs()
+	deleted, err := s.deleteCallJobs(ctx, tenantID, callIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete data subject rows: %w", err)
+	}
+	rows += deleted
+	for id, fp := range s.fingerprints {
+		if fp.TenantID == tenantID && containsString(callIDs, fp.CallID) {
+			delete(s.fingerprints, id)
+			rows++
+		}
+	}
+
+	return rows, nil
+}
+
+// SaveDataSubjectLog inserts or replaces a compliance log entry
+func (s *MemoryStore) SaveDataSubjectLog(ctx context.Context, log *models.DataSubjectLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *log
+	stored.RetainedCalls = append([]string(nil), log.RetainedCalls...)
+	stored.CRMErasures = append([]models.CRMErasure(nil), log.CRMErasures...)
+	s.dataSubjectLogs[log.LogID] = stored
+	return nil
+}
+
+// ListDataSubjectLogs lists a tenant's data subject requests, newest first, optionally for one subject
+func (s *MemoryStore) ListDataSubjectLogs(ctx context.Context, tenantID, subjectHash string) ([]*models.DataSubjectLog, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var logs []*models.DataSubjectLog
+	for _, log := range s.dataSubjectLogs {
+		if log.TenantID == tenantID && (subjectHash == "" || log.SubjectHash == subjectHash) {
+			l := log
+			logs = append(logs, &l)
+		}
+	}
+	sort.Slice(logs, func(i, j int) bool { return logs[i].CreatedAt.After(logs[j].CreatedAt) })
+	return logs, nil
+}
+
+// setCallerPhone indexes a new request by its caller's phone number, as the Spanner repository
+// does when it writes the caller_phone column
+func (s *MemoryStore) setCallerPhone(req *models.Request) {
+	if phone := CallerPhone(req.Data); phone != "" {
+		s.callerPhones[req.RequestID] = phone
+	}
+}
+
+// sealRequest returns a copy of a request to store, with its sensitive columns encrypted
+func (s *MemoryStore) sealRequest(ctx context.Context, req models.Request) (models.Request, error) {
+	req = cloneRequest(req)
+	var err error
+	if req.TranscriptionData, err = s.sealField(ctx, req.TenantID, encryption.PurposeTranscription, req.TranscriptionData); err != nil {
+		return req, err
+	}
+	if req.AIAnalysis, err = s.sealField(ctx, req.TenantID, encryption.PurposeAIAnalysis, req.AIAnalysis); err != nil {
+		return req, err
+	}
+	if req.AIExtracted, err = s.sealExtracted(ctx, req.TenantID, req.AIExtracted); err != nil {
+		return req, err
+	}
+	return req, nil
+}
+
+// openRequest returns a copy of a stored request with its encrypted columns decrypted
+func (s *MemoryStore) openRequest(ctx context.Context, req models.Request) (*models.Request, error) {
+	req = cloneRequest(req)
+	var err error
+	if req.TranscriptionData, err = s.openField(ctx, req.TenantID, encryption.PurposeTranscription, req.TranscriptionData); err != nil {
+		return nil, err
+	}
+	if req.AIAnalysis, err = s.openField(ctx, req.TenantID, encryption.PurposeAIAnalysis, req.AIAnalysis); err != nil {
+		return nil, err
+	}
+	extracted, err := s.openField(ctx, req.TenantID, encryption.PurposeAIExtracted, &req.AIExtracted)
+	if err != nil {
+		return nil, err
+	}
+	req.AIExtracted = *extracted
+	return &req, nil
+}
+
+// sealExtracted encrypts the extracted contact details of a request, keeping the empty '{}'
+// placeholder as is like the Spanner repository does
+func (s *MemoryStore) sealExtracted(ctx context.Context, tenantID, aiExtracted string) (string, error) {
+	if aiExtracted == "" || aiExtracted == "{}" {
+		return aiExtracted, nil
+	}
+	sealed, err := s.sealField(ctx, tenantID, encryption.PurposeAIExtracted, &aiExtracted)
+	if err != nil {
+		return "", err
+	}
+	return *sealed, nil
+}
+
+// sealField encrypts a nullable column value when encryption is enabled
+func (s *MemoryStore) sealField(ctx context.Context, tenantID, purpose string, value *string) (*string, error) {
+	if s.envelope == nil || value == nil {
+		return value, nil
+	}
+	sealed, err := s.envelope.EncryptField(ctx, tenantID, purpose, *value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt %s: %w", purpose, err)
+	}
+	return &sealed, nil
+}
+
+// openField decrypts a nullable column value written by sealField
+func (s *MemoryStore) openField(ctx context.Context, tenantID, purpose string, value *string) (*string, error) {
+	if value == nil || !encryption.IsEncryptedField(*value) {
+		return value, nil
+	}
+	if s.envelope == nil {
+		return nil, fmt.Errorf("failed to decrypt %s: encryption is not configured", purpose)
+	}
+	opened, err := s.envelope.DecryptField(ctx, tenantID, purpose, *value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", purpose, err)
+	}
+	return &opened, nil
+}
+
+// cloneRequest copies a request's nullable columns so stored rows never share memory with callers
+func cloneRequest(req models.Request) models.Request {
+	req.CallID = clonePtr(req.CallID)
+	req.RecordingURL = clonePtr(req.RecordingURL)
+	req.TranscriptionData = clonePtr(req.TranscriptionData)
+	req.AIAnalysis = clonePtr(req.AIAnalysis)
+	req.LeadScore = clonePtr(req.LeadScore)
+	req.SpamLikelihood = clonePtr(req.SpamLikelihood)
+	req.CallClassification = clonePtr(req.CallClassification)
+	return req
+}
+
+// clonePtr copies the value behind a pointer
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// containsAnyTerm reports whether any of the values contains any of the lower-case terms, ignoring case
+func containsAnyTerm(terms []string, values ...string) bool {
+	for _, value := range values {
+		lower := strings.ToLower(value)
+		for _, term := range terms {
+			if strings.Contains(lower, term) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsString reports whether a slice contains a value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// stringValue dereferences a nullable column, treating NULL as ""
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/home-renovators/ingestion-pipeline/internal/encryption"
+	"github.com/home-renovators/ingestion-pipeline/pkg/audio"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+	"github.com/home-renovators/ingestion-pipeline/pkg/privacy"
+	"github.com/home-renovators/ingestion-pipeline/pkg/retention"
+)
+
+// ErrNotFound is wrapped by every lookup that finds no row, so callers can use errors.Is
+var ErrNotFound = errors.New("not found")
+
+// OfficeRepository reads tenants and their offices
+type OfficeRepository interface {
+	// GetOfficeByCallRailCompanyID returns the active office of a CallRail company, or nil if there is none
+	GetOfficeByCallRailCompanyID(ctx context.Context, callRailCompanyID, tenantID string) (*models.Office, error)
+	GetOfficeByTenantID(ctx context.Context, tenantID string) (*models.Office, error)
+	ListActiveOffices(ctx context.Context) ([]*models.Office, error)
+	TenantExists(ctx context.Context, tenantID string) (bool, error)
+}
+
+// RequestRepository stores lead requests
+type RequestRepository interface {
+	CreateRequest(ctx context.Context, req *models.Request) error
+	// CreateRequestWithRecording creates a request and its call recording in one transaction
+	CreateRequestWithRecording(ctx context.Context, req *models.Request, recording *models.CallRecording) error
+	GetRequest(ctx context.Context, tenantID, requestID string) (*models.Request, error)
+	// GetRequestByCallID returns the request of a call, or nil if there is none
+	GetRequestByCallID(ctx context.Context, callID string) (*models.Request, error)
+	GetRequestsByTenant(ctx context.Context, tenantID string, limit int, offset int) ([]*models.Request, error)
+	UpdateRequestClassification(ctx context.Context, tenantID, requestID, classification, status string) error
+	// UpdateRequestAnalysis stores a request's AI analysis JSON with the lead score and spam likelihood taken from it
+	UpdateRequestAnalysis(ctx context.Context, tenantID, requestID, aiAnalysis string, leadScore *int, spamLikelihood *float64) error
+	// UpdateRequestExtraction stores the contact details extracted from a request's call as JSON
+	UpdateRequestExtraction(ctx context.Context, tenantID, requestID, aiExtracted string) error
+	// GetCallerActivity counts the requests from a phone number created since a time, across every
+	// tenant, so a dialer working through businesses stands out
+	GetCallerActivity(ctx context.Context, phone string, since time.Time) (*models.CallerActivity, error)
+	// ListRequestsByCallerPhone lists a tenant's most recent requests from a phone number, newest first
+	ListRequestsByCallerPhone(ctx context.Context, tenantID, phone string, limit int) ([]*models.Request, error)
+	// GetRequestCountsByTenant counts a tenant's requests since a time by communication mode
+	GetRequestCountsByTenant(ctx context.Context, tenantID string, since time.Time) (map[string]int64, error)
+	GetAverageLeadScoreByTenant(ctx context.Context, tenantID string, since time.Time) (float64, error)
+}
+
+// RecordingRepository stores call recordings and their transcriptions
+type RecordingRepository interface {
+	CreateCallRecording(ctx context.Context, recording *models.CallRecording) error
+	GetCallRecording(ctx context.Context, tenantID, recordingID string) (*models.CallRecording, error)
+	UpdateCallRecordingStatus(ctx context.Context, tenantID, recordingID, status string) error
+	GetCallRecordingTranscription(ctx context.Context, tenantID, recordingID string) (*models.TranscriptionResult, error)
+	UpdateCallRecordingTranscription(ctx context.Context, tenantID, recordingID string, transcriptionData string) error
+}
+
+// WebhookEventRepository stores received webhooks
+type WebhookEventRepository interface {
+	CreateWebhookEvent(ctx context.Context, event *models.WebhookEvent) error
+	UpdateWebhookEventStatus(ctx context.Context, eventID, status string) error
+}
+
+// AILogRepository stores AI processing logs
+type AILogRepository interface {
+	CreateAIProcessingLog(ctx context.Context, log *models.AIProcessingLog) error
+	GetAIProcessingLog(ctx context.Context, tenantID, logID string) (*models.AIProcessingLog, error)
+}
+
+// CRMIntegrationRepository stores CRM integration records
+type CRMIntegrationRepository interface {
+	CreateCRMIntegration(ctx context.Context, integration *models.CRMIntegration) error
+	GetCRMIntegration(ctx context.Context, tenantID, integrationID string) (*models.CRMIntegration, error)
+	UpdateCRMIntegration(ctx context.Context, integration *models.CRMIntegration) error
+	// FindCRMIntegrations returns a tenant's CRM integration records whose config contains any of
+	// the lower-case terms, e.g. request IDs
+	FindCRMIntegrations(ctx context.Context, tenantID string, terms []string) ([]*models.CRMIntegration, error)
+}
+
+// ComplianceRepository stores legal holds and the data subject compliance log
+type ComplianceRepository interface {
+	CreateLegalHold(ctx context.Context, hold *models.LegalHold) error
+	ListLegalHolds(ctx context.Context, tenantID string) ([]*models.LegalHold, error)
+	ReleaseLegalHold(ctx context.Context, tenantID, holdID string) error
+	SaveDataSubjectLog(ctx context.Context, log *models.DataSubjectLog) error
+	ListDataSubjectLogs(ctx context.Context, tenantID, subjectHash string) ([]*models.DataSubjectLog, error)
+}
+
+// Repository is the data layer shared by every service
+type Repository interface {
+	OfficeRepository
+	RequestRepository
+	RecordingRepository
+	WebhookEventRepository
+	AILogRepository
+	CRMIntegrationRepository
+	Close()
+}
+
+// Store is the full data layer, including the stores of the audio, encryption, retention and
+// privacy packages
+type Store interface {
+	Repository
+	ComplianceRepository
+	audio.JobStore
+	audio.FingerprintStore
+	encryption.KeyStore
+	retention.Store
+	privacy.Store
+}
+
+// CallerPhone returns the normalized customer phone number in a request's data, "" if there is none
+func CallerPhone(data string) string {
+	var fields struct {
+		CustomerPhoneNumber string `json:"customer_phone_number"`
+	}
+	if err := json.Unmarshal([]byte(data), &fields); err != nil {
+		return ""
+	}
+	return privacy.NormalizePhone(fields.CustomerPhoneNumber)
+}
@@ -3,25 +3,38 @@ package spanner
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"cloud.google.com/go/spanner"
 	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
 
+	"github.com/home-renovators/ingestion-pipeline/internal/encryption"
+	"github.com/home-renovators/ingestion-pipeline/internal/migrations"
+	"github.com/home-renovators/ingestion-pipeline/internal/repository"
+	"github.com/home-renovators/ingestion-pipeline/pkg/audio"
 	"github.com/home-renovators/ingestion-pipeline/pkg/config"
 	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+	"github.com/home-renovators/ingestion-pipeline/pkg/privacy"
+	"github.com/home-renovators/ingestion-pipeline/pkg/retention"
 )
 
 // Repository handles Cloud Spanner database operations
 type Repository struct {
 	client   *spanner.Client
 	database string
+	envelope *encryption.Envelope // encrypts sensitive columns when set
 }
 
+// Repository is the Spanner implementation of the shared data layer
+var _ repository.Store = (*Repository)(nil)
+
 // NewRepository creates a new Spanner repository
 func NewRepository(ctx context.Context, cfg *config.Config) (*Repository, error) {
-	databasePath := fmt.Sprintf("projects/%s/instances/%s/databases/%s",
-		cfg.ProjectID, cfg.SpannerInstance, cfg.SpannerDatabase)
+	databasePath := cfg.SpannerDatabasePath()
 
 	client, err := spanner.NewClient(ctx, databasePath)
 	if err != nil {
@@ -34,11 +47,79 @@ func NewRepository(ctx context.Context, cfg *config.Config) (*Repository, error)
 	}, nil
 }
 
+// SchemaVersion returns the latest schema migration applied to the database
+func (r *Repository) SchemaVersion(ctx context.Context) (int, error) {
+	return migrations.ReadVersion(ctx, r.client)
+}
+
 // Close closes the Spanner client
 func (r *Repository) Close() {
 	r.client.Close()
 }
 
+// SetEnvelope enables encryption of transcripts, AI analysis and extracted contact details with
+// per-tenant data keys. Rows written before encryption was enabled are still read as plaintext.
+func (r *Repository) SetEnvelope(envelope *encryption.Envelope) {
+	r.envelope = envelope
+}
+
+// sealField encrypts a nullable column value when encryption is enabled
+func (r *Repository) sealField(ctx context.Context, tenantID, purpose string, value *string) (*string, error) {
+	if r.envelope == nil || value == nil {
+		return value, nil
+	}
+	sealed, err := r.envelope.EncryptField(ctx, tenantID, purpose, *value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt %s: %w", purpose, err)
+	}
+	return &sealed, nil
+}
+
+// openField decrypts a nullable column value written by sealField
+func (r *Repository) openField(ctx context.Context, tenantID, purpose string, value *string) (*string, error) {
+	if value == nil || !encryption.IsEncryptedField(*value) {
+		return value, nil
+	}
+	if r.envelope == nil {
+		return nil, fmt.Errorf("failed to decrypt %s: encryption is not configured", purpose)
+	}
+	opened, err := r.envelope.DecryptField(ctx, tenantID, purpose, *value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", purpose, err)
+	}
+	return &opened, nil
+}
+
+// openRequest decrypts the encrypted columns of a request in place
+func (r *Repository) openRequest(ctx context.Context, req *models.Request) error {
+	var err error
+	if req.TranscriptionData, err = r.openField(ctx, req.TenantID, encryption.PurposeTranscription, req.TranscriptionData); err != nil {
+		return err
+	}
+	if req.AIAnalysis, err = r.openField(ctx, req.TenantID, encryption.PurposeAIAnalysis, req.AIAnalysis); err != nil {
+		return err
+	}
+	extracted, err := r.openField(ctx, req.TenantID, encryption.PurposeAIExtracted, &req.AIExtracted)
+	if err != nil {
+		return err
+	}
+	req.AIExtracted = *extracted
+	return nil
+}
+
+// sealExtracted encrypts the extracted contact details of a request. The empty '{}' placeholder
+// holds nothing and is kept as is, so retention can still tell which rows have AI output.
+func (r *Repository) sealExtracted(ctx context.Context, tenantID, aiExtracted string) (string, error) {
+	if aiExtracted == "" || aiExtracted == "{}" {
+		return aiExtracted, nil
+	}
+	sealed, err := r.sealField(ctx, tenantID, encryption.PurposeAIExtracted, &aiExtracted)
+	if err != nil {
+		return "", err
+	}
+	return *sealed, nil
+}
+
 // GetOfficeByCallRailCompanyID retrieves an office by CallRail company ID and tenant ID
 func (r *Repository) GetOfficeByCallRailCompanyID(ctx context.Context, callRailCompanyID, tenantID string) (*models.Office, error) {
 	stmt := spanner.Statement{
@@ -111,35 +192,12 @@ func (r *Repository) TenantExists(ctx context.Context, tenantID string) (bool, e
 
 // CreateRequest creates a new request record
 func (r *Repository) CreateRequest(ctx context.Context, req *models.Request) error {
-	_, err := r.client.Apply(ctx, []*spanner.Mutation{
-		spanner.Insert("requests",
-			[]string{
-				"request_id", "tenant_id", "source", "request_type", "status",
-				"data", "ai_normalized", "ai_extracted", "call_id", "recording_url",
-				"transcription_data", "ai_analysis", "lead_score", "communication_mode",
-				"spam_likelihood", "created_at", "updated_at",
-			},
-			[]interface{}{
-				req.RequestID,
-				req.TenantID,
-				req.Source,
-				req.RequestType,
-				req.Status,
-				req.Data,
-				req.AINormalized,
-				req.AIExtracted,
-				req.CallID,
-				req.RecordingURL,
-				req.TranscriptionData,
-				req.AIAnalysis,
-				req.LeadScore,
-				req.CommunicationMode,
-				req.SpamLikelihood,
-				req.CreatedAt,
-				req.UpdatedAt,
-			},
-		),
-	})
+	mutation, err := r.requestMutation(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.Apply(ctx, []*spanner.Mutation{mutation})
 
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
@@ -150,22 +208,7 @@ func (r *Repository) CreateRequest(ctx context.Context, req *models.Request) err
 
 // CreateCallRecording creates a new call recording record
 func (r *Repository) CreateCallRecording(ctx context.Context, recording *models.CallRecording) error {
-	_, err := r.client.Apply(ctx, []*spanner.Mutation{
-		spanner.Insert("call_recordings",
-			[]string{
-				"recording_id", "tenant_id", "call_id", "storage_url",
-				"transcription_status", "created_at",
-			},
-			[]interface{}{
-				recording.RecordingID,
-				recording.TenantID,
-				recording.CallID,
-				recording.StorageURL,
-				recording.TranscriptionStatus,
-				recording.CreatedAt,
-			},
-		),
-	})
+	_, err := r.client.Apply(ctx, []*spanner.Mutation{callRecordingMutation(recording)})
 
 	if err != nil {
 		return fmt.Errorf("failed to create call recording: %w", err)
@@ -174,6 +217,95 @@ func (r *Repository) CreateCallRecording(ctx context.Context, recording *models.
 	return nil
 }
 
+// CreateRequestWithRecording creates a request and its call recording in a single commit
+func (r *Repository) CreateRequestWithRecording(ctx context.Context, req *models.Request, recording *models.CallRecording) error {
+	mutation, err := r.requestMutation(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	mutations := []*spanner.Mutation{mutation}
+	if recording != nil {
+		mutations = append(mutations, callRecordingMutation(recording))
+	}
+
+	_, err = r.client.Apply(ctx, mutations)
+
+	if err != nil {
+		return fmt.Errorf("failed to create request with recording: %w", err)
+	}
+
+	return nil
+}
+
+// requestMutation returns the insert of a request row, encrypting its sensitive columns
+func (r *Repository) requestMutation(ctx context.Context, req *models.Request) (*spanner.Mutation, error) {
+	transcriptionData, err := r.sealField(ctx, req.TenantID, encryption.PurposeTranscription, req.TranscriptionData)
+	if err != nil {
+		return nil, err
+	}
+	aiAnalysis, err := r.sealField(ctx, req.TenantID, encryption.PurposeAIAnalysis, req.AIAnalysis)
+	if err != nil {
+		return nil, err
+	}
+	aiExtracted, err := r.sealExtracted(ctx, req.TenantID, req.AIExtracted)
+	if err != nil {
+		return nil, err
+	}
+	var callerPhone spanner.NullString
+	if phone := repository.CallerPhone(req.Data); phone != "" {
+		callerPhone = spanner.NullString{StringVal: phone, Valid: true}
+	}
+
+	return spanner.Insert("requests",
+		[]string{
+			"request_id", "tenant_id", "source", "request_type", "status",
+			"data", "ai_normalized", "ai_extracted", "call_id", "recording_url",
+			"transcription_data", "ai_analysis", "lead_score", "communication_mode",
+			"spam_likelihood", "call_classification", "caller_phone", "created_at", "updated_at",
+		},
+		[]interface{}{
+			req.RequestID,
+			req.TenantID,
+			req.Source,
+			req.RequestType,
+			req.Status,
+			req.Data,
+			req.AINormalized,
+			aiExtracted,
+			req.CallID,
+			req.RecordingURL,
+			transcriptionData,
+			aiAnalysis,
+			req.LeadScore,
+			req.CommunicationMode,
+			req.SpamLikelihood,
+			req.CallClassification,
+			callerPhone,
+			req.CreatedAt,
+			req.UpdatedAt,
+		},
+	), nil
+}
+
+// callRecordingMutation returns the insert of a call recording row
+func callRecordingMutation(recording *models.CallRecording) *spanner.Mutation {
+	return spanner.Insert("call_recordings",
+		[]string{
+			"recording_id", "tenant_id", "call_id", "storage_url",
+			"transcription_status", "created_at",
+		},
+		[]interface{}{
+			recording.RecordingID,
+			recording.TenantID,
+			recording.CallID,
+			recording.StorageURL,
+			recording.TranscriptionStatus,
+			recording.CreatedAt,
+		},
+	)
+}
+
 // CreateWebhookEvent creates a new webhook event record
 func (r *Repository) CreateWebhookEvent(ctx context.Context, event *models.WebhookEvent) error {
 	_, err := r.client.Apply(ctx, []*spanner.Mutation{
@@ -207,6 +339,9 @@ func (r *Repository) UpdateWebhookEventStatus(ctx context.Context, eventID, stat
 		),
 	})
 
+	if spanner.ErrCode(err) == codes.NotFound {
+		return fmt.Errorf("webhook event %w", repository.ErrNotFound)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update webhook event status: %w", err)
 	}
@@ -214,13 +349,16 @@ func (r *Repository) UpdateWebhookEventStatus(ctx context.Context, eventID, stat
 	return nil
 }
 
-// UpdateCallRecordingStatus updates the transcription status of a call recording
-func (r *Repository) UpdateCallRecordingStatus(ctx context.Context, recordingID, status string) error {
-	_, err := r.client.Apply(ctx, []*spanner.Mutation{
-		spanner.Update("call_recordings",
-			[]string{"recording_id", "transcription_status"},
-			[]interface{}{recordingID, status},
-		),
+// UpdateCallRecordingStatus updates the transcription status of a tenant's call recording
+func (r *Repository) UpdateCallRecordingStatus(ctx context.Context, tenantID, recordingID, status string) error {
+	err := r.updateOne(ctx, "call recording", spanner.Statement{
+		SQL: `UPDATE call_recordings SET transcription_status = @status
+		      WHERE tenant_id = @tenant_id AND recording_id = @recording_id`,
+		Params: map[string]interface{}{
+			"tenant_id":    tenantID,
+			"recording_id": recordingID,
+			"status":       status,
+		},
 	})
 
 	if err != nil {
@@ -230,13 +368,273 @@ func (r *Repository) UpdateCallRecordingStatus(ctx context.Context, recordingID,
 	return nil
 }
 
+// UpdateRequestClassification records how a tenant's call was classified and the resulting request status
+func (r *Repository) UpdateRequestClassification(ctx context.Context, tenantID, requestID, classification, status string) error {
+	err := r.updateOne(ctx, "request", spanner.Statement{
+		SQL: `UPDATE requests
+		      SET call_classification = @classification, status = @status, updated_at = CURRENT_TIMESTAMP()
+		      WHERE tenant_id = @tenant_id AND request_id = @request_id`,
+		Params: map[string]interface{}{
+			"tenant_id":      tenantID,
+			"request_id":     requestID,
+			"classification": classification,
+			"status":         status,
+		},
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to update request classification: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateRequestAnalysis stores a request's AI analysis, encrypted, with its lead score and spam likelihood
+func (r *Repository) UpdateRequestAnalysis(ctx context.Context, tenantID, requestID, aiAnalysis string, leadScore *int, spamLikelihood *float64) error {
+	data, err := r.sealField(ctx, tenantID, encryption.PurposeAIAnalysis, &aiAnalysis)
+	if err != nil {
+		return err
+	}
+
+	var score spanner.NullInt64
+	if leadScore != nil {
+		score = spanner.NullInt64{Int64: int64(*leadScore), Valid: true}
+	}
+	var spam spanner.NullFloat64
+	if spamLikelihood != nil {
+		spam = spanner.NullFloat64{Float64: *spamLikelihood, Valid: true}
+	}
+
+	err = r.updateOne(ctx, "request", spanner.Statement{
+		SQL: `UPDATE requests
+		      SET ai_analysis = @ai_analysis, lead_score = @lead_score, spam_likelihood = @spam_likelihood,
+		          updated_at = CURRENT_TIMESTAMP()
+		      WHERE tenant_id = @tenant_id AND request_id = @request_id`,
+		Params: map[string]interface{}{
+			"tenant_id":       tenantID,
+			"request_id":      requestID,
+			"ai_analysis":     *data,
+			"lead_score":      score,
+			"spam_likelihood": spam,
+		},
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to update request analysis: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateRequestExtraction stores the contact details extracted from a request's call, encrypted
+func (r *Repository) UpdateRequestExtraction(ctx context.Context, tenantID, requestID, aiExtracted string) error {
+	aiExtracted, err := r.sealExtracted(ctx, tenantID, aiExtracted)
+	if err != nil {
+		return err
+	}
+
+	err = r.updateOne(ctx, "request", spanner.Statement{
+		SQL: `UPDATE requests
+		      SET ai_extracted = @ai_extracted, updated_at = CURRENT_TIMESTAMP()
+		      WHERE tenant_id = @tenant_id AND request_id = @request_id`,
+		Params: map[string]interface{}{
+			"tenant_id":    tenantID,
+			"request_id":   requestID,
+			"ai_extracted": aiExtracted,
+		},
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to update request extraction: %w", err)
+	}
+
+	return nil
+}
+
+// GetCallerActivity counts the requests from a phone number created since a time, across every tenant
+func (r *Repository) GetCallerActivity(ctx context.Context, phone string, since time.Time) (*models.CallerActivity, error) {
+	activity := &models.CallerActivity{}
+	phone = privacy.NormalizePhone(phone)
+	if phone == "" {
+		return activity, nil
+	}
+
+	stmt := spanner.Statement{
+		SQL: `SELECT COUNT(*), COUNT(DISTINCT tenant_id)
+		      FROM requests
+		      WHERE caller_phone = @caller_phone AND created_at >= @since`,
+		Params: map[string]interface{}{
+			"caller_phone": phone,
+			"since":        since,
+		},
+	}
+
+	iter := r.client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+
+	row, err := iter.Next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count caller requests: %w", err)
+	}
+
+	var requests, tenants int64
+	if err := row.Columns(&requests, &tenants); err != nil {
+		return nil, fmt.Errorf("failed to parse caller activity: %w", err)
+	}
+	activity.Requests = int(requests)
+	activity.Tenants = int(tenants)
+
+	return activity, nil
+}
+
+// ListRequestsByCallerPhone lists a tenant's most recent requests from a phone number, newest first
+func (r *Repository) ListRequestsByCallerPhone(ctx context.Context, tenantID, phone string, limit int) ([]*models.Request, error) {
+	phone = privacy.NormalizePhone(phone)
+	if phone == "" {
+		return nil, nil
+	}
+
+	stmt := spanner.Statement{
+		SQL: `SELECT request_id, tenant_id, source, request_type, status, data,
+		             ai_normalized, ai_extracted, call_id, recording_url,
+		             transcription_data, ai_analysis, lead_score, communication_mode,
+		             spam_likelihood, call_classification, created_at, updated_at
+		      FROM requests
+		      WHERE caller_phone = @caller_phone AND tenant_id = @tenant_id
+		      ORDER BY created_at DESC
+		      LIMIT @limit`,
+		Params: map[string]interface{}{
+			"caller_phone": phone,
+			"tenant_id":    tenantID,
+			"limit":        limit,
+		},
+	}
+
+	iter := r.client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+
+	var requests []*models.Request
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list caller requests: %w", err)
+		}
+
+		var req models.Request
+		if err := row.Columns(
+			&req.RequestID,
+			&req.TenantID,
+			&req.Source,
+			&req.RequestType,
+			&req.Status,
+			&req.Data,
+			&req.AINormalized,
+			&req.AIExtracted,
+			&req.CallID,
+			&req.RecordingURL,
+			&req.TranscriptionData,
+			&req.AIAnalysis,
+			&req.LeadScore,
+			&req.CommunicationMode,
+			&req.SpamLikelihood,
+			&req.CallClassification,
+			&req.CreatedAt,
+			&req.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan request row: %w", err)
+		}
+		if err := r.openRequest(ctx, &req); err != nil {
+			return nil, err
+		}
+		requests = append(requests, &req)
+	}
+
+	return requests, nil
+}
+
+// updateOne runs a DML statement that must change a row, returning ErrNotFound if it changes none
+func (r *Repository) updateOne(ctx context.Context, entity string, stmt spanner.Statement) error {
+	_, err := r.client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		count, err := txn.Update(ctx, stmt)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("%s %w", entity, repository.ErrNotFound)
+		}
+		return nil
+	})
+	return err
+}
+
+// GetRequest retrieves a tenant's request by ID
+func (r *Repository) GetRequest(ctx context.Context, tenantID, requestID string) (*models.Request, error) {
+	stmt := spanner.Statement{
+		SQL: `SELECT request_id, tenant_id, source, request_type, status, data,
+		             ai_normalized, ai_extracted, call_id, recording_url,
+		             transcription_data, ai_analysis, lead_score, communication_mode,
+		             spam_likelihood, call_classification, created_at, updated_at
+		      FROM requests
+		      WHERE tenant_id = @tenant_id
+		        AND request_id = @request_id`,
+		Params: map[string]interface{}{
+			"tenant_id":  tenantID,
+			"request_id": requestID,
+		},
+	}
+
+	iter := r.client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+
+	row, err := iter.Next()
+	if err == iterator.Done {
+		return nil, fmt.Errorf("request %w", repository.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query request: %w", err)
+	}
+
+	var req models.Request
+	err = row.Columns(
+		&req.RequestID,
+		&req.TenantID,
+		&req.Source,
+		&req.RequestType,
+		&req.Status,
+		&req.Data,
+		&req.AINormalized,
+		&req.AIExtracted,
+		&req.CallID,
+		&req.RecordingURL,
+		&req.TranscriptionData,
+		&req.AIAnalysis,
+		&req.LeadScore,
+		&req.CommunicationMode,
+		&req.SpamLikelihood,
+		&req.CallClassification,
+		&req.CreatedAt,
+		&req.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan request row: %w", err)
+	}
+	if err := r.openRequest(ctx, &req); err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
+
 // GetRequestByCallID retrieves a request by call ID
 func (r *Repository) GetRequestByCallID(ctx context.Context, callID string) (*models.Request, error) {
 	stmt := spanner.Statement{
 		SQL: `SELECT request_id, tenant_id, source, request_type, status, data,
 		             ai_normalized, ai_extracted, call_id, recording_url,
 		             transcription_data, ai_analysis, lead_score, communication_mode,
-		             spam_likelihood, created_at, updated_at
+		             spam_likelihood, call_classification, created_at, updated_at
 		      FROM requests
 		      WHERE call_id = @call_id`,
 		Params: map[string]interface{}{
@@ -272,12 +670,16 @@ func (r *Repository) GetRequestByCallID(ctx context.Context, callID string) (*mo
 		&req.LeadScore,
 		&req.CommunicationMode,
 		&req.SpamLikelihood,
+		&req.CallClassification,
 		&req.CreatedAt,
 		&req.UpdatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan request row: %w", err)
 	}
+	if err := r.openRequest(ctx, &req); err != nil {
+		return nil, err
+	}
 
 	return &req, nil
 }
@@ -288,7 +690,7 @@ func (r *Repository) GetRequestsByTenant(ctx context.Context, tenantID string, l
 		SQL: `SELECT request_id, tenant_id, source, request_type, status, data,
 		             ai_normalized, ai_extracted, call_id, recording_url,
 		             transcription_data, ai_analysis, lead_score, communication_mode,
-		             spam_likelihood, created_at, updated_at
+		             spam_likelihood, call_classification, created_at, updated_at
 		      FROM requests
 		      WHERE tenant_id = @tenant_id
 		      ORDER BY created_at DESC
@@ -330,12 +732,16 @@ func (r *Repository) GetRequestsByTenant(ctx context.Context, tenantID string, l
 			&req.LeadScore,
 			&req.CommunicationMode,
 			&req.SpamLikelihood,
+			&req.CallClassification,
 			&req.CreatedAt,
 			&req.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan request row: %w", err)
 		}
+		if err := r.openRequest(ctx, &req); err != nil {
+			return nil, err
+		}
 
 		requests = append(requests, &req)
 	}
@@ -343,12 +749,84 @@ func (r *Repository) GetRequestsByTenant(ctx context.Context, tenantID string, l
 	return requests, nil
 }
 
-// Helper function to marshal JSON data
-func marshalJSON(v interface{}) (string, error) {
-	data, err := json.Marshal(v)
-	if err != nil {
-		return "", err
-	}
+// GetRequestCountsByTenant counts a tenant's requests created since a time by communication mode
+func (r *Repository) GetRequestCountsByTenant(ctx context.Context, tenantID string, since time.Time) (map[string]int64, error) {
+	stmt := spanner.Statement{
+		SQL: `SELECT communication_mode, COUNT(*) AS count
+		      FROM requests
+		      WHERE tenant_id = @tenant_id AND created_at >= @since
+		      GROUP BY communication_mode`,
+		Params: map[string]interface{}{
+			"tenant_id": tenantID,
+			"since":     since,
+		},
+	}
+
+	iter := r.client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+
+	counts := make(map[string]int64)
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to query request counts: %w", err)
+		}
+
+		var mode string
+		var count int64
+		if err := row.Columns(&mode, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan request count row: %w", err)
+		}
+		counts[mode] = count
+	}
+
+	return counts, nil
+}
+
+// GetAverageLeadScoreByTenant averages the lead scores of a tenant's requests created since a time,
+// returning 0 when none are scored
+func (r *Repository) GetAverageLeadScoreByTenant(ctx context.Context, tenantID string, since time.Time) (float64, error) {
+	stmt := spanner.Statement{
+		SQL: `SELECT AVG(CAST(lead_score AS FLOAT64)) AS avg_score
+		      FROM requests
+		      WHERE tenant_id = @tenant_id AND lead_score IS NOT NULL AND created_at >= @since`,
+		Params: map[string]interface{}{
+			"tenant_id": tenantID,
+			"since":     since,
+		},
+	}
+
+	iter := r.client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+
+	row, err := iter.Next()
+	if err == iterator.Done {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to query average lead score: %w", err)
+	}
+
+	var avgScore spanner.NullFloat64
+	if err := row.Columns(&avgScore); err != nil {
+		return 0, fmt.Errorf("failed to scan average lead score: %w", err)
+	}
+	if !avgScore.Valid {
+		return 0, nil
+	}
+
+	return avgScore.Float64, nil
+}
+
+// Helper function to marshal JSON data
+func marshalJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
 	return string(data), nil
 }
 
@@ -370,7 +848,7 @@ func (r *Repository) GetOfficeByTenantID(ctx context.Context, tenantID string) (
 
 	row, err := iter.Next()
 	if err == iterator.Done {
-		return nil, fmt.Errorf("office not found for tenant %s", tenantID)
+		return nil, fmt.Errorf("office for tenant %s %w", tenantID, repository.ErrNotFound)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to query office: %w", err)
@@ -413,7 +891,7 @@ func (r *Repository) GetCallRecording(ctx context.Context, tenantID, recordingID
 
 	row, err := iter.Next()
 	if err == iterator.Done {
-		return nil, fmt.Errorf("call recording not found")
+		return nil, fmt.Errorf("call recording %w", repository.ErrNotFound)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to query call recording: %w", err)
@@ -435,13 +913,65 @@ func (r *Repository) GetCallRecording(ctx context.Context, tenantID, recordingID
 	return &recording, nil
 }
 
-// UpdateCallRecordingTranscription updates the transcription data of a call recording
-func (r *Repository) UpdateCallRecordingTranscription(ctx context.Context, recordingID string, transcriptionData string) error {
-	_, err := r.client.Apply(ctx, []*spanner.Mutation{
-		spanner.Update("call_recordings",
-			[]string{"recording_id", "transcription_data"},
-			[]interface{}{recordingID, transcriptionData},
-		),
+// GetCallRecordingTranscription retrieves the stored transcription of a call recording
+func (r *Repository) GetCallRecordingTranscription(ctx context.Context, tenantID, recordingID string) (*models.TranscriptionResult, error) {
+	stmt := spanner.Statement{
+		SQL: `SELECT transcription_data
+		      FROM call_recordings
+		      WHERE tenant_id = @tenant_id
+		        AND recording_id = @recording_id`,
+		Params: map[string]interface{}{
+			"tenant_id":    tenantID,
+			"recording_id": recordingID,
+		},
+	}
+
+	iter := r.client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+
+	row, err := iter.Next()
+	if err == iterator.Done {
+		return nil, fmt.Errorf("call recording %w", repository.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query call recording transcription: %w", err)
+	}
+
+	var transcriptionData spanner.NullString
+	if err := row.Columns(&transcriptionData); err != nil {
+		return nil, fmt.Errorf("failed to scan call recording transcription: %w", err)
+	}
+	if !transcriptionData.Valid || transcriptionData.StringVal == "" {
+		return nil, fmt.Errorf("call recording has no transcription")
+	}
+	data, err := r.openField(ctx, tenantID, encryption.PurposeTranscription, &transcriptionData.StringVal)
+	if err != nil {
+		return nil, err
+	}
+
+	var result models.TranscriptionResult
+	if err := unmarshalJSON(*data, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transcription: %w", err)
+	}
+
+	return &result, nil
+}
+
+// UpdateCallRecordingTranscription updates the transcription data of a tenant's call recording
+func (r *Repository) UpdateCallRecordingTranscription(ctx context.Context, tenantID, recordingID string, transcriptionData string) error {
+	data, err := r.sealField(ctx, tenantID, encryption.PurposeTranscription, &transcriptionData)
+	if err != nil {
+		return err
+	}
+
+	err = r.updateOne(ctx, "call recording", spanner.Statement{
+		SQL: `UPDATE call_recordings SET transcription_data = @transcription_data
+		      WHERE tenant_id = @tenant_id AND recording_id = @recording_id`,
+		Params: map[string]interface{}{
+			"tenant_id":          tenantID,
+			"recording_id":       recordingID,
+			"transcription_data": *data,
+		},
 	})
 
 	if err != nil {
@@ -453,7 +983,12 @@ func (r *Repository) UpdateCallRecordingTranscription(ctx context.Context, recor
 
 // CreateAIProcessingLog creates a new AI processing log record
 func (r *Repository) CreateAIProcessingLog(ctx context.Context, log *models.AIProcessingLog) error {
-	_, err := r.client.Apply(ctx, []*spanner.Mutation{
+	processingData, err := r.sealField(ctx, log.TenantID, encryption.PurposeProcessingData, &log.ProcessingData)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.Apply(ctx, []*spanner.Mutation{
 		spanner.Insert("ai_processing_logs",
 			[]string{
 				"log_id", "tenant_id", "request_id", "analysis_type",
@@ -465,7 +1000,7 @@ func (r *Repository) CreateAIProcessingLog(ctx context.Context, log *models.AIPr
 				log.RequestID,
 				log.AnalysisType,
 				log.Status,
-				log.ProcessingData,
+				*processingData,
 				log.CreatedAt,
 				log.UpdatedAt,
 			},
@@ -498,7 +1033,7 @@ func (r *Repository) GetAIProcessingLog(ctx context.Context, tenantID, logID str
 
 	row, err := iter.Next()
 	if err == iterator.Done {
-		return nil, fmt.Errorf("AI processing log not found")
+		return nil, fmt.Errorf("AI processing log %w", repository.ErrNotFound)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to query AI processing log: %w", err)
@@ -518,6 +1053,11 @@ func (r *Repository) GetAIProcessingLog(ctx context.Context, tenantID, logID str
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan AI processing log row: %w", err)
 	}
+	processingData, err := r.openField(ctx, log.TenantID, encryption.PurposeProcessingData, &log.ProcessingData)
+	if err != nil {
+		return nil, err
+	}
+	log.ProcessingData = *processingData
 
 	return &log, nil
 }
@@ -568,7 +1108,7 @@ func (r *Repository) GetCRMIntegration(ctx context.Context, tenantID, integratio
 
 	row, err := iter.Next()
 	if err == iterator.Done {
-		return nil, fmt.Errorf("CRM integration not found")
+		return nil, fmt.Errorf("CRM integration %w", repository.ErrNotFound)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to query CRM integration: %w", err)
@@ -610,6 +1150,9 @@ func (r *Repository) UpdateCRMIntegration(ctx context.Context, integration *mode
 		),
 	})
 
+	if spanner.ErrCode(err) == codes.NotFound {
+		return fmt.Errorf("CRM integration %w", repository.ErrNotFound)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update CRM integration: %w", err)
 	}
@@ -617,6 +1160,1231 @@ func (r *Repository) UpdateCRMIntegration(ctx context.Context, integration *mode
 	return nil
 }
 
+// Repository persists audio transcription jobs
+var _ audio.JobStore = (*Repository)(nil)
+
+// SaveTranscriptionJob inserts or replaces a transcription job. The redacted result stored on a
+// completed job is kept in its own column, sealed like transcription_data.
+func (r *Repository) SaveTranscriptionJob(ctx context.Context, job *audio.TranscriptionJob) error {
+	var tenantID, callID string
+	if job.Request != nil {
+		tenantID = job.Request.TenantID
+		callID = job.Request.CallID
+	}
+
+	var resultData *string
+	if job.Result != nil {
+		result, err := json.Marshal(job.Result)
+		if err != nil {
+			return fmt.Errorf("failed to marshal transcription result: %w", err)
+		}
+		data := string(result)
+		if resultData, err = r.sealField(ctx, tenantID, encryption.PurposeTranscription, &data); err != nil {
+			return err
+		}
+	}
+
+	stored := *job
+	stored.Result = nil
+	jobData, err := json.Marshal(&stored)
+	if err != nil {
+		return fmt.Errorf("failed to marshal transcription job: %w", err)
+	}
+
+	_, err = r.client.Apply(ctx, []*spanner.Mutation{
+		spanner.InsertOrUpdate("transcription_jobs",
+			[]string{
+				"job_id", "tenant_id", "call_id", "status", "progress",
+				"operation_name", "job_data", "result_data", "started_at", "updated_at", "ended_at",
+			},
+			[]interface{}{
+				job.JobID,
+				tenantID,
+				callID,
+				job.Status.Status,
+				job.Status.Progress,
+				job.Status.OperationName,
+				string(jobData),
+				resultData,
+				job.Status.StartTime,
+				job.Status.UpdatedAt,
+				job.Status.EndTime,
+			},
+		),
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to save transcription job: %w", err)
+	}
+
+	return nil
+}
+
+// transcriptionJobColumns are the columns scanTranscriptionJob reads
+var transcriptionJobColumns = []string{"job_data", "tenant_id", "result_data"}
+
+// scanTranscriptionJob decodes a transcription job row, opening its sealed result
+func (r *Repository) scanTranscriptionJob(ctx context.Context, row *spanner.Row) (*audio.TranscriptionJob, error) {
+	var jobData, tenantID string
+	var resultData *string
+	if err := row.Columns(&jobData, &tenantID, &resultData); err != nil {
+		return nil, fmt.Errorf("failed to scan transcription job row: %w", err)
+	}
+
+	var job audio.TranscriptionJob
+	if err := unmarshalJSON(jobData, &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transcription job: %w", err)
+	}
+
+	resultData, err := r.openField(ctx, tenantID, encryption.PurposeTranscription, resultData)
+	if err != nil {
+		return nil, err
+	}
+	if resultData != nil {
+		if err := unmarshalJSON(*resultData, &job.Result); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal transcription result: %w", err)
+		}
+	}
+
+	return &job, nil
+}
+
+// GetTranscriptionJob retrieves a transcription job by ID
+func (r *Repository) GetTranscriptionJob(ctx context.Context, jobID string) (*audio.TranscriptionJob, error) {
+	row, err := r.client.Single().ReadRow(ctx, "transcription_jobs", spanner.Key{jobID}, transcriptionJobColumns)
+	if spanner.ErrCode(err) == codes.NotFound {
+		return nil, audio.ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcription job: %w", err)
+	}
+
+	return r.scanTranscriptionJob(ctx, row)
+}
+
+// ListTranscriptionJobs lists transcription jobs matching the filter, oldest first
+func (r *Repository) ListTranscriptionJobs(ctx context.Context, filter audio.JobFilter) ([]*audio.TranscriptionJob, error) {
+	sql := `SELECT job_data, tenant_id, result_data FROM transcription_jobs WHERE TRUE`
+	params := map[string]interface{}{}
+
+	if filter.TenantID != "" {
+		sql += ` AND tenant_id = @tenant_id`
+		params["tenant_id"] = filter.TenantID
+	}
+	if filter.CallID != "" {
+		sql += ` AND call_id = @call_id`
+		params["call_id"] = filter.CallID
+	}
+	if filter.Status != "" {
+		sql += ` AND status = @status`
+		params["status"] = filter.Status
+	}
+	if !filter.EndedBefore.IsZero() {
+		sql += ` AND ended_at < @ended_before`
+		params["ended_before"] = filter.EndedBefore
+	}
+	sql += ` ORDER BY started_at`
+
+	iter := r.client.Single().Query(ctx, spanner.Statement{SQL: sql, Params: params})
+	defer iter.Stop()
+
+	var jobs []*audio.TranscriptionJob
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to query transcription jobs: %w", err)
+		}
+
+		job, err := r.scanTranscriptionJob(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// ClaimTranscriptionJob claims a transcription job for owner with a conditional update, so only one
+// instance holds a job at a time
+func (r *Repository) ClaimTranscriptionJob(ctx context.Context, jobID, owner string, until time.Time) (bool, error) {
+	var claimed bool
+	_, err := r.client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		count, err := txn.Update(ctx, spanner.Statement{
+			SQL: `UPDATE transcription_jobs
+			      SET claimed_by = @owner, claim_expires_at = @until
+			      WHERE job_id = @job_id
+			        AND (claimed_by IS NULL OR claimed_by = @owner OR claim_expires_at < @now)`,
+			Params: map[string]interface{}{
+				"job_id": jobID,
+				"owner":  owner,
+				"until":  until,
+				"now":    time.Now(),
+			},
+		})
+		if err != nil {
+			return err
+		}
+		claimed = count > 0
+		if claimed {
+			return nil
+		}
+
+		// Nothing changed: either another instance holds the job or it doesn't exist
+		if _, err := txn.ReadRow(ctx, "transcription_jobs", spanner.Key{jobID}, []string{"job_id"}); err != nil {
+			if spanner.ErrCode(err) == codes.NotFound {
+				return audio.ErrJobNotFound
+			}
+			return err
+		}
+		return nil
+	})
+	if errors.Is(err, audio.ErrJobNotFound) {
+		return false, err
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to claim transcription job: %w", err)
+	}
+
+	return claimed, nil
+}
+
+// ReleaseTranscriptionJob clears owner's claim on a transcription job
+func (r *Repository) ReleaseTranscriptionJob(ctx context.Context, jobID, owner string) error {
+	_, err := r.client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		_, err := txn.Update(ctx, spanner.Statement{
+			SQL: `UPDATE transcription_jobs
+			      SET claimed_by = NULL, claim_expires_at = NULL
+			      WHERE job_id = @job_id AND claimed_by = @owner`,
+			Params: map[string]interface{}{
+				"job_id": jobID,
+				"owner":  owner,
+			},
+		})
+		return err
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to release transcription job: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteTranscriptionJob deletes a transcription job
+func (r *Repository) DeleteTranscriptionJob(ctx context.Context, jobID string) error {
+	_, err := r.client.Apply(ctx, []*spanner.Mutation{
+		spanner.Delete("transcription_jobs", spanner.Key{jobID}),
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to delete transcription job: %w", err)
+	}
+
+	return nil
+}
+
+// Repository stores fingerprints for the audio service's duplicate and robocall checks
+var _ audio.FingerprintStore = (*Repository)(nil)
+
+// Columns of the recording and robocall fingerprint tables
+var (
+	recordingFingerprintColumns = []string{"recording_id", "tenant_id", "call_id", "digest", "fingerprint", "hop_ms", "duration_ms", "created_at"}
+	robocallFingerprintColumns  = []string{"fingerprint_id", "label", "digest", "fingerprint", "hop_ms", "duration_ms", "source_tenant_id", "source_recording_id", "created_at"}
+)
+
+// SaveRecordingFingerprint inserts or updates the fingerprint of a recording
+func (r *Repository) SaveRecordingFingerprint(ctx context.Context, fp *models.RecordingFingerprint) error {
+	_, err := r.client.Apply(ctx, []*spanner.Mutation{
+		spanner.InsertOrUpdate("recording_fingerprints", recordingFingerprintColumns,
+			[]interface{}{
+				fp.RecordingID,
+				fp.TenantID,
+				fp.CallID,
+				fp.Digest,
+				fp.Fingerprint,
+				fp.HopMs,
+				fp.DurationMs,
+				fp.CreatedAt,
+			},
+		),
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to save recording fingerprint: %w", err)
+	}
+
+	return nil
+}
+
+// GetRecordingFingerprint retrieves the fingerprint of a recording
+func (r *Repository) GetRecordingFingerprint(ctx context.Context, recordingID string) (*models.RecordingFingerprint, error) {
+	row, err := r.client.Single().ReadRow(ctx, "recording_fingerprints", spanner.Key{recordingID}, recordingFingerprintColumns)
+	if spanner.ErrCode(err) == codes.NotFound {
+		return nil, audio.ErrFingerprintNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording fingerprint: %w", err)
+	}
+
+	return scanRecordingFingerprint(row)
+}
+
+// ListRecordingFingerprints lists recording fingerprints matching the filter
+func (r *Repository) ListRecordingFingerprints(ctx context.Context, filter audio.FingerprintFilter) ([]*models.RecordingFingerprint, error) {
+	sql := `SELECT recording_id, tenant_id, call_id, digest, fingerprint, hop_ms, duration_ms, created_at
+		FROM recording_fingerprints WHERE TRUE`
+	params := map[string]interface{}{}
+
+	if filter.TenantID != "" {
+		sql += ` AND tenant_id = @tenant_id`
+		params["tenant_id"] = filter.TenantID
+	}
+	if !filter.Since.IsZero() {
+		sql += ` AND created_at >= @since`
+		params["since"] = filter.Since
+	}
+	if filter.MinDurationMs > 0 {
+		sql += ` AND duration_ms >= @min_duration_ms`
+		params["min_duration_ms"] = filter.MinDurationMs
+	}
+	if filter.MaxDurationMs > 0 {
+		sql += ` AND duration_ms <= @max_duration_ms`
+		params["max_duration_ms"] = filter.MaxDurationMs
+	}
+
+	iter := r.client.Single().Query(ctx, spanner.Statement{SQL: sql, Params: params})
+	defer iter.Stop()
+
+	var fps []*models.RecordingFingerprint
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to query recording fingerprints: %w", err)
+		}
+
+		fp, err := scanRecordingFingerprint(row)
+		if err != nil {
+			return nil, err
+		}
+		fps = append(fps, fp)
+	}
+
+	return fps, nil
+}
+
+// SaveRobocallFingerprint adds a fingerprint to the global robocall list
+func (r *Repository) SaveRobocallFingerprint(ctx context.Context, fp *models.RobocallFingerprint) error {
+	_, err := r.client.Apply(ctx, []*spanner.Mutation{
+		spanner.InsertOrUpdate("robocall_fingerprints", robocallFingerprintColumns,
+			[]interface{}{
+				fp.FingerprintID,
+				fp.Label,
+				fp.Digest,
+				fp.Fingerprint,
+				fp.HopMs,
+				fp.DurationMs,
+				fp.SourceTenantID,
+				fp.SourceRecordingID,
+				fp.CreatedAt,
+			},
+		),
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to save robocall fingerprint: %w", err)
+	}
+
+	return nil
+}
+
+// ListRobocallFingerprints lists every known robocall fingerprint
+func (r *Repository) ListRobocallFingerprints(ctx context.Context) ([]*models.RobocallFingerprint, error) {
+	iter := r.client.Single().Read(ctx, "robocall_fingerprints", spanner.AllKeys(), robocallFingerprintColumns)
+	defer iter.Stop()
+
+	var fps []*models.RobocallFingerprint
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read robocall fingerprints: %w", err)
+		}
+
+		var fp models.RobocallFingerprint
+		if err := row.Columns(
+			&fp.FingerprintID,
+			&fp.Label,
+			&fp.Digest,
+			&fp.Fingerprint,
+			&fp.HopMs,
+			&fp.DurationMs,
+			&fp.SourceTenantID,
+			&fp.SourceRecordingID,
+			&fp.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan robocall fingerprint row: %w", err)
+		}
+		fps = append(fps, &fp)
+	}
+
+	return fps, nil
+}
+
+// scanRecordingFingerprint reads a recording fingerprint row
+func scanRecordingFingerprint(row *spanner.Row) (*models.RecordingFingerprint, error) {
+	var fp models.RecordingFingerprint
+	if err := row.Columns(
+		&fp.RecordingID,
+		&fp.TenantID,
+		&fp.CallID,
+		&fp.Digest,
+		&fp.Fingerprint,
+		&fp.HopMs,
+		&fp.DurationMs,
+		&fp.CreatedAt,
+	); err != nil {
+		return nil, fmt.Errorf("failed to scan recording fingerprint row: %w", err)
+	}
+	return &fp, nil
+}
+
+// Repository persists wrapped tenant data keys
+var _ encryption.KeyStore = (*Repository)(nil)
+
+// Columns of the tenant data key table
+var tenantDataKeyColumns = []string{"tenant_id", "key_version", "wrapped_key", "kek_name", "state", "created_at", "destroyed_at"}
+
+// ListTenantKeys lists every data key version of a tenant, oldest first
+func (r *Repository) ListTenantKeys(ctx context.Context, tenantID string) ([]*models.TenantDataKey, error) {
+	iter := r.client.Single().Read(ctx, "tenant_data_keys", spanner.Key{tenantID}.AsPrefix(), tenantDataKeyColumns)
+	defer iter.Stop()
+
+	var keys []*models.TenantDataKey
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tenant data keys: %w", err)
+		}
+
+		var key models.TenantDataKey
+		var destroyedAt spanner.NullTime
+		if err := row.Columns(
+			&key.TenantID,
+			&key.KeyVersion,
+			&key.WrappedKey,
+			&key.KEKName,
+			&key.State,
+			&key.CreatedAt,
+			&destroyedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant data key row: %w", err)
+		}
+		if destroyedAt.Valid {
+			key.DestroyedAt = &destroyedAt.Time
+		}
+		keys = append(keys, &key)
+	}
+
+	return keys, nil
+}
+
+// SaveTenantKeys inserts new data key versions and updates existing ones in one commit
+func (r *Repository) SaveTenantKeys(ctx context.Context, created, updated []*models.TenantDataKey) error {
+	var mutations []*spanner.Mutation
+	for _, key := range created {
+		mutations = append(mutations, spanner.Insert("tenant_data_keys", tenantDataKeyColumns, tenantDataKeyValues(key)))
+	}
+	for _, key := range updated {
+		mutations = append(mutations, spanner.Update("tenant_data_keys", tenantDataKeyColumns, tenantDataKeyValues(key)))
+	}
+	if len(mutations) == 0 {
+		return nil
+	}
+
+	_, err := r.client.Apply(ctx, mutations)
+	if spanner.ErrCode(err) == codes.AlreadyExists {
+		return encryption.ErrKeyExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save tenant data keys: %w", err)
+	}
+
+	return nil
+}
+
+// tenantDataKeyValues returns the column values of a data key row
+func tenantDataKeyValues(key *models.TenantDataKey) []interface{} {
+	destroyedAt := spanner.NullTime{}
+	if key.DestroyedAt != nil {
+		destroyedAt = spanner.NullTime{Time: *key.DestroyedAt, Valid: true}
+	}
+	return []interface{}{
+		key.TenantID,
+		key.KeyVersion,
+		key.WrappedKey,
+		key.KEKName,
+		key.State,
+		key.CreatedAt,
+		destroyedAt,
+	}
+}
+
+// Repository finds and purges expired call data for the retention worker
+var _ retention.Store = (*Repository)(nil)
+
+// Columns of the legal hold table
+var legalHoldColumns = []string{"hold_id", "tenant_id", "call_id", "reason", "created_by", "created_at", "released_at"}
+
+// purgeCandidateQueries select a tenant's calls still holding data of each class, oldest first
+var purgeCandidateQueries = map[models.DataClass]string{
+	models.DataClassAudio: `SELECT recording_id, call_id, '' AS request_id, created_at
+		FROM call_recordings
+		WHERE tenant_id = @tenant_id AND created_at < @before AND audio_purged_at IS NULL
+		ORDER BY created_at LIMIT @limit`,
+	models.DataClassTranscript: `SELECT cr.recording_id, cr.call_id, IFNULL(rq.request_id, '') AS request_id, cr.created_at
+		FROM call_recordings cr
+		LEFT JOIN requests rq ON rq.tenant_id = cr.tenant_id AND rq.call_id = cr.call_id
+		WHERE cr.tenant_id = @tenant_id AND cr.created_at < @before
+		  AND (cr.transcription_data IS NOT NULL OR rq.transcription_data IS NOT NULL
+		    OR EXISTS (SELECT 1 FROM transcription_jobs tj WHERE tj.tenant_id = cr.tenant_id AND tj.call_id = cr.call_id))
+		ORDER BY cr.created_at LIMIT @limit`,
+	models.DataClassAIOutput: `SELECT '' AS recording_id, IFNULL(call_id, '') AS call_id, request_id, created_at
+		FROM requests
+		WHERE tenant_id = @tenant_id AND created_at < @before
+		  AND (ai_analysis IS NOT NULL OR ai_extracted != '{}' OR ai_normalized != '{}')
+		ORDER BY created_at LIMIT @limit`,
+	models.DataClassWebhook: `SELECT '' AS recording_id, IFNULL(call_id, '') AS call_id, request_id, created_at
+		FROM requests
+		WHERE tenant_id = @tenant_id AND created_at < @before AND data != '{}'
+		ORDER BY created_at LIMIT @limit`,
+}
+
+// ListPurgeCandidates lists up to limit calls of a tenant holding data of the class created before the cutoff
+func (r *Repository) ListPurgeCandidates(ctx context.Context, tenantID string, class models.DataClass, before time.Time, limit int) ([]retention.Candidate, error) {
+	sql, ok := purgeCandidateQueries[class]
+	if !ok {
+		return nil, fmt.Errorf("unknown data class %q", class)
+	}
+
+	iter := r.client.Single().Query(ctx, spanner.Statement{
+		SQL: sql,
+		Params: map[string]interface{}{
+			"tenant_id": tenantID,
+			"before":    before,
+			"limit":     int64(limit),
+		},
+	})
+	defer iter.Stop()
+
+	var candidates []retention.Candidate
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to query purge candidates: %w", err)
+		}
+
+		candidate := retention.Candidate{TenantID: tenantID}
+		if err := row.Columns(&candidate.RecordingID, &candidate.CallID, &candidate.RequestID, &candidate.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan purge candidate row: %w", err)
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, nil
+}
+
+// PurgeCallData removes a call's data of the class and inserts the audit record in one transaction
+func (r *Repository) PurgeCallData(ctx context.Context, candidate retention.Candidate, class models.DataClass, audit *models.PurgeAuditRecord) (int64, error) {
+	statements := purgeStatements(candidate, class)
+	if statements == nil {
+		return 0, fmt.Errorf("unknown data class %q", class)
+	}
+
+	var rows int64
+	_, err := r.client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		rows = 0
+		for _, stmt := range statements {
+			count, err := txn.Update(ctx, stmt)
+			if err != nil {
+				return err
+			}
+			rows += count
+		}
+
+		audit.RowsAffected = rows
+		return txn.BufferWrite([]*spanner.Mutation{
+			spanner.Insert("purge_audit_log",
+				[]string{
+					"audit_id", "tenant_id", "call_id", "request_id", "recording_id", "data_class",
+					"retention_days", "cutoff", "objects_deleted", "rows_affected", "purged_at",
+				},
+				[]interface{}{
+					audit.AuditID,
+					audit.TenantID,
+					audit.CallID,
+					audit.RequestID,
+					audit.RecordingID,
+					string(audit.DataClass),
+					int64(audit.RetentionDays),
+					audit.Cutoff,
+					audit.ObjectsDeleted,
+					audit.RowsAffected,
+					audit.PurgedAt,
+				},
+			),
+		})
+	})
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge %s data: %w", class, err)
+	}
+
+	return rows, nil
+}
+
+// purgeStatements returns the DML removing a call's data of the class
+func purgeStatements(candidate retention.Candidate, class models.DataClass) []spanner.Statement {
+	params := map[string]interface{}{
+		"tenant_id":    candidate.TenantID,
+		"call_id":      candidate.CallID,
+		"request_id":   candidate.RequestID,
+		"recording_id": candidate.RecordingID,
+	}
+	stmt := func(sql string) spanner.Statement {
+		return spanner.Statement{SQL: sql, Params: params}
+	}
+
+	switch class {
+	case models.DataClassAudio:
+		return []spanner.Statement{
+			stmt(`UPDATE call_recordings SET audio_purged_at = CURRENT_TIMESTAMP()
+				WHERE tenant_id = @tenant_id AND recording_id = @recording_id`),
+		}
+	case models.DataClassTranscript:
+		return []spanner.Statement{
+			stmt(`UPDATE call_recordings SET transcription_data = NULL
+				WHERE tenant_id = @tenant_id AND call_id = @call_id`),
+			stmt(`UPDATE requests SET transcription_data = NULL
+				WHERE tenant_id = @tenant_id AND call_id = @call_id`),
+			stmt(`DELETE FROM transcription_jobs
+				WHERE tenant_id = @tenant_id AND call_id = @call_id`),
+			stmt(`DELETE FROM ai_processing_logs
+				WHERE tenant_id = @tenant_id AND analysis_type = 'transcription'
+				  AND request_id IN (SELECT request_id FROM requests WHERE tenant_id = @tenant_id AND call_id = @call_id)`),
+		}
+	case models.DataClassAIOutput:
+		return []spanner.Statement{
+			stmt(`UPDATE requests SET ai_analysis = NULL, ai_extracted = '{}', ai_normalized = '{}'
+				WHERE tenant_id = @tenant_id AND request_id = @request_id`),
+			stmt(`DELETE FROM ai_processing_logs
+				WHERE tenant_id = @tenant_id AND request_id = @request_id AND analysis_type != 'transcription'`),
+		}
+	case models.DataClassWebhook:
+		return []spanner.Statement{
+			stmt(`UPDATE requests SET data = '{}', caller_phone = NULL
+				WHERE tenant_id = @tenant_id AND request_id = @request_id`),
+			stmt(`DELETE FROM webhook_events WHERE call_id = @call_id AND @call_id != ''`),
+		}
+	}
+	return nil
+}
+
+// CreateLegalHold creates a legal hold
+func (r *Repository) CreateLegalHold(ctx context.Context, hold *models.LegalHold) error {
+	_, err := r.client.Apply(ctx, []*spanner.Mutation{
+		spanner.Insert("legal_holds", legalHoldColumns, []interface{}{
+			hold.HoldID,
+			hold.TenantID,
+			hold.CallID,
+			hold.Reason,
+			hold.CreatedBy,
+			hold.CreatedAt,
+			spanner.NullTime{},
+		}),
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to create legal hold: %w", err)
+	}
+
+	return nil
+}
+
+// ListLegalHolds lists the legal holds of a tenant that have not been released
+func (r *Repository) ListLegalHolds(ctx context.Context, tenantID string) ([]*models.LegalHold, error) {
+	stmt := spanner.Statement{
+		SQL: `SELECT hold_id, tenant_id, call_id, reason, created_by, created_at, released_at
+		      FROM legal_holds
+		      WHERE tenant_id = @tenant_id
+		        AND released_at IS NULL
+		      ORDER BY created_at`,
+		Params: map[string]interface{}{
+			"tenant_id": tenantID,
+		},
+	}
+
+	iter := r.client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+
+	var holds []*models.LegalHold
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to query legal holds: %w", err)
+		}
+
+		var hold models.LegalHold
+		var releasedAt spanner.NullTime
+		if err := row.Columns(
+			&hold.HoldID,
+			&hold.TenantID,
+			&hold.CallID,
+			&hold.Reason,
+			&hold.CreatedBy,
+			&hold.CreatedAt,
+			&releasedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan legal hold row: %w", err)
+		}
+		if releasedAt.Valid {
+			hold.ReleasedAt = &releasedAt.Time
+		}
+		holds = append(holds, &hold)
+	}
+
+	return holds, nil
+}
+
+// ReleaseLegalHold releases a tenant's legal hold
+func (r *Repository) ReleaseLegalHold(ctx context.Context, tenantID, holdID string) error {
+	_, err := r.client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		count, err := txn.Update(ctx, spanner.Statement{
+			SQL: `UPDATE legal_holds SET released_at = CURRENT_TIMESTAMP()
+			      WHERE hold_id = @hold_id AND tenant_id = @tenant_id AND released_at IS NULL`,
+			Params: map[string]interface{}{
+				"hold_id":   holdID,
+				"tenant_id": tenantID,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("legal hold %w", repository.ErrNotFound)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to release legal hold: %w", err)
+	}
+
+	return nil
+}
+
+// ListActiveOffices lists the active offices of every tenant
+func (r *Repository) ListActiveOffices(ctx context.Context) ([]*models.Office, error) {
+	stmt := spanner.Statement{
+		SQL: `SELECT tenant_id, office_id, callrail_company_id, callrail_api_key,
+		             workflow_config, status, created_at, updated_at
+		      FROM offices
+		      WHERE status = 'active'
+		      ORDER BY tenant_id`,
+	}
+
+	iter := r.client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+
+	var offices []*models.Office
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to query offices: %w", err)
+		}
+
+		var office models.Office
+		if err := row.Columns(
+			&office.TenantID,
+			&office.OfficeID,
+			&office.CallRailCompanyID,
+			&office.CallRailAPIKey,
+			&office.WorkflowConfig,
+			&office.Status,
+			&office.CreatedAt,
+			&office.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan office row: %w", err)
+		}
+		offices = append(offices, &office)
+	}
+
+	return offices, nil
+}
+
+// Repository finds and erases data subjects' records
+var _ privacy.Store = (*Repository)(nil)
+
+// termFilter returns a condition matching rows where any of the columns contains any of the terms,
+// adding the terms to params
+func termFilter(columns []string, terms []string, params map[string]interface{}) string {
+	var conditions []string
+	for i, term := range terms {
+		name := fmt.Sprintf("term%d", i)
+		params[name] = term
+		for _, column := range columns {
+			conditions = append(conditions, fmt.Sprintf("STRPOS(LOWER(IFNULL(%s, '')), @%s) > 0", column, name))
+		}
+	}
+	if len(conditions) == 0 {
+		return "FALSE"
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")"
+}
+
+// FindRequests lists a tenant's requests whose raw or extracted data contains any of the terms
+func (r *Repository) FindRequests(ctx context.Context, tenantID string, terms []string) ([]*models.Request, error) {
+	params := map[string]interface{}{"tenant_id": tenantID}
+	stmt := spanner.Statement{
+		SQL: `SELECT request_id, tenant_id, source, request_type, status, data,
+		             ai_normalized, ai_extracted, call_id, recording_url,
+		             transcription_data, ai_analysis, lead_score, communication_mode,
+		             spam_likelihood, call_classification, created_at, updated_at
+		      FROM requests
+		      WHERE tenant_id = @tenant_id
+		        AND (` + termFilter([]string{"data", "ai_extracted", "ai_normalized"}, terms, params) + `
+		             OR STARTS_WITH(ai_extracted, @sealed_prefix))
+		      ORDER BY created_at`,
+		Params: params,
+	}
+	// Encrypted extracted data can only be searched once decrypted
+	params["sealed_prefix"] = encryption.FieldPrefix
+
+	iter := r.client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+
+	var requests []*models.Request
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to search requests: %w", err)
+		}
+
+		var req models.Request
+		if err := row.Columns(
+			&req.RequestID,
+			&req.TenantID,
+			&req.Source,
+			&req.RequestType,
+			&req.Status,
+			&req.Data,
+			&req.AINormalized,
+			&req.AIExtracted,
+			&req.CallID,
+			&req.RecordingURL,
+			&req.TranscriptionData,
+			&req.AIAnalysis,
+			&req.LeadScore,
+			&req.CommunicationMode,
+			&req.SpamLikelihood,
+			&req.CallClassification,
+			&req.CreatedAt,
+			&req.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan request row: %w", err)
+		}
+		sealed := encryption.IsEncryptedField(req.AIExtracted)
+		if err := r.openRequest(ctx, &req); err != nil {
+			return nil, err
+		}
+		if sealed && !containsAnyTerm(terms, req.Data, req.AIExtracted, req.AINormalized) {
+			continue
+		}
+		requests = append(requests, &req)
+	}
+
+	return requests, nil
+}
+
+// containsAnyTerm reports whether any of the values contains any of the lower-case terms, ignoring case
+func containsAnyTerm(terms []string, values ...string) bool {
+	for _, value := range values {
+		lower := strings.ToLower(value)
+		for _, term := range terms {
+			if strings.Contains(lower, term) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FindCRMIntegrations lists a tenant's CRM integration records whose config contains any of the terms
+func (r *Repository) FindCRMIntegrations(ctx context.Context, tenantID string, terms []string) ([]*models.CRMIntegration, error) {
+	params := map[string]interface{}{"tenant_id": tenantID}
+	stmt := spanner.Statement{
+		SQL: `SELECT integration_id, tenant_id, crm_type, config,
+		             status, created_at, updated_at
+		      FROM crm_integrations
+		      WHERE tenant_id = @tenant_id
+		        AND ` + termFilter([]string{"config"}, terms, params),
+		Params: params,
+	}
+
+	iter := r.client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+
+	var integrations []*models.CRMIntegration
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to search CRM integrations: %w", err)
+		}
+
+		var integration models.CRMIntegration
+		if err := row.Columns(
+			&integration.IntegrationID,
+			&integration.TenantID,
+			&integration.CRMType,
+			&integration.Config,
+			&integration.Status,
+			&integration.CreatedAt,
+			&integration.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan CRM integration row: %w", err)
+		}
+		integrations = append(integrations, &integration)
+	}
+
+	return integrations, nil
+}
+
+// ListCallRecordingsByCall lists the recordings of a call, including their transcriptions
+func (r *Repository) ListCallRecordingsByCall(ctx context.Context, tenantID, callID string) ([]*models.CallRecording, error) {
+	stmt := spanner.Statement{
+		SQL: `SELECT recording_id, tenant_id, call_id, storage_url,
+		             transcription_status, transcription_data, created_at
+		      FROM call_recordings
+		      WHERE tenant_id = @tenant_id
+		        AND call_id = @call_id`,
+		Params: map[string]interface{}{
+			"tenant_id": tenantID,
+			"call_id":   callID,
+		},
+	}
+
+	iter := r.client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+
+	var recordings []*models.CallRecording
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to query call recordings: %w", err)
+		}
+
+		var recording models.CallRecording
+		if err := row.Columns(
+			&recording.RecordingID,
+			&recording.TenantID,
+			&recording.CallID,
+			&recording.StorageURL,
+			&recording.TranscriptionStatus,
+			&recording.TranscriptionData,
+			&recording.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan call recording row: %w", err)
+		}
+		if recording.TranscriptionData, err = r.openField(ctx, tenantID, encryption.PurposeTranscription, recording.TranscriptionData); err != nil {
+			return nil, err
+		}
+		recordings = append(recordings, &recording)
+	}
+
+	return recordings, nil
+}
+
+// ListAIProcessingLogsByRequest lists the AI processing logs of a request
+func (r *Repository) ListAIProcessingLogsByRequest(ctx context.Context, tenantID, requestID string) ([]*models.AIProcessingLog, error) {
+	stmt := spanner.Statement{
+		SQL: `SELECT log_id, tenant_id, request_id, analysis_type,
+		             status, processing_data, created_at, updated_at
+		      FROM ai_processing_logs
+		      WHERE tenant_id = @tenant_id
+		        AND request_id = @request_id
+		      ORDER BY created_at`,
+		Params: map[string]interface{}{
+			"tenant_id":  tenantID,
+			"request_id": requestID,
+		},
+	}
+
+	iter := r.client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+
+	var logs []*models.AIProcessingLog
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to query AI processing logs: %w", err)
+		}
+
+		var log models.AIProcessingLog
+		if err := row.Columns(
+			&log.LogID,
+			&log.TenantID,
+			&log.RequestID,
+			&log.AnalysisType,
+			&log.Status,
+			&log.ProcessingData,
+			&log.CreatedAt,
+			&log.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan AI processing log row: %w", err)
+		}
+		processingData, err := r.openField(ctx, tenantID, encryption.PurposeProcessingData, &log.ProcessingData)
+		if err != nil {
+			return nil, err
+		}
+		log.ProcessingData = *processingData
+		logs = append(logs, &log)
+	}
+
+	return logs, nil
+}
+
+// ListWebhookEventsByCall lists the webhook events received for a call
+func (r *Repository) ListWebhookEventsByCall(ctx context.Context, callID string) ([]*models.WebhookEvent, error) {
+	stmt := spanner.Statement{
+		SQL: `SELECT event_id, webhook_source, call_id, processing_status, created_at
+		      FROM webhook_events
+		      WHERE call_id = @call_id`,
+		Params: map[string]interface{}{
+			"call_id": callID,
+		},
+	}
+
+	iter := r.client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+
+	var events []*models.WebhookEvent
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to query webhook events: %w", err)
+		}
+
+		var event models.WebhookEvent
+		if err := row.Columns(
+			&event.EventID,
+			&event.WebhookSource,
+			&event.CallID,
+			&event.ProcessingStatus,
+			&event.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook event row: %w", err)
+		}
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
+
+// DeleteSubjectRows deletes a data subject's rows, and the jobs and fingerprints of their calls, in
+// one transaction
+func (r *Repository) DeleteSubjectRows(ctx context.Context, tenantID string, artifacts *privacy.Artifacts) (int64, error) {
+	var requestIDs, recordingIDs, logIDs, integrationIDs, eventIDs []string
+	for _, req := range artifacts.Requests {
+		requestIDs = append(requestIDs, req.RequestID)
+	}
+	for _, recording := range artifacts.CallRecordings {
+		recordingIDs = append(recordingIDs, recording.RecordingID)
+	}
+	for _, log := range artifacts.ProcessingLogs {
+		logIDs = append(logIDs, log.LogID)
+	}
+	for _, integration := range artifacts.CRMIntegrations {
+		integrationIDs = append(integrationIDs, integration.IntegrationID)
+	}
+	for _, event := range artifacts.WebhookEvents {
+		eventIDs = append(eventIDs, event.EventID)
+	}
+
+	params := map[string]interface{}{
+		"tenant_id":       tenantID,
+		"request_ids":     requestIDs,
+		"recording_ids":   recordingIDs,
+		"log_ids":         logIDs,
+		"integration_ids": integrationIDs,
+		"event_ids":       eventIDs,
+		"call_ids":        artifacts.CallIDs(),
+	}
+	var statements []spanner.Statement
+	for _, sql := range []string{
+		`DELETE FROM requests WHERE tenant_id = @tenant_id AND request_id IN UNNEST(@request_ids)`,
+		`DELETE FROM call_recordings WHERE tenant_id = @tenant_id AND recording_id IN UNNEST(@recording_ids)`,
+		`DELETE FROM ai_processing_logs WHERE tenant_id = @tenant_id AND log_id IN UNNEST(@log_ids)`,
+		`DELETE FROM crm_integrations WHERE tenant_id = @tenant_id AND integration_id IN UNNEST(@integration_ids)`,
+		`DELETE FROM webhook_events WHERE event_id IN UNNEST(@event_ids)`,
+		`DELETE FROM transcription_jobs WHERE tenant_id = @tenant_id AND call_id IN UNNEST(@call_ids)`,
+		`DELETE FROM recording_fingerprints WHERE tenant_id = @tenant_id AND call_id IN UNNEST(@call_ids)`,
+	} {
+		statements = append(statements, spanner.Statement{SQL: sql, Params: params})
+	}
+
+	var rows int64
+	_, err := r.client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		counts, err := txn.BatchUpdate(ctx, statements)
+		if err != nil {
+			return err
+		}
+		rows = 0
+		for _, count := range counts {
+			rows += count
+		}
+		return nil
+	})
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete data subject rows: %w", err)
+	}
+
+	return rows, nil
+}
+
+// Columns of the data subject compliance log
+var dataSubjectLogColumns = []string{
+	"log_id", "tenant_id", "request_type", "subject_hash", "requested_by", "status", "counts",
+	"retained_calls", "crm_erasures", "verified", "error", "created_at", "completed_at",
+}
+
+// SaveDataSubjectLog writes a data subject request to the compliance log
+func (r *Repository) SaveDataSubjectLog(ctx context.Context, log *models.DataSubjectLog) error {
+	counts, err := marshalJSON(log.Counts)
+	if err != nil {
+		return fmt.Errorf("failed to marshal counts: %w", err)
+	}
+	erasures, err := marshalJSON(log.CRMErasures)
+	if err != nil {
+		return fmt.Errorf("failed to marshal CRM erasures: %w", err)
+	}
+
+	_, err = r.client.Apply(ctx, []*spanner.Mutation{
+		spanner.InsertOrUpdate("data_subject_log", dataSubjectLogColumns, []interface{}{
+			log.LogID,
+			log.TenantID,
+			log.RequestType,
+			log.SubjectHash,
+			log.RequestedBy,
+			log.Status,
+			counts,
+			log.RetainedCalls,
+			erasures,
+			log.Verified,
+			log.Error,
+			log.CreatedAt,
+			log.CompletedAt,
+		}),
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to save data subject log: %w", err)
+	}
+
+	return nil
+}
+
+// ListDataSubjectLogs lists a tenant's data subject requests, newest first, optionally for one subject
+func (r *Repository) ListDataSubjectLogs(ctx context.Context, tenantID, subjectHash string) ([]*models.DataSubjectLog, error) {
+	sql := `SELECT ` + strings.Join(dataSubjectLogColumns, ", ") + `
+		FROM data_subject_log
+		WHERE tenant_id = @tenant_id`
+	params := map[string]interface{}{"tenant_id": tenantID}
+	if subjectHash != "" {
+		sql += ` AND subject_hash = @subject_hash`
+		params["subject_hash"] = subjectHash
+	}
+	sql += ` ORDER BY created_at DESC`
+
+	iter := r.client.Single().Query(ctx, spanner.Statement{SQL: sql, Params: params})
+	defer iter.Stop()
+
+	var logs []*models.DataSubjectLog
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to query data subject logs: %w", err)
+		}
+
+		var log models.DataSubjectLog
+		var counts, erasures string
+		if err := row.Columns(
+			&log.LogID,
+			&log.TenantID,
+			&log.RequestType,
+			&log.SubjectHash,
+			&log.RequestedBy,
+			&log.Status,
+			&counts,
+			&log.RetainedCalls,
+			&erasures,
+			&log.Verified,
+			&log.Error,
+			&log.CreatedAt,
+			&log.CompletedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan data subject log row: %w", err)
+		}
+		if err := unmarshalJSON(counts, &log.Counts); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal counts: %w", err)
+		}
+		if err := unmarshalJSON(erasures, &log.CRMErasures); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal CRM erasures: %w", err)
+		}
+		logs = append(logs, &log)
+	}
+
+	return logs, nil
+}
+
 // Helper function to unmarshal JSON data
 func unmarshalJSON(data string, v interface{}) error {
 	return json.Unmarshal([]byte(data), v)
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrObjectNotFound is returned when an object does not exist
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key         string            `json:"key"`
+	Size        int64             `json:"size"`
+	ContentType string            `json:"content_type,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Updated     time.Time         `json:"updated"`
+}
+
+// PutOptions sets the attributes of a stored object
+type PutOptions struct {
+	ContentType string
+	Metadata    map[string]string
+}
+
+// BlobStore is an object store holding call recordings. Keys are slash-separated paths built by
+// the functions in layout.go.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, opts *PutOptions) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	SignedURL(ctx context.Context, key, method string, expiration time.Duration) (string, error)
+	Copy(ctx context.Context, srcKey, dstKey string) error
+	// URI returns the backend URI of an object, e.g. gs://bucket/key
+	URI(key string) string
+	Close() error
+}
+
+// BucketManager is implemented by backends that can create buckets and manage their lifecycle
+type BucketManager interface {
+	EnsureBucket(ctx context.Context, location string) error
+	SetLifecyclePolicy(ctx context.Context, retentionDays int) error
+}
+
+// Backend names accepted by NewBlobStore
+const (
+	BackendGCS   = "gcs"
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// StoreConfig selects and configures a blob store backend
+type StoreConfig struct {
+	Backend   string
+	Bucket    string
+	ProjectID string
+
+	// Local filesystem backend
+	LocalRoot       string
+	LocalBaseURL    string
+	LocalSigningKey string
+
+	// S3-compatible backend
+	S3Endpoint        string
+	S3Region          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3PathStyle       bool
+	HTTPClient        *http.Client
+}
+
+// NewBlobStore creates the blob store for the configured backend
+func NewBlobStore(ctx context.Context, config StoreConfig) (BlobStore, error) {
+	switch config.Backend {
+	case BackendGCS, "":
+		return NewGCSStore(ctx, config.Bucket, config.ProjectID)
+	case BackendLocal:
+		return NewLocalStore(config.LocalRoot, config.LocalBaseURL, config.LocalSigningKey)
+	case BackendS3:
+		return NewS3Store(S3Config{
+			Endpoint:        config.S3Endpoint,
+			Region:          config.S3Region,
+			Bucket:          config.Bucket,
+			AccessKeyID:     config.S3AccessKeyID,
+			SecretAccessKey: config.S3SecretAccessKey,
+			PathStyle:       config.S3PathStyle,
+			HTTPClient:      config.HTTPClient,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported storage backend: %s", config.Backend)
+	}
+}
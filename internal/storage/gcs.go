@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// GCSStore is a BlobStore backed by a Cloud Storage bucket
+type GCSStore struct {
+	client    *storage.Client
+	bucket    string
+	projectID string
+}
+
+// NewGCSStore creates a blob store for a Cloud Storage bucket
+func NewGCSStore(ctx context.Context, bucket, projectID string) (*GCSStore, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+
+	return &GCSStore{
+		client:    client,
+		bucket:    bucket,
+		projectID: projectID,
+	}, nil
+}
+
+// Close closes the storage client
+func (g *GCSStore) Close() error {
+	return g.client.Close()
+}
+
+// URI returns the gs:// URI of an object
+func (g *GCSStore) URI(key string) string {
+	return fmt.Sprintf("gs://%s/%s", g.bucket, key)
+}
+
+// Put writes an object
+func (g *GCSStore) Put(ctx context.Context, key string, data []byte, opts *PutOptions) error {
+	w := g.client.Bucket(g.bucket).Object(key).NewWriter(ctx)
+	if opts != nil {
+		w.ContentType = opts.ContentType
+		w.Metadata = opts.Metadata
+	}
+
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to close writer: %w", err)
+	}
+
+	return nil
+}
+
+// Get reads an object
+func (g *GCSStore) Get(ctx context.Context, key string) ([]byte, error) {
+	reader, err := g.client.Bucket(g.bucket).Object(key).NewReader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reader: %w", gcsError(err))
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+
+	return data, nil
+}
+
+// Delete removes an object
+func (g *GCSStore) Delete(ctx context.Context, key string) error {
+	if err := g.client.Bucket(g.bucket).Object(key).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete object: %w", gcsError(err))
+	}
+	return nil
+}
+
+// List returns the objects under a prefix
+func (g *GCSStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	iter := g.client.Bucket(g.bucket).Objects(ctx, &storage.Query{Prefix: prefix})
+
+	var objects []ObjectInfo
+	for {
+		attrs, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate objects: %w", err)
+		}
+
+		objects = append(objects, gcsObjectInfo(attrs))
+	}
+
+	return objects, nil
+}
+
+// Stat returns an object's attributes
+func (g *GCSStore) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	attrs, err := g.client.Bucket(g.bucket).Object(key).Attrs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object attributes: %w", gcsError(err))
+	}
+
+	info := gcsObjectInfo(attrs)
+	return &info, nil
+}
+
+// SignedURL generates a V4 signed URL for an object
+func (g *GCSStore) SignedURL(ctx context.Context, key, method string, expiration time.Duration) (string, error) {
+	opts := &storage.SignedURLOptions{
+		Scheme:  storage.SigningSchemeV4,
+		Method:  method,
+		Expires: time.Now().Add(expiration),
+	}
+
+	url, err := g.client.Bucket(g.bucket).SignedURL(key, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate signed URL: %w", err)
+	}
+
+	return url, nil
+}
+
+// Copy copies an object within the bucket
+func (g *GCSStore) Copy(ctx context.Context, srcKey, dstKey string) error {
+	bucket := g.client.Bucket(g.bucket)
+	if _, err := bucket.Object(dstKey).CopierFrom(bucket.Object(srcKey)).Run(ctx); err != nil {
+		return fmt.Errorf("failed to copy object: %w", gcsError(err))
+	}
+	return nil
+}
+
+// EnsureBucket creates the bucket if it doesn't exist
+func (g *GCSStore) EnsureBucket(ctx context.Context, location string) error {
+	bucket := g.client.Bucket(g.bucket)
+
+	// Check if bucket exists
+	if _, err := bucket.Attrs(ctx); err == nil {
+		return nil
+	}
+
+	if err := bucket.Create(ctx, g.projectID, &storage.BucketAttrs{
+		Location:          location,
+		StorageClass:      "STANDARD",
+		VersioningEnabled: false,
+		UniformBucketLevelAccess: storage.UniformBucketLevelAccess{
+			Enabled: true,
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to create bucket: %w", err)
+	}
+
+	return nil
+}
+
+// SetLifecyclePolicy moves objects to colder storage classes as they age and deletes them after
+// the retention period. A retentionDays of zero or less leaves out the delete rule, for when the
+// retention purge worker deletes recordings so per-tenant policies and legal holds are honoured.
+func (g *GCSStore) SetLifecyclePolicy(ctx context.Context, retentionDays int) error {
+	lifecycle := storage.Lifecycle{
+		Rules: []storage.LifecycleRule{
+			{
+				Action:    storage.LifecycleAction{Type: "SetStorageClass", StorageClass: "COLDLINE"},
+				Condition: storage.LifecycleCondition{AgeInDays: 90},
+			},
+			{
+				Action:    storage.LifecycleAction{Type: "SetStorageClass", StorageClass: "ARCHIVE"},
+				Condition: storage.LifecycleCondition{AgeInDays: 365},
+			},
+		},
+	}
+	if retentionDays > 0 {
+		lifecycle.Rules = append(lifecycle.Rules, storage.LifecycleRule{
+			Action:    storage.LifecycleAction{Type: "Delete"},
+			Condition: storage.LifecycleCondition{AgeInDays: int64(retentionDays)},
+		})
+	}
+
+	if _, err := g.client.Bucket(g.bucket).Update(ctx, storage.BucketAttrsToUpdate{Lifecycle: &lifecycle}); err != nil {
+		return fmt.Errorf("failed to update bucket lifecycle: %w", err)
+	}
+
+	return nil
+}
+
+// gcsObjectInfo converts Cloud Storage attributes to ObjectInfo
+func gcsObjectInfo(attrs *storage.ObjectAttrs) ObjectInfo {
+	return ObjectInfo{
+		Key:         attrs.Name,
+		Size:        attrs.Size,
+		ContentType: attrs.ContentType,
+		Metadata:    attrs.Metadata,
+		Updated:     attrs.Updated,
+	}
+}
+
+// gcsError maps a missing object to ErrObjectNotFound
+func gcsError(err error) error {
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return fmt.Errorf("%w: %v", ErrObjectNotFound, err)
+	}
+	return err
+}
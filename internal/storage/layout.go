@@ -0,0 +1,31 @@
+package storage
+
+import "fmt"
+
+// The object key layout is defined here and nowhere else. Every tenant's objects live under the
+// tenant ID so a tenant can be listed, exported or purged by prefix.
+
+// TenantPrefix returns the prefix of every object belonging to a tenant
+func TenantPrefix(tenantID string) string {
+	return fmt.Sprintf("%s/", tenantID)
+}
+
+// CallsPrefix returns the prefix of a tenant's call recordings
+func CallsPrefix(tenantID string) string {
+	return fmt.Sprintf("%s/calls/", tenantID)
+}
+
+// RecordingKey returns the key of a call's original recording
+func RecordingKey(tenantID, callID string) string {
+	return fmt.Sprintf("%s/calls/%s.mp3", tenantID, callID)
+}
+
+// RedactedRecordingKey returns the key of a call's recording with PII bleeped out
+func RedactedRecordingKey(tenantID, callID string) string {
+	return fmt.Sprintf("%s/calls/%s.redacted.wav", tenantID, callID)
+}
+
+// CallObjectKeys returns the keys of every object that may be stored for a call
+func CallObjectKeys(tenantID, callID string) []string {
+	return []string{RecordingKey(tenantID, callID), RedactedRecordingKey(tenantID, callID)}
+}
@@ -0,0 +1,357 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// localMetaSuffix names the sidecar file holding an object's content type and metadata
+const localMetaSuffix = ".meta.json"
+
+// LocalStore is a BlobStore on the local filesystem, for on-prem tenants and tests
+type LocalStore struct {
+	root       string
+	baseURL    string
+	signingKey []byte
+}
+
+// localMeta is the sidecar stored next to each object
+type localMeta struct {
+	ContentType string            `json:"content_type,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// NewLocalStore creates a blob store rooted at a directory. Signed URLs point at baseURL and are
+// verified by Handler with signingKey.
+func NewLocalStore(root, baseURL, signingKey string) (*LocalStore, error) {
+	if root == "" {
+		return nil, fmt.Errorf("local storage root is required")
+	}
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create storage root: %w", err)
+	}
+
+	return &LocalStore{
+		root:       root,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		signingKey: []byte(signingKey),
+	}, nil
+}
+
+// Close is a no-op for the local store
+func (l *LocalStore) Close() error {
+	return nil
+}
+
+// URI returns the file:// URI of an object
+func (l *LocalStore) URI(key string) string {
+	p, err := l.path(key)
+	if err != nil {
+		return ""
+	}
+	return "file://" + filepath.ToSlash(p)
+}
+
+// Put writes an object and its sidecar
+func (l *LocalStore) Put(ctx context.Context, key string, data []byte, opts *PutOptions) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+		return fmt.Errorf("failed to create object directory: %w", err)
+	}
+
+	// Write to a temporary file first so readers never see a partial object
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+
+	var meta localMeta
+	if opts != nil {
+		meta = localMeta{ContentType: opts.ContentType, Metadata: opts.Metadata}
+	}
+	metaData, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to marshal object metadata: %w", err)
+	}
+	if err := os.WriteFile(p+localMetaSuffix, metaData, 0o640); err != nil {
+		return fmt.Errorf("failed to write object metadata: %w", err)
+	}
+
+	return nil
+}
+
+// Get reads an object
+func (l *LocalStore) Get(ctx context.Context, key string) ([]byte, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", localError(err))
+	}
+	return data, nil
+}
+
+// Delete removes an object and its sidecar
+func (l *LocalStore) Delete(ctx context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(p); err != nil {
+		return fmt.Errorf("failed to delete object: %w", localError(err))
+	}
+	os.Remove(p + localMetaSuffix)
+	return nil
+}
+
+// List returns the objects whose keys start with prefix
+func (l *LocalStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	// Walk from the deepest directory the prefix names, then filter on the full prefix
+	dir := l.root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		p, err := l.path(prefix[:i])
+		if err != nil {
+			return nil, err
+		}
+		dir = p
+	}
+
+	var objects []ObjectInfo
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(p, localMetaSuffix) || strings.HasSuffix(p, ".tmp") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := l.stat(key, p)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, *info)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	return objects, nil
+}
+
+// Stat returns an object's attributes
+func (l *LocalStore) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := l.stat(key, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object attributes: %w", localError(err))
+	}
+	return info, nil
+}
+
+// SignedURL returns a URL under the store's base URL, signed with HMAC-SHA256 over the method, key
+// and expiry
+func (l *LocalStore) SignedURL(ctx context.Context, key, method string, expiration time.Duration) (string, error) {
+	if len(l.signingKey) == 0 {
+		return "", fmt.Errorf("local storage signing key is not configured")
+	}
+	if _, err := l.path(key); err != nil {
+		return "", err
+	}
+
+	expires := time.Now().Add(expiration).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", l.sign(method, key, expires))
+
+	return fmt.Sprintf("%s/%s?%s", l.baseURL, escapeKey(key), query.Encode()), nil
+}
+
+// Copy copies an object and its sidecar
+func (l *LocalStore) Copy(ctx context.Context, srcKey, dstKey string) error {
+	src, err := l.path(srcKey)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("failed to copy object: %w", localError(err))
+	}
+	meta := l.readMeta(src)
+
+	if err := l.Put(ctx, dstKey, data, &PutOptions{ContentType: meta.ContentType, Metadata: meta.Metadata}); err != nil {
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+	return nil
+}
+
+// Handler serves objects through signed URLs. Mount it at the path of the store's base URL with
+// http.StripPrefix.
+func (l *LocalStore) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+
+		expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+		if err != nil || len(l.signingKey) == 0 {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+		expected := l.sign(r.Method, key, expires)
+		if !hmac.Equal([]byte(expected), []byte(r.URL.Query().Get("signature"))) {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+		if time.Now().Unix() > expires {
+			http.Error(w, "signed URL expired", http.StatusForbidden)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			data, err := l.Get(r.Context(), key)
+			if err != nil {
+				writeLocalError(w, err)
+				return
+			}
+			p, _ := l.path(key)
+			if ct := l.readMeta(p).ContentType; ct != "" {
+				w.Header().Set("Content-Type", ct)
+			}
+			w.Write(data)
+		case http.MethodPut:
+			data, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := l.Put(r.Context(), key, data, &PutOptions{ContentType: r.Header.Get("Content-Type")}); err != nil {
+				writeLocalError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// path resolves a key to a file under the root, rejecting keys that escape it
+func (l *LocalStore) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", fmt.Errorf("invalid object key: %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == ".." {
+			return "", fmt.Errorf("invalid object key: %q", key)
+		}
+	}
+	if strings.HasSuffix(key, localMetaSuffix) {
+		return "", fmt.Errorf("invalid object key: %q", key)
+	}
+	return filepath.Join(l.root, filepath.FromSlash(path.Clean(key))), nil
+}
+
+// stat builds the ObjectInfo of a file
+func (l *LocalStore) stat(key, p string) (*ObjectInfo, error) {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return nil, fs.ErrNotExist
+	}
+
+	meta := l.readMeta(p)
+	return &ObjectInfo{
+		Key:         key,
+		Size:        fi.Size(),
+		ContentType: meta.ContentType,
+		Metadata:    meta.Metadata,
+		Updated:     fi.ModTime(),
+	}, nil
+}
+
+// readMeta reads an object's sidecar, returning empty metadata when there is none
+func (l *LocalStore) readMeta(p string) localMeta {
+	var meta localMeta
+	if data, err := os.ReadFile(p + localMetaSuffix); err == nil {
+		json.Unmarshal(data, &meta)
+	}
+	return meta
+}
+
+// sign computes the signature of a signed URL
+func (l *LocalStore) sign(method, key string, expires int64) string {
+	mac := hmac.New(sha256.New, l.signingKey)
+	fmt.Fprintf(mac, "%s\n%s\n%d", strings.ToUpper(method), key, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// localError maps a missing file to ErrObjectNotFound
+func localError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %v", ErrObjectNotFound, err)
+	}
+	return err
+}
+
+// writeLocalError writes the HTTP status matching a store error
+func writeLocalError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrObjectNotFound) {
+		http.Error(w, "object not found", http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// escapeKey URL-escapes each segment of a key
+func escapeKey(key string) string {
+	parts := strings.Split(key, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,401 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// s3MetaPrefix prefixes user metadata headers
+	s3MetaPrefix = "x-amz-meta-"
+	// s3Algorithm is the SigV4 signing algorithm name
+	s3Algorithm = "AWS4-HMAC-SHA256"
+	// s3TimeFormat is the SigV4 timestamp format
+	s3TimeFormat = "20060102T150405Z"
+)
+
+// S3Config configures an S3-compatible store such as MinIO
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PathStyle       bool // address the bucket in the path rather than the host name
+	HTTPClient      *http.Client
+}
+
+// S3Store is a BlobStore speaking the S3 REST API, signed with AWS Signature Version 4
+type S3Store struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3Store creates a blob store for an S3-compatible bucket
+func NewS3Store(config S3Config) (*S3Store, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("s3 bucket is required")
+	}
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint: %q", config.Endpoint)
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+
+	return &S3Store{
+		config:   config,
+		endpoint: endpoint,
+		client:   client,
+		now:      time.Now,
+	}, nil
+}
+
+// Close is a no-op for the S3 store
+func (s *S3Store) Close() error {
+	return nil
+}
+
+// URI returns the s3:// URI of an object
+func (s *S3Store) URI(key string) string {
+	return fmt.Sprintf("s3://%s/%s", s.config.Bucket, key)
+}
+
+// Put uploads an object
+func (s *S3Store) Put(ctx context.Context, key string, data []byte, opts *PutOptions) error {
+	header := http.Header{}
+	if opts != nil {
+		if opts.ContentType != "" {
+			header.Set("Content-Type", opts.ContentType)
+		}
+		for k, v := range opts.Metadata {
+			header.Set(s3MetaPrefix+k, v)
+		}
+	}
+
+	resp, err := s.do(ctx, http.MethodPut, key, nil, header, data)
+	if err != nil {
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get downloads an object
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	return data, nil
+}
+
+// Delete removes an object. S3 reports success for missing keys, so a HEAD first keeps the
+// ErrObjectNotFound contract of the other backends.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if _, err := s.Stat(ctx, key); err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// s3ListResult is the ListObjectsV2 response body
+type s3ListResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List returns the objects under a prefix. ListObjectsV2 doesn't return content types or user
+// metadata; use Stat for those.
+func (s *S3Store) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+		var result s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode object listing: %w", err)
+		}
+
+		for _, c := range result.Contents {
+			objects = append(objects, ObjectInfo{Key: c.Key, Size: c.Size, Updated: c.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+
+	return objects, nil
+}
+
+// Stat returns an object's attributes
+func (s *S3Store) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	resp, err := s.do(ctx, http.MethodHead, key, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object attributes: %w", err)
+	}
+	resp.Body.Close()
+
+	info := &ObjectInfo{
+		Key:         key,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if updated, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.Updated = updated
+	}
+	for name, values := range resp.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, s3MetaPrefix) && len(values) > 0 {
+			if info.Metadata == nil {
+				info.Metadata = make(map[string]string)
+			}
+			info.Metadata[strings.TrimPrefix(lower, s3MetaPrefix)] = values[0]
+		}
+	}
+
+	return info, nil
+}
+
+// SignedURL returns a presigned URL using SigV4 query authentication
+func (s *S3Store) SignedURL(ctx context.Context, key, method string, expiration time.Duration) (string, error) {
+	if expiration <= 0 || expiration > 7*24*time.Hour {
+		return "", fmt.Errorf("signed URL expiration must be between 0 and 7 days")
+	}
+
+	u := s.objectURL(key)
+	now := s.now().UTC()
+	scope := s.scope(now)
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s.config.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expiration.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonical := strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	query.Set("X-Amz-Signature", s.signature(now, scope, canonical))
+
+	u.RawQuery = canonicalQuery(query)
+	return u.String(), nil
+}
+
+// Copy copies an object within the bucket
+func (s *S3Store) Copy(ctx context.Context, srcKey, dstKey string) error {
+	header := http.Header{}
+	header.Set("x-amz-copy-source", "/"+s.config.Bucket+"/"+escapeKey(srcKey))
+
+	resp, err := s.do(ctx, http.MethodPut, dstKey, nil, header, nil)
+	if err != nil {
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends a signed request and maps error statuses, returning the response on success
+func (s *S3Store) do(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	u := s.objectURL(key)
+	if query != nil {
+		u.RawQuery = canonicalQuery(query)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range header {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	req.ContentLength = int64(len(body))
+	s.signRequest(req, body)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+		}
+		return nil, fmt.Errorf("s3 returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	return resp, nil
+}
+
+// objectURL returns the URL of an object, or of the bucket when key is empty
+func (s *S3Store) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	base := strings.TrimSuffix(u.Path, "/")
+	if s.config.PathStyle {
+		u.Path = base + "/" + s.config.Bucket + "/" + key
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+		u.Path = base + "/" + key
+	}
+	u.RawPath = ""
+	return &u
+}
+
+// signRequest adds SigV4 Authorization headers to a request
+func (s *S3Store) signRequest(req *http.Request, body []byte) {
+	now := s.now().UTC()
+	payloadHash := sha256Hex(body)
+	req.Header.Set("x-amz-date", now.Format(s3TimeFormat))
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	req.Header.Set("Host", req.URL.Host)
+
+	// Sign host and every x-amz-* and content-type header
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "content-type" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := s.scope(now)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.config.AccessKeyID, scope, signedHeaders, s.signature(now, scope, canonical)))
+}
+
+// scope returns the SigV4 credential scope for a date
+func (s *S3Store) scope(t time.Time) string {
+	return fmt.Sprintf("%s/%s/s3/aws4_request", t.Format("20060102"), s.config.Region)
+}
+
+// signature signs a canonical request with the derived SigV4 key
+func (s *S3Store) signature(t time.Time, scope, canonicalRequest string) string {
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		t.Format(s3TimeFormat),
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), t.Format("20060102"))
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalQuery encodes query parameters sorted by key with SigV4 escaping
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, s3Escape(k)+"="+s3Escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes everything except unreserved characters, as SigV4 requires
+func s3Escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// hmacSHA256 computes an HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// sha256Hex returns the hex SHA-256 of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -2,231 +2,257 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io"
+	"strings"
 	"time"
 
-	"cloud.google.com/go/storage"
-
+	"github.com/home-renovators/ingestion-pipeline/internal/encryption"
 	"github.com/home-renovators/ingestion-pipeline/pkg/config"
 )
 
-// Service handles Cloud Storage operations
+// Service stores call recordings in the configured blob store
 type Service struct {
-	client     *storage.Client
-	audioBucket string
-	projectID  string
+	store    BlobStore
+	envelope *encryption.Envelope // encrypts recordings when set
 }
 
-// NewService creates a new storage service
+// NewService creates a new storage service for the configured backend
 func NewService(ctx context.Context, cfg *config.Config) (*Service, error) {
-	client, err := storage.NewClient(ctx)
+	store, err := NewBlobStore(ctx, StoreConfig{
+		Backend:           cfg.StorageBackend,
+		Bucket:            cfg.AudioBucket,
+		ProjectID:         cfg.ProjectID,
+		LocalRoot:         cfg.LocalStorageRoot,
+		LocalBaseURL:      cfg.LocalStorageURL,
+		LocalSigningKey:   cfg.LocalSigningKey,
+		S3Endpoint:        cfg.S3Endpoint,
+		S3Region:          cfg.S3Region,
+		S3AccessKeyID:     cfg.S3AccessKeyID,
+		S3SecretAccessKey: cfg.S3SecretAccessKey,
+		S3PathStyle:       cfg.S3PathStyle,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create storage client: %w", err)
+		return nil, fmt.Errorf("failed to create blob store: %w", err)
 	}
 
-	return &Service{
-		client:     client,
-		audioBucket: cfg.AudioBucket,
-		projectID:  cfg.ProjectID,
-	}, nil
+	return NewServiceWithStore(store), nil
+}
+
+// NewServiceWithStore creates a storage service on an existing blob store
+func NewServiceWithStore(store BlobStore) *Service {
+	return &Service{store: store}
 }
 
-// Close closes the storage client
+// SetEnvelope enables client-side encryption of recordings with per-tenant data keys. Recordings
+// stored before encryption was enabled are still read as plaintext.
+func (s *Service) SetEnvelope(envelope *encryption.Envelope) {
+	s.envelope = envelope
+}
+
+// Encrypted reports whether recordings are encrypted client-side, in which case they can only be
+// read through this service
+func (s *Service) Encrypted() bool {
+	return s.envelope != nil
+}
+
+// Store returns the underlying blob store
+func (s *Service) Store() BlobStore {
+	return s.store
+}
+
+// Close closes the blob store
 func (s *Service) Close() error {
-	return s.client.Close()
+	return s.store.Close()
 }
 
-// StoreAudioFile stores an audio file in Cloud Storage
+// StoreAudioFile stores a call recording and returns its URI
 func (s *Service) StoreAudioFile(ctx context.Context, tenantID, callID string, audioData []byte) (string, error) {
-	objectPath := fmt.Sprintf("%s/calls/%s.mp3", tenantID, callID)
-
-	bucket := s.client.Bucket(s.audioBucket)
-	obj := bucket.Object(objectPath)
-
-	// Set object attributes
-	w := obj.NewWriter(ctx)
-	w.ContentType = "audio/mpeg"
-	w.Metadata = map[string]string{
-		"tenant_id": tenantID,
-		"call_id":   callID,
-		"uploaded_at": time.Now().Format(time.RFC3339),
+	key := RecordingKey(tenantID, callID)
+
+	opts, audioData, err := s.seal(ctx, tenantID, audioData, &PutOptions{
+		ContentType: "audio/mpeg",
+		Metadata: map[string]string{
+			"tenant_id":   tenantID,
+			"call_id":     callID,
+			"uploaded_at": time.Now().Format(time.RFC3339),
+		},
+	})
+	if err != nil {
+		return "", err
 	}
 
-	// Write the audio data
-	if _, err := w.Write(audioData); err != nil {
-		w.Close()
+	if err := s.store.Put(ctx, key, audioData, opts); err != nil {
 		return "", fmt.Errorf("failed to write audio data: %w", err)
 	}
 
-	if err := w.Close(); err != nil {
-		return "", fmt.Errorf("failed to close writer: %w", err)
-	}
-
-	// Return the GCS URI
-	storageURL := fmt.Sprintf("gs://%s/%s", s.audioBucket, objectPath)
-	return storageURL, nil
+	return s.store.URI(key), nil
 }
 
-// GetAudioFile retrieves an audio file from Cloud Storage
-func (s *Service) GetAudioFile(ctx context.Context, tenantID, callID string) ([]byte, error) {
-	objectPath := fmt.Sprintf("%s/calls/%s.mp3", tenantID, callID)
-
-	bucket := s.client.Bucket(s.audioBucket)
-	obj := bucket.Object(objectPath)
-
-	reader, err := obj.NewReader(ctx)
+// StoreRedactedAudioFile stores the redacted WAV copy of a call recording alongside the original.
+// The original is kept for reprocessing but is no longer served for playback, and both are deleted
+// with the rest of the call.
+func (s *Service) StoreRedactedAudioFile(ctx context.Context, tenantID, callID string, audioData []byte) (string, error) {
+	key := RedactedRecordingKey(tenantID, callID)
+
+	opts, audioData, err := s.seal(ctx, tenantID, audioData, &PutOptions{
+		ContentType: "audio/wav",
+		Metadata: map[string]string{
+			"tenant_id":   tenantID,
+			"call_id":     callID,
+			"redacted":    "true",
+			"uploaded_at": time.Now().Format(time.RFC3339),
+		},
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create reader: %w", err)
+		return "", err
 	}
-	defer reader.Close()
 
-	audioData, err := io.ReadAll(reader)
+	if err := s.store.Put(ctx, key, audioData, opts); err != nil {
+		return "", fmt.Errorf("failed to write redacted audio data: %w", err)
+	}
+
+	return s.store.URI(key), nil
+}
+
+// GetAudioFile retrieves a call recording
+func (s *Service) GetAudioFile(ctx context.Context, tenantID, callID string) ([]byte, error) {
+	audioData, err := s.store.Get(ctx, RecordingKey(tenantID, callID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read audio data: %w", err)
 	}
 
-	return audioData, nil
+	return s.open(ctx, tenantID, audioData)
 }
 
-// DeleteAudioFile deletes an audio file from Cloud Storage
+// DeleteAudioFile deletes a call recording
 func (s *Service) DeleteAudioFile(ctx context.Context, tenantID, callID string) error {
-	objectPath := fmt.Sprintf("%s/calls/%s.mp3", tenantID, callID)
-
-	bucket := s.client.Bucket(s.audioBucket)
-	obj := bucket.Object(objectPath)
-
-	if err := obj.Delete(ctx); err != nil {
+	if err := s.store.Delete(ctx, RecordingKey(tenantID, callID)); err != nil {
 		return fmt.Errorf("failed to delete audio file: %w", err)
 	}
 
 	return nil
 }
 
-// ListAudioFiles lists audio files for a tenant
-func (s *Service) ListAudioFiles(ctx context.Context, tenantID string) ([]string, error) {
-	prefix := fmt.Sprintf("%s/calls/", tenantID)
+// ListCallObjects returns the keys of the recordings stored for a call
+func (s *Service) ListCallObjects(ctx context.Context, tenantID, callID string) ([]string, error) {
+	var keys []string
+	for _, key := range CallObjectKeys(tenantID, callID) {
+		if _, err := s.store.Stat(ctx, key); err != nil {
+			if errors.Is(err, ErrObjectNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to stat %s: %w", key, err)
+		}
+		keys = append(keys, key)
+	}
 
-	bucket := s.client.Bucket(s.audioBucket)
-	query := &storage.Query{Prefix: prefix}
+	return keys, nil
+}
 
-	iter := bucket.Objects(ctx, query)
+// ReadObject reads and decrypts one of a tenant's objects
+func (s *Service) ReadObject(ctx context.Context, tenantID, key string) ([]byte, error) {
+	if !strings.HasPrefix(key, TenantPrefix(tenantID)) {
+		return nil, fmt.Errorf("object %s does not belong to tenant %s", key, tenantID)
+	}
 
-	var files []string
-	for {
-		objAttrs, err := iter.Next()
-		if err == storage.ErrObjectNotExist {
-			break
-		}
-		if err != nil {
-			return nil, fmt.Errorf("failed to iterate objects: %w", err)
+	data, err := s.store.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+
+	return s.open(ctx, tenantID, data)
+}
+
+// DeleteCallObjects deletes the original and redacted recordings of a call, returning how many
+// existed
+func (s *Service) DeleteCallObjects(ctx context.Context, tenantID, callID string) (int, error) {
+	deleted := 0
+	for _, key := range CallObjectKeys(tenantID, callID) {
+		if err := s.store.Delete(ctx, key); err != nil {
+			if errors.Is(err, ErrObjectNotFound) {
+				continue
+			}
+			return deleted, fmt.Errorf("failed to delete %s: %w", key, err)
 		}
+		deleted++
+	}
+
+	return deleted, nil
+}
 
-		files = append(files, objAttrs.Name)
+// ListAudioFiles lists the recording keys for a tenant
+func (s *Service) ListAudioFiles(ctx context.Context, tenantID string) ([]string, error) {
+	objects, err := s.store.List(ctx, CallsPrefix(tenantID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audio files: %w", err)
+	}
+
+	files := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		files = append(files, obj.Key)
 	}
 
 	return files, nil
 }
 
-// GetAudioFileMetadata retrieves metadata for an audio file
+// GetAudioFileMetadata retrieves metadata for a call recording
 func (s *Service) GetAudioFileMetadata(ctx context.Context, tenantID, callID string) (map[string]string, error) {
-	objectPath := fmt.Sprintf("%s/calls/%s.mp3", tenantID, callID)
-
-	bucket := s.client.Bucket(s.audioBucket)
-	obj := bucket.Object(objectPath)
-
-	attrs, err := obj.Attrs(ctx)
+	info, err := s.store.Stat(ctx, RecordingKey(tenantID, callID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get object attributes: %w", err)
 	}
 
-	return attrs.Metadata, nil
+	return info.Metadata, nil
 }
 
 // SetLifecyclePolicy sets lifecycle policies for the audio bucket
 func (s *Service) SetLifecyclePolicy(ctx context.Context, retentionDays int) error {
-	bucket := s.client.Bucket(s.audioBucket)
-
-	lifecycle := storage.Lifecycle{
-		Rules: []storage.LifecycleRule{
-			{
-				Action: storage.LifecycleAction{
-					Type:         "SetStorageClass",
-					StorageClass: "COLDLINE",
-				},
-				Condition: storage.LifecycleCondition{
-					AgeInDays: 90,
-				},
-			},
-			{
-				Action: storage.LifecycleAction{
-					Type:         "SetStorageClass",
-					StorageClass: "ARCHIVE",
-				},
-				Condition: storage.LifecycleCondition{
-					AgeInDays: 365,
-				},
-			},
-			{
-				Action: storage.LifecycleAction{
-					Type: "Delete",
-				},
-				Condition: storage.LifecycleCondition{
-					AgeInDays: int64(retentionDays),
-				},
-			},
-		},
-	}
-
-	bucketAttrsToUpdate := storage.BucketAttrsToUpdate{
-		Lifecycle: &lifecycle,
-	}
-
-	if _, err := bucket.Update(ctx, bucketAttrsToUpdate); err != nil {
-		return fmt.Errorf("failed to update bucket lifecycle: %w", err)
+	manager, ok := s.store.(BucketManager)
+	if !ok {
+		return fmt.Errorf("storage backend does not support lifecycle policies")
 	}
 
-	return nil
+	return manager.SetLifecyclePolicy(ctx, retentionDays)
 }
 
 // CreateBucketIfNotExists creates the audio bucket if it doesn't exist
 func (s *Service) CreateBucketIfNotExists(ctx context.Context, location string) error {
-	bucket := s.client.Bucket(s.audioBucket)
-
-	// Check if bucket exists
-	_, err := bucket.Attrs(ctx)
-	if err == nil {
-		return nil // Bucket already exists
+	manager, ok := s.store.(BucketManager)
+	if !ok {
+		return fmt.Errorf("storage backend does not support bucket management")
 	}
 
-	// Create bucket
-	if err := bucket.Create(ctx, s.projectID, &storage.BucketAttrs{
-		Location:     location,
-		StorageClass: "STANDARD",
-		VersioningEnabled: false,
-		UniformBucketLevelAccess: storage.UniformBucketLevelAccess{
-			Enabled: true,
-		},
-	}); err != nil {
-		return fmt.Errorf("failed to create bucket: %w", err)
-	}
+	return manager.EnsureBucket(ctx, location)
+}
 
-	return nil
+// PlaybackKey returns the key of the recording served for a call: the redacted copy when there is
+// one, the original otherwise
+func (s *Service) PlaybackKey(ctx context.Context, tenantID, callID string) (string, error) {
+	key := RedactedRecordingKey(tenantID, callID)
+	if _, err := s.store.Stat(ctx, key); err != nil {
+		if !errors.Is(err, ErrObjectNotFound) {
+			return "", fmt.Errorf("failed to stat %s: %w", key, err)
+		}
+		key = RecordingKey(tenantID, callID)
+	}
+	return key, nil
 }
 
-// GenerateSignedURL generates a signed URL for accessing an audio file
+// GenerateSignedURL generates a signed URL for playing back a call recording, the redacted copy
+// when PII was bleeped out. Encrypted recordings can't be served this way since the reader would
+// get ciphertext.
 func (s *Service) GenerateSignedURL(ctx context.Context, tenantID, callID string, expiration time.Duration) (string, error) {
-	objectPath := fmt.Sprintf("%s/calls/%s.mp3", tenantID, callID)
-
-	bucket := s.client.Bucket(s.audioBucket)
+	if s.envelope != nil {
+		return "", fmt.Errorf("signed URLs are not available for encrypted recordings")
+	}
 
-	opts := &storage.SignedURLOptions{
-		Scheme:  storage.SigningSchemeV4,
-		Method:  "GET",
-		Expires: time.Now().Add(expiration),
+	key, err := s.PlaybackKey(ctx, tenantID, callID)
+	if err != nil {
+		return "", err
 	}
 
-	url, err := bucket.SignedURL(objectPath, opts)
+	url, err := s.store.SignedURL(ctx, key, "GET", expiration)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate signed URL: %w", err)
 	}
@@ -234,15 +260,9 @@ func (s *Service) GenerateSignedURL(ctx context.Context, tenantID, callID string
 	return url, nil
 }
 
-// CopyFile copies a file within the storage bucket
+// CopyFile copies an object within the store
 func (s *Service) CopyFile(ctx context.Context, srcPath, destPath string) error {
-	srcBucket := s.client.Bucket(s.audioBucket)
-	srcObj := srcBucket.Object(srcPath)
-
-	destBucket := s.client.Bucket(s.audioBucket)
-	destObj := destBucket.Object(destPath)
-
-	if _, err := destObj.CopierFrom(srcObj).Run(ctx); err != nil {
+	if err := s.store.Copy(ctx, srcPath, destPath); err != nil {
 		return fmt.Errorf("failed to copy file: %w", err)
 	}
 
@@ -251,36 +271,59 @@ func (s *Service) CopyFile(ctx context.Context, srcPath, destPath string) error
 
 // GetStorageStatistics returns storage statistics for a tenant
 func (s *Service) GetStorageStatistics(ctx context.Context, tenantID string) (*StorageStatistics, error) {
-	prefix := fmt.Sprintf("%s/calls/", tenantID)
-
-	bucket := s.client.Bucket(s.audioBucket)
-	query := &storage.Query{Prefix: prefix}
-
-	iter := bucket.Objects(ctx, query)
+	objects, err := s.store.List(ctx, CallsPrefix(tenantID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audio files: %w", err)
+	}
 
 	stats := &StorageStatistics{
 		TenantID: tenantID,
 	}
-
-	for {
-		objAttrs, err := iter.Next()
-		if err == storage.ErrObjectNotExist {
-			break
-		}
-		if err != nil {
-			return nil, fmt.Errorf("failed to iterate objects: %w", err)
-		}
-
+	for _, obj := range objects {
 		stats.FileCount++
-		stats.TotalSizeBytes += objAttrs.Size
+		stats.TotalSizeBytes += obj.Size
 	}
 
 	return stats, nil
 }
 
+// seal encrypts a recording for a tenant when encryption is enabled, recording the original
+// content type in the object metadata
+func (s *Service) seal(ctx context.Context, tenantID string, data []byte, opts *PutOptions) (*PutOptions, []byte, error) {
+	if s.envelope == nil {
+		return opts, data, nil
+	}
+
+	sealed, err := s.envelope.Encrypt(ctx, tenantID, encryption.PurposeAudio, data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encrypt audio data: %w", err)
+	}
+
+	opts.Metadata["encrypted"] = "true"
+	opts.Metadata["original_content_type"] = opts.ContentType
+	opts.ContentType = "application/octet-stream"
+	return opts, sealed, nil
+}
+
+// open decrypts a recording written by seal. Plaintext recordings are returned unchanged.
+func (s *Service) open(ctx context.Context, tenantID string, data []byte) ([]byte, error) {
+	if !encryption.IsEncrypted(data) {
+		return data, nil
+	}
+	if s.envelope == nil {
+		return nil, fmt.Errorf("recording is encrypted but encryption is not configured")
+	}
+
+	opened, err := s.envelope.Decrypt(ctx, tenantID, encryption.PurposeAudio, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt audio data: %w", err)
+	}
+	return opened, nil
+}
+
 // StorageStatistics represents storage usage statistics
 type StorageStatistics struct {
 	TenantID       string `json:"tenant_id"`
 	FileCount      int64  `json:"file_count"`
 	TotalSizeBytes int64  `json:"total_size_bytes"`
-}
\ No newline at end of file
+}
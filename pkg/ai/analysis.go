@@ -2,28 +2,27 @@ package ai
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"strings"
 	"time"
 
-	aiplatform "cloud.google.com/go/aiplatform/apiv1"
-	"cloud.google.com/go/aiplatform/apiv1/aiplatformpb"
-	"google.golang.org/protobuf/types/known/structpb"
-
 	"github.com/home-renovators/ingestion-pipeline/pkg/models"
 )
 
-// AnalysisService handles AI-powered content analysis using Vertex AI
+// AnalysisService handles AI-powered content analysis using an LLM provider
 type AnalysisService struct {
-	aiClient *aiplatform.PredictionClient
-	config   *AnalysisConfig
+	llm     LLMProvider
+	prompts *PromptLibrary
+	config  *AnalysisConfig
 }
 
 // AnalysisConfig contains configuration for AI analysis
 type AnalysisConfig struct {
+	Provider     string  `json:"provider"` // vertex (default), openai or fake
 	ProjectID    string  `json:"project_id"`
 	Location     string  `json:"location"`
+	BaseURL      string  `json:"base_url"` // OpenAI-compatible API root
+	APIKey       string  `json:"-"`
+	FakeScript   string  `json:"fake_script"`
 	Model        string  `json:"model"` // e.g., "gemini-2.5-flash"
 	Temperature  float32 `json:"temperature"`
 	MaxTokens    int32   `json:"max_tokens"`
@@ -48,20 +47,37 @@ func NewAnalysisService(ctx context.Context, config *AnalysisConfig) (*AnalysisS
 		config = DefaultAnalysisConfig()
 	}
 
-	aiClient, err := aiplatform.NewPredictionClient(ctx)
+	llm, err := NewProvider(ctx, &ProviderConfig{
+		Provider:   config.Provider,
+		ProjectID:  config.ProjectID,
+		Location:   config.Location,
+		BaseURL:    config.BaseURL,
+		APIKey:     config.APIKey,
+		FakeScript: config.FakeScript,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create AI platform client: %w", err)
+		return nil, fmt.Errorf("failed to create LLM provider: %w", err)
+	}
+
+	return NewAnalysisServiceWithProvider(llm, config), nil
+}
+
+// NewAnalysisServiceWithProvider creates an AI analysis service using the given LLM provider
+func NewAnalysisServiceWithProvider(llm LLMProvider, config *AnalysisConfig) *AnalysisService {
+	if config == nil {
+		config = DefaultAnalysisConfig()
 	}
 
 	return &AnalysisService{
-		aiClient: aiClient,
-		config:   config,
-	}, nil
+		llm:     llm,
+		prompts: DefaultPromptLibrary(),
+		config:  config,
+	}
 }
 
 // Close closes the analysis service
 func (as *AnalysisService) Close() error {
-	return as.aiClient.Close()
+	return as.llm.Close()
 }
 
 // AnalysisRequest contains parameters for content analysis
@@ -75,6 +91,10 @@ type AnalysisRequest struct {
 	CustomConfig   *AnalysisConfig       `json:"custom_config,omitempty"`
 	Context        map[string]interface{} `json:"context,omitempty"`
 	Priority       AnalysisPriority      `json:"priority"`
+	Language       string                `json:"language,omitempty"` // transcript language, e.g. "es-US"
+	PromptConfig   *models.AnalysisPromptConfig `json:"prompt_config,omitempty"`   // the tenant's prompt customization
+	TemplateVersion int                  `json:"template_version,omitempty"` // overrides the tenant's pinned template version
+	CallerHistory  []models.PriorInteraction `json:"caller_history,omitempty"` // the caller's earlier calls to the tenant, newest first
 }
 
 // AnalysisType defines the type of analysis to perform
@@ -86,6 +106,9 @@ const (
 	AnalysisTypeSentiment AnalysisType = "sentiment_analysis"
 	AnalysisTypeIntent    AnalysisType = "intent_classification"
 	AnalysisTypeLeadScore AnalysisType = "lead_scoring"
+	AnalysisTypeSummary   AnalysisType = "call_summary"
+	AnalysisTypeEntities  AnalysisType = "entity_extraction"
+	AnalysisTypeCombined  AnalysisType = "combined_analysis" // content, spam, sentiment, intent, lead score, summary and entities in one call
 	AnalysisTypeCustom    AnalysisType = "custom"
 )
 
@@ -105,6 +128,10 @@ type AnalysisResponse struct {
 	CallAnalysis *models.CallAnalysis   `json:"call_analysis,omitempty"`
 	SpamResult   *SpamAnalysisResult    `json:"spam_result,omitempty"`
 	SentimentResult *SentimentAnalysisResult `json:"sentiment_result,omitempty"`
+	IntentResult *IntentClassificationResult `json:"intent_result,omitempty"`
+	LeadScoreResult *LeadScoreResult `json:"lead_score_result,omitempty"`
+	SummaryResult *models.CallSummary `json:"summary_result,omitempty"`
+	EntitiesResult *models.CallEntities `json:"entities_result,omitempty"`
 	CustomResult map[string]interface{} `json:"custom_result,omitempty"`
 	Success      bool                   `json:"success"`
 	Error        string                 `json:"error,omitempty"`
@@ -116,21 +143,90 @@ type AnalysisResponse struct {
 
 // SpamAnalysisResult contains spam detection results
 type SpamAnalysisResult struct {
-	SpamLikelihood float64           `json:"spam_likelihood"` // 0-100
-	Confidence     float64           `json:"confidence"`      // 0-1
+	SpamLikelihood float64           `json:"spam_likelihood" schema:"min=0,max=100"` // 0-100
+	Confidence     float64           `json:"confidence" schema:"min=0,max=1"`        // 0-1
 	Indicators     []string          `json:"indicators"`
 	Reasoning      string            `json:"reasoning"`
-	Details        map[string]interface{} `json:"details"`
+	Details        map[string]interface{} `json:"details" schema:"-"`
 }
 
 // SentimentAnalysisResult contains sentiment analysis results
 type SentimentAnalysisResult struct {
-	Sentiment           string            `json:"sentiment"` // positive, neutral, negative
-	Confidence          float64           `json:"confidence"` // 0-1
+	Sentiment           string            `json:"sentiment" schema:"enum=positive|neutral|negative"`
+	Confidence          float64           `json:"confidence" schema:"min=0,max=1"`
 	EmotionalTone       string            `json:"emotional_tone"`
-	CustomerSatisfaction string           `json:"customer_satisfaction"` // high, medium, low
+	CustomerSatisfaction string           `json:"customer_satisfaction" schema:"enum=high|medium|low"`
 	KeyEmotions         []string          `json:"key_emotions"`
-	Details             map[string]interface{} `json:"details"`
+	Details             map[string]interface{} `json:"details" schema:"-"`
+}
+
+// IntentClassificationResult contains intent classification results
+type IntentClassificationResult struct {
+	PrimaryIntent    string   `json:"primary_intent" schema:"enum=quote_request|information_seeking|appointment_booking|complaint|follow_up|emergency|other"`
+	Confidence       float64  `json:"confidence" schema:"min=0,max=1"`
+	SecondaryIntents []string `json:"secondary_intents" schema:"enum=quote_request|information_seeking|appointment_booking|complaint|follow_up|emergency|other"`
+	Reasoning        string   `json:"reasoning"`
+}
+
+// LeadScoreResult contains lead scoring results
+type LeadScoreResult struct {
+	LeadScore      int                `json:"lead_score" schema:"min=1,max=100"`
+	Confidence     float64            `json:"confidence" schema:"min=0,max=1"`
+	ScoringFactors LeadScoringFactors `json:"scoring_factors"`
+	Reasoning      string             `json:"reasoning"`
+}
+
+// LeadScoringFactors are the 1-10 ratings a lead score is built from
+type LeadScoringFactors struct {
+	ProjectComplexity int `json:"project_complexity" schema:"min=1,max=10"`
+	BuyingReadiness   int `json:"buying_readiness" schema:"min=1,max=10"`
+	TimelineUrgency   int `json:"timeline_urgency" schema:"min=1,max=10"`
+	BudgetCapability  int `json:"budget_capability" schema:"min=1,max=10"`
+	EngagementQuality int `json:"engagement_quality" schema:"min=1,max=10"`
+}
+
+// CombinedAnalysisResult is the content, spam, sentiment, intent, lead score, summary and
+// entity extraction analyses of a call, answered in a single model call
+type CombinedAnalysisResult struct {
+	CallAnalysis models.CallAnalysis        `json:"call_analysis"`
+	Spam         SpamAnalysisResult         `json:"spam"`
+	Sentiment    SentimentAnalysisResult    `json:"sentiment"`
+	Intent       IntentClassificationResult `json:"intent"`
+	LeadScore    LeadScoreResult            `json:"lead_score"`
+	Summary      models.CallSummary         `json:"summary"`
+	Entities     models.CallEntities        `json:"entities"`
+}
+
+// CombinedMaxOutputTokens is the output budget of a combined analysis, which answers seven analyses at once
+const CombinedMaxOutputTokens int32 = 3072
+
+// Reconcile makes the call analysis agree with the dedicated sections where both answer the
+// same question. The dedicated sections are reasoned in more detail, so they win.
+func (r *CombinedAnalysisResult) Reconcile() {
+	r.CallAnalysis.Sentiment = r.Sentiment.Sentiment
+	r.CallAnalysis.LeadScore = r.LeadScore.LeadScore
+	// The intent section also knows "emergency", which the call analysis has no value for
+	if r.Intent.PrimaryIntent != "emergency" {
+		r.CallAnalysis.Intent = r.Intent.PrimaryIntent
+	}
+	r.CallAnalysis.Summary = &r.Summary
+}
+
+// responseSchemas are the JSON shapes Gemini must answer each analysis type with
+var responseSchemas = map[AnalysisType]*Schema{
+	AnalysisTypeContent:   SchemaFor(models.CallAnalysis{}),
+	AnalysisTypeSpam:      SchemaFor(SpamAnalysisResult{}),
+	AnalysisTypeSentiment: SchemaFor(SentimentAnalysisResult{}),
+	AnalysisTypeIntent:    SchemaFor(IntentClassificationResult{}),
+	AnalysisTypeLeadScore: SchemaFor(LeadScoreResult{}),
+	AnalysisTypeSummary:   SchemaFor(models.CallSummary{}),
+	AnalysisTypeEntities:  SchemaFor(models.CallEntities{}),
+	AnalysisTypeCombined:  SchemaFor(CombinedAnalysisResult{}),
+}
+
+// ResponseSchema returns the response schema of an analysis type, nil for free-form types
+func ResponseSchema(analysisType AnalysisType) *Schema {
+	return responseSchemas[analysisType]
 }
 
 // AnalysisMetadata contains metadata about the analysis process
@@ -140,6 +236,9 @@ type AnalysisMetadata struct {
 	OutputTokens    int64              `json:"output_tokens"`
 	ProcessingTime  time.Duration      `json:"processing_time"`
 	PromptTemplate  string             `json:"prompt_template"`
+	TemplateID      string             `json:"template_id,omitempty"`
+	TemplateVersion int                `json:"template_version,omitempty"`
+	Repaired        bool               `json:"repaired"` // the first answer was unusable and a repair prompt was issued
 	Parameters      map[string]interface{} `json:"parameters"`
 }
 
@@ -162,72 +261,50 @@ func (as *AnalysisService) AnalyzeContent(ctx context.Context, req *AnalysisRequ
 	}
 
 	var prompt string
+	var err error
 	switch req.AnalysisType {
-	case AnalysisTypeContent:
-		prompt = as.buildContentAnalysisPrompt(req.Transcription, req.CallDetails)
-	case AnalysisTypeSpam:
-		prompt = as.buildSpamDetectionPrompt(req.Transcription, req.CallDetails)
-	case AnalysisTypeSentiment:
-		prompt = as.buildSentimentAnalysisPrompt(req.Transcription, req.CallDetails)
-	case AnalysisTypeIntent:
-		prompt = as.buildIntentClassificationPrompt(req.Transcription, req.CallDetails)
-	case AnalysisTypeLeadScore:
-		prompt = as.buildLeadScoringPrompt(req.Transcription, req.CallDetails)
 	case AnalysisTypeCustom:
 		if req.CustomPrompt == "" {
 			return nil, fmt.Errorf("custom prompt required for custom analysis type")
 		}
 		prompt = req.CustomPrompt
 	default:
-		return nil, fmt.Errorf("unsupported analysis type: %s", req.AnalysisType)
+		rendered, err := as.prompts.RenderWithHistory(req.AnalysisType, req.TemplateVersion, req.PromptConfig, req.Transcription, req.CallDetails, req.CallerHistory)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build %s prompt: %w", req.AnalysisType, err)
+		}
+		prompt = rendered.Text + LanguageInstruction(req.Language)
+		response.Metadata.TemplateID = rendered.TemplateID
+		response.Metadata.TemplateVersion = rendered.TemplateVersion
 	}
 
 	response.Metadata.PromptTemplate = prompt
-	response.Metadata.InputTokens = int64(len(prompt))
-
-	// Create prediction request
-	endpoint := fmt.Sprintf("projects/%s/locations/%s/publishers/google/models/%s",
-		config.ProjectID, config.Location, config.Model)
-
-	instances, err := as.createGeminiInstances(prompt, config)
-	if err != nil {
-		response.Success = false
-		response.Error = fmt.Sprintf("failed to create instances: %v", err)
-		response.EndTime = time.Now()
-		response.Duration = time.Since(startTime)
-		return response, err
-	}
-
-	parameters, err := as.createGeminiParameters(config)
-	if err != nil {
-		response.Success = false
-		response.Error = fmt.Sprintf("failed to create parameters: %v", err)
-		response.EndTime = time.Now()
-		response.Duration = time.Since(startTime)
-		return response, err
+	response.Metadata.ModelUsed = config.Model
+
+	genReq := &GenerateRequest{
+		Model:           config.Model,
+		Prompt:          prompt,
+		Temperature:     config.Temperature,
+		MaxOutputTokens: config.MaxTokens,
+		TopP:            config.TopP,
+		TopK:            config.TopK,
 	}
 
-	predictionReq := &aiplatformpb.PredictRequest{
-		Endpoint:   endpoint,
-		Instances:  instances,
-		Parameters: parameters,
-	}
-
-	// Make the prediction
-	resp, err := as.aiClient.Predict(ctx, predictionReq)
-	if err != nil {
-		response.Success = false
-		response.Error = fmt.Sprintf("prediction failed: %v", err)
-		response.EndTime = time.Now()
-		response.Duration = time.Since(startTime)
-		return response, err
+	if req.AnalysisType == AnalysisTypeCustom {
+		err = as.generateCustom(ctx, genReq, response)
+	} else {
+		// Structured analysis types are constrained to, and validated against, their result type's JSON schema
+		genReq.ResponseSchema = TenantResponseSchema(req.AnalysisType, req.PromptConfig)
+		if req.AnalysisType == AnalysisTypeCombined && genReq.MaxOutputTokens < CombinedMaxOutputTokens {
+			genReq.MaxOutputTokens = CombinedMaxOutputTokens
+		}
+		err = as.generateStructured(ctx, genReq, req.AnalysisType, response)
+		ResolveEntities(response.EntitiesResult, req.Transcription)
+		ResolveContinuity(response.CallAnalysis, req.CallerHistory)
 	}
-
-	// Parse the response based on analysis type
-	err = as.parseAnalysisResponse(resp, req.AnalysisType, response)
 	if err != nil {
 		response.Success = false
-		response.Error = fmt.Sprintf("failed to parse response: %v", err)
+		response.Error = err.Error()
 		response.EndTime = time.Now()
 		response.Duration = time.Since(startTime)
 		return response, err
@@ -283,265 +360,87 @@ func (as *AnalysisService) AnalyzeBatch(ctx context.Context, requests []*Analysi
 	return responses, nil
 }
 
-// buildContentAnalysisPrompt creates the prompt for comprehensive content analysis
-func (as *AnalysisService) buildContentAnalysisPrompt(transcription string, callDetails *models.CallDetails) string {
-	var callInfo string
-	if callDetails != nil {
-		callInfo = fmt.Sprintf(`
-CALL METADATA:
-- Customer Name: %s
-- Customer Phone: %s
-- Customer Location: %s, %s
-- Call Duration: %d seconds
-- Source: %s
-- Tags: %s
-- Lead Status: %s`,
-			callDetails.CustomerName,
-			callDetails.CustomerPhoneNumber,
-			callDetails.CustomerCity,
-			callDetails.CustomerState,
-			callDetails.Duration,
-			callDetails.Source,
-			strings.Join(callDetails.Tags, ", "),
-			callDetails.LeadStatus)
-	}
-
-	return fmt.Sprintf(`
-Analyze this phone call transcription for a home remodeling company:
-
-TRANSCRIPT: %s
-%s
-
-Extract the following information in JSON format:
-{
-  "intent": "quote_request|information_seeking|appointment_booking|complaint|follow_up|other",
-  "project_type": "kitchen|bathroom|whole_home|addition|flooring|roofing|windows|doors|other",
-  "timeline": "immediate|1-3_months|3-6_months|6+_months|unknown",
-  "budget_indicator": "high|medium|low|unknown",
-  "sentiment": "positive|neutral|negative",
-  "lead_score": 1-100,
-  "urgency": "high|medium|low",
-  "appointment_requested": true|false,
-  "follow_up_required": true|false,
-  "key_details": ["detail1", "detail2", "detail3"]
-}
-
-Consider these factors for lead scoring:
-- Project type complexity (kitchen/bathroom = higher score)
-- Customer engagement level
-- Timeline urgency
-- Budget indicators
-- Location within service area
-- Quality of conversation
-
-Respond with ONLY the JSON object, no additional text.`,
-		transcription, callInfo)
-}
-
-// buildSpamDetectionPrompt creates the prompt for spam detection
-func (as *AnalysisService) buildSpamDetectionPrompt(transcription string, callDetails *models.CallDetails) string {
-	var callInfo string
-	if callDetails != nil {
-		callInfo = fmt.Sprintf(`
-CALLER: %s
-PHONE: %s
-DURATION: %d seconds`,
-			callDetails.CustomerName,
-			callDetails.CustomerPhoneNumber,
-			callDetails.Duration)
-	}
-
-	return fmt.Sprintf(`
-Analyze this phone call for spam likelihood:
-
-TRANSCRIPT: %s
-%s
-
-Evaluate for spam indicators:
-- Robotic or scripted speech patterns
-- Generic sales pitches
-- Suspicious caller behavior
-- Short call duration with generic content
-- Known spam phone patterns
-- Telemarketing characteristics
-
-Return ONLY a JSON object:
-{
-  "spam_likelihood": 0-100,
-  "confidence": 0.0-1.0,
-  "indicators": ["list", "of", "spam", "indicators"],
-  "reasoning": "brief explanation of spam assessment"
-}`, transcription, callInfo)
-}
-
-// buildSentimentAnalysisPrompt creates the prompt for sentiment analysis
-func (as *AnalysisService) buildSentimentAnalysisPrompt(transcription string, callDetails *models.CallDetails) string {
-	return fmt.Sprintf(`
-Analyze the sentiment of this call transcription:
-
-TRANSCRIPT: %s
-
-Return ONLY a JSON object with:
-{
-  "sentiment": "positive|neutral|negative",
-  "confidence": 0.0-1.0,
-  "emotional_tone": "string describing emotional tone",
-  "customer_satisfaction": "high|medium|low",
-  "key_emotions": ["emotion1", "emotion2"]
-}`, transcription)
-}
-
-// buildIntentClassificationPrompt creates the prompt for intent classification
-func (as *AnalysisService) buildIntentClassificationPrompt(transcription string, callDetails *models.CallDetails) string {
-	return fmt.Sprintf(`
-Classify the primary intent of this customer call:
-
-TRANSCRIPT: %s
-
-Return ONLY a JSON object:
-{
-  "primary_intent": "quote_request|information_seeking|appointment_booking|complaint|follow_up|emergency|other",
-  "confidence": 0.0-1.0,
-  "secondary_intents": ["list", "of", "secondary", "intents"],
-  "reasoning": "brief explanation of intent classification"
-}`, transcription)
-}
-
-// buildLeadScoringPrompt creates the prompt for lead scoring
-func (as *AnalysisService) buildLeadScoringPrompt(transcription string, callDetails *models.CallDetails) string {
-	var callInfo string
-	if callDetails != nil {
-		callInfo = fmt.Sprintf(`
-CALL CONTEXT:
-- Duration: %d seconds
-- Customer Location: %s, %s
-- Source: %s`,
-			callDetails.Duration,
-			callDetails.CustomerCity,
-			callDetails.CustomerState,
-			callDetails.Source)
-	}
-
-	return fmt.Sprintf(`
-Score this lead based on the call transcription:
-
-TRANSCRIPT: %s
-%s
-
-Evaluate based on:
-- Project complexity and value potential
-- Customer readiness to buy
-- Timeline urgency
-- Budget capability indicators
-- Engagement quality
-
-Return ONLY a JSON object:
-{
-  "lead_score": 1-100,
-  "confidence": 0.0-1.0,
-  "scoring_factors": {
-    "project_complexity": 1-10,
-    "buying_readiness": 1-10,
-    "timeline_urgency": 1-10,
-    "budget_capability": 1-10,
-    "engagement_quality": 1-10
-  },
-  "reasoning": "explanation of score"
-}`, transcription, callInfo)
-}
-
-// createGeminiInstances creates instances for Gemini prediction
-func (as *AnalysisService) createGeminiInstances(prompt string, config *AnalysisConfig) ([]*structpb.Value, error) {
-	instance := map[string]interface{}{
-		"inputs": prompt,
-		"parameters": map[string]interface{}{
-			"temperature":     config.Temperature,
-			"maxOutputTokens": config.MaxTokens,
-			"topP":           config.TopP,
-			"topK":           config.TopK,
-		},
+// generateStructured asks for the analysis type's typed result, repairing an unusable answer once
+func (as *AnalysisService) generateStructured(ctx context.Context, genReq *GenerateRequest, analysisType AnalysisType, response *AnalysisResponse) error {
+	var target interface{}
+	switch analysisType {
+	case AnalysisTypeContent:
+		target = &models.CallAnalysis{}
+	case AnalysisTypeSpam:
+		target = &SpamAnalysisResult{}
+	case AnalysisTypeSentiment:
+		target = &SentimentAnalysisResult{}
+	case AnalysisTypeIntent:
+		target = &IntentClassificationResult{}
+	case AnalysisTypeLeadScore:
+		target = &LeadScoreResult{}
+	case AnalysisTypeSummary:
+		target = &models.CallSummary{}
+	case AnalysisTypeEntities:
+		target = &models.CallEntities{}
+	case AnalysisTypeCombined:
+		target = &CombinedAnalysisResult{}
+	default:
+		return fmt.Errorf("unsupported analysis type: %s", analysisType)
 	}
 
-	instanceValue, err := structpb.NewValue(instance)
+	structured, err := GenerateJSON(ctx, as.llm, genReq, target)
+	response.Metadata.InputTokens = structured.Usage.InputTokens
+	response.Metadata.OutputTokens = structured.Usage.OutputTokens
+	response.Metadata.Repaired = structured.Repaired
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("%s failed: %w", analysisType, err)
 	}
 
-	return []*structpb.Value{instanceValue}, nil
-}
-
-// createGeminiParameters creates parameters for Gemini prediction
-func (as *AnalysisService) createGeminiParameters(config *AnalysisConfig) (*structpb.Value, error) {
-	parameters := map[string]interface{}{
-		"temperature":     config.Temperature,
-		"maxOutputTokens": config.MaxTokens,
-		"topP":           config.TopP,
-		"topK":           config.TopK,
+	switch result := target.(type) {
+	case *models.CallAnalysis:
+		response.CallAnalysis = result
+	case *SpamAnalysisResult:
+		response.SpamResult = result
+	case *SentimentAnalysisResult:
+		response.SentimentResult = result
+	case *IntentClassificationResult:
+		response.IntentResult = result
+	case *LeadScoreResult:
+		response.LeadScoreResult = result
+	case *models.CallSummary:
+		response.SummaryResult = result
+	case *models.CallEntities:
+		response.EntitiesResult = result
+	case *CombinedAnalysisResult:
+		// One answer fills every result a separate analysis would have returned
+		result.Reconcile()
+		response.CallAnalysis = &result.CallAnalysis
+		response.SpamResult = &result.Spam
+		response.SentimentResult = &result.Sentiment
+		response.IntentResult = &result.Intent
+		response.LeadScoreResult = &result.LeadScore
+		response.SummaryResult = &result.Summary
+		response.EntitiesResult = &result.Entities
 	}
 
-	return structpb.NewValue(parameters)
+	return nil
 }
 
-// parseAnalysisResponse parses the Gemini response based on analysis type
-func (as *AnalysisService) parseAnalysisResponse(resp *aiplatformpb.PredictResponse, analysisType AnalysisType, response *AnalysisResponse) error {
-	if len(resp.Predictions) == 0 {
-		return fmt.Errorf("no predictions returned")
+// generateCustom runs a free-form prompt, keeping the answer as JSON when it is JSON
+func (as *AnalysisService) generateCustom(ctx context.Context, genReq *GenerateRequest, response *AnalysisResponse) error {
+	result, err := as.llm.GenerateContent(ctx, genReq)
+	if result != nil {
+		response.Metadata.InputTokens = result.Usage.InputTokens
+		response.Metadata.OutputTokens = result.Usage.OutputTokens
 	}
-
-	prediction := resp.Predictions[0]
-	predictionMap := prediction.GetStructValue().AsMap()
-
-	content, ok := predictionMap["content"]
-	if !ok {
-		return fmt.Errorf("no content in prediction")
-	}
-
-	contentStr, ok := content.(string)
-	if !ok {
-		return fmt.Errorf("content is not a string")
+	if err != nil {
+		return fmt.Errorf("generation failed: %w", err)
 	}
 
-	response.Metadata.OutputTokens = int64(len(contentStr))
-
-	switch analysisType {
-	case AnalysisTypeContent:
-		var analysis models.CallAnalysis
-		if err := json.Unmarshal([]byte(contentStr), &analysis); err != nil {
-			return fmt.Errorf("failed to parse content analysis response: %w", err)
-		}
-		response.CallAnalysis = &analysis
-
-	case AnalysisTypeSpam:
-		var spamResult SpamAnalysisResult
-		if err := json.Unmarshal([]byte(contentStr), &spamResult); err != nil {
-			return fmt.Errorf("failed to parse spam detection response: %w", err)
-		}
-		response.SpamResult = &spamResult
-
-	case AnalysisTypeSentiment:
-		var sentimentResult SentimentAnalysisResult
-		if err := json.Unmarshal([]byte(contentStr), &sentimentResult); err != nil {
-			return fmt.Errorf("failed to parse sentiment analysis response: %w", err)
-		}
-		response.SentimentResult = &sentimentResult
-
-	case AnalysisTypeCustom:
-		// For custom analysis, try to parse as generic JSON
-		var customResult map[string]interface{}
-		if err := json.Unmarshal([]byte(contentStr), &customResult); err != nil {
-			// If JSON parsing fails, store as raw string
-			customResult = map[string]interface{}{
-				"raw_response": contentStr,
-			}
-		}
-		response.CustomResult = customResult
-
-	default:
-		// For other types, store the raw content
-		response.CustomResult = map[string]interface{}{
-			"raw_response": contentStr,
+	var customResult map[string]interface{}
+	if err := DecodeJSON(result.Text, nil, &customResult); err != nil {
+		// If JSON parsing fails, store as raw string
+		customResult = map[string]interface{}{
+			"raw_response": result.Text,
 		}
 	}
+	response.CustomResult = customResult
 
 	return nil
-}
\ No newline at end of file
+}
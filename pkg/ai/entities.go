@@ -0,0 +1,114 @@
+package ai
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// UnverifiedEntityConfidence caps the confidence of an entity whose quote isn't in the
+// transcript, so a value the model made up never outranks what CallRail knows
+const UnverifiedEntityConfidence = 0.3
+
+var zipCodePattern = regexp.MustCompile(`^\d{5}(-\d{4})?$`)
+
+// ResolveEntities normalizes extracted entities and locates their quotes in the transcript.
+// An entity whose value doesn't normalize, e.g. an email without a domain, is dropped.
+func ResolveEntities(entities *models.CallEntities, transcript string) {
+	if entities == nil {
+		return
+	}
+
+	entities.CustomerName = resolveEntity(entities.CustomerName, transcript, normalizeName)
+	entities.Email = resolveEntity(entities.Email, transcript, normalizeEmail)
+	entities.CallbackTime = resolveEntity(entities.CallbackTime, transcript, collapseSpaces)
+
+	if address := entities.Address; address != nil {
+		address.Street = collapseSpaces(address.Street)
+		address.City = normalizeName(address.City)
+		address.State = strings.ToUpper(collapseSpaces(address.State))
+		address.ZipCode = collapseSpaces(address.ZipCode)
+		if !zipCodePattern.MatchString(address.ZipCode) {
+			address.ZipCode = ""
+		}
+		if address.Street == "" && address.City == "" && address.State == "" && address.ZipCode == "" {
+			entities.Address = nil
+		} else {
+			address.Span = locateQuote(transcript, address.Quote, &address.Confidence)
+		}
+	}
+}
+
+func resolveEntity(entity *models.ExtractedEntity, transcript string, normalize func(string) string) *models.ExtractedEntity {
+	if entity == nil {
+		return nil
+	}
+	entity.Value = normalize(entity.Value)
+	if entity.Value == "" {
+		return nil
+	}
+	entity.Span = locateQuote(transcript, entity.Quote, &entity.Confidence)
+	return entity
+}
+
+// locateQuote finds a quote in the transcript, ignoring case, and caps the confidence when it
+// isn't there
+func locateQuote(transcript, quote string, confidence *float64) *models.TranscriptSpan {
+	quote = strings.TrimSpace(quote)
+	start := -1
+	if quote != "" {
+		start = strings.Index(transcript, quote)
+		if start < 0 {
+			// Lowercasing keeps byte offsets only when it keeps the length
+			lower := strings.ToLower(transcript)
+			if len(lower) == len(transcript) {
+				start = strings.Index(lower, strings.ToLower(quote))
+			}
+		}
+	}
+	if start < 0 {
+		if *confidence > UnverifiedEntityConfidence {
+			*confidence = UnverifiedEntityConfidence
+		}
+		return nil
+	}
+	return &models.TranscriptSpan{Start: start, End: start + len(quote)}
+}
+
+// normalizeName capitalizes a name that came back in a single case, e.g. "jane doe" or "JANE DOE"
+func normalizeName(name string) string {
+	name = strings.Trim(collapseSpaces(name), ".,")
+	if name != strings.ToLower(name) && name != strings.ToUpper(name) {
+		return name
+	}
+
+	runes := []rune(strings.ToLower(name))
+	for i := range runes {
+		// Capitalize each word and each part of "O'Neil" or "Smith-Jones"
+		if i == 0 || runes[i-1] == ' ' || runes[i-1] == '\'' || runes[i-1] == '-' {
+			runes[i] = unicode.ToUpper(runes[i])
+		}
+	}
+	return string(runes)
+}
+
+// normalizeEmail returns a lowercase email address, or "" if it isn't one
+func normalizeEmail(email string) string {
+	email = strings.ToLower(strings.Trim(strings.Join(strings.Fields(email), ""), "."))
+	parsed, err := mail.ParseAddress(email)
+	if err != nil || parsed.Address != email {
+		return ""
+	}
+	_, domain, _ := strings.Cut(email, "@")
+	if !strings.Contains(domain, ".") {
+		return ""
+	}
+	return email
+}
+
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
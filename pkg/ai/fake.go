@@ -0,0 +1,142 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// FakeProvider answers prompts from a script keyed by prompt hash, without calling any model
+type FakeProvider struct {
+	mu        sync.Mutex
+	responses map[string]FakeResponse
+	fallback  *FakeResponse
+	calls     []GenerateRequest
+}
+
+// FakeProvider is the scripted LLM provider
+var _ LLMProvider = (*FakeProvider)(nil)
+
+// FakeResponse is a scripted answer; Error makes the call fail with that message
+type FakeResponse struct {
+	Text         string     `json:"text"`
+	Error        string     `json:"error,omitempty"`
+	FinishReason string     `json:"finish_reason,omitempty"`
+	Usage        TokenUsage `json:"usage"`
+}
+
+// FakeScript is the file format of a fake provider script
+type FakeScript struct {
+	Responses map[string]FakeResponse `json:"responses"`         // keyed by PromptHash
+	Default   *FakeResponse           `json:"default,omitempty"` // for prompts without a scripted answer
+}
+
+// ErrNoScriptedResponse is returned for a prompt the fake has no answer for
+var ErrNoScriptedResponse = errors.New("no scripted response for prompt")
+
+// PromptHash returns the key a fake provider script uses for a prompt
+func PromptHash(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewFakeProvider creates a fake provider with an empty script
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{responses: make(map[string]FakeResponse)}
+}
+
+// LoadFakeProvider creates a fake provider from a JSON script file
+func LoadFakeProvider(path string) (*FakeProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fake provider script: %w", err)
+	}
+
+	var script FakeScript
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("failed to parse fake provider script: %w", err)
+	}
+
+	fake := NewFakeProvider()
+	for hash, response := range script.Responses {
+		fake.RespondToHash(hash, response)
+	}
+	if script.Default != nil {
+		fake.SetDefault(*script.Default)
+	}
+
+	return fake, nil
+}
+
+// Respond scripts the answer to a prompt
+func (f *FakeProvider) Respond(prompt string, response FakeResponse) {
+	f.RespondToHash(PromptHash(prompt), response)
+}
+
+// RespondToHash scripts the answer to the prompt with the given hash
+func (f *FakeProvider) RespondToHash(hash string, response FakeResponse) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses[hash] = response
+}
+
+// SetDefault scripts the answer to every prompt without its own
+func (f *FakeProvider) SetDefault(response FakeResponse) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fallback = &response
+}
+
+// Calls returns the requests the fake has received, oldest first
+func (f *FakeProvider) Calls() []GenerateRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]GenerateRequest(nil), f.calls...)
+}
+
+// GenerateContent returns the scripted answer to the prompt
+func (f *FakeProvider) GenerateContent(ctx context.Context, req *GenerateRequest) (*GenerateResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, *req)
+	hash := PromptHash(req.Prompt)
+	response, ok := f.responses[hash]
+	if !ok && f.fallback != nil {
+		response, ok = *f.fallback, true
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrNoScriptedResponse, hash)
+	}
+
+	usage := response.Usage
+	if usage == (TokenUsage{}) {
+		// Roughly four characters per token, so cost accounting has something to add up
+		usage = TokenUsage{InputTokens: int64(len(req.Prompt)+3) / 4, OutputTokens: int64(len(response.Text)+3) / 4}
+	}
+	finishReason := response.FinishReason
+	if finishReason == "" {
+		finishReason = "STOP"
+	}
+
+	result := &GenerateResult{Text: response.Text, FinishReason: finishReason, Usage: usage}
+	if response.Error != "" {
+		return result, errors.New(response.Error)
+	}
+
+	return result, nil
+}
+
+// Close does nothing
+func (f *FakeProvider) Close() error {
+	return nil
+}
@@ -0,0 +1,211 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+// GeminiClient calls Gemini models on Vertex AI through the generateContent API
+type GeminiClient struct {
+	httpClient *http.Client
+	baseURL    string // empty uses the regional Vertex AI endpoint
+	projectID  string
+	location   string
+}
+
+// GeminiClient is the Vertex AI LLM provider
+var _ LLMProvider = (*GeminiClient)(nil)
+
+// GenerateRequest is a single-turn generation call
+type GenerateRequest struct {
+	Model           string
+	Prompt          string
+	Temperature     float32
+	MaxOutputTokens int32
+	TopP            float32
+	TopK            int32
+	ResponseSchema  *Schema // when set the model must answer with JSON matching it
+}
+
+// GenerateResult is the model's answer and what it cost
+type GenerateResult struct {
+	Text         string
+	FinishReason string
+	Usage        TokenUsage
+}
+
+// TokenUsage is the token accounting reported by the model
+type TokenUsage struct {
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"` // includes thinking tokens, which are billed as output
+}
+
+// NewGeminiClient creates a client for a project and location, authenticated with application default credentials
+func NewGeminiClient(ctx context.Context, projectID, location string) (*GeminiClient, error) {
+	httpClient, _, err := htransport.NewClient(ctx, option.WithScopes("https://www.googleapis.com/auth/cloud-platform"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Vertex AI HTTP client: %w", err)
+	}
+	return &GeminiClient{httpClient: httpClient, projectID: projectID, location: location}, nil
+}
+
+// NewGeminiClientWithHTTP creates a client sending requests to baseURL with the given HTTP client
+func NewGeminiClientWithHTTP(httpClient *http.Client, baseURL, projectID, location string) *GeminiClient {
+	return &GeminiClient{
+		httpClient: httpClient,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		projectID:  projectID,
+		location:   location,
+	}
+}
+
+// Close releases idle connections
+func (c *GeminiClient) Close() error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+type generateContentRequest struct {
+	Contents         []geminiContent        `json:"contents"`
+	GenerationConfig geminiGenerationConfig `json:"generationConfig"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature      float32 `json:"temperature"`
+	MaxOutputTokens  int32   `json:"maxOutputTokens,omitempty"`
+	TopP             float32 `json:"topP,omitempty"`
+	TopK             int32   `json:"topK,omitempty"`
+	ResponseMimeType string  `json:"responseMimeType,omitempty"`
+	ResponseSchema   *Schema `json:"responseSchema,omitempty"`
+}
+
+type generateContentResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata struct {
+		PromptTokenCount     int64 `json:"promptTokenCount"`
+		CandidatesTokenCount int64 `json:"candidatesTokenCount"`
+		ThoughtsTokenCount   int64 `json:"thoughtsTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+type geminiErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// GenerateContent sends a prompt to the model and returns its text and token usage
+func (c *GeminiClient) GenerateContent(ctx context.Context, req *GenerateRequest) (*GenerateResult, error) {
+	config := geminiGenerationConfig{
+		Temperature:     req.Temperature,
+		MaxOutputTokens: req.MaxOutputTokens,
+		TopP:            req.TopP,
+		TopK:            req.TopK,
+	}
+	if req.ResponseSchema != nil {
+		config.ResponseMimeType = "application/json"
+		config.ResponseSchema = req.ResponseSchema
+	}
+
+	body, err := json.Marshal(generateContentRequest{
+		Contents:         []geminiContent{{Role: "user", Parts: []geminiPart{{Text: req.Prompt}}}},
+		GenerationConfig: config,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal generateContent request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.modelURL(req), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create generateContent request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("generateContent request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read generateContent response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr geminiErrorResponse
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			return nil, fmt.Errorf("generateContent failed with %s: %s", apiErr.Error.Status, apiErr.Error.Message)
+		}
+		return nil, fmt.Errorf("generateContent failed with HTTP %d", resp.StatusCode)
+	}
+
+	var parsed generateContentResponse
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse generateContent response: %w", err)
+	}
+
+	result := &GenerateResult{
+		Usage: TokenUsage{
+			InputTokens:  parsed.UsageMetadata.PromptTokenCount,
+			OutputTokens: parsed.UsageMetadata.CandidatesTokenCount + parsed.UsageMetadata.ThoughtsTokenCount,
+		},
+	}
+	if parsed.PromptFeedback.BlockReason != "" {
+		return result, fmt.Errorf("prompt blocked: %s", parsed.PromptFeedback.BlockReason)
+	}
+	if len(parsed.Candidates) == 0 {
+		return result, fmt.Errorf("no candidates returned")
+	}
+
+	candidate := parsed.Candidates[0]
+	result.FinishReason = candidate.FinishReason
+	var text strings.Builder
+	for _, part := range candidate.Content.Parts {
+		text.WriteString(part.Text)
+	}
+	result.Text = text.String()
+
+	if result.Text == "" {
+		return result, fmt.Errorf("empty response from model (finish reason %s)", candidate.FinishReason)
+	}
+
+	return result, nil
+}
+
+// modelURL returns the generateContent URL of the request's publisher model
+func (c *GeminiClient) modelURL(req *GenerateRequest) string {
+	baseURL := c.baseURL
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("https://%s-aiplatform.googleapis.com", c.location)
+		if c.location == "global" {
+			baseURL = "https://aiplatform.googleapis.com"
+		}
+	}
+	return fmt.Sprintf("%s/v1/projects/%s/locations/%s/publishers/google/models/%s:generateContent",
+		baseURL, c.projectID, c.location, req.Model)
+}
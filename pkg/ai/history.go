@@ -0,0 +1,98 @@
+package ai
+
+import (
+	"encoding/json"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// NewPriorInteractions describes a caller's earlier requests for the analysis prompt, in the
+// order given. Each request's CRM stage comes from its most recently updated CRM integration.
+func NewPriorInteractions(requests []*models.Request, integrations []*models.CRMIntegration) []models.PriorInteraction {
+	stages := crmStages(integrations)
+
+	history := make([]models.PriorInteraction, 0, len(requests))
+	for _, req := range requests {
+		interaction := models.PriorInteraction{
+			RequestID: req.RequestID,
+			Date:      req.CreatedAt,
+			CRMStage:  stages[req.RequestID],
+		}
+
+		var analysis models.CallAnalysis
+		if req.AIAnalysis != nil && json.Unmarshal([]byte(*req.AIAnalysis), &analysis) == nil {
+			interaction.Intent = analysis.Intent
+			interaction.ProjectType = analysis.ProjectType
+			if analysis.Summary != nil {
+				interaction.Summary = analysis.Summary.Summary
+			}
+		}
+		history = append(history, interaction)
+	}
+	return history
+}
+
+// crmStages returns the CRM stage of each request with a CRM integration: the lead's status when
+// the integration recorded one, otherwise the integration's own status
+func crmStages(integrations []*models.CRMIntegration) map[string]string {
+	stages := make(map[string]string)
+	latest := make(map[string]*models.CRMIntegration)
+	for _, integration := range integrations {
+		var config struct {
+			RequestID string `json:"request_id"`
+			LeadData  struct {
+				LeadStatus string `json:"lead_status"`
+			} `json:"lead_data"`
+		}
+		if json.Unmarshal([]byte(integration.Config), &config) != nil || config.RequestID == "" {
+			continue
+		}
+		if previous := latest[config.RequestID]; previous != nil && previous.UpdatedAt.After(integration.UpdatedAt) {
+			continue
+		}
+
+		latest[config.RequestID] = integration
+		stages[config.RequestID] = config.LeadData.LeadStatus
+		if stages[config.RequestID] == "" {
+			stages[config.RequestID] = integration.Status
+		}
+	}
+	return stages
+}
+
+// ResolveContinuity settles how a call relates to the caller's history. A caller without history
+// is always a new project, a missing answer falls back to the call's intent, and a follow-up or
+// complaint points at one of the earlier requests, the latest unless the model named another.
+func ResolveContinuity(analysis *models.CallAnalysis, history []models.PriorInteraction) {
+	if analysis == nil {
+		return
+	}
+	if len(history) == 0 {
+		analysis.Continuity = &models.CallContinuity{
+			Relationship: models.CallRelationshipNewProject,
+			Reasoning:    "no earlier calls from this number",
+		}
+		return
+	}
+
+	continuity := analysis.Continuity
+	if continuity == nil {
+		continuity = &models.CallContinuity{Relationship: models.CallRelationshipNewProject, Reasoning: "inferred from the call's intent"}
+		switch analysis.Intent {
+		case models.CallRelationshipFollowUp, models.CallRelationshipComplaint:
+			continuity.Relationship = analysis.Intent
+		}
+		analysis.Continuity = continuity
+	}
+
+	if continuity.Relationship == models.CallRelationshipNewProject {
+		continuity.RelatedRequestID = ""
+		return
+	}
+	for _, interaction := range history {
+		if interaction.RequestID == continuity.RelatedRequestID {
+			return
+		}
+	}
+	continuity.RelatedRequestID = history[0].RequestID
+}
@@ -0,0 +1,23 @@
+package ai
+
+import (
+	"fmt"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// LanguageInstruction returns the prompt section for a non-English transcript. Gemini reads the
+// call in its source language, since translating first loses detail, but answers in English so
+// structured fields stay comparable across tenants. English transcripts need no instruction.
+func LanguageInstruction(language string) string {
+	if models.IsEnglish(language) {
+		return ""
+	}
+
+	name := models.LanguageName(language)
+	return fmt.Sprintf(`
+
+LANGUAGE: The transcript is in %s (%s). Analyze it in %s without translating it first.
+Write every JSON value in English, keep enum values exactly as listed, and translate quoted
+details into English.`, name, language, name)
+}
@@ -0,0 +1,158 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAIClient calls an OpenAI-compatible chat completions API, such as a local vLLM or Ollama server
+type OpenAIClient struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string // optional, self-hosted servers usually don't need one
+}
+
+// OpenAIClient is the OpenAI-compatible LLM provider
+var _ LLMProvider = (*OpenAIClient)(nil)
+
+// NewOpenAIClient creates a client for the API rooted at baseURL, e.g. http://localhost:8000/v1
+func NewOpenAIClient(baseURL, apiKey string) *OpenAIClient {
+	// Local models on modest hardware can take minutes to answer a long transcript
+	return NewOpenAIClientWithHTTP(&http.Client{Timeout: 5 * time.Minute}, baseURL, apiKey)
+}
+
+// NewOpenAIClientWithHTTP creates a client using the given HTTP client
+func NewOpenAIClientWithHTTP(httpClient *http.Client, baseURL, apiKey string) *OpenAIClient {
+	return &OpenAIClient{
+		httpClient: httpClient,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+	}
+}
+
+// Close releases idle connections
+func (c *OpenAIClient) Close() error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+type chatCompletionRequest struct {
+	Model          string              `json:"model"`
+	Messages       []chatMessage       `json:"messages"`
+	Temperature    float32             `json:"temperature"`
+	TopP           float32             `json:"top_p,omitempty"`
+	MaxTokens      int32               `json:"max_tokens,omitempty"`
+	ResponseFormat *chatResponseFormat `json:"response_format,omitempty"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatResponseFormat struct {
+	Type       string          `json:"type"`
+	JSONSchema *chatJSONSchema `json:"json_schema,omitempty"`
+}
+
+type chatJSONSchema struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+}
+
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message      chatMessage `json:"message"`
+		FinishReason string      `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int64 `json:"prompt_tokens"`
+		CompletionTokens int64 `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+type chatErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+// GenerateContent sends the prompt as a single user message and returns the answer and token usage
+func (c *OpenAIClient) GenerateContent(ctx context.Context, req *GenerateRequest) (*GenerateResult, error) {
+	chatReq := chatCompletionRequest{
+		Model:       req.Model,
+		Messages:    []chatMessage{{Role: "user", Content: req.Prompt}},
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		MaxTokens:   req.MaxOutputTokens,
+	}
+	if req.ResponseSchema != nil {
+		chatReq.ResponseFormat = &chatResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &chatJSONSchema{Name: "result", Schema: req.ResponseSchema.JSONSchema()},
+		}
+	}
+
+	body, err := json.Marshal(chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal chat completion request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chat completion request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("chat completion request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chat completion response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr chatErrorResponse
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			return nil, fmt.Errorf("chat completion failed with HTTP %d: %s", resp.StatusCode, apiErr.Error.Message)
+		}
+		return nil, fmt.Errorf("chat completion failed with HTTP %d", resp.StatusCode)
+	}
+
+	var parsed chatCompletionResponse
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse chat completion response: %w", err)
+	}
+
+	result := &GenerateResult{
+		Usage: TokenUsage{
+			InputTokens:  parsed.Usage.PromptTokens,
+			OutputTokens: parsed.Usage.CompletionTokens,
+		},
+	}
+	if len(parsed.Choices) == 0 {
+		return result, fmt.Errorf("no choices returned")
+	}
+
+	choice := parsed.Choices[0]
+	result.Text = choice.Message.Content
+	result.FinishReason = choice.FinishReason
+	if result.Text == "" {
+		return result, fmt.Errorf("empty response from model (finish reason %s)", choice.FinishReason)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,299 @@
+package ai
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+//go:embed prompts/*.tmpl
+var promptFiles embed.FS
+
+// Prompt defaults for tenants that have not configured their own
+var (
+	DefaultBusinessType    = "home remodeling company"
+	DefaultProjectTypes    = []string{"kitchen", "bathroom", "whole_home", "addition", "flooring", "roofing", "windows", "doors", "other"}
+	DefaultScoringCriteria = []string{
+		"Project type complexity (kitchen/bathroom = higher score)",
+		"Customer engagement level",
+		"Timeline urgency",
+		"Budget indicators",
+		"Location within service area",
+		"Quality of conversation",
+	}
+)
+
+// promptFileName matches template files such as content_analysis.v2.tmpl
+var promptFileName = regexp.MustCompile(`^([a-z_]+)\.v([0-9]+)\.tmpl$`)
+
+// PromptTemplate is one version of the text/template an analysis prompt is rendered from
+type PromptTemplate struct {
+	ID       string // the analysis type, e.g. content_analysis
+	Version  int
+	Source   string
+	template *template.Template
+}
+
+// PromptData is what a prompt template is rendered with
+type PromptData struct {
+	Transcript      string
+	Call            *models.CallDetails // nil when the request has no call metadata
+	BusinessType    string
+	ProjectTypes    []string
+	ScoringCriteria []string
+	History         []models.PriorInteraction // the caller's earlier calls, newest first
+}
+
+// RenderedPrompt is a prompt and the template version it was rendered from
+type RenderedPrompt struct {
+	TemplateID      string `json:"template_id"`
+	TemplateVersion int    `json:"template_version"`
+	Text            string `json:"text"`
+}
+
+// PromptLibrary holds every version of every prompt template
+type PromptLibrary struct {
+	templates map[string][]*PromptTemplate // by ID, oldest version first
+}
+
+var defaultPromptLibrary *PromptLibrary
+
+func init() {
+	library, err := LoadPromptLibrary(promptFiles)
+	if err != nil {
+		// The templates are embedded at build time, so this is a programming error
+		panic(fmt.Sprintf("ai: %v", err))
+	}
+	defaultPromptLibrary = library
+}
+
+// DefaultPromptLibrary returns the prompt templates shipped with the service
+func DefaultPromptLibrary() *PromptLibrary {
+	return defaultPromptLibrary
+}
+
+// LoadPromptLibrary loads <id>.v<N>.tmpl templates from any directory of fsys. Each ID's
+// versions must run from 1 without gaps.
+func LoadPromptLibrary(fsys fs.FS) (*PromptLibrary, error) {
+	library := &PromptLibrary{templates: make(map[string][]*PromptTemplate)}
+
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || path.Ext(name) != ".tmpl" {
+			return err
+		}
+
+		match := promptFileName.FindStringSubmatch(path.Base(name))
+		if match == nil {
+			return fmt.Errorf("prompt template %s is not named <id>.v<N>.tmpl", name)
+		}
+		version, _ := strconv.Atoi(match[2])
+
+		source, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("failed to read prompt template %s: %w", name, err)
+		}
+		parsed, err := template.New(path.Base(name)).Funcs(promptFuncs).Option("missingkey=error").Parse(string(source))
+		if err != nil {
+			return fmt.Errorf("failed to parse prompt template %s: %w", name, err)
+		}
+
+		library.templates[match[1]] = append(library.templates[match[1]], &PromptTemplate{
+			ID:       match[1],
+			Version:  version,
+			Source:   string(source),
+			template: parsed,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for id, versions := range library.templates {
+		sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+		for i, tmpl := range versions {
+			if tmpl.Version != i+1 {
+				return nil, fmt.Errorf("prompt template %s is missing version %d", id, i+1)
+			}
+		}
+	}
+
+	return library, nil
+}
+
+// promptFuncs are the helpers prompt templates can call
+var promptFuncs = template.FuncMap{
+	// enum lists allowed values the way the prompts show them, e.g. kitchen|bathroom|other
+	"enum": func(values []string) string { return strings.Join(values, "|") },
+	"join": func(values []string) string { return strings.Join(values, ", ") },
+	"date": func(t time.Time) string { return t.Format("2006-01-02") },
+	// labels lists a taxonomy in prose, without the catch-all "other"
+	"labels": func(values []string) string {
+		var labels []string
+		for _, value := range values {
+			if value != "other" {
+				labels = append(labels, strings.ReplaceAll(value, "_", " "))
+			}
+		}
+		return strings.Join(labels, ", ")
+	},
+}
+
+// Template returns a version of a template, or its latest version when version is 0
+func (l *PromptLibrary) Template(id string, version int) (*PromptTemplate, error) {
+	versions := l.templates[id]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no prompt template %q", id)
+	}
+	if version == 0 {
+		return versions[len(versions)-1], nil
+	}
+	if version < 0 || version > len(versions) {
+		return nil, fmt.Errorf("prompt template %s has no version %d", id, version)
+	}
+	return versions[version-1], nil
+}
+
+// Versions returns the available versions of a template, oldest first
+func (l *PromptLibrary) Versions(id string) []int {
+	var versions []int
+	for _, tmpl := range l.templates[id] {
+		versions = append(versions, tmpl.Version)
+	}
+	return versions
+}
+
+// Render renders the prompt of an analysis type for a tenant. The tenant's pinned template
+// version is used unless version is set; 0 everywhere means the latest version.
+func (l *PromptLibrary) Render(analysisType AnalysisType, version int, cfg *models.AnalysisPromptConfig, transcript string, callDetails *models.CallDetails) (*RenderedPrompt, error) {
+	return l.RenderWithHistory(analysisType, version, cfg, transcript, callDetails, nil)
+}
+
+// RenderWithHistory renders a prompt like Render, showing the caller's earlier calls to prompts
+// that relate the call to them
+func (l *PromptLibrary) RenderWithHistory(analysisType AnalysisType, version int, cfg *models.AnalysisPromptConfig, transcript string, callDetails *models.CallDetails, history []models.PriorInteraction) (*RenderedPrompt, error) {
+	if version == 0 && cfg != nil {
+		version = cfg.TemplateVersions[string(analysisType)]
+	}
+	tmpl, err := l.Template(string(analysisType), version)
+	if err != nil {
+		return nil, err
+	}
+
+	data := NewPromptData(cfg)
+	data.Transcript = transcript
+	data.Call = callDetails
+	data.History = history
+
+	var text bytes.Buffer
+	if err := tmpl.template.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render prompt template %s v%d: %w", tmpl.ID, tmpl.Version, err)
+	}
+
+	return &RenderedPrompt{
+		TemplateID:      tmpl.ID,
+		TemplateVersion: tmpl.Version,
+		Text:            text.String(),
+	}, nil
+}
+
+// NewPromptData returns the template data for a tenant's configuration, filling in the defaults
+func NewPromptData(cfg *models.AnalysisPromptConfig) *PromptData {
+	data := &PromptData{
+		BusinessType:    DefaultBusinessType,
+		ProjectTypes:    DefaultProjectTypes,
+		ScoringCriteria: DefaultScoringCriteria,
+	}
+	if cfg == nil {
+		return data
+	}
+
+	if cfg.BusinessType != "" {
+		data.BusinessType = cfg.BusinessType
+	}
+	if len(cfg.ProjectTypes) > 0 {
+		data.ProjectTypes = ProjectTaxonomy(cfg)
+	}
+	if len(cfg.ScoringCriteria) > 0 {
+		data.ScoringCriteria = cfg.ScoringCriteria
+	}
+
+	return data
+}
+
+// ProjectTaxonomy returns a tenant's project_type values in enum form, always ending in "other"
+func ProjectTaxonomy(cfg *models.AnalysisPromptConfig) []string {
+	if cfg == nil || len(cfg.ProjectTypes) == 0 {
+		return DefaultProjectTypes
+	}
+
+	var taxonomy []string
+	seen := map[string]bool{"other": true}
+	for _, projectType := range cfg.ProjectTypes {
+		token := enumToken(projectType)
+		if token != "" && !seen[token] {
+			seen[token] = true
+			taxonomy = append(taxonomy, token)
+		}
+	}
+
+	return append(taxonomy, "other")
+}
+
+// TenantResponseSchema returns the response schema of an analysis type with the tenant's
+// project taxonomy in place of the default one, including inside nested results
+func TenantResponseSchema(analysisType AnalysisType, cfg *models.AnalysisPromptConfig) *Schema {
+	schema := ResponseSchema(analysisType)
+	if schema == nil || cfg == nil || len(cfg.ProjectTypes) == 0 {
+		return schema
+	}
+	return withProjectTaxonomy(schema, ProjectTaxonomy(cfg))
+}
+
+// withProjectTaxonomy copies the parts of an object schema that lead to a project_type
+// property, replacing its enum; the shared schema is left untouched
+func withProjectTaxonomy(schema *Schema, taxonomy []string) *Schema {
+	if schema.Type != SchemaTypeObject {
+		return schema
+	}
+
+	var tenantSchema *Schema
+	for name, property := range schema.Properties {
+		replaced := property
+		if name == "project_type" && len(property.Enum) > 0 {
+			projectType := *property
+			projectType.Enum = taxonomy
+			replaced = &projectType
+		} else {
+			replaced = withProjectTaxonomy(property, taxonomy)
+		}
+		if replaced == property {
+			continue
+		}
+
+		if tenantSchema == nil {
+			copied := *schema
+			copied.Properties = make(map[string]*Schema, len(schema.Properties))
+			for key, value := range schema.Properties {
+				copied.Properties[key] = value
+			}
+			tenantSchema = &copied
+		}
+		tenantSchema.Properties[name] = replaced
+	}
+
+	if tenantSchema == nil {
+		return schema
+	}
+	return tenantSchema
+}
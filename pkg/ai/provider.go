@@ -0,0 +1,51 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+)
+
+// LLMProvider generates a model answer for a single prompt
+type LLMProvider interface {
+	// GenerateContent answers the prompt, as JSON matching req.ResponseSchema when one is set.
+	// The result carries token usage even when an error is returned after the model was called.
+	GenerateContent(ctx context.Context, req *GenerateRequest) (*GenerateResult, error)
+	Close() error
+}
+
+// Supported LLM providers
+const (
+	ProviderVertex = "vertex" // Gemini on Vertex AI
+	ProviderOpenAI = "openai" // any OpenAI-compatible chat completions API, e.g. vLLM or Ollama
+	ProviderFake   = "fake"   // scripted answers for offline development and tests
+)
+
+// ProviderConfig selects and configures an LLM provider
+type ProviderConfig struct {
+	Provider   string `json:"provider"` // vertex, openai or fake
+	ProjectID  string `json:"project_id"`
+	Location   string `json:"location"`
+	BaseURL    string `json:"base_url"` // OpenAI-compatible API root, e.g. http://localhost:11434/v1
+	APIKey     string `json:"-"`
+	FakeScript string `json:"fake_script"` // JSON file of scripted answers for the fake provider
+}
+
+// NewProvider creates the configured LLM provider
+func NewProvider(ctx context.Context, cfg *ProviderConfig) (LLMProvider, error) {
+	switch cfg.Provider {
+	case ProviderVertex, "":
+		return NewGeminiClient(ctx, cfg.ProjectID, cfg.Location)
+	case ProviderOpenAI:
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("the openai provider requires a base URL")
+		}
+		return NewOpenAIClient(cfg.BaseURL, cfg.APIKey), nil
+	case ProviderFake:
+		if cfg.FakeScript == "" {
+			return NewFakeProvider(), nil
+		}
+		return LoadFakeProvider(cfg.FakeScript)
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q", cfg.Provider)
+	}
+}
@@ -0,0 +1,196 @@
+package ai
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is the OpenAPI subset Gemini accepts as a responseSchema
+type Schema struct {
+	Type        string             `json:"type"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Nullable    bool               `json:"nullable,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+}
+
+// Schema types as named by the Vertex AI API
+const (
+	SchemaTypeObject  = "OBJECT"
+	SchemaTypeArray   = "ARRAY"
+	SchemaTypeString  = "STRING"
+	SchemaTypeInteger = "INTEGER"
+	SchemaTypeNumber  = "NUMBER"
+	SchemaTypeBoolean = "BOOLEAN"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaFor derives a response schema from a result struct.
+//
+// Properties come from the json tags; fields without omitempty are required.
+// A `schema` tag adds constraints, e.g. `schema:"enum=low|medium|high"` or
+// `schema:"min=0,max=1"`, and `schema:"-"` leaves a field out of the schema.
+func SchemaFor(v interface{}) *Schema {
+	schema, err := schemaForType(reflect.TypeOf(v))
+	if err != nil {
+		// Result types are fixed at compile time, so this is a programming error
+		panic(fmt.Sprintf("ai: %v", err))
+	}
+	return schema
+}
+
+func schemaForType(t reflect.Type) (*Schema, error) {
+	if t.Kind() == reflect.Ptr {
+		schema, err := schemaForType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		schema.Nullable = true
+		return schema, nil
+	}
+	if t == timeType {
+		return &Schema{Type: SchemaTypeString, Format: "date-time"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: SchemaTypeString}, nil
+	case reflect.Bool:
+		return &Schema{Type: SchemaTypeBoolean}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: SchemaTypeInteger}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: SchemaTypeNumber}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := schemaForType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: SchemaTypeArray, Items: items}, nil
+	case reflect.Struct:
+		return schemaForStruct(t)
+	default:
+		return nil, fmt.Errorf("no response schema for %s, tag the field schema:\"-\"", t)
+	}
+}
+
+func schemaForStruct(t reflect.Type) (*Schema, error) {
+	schema := &Schema{Type: SchemaTypeObject, Properties: map[string]*Schema{}}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Tag.Get("schema") == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property, err := schemaForType(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name(), field.Name, err)
+		}
+		if err := applySchemaTag(property, field.Tag.Get("schema")); err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name(), field.Name, err)
+		}
+
+		schema.Properties[name] = property
+		if !strings.Contains(opts, "omitempty") && !property.Nullable {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema, nil
+}
+
+// applySchemaTag applies the enum, min and max constraints of a schema tag.
+// Constraints on a slice apply to its items.
+func applySchemaTag(schema *Schema, tag string) error {
+	if tag == "" {
+		return nil
+	}
+	target := schema
+	if schema.Type == SchemaTypeArray {
+		target = schema.Items
+	}
+
+	for _, option := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "enum":
+			target.Enum = strings.Split(value, "|")
+		case "min", "max":
+			bound, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid schema %s %q: %w", key, value, err)
+			}
+			if key == "min" {
+				target.Minimum = &bound
+			} else {
+				target.Maximum = &bound
+			}
+		default:
+			return fmt.Errorf("unknown schema option %q", key)
+		}
+	}
+
+	return nil
+}
+
+// JSONSchema converts the schema to standard JSON Schema, as OpenAI-compatible APIs expect it
+func (s *Schema) JSONSchema() map[string]interface{} {
+	out := map[string]interface{}{}
+
+	schemaType := strings.ToLower(s.Type)
+	if s.Nullable {
+		out["type"] = []string{schemaType, "null"}
+	} else {
+		out["type"] = schemaType
+	}
+	if s.Format != "" {
+		out["format"] = s.Format
+	}
+	if s.Description != "" {
+		out["description"] = s.Description
+	}
+	if len(s.Enum) > 0 {
+		out["enum"] = s.Enum
+	}
+	if s.Minimum != nil {
+		out["minimum"] = *s.Minimum
+	}
+	if s.Maximum != nil {
+		out["maximum"] = *s.Maximum
+	}
+	if s.Items != nil {
+		out["items"] = s.Items.JSONSchema()
+	}
+	if s.Type == SchemaTypeObject {
+		properties := map[string]interface{}{}
+		for name, property := range s.Properties {
+			properties[name] = property.JSONSchema()
+		}
+		out["properties"] = properties
+		out["additionalProperties"] = false
+		if len(s.Required) > 0 {
+			out["required"] = s.Required
+		}
+	}
+
+	return out
+}
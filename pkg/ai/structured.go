@@ -0,0 +1,347 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValidationError lists the ways a model answer does not match its response schema
+type ValidationError struct {
+	Problems []string
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	return "invalid model output: " + strings.Join(e.Problems, "; ")
+}
+
+// StructuredResult describes how a structured answer was obtained
+type StructuredResult struct {
+	Text     string     `json:"text"`     // the answer that was decoded
+	Usage    TokenUsage `json:"usage"`    // summed over the original and repair calls
+	Repaired bool       `json:"repaired"` // the first answer was unusable and a repair prompt was issued
+}
+
+// GenerateJSON asks the model for JSON matching req.ResponseSchema and decodes it into out.
+// An answer that does not parse or validate gets a single repair prompt before the call fails.
+func GenerateJSON(ctx context.Context, llm LLMProvider, req *GenerateRequest, out interface{}) (*StructuredResult, error) {
+	structured := &StructuredResult{}
+
+	result, err := llm.GenerateContent(ctx, req)
+	if result != nil {
+		structured.Text = result.Text
+		structured.Usage = result.Usage
+	}
+	if err != nil {
+		return structured, err
+	}
+
+	decodeErr := DecodeJSON(result.Text, req.ResponseSchema, out)
+	if decodeErr == nil {
+		return structured, nil
+	}
+
+	repair := *req
+	repair.Prompt = buildRepairPrompt(req, result.Text, decodeErr)
+	structured.Repaired = true
+
+	result, err = llm.GenerateContent(ctx, &repair)
+	if result != nil {
+		structured.Text = result.Text
+		structured.Usage.InputTokens += result.Usage.InputTokens
+		structured.Usage.OutputTokens += result.Usage.OutputTokens
+	}
+	if err != nil {
+		return structured, fmt.Errorf("repair after %v failed: %w", decodeErr, err)
+	}
+
+	if err := DecodeJSON(result.Text, req.ResponseSchema, out); err != nil {
+		return structured, fmt.Errorf("answer still unusable after repair: %w", err)
+	}
+
+	return structured, nil
+}
+
+// buildRepairPrompt asks the model to correct an unusable answer to the original prompt
+func buildRepairPrompt(req *GenerateRequest, answer string, problem error) string {
+	var schema []byte
+	if req.ResponseSchema != nil {
+		schema, _ = json.MarshalIndent(req.ResponseSchema.JSONSchema(), "", "  ")
+	}
+
+	return fmt.Sprintf(`%s
+
+---
+Your previous answer to the request above could not be used.
+
+PREVIOUS ANSWER:
+%s
+
+PROBLEM: %v
+
+Return ONLY the corrected JSON object, with no explanation or code fences, matching this JSON schema:
+%s`, req.Prompt, answer, problem, schema)
+}
+
+// DecodeJSON decodes a model answer into out, tolerating code fences and surrounding prose.
+//
+// With a schema, near-miss values are coerced first ("Kitchen" becomes kitchen, "85"
+// becomes 85, a 0-1 confidence given as 85 becomes 0.85), and anything still outside
+// the schema's enums and ranges is reported as a *ValidationError.
+func DecodeJSON(text string, schema *Schema, out interface{}) error {
+	body := extractJSON(text)
+	if body == "" {
+		return fmt.Errorf("no JSON object in answer")
+	}
+
+	if schema == nil {
+		if err := json.Unmarshal([]byte(body), out); err != nil {
+			return fmt.Errorf("failed to parse JSON answer: %w", err)
+		}
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(body), &value); err != nil {
+		return fmt.Errorf("failed to parse JSON answer: %w", err)
+	}
+
+	var problems []string
+	value = schema.normalize(value, "", &problems)
+	if len(problems) > 0 {
+		// Objects are walked in map order; sort so the same answer always reads the same
+		sort.Strings(problems)
+		return &ValidationError{Problems: problems}
+	}
+
+	normalized, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode normalized answer: %w", err)
+	}
+	if err := json.Unmarshal(normalized, out); err != nil {
+		return fmt.Errorf("failed to decode normalized answer: %w", err)
+	}
+
+	return nil
+}
+
+// IsValidationError reports whether err is, or wraps, a *ValidationError
+func IsValidationError(err error) bool {
+	var validationErr *ValidationError
+	return errors.As(err, &validationErr)
+}
+
+// extractJSON returns the JSON value in a model answer, dropping code fences and prose around it
+func extractJSON(text string) string {
+	text = strings.TrimSpace(text)
+
+	if strings.HasPrefix(text, "```") {
+		// Drop the opening fence line, e.g. ```json, and the closing fence
+		if newline := strings.IndexByte(text, '\n'); newline >= 0 {
+			text = text[newline+1:]
+		} else {
+			text = strings.TrimPrefix(text, "```")
+		}
+		if end := strings.LastIndex(text, "```"); end >= 0 {
+			text = text[:end]
+		}
+		text = strings.TrimSpace(text)
+	}
+
+	if strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[") {
+		return text
+	}
+
+	start := strings.IndexByte(text, '{')
+	end := strings.LastIndexByte(text, '}')
+	if start < 0 || end < start {
+		return ""
+	}
+	return text[start : end+1]
+}
+
+// normalize coerces a decoded JSON value towards the schema and records what cannot be coerced
+func (s *Schema) normalize(value interface{}, path string, problems *[]string) interface{} {
+	if value == nil {
+		if s.Type == SchemaTypeArray && !s.Nullable {
+			return []interface{}{}
+		}
+		if !s.Nullable {
+			*problems = append(*problems, fmt.Sprintf("%s must not be null", displayPath(path)))
+		}
+		return nil
+	}
+
+	switch s.Type {
+	case SchemaTypeObject:
+		return s.normalizeObject(value, path, problems)
+	case SchemaTypeArray:
+		items, ok := value.([]interface{})
+		if !ok {
+			// A lone value where a list was expected
+			items = []interface{}{value}
+		}
+		for i, item := range items {
+			items[i] = s.Items.normalize(item, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+		return items
+	case SchemaTypeString:
+		return s.normalizeString(value, path, problems)
+	case SchemaTypeInteger, SchemaTypeNumber:
+		return s.normalizeNumber(value, path, problems)
+	case SchemaTypeBoolean:
+		return normalizeBoolean(value, path, problems)
+	default:
+		return value
+	}
+}
+
+func (s *Schema) normalizeObject(value interface{}, path string, problems *[]string) interface{} {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		*problems = append(*problems, fmt.Sprintf("%s must be an object", displayPath(path)))
+		return value
+	}
+
+	// Match keys loosely, so "Lead Score" answers lead_score
+	byToken := make(map[string]string, len(s.Properties))
+	for name := range s.Properties {
+		byToken[enumToken(name)] = name
+	}
+
+	normalized := make(map[string]interface{}, len(object))
+	for key, item := range object {
+		name, known := byToken[enumToken(key)]
+		if !known {
+			// Fields outside the schema, e.g. details, pass through untouched
+			normalized[key] = item
+			continue
+		}
+		normalized[name] = s.Properties[name].normalize(item, joinPath(path, name), problems)
+	}
+
+	for _, name := range s.Required {
+		if _, present := normalized[name]; !present {
+			*problems = append(*problems, fmt.Sprintf("%s is missing", joinPath(path, name)))
+		}
+	}
+
+	return normalized
+}
+
+func (s *Schema) normalizeString(value interface{}, path string, problems *[]string) interface{} {
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		text = strconv.FormatBool(v)
+	default:
+		*problems = append(*problems, fmt.Sprintf("%s must be a string", displayPath(path)))
+		return value
+	}
+
+	if len(s.Enum) == 0 {
+		return text
+	}
+	token := enumToken(text)
+	for _, allowed := range s.Enum {
+		if enumToken(allowed) == token {
+			return allowed
+		}
+	}
+
+	*problems = append(*problems, fmt.Sprintf("%s %q is not one of %s", displayPath(path), text, strings.Join(s.Enum, ", ")))
+	return text
+}
+
+func (s *Schema) normalizeNumber(value interface{}, path string, problems *[]string) interface{} {
+	var number float64
+	switch v := value.(type) {
+	case float64:
+		number = v
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v), "%")), 64)
+		if err != nil {
+			*problems = append(*problems, fmt.Sprintf("%s %q is not a number", displayPath(path), v))
+			return value
+		}
+		number = parsed
+	default:
+		*problems = append(*problems, fmt.Sprintf("%s must be a number", displayPath(path)))
+		return value
+	}
+
+	// A 0-1 value given as a percentage
+	if s.Minimum != nil && s.Maximum != nil && *s.Minimum == 0 && *s.Maximum == 1 && number > 1 && number <= 100 {
+		number /= 100
+	}
+	if s.Type == SchemaTypeInteger {
+		number = math.Round(number)
+	}
+
+	if (s.Minimum != nil && number < *s.Minimum) || (s.Maximum != nil && number > *s.Maximum) {
+		*problems = append(*problems, fmt.Sprintf("%s %v is outside %s", displayPath(path), number, s.rangeText()))
+	}
+
+	return number
+}
+
+func normalizeBoolean(value interface{}, path string, problems *[]string) interface{} {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "yes", "y":
+			return true
+		case "false", "no", "n":
+			return false
+		}
+	}
+
+	*problems = append(*problems, fmt.Sprintf("%s must be true or false", displayPath(path)))
+	return value
+}
+
+// rangeText describes the schema's numeric bounds
+func (s *Schema) rangeText() string {
+	switch {
+	case s.Minimum != nil && s.Maximum != nil:
+		return fmt.Sprintf("%v-%v", *s.Minimum, *s.Maximum)
+	case s.Minimum != nil:
+		return fmt.Sprintf(">= %v", *s.Minimum)
+	default:
+		return fmt.Sprintf("<= %v", *s.Maximum)
+	}
+}
+
+// enumToken reduces a value to the form near-miss spellings share: lowercase, with
+// spaces and hyphens treated as underscores
+func enumToken(value string) string {
+	token := strings.ToLower(strings.TrimSpace(value))
+	token = strings.NewReplacer(" ", "_", "-", "_").Replace(token)
+	return token
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "answer"
+	}
+	return path
+}
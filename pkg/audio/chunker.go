@@ -0,0 +1,509 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/speech/apiv1/speechpb"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/scheduler"
+)
+
+// ChunkingConfig controls how long recordings are split before transcription
+type ChunkingConfig struct {
+	MinDuration      time.Duration `json:"min_duration"` // recordings at least this long are transcribed in chunks
+	MaxChunkDuration time.Duration `json:"max_chunk_duration"`
+	Overlap          time.Duration `json:"overlap"`
+	SearchWindow     time.Duration `json:"search_window"`     // how far back from the hard limit to look for silence
+	MinSilence       time.Duration `json:"min_silence"`       // shortest pause treated as a cut point
+	SilenceThreshold float64       `json:"silence_threshold"` // dBFS below which a frame is silent
+	FrameDuration    time.Duration `json:"frame_duration"`
+	Concurrency      int           `json:"concurrency"`
+	MaxRetries       int           `json:"max_retries"`
+}
+
+// DefaultChunkingConfig returns chunking defaults suited to 8kHz phone audio
+func DefaultChunkingConfig() *ChunkingConfig {
+	return &ChunkingConfig{
+		MinDuration:      10 * time.Minute,
+		MaxChunkDuration: 4 * time.Minute,
+		Overlap:          3 * time.Second,
+		SearchWindow:     30 * time.Second,
+		MinSilence:       300 * time.Millisecond,
+		SilenceThreshold: -40,
+		FrameDuration:    20 * time.Millisecond,
+		Concurrency:      4,
+		MaxRetries:       2,
+	}
+}
+
+// ChunkSampleRate is the rate chunks are sent at. Speech-to-Text gains nothing from higher rates
+// on speech, and at 16kHz a default-length chunk stays well under MaxInlineAudioBytes.
+const ChunkSampleRate = 16000
+
+// ChunkWindow describes one overlapping slice of a recording
+type ChunkWindow struct {
+	Index        int           `json:"index"`
+	Start        time.Duration `json:"start"`
+	End          time.Duration `json:"end"`
+	CutAtSilence bool          `json:"cut_at_silence"`
+}
+
+// ChunkTranscript holds the recognized words of one chunk, timed relative to the chunk start
+type ChunkTranscript struct {
+	Window     ChunkWindow
+	Words      []*speechpb.WordInfo
+	Confidence float32
+	Language   string // dominant language detected in the chunk
+}
+
+// Chunker splits recordings on silence boundaries into overlapping windows
+type Chunker struct {
+	config *ChunkingConfig
+}
+
+// NewChunker creates a new chunker
+func NewChunker(config *ChunkingConfig) *Chunker {
+	if config == nil {
+		config = DefaultChunkingConfig()
+	}
+	return &Chunker{config: config}
+}
+
+// Plan computes the chunk windows for the given audio. Short recordings produce a single window.
+// Chunks are sent inline, so none is planned longer than MaxInlineAudioBytes of LINEAR16 holds
+// at the audio's sample rate.
+func (c *Chunker) Plan(pcm *PCMAudio) []ChunkWindow {
+	maxChunk := c.config.MaxChunkDuration
+	if pcm.SampleRate > 0 {
+		inline := time.Duration(MaxInlineAudioBytes/(2*pcm.SampleRate)) * time.Second
+		if maxChunk <= 0 || maxChunk > inline {
+			maxChunk = inline
+		}
+	}
+
+	total := pcm.Duration()
+	if total <= maxChunk {
+		return []ChunkWindow{{Index: 0, Start: 0, End: total}}
+	}
+
+	energies := pcm.FrameEnergies(c.config.FrameDuration)
+	silent := make([]bool, len(energies))
+	for i, e := range energies {
+		silent[i] = e < c.config.SilenceThreshold
+	}
+
+	var windows []ChunkWindow
+	start := time.Duration(0)
+	for start < total {
+		limit := start + maxChunk
+		if limit >= total {
+			windows = append(windows, ChunkWindow{Index: len(windows), Start: start, End: total})
+			break
+		}
+
+		end, atSilence := c.findCut(silent, start, limit)
+		windows = append(windows, ChunkWindow{Index: len(windows), Start: start, End: end, CutAtSilence: atSilence})
+
+		next := end - c.config.Overlap
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+
+	return windows
+}
+
+// findCut looks back from limit for the pause nearest to it and returns the middle of that pause
+func (c *Chunker) findCut(silent []bool, start, limit time.Duration) (time.Duration, bool) {
+	frame := c.config.FrameDuration
+	minFrames := int(c.config.MinSilence / frame)
+	if minFrames < 1 {
+		minFrames = 1
+	}
+
+	earliest := limit - c.config.SearchWindow
+	if earliest < start+c.config.Overlap*2 {
+		earliest = start + c.config.Overlap*2
+	}
+
+	lo := int(earliest / frame)
+	hi := int(limit / frame)
+	if hi > len(silent) {
+		hi = len(silent)
+	}
+
+	// Walk backwards so the first qualifying run is the one closest to the limit
+	run := 0
+	for i := hi - 1; i >= lo; i-- {
+		if silent[i] {
+			run++
+			continue
+		}
+		if run >= minFrames {
+			mid := i + 1 + run/2
+			return time.Duration(mid) * frame, true
+		}
+		run = 0
+	}
+	if run >= minFrames {
+		mid := lo + run/2
+		return time.Duration(mid) * frame, true
+	}
+
+	return limit, false
+}
+
+// StitchChunks merges per-chunk words into a single timeline. Words in overlapping regions are
+// kept from whichever chunk owns that half of the overlap, timestamps are shifted to be
+// absolute, and speaker tags are renumbered so the same speaker keeps one label across chunks.
+func StitchChunks(chunks []ChunkTranscript) []*speechpb.WordInfo {
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].Window.Index < chunks[j].Window.Index })
+
+	var stitched []*speechpb.WordInfo
+	var previous []*speechpb.WordInfo
+	nextGlobalTag := int32(1)
+
+	for i, chunk := range chunks {
+		// Shift words to absolute time
+		words := make([]*speechpb.WordInfo, 0, len(chunk.Words))
+		for _, w := range chunk.Words {
+			words = append(words, &speechpb.WordInfo{
+				Word:       w.Word,
+				StartTime:  toDurationpb(durationOf(w.StartTime) + chunk.Window.Start),
+				EndTime:    toDurationpb(durationOf(w.EndTime) + chunk.Window.Start),
+				Confidence: w.Confidence,
+				SpeakerTag: w.SpeakerTag,
+			})
+		}
+
+		// Map local speaker tags onto the global numbering using words both chunks heard
+		tagMap := matchSpeakers(previous, words)
+		used := make(map[int32]bool)
+		for _, g := range tagMap {
+			used[g] = true
+		}
+		for _, w := range words {
+			if w.SpeakerTag == 0 {
+				continue
+			}
+			if _, ok := tagMap[w.SpeakerTag]; ok {
+				continue
+			}
+			// Unmatched speakers take the lowest free existing label, or a new one
+			g := int32(1)
+			for g < nextGlobalTag && used[g] {
+				g++
+			}
+			tagMap[w.SpeakerTag] = g
+			used[g] = true
+			if g >= nextGlobalTag {
+				nextGlobalTag = g + 1
+			}
+		}
+
+		// Each chunk owns its window up to the middle of the overlap with its neighbours
+		lower := chunk.Window.Start
+		if i > 0 {
+			lower = overlapMidpoint(chunks[i-1].Window, chunk.Window)
+		}
+		upper := chunk.Window.End
+		if i < len(chunks)-1 {
+			upper = overlapMidpoint(chunk.Window, chunks[i+1].Window)
+		}
+
+		for _, w := range words {
+			if w.SpeakerTag != 0 {
+				w.SpeakerTag = tagMap[w.SpeakerTag]
+			}
+			start := durationOf(w.StartTime)
+			if start < lower || (start >= upper && i < len(chunks)-1) {
+				continue
+			}
+			stitched = append(stitched, w)
+		}
+
+		previous = words
+	}
+
+	return stitched
+}
+
+// overlapMidpoint returns the point halfway through the overlap of two consecutive windows
+func overlapMidpoint(a, b ChunkWindow) time.Duration {
+	if b.Start >= a.End {
+		return b.Start
+	}
+	return b.Start + (a.End-b.Start)/2
+}
+
+// matchSpeakers votes on which global speaker each local tag corresponds to by pairing
+// identical words spoken at the same time in the overlap of two chunks
+func matchSpeakers(previous, current []*speechpb.WordInfo) map[int32]int32 {
+	const tolerance = 500 * time.Millisecond
+
+	votes := make(map[[2]int32]int)
+	for _, cur := range current {
+		if cur.SpeakerTag == 0 {
+			continue
+		}
+		for _, prev := range previous {
+			if prev.SpeakerTag == 0 || !sameWord(prev.Word, cur.Word) {
+				continue
+			}
+			diff := durationOf(prev.StartTime) - durationOf(cur.StartTime)
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff <= tolerance {
+				votes[[2]int32{cur.SpeakerTag, prev.SpeakerTag}]++
+			}
+		}
+	}
+
+	type vote struct {
+		local, global int32
+		count         int
+	}
+	ranked := make([]vote, 0, len(votes))
+	for k, n := range votes {
+		ranked = append(ranked, vote{local: k[0], global: k[1], count: n})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].count != ranked[j].count {
+			return ranked[i].count > ranked[j].count
+		}
+		return ranked[i].local < ranked[j].local
+	})
+
+	mapping := make(map[int32]int32)
+	taken := make(map[int32]bool)
+	for _, v := range ranked {
+		if _, ok := mapping[v.local]; ok || taken[v.global] {
+			continue
+		}
+		mapping[v.local] = v.global
+		taken[v.global] = true
+	}
+	return mapping
+}
+
+// sameWord compares words ignoring case and trailing punctuation
+func sameWord(a, b string) bool {
+	norm := func(s string) string {
+		return strings.ToLower(strings.Trim(s, ".,?!;:\"'"))
+	}
+	return norm(a) == norm(b)
+}
+
+// TranscribeChunked transcribes long PCM audio by splitting it into overlapping chunks,
+// transcribing them concurrently with per-chunk retries, and stitching the results. Audio above
+// ChunkSampleRate is downsampled first so each chunk fits inline. Every chunk
+// takes its own scheduler slot, so a chunked job counts against the global and tenant budgets
+// once per recognition it runs.
+func (ts *TranscriptionService) TranscribeChunked(ctx context.Context, req *TranscriptionRequest, pcm *PCMAudio) (*TranscriptionResponse, error) {
+	startTime := time.Now()
+
+	response := &TranscriptionResponse{
+		Request:   req,
+		StartTime: startTime,
+		Metadata:  make(map[string]interface{}),
+	}
+
+	config := ts.configFor(req)
+	chunking := config.Chunking
+	if chunking == nil {
+		chunking = DefaultChunkingConfig()
+	}
+
+	if req.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, req.Timeout)
+		defer cancel()
+	}
+
+	pcm = pcm.Resample(ChunkSampleRate)
+	windows := NewChunker(chunking).Plan(pcm)
+	response.Metadata["chunk_count"] = len(windows)
+
+	concurrency := chunking.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	semaphore := make(chan struct{}, concurrency)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		chunks   = make([]ChunkTranscript, len(windows))
+		failures []string
+	)
+
+	for _, window := range windows {
+		wg.Add(1)
+		go func(w ChunkWindow) {
+			defer wg.Done()
+			semaphore <- struct{}{}        // Acquire
+			defer func() { <-semaphore }() // Release
+
+			chunk, err := ts.transcribeChunkWithRetry(ctx, req, pcm.Slice(w.Start, w.End), w, config, chunking.MaxRetries)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("chunk %d (%s-%s): %v", w.Index, w.Start, w.End, err))
+				return
+			}
+			chunks[w.Index] = *chunk
+		}(window)
+	}
+	wg.Wait()
+
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		response.Success = false
+		response.Error = fmt.Sprintf("%d of %d chunks failed: %s", len(failures), len(windows), strings.Join(failures, "; "))
+		response.Metadata["failed_chunks"] = failures
+		response.EndTime = time.Now()
+		response.Duration = time.Since(startTime)
+		return response, fmt.Errorf("chunked transcription failed: %s", response.Error)
+	}
+
+	words := StitchChunks(chunks)
+	response.Result = ts.buildTranscriptionResult(buildStitchedResponse(chunks, words).Results, config)
+	response.Result.Duration = pcm.Duration().Seconds()
+
+	response.Success = true
+	response.EndTime = time.Now()
+	response.Duration = time.Since(startTime)
+	response.Metadata["total_words"] = len(words)
+
+	return response, nil
+}
+
+// transcribeChunkWithRetry transcribes a single chunk, retrying transient failures with backoff.
+// Each attempt holds a scheduler slot only while it runs.
+func (ts *TranscriptionService) transcribeChunkWithRetry(ctx context.Context, req *TranscriptionRequest, pcm *PCMAudio, window ChunkWindow, config *TranscriptionConfig, maxRetries int) (*ChunkTranscript, error) {
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Duration(attempt) * 2 * time.Second):
+			}
+		}
+
+		release, err := ts.scheduler.Acquire(ctx, req.TenantID, scheduler.Priority(req.Priority))
+		if err != nil {
+			return nil, err
+		}
+		chunk, err := ts.transcribeChunk(ctx, pcm, window, config)
+		release()
+		if err == nil {
+			return chunk, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// transcribeChunk sends one chunk of LINEAR16 audio to Speech-to-Text
+func (ts *TranscriptionService) transcribeChunk(ctx context.Context, pcm *PCMAudio, window ChunkWindow, config *TranscriptionConfig) (*ChunkTranscript, error) {
+	recognitionConfig := ts.buildRecognitionConfig(config)
+	recognitionConfig.Encoding = speechpb.RecognitionConfig_LINEAR16
+	recognitionConfig.SampleRateHertz = int32(pcm.SampleRate)
+
+	operation, err := ts.speechClient.LongRunningRecognize(ctx, &speechpb.LongRunningRecognizeRequest{
+		Config: recognitionConfig,
+		Audio: &speechpb.RecognitionAudio{
+			AudioSource: &speechpb.RecognitionAudio_Content{Content: pcm.LinearBytes()},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transcription: %w", err)
+	}
+
+	resp, err := operation.Wait(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("transcription failed: %w", err)
+	}
+
+	recognized := resp.Results
+	if config.EnableDiarization {
+		recognized = diarizedResults(recognized)
+	}
+
+	chunk := &ChunkTranscript{Window: window}
+	var totalConfidence float32
+	results := 0
+	for _, res := range recognized {
+		if len(res.Alternatives) == 0 {
+			continue
+		}
+		alt := res.Alternatives[0]
+		totalConfidence += alt.Confidence
+		results++
+		chunk.Words = append(chunk.Words, alt.Words...)
+	}
+	if results > 0 {
+		chunk.Confidence = totalConfidence / float32(results)
+	}
+	chunk.Language = dominantLanguage(recognized, config.LanguageCode)
+
+	return chunk, nil
+}
+
+// buildStitchedResponse wraps stitched words in a recognize response, one result per chunk,
+// so the regular result building produces the final transcript
+func buildStitchedResponse(chunks []ChunkTranscript, words []*speechpb.WordInfo) *speechpb.LongRunningRecognizeResponse {
+	resp := &speechpb.LongRunningRecognizeResponse{}
+
+	wordIdx := 0
+	for i, chunk := range chunks {
+		upper := time.Duration(1<<63 - 1)
+		if i < len(chunks)-1 {
+			upper = overlapMidpoint(chunk.Window, chunks[i+1].Window)
+		}
+
+		var chunkWords []*speechpb.WordInfo
+		var text []string
+		for wordIdx < len(words) && durationOf(words[wordIdx].StartTime) < upper {
+			chunkWords = append(chunkWords, words[wordIdx])
+			text = append(text, words[wordIdx].Word)
+			wordIdx++
+		}
+		if len(chunkWords) == 0 {
+			continue
+		}
+
+		resp.Results = append(resp.Results, &speechpb.SpeechRecognitionResult{
+			Alternatives: []*speechpb.SpeechRecognitionAlternative{{
+				Transcript: strings.Join(text, " "),
+				Confidence: chunk.Confidence,
+				Words:      chunkWords,
+			}},
+			LanguageCode: chunk.Language,
+		})
+	}
+
+	return resp
+}
+
+// durationOf converts a protobuf duration into a time.Duration
+func durationOf(d *durationpb.Duration) time.Duration {
+	if d == nil {
+		return 0
+	}
+	return d.AsDuration()
+}
+
+// toDurationpb converts a time.Duration into a protobuf duration
+func toDurationpb(d time.Duration) *durationpb.Duration {
+	return durationpb.New(d)
+}
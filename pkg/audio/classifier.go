@@ -0,0 +1,352 @@
+package audio
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// ClassifierConfig contains thresholds for pre-transcription call classification
+type ClassifierConfig struct {
+	FrameDuration     time.Duration `json:"frame_duration"`
+	SilenceThreshold  float64       `json:"silence_threshold"`   // dBFS below which a frame is silent
+	MinSpeechDuration time.Duration `json:"min_speech_duration"` // less voiced audio than this is no_speech
+	NoSpeechRatio     float64       `json:"no_speech_ratio"`     // silence ratio above which a call is no_speech
+	MaxVoicemail      time.Duration `json:"max_voicemail"`       // unanswered calls longer than this are not treated as voicemail
+	BeepFrequencies   []float64     `json:"beep_frequencies"`
+	BeepMinDuration   time.Duration `json:"beep_min_duration"`
+	BeepMaxDuration   time.Duration `json:"beep_max_duration"`
+	BeepToneRatio     float64       `json:"beep_tone_ratio"` // share of frame energy that must sit on the tone
+}
+
+// DefaultClassifierConfig returns classifier defaults tuned for phone recordings
+func DefaultClassifierConfig() *ClassifierConfig {
+	return &ClassifierConfig{
+		FrameDuration:     20 * time.Millisecond,
+		SilenceThreshold:  -40,
+		MinSpeechDuration: 2 * time.Second,
+		NoSpeechRatio:     0.95,
+		MaxVoicemail:      3 * time.Minute,
+		BeepFrequencies:   []float64{440, 850, 950, 1000, 1400},
+		BeepMinDuration:   150 * time.Millisecond,
+		BeepMaxDuration:   2 * time.Second,
+		BeepToneRatio:     0.6,
+	}
+}
+
+// CallClassification is the result of classifying a call before or after transcription
+type CallClassification struct {
+	Class          models.CallClass `json:"class"`
+	Confidence     float64          `json:"confidence"`
+	SilenceRatio   float64          `json:"silence_ratio"`
+	SpeechDuration float64          `json:"speech_duration_seconds"`
+	TotalDuration  float64          `json:"total_duration_seconds"`
+	BeepDetected   bool             `json:"beep_detected"`
+	BeepOffset     float64          `json:"beep_offset_seconds,omitempty"`
+	Reasons        []string         `json:"reasons"`
+}
+
+// CallClassifier classifies calls as conversation, voicemail, IVR-only or no speech
+type CallClassifier struct {
+	config *ClassifierConfig
+}
+
+// NewCallClassifier creates a new call classifier
+func NewCallClassifier(config *ClassifierConfig) *CallClassifier {
+	if config == nil {
+		config = DefaultClassifierConfig()
+	}
+	return &CallClassifier{config: config}
+}
+
+// ClassifyAudio classifies a call from its audio and CallRail metadata. pcm may be nil when the
+// recording is in a format that cannot be decoded locally, in which case only metadata is used.
+// answered is nil and callDuration zero when CallRail didn't say; unknown metadata never makes a
+// call voicemail.
+func (c *CallClassifier) ClassifyAudio(pcm *PCMAudio, answered *bool, callDuration time.Duration) *CallClassification {
+	result := &CallClassification{
+		Class:         models.CallClassConversation,
+		Confidence:    0.5,
+		TotalDuration: callDuration.Seconds(),
+	}
+
+	if pcm != nil && len(pcm.Samples) > 0 {
+		energies := pcm.FrameEnergies(c.config.FrameDuration)
+		voiced := 0
+		for _, e := range energies {
+			if e >= c.config.SilenceThreshold {
+				voiced++
+			}
+		}
+		result.TotalDuration = pcm.Duration().Seconds()
+		result.SilenceRatio = 1 - float64(voiced)/float64(len(energies))
+		result.SpeechDuration = (time.Duration(voiced) * c.config.FrameDuration).Seconds()
+
+		if offset, ok := c.detectBeep(pcm); ok {
+			result.BeepDetected = true
+			result.BeepOffset = offset.Seconds()
+			result.Reasons = append(result.Reasons, "voicemail beep tone detected")
+		}
+
+		if result.SilenceRatio >= c.config.NoSpeechRatio || result.SpeechDuration < c.config.MinSpeechDuration.Seconds() {
+			result.Class = models.CallClassNoSpeech
+			result.Confidence = 0.9
+			result.Reasons = append(result.Reasons, "recording is almost entirely silent")
+			return result
+		}
+	}
+
+	if answered != nil && !*answered {
+		if pcm == nil && callDuration > 0 && callDuration < c.config.MinSpeechDuration {
+			result.Class = models.CallClassNoSpeech
+			result.Confidence = 0.7
+			result.Reasons = append(result.Reasons, "unanswered call shorter than minimum speech duration")
+			return result
+		}
+		if callDuration <= c.config.MaxVoicemail || result.BeepDetected {
+			result.Class = models.CallClassVoicemail
+			result.Confidence = 0.7
+			if result.BeepDetected {
+				result.Confidence = 0.9
+			}
+			result.Reasons = append(result.Reasons, "call was not answered")
+			return result
+		}
+	}
+
+	if result.BeepDetected {
+		result.Class = models.CallClassVoicemail
+		result.Confidence = 0.75
+	}
+
+	return result
+}
+
+// detectBeep looks for a sustained narrowband tone typical of a voicemail beep
+func (c *CallClassifier) detectBeep(pcm *PCMAudio) (time.Duration, bool) {
+	frameSize := pcm.SampleAt(c.config.FrameDuration)
+	if frameSize <= 0 {
+		return 0, false
+	}
+
+	minFrames := int(c.config.BeepMinDuration / c.config.FrameDuration)
+	maxFrames := int(c.config.BeepMaxDuration / c.config.FrameDuration)
+
+	run := 0
+	runFreq := 0.0
+	runStart := 0
+	for frame := 0; (frame+1)*frameSize <= len(pcm.Samples); frame++ {
+		samples := pcm.Samples[frame*frameSize : (frame+1)*frameSize]
+
+		freq := c.dominantTone(samples, pcm.SampleRate)
+		if freq > 0 && (run == 0 || freq == runFreq) {
+			if run == 0 {
+				runStart = frame
+				runFreq = freq
+			}
+			run++
+			continue
+		}
+
+		if run >= minFrames && run <= maxFrames {
+			return time.Duration(runStart) * c.config.FrameDuration, true
+		}
+		run = 0
+		if freq > 0 {
+			runStart = frame
+			runFreq = freq
+			run = 1
+		}
+	}
+
+	if run >= minFrames && run <= maxFrames {
+		return time.Duration(runStart) * c.config.FrameDuration, true
+	}
+	return 0, false
+}
+
+// dominantTone returns the beep frequency carrying most of the frame's energy, or 0 if none does
+func (c *CallClassifier) dominantTone(samples []int16, sampleRate int) float64 {
+	var total float64
+	for _, s := range samples {
+		v := float64(s)
+		total += v * v
+	}
+	if total == 0 || rmsDBFS(samples) < c.config.SilenceThreshold {
+		return 0
+	}
+
+	for _, freq := range c.config.BeepFrequencies {
+		// Goertzel power normalised so a pure tone at freq yields a ratio close to 1
+		power := goertzel(samples, sampleRate, freq)
+		ratio := 2 * power / (float64(len(samples)) * total)
+		if ratio >= c.config.BeepToneRatio {
+			return freq
+		}
+	}
+	return 0
+}
+
+// goertzel computes the signal power at a single frequency
+func goertzel(samples []int16, sampleRate int, freq float64) float64 {
+	coeff := 2 * math.Cos(2*math.Pi*freq/float64(sampleRate))
+	var s1, s2 float64
+	for _, x := range samples {
+		s0 := float64(x) + coeff*s1 - s2
+		s2 = s1
+		s1 = s0
+	}
+	return s1*s1 + s2*s2 - coeff*s1*s2
+}
+
+var voicemailPhrases = []string{
+	"leave a message",
+	"leave your message",
+	"after the tone",
+	"after the beep",
+	"not available to take your call",
+	"unable to take your call",
+	"you have reached the voicemail",
+	"you've reached the voicemail",
+	"mailbox is full",
+	"please leave your name and number",
+	"call you back as soon as",
+}
+
+var ivrPhrases = []string{
+	"press 1",
+	"press one",
+	"press 2",
+	"press two",
+	"para español",
+	"para espanol",
+	"your call is important to us",
+	"please stay on the line",
+	"please hold",
+	"main menu",
+	"for all other inquiries",
+	"this call may be recorded",
+}
+
+// ClassifyTranscript refines an audio-based classification using phrases in the transcript
+func (c *CallClassifier) ClassifyTranscript(prior *CallClassification, transcript string, speakerCount int) *CallClassification {
+	result := prior
+	if result == nil {
+		result = &CallClassification{Class: models.CallClassConversation, Confidence: 0.5}
+	}
+
+	text := strings.ToLower(strings.TrimSpace(transcript))
+	if text == "" {
+		result.Class = models.CallClassNoSpeech
+		result.Confidence = 0.95
+		result.Reasons = append(result.Reasons, "transcript is empty")
+		return result
+	}
+
+	voicemailHits := countPhrases(text, voicemailPhrases)
+	ivrHits := countPhrases(text, ivrPhrases)
+	words := len(strings.Fields(text))
+
+	switch {
+	case ivrHits >= 2 && voicemailHits == 0 && (speakerCount <= 1 || words < 60):
+		result.Class = models.CallClassIVROnly
+		result.Confidence = math.Min(0.6+0.1*float64(ivrHits), 0.95)
+		result.Reasons = append(result.Reasons, "transcript contains automated menu prompts only")
+	case voicemailHits > 0 && speakerCount <= 1:
+		result.Class = models.CallClassVoicemail
+		result.Confidence = math.Min(0.7+0.1*float64(voicemailHits), 0.95)
+		result.Reasons = append(result.Reasons, "transcript contains a voicemail greeting")
+	case result.Class == models.CallClassVoicemail && speakerCount >= 2 && voicemailHits == 0:
+		// Two people actually talked; the beep or unanswered flag was misleading
+		result.Class = models.CallClassConversation
+		result.Confidence = 0.6
+		result.Reasons = append(result.Reasons, "transcript has a two-sided conversation")
+	}
+
+	return result
+}
+
+// countPhrases counts how many of the phrases appear in text
+func countPhrases(text string, phrases []string) int {
+	hits := 0
+	for _, phrase := range phrases {
+		if strings.Contains(text, phrase) {
+			hits++
+		}
+	}
+	return hits
+}
+
+// ScreeningDecision says which downstream stages should run for a classified call
+type ScreeningDecision struct {
+	Transcribe   bool                `json:"transcribe"`
+	AnalysisMode models.AnalysisMode `json:"analysis_mode"`
+}
+
+// Decide applies the tenant's screening policy to a classification
+func Decide(classification *CallClassification, policy models.CallScreeningConfig) ScreeningDecision {
+	decision := ScreeningDecision{Transcribe: true, AnalysisMode: models.AnalysisModeFull}
+	if !policy.Enabled || classification == nil {
+		return decision
+	}
+
+	switch classification.Class {
+	case models.CallClassNoSpeech:
+		decision.Transcribe = !policy.SkipTranscriptionForNoSpeech
+		decision.AnalysisMode = models.AnalysisModeSkip
+	case models.CallClassVoicemail:
+		decision.AnalysisMode = policy.VoicemailAnalysis
+	case models.CallClassIVROnly:
+		decision.AnalysisMode = policy.IVRAnalysis
+	}
+
+	if decision.AnalysisMode == "" {
+		decision.AnalysisMode = models.AnalysisModeSummary
+	}
+	return decision
+}
+
+// BuildVoicemailLead creates the short lead record kept for voicemails instead of a full analysis
+func BuildVoicemailLead(classification *CallClassification, transcript, callerName, callerPhone string) *models.VoicemailLead {
+	lead := &models.VoicemailLead{
+		CallerName:     callerName,
+		CallerPhone:    callerPhone,
+		Classification: classification.Class,
+		Duration:       classification.TotalDuration,
+		ReceivedAt:     time.Now().UTC(),
+	}
+
+	// Drop the greeting so the summary starts with what the caller said
+	text := strings.TrimSpace(transcript)
+	if classification.BeepDetected {
+		lower := strings.ToLower(text)
+		for _, marker := range []string{"after the tone", "after the beep"} {
+			if idx := strings.Index(lower, marker); idx >= 0 {
+				if end := strings.IndexAny(text[idx:], ".!?"); end >= 0 {
+					text = strings.TrimSpace(text[idx+end+1:])
+				}
+				break
+			}
+		}
+	}
+
+	const maxSummary = 280
+	if len(text) > maxSummary {
+		cut := strings.LastIndex(text[:maxSummary], " ")
+		if cut <= 0 {
+			cut = maxSummary
+		}
+		text = text[:cut] + "..."
+	}
+	lead.Message = text
+
+	lower := strings.ToLower(text)
+	lead.CallbackRequested = strings.Contains(lower, "call me back") ||
+		strings.Contains(lower, "give me a call") ||
+		strings.Contains(lower, "call back") ||
+		strings.Contains(lower, "reach me")
+
+	return lead
+}
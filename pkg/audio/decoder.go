@@ -0,0 +1,102 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnsupportedFormat is returned when a recording can't be decoded locally
+var ErrUnsupportedFormat = errors.New("unsupported audio format")
+
+// DecoderConfig configures how stored recordings are decoded for local analysis
+type DecoderConfig struct {
+	FFmpegPath string        `json:"ffmpeg_path"` // decodes MP3 and other compressed formats; empty for WAV only
+	SampleRate int           `json:"sample_rate"` // compressed recordings are resampled to this rate
+	Timeout    time.Duration `json:"timeout"`
+}
+
+// DefaultDecoderConfig returns the decoder configuration used in production
+func DefaultDecoderConfig() *DecoderConfig {
+	return &DecoderConfig{
+		FFmpegPath: "ffmpeg",
+		SampleRate: 8000, // phone audio, as transcription is configured for
+		Timeout:    2 * time.Minute,
+	}
+}
+
+// Decoder decodes stored recordings into mono PCM. WAV files are decoded natively; CallRail's
+// MP3 recordings and other compressed formats are decoded by ffmpeg.
+type Decoder struct {
+	config *DecoderConfig
+}
+
+// NewDecoder creates a decoder with the given configuration
+func NewDecoder(config *DecoderConfig) *Decoder {
+	if config == nil {
+		config = DefaultDecoderConfig()
+	}
+	return &Decoder{config: config}
+}
+
+// Decode decodes a recording of any supported format into mono PCM
+func (d *Decoder) Decode(ctx context.Context, data []byte) (*PCMAudio, error) {
+	if isWAV(data) {
+		return DecodeWAV(data)
+	}
+	if d.config.FFmpegPath == "" {
+		return nil, fmt.Errorf("%w: only WAV can be decoded without ffmpeg", ErrUnsupportedFormat)
+	}
+	return d.decodeFFmpeg(ctx, data)
+}
+
+// decodeFFmpeg pipes the recording through ffmpeg, reading back mono 16-bit little-endian PCM
+func (d *Decoder) decodeFFmpeg(ctx context.Context, data []byte) (*PCMAudio, error) {
+	if d.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.config.Timeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, d.config.FFmpegPath,
+		"-hide_banner", "-loglevel", "error",
+		"-i", "pipe:0",
+		"-f", "s16le", "-acodec", "pcm_s16le", "-ac", "1", "-ar", strconv.Itoa(d.config.SampleRate),
+		"pipe:1")
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("%w: ffmpeg is not installed", ErrUnsupportedFormat)
+		}
+		return nil, fmt.Errorf("failed to decode recording with ffmpeg: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() < 2 {
+		return nil, fmt.Errorf("failed to decode recording with ffmpeg: no audio decoded")
+	}
+
+	return DecodeLinear16(stdout.Bytes(), d.config.SampleRate), nil
+}
+
+// DecodeLinear16 decodes mono little-endian LINEAR16 samples, the inverse of LinearBytes
+func DecodeLinear16(data []byte, sampleRate int) *PCMAudio {
+	samples := make([]int16, len(data)/2)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(data[i*2:]))
+	}
+	return &PCMAudio{Samples: samples, SampleRate: sampleRate}
+}
+
+// isWAV reports whether data starts with a RIFF/WAVE header
+func isWAV(data []byte) bool {
+	return len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WAVE"
+}
@@ -0,0 +1,512 @@
+package audio
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"math/cmplx"
+	"sync"
+	"time"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// ErrFingerprintNotFound is returned when no fingerprint is stored for a recording
+var ErrFingerprintNotFound = errors.New("fingerprint not found")
+
+// fingerprintBands is the number of frequency bands; adjacent band pairs give 32 bits per frame
+const fingerprintBands = 33
+
+// FingerprintConfig contains fingerprint extraction and matching settings
+type FingerprintConfig struct {
+	FrameLength         time.Duration `json:"frame_length"`
+	FrameHop            time.Duration `json:"frame_hop"`
+	MinFrequency        float64       `json:"min_frequency"`
+	MaxFrequency        float64       `json:"max_frequency"`
+	MinDuration         time.Duration `json:"min_duration"`         // shorter recordings are not fingerprinted
+	DuplicateWindow     time.Duration `json:"duplicate_window"`     // how far back to look for tenant duplicates
+	DurationTolerance   float64       `json:"duration_tolerance"`   // candidate duplicates differ in length by at most this fraction
+	MaxAlignShift       time.Duration `json:"max_align_shift"`      // duplicates may start this far apart
+	DuplicateSimilarity float64       `json:"duplicate_similarity"` // minimum similarity for a near duplicate
+	RobocallSimilarity  float64       `json:"robocall_similarity"`  // minimum similarity to a known robocall
+}
+
+// DefaultFingerprintConfig returns fingerprint settings for narrowband phone audio
+func DefaultFingerprintConfig() *FingerprintConfig {
+	return &FingerprintConfig{
+		FrameLength:         256 * time.Millisecond,
+		FrameHop:            32 * time.Millisecond,
+		MinFrequency:        300,
+		MaxFrequency:        3400,
+		MinDuration:         3 * time.Second,
+		DuplicateWindow:     30 * 24 * time.Hour,
+		DurationTolerance:   0.1,
+		MaxAlignShift:       5 * time.Second,
+		DuplicateSimilarity: 0.85,
+		RobocallSimilarity:  0.8,
+	}
+}
+
+// Fingerprint is a chromaprint-style acoustic fingerprint: one 32-bit sub-fingerprint per frame,
+// each bit the sign of the energy difference between adjacent bands compared with the previous
+// frame. Re-encoding, volume changes and line noise flip few bits, so similar audio stays similar.
+type Fingerprint struct {
+	Hashes []uint32
+	Hop    time.Duration
+}
+
+// Duration returns the length of audio the fingerprint covers
+func (f *Fingerprint) Duration() time.Duration {
+	return time.Duration(len(f.Hashes)) * f.Hop
+}
+
+// Encode serialises the fingerprint hashes for storage
+func (f *Fingerprint) Encode() string {
+	data := make([]byte, 4*len(f.Hashes))
+	for i, h := range f.Hashes {
+		binary.LittleEndian.PutUint32(data[4*i:], h)
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// Digest returns a hash of the fingerprint; identical audio has identical digests
+func (f *Fingerprint) Digest() string {
+	sum := sha256.Sum256([]byte(f.Encode()))
+	return hex.EncodeToString(sum[:])
+}
+
+// DecodeFingerprint parses a fingerprint stored with Encode
+func DecodeFingerprint(encoded string, hop time.Duration) (*Fingerprint, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode fingerprint: %w", err)
+	}
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("invalid fingerprint length %d", len(data))
+	}
+
+	f := &Fingerprint{Hashes: make([]uint32, len(data)/4), Hop: hop}
+	for i := range f.Hashes {
+		f.Hashes[i] = binary.LittleEndian.Uint32(data[4*i:])
+	}
+	return f, nil
+}
+
+// ComputeFingerprint extracts the acoustic fingerprint of a recording
+func ComputeFingerprint(pcm *PCMAudio, config *FingerprintConfig) *Fingerprint {
+	if config == nil {
+		config = DefaultFingerprintConfig()
+	}
+	f := &Fingerprint{Hop: config.FrameHop}
+	if pcm == nil || pcm.SampleRate <= 0 {
+		return f
+	}
+
+	size := 1
+	for size < int(float64(pcm.SampleRate)*config.FrameLength.Seconds()) {
+		size <<= 1
+	}
+	hop := int(float64(pcm.SampleRate) * config.FrameHop.Seconds())
+	if hop <= 0 || len(pcm.Samples) < size {
+		return f
+	}
+
+	edges := bandEdges(size, pcm.SampleRate, config.MinFrequency, config.MaxFrequency)
+	window := hannWindow(size)
+	buf := make([]complex128, size)
+
+	var previous []float64
+	for start := 0; start+size <= len(pcm.Samples); start += hop {
+		for i := 0; i < size; i++ {
+			buf[i] = complex(float64(pcm.Samples[start+i])*window[i], 0)
+		}
+		fft(buf)
+
+		energies := make([]float64, fingerprintBands)
+		for b := 0; b < fingerprintBands; b++ {
+			for k := edges[b]; k < edges[b+1]; k++ {
+				mag := cmplx.Abs(buf[k])
+				energies[b] += mag * mag
+			}
+		}
+
+		if previous != nil {
+			var hash uint32
+			for b := 0; b < fingerprintBands-1; b++ {
+				if (energies[b]-energies[b+1])-(previous[b]-previous[b+1]) > 0 {
+					hash |= 1 << uint(b)
+				}
+			}
+			f.Hashes = append(f.Hashes, hash)
+		}
+		previous = energies
+	}
+	return f
+}
+
+// Similarity compares two fingerprints at the best alignment within maxShift frames of each other
+// (negative for any alignment) and returns 1 minus the bit error rate. Unrelated audio scores
+// about 0.5. At least 80% of the shorter fingerprint must overlap.
+func Similarity(a, b *Fingerprint, maxShift int) float64 {
+	if a == nil || b == nil || len(a.Hashes) == 0 || len(b.Hashes) == 0 {
+		return 0
+	}
+
+	shorter := len(a.Hashes)
+	if len(b.Hashes) < shorter {
+		shorter = len(b.Hashes)
+	}
+	minOverlap := int(math.Ceil(0.8 * float64(shorter)))
+
+	// shift is the index in b aligned with the start of a
+	lo, hi := -(len(a.Hashes) - minOverlap), len(b.Hashes)-minOverlap
+	if maxShift >= 0 {
+		if lo < -maxShift {
+			lo = -maxShift
+		}
+		if hi > maxShift {
+			hi = maxShift
+		}
+	}
+
+	best := 0.0
+	for shift := lo; shift <= hi; shift++ {
+		ai, bi := 0, shift
+		if shift < 0 {
+			ai, bi = -shift, 0
+		}
+		overlap := len(a.Hashes) - ai
+		if rest := len(b.Hashes) - bi; rest < overlap {
+			overlap = rest
+		}
+		if overlap < minOverlap || overlap <= 0 {
+			continue
+		}
+
+		diff := 0
+		for i := 0; i < overlap; i++ {
+			diff += bits.OnesCount32(a.Hashes[ai+i] ^ b.Hashes[bi+i])
+		}
+		if sim := 1 - float64(diff)/float64(32*overlap); sim > best {
+			best = sim
+		}
+	}
+	return best
+}
+
+// FingerprintFilter selects stored recording fingerprints
+type FingerprintFilter struct {
+	TenantID      string
+	Since         time.Time
+	MinDurationMs int64
+	MaxDurationMs int64
+}
+
+// Matches reports whether a fingerprint satisfies the filter
+func (f FingerprintFilter) Matches(fp *models.RecordingFingerprint) bool {
+	if f.TenantID != "" && fp.TenantID != f.TenantID {
+		return false
+	}
+	if !f.Since.IsZero() && fp.CreatedAt.Before(f.Since) {
+		return false
+	}
+	if f.MinDurationMs > 0 && fp.DurationMs < f.MinDurationMs {
+		return false
+	}
+	if f.MaxDurationMs > 0 && fp.DurationMs > f.MaxDurationMs {
+		return false
+	}
+	return true
+}
+
+// FingerprintStore persists recording fingerprints and the global robocall list
+type FingerprintStore interface {
+	SaveRecordingFingerprint(ctx context.Context, fp *models.RecordingFingerprint) error
+	GetRecordingFingerprint(ctx context.Context, recordingID string) (*models.RecordingFingerprint, error)
+	ListRecordingFingerprints(ctx context.Context, filter FingerprintFilter) ([]*models.RecordingFingerprint, error)
+	SaveRobocallFingerprint(ctx context.Context, fp *models.RobocallFingerprint) error
+	ListRobocallFingerprints(ctx context.Context) ([]*models.RobocallFingerprint, error)
+}
+
+// MemoryFingerprintStore keeps fingerprints in memory
+type MemoryFingerprintStore struct {
+	mu         sync.RWMutex
+	recordings map[string]models.RecordingFingerprint
+	robocalls  map[string]models.RobocallFingerprint
+}
+
+// NewMemoryFingerprintStore creates an empty in-memory fingerprint store
+func NewMemoryFingerprintStore() *MemoryFingerprintStore {
+	return &MemoryFingerprintStore{
+		recordings: make(map[string]models.RecordingFingerprint),
+		robocalls:  make(map[string]models.RobocallFingerprint),
+	}
+}
+
+// SaveRecordingFingerprint stores a recording fingerprint
+func (s *MemoryFingerprintStore) SaveRecordingFingerprint(ctx context.Context, fp *models.RecordingFingerprint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recordings[fp.RecordingID] = *fp
+	return nil
+}
+
+// GetRecordingFingerprint returns the fingerprint of a recording
+func (s *MemoryFingerprintStore) GetRecordingFingerprint(ctx context.Context, recordingID string) (*models.RecordingFingerprint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fp, ok := s.recordings[recordingID]
+	if !ok {
+		return nil, ErrFingerprintNotFound
+	}
+	return &fp, nil
+}
+
+// ListRecordingFingerprints returns the fingerprints matching a filter
+func (s *MemoryFingerprintStore) ListRecordingFingerprints(ctx context.Context, filter FingerprintFilter) ([]*models.RecordingFingerprint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var fps []*models.RecordingFingerprint
+	for _, fp := range s.recordings {
+		if filter.Matches(&fp) {
+			fp := fp
+			fps = append(fps, &fp)
+		}
+	}
+	return fps, nil
+}
+
+// SaveRobocallFingerprint adds a fingerprint to the robocall list
+func (s *MemoryFingerprintStore) SaveRobocallFingerprint(ctx context.Context, fp *models.RobocallFingerprint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.robocalls[fp.FingerprintID] = *fp
+	return nil
+}
+
+// ListRobocallFingerprints returns every known robocall fingerprint
+func (s *MemoryFingerprintStore) ListRobocallFingerprints(ctx context.Context) ([]*models.RobocallFingerprint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fps := make([]*models.RobocallFingerprint, 0, len(s.robocalls))
+	for _, fp := range s.robocalls {
+		fp := fp
+		fps = append(fps, &fp)
+	}
+	return fps, nil
+}
+
+// FingerprintMatcher fingerprints recordings and checks them against earlier tenant recordings
+// and the global robocall list
+type FingerprintMatcher struct {
+	store  FingerprintStore
+	config *FingerprintConfig
+}
+
+// NewFingerprintMatcher creates a matcher; a nil store keeps fingerprints in memory
+func NewFingerprintMatcher(store FingerprintStore, config *FingerprintConfig) *FingerprintMatcher {
+	if store == nil {
+		store = NewMemoryFingerprintStore()
+	}
+	if config == nil {
+		config = DefaultFingerprintConfig()
+	}
+	return &FingerprintMatcher{store: store, config: config}
+}
+
+// Fingerprint computes the stored fingerprint of a recording, or nil if it is too short
+func (m *FingerprintMatcher) Fingerprint(pcm *PCMAudio, tenantID, recordingID, callID string) *models.RecordingFingerprint {
+	fp := ComputeFingerprint(pcm, m.config)
+	if fp.Duration() < m.config.MinDuration {
+		return nil
+	}
+	return &models.RecordingFingerprint{
+		RecordingID: recordingID,
+		TenantID:    tenantID,
+		CallID:      callID,
+		Digest:      fp.Digest(),
+		Fingerprint: fp.Encode(),
+		HopMs:       fp.Hop.Milliseconds(),
+		DurationMs:  fp.Duration().Milliseconds(),
+		CreatedAt:   time.Now().UTC(),
+	}
+}
+
+// Check compares a recording fingerprint with the tenant's recent recordings and known robocalls
+func (m *FingerprintMatcher) Check(ctx context.Context, rec *models.RecordingFingerprint) (*models.FingerprintMatch, error) {
+	fp, err := DecodeFingerprint(rec.Fingerprint, time.Duration(rec.HopMs)*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	match := &models.FingerprintMatch{}
+
+	tolerance := int64(float64(rec.DurationMs) * m.config.DurationTolerance)
+	candidates, err := m.store.ListRecordingFingerprints(ctx, FingerprintFilter{
+		TenantID:      rec.TenantID,
+		Since:         time.Now().Add(-m.config.DuplicateWindow),
+		MinDurationMs: rec.DurationMs - tolerance,
+		MaxDurationMs: rec.DurationMs + tolerance,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant fingerprints: %w", err)
+	}
+
+	maxShift := int(m.config.MaxAlignShift / fp.Hop)
+	for _, candidate := range candidates {
+		if candidate.RecordingID == rec.RecordingID {
+			continue
+		}
+		similarity := 1.0
+		exact := candidate.Digest == rec.Digest
+		if !exact {
+			other, err := DecodeFingerprint(candidate.Fingerprint, time.Duration(candidate.HopMs)*time.Millisecond)
+			if err != nil {
+				continue
+			}
+			similarity = Similarity(fp, other, maxShift)
+		}
+		if similarity >= m.config.DuplicateSimilarity && similarity > match.DuplicateSimilarity {
+			match.Duplicate = true
+			match.Exact = exact
+			match.DuplicateOf = candidate.RecordingID
+			match.DuplicateCallID = candidate.CallID
+			match.DuplicateSimilarity = similarity
+		}
+	}
+
+	robocalls, err := m.store.ListRobocallFingerprints(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list robocall fingerprints: %w", err)
+	}
+	for _, robocall := range robocalls {
+		known, err := DecodeFingerprint(robocall.Fingerprint, time.Duration(robocall.HopMs)*time.Millisecond)
+		if err != nil {
+			continue
+		}
+		// The message may start anywhere in the recording
+		similarity := Similarity(fp, known, -1)
+		if robocall.Digest == rec.Digest {
+			similarity = 1
+		}
+		if similarity >= m.config.RobocallSimilarity && similarity > match.RobocallSimilarity {
+			match.Robocall = true
+			match.RobocallID = robocall.FingerprintID
+			match.RobocallLabel = robocall.Label
+			match.RobocallSimilarity = similarity
+		}
+	}
+
+	if match.Robocall {
+		// Scale from 90 at the threshold to 100 for an identical recording
+		span := 1 - m.config.RobocallSimilarity
+		match.SpamLikelihood = 90
+		if span > 0 {
+			match.SpamLikelihood += 10 * (match.RobocallSimilarity - m.config.RobocallSimilarity) / span
+		}
+	}
+
+	return match, nil
+}
+
+// Register stores a recording fingerprint for future duplicate checks
+func (m *FingerprintMatcher) Register(ctx context.Context, rec *models.RecordingFingerprint) error {
+	if err := m.store.SaveRecordingFingerprint(ctx, rec); err != nil {
+		return fmt.Errorf("failed to save recording fingerprint: %w", err)
+	}
+	return nil
+}
+
+// MarkRobocall adds the fingerprint of one of a tenant's recordings to the global robocall list.
+// Recordings of other tenants are reported as ErrFingerprintNotFound.
+func (m *FingerprintMatcher) MarkRobocall(ctx context.Context, tenantID, recordingID, label string) (*models.RobocallFingerprint, error) {
+	rec, err := m.store.GetRecordingFingerprint(ctx, recordingID)
+	if err != nil {
+		return nil, err
+	}
+	if rec.TenantID != tenantID {
+		return nil, ErrFingerprintNotFound
+	}
+
+	robocall := &models.RobocallFingerprint{
+		FingerprintID:     models.NewProcessingID(),
+		Label:             label,
+		Digest:            rec.Digest,
+		Fingerprint:       rec.Fingerprint,
+		HopMs:             rec.HopMs,
+		DurationMs:        rec.DurationMs,
+		SourceTenantID:    rec.TenantID,
+		SourceRecordingID: rec.RecordingID,
+		CreatedAt:         time.Now().UTC(),
+	}
+	if err := m.store.SaveRobocallFingerprint(ctx, robocall); err != nil {
+		return nil, fmt.Errorf("failed to save robocall fingerprint: %w", err)
+	}
+	return robocall, nil
+}
+
+// bandEdges returns FFT bin boundaries for log-spaced bands between minFreq and maxFreq
+func bandEdges(size, sampleRate int, minFreq, maxFreq float64) []int {
+	nyquist := float64(sampleRate) / 2
+	if maxFreq > nyquist {
+		maxFreq = nyquist
+	}
+	if minFreq <= 0 || minFreq >= maxFreq {
+		minFreq = maxFreq / 10
+	}
+
+	edges := make([]int, fingerprintBands+1)
+	ratio := math.Pow(maxFreq/minFreq, 1/float64(fingerprintBands))
+	for i := range edges {
+		freq := minFreq * math.Pow(ratio, float64(i))
+		edges[i] = int(freq * float64(size) / float64(sampleRate))
+		if i > 0 && edges[i] <= edges[i-1] {
+			edges[i] = edges[i-1] + 1
+		}
+	}
+	if last := size / 2; edges[fingerprintBands] > last {
+		edges[fingerprintBands] = last
+	}
+	return edges
+}
+
+// hannWindow returns a Hann window of the given size
+func hannWindow(size int) []float64 {
+	window := make([]float64, size)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(size-1))
+	}
+	return window
+}
+
+// fft computes an in-place radix-2 FFT; len(x) must be a power of two
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for length := 2; length <= n; length <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(length)))
+		for start := 0; start < n; start += length {
+			w := complex(1, 0)
+			for k := 0; k < length/2; k++ {
+				u, v := x[start+k], x[start+k+length/2]*w
+				x[start+k], x[start+k+length/2] = u+v, u-v
+				w *= step
+			}
+		}
+	}
+}
@@ -0,0 +1,782 @@
+package audio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	speech "cloud.google.com/go/speech/apiv1"
+	"cloud.google.com/go/speech/apiv1/speechpb"
+	"github.com/google/uuid"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+	"github.com/home-renovators/ingestion-pipeline/pkg/scheduler"
+)
+
+// Transcription job statuses
+const (
+	JobStatusPending    = "pending"
+	JobStatusProcessing = "processing"
+	JobStatusCompleted  = "completed"
+	JobStatusFailed     = "failed"
+)
+
+// ErrJobNotFound is returned when a transcription job does not exist
+var ErrJobNotFound = errors.New("transcription job not found")
+
+// ErrJobClaimLost is returned when this instance's claim on a job lapsed while running it, so
+// another instance may have taken the job over
+var ErrJobClaimLost = errors.New("transcription job claim was lost")
+
+// TranscriptionStatus represents the status of a transcription job
+type TranscriptionStatus struct {
+	JobID         string                 `json:"job_id"`
+	Status        string                 `json:"status"`   // pending, processing, completed, failed
+	Progress      float64                `json:"progress"` // 0.0 to 1.0
+	OperationName string                 `json:"operation_name,omitempty"`
+	StartTime     time.Time              `json:"start_time"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+	EndTime       *time.Time             `json:"end_time,omitempty"`
+	Error         string                 `json:"error,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata"`
+}
+
+// TranscriptionJob represents a transcription job for tracking. Jobs hold what is needed to resume
+// them and, once the caller has redacted it, the call's result so a redelivered call doesn't pay
+// for it again. Stores seal the result when encryption is configured.
+type TranscriptionJob struct {
+	JobID   string                      `json:"job_id"`
+	Request *TranscriptionRequest       `json:"request"`
+	Status  *TranscriptionStatus        `json:"status"`
+	Result  *models.TranscriptionResult `json:"result,omitempty"`
+}
+
+// Done reports whether the job has reached a terminal status
+func (j *TranscriptionJob) Done() bool {
+	return j.Status.Status == JobStatusCompleted || j.Status.Status == JobStatusFailed
+}
+
+// JobFilter narrows a job listing; empty fields match everything
+type JobFilter struct {
+	TenantID    string
+	CallID      string
+	Status      string
+	EndedBefore time.Time
+}
+
+// Matches reports whether a job satisfies the filter
+func (f JobFilter) Matches(job *TranscriptionJob) bool {
+	if f.TenantID != "" && (job.Request == nil || job.Request.TenantID != f.TenantID) {
+		return false
+	}
+	if f.CallID != "" && (job.Request == nil || job.Request.CallID != f.CallID) {
+		return false
+	}
+	if f.Status != "" && job.Status.Status != f.Status {
+		return false
+	}
+	if !f.EndedBefore.IsZero() && (job.Status.EndTime == nil || !job.Status.EndTime.Before(f.EndedBefore)) {
+		return false
+	}
+	return true
+}
+
+// JobStore persists transcription jobs so they survive restarts. Claims are kept apart from the
+// job itself, so saving a job never changes who holds it.
+type JobStore interface {
+	SaveTranscriptionJob(ctx context.Context, job *TranscriptionJob) error
+	GetTranscriptionJob(ctx context.Context, jobID string) (*TranscriptionJob, error)
+	ListTranscriptionJobs(ctx context.Context, filter JobFilter) ([]*TranscriptionJob, error)
+	DeleteTranscriptionJob(ctx context.Context, jobID string) error
+
+	// ClaimTranscriptionJob claims a job for owner until the given time, renewing owner's own
+	// claim. It reports false when another owner's claim has not expired.
+	ClaimTranscriptionJob(ctx context.Context, jobID, owner string, until time.Time) (bool, error)
+	// ReleaseTranscriptionJob ends owner's claim on a job; other owners' claims are left alone
+	ReleaseTranscriptionJob(ctx context.Context, jobID, owner string) error
+}
+
+// jobClaim is an instance's hold on a job
+type jobClaim struct {
+	owner string
+	until time.Time
+}
+
+// MemoryJobStore is an in-process JobStore for tests and single-instance deployments
+type MemoryJobStore struct {
+	mu     sync.RWMutex
+	jobs   map[string]*TranscriptionJob
+	claims map[string]jobClaim
+}
+
+// NewMemoryJobStore creates a new in-memory job store
+func NewMemoryJobStore() *MemoryJobStore {
+	return &MemoryJobStore{
+		jobs:   make(map[string]*TranscriptionJob),
+		claims: make(map[string]jobClaim),
+	}
+}
+
+// SaveTranscriptionJob inserts or replaces a job
+func (s *MemoryJobStore) SaveTranscriptionJob(ctx context.Context, job *TranscriptionJob) error {
+	stored, err := cloneJob(job)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.JobID] = stored
+	return nil
+}
+
+// GetTranscriptionJob retrieves a job by ID
+func (s *MemoryJobStore) GetTranscriptionJob(ctx context.Context, jobID string) (*TranscriptionJob, error) {
+	s.mu.RLock()
+	job, exists := s.jobs[jobID]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, ErrJobNotFound
+	}
+	return cloneJob(job)
+}
+
+// ListTranscriptionJobs returns the jobs matching the filter, oldest first
+func (s *MemoryJobStore) ListTranscriptionJobs(ctx context.Context, filter JobFilter) ([]*TranscriptionJob, error) {
+	s.mu.RLock()
+	var matched []*TranscriptionJob
+	for _, job := range s.jobs {
+		if filter.Matches(job) {
+			matched = append(matched, job)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].Status.StartTime.Before(matched[j].Status.StartTime)
+	})
+
+	jobs := make([]*TranscriptionJob, 0, len(matched))
+	for _, job := range matched {
+		clone, err := cloneJob(job)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, clone)
+	}
+	return jobs, nil
+}
+
+// DeleteTranscriptionJob removes a job
+func (s *MemoryJobStore) DeleteTranscriptionJob(ctx context.Context, jobID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, jobID)
+	delete(s.claims, jobID)
+	return nil
+}
+
+// ClaimTranscriptionJob claims a job for owner unless another owner holds an unexpired claim
+func (s *MemoryJobStore) ClaimTranscriptionJob(ctx context.Context, jobID, owner string, until time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[jobID]; !exists {
+		return false, ErrJobNotFound
+	}
+	if claim, held := s.claims[jobID]; held && claim.owner != owner && claim.until.After(time.Now()) {
+		return false, nil
+	}
+	s.claims[jobID] = jobClaim{owner: owner, until: until}
+	return true, nil
+}
+
+// ReleaseTranscriptionJob ends owner's claim on a job
+func (s *MemoryJobStore) ReleaseTranscriptionJob(ctx context.Context, jobID, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if claim, held := s.claims[jobID]; held && claim.owner == owner {
+		delete(s.claims, jobID)
+	}
+	return nil
+}
+
+// cloneJob deep-copies a job so stored state is never shared with callers
+func cloneJob(job *TranscriptionJob) (*TranscriptionJob, error) {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal transcription job: %w", err)
+	}
+
+	var clone TranscriptionJob
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transcription job: %w", err)
+	}
+	return &clone, nil
+}
+
+// JobManager manages transcription jobs on top of a JobStore. It is safe for concurrent use.
+// Each manager is one owner of job claims, so instances sharing a store never run the same job.
+type JobManager struct {
+	store        JobStore
+	owner        string
+	pollInterval time.Duration
+	claimLease   time.Duration // how long a claim outlives an instance that stops renewing it
+
+	mu sync.Mutex
+}
+
+// NewJobManager creates a new job manager; a nil store keeps jobs in memory
+func NewJobManager(store JobStore) *JobManager {
+	if store == nil {
+		store = NewMemoryJobStore()
+	}
+	return &JobManager{
+		store:        store,
+		owner:        uuid.New().String(),
+		pollInterval: 5 * time.Second,
+		claimLease:   time.Minute,
+	}
+}
+
+// WithPollInterval sets how often operations, and jobs held by other instances, are checked
+func (jm *JobManager) WithPollInterval(interval time.Duration) *JobManager {
+	jm.pollInterval = interval
+	return jm
+}
+
+// WithClaimLease sets how long a claim outlives an instance that stops renewing it, which is also
+// how long a completed job waits for its result to be stored
+func (jm *JobManager) WithClaimLease(lease time.Duration) *JobManager {
+	jm.claimLease = lease
+	return jm
+}
+
+// CreateJob creates a new transcription job
+func (jm *JobManager) CreateJob(ctx context.Context, request *TranscriptionRequest) (*TranscriptionJob, error) {
+	jobID := "transcription_" + uuid.New().String()
+	now := time.Now().UTC()
+
+	job := &TranscriptionJob{
+		JobID:   jobID,
+		Request: request,
+		Status: &TranscriptionStatus{
+			JobID:     jobID,
+			Status:    JobStatusPending,
+			Progress:  0.0,
+			StartTime: now,
+			UpdatedAt: now,
+			Metadata:  make(map[string]interface{}),
+		},
+	}
+
+	if err := jm.store.SaveTranscriptionJob(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to save transcription job: %w", err)
+	}
+	return job, nil
+}
+
+// update applies fn to the stored job under the manager lock and saves the result
+func (jm *JobManager) update(ctx context.Context, jobID string, fn func(job *TranscriptionJob)) (*TranscriptionJob, error) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	job, err := jm.store.GetTranscriptionJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	fn(job)
+	job.Status.UpdatedAt = time.Now().UTC()
+
+	if err := jm.store.SaveTranscriptionJob(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to save transcription job: %w", err)
+	}
+	return job, nil
+}
+
+// SetOperation records the Speech-to-Text operation backing a job and marks it processing
+func (jm *JobManager) SetOperation(ctx context.Context, jobID, operationName string) error {
+	_, err := jm.update(ctx, jobID, func(job *TranscriptionJob) {
+		job.Status.OperationName = operationName
+		job.Status.Status = JobStatusProcessing
+	})
+	return err
+}
+
+// UpdateJobStatus updates the status of a transcription job
+func (jm *JobManager) UpdateJobStatus(ctx context.Context, jobID string, status string, progress float64, errMsg string) error {
+	_, err := jm.update(ctx, jobID, func(job *TranscriptionJob) {
+		job.Status.Status = status
+		job.Status.Progress = progress
+		if errMsg != "" {
+			job.Status.Error = errMsg
+		}
+		if status == JobStatusCompleted || status == JobStatusFailed {
+			now := time.Now().UTC()
+			job.Status.EndTime = &now
+		}
+	})
+	return err
+}
+
+// CompleteJob marks a job as completed, recording the size and language of the result but not
+// its content. The caller stores the content with StoreResult once it has been redacted.
+func (jm *JobManager) CompleteJob(ctx context.Context, jobID string, response *TranscriptionResponse) error {
+	_, err := jm.update(ctx, jobID, func(job *TranscriptionJob) {
+		job.Status.Status = JobStatusCompleted
+		job.Status.Progress = 1.0
+		if response.Result != nil {
+			if job.Status.Metadata == nil {
+				job.Status.Metadata = make(map[string]interface{})
+			}
+			job.Status.Metadata["word_count"] = len(response.Result.WordDetails)
+			job.Status.Metadata["language"] = response.Result.Language
+		}
+		now := time.Now().UTC()
+		job.Status.EndTime = &now
+	})
+	return err
+}
+
+// StoreResult stores a call's final, redacted result on each of its completed jobs, so a
+// redelivery of the call gets it back from whichever quality attempt it looks up
+func (jm *JobManager) StoreResult(ctx context.Context, tenantID, callID string, result *models.TranscriptionResult) error {
+	jobs, err := jm.store.ListTranscriptionJobs(ctx, JobFilter{TenantID: tenantID, CallID: callID, Status: JobStatusCompleted})
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if _, err := jm.update(ctx, job.JobID, func(job *TranscriptionJob) { job.Result = result }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storingResult reports whether a completed job is still waiting for its result. The instance
+// that completed it has a claim lease's time to redact and store the result before the job is
+// given up on.
+func (jm *JobManager) storingResult(job *TranscriptionJob) bool {
+	return job.Status.Status == JobStatusCompleted && job.Result == nil && time.Since(job.Status.UpdatedAt) < jm.claimLease
+}
+
+// GetJob retrieves a transcription job by ID
+func (jm *JobManager) GetJob(ctx context.Context, jobID string) (*TranscriptionJob, error) {
+	return jm.store.GetTranscriptionJob(ctx, jobID)
+}
+
+// GetJobStatus retrieves just the status of a transcription job
+func (jm *JobManager) GetJobStatus(ctx context.Context, jobID string) (*TranscriptionStatus, error) {
+	job, err := jm.store.GetTranscriptionJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return job.Status, nil
+}
+
+// ListJobs returns all jobs (optionally filtered by status)
+func (jm *JobManager) ListJobs(ctx context.Context, statusFilter string) ([]*TranscriptionJob, error) {
+	return jm.store.ListTranscriptionJobs(ctx, JobFilter{Status: statusFilter})
+}
+
+// FindLatestJob returns the most recently started job for a call
+func (jm *JobManager) FindLatestJob(ctx context.Context, tenantID, callID string) (*TranscriptionJob, error) {
+	jobs, err := jm.store.ListTranscriptionJobs(ctx, JobFilter{TenantID: tenantID, CallID: callID})
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, ErrJobNotFound
+	}
+	return jobs[len(jobs)-1], nil
+}
+
+// latestAttempt returns the most recently started job for the request's call made with the same
+// quality attempt, so a retry with an alternate model is never answered with the primary's job
+func (jm *JobManager) latestAttempt(ctx context.Context, req *TranscriptionRequest) (*TranscriptionJob, error) {
+	jobs, err := jm.store.ListTranscriptionJobs(ctx, JobFilter{TenantID: req.TenantID, CallID: req.CallID})
+	if err != nil {
+		return nil, err
+	}
+	for i := len(jobs) - 1; i >= 0; i-- {
+		if jobs[i].Request != nil && jobs[i].Request.Metadata[qualityAttemptMetadataKey] == req.Metadata[qualityAttemptMetadataKey] {
+			return jobs[i], nil
+		}
+	}
+	return nil, ErrJobNotFound
+}
+
+// ResumableJobs returns processing jobs whose Speech operation can be polled again
+func (jm *JobManager) ResumableJobs(ctx context.Context) ([]*TranscriptionJob, error) {
+	jobs, err := jm.store.ListTranscriptionJobs(ctx, JobFilter{Status: JobStatusProcessing})
+	if err != nil {
+		return nil, err
+	}
+
+	var resumable []*TranscriptionJob
+	for _, job := range jobs {
+		if job.Status.OperationName != "" {
+			resumable = append(resumable, job)
+		}
+	}
+	return resumable, nil
+}
+
+// CleanupCompletedJobs removes completed/failed jobs older than the specified duration
+func (jm *JobManager) CleanupCompletedJobs(ctx context.Context, olderThan time.Duration) (int, error) {
+	cutoff := time.Now().Add(-olderThan)
+	cleaned := 0
+
+	for _, status := range []string{JobStatusCompleted, JobStatusFailed} {
+		jobs, err := jm.store.ListTranscriptionJobs(ctx, JobFilter{Status: status, EndedBefore: cutoff})
+		if err != nil {
+			return cleaned, err
+		}
+		for _, job := range jobs {
+			if err := jm.store.DeleteTranscriptionJob(ctx, job.JobID); err != nil {
+				return cleaned, fmt.Errorf("failed to delete transcription job %s: %w", job.JobID, err)
+			}
+			cleaned++
+		}
+	}
+
+	return cleaned, nil
+}
+
+// hold claims a job for this manager through the store and keeps renewing the claim until the
+// returned release is called. It reports false when another instance holds the job. The returned
+// context is cancelled with ErrJobClaimLost once a renewal finds another instance holding the job,
+// or renewals have failed for a whole lease, so work under it stops before the job runs twice.
+func (jm *JobManager) hold(ctx context.Context, jobID string) (context.Context, func(), bool, error) {
+	held, err := jm.store.ClaimTranscriptionJob(ctx, jobID, jm.owner, time.Now().Add(jm.claimLease))
+	if err != nil || !held {
+		return nil, nil, held, err
+	}
+
+	heldCtx, cancel := context.WithCancelCause(ctx)
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(jm.claimLease / 3)
+		defer ticker.Stop()
+		renewed := time.Now()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-heldCtx.Done():
+				return
+			case <-ticker.C:
+				held, err := jm.store.ClaimTranscriptionJob(heldCtx, jobID, jm.owner, time.Now().Add(jm.claimLease))
+				switch {
+				case err == nil && held:
+					renewed = time.Now()
+				case err == nil, time.Since(renewed) >= jm.claimLease:
+					cancel(ErrJobClaimLost)
+					return
+				}
+				// A failed renewal is retried on the next tick, well before the lease runs out
+			}
+		}
+	}()
+
+	var once sync.Once
+	return heldCtx, func() {
+		once.Do(func() {
+			close(stop)
+			<-stopped
+			cancel(nil)
+			// Released even when ctx is cancelled, so another instance can pick the job up at once
+			jm.store.ReleaseTranscriptionJob(context.WithoutCancel(ctx), jobID, jm.owner)
+		})
+	}, true, nil
+}
+
+// claimError reports a job stopped because its claim was lost as ErrJobClaimLost, and returns
+// other errors as they are
+func claimError(heldCtx context.Context, jobID string, err error) error {
+	if err != nil && errors.Is(context.Cause(heldCtx), ErrJobClaimLost) {
+		return fmt.Errorf("transcription job %s: %w", jobID, ErrJobClaimLost)
+	}
+	return err
+}
+
+// TranscribeWithJob transcribes audio under a tracked job. A call whose job already completed gets
+// the stored result back; one whose job is still running, here or on another instance, has that
+// job finished rather than starting another. A job that completed without a result ever being
+// stored is redone in a new job.
+func (ts *TranscriptionService) TranscribeWithJob(ctx context.Context, jm *JobManager, req *TranscriptionRequest) (*TranscriptionResponse, error) {
+	if req.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, req.Timeout)
+		defer cancel()
+	}
+
+	existing, err := jm.latestAttempt(ctx, req)
+	if err != nil && !errors.Is(err, ErrJobNotFound) {
+		return nil, fmt.Errorf("failed to look up transcription job: %w", err)
+	}
+	if existing != nil {
+		switch {
+		case existing.Status.Status == JobStatusCompleted && existing.Result != nil:
+			return completedResponse(existing, req), nil
+		case !existing.Done(), jm.storingResult(existing):
+			return ts.takeOverJob(ctx, jm, existing, req)
+		}
+	}
+
+	job, err := jm.CreateJob(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return ts.takeOverJob(ctx, jm, job, req)
+}
+
+// takeOverJob runs a job once this instance holds its claim. While another instance holds it, the
+// job is checked every poll interval until it finishes, or until its claim lapses and it can be
+// taken over. The claim is only kept while the job runs.
+func (ts *TranscriptionService) takeOverJob(ctx context.Context, jm *JobManager, job *TranscriptionJob, req *TranscriptionRequest) (*TranscriptionResponse, error) {
+	for {
+		heldCtx, release, held, err := jm.hold(ctx, job.JobID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim transcription job: %w", err)
+		}
+
+		// Re-read under the claim: the instance that held the job may have just finished it
+		current, err := jm.GetJob(ctx, job.JobID)
+		if err == nil && held && !current.Done() {
+			defer release()
+			// The caller's request carries the audio the stored one leaves out
+			current.Request = req
+			response, err := ts.runJob(heldCtx, jm, current, req)
+			return response, claimError(heldCtx, current.JobID, err)
+		}
+		if held {
+			release()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read transcription job: %w", err)
+		}
+
+		switch {
+		case current.Status.Status == JobStatusCompleted && current.Result != nil:
+			return completedResponse(current, req), nil
+		case jm.storingResult(current):
+			// The instance that ran the job is still redacting its result; checked again below
+		case current.Status.Status == JobStatusCompleted:
+			// Its result was never stored, so the recording is transcribed again
+			if job, err = jm.CreateJob(ctx, req); err != nil {
+				return nil, err
+			}
+			continue
+		case current.Done():
+			return nil, fmt.Errorf("transcription job %s for call %s finished without a result: %s", current.JobID, req.CallID, current.Status.Error)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(jm.pollInterval):
+		}
+	}
+}
+
+// runJob runs a job this instance holds. A job with a Speech-to-Text operation is polled again
+// instead of re-submitting; otherwise the recording is transcribed, in chunks when it is long.
+func (ts *TranscriptionService) runJob(ctx context.Context, jm *JobManager, job *TranscriptionJob, req *TranscriptionRequest) (*TranscriptionResponse, error) {
+	if job.Status.OperationName != "" {
+		return ts.pollJob(ctx, jm, job, ts.speechClient.LongRunningRecognizeOperation(job.Status.OperationName))
+	}
+
+	// Long recordings are split into chunks rather than waiting on one operation that may time out.
+	// The chunks take scheduler slots themselves, so the job doesn't hold one.
+	config := ts.configFor(req)
+	if chunked(req, config) {
+		return ts.transcribeChunkedJob(ctx, jm, job, req)
+	}
+	if err := checkAudioSource(req); err != nil {
+		jm.UpdateJobStatus(ctx, job.JobID, JobStatusFailed, 0, err.Error())
+		return nil, err
+	}
+
+	// Only new submissions compete for a slot; resumed operations are already running
+	release, err := ts.scheduler.Acquire(ctx, req.TenantID, scheduler.Priority(req.Priority))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	operation, err := ts.speechClient.LongRunningRecognize(ctx, &speechpb.LongRunningRecognizeRequest{
+		Config: ts.buildRecognitionConfig(config),
+		Audio:  recognitionAudio(req),
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			jm.UpdateJobStatus(ctx, job.JobID, JobStatusFailed, 0, err.Error())
+		}
+		return nil, fmt.Errorf("failed to start transcription: %w", err)
+	}
+
+	if err := jm.SetOperation(ctx, job.JobID, operation.Name()); err != nil {
+		return nil, fmt.Errorf("failed to record transcription operation: %w", err)
+	}
+	job.Status.OperationName = operation.Name()
+
+	return ts.pollJob(ctx, jm, job, operation)
+}
+
+// completedResponse answers a request with the stored result of a job that already completed
+func completedResponse(job *TranscriptionJob, req *TranscriptionRequest) *TranscriptionResponse {
+	response := &TranscriptionResponse{
+		Request:   req,
+		Result:    job.Result,
+		Success:   true,
+		StartTime: job.Status.StartTime,
+		Metadata: map[string]interface{}{
+			"job_id": job.JobID,
+			"reused": true,
+		},
+	}
+	if job.Status.EndTime != nil {
+		response.EndTime = *job.Status.EndTime
+		response.Duration = response.EndTime.Sub(response.StartTime)
+	}
+	return response
+}
+
+// chunked reports whether a request's recording is transcribed in chunks: it is long, or too big
+// to send inline in one piece
+func chunked(req *TranscriptionRequest, config *TranscriptionConfig) bool {
+	if req.PCM == nil || config.Chunking == nil {
+		return false
+	}
+	if len(req.AudioContent) > MaxInlineAudioBytes {
+		return true
+	}
+	return config.Chunking.MinDuration > 0 && req.PCM.Duration() >= config.Chunking.MinDuration
+}
+
+// checkAudioSource rejects a request that can't be sent in one operation: an encrypted recording
+// without its decrypted audio, which would hand Speech-to-Text ciphertext, or inline audio over
+// the limit
+func checkAudioSource(req *TranscriptionRequest) error {
+	if req.Encrypted && len(req.AudioContent) == 0 {
+		return fmt.Errorf("encrypted recording of call %s was not loaded for transcription", req.CallID)
+	}
+	if len(req.AudioContent) > MaxInlineAudioBytes {
+		return fmt.Errorf("recording of call %s is %d bytes, over the %d byte inline limit, and could not be decoded for chunking", req.CallID, len(req.AudioContent), MaxInlineAudioBytes)
+	}
+	return nil
+}
+
+// transcribeChunkedJob transcribes a long recording in chunks under a tracked job. Chunks have no
+// single operation to poll, so a job interrupted by a restart is redone when the call is redelivered.
+func (ts *TranscriptionService) transcribeChunkedJob(ctx context.Context, jm *JobManager, job *TranscriptionJob, req *TranscriptionRequest) (*TranscriptionResponse, error) {
+	response, err := ts.TranscribeChunked(ctx, req, req.PCM)
+	if err != nil {
+		if ctx.Err() == nil {
+			jm.UpdateJobStatus(ctx, job.JobID, JobStatusFailed, 0, err.Error())
+		}
+		return nil, err
+	}
+
+	response.Metadata["job_id"] = job.JobID
+	if err := jm.CompleteJob(ctx, job.JobID, response); err != nil {
+		return response, fmt.Errorf("failed to complete transcription job: %w", err)
+	}
+	return response, nil
+}
+
+// ResumeJobs resumes polling every processing job left behind by a previous instance.
+// onComplete is called from the polling goroutine once each job finishes.
+func (ts *TranscriptionService) ResumeJobs(ctx context.Context, jm *JobManager, onComplete func(job *TranscriptionJob, response *TranscriptionResponse, err error)) (int, error) {
+	jobs, err := jm.ResumableJobs(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list resumable transcription jobs: %w", err)
+	}
+
+	resumed := 0
+	for _, job := range jobs {
+		heldCtx, release, held, err := jm.hold(ctx, job.JobID)
+		if err != nil {
+			return resumed, fmt.Errorf("failed to claim transcription job %s: %w", job.JobID, err)
+		}
+		if !held {
+			continue
+		}
+		resumed++
+
+		go func(job *TranscriptionJob) {
+			defer release()
+			response, err := ts.pollJob(heldCtx, jm, job, ts.speechClient.LongRunningRecognizeOperation(job.Status.OperationName))
+			err = claimError(heldCtx, job.JobID, err)
+			if onComplete != nil {
+				onComplete(job, response, err)
+			}
+		}(job)
+	}
+
+	return resumed, nil
+}
+
+// pollJob polls a Speech-to-Text operation until it finishes, recording progress on the job
+func (ts *TranscriptionService) pollJob(ctx context.Context, jm *JobManager, job *TranscriptionJob, operation *speech.LongRunningRecognizeOperation) (*TranscriptionResponse, error) {
+	config := ts.config
+	if job.Request != nil {
+		config = ts.configFor(job.Request)
+	}
+
+	response := &TranscriptionResponse{
+		Request:   job.Request,
+		StartTime: job.Status.StartTime,
+		Metadata: map[string]interface{}{
+			"job_id":         job.JobID,
+			"operation_name": operation.Name(),
+		},
+	}
+
+	for {
+		speechResponse, err := operation.Poll(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				// Leave the job processing so the next instance can resume it
+				return nil, ctx.Err()
+			}
+			jm.UpdateJobStatus(ctx, job.JobID, JobStatusFailed, job.Status.Progress, err.Error())
+			return nil, fmt.Errorf("transcription failed: %w", err)
+		}
+
+		if operation.Done() {
+			response.Result = ts.processTranscriptionResults(speechResponse, config)
+			response.Success = true
+			response.EndTime = time.Now()
+			response.Duration = response.EndTime.Sub(response.StartTime)
+			response.Metadata["total_results"] = len(speechResponse.Results)
+
+			if err := jm.CompleteJob(ctx, job.JobID, response); err != nil {
+				return response, fmt.Errorf("failed to complete transcription job: %w", err)
+			}
+			return response, nil
+		}
+
+		if metadata, err := operation.Metadata(); err == nil && metadata != nil {
+			job.Status.Progress = float64(metadata.GetProgressPercent()) / 100
+			// Progress is best effort; a failed write is retried on the next poll
+			jm.UpdateJobStatus(ctx, job.JobID, JobStatusProcessing, job.Status.Progress, "")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(jm.pollInterval):
+		}
+	}
+}
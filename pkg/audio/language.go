@@ -0,0 +1,89 @@
+package audio
+
+import (
+	"strings"
+
+	"cloud.google.com/go/speech/apiv1/speechpb"
+)
+
+// MaxAlternativeLanguages is the number of alternative languages Speech-to-Text accepts per request
+const MaxAlternativeLanguages = 3
+
+// NormalizeLanguageCode canonicalises a BCP-47 code, e.g. "ES-us" becomes "es-US"
+func NormalizeLanguageCode(code string) string {
+	parts := strings.Split(strings.TrimSpace(code), "-")
+	if len(parts) == 0 || parts[0] == "" {
+		return ""
+	}
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) == 2 {
+			parts[i] = strings.ToUpper(parts[i])
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// WithLanguages returns a copy of the config that recognizes the given languages, the first as the
+// primary language and up to MaxAlternativeLanguages more for automatic language identification.
+// The config is returned unchanged when no languages are given.
+func (c *TranscriptionConfig) WithLanguages(languages []string) *TranscriptionConfig {
+	var codes []string
+	seen := make(map[string]bool)
+	for _, lang := range languages {
+		code := NormalizeLanguageCode(lang)
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+	if len(codes) == 0 {
+		return c
+	}
+
+	config := *c
+	config.LanguageCode = codes[0]
+	config.AlternativeLanguageCodes = nil
+	for _, code := range codes[1:] {
+		if len(config.AlternativeLanguageCodes) == MaxAlternativeLanguages {
+			break
+		}
+		config.AlternativeLanguageCodes = append(config.AlternativeLanguageCodes, code)
+	}
+	return &config
+}
+
+// dominantLanguage returns the language most of the recognized words were in. Results without a
+// detected language count towards the fallback, which is normally the primary language.
+func dominantLanguage(results []*speechpb.SpeechRecognitionResult, fallback string) string {
+	counts := make(map[string]int)
+	var order []string
+	for _, res := range results {
+		if len(res.Alternatives) == 0 {
+			continue
+		}
+		lang := NormalizeLanguageCode(res.LanguageCode)
+		if lang == "" {
+			lang = NormalizeLanguageCode(fallback)
+		}
+
+		words := len(res.Alternatives[0].Words)
+		if words == 0 {
+			words = len(strings.Fields(res.Alternatives[0].Transcript))
+		}
+		if _, ok := counts[lang]; !ok {
+			order = append(order, lang)
+		}
+		counts[lang] += words
+	}
+
+	best := NormalizeLanguageCode(fallback)
+	bestCount := -1
+	for _, lang := range order {
+		if counts[lang] > bestCount {
+			best, bestCount = lang, counts[lang]
+		}
+	}
+	return best
+}
@@ -0,0 +1,209 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// PCMAudio holds mono 16-bit linear PCM samples decoded from a recording
+type PCMAudio struct {
+	Samples    []int16 `json:"-"`
+	SampleRate int     `json:"sample_rate"`
+}
+
+// Duration returns the playback duration of the audio
+func (p *PCMAudio) Duration() time.Duration {
+	if p == nil || p.SampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(len(p.Samples)) * time.Second / time.Duration(p.SampleRate)
+}
+
+// SampleAt converts a time offset into a sample index clamped to the audio bounds
+func (p *PCMAudio) SampleAt(offset time.Duration) int {
+	idx := int(offset.Seconds() * float64(p.SampleRate))
+	if idx < 0 {
+		return 0
+	}
+	if idx > len(p.Samples) {
+		return len(p.Samples)
+	}
+	return idx
+}
+
+// OffsetOf converts a sample index into a time offset
+func (p *PCMAudio) OffsetOf(sample int) time.Duration {
+	if p.SampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(sample) * time.Second / time.Duration(p.SampleRate)
+}
+
+// Slice returns the audio between two time offsets, sharing the underlying samples
+func (p *PCMAudio) Slice(start, end time.Duration) *PCMAudio {
+	return &PCMAudio{
+		Samples:    p.Samples[p.SampleAt(start):p.SampleAt(end)],
+		SampleRate: p.SampleRate,
+	}
+}
+
+// Resample returns the audio at a lower sample rate, averaging the samples each output sample
+// covers so higher frequencies don't alias. Audio already at or below the rate is returned as is.
+func (p *PCMAudio) Resample(rate int) *PCMAudio {
+	if rate <= 0 || p.SampleRate <= rate {
+		return p
+	}
+
+	samples := make([]int16, int(int64(len(p.Samples))*int64(rate)/int64(p.SampleRate)))
+	for i := range samples {
+		lo := int(int64(i) * int64(p.SampleRate) / int64(rate))
+		hi := int(int64(i+1) * int64(p.SampleRate) / int64(rate))
+		if hi > len(p.Samples) {
+			hi = len(p.Samples)
+		}
+		var sum int64
+		for _, s := range p.Samples[lo:hi] {
+			sum += int64(s)
+		}
+		samples[i] = int16(sum / int64(hi-lo))
+	}
+	return &PCMAudio{Samples: samples, SampleRate: rate}
+}
+
+// LinearBytes encodes the samples as little-endian LINEAR16 for the Speech API
+func (p *PCMAudio) LinearBytes() []byte {
+	buf := make([]byte, len(p.Samples)*2)
+	for i, s := range p.Samples {
+		binary.LittleEndian.PutUint16(buf[i*2:], uint16(s))
+	}
+	return buf
+}
+
+// FrameEnergies computes the RMS level in dBFS of consecutive frames of the given length
+func (p *PCMAudio) FrameEnergies(frame time.Duration) []float64 {
+	frameSize := p.SampleAt(frame)
+	if frameSize <= 0 {
+		return nil
+	}
+
+	energies := make([]float64, 0, len(p.Samples)/frameSize+1)
+	for start := 0; start < len(p.Samples); start += frameSize {
+		end := start + frameSize
+		if end > len(p.Samples) {
+			end = len(p.Samples)
+		}
+		energies = append(energies, rmsDBFS(p.Samples[start:end]))
+	}
+	return energies
+}
+
+// rmsDBFS returns the RMS level of samples relative to full scale
+func rmsDBFS(samples []int16) float64 {
+	if len(samples) == 0 {
+		return -120
+	}
+
+	var sum float64
+	for _, s := range samples {
+		v := float64(s) / 32768.0
+		sum += v * v
+	}
+	rms := math.Sqrt(sum / float64(len(samples)))
+	if rms <= 1e-6 {
+		return -120
+	}
+	return 20 * math.Log10(rms)
+}
+
+// DecodeWAV decodes a RIFF/WAVE file containing 16-bit PCM into mono samples
+func DecodeWAV(data []byte) (*PCMAudio, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a RIFF/WAVE file")
+	}
+
+	var (
+		channels      int
+		sampleRate    int
+		bitsPerSample int
+		pcmData       []byte
+	)
+
+	pos := 12
+	for pos+8 <= len(data) {
+		chunkID := string(data[pos : pos+4])
+		chunkSize := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		body := pos + 8
+		if body+chunkSize > len(data) {
+			chunkSize = len(data) - body
+		}
+
+		switch chunkID {
+		case "fmt ":
+			if chunkSize < 16 {
+				return nil, fmt.Errorf("invalid fmt chunk")
+			}
+			format := binary.LittleEndian.Uint16(data[body : body+2])
+			if format != 1 {
+				return nil, fmt.Errorf("unsupported WAV format %d (only PCM is supported)", format)
+			}
+			channels = int(binary.LittleEndian.Uint16(data[body+2 : body+4]))
+			sampleRate = int(binary.LittleEndian.Uint32(data[body+4 : body+8]))
+			bitsPerSample = int(binary.LittleEndian.Uint16(data[body+14 : body+16]))
+		case "data":
+			pcmData = data[body : body+chunkSize]
+		}
+
+		// Chunks are word aligned
+		pos = body + chunkSize + chunkSize%2
+	}
+
+	if sampleRate == 0 || channels == 0 {
+		return nil, fmt.Errorf("missing fmt chunk")
+	}
+	if bitsPerSample != 16 {
+		return nil, fmt.Errorf("unsupported bits per sample: %d", bitsPerSample)
+	}
+
+	frameBytes := 2 * channels
+	frames := len(pcmData) / frameBytes
+	samples := make([]int16, frames)
+	for i := 0; i < frames; i++ {
+		// Downmix to mono by averaging channels
+		var sum int
+		for ch := 0; ch < channels; ch++ {
+			off := i*frameBytes + ch*2
+			sum += int(int16(binary.LittleEndian.Uint16(pcmData[off : off+2])))
+		}
+		samples[i] = int16(sum / channels)
+	}
+
+	return &PCMAudio{Samples: samples, SampleRate: sampleRate}, nil
+}
+
+// EncodeWAV encodes mono PCM audio as a 16-bit RIFF/WAVE file
+func EncodeWAV(p *PCMAudio) []byte {
+	var buf bytes.Buffer
+	dataSize := uint32(len(p.Samples) * 2)
+
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, 36+dataSize)
+	buf.WriteString("WAVE")
+
+	buf.WriteString("fmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(1)) // PCM
+	binary.Write(&buf, binary.LittleEndian, uint16(1)) // mono
+	binary.Write(&buf, binary.LittleEndian, uint32(p.SampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(p.SampleRate*2))
+	binary.Write(&buf, binary.LittleEndian, uint16(2))
+	binary.Write(&buf, binary.LittleEndian, uint16(16))
+
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, dataSize)
+	buf.Write(p.LinearBytes())
+
+	return buf.Bytes()
+}
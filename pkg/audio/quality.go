@@ -0,0 +1,274 @@
+package audio
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+	"unicode"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// Transcript quality issues
+const (
+	QualityIssueEmpty         = "empty_transcript"
+	QualityIssueLowConfidence = "low_average_confidence"
+	QualityIssueLowPercentile = "low_percentile_confidence"
+	QualityIssueUnknownWords  = "unknown_words"
+	QualityIssueSpeakerCount  = "implausible_speaker_count"
+)
+
+const (
+	// qualityAttemptMetadataKey marks a request as a re-transcription attempt
+	qualityAttemptMetadataKey = "quality_attempt"
+	// primaryAttemptName names the first transcription of a recording
+	primaryAttemptName = "primary"
+)
+
+// AlternateConfig overrides the recognition model for a re-transcription attempt. The sample rate
+// is never overridden: it describes the recording, which is sent as it is.
+type AlternateConfig struct {
+	Name        string `json:"name"`
+	Model       string `json:"model"`
+	UseEnhanced bool   `json:"use_enhanced"`
+}
+
+// QualityConfig contains the thresholds that flag a poor transcript and how to retry it
+type QualityConfig struct {
+	Enabled                    bool              `json:"enabled"`
+	MinAverageConfidence       float64           `json:"min_average_confidence"`
+	LowPercentile              float64           `json:"low_percentile"` // e.g. 0.1 for the 10th percentile
+	MinLowPercentileConfidence float64           `json:"min_low_percentile_confidence"`
+	UnknownWordConfidence      float64           `json:"unknown_word_confidence"` // words below this count as unknown
+	MaxUnknownWordRatio        float64           `json:"max_unknown_word_ratio"`
+	MaxSpeakerCount            int               `json:"max_speaker_count"`
+	MaxRetries                 int               `json:"max_retries"`
+	MinRetryDuration           time.Duration     `json:"min_retry_duration"` // shorter recordings are never retried
+	Alternates                 []AlternateConfig `json:"alternates"`
+}
+
+// DefaultQualityConfig returns quality thresholds tuned for 8 kHz phone audio
+func DefaultQualityConfig() *QualityConfig {
+	return &QualityConfig{
+		Enabled:                    true,
+		MinAverageConfidence:       0.75,
+		LowPercentile:              0.1,
+		MinLowPercentileConfidence: 0.35,
+		UnknownWordConfidence:      0.3,
+		MaxUnknownWordRatio:        0.15,
+		MaxSpeakerCount:            3,
+		MaxRetries:                 1,
+		MinRetryDuration:           5 * time.Second,
+		Alternates: []AlternateConfig{
+			{Name: "phone_call_enhanced", Model: "phone_call", UseEnhanced: true},
+		},
+	}
+}
+
+// EvaluateQuality scores a transcript on word confidence, unrecognised words and speaker count
+func EvaluateQuality(result *models.TranscriptionResult, config *QualityConfig) *models.TranscriptQuality {
+	if config == nil {
+		config = DefaultQualityConfig()
+	}
+
+	quality := &models.TranscriptQuality{}
+	if result == nil || (result.Transcript == "" && len(result.WordDetails) == 0) {
+		quality.Issues = []string{QualityIssueEmpty}
+		quality.Poor = true
+		return quality
+	}
+	quality.SpeakerCount = result.SpeakerCount
+
+	// Word confidences are zero when word confidence was not requested; fall back to the result average
+	var confidences []float64
+	unknown := 0
+	for _, w := range result.WordDetails {
+		if w.Confidence > 0 {
+			confidences = append(confidences, float64(w.Confidence))
+		}
+		if !hasLetterOrDigit(w.Word) || (w.Confidence > 0 && float64(w.Confidence) < config.UnknownWordConfidence) {
+			unknown++
+		}
+	}
+	quality.WordCount = len(result.WordDetails)
+
+	if len(confidences) > 0 {
+		sort.Float64s(confidences)
+		sum := 0.0
+		for _, c := range confidences {
+			sum += c
+		}
+		quality.AverageConfidence = sum / float64(len(confidences))
+		quality.LowPercentileConfidence = confidences[int(config.LowPercentile*float64(len(confidences)-1))]
+	} else {
+		quality.AverageConfidence = float64(result.Confidence)
+		quality.LowPercentileConfidence = float64(result.Confidence)
+	}
+	if quality.WordCount > 0 {
+		quality.UnknownWordRatio = float64(unknown) / float64(quality.WordCount)
+	}
+
+	if quality.AverageConfidence < config.MinAverageConfidence {
+		quality.Issues = append(quality.Issues, QualityIssueLowConfidence)
+	}
+	if quality.LowPercentileConfidence < config.MinLowPercentileConfidence {
+		quality.Issues = append(quality.Issues, QualityIssueLowPercentile)
+	}
+	if quality.UnknownWordRatio > config.MaxUnknownWordRatio {
+		quality.Issues = append(quality.Issues, QualityIssueUnknownWords)
+	}
+	if config.MaxSpeakerCount > 0 && quality.SpeakerCount > config.MaxSpeakerCount {
+		// Diarization splitting one voice into many usually means the audio was misread
+		quality.Issues = append(quality.Issues, QualityIssueSpeakerCount)
+	}
+
+	score := 0.5*quality.AverageConfidence + 0.3*quality.LowPercentileConfidence + 0.2*(1-quality.UnknownWordRatio)
+	if containsString(quality.Issues, QualityIssueSpeakerCount) {
+		score -= 0.1
+	}
+	quality.Score = math.Max(0, math.Min(1, score))
+	quality.Poor = len(quality.Issues) > 0
+
+	return quality
+}
+
+// TranscribeWithQuality transcribes under a tracked job and re-transcribes poor results with the
+// alternate configs, keeping the best scoring transcript
+func (ts *TranscriptionService) TranscribeWithQuality(ctx context.Context, jm *JobManager, req *TranscriptionRequest) (*TranscriptionResponse, error) {
+	response, err := ts.TranscribeWithJob(ctx, jm, req)
+	if err != nil {
+		return nil, err
+	}
+	return ts.ImproveTranscription(ctx, jm, response), nil
+}
+
+// ImproveTranscription scores a finished transcription and, if it is poor, retries it with the
+// alternate configs. Every attempt is recorded on the returned result. A response that is itself a
+// retry is only scored, so a resumed retry does not start another round, and recordings without
+// speech are never retried.
+func (ts *TranscriptionService) ImproveTranscription(ctx context.Context, jm *JobManager, response *TranscriptionResponse) *TranscriptionResponse {
+	qc := ts.config.Quality
+	if qc == nil || !qc.Enabled || response == nil || response.Result == nil || response.Request == nil {
+		return response
+	}
+
+	req := response.Request
+	config := ts.configFor(req)
+	response.Result.Quality = EvaluateQuality(response.Result, qc)
+	if !response.Result.Quality.Poor || req.Metadata[qualityAttemptMetadataKey] != "" || !worthRetrying(req, qc) {
+		return response
+	}
+
+	best := response
+	attempts := []models.TranscriptionAttempt{newAttempt(primaryAttemptName, response, config)}
+	retries := 0
+	for _, alt := range qc.Alternates {
+		if retries >= qc.MaxRetries || ctx.Err() != nil {
+			break
+		}
+		altConfig := alt.apply(config)
+		if altConfig.Model == config.Model && altConfig.UseEnhanced == config.UseEnhanced {
+			continue
+		}
+		retries++
+
+		retryResp, err := ts.TranscribeWithJob(ctx, jm, alt.request(req, altConfig))
+		if err != nil || retryResp == nil || retryResp.Result == nil {
+			attempt := models.TranscriptionAttempt{Name: alt.Name, Model: altConfig.Model, UseEnhanced: altConfig.UseEnhanced, SampleRateHertz: altConfig.SampleRateHertz}
+			if err != nil {
+				attempt.Error = err.Error()
+			}
+			attempts = append(attempts, attempt)
+			continue
+		}
+
+		retryResp.Result.Quality = EvaluateQuality(retryResp.Result, qc)
+		attempts = append(attempts, newAttempt(alt.Name, retryResp, altConfig))
+		if retryResp.Result.Quality.Score > best.Result.Quality.Score {
+			best = retryResp
+		}
+		if !retryResp.Result.Quality.Poor {
+			break
+		}
+	}
+
+	for i := range attempts {
+		attempts[i].Selected = attempts[i].JobID != "" && attempts[i].JobID == jobIDOf(best)
+	}
+	best.Result.Attempts = attempts
+	best.Metadata["transcription_attempts"] = len(attempts)
+	return best
+}
+
+// worthRetrying reports whether another model could improve a poor transcript. Recordings the
+// classifier found no speech in, or too short to hold much, come back poor whatever the model.
+func worthRetrying(req *TranscriptionRequest, qc *QualityConfig) bool {
+	if req.NoSpeech {
+		return false
+	}
+	return req.AudioDuration == 0 || req.AudioDuration >= qc.MinRetryDuration
+}
+
+// apply returns a copy of config with the alternate's overrides
+func (a AlternateConfig) apply(config *TranscriptionConfig) *TranscriptionConfig {
+	altConfig := *config
+	if a.Model != "" {
+		altConfig.Model = a.Model
+	}
+	altConfig.UseEnhanced = a.UseEnhanced
+	return &altConfig
+}
+
+// request builds the retry request, marking it so it is not retried again
+func (a AlternateConfig) request(req *TranscriptionRequest, config *TranscriptionConfig) *TranscriptionRequest {
+	retry := *req
+	retry.CustomConfig = config
+	retry.Metadata = make(map[string]string, len(req.Metadata)+1)
+	for k, v := range req.Metadata {
+		retry.Metadata[k] = v
+	}
+	retry.Metadata[qualityAttemptMetadataKey] = a.Name
+	return &retry
+}
+
+// newAttempt records a completed transcription attempt
+func newAttempt(name string, response *TranscriptionResponse, config *TranscriptionConfig) models.TranscriptionAttempt {
+	return models.TranscriptionAttempt{
+		Name:            name,
+		JobID:           jobIDOf(response),
+		Model:           config.Model,
+		UseEnhanced:     config.UseEnhanced,
+		SampleRateHertz: config.SampleRateHertz,
+		Quality:         response.Result.Quality,
+	}
+}
+
+// jobIDOf returns the transcription job a response belongs to
+func jobIDOf(response *TranscriptionResponse) string {
+	if response == nil || response.Metadata == nil {
+		return ""
+	}
+	jobID, _ := response.Metadata["job_id"].(string)
+	return jobID
+}
+
+// hasLetterOrDigit reports whether a word contains anything recognisable
+func hasLetterOrDigit(word string) bool {
+	for _, r := range word {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString reports whether values contains s
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
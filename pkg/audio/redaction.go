@@ -0,0 +1,510 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// defaultRedactedTypes are redacted when a tenant enables redaction without choosing entities.
+// Phone numbers, emails and addresses are usually lead details the tenant needs to keep.
+var defaultRedactedTypes = []models.PIIType{models.PIICardNumber, models.PIISSN}
+
+// PIIEntity is a detected piece of personal data. The matched text itself is never retained.
+type PIIEntity struct {
+	Type      models.PIIType `json:"type"`
+	Source    string         `json:"source"` // pattern or ner
+	Start     int            `json:"start"`  // byte offsets in the analysed text
+	End       int            `json:"end"`
+	StartTime models.Offset  `json:"start_time,omitempty"`
+	EndTime   models.Offset  `json:"end_time,omitempty"`
+}
+
+// EntityRecognizer finds named entities such as people and addresses in free text. Offsets are
+// byte offsets into text.
+type EntityRecognizer interface {
+	Recognize(ctx context.Context, text string) ([]PIIEntity, error)
+}
+
+// TimeRange is a span of audio
+type TimeRange struct {
+	Start time.Duration `json:"start"`
+	End   time.Duration `json:"end"`
+}
+
+// RedactionResult is a redacted copy of a transcription and where PII was found
+type RedactionResult struct {
+	Transcription *models.TranscriptionResult `json:"transcription"`
+	Entities      []PIIEntity                 `json:"entities"`
+	AudioRanges   []TimeRange                 `json:"audio_ranges"`
+}
+
+// Counts returns the number of redacted entities per type
+func (r *RedactionResult) Counts() map[models.PIIType]int {
+	counts := make(map[models.PIIType]int)
+	for _, e := range r.Entities {
+		counts[e.Type]++
+	}
+	return counts
+}
+
+// Redactor detects and removes PII from transcripts according to a tenant policy
+type Redactor struct {
+	types map[models.PIIType]bool
+	ner   EntityRecognizer
+}
+
+// NewRedactor creates a redactor for a tenant policy; ner may be nil
+func NewRedactor(policy models.RedactionConfig, ner EntityRecognizer) *Redactor {
+	entities := policy.Entities
+	if len(entities) == 0 {
+		entities = defaultRedactedTypes
+	}
+
+	types := make(map[models.PIIType]bool, len(entities))
+	for _, t := range entities {
+		types[t] = true
+	}
+
+	if !policy.UseNER {
+		ner = nil
+	}
+	return &Redactor{types: types, ner: ner}
+}
+
+// Placeholder returns the typed placeholder that replaces an entity, e.g. [CARD_NUMBER]
+func Placeholder(t models.PIIType) string {
+	return "[" + strings.ToUpper(string(t)) + "]"
+}
+
+// textToken is a whitespace-delimited token and its byte range in the joined text
+type textToken struct {
+	text  string
+	start int
+	end   int
+}
+
+// tokenize splits text on whitespace, keeping byte offsets
+func tokenize(text string) []textToken {
+	var tokens []textToken
+	start := -1
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				tokens = append(tokens, textToken{text: text[start:i], start: start, end: i})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, textToken{text: text[start:], start: start, end: len(text)})
+	}
+	return tokens
+}
+
+// span is a detected entity expressed as an inclusive-exclusive token range
+type span struct {
+	typ    models.PIIType
+	source string
+	first  int
+	last   int // exclusive
+}
+
+var (
+	emailPattern         = regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}\b`)
+	spokenEmailPattern   = regexp.MustCompile(`(?i)\b[a-z0-9._-]+(?:\s+(?:dot|underscore)\s+[a-z0-9]+)*\s+at\s+[a-z0-9-]+(?:\s+dot\s+[a-z0-9-]+)*\s+dot\s+(?:com|net|org|edu|gov|us|io|co|biz|info)\b`)
+	streetAddressPattern = regexp.MustCompile(`(?i)\b\d{1,6}(?:\s+[a-z0-9'.-]+){1,4}\s+(?:street|st|avenue|ave|road|rd|drive|dr|lane|ln|boulevard|blvd|court|ct|way|circle|cir|place|pl|parkway|pkwy|terrace|ter|trail|trl|highway|hwy)\b\.?`)
+	ssnContextPattern    = regexp.MustCompile(`(?i)\b(?:social|security|ssn)\b`)
+	dashedSSNPattern     = regexp.MustCompile(`^\d{3}-\d{2}-\d{4}$`)
+	numericTokenPattern  = regexp.MustCompile(`^[0-9][0-9-]*$`)
+)
+
+// spokenDigits maps number words Speech-to-Text leaves unconverted to digits
+var spokenDigits = map[string]string{
+	"zero": "0", "oh": "0", "o": "0",
+	"one": "1", "two": "2", "three": "3", "four": "4", "five": "5",
+	"six": "6", "seven": "7", "eight": "8", "nine": "9",
+}
+
+// Detect finds the PII entities in text that the policy redacts
+func (r *Redactor) Detect(ctx context.Context, text string) ([]PIIEntity, error) {
+	tokens := tokenize(text)
+	spans, err := r.detectSpans(ctx, text, tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	entities := make([]PIIEntity, 0, len(spans))
+	for _, sp := range spans {
+		entities = append(entities, PIIEntity{
+			Type:   sp.typ,
+			Source: sp.source,
+			Start:  tokens[sp.first].start,
+			End:    tokens[sp.last-1].end,
+		})
+	}
+	return entities, nil
+}
+
+// RedactText replaces the PII in text with typed placeholders
+func (r *Redactor) RedactText(ctx context.Context, text string) (string, []PIIEntity, error) {
+	tokens := tokenize(text)
+	spans, err := r.detectSpans(ctx, text, tokens)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(spans) == 0 {
+		return text, nil, nil
+	}
+
+	var b strings.Builder
+	entities := make([]PIIEntity, 0, len(spans))
+	pos := 0
+	for _, sp := range spans {
+		start, end := tokens[sp.first].start, tokens[sp.last-1].end
+		b.WriteString(text[pos:start])
+		b.WriteString(Placeholder(sp.typ))
+		b.WriteString(trailingPunctuation(tokens[sp.last-1].text))
+		pos = end
+
+		entities = append(entities, PIIEntity{Type: sp.typ, Source: sp.source, Start: start, End: end})
+	}
+	b.WriteString(text[pos:])
+
+	return b.String(), entities, nil
+}
+
+// RedactTranscription returns a redacted copy of a transcription together with the audio time
+// ranges that held PII. The input is not modified.
+func (r *Redactor) RedactTranscription(ctx context.Context, result *models.TranscriptionResult) (*RedactionResult, error) {
+	redacted := *result
+	out := &RedactionResult{Transcription: &redacted}
+
+	transcript, transcriptEntities, err := r.RedactText(ctx, result.Transcript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redact transcript: %w", err)
+	}
+	redacted.Transcript = transcript
+
+	redacted.SpeakerDiarization = make([]models.SpeakerSegment, len(result.SpeakerDiarization))
+	for i, seg := range result.SpeakerDiarization {
+		text, _, err := r.RedactText(ctx, seg.Text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to redact speaker segment: %w", err)
+		}
+		seg.Text = text
+		redacted.SpeakerDiarization[i] = seg
+	}
+
+	if len(result.WordDetails) == 0 {
+		out.Entities = transcriptEntities
+		return out, nil
+	}
+
+	// Word-level pass gives the audio time range of each entity
+	words := make([]string, len(result.WordDetails))
+	for i, w := range result.WordDetails {
+		words[i] = w.Word
+	}
+	joined := strings.Join(words, " ")
+	tokens := tokenize(joined)
+	spans, err := r.detectSpans(ctx, joined, tokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redact words: %w", err)
+	}
+
+	// tokenize drops empty words, so map tokens back to word indexes
+	tokenWord := make([]int, 0, len(tokens))
+	for i, w := range result.WordDetails {
+		if strings.TrimSpace(w.Word) != "" {
+			tokenWord = append(tokenWord, i)
+		}
+	}
+
+	redacted.WordDetails = make([]models.WordDetail, 0, len(result.WordDetails))
+	next := 0
+	for _, sp := range spans {
+		firstWord, lastWord := tokenWord[sp.first], tokenWord[sp.last-1]
+		redacted.WordDetails = append(redacted.WordDetails, result.WordDetails[next:firstWord]...)
+
+		merged := result.WordDetails[firstWord]
+		merged.Word = Placeholder(sp.typ) + trailingPunctuation(result.WordDetails[lastWord].Word)
+		merged.EndTime = result.WordDetails[lastWord].EndTime
+		redacted.WordDetails = append(redacted.WordDetails, merged)
+		next = lastWord + 1
+
+		out.Entities = append(out.Entities, PIIEntity{
+			Type:      sp.typ,
+			Source:    sp.source,
+			Start:     tokens[sp.first].start,
+			End:       tokens[sp.last-1].end,
+			StartTime: merged.StartTime,
+			EndTime:   merged.EndTime,
+		})
+		out.AudioRanges = append(out.AudioRanges, TimeRange{
+			Start: merged.StartTime.Duration(),
+			End:   merged.EndTime.Duration(),
+		})
+	}
+	redacted.WordDetails = append(redacted.WordDetails, result.WordDetails[next:]...)
+
+	return out, nil
+}
+
+// detectSpans runs every enabled detector over the tokens and returns non-overlapping spans in order
+func (r *Redactor) detectSpans(ctx context.Context, text string, tokens []textToken) ([]span, error) {
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	claimed := make([]bool, len(tokens))
+	var spans []span
+	add := func(sp span) {
+		if !r.types[sp.typ] || sp.first >= sp.last {
+			return
+		}
+		for i := sp.first; i < sp.last; i++ {
+			if claimed[i] {
+				return
+			}
+		}
+		for i := sp.first; i < sp.last; i++ {
+			claimed[i] = true
+		}
+		spans = append(spans, sp)
+	}
+
+	// Numeric runs first so card numbers are not mistaken for addresses
+	for _, sp := range detectNumbers(tokens) {
+		add(sp)
+	}
+	for _, sp := range matchPattern(emailPattern, models.PIIEmail, text, tokens) {
+		add(sp)
+	}
+	for _, sp := range matchPattern(spokenEmailPattern, models.PIIEmail, text, tokens) {
+		add(sp)
+	}
+	for _, sp := range matchPattern(streetAddressPattern, models.PIIAddress, text, tokens) {
+		add(sp)
+	}
+
+	if r.ner != nil {
+		entities, err := r.ner.Recognize(ctx, text)
+		if err != nil {
+			return nil, fmt.Errorf("entity recognition failed: %w", err)
+		}
+		for _, e := range entities {
+			if first, last, ok := tokenRange(tokens, e.Start, e.End); ok {
+				add(span{typ: e.Type, source: "ner", first: first, last: last})
+			}
+		}
+	}
+
+	// Order by position for in-place replacement
+	for i := 1; i < len(spans); i++ {
+		for j := i; j > 0 && spans[j].first < spans[j-1].first; j-- {
+			spans[j], spans[j-1] = spans[j-1], spans[j]
+		}
+	}
+	return spans, nil
+}
+
+// matchPattern converts regex matches on text into token spans
+func matchPattern(pattern *regexp.Regexp, typ models.PIIType, text string, tokens []textToken) []span {
+	var spans []span
+	for _, m := range pattern.FindAllStringIndex(text, -1) {
+		if first, last, ok := tokenRange(tokens, m[0], m[1]); ok {
+			spans = append(spans, span{typ: typ, source: "pattern", first: first, last: last})
+		}
+	}
+	return spans
+}
+
+// tokenRange returns the tokens overlapping the byte range [start, end)
+func tokenRange(tokens []textToken, start, end int) (int, int, bool) {
+	first, last := -1, -1
+	for i, tok := range tokens {
+		if tok.end > start && tok.start < end {
+			if first < 0 {
+				first = i
+			}
+			last = i + 1
+		}
+	}
+	return first, last, first >= 0
+}
+
+// detectNumbers finds card numbers, SSNs and phone numbers in runs of digit tokens, including
+// digits spoken as words ("four one one one")
+func detectNumbers(tokens []textToken) []span {
+	var spans []span
+
+	i := 0
+	for i < len(tokens) {
+		if _, ok := tokenDigits(tokens[i].text); !ok {
+			i++
+			continue
+		}
+
+		// Collect the run and remember which token each digit came from
+		var digits strings.Builder
+		var owner []int
+		j := i
+		for ; j < len(tokens); j++ {
+			d, ok := tokenDigits(tokens[j].text)
+			if !ok {
+				break
+			}
+			for range d {
+				owner = append(owner, j)
+			}
+			digits.WriteString(d)
+		}
+
+		spans = append(spans, classifyDigitRun(tokens, i, j, digits.String(), owner)...)
+		i = j
+	}
+
+	return spans
+}
+
+// classifyDigitRun decides what a run of digits spanning tokens [first, last) contains
+func classifyDigitRun(tokens []textToken, first, last int, digits string, owner []int) []span {
+	toSpan := func(typ models.PIIType, from, to int) span {
+		return span{typ: typ, source: "pattern", first: owner[from], last: owner[to-1] + 1}
+	}
+
+	// Card numbers start the run and may be followed by more digits such as the expiry date.
+	// Issuer prefixes 2-6 cover Mastercard, Amex, Visa and Discover.
+	if len(digits) >= 13 && digits[0] >= '2' && digits[0] <= '6' {
+		for length := 19; length >= 13; length-- {
+			if length > len(digits) {
+				continue
+			}
+			if luhnValid(digits[:length]) && !repeatedDigit(digits[:length]) {
+				return []span{toSpan(models.PIICardNumber, 0, length)}
+			}
+		}
+	}
+
+	switch {
+	case len(digits) == 9 && validSSN(digits) && (hasSSNContext(tokens, first) || (last-first == 1 && dashedSSNPattern.MatchString(trimToken(tokens[first].text)))):
+		return []span{toSpan(models.PIISSN, 0, len(digits))}
+	case len(digits) == 10 || (len(digits) == 11 && digits[0] == '1'):
+		return []span{toSpan(models.PIIPhone, 0, len(digits))}
+	}
+	return nil
+}
+
+// tokenDigits returns the digits a token contributes to a number, if it is numeric
+func tokenDigits(text string) (string, bool) {
+	core := strings.ToLower(trimToken(text))
+	if core == "" {
+		return "", false
+	}
+	if d, ok := spokenDigits[core]; ok {
+		return d, true
+	}
+	if numericTokenPattern.MatchString(core) {
+		return strings.ReplaceAll(core, "-", ""), true
+	}
+	return "", false
+}
+
+// hasSSNContext reports whether "social security" or "SSN" was said shortly before token i
+func hasSSNContext(tokens []textToken, i int) bool {
+	from := i - 8
+	if from < 0 {
+		from = 0
+	}
+	for _, tok := range tokens[from:i] {
+		if ssnContextPattern.MatchString(tok.text) {
+			return true
+		}
+	}
+	return false
+}
+
+// luhnValid checks a card number checksum
+func luhnValid(number string) bool {
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// repeatedDigit reports whether a number is a single repeated digit, e.g. all zeros
+func repeatedDigit(number string) bool {
+	return strings.Count(number, number[:1]) == len(number)
+}
+
+// validSSN applies the SSA rules for issued numbers
+func validSSN(digits string) bool {
+	area, group, serial := digits[:3], digits[3:5], digits[5:]
+	if area == "000" || area == "666" || area[0] == '9' {
+		return false
+	}
+	return group != "00" && serial != "0000"
+}
+
+// trimToken strips punctuation around a token
+func trimToken(text string) string {
+	return strings.TrimFunc(text, func(r rune) bool {
+		return unicode.IsPunct(r) && r != '-'
+	})
+}
+
+// trailingPunctuation returns sentence punctuation at the end of a token
+func trailingPunctuation(text string) string {
+	end := len(text)
+	for end > 0 && strings.ContainsRune(".,!?;:", rune(text[end-1])) {
+		end--
+	}
+	return text[end:]
+}
+
+// BleepAudio returns a copy of pcm with each range replaced by a 1 kHz tone
+func BleepAudio(pcm *PCMAudio, ranges []TimeRange, padding time.Duration) *PCMAudio {
+	out := &PCMAudio{
+		Samples:    append([]int16(nil), pcm.Samples...),
+		SampleRate: pcm.SampleRate,
+	}
+
+	for _, rg := range ranges {
+		start := pcm.SampleAt(rg.Start - padding)
+		end := pcm.SampleAt(rg.End + padding)
+		if start < 0 {
+			start = 0
+		}
+		if end > len(out.Samples) {
+			end = len(out.Samples)
+		}
+		for i := start; i < end; i++ {
+			out.Samples[i] = int16(6000 * math.Sin(2*math.Pi*1000*float64(i)/float64(pcm.SampleRate)))
+		}
+	}
+	return out
+}
@@ -11,12 +11,14 @@ import (
 	"google.golang.org/protobuf/types/known/durationpb"
 
 	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+	"github.com/home-renovators/ingestion-pipeline/pkg/scheduler"
 )
 
 // TranscriptionService handles audio transcription using Google Speech-to-Text
 type TranscriptionService struct {
 	speechClient *speech.Client
 	config       *TranscriptionConfig
+	scheduler    *scheduler.Scheduler
 }
 
 // TranscriptionConfig contains configuration for the transcription service
@@ -25,6 +27,7 @@ type TranscriptionConfig struct {
 	Location             string  `json:"location"`
 	Model                string  `json:"model"` // e.g., "chirp-3"
 	LanguageCode         string  `json:"language_code"`
+	AlternativeLanguageCodes []string `json:"alternative_language_codes,omitempty"` // enables automatic language identification
 	SampleRateHertz      int32   `json:"sample_rate_hertz"`
 	EnableDiarization    bool    `json:"enable_diarization"`
 	EnablePunctuation    bool    `json:"enable_punctuation"`
@@ -34,6 +37,9 @@ type TranscriptionConfig struct {
 	MaxSpeakerCount      int32   `json:"max_speaker_count"`
 	AudioEncoding        speechpb.RecognitionConfig_AudioEncoding `json:"audio_encoding"`
 	UseEnhanced          bool    `json:"use_enhanced"`
+	Chunking             *ChunkingConfig `json:"chunking,omitempty"`
+	Quality              *QualityConfig  `json:"quality,omitempty"`
+	Scheduling           *scheduler.Config `json:"scheduling,omitempty"`
 }
 
 // DefaultTranscriptionConfig returns default configuration for phone calls
@@ -50,6 +56,9 @@ func DefaultTranscriptionConfig() *TranscriptionConfig {
 		MaxSpeakerCount:      2, // Customer and agent
 		AudioEncoding:        speechpb.RecognitionConfig_MP3,
 		UseEnhanced:          true,
+		Chunking:             DefaultChunkingConfig(),
+		Quality:              DefaultQualityConfig(),
+		Scheduling:           scheduler.DefaultConfig(),
 	}
 }
 
@@ -64,10 +73,24 @@ func NewTranscriptionService(ctx context.Context, config *TranscriptionConfig) (
 		return nil, fmt.Errorf("failed to create speech client: %w", err)
 	}
 
+	return NewTranscriptionServiceWithClient(speechClient, config), nil
+}
+
+// NewTranscriptionServiceWithClient creates a transcription service on an existing Speech-to-Text client
+func NewTranscriptionServiceWithClient(speechClient *speech.Client, config *TranscriptionConfig) *TranscriptionService {
+	if config == nil {
+		config = DefaultTranscriptionConfig()
+	}
 	return &TranscriptionService{
 		speechClient: speechClient,
 		config:       config,
-	}, nil
+		scheduler:    scheduler.New(config.Scheduling),
+	}
+}
+
+// SchedulerStats returns a snapshot of the Speech-to-Text work running and queued
+func (ts *TranscriptionService) SchedulerStats() scheduler.Stats {
+	return ts.scheduler.Stats()
 }
 
 // Close closes the transcription service
@@ -78,12 +101,18 @@ func (ts *TranscriptionService) Close() error {
 // TranscriptionRequest contains the parameters for a transcription request
 type TranscriptionRequest struct {
 	AudioURI         string                     `json:"audio_uri"`
+	AudioContent     []byte                     `json:"-"` // decrypted audio sent inline instead of AudioURI; never persisted
+	Encrypted        bool                       `json:"encrypted,omitempty"` // AudioURI is client-side encrypted, so only AudioContent or PCM may be sent
+	PCM              *PCMAudio                  `json:"-"` // decoded recording, transcribed in chunks when long; never persisted
 	CallID           string                     `json:"call_id"`
 	TenantID         string                     `json:"tenant_id"`
 	CustomConfig     *TranscriptionConfig       `json:"custom_config,omitempty"`
+	Languages        []string                   `json:"languages,omitempty"` // tenant's expected languages, primary first
 	Metadata         map[string]string          `json:"metadata,omitempty"`
 	Priority         TranscriptionPriority      `json:"priority"`
 	Timeout          time.Duration              `json:"timeout"`
+	AudioDuration    time.Duration              `json:"audio_duration,omitempty"` // length of the recording, 0 if unknown
+	NoSpeech         bool                       `json:"no_speech,omitempty"`      // the call classifier found no speech in the recording
 }
 
 // TranscriptionPriority defines the priority of transcription requests
@@ -119,10 +148,7 @@ func (ts *TranscriptionService) TranscribeAudio(ctx context.Context, req *Transc
 	}
 
 	// Use custom config if provided, otherwise use service default
-	config := ts.config
-	if req.CustomConfig != nil {
-		config = req.CustomConfig
-	}
+	config := ts.configFor(req)
 
 	// Set timeout if specified
 	if req.Timeout > 0 {
@@ -132,37 +158,12 @@ func (ts *TranscriptionService) TranscribeAudio(ctx context.Context, req *Transc
 	}
 
 	// Create recognition config
-	recognitionConfig := &speechpb.RecognitionConfig{
-		Encoding:                   config.AudioEncoding,
-		SampleRateHertz:           config.SampleRateHertz,
-		LanguageCode:              config.LanguageCode,
-		EnableAutomaticPunctuation: config.EnablePunctuation,
-		EnableWordTimeOffsets:      config.EnableWordTimestamp,
-		EnableWordConfidence:       config.EnableWordConfidence,
-		Model:                      config.Model,
-		UseEnhanced:               config.UseEnhanced,
-	}
-
-	// Configure speaker diarization if enabled
-	if config.EnableDiarization {
-		recognitionConfig.DiarizationConfig = &speechpb.SpeakerDiarizationConfig{
-			EnableSpeakerDiarization: true,
-			MinSpeakerCount:         config.MinSpeakerCount,
-			MaxSpeakerCount:         config.MaxSpeakerCount,
-		}
-	}
-
-	// Create audio source
-	audio := &speechpb.RecognitionAudio{
-		AudioSource: &speechpb.RecognitionAudio_Uri{
-			Uri: req.AudioURI,
-		},
-	}
+	recognitionConfig := ts.buildRecognitionConfig(config)
 
 	// Create long running recognition request
 	longRunningReq := &speechpb.LongRunningRecognizeRequest{
 		Config: recognitionConfig,
-		Audio:  audio,
+		Audio:  recognitionAudio(req),
 	}
 
 	// Start long-running recognition
@@ -200,6 +201,59 @@ func (ts *TranscriptionService) TranscribeAudio(ctx context.Context, req *Transc
 	return response, nil
 }
 
+// MaxInlineAudioBytes is the largest recording Speech-to-Text accepts inline
+const MaxInlineAudioBytes = 10 << 20
+
+// recognitionAudio returns the audio source of a request. Client-side encrypted recordings can't
+// be read by Speech-to-Text from storage, so they are decrypted locally and sent inline, which
+// Speech-to-Text limits to MaxInlineAudioBytes.
+func recognitionAudio(req *TranscriptionRequest) *speechpb.RecognitionAudio {
+	if len(req.AudioContent) > 0 {
+		return &speechpb.RecognitionAudio{
+			AudioSource: &speechpb.RecognitionAudio_Content{Content: req.AudioContent},
+		}
+	}
+	return &speechpb.RecognitionAudio{
+		AudioSource: &speechpb.RecognitionAudio_Uri{Uri: req.AudioURI},
+	}
+}
+
+// configFor returns the config for a request: its custom config or the service default, set to
+// recognize the request's languages
+func (ts *TranscriptionService) configFor(req *TranscriptionRequest) *TranscriptionConfig {
+	config := ts.config
+	if req.CustomConfig != nil {
+		config = req.CustomConfig
+	}
+	return config.WithLanguages(req.Languages)
+}
+
+// buildRecognitionConfig converts a transcription config into a Speech-to-Text recognition config
+func (ts *TranscriptionService) buildRecognitionConfig(config *TranscriptionConfig) *speechpb.RecognitionConfig {
+	recognitionConfig := &speechpb.RecognitionConfig{
+		Encoding:                   config.AudioEncoding,
+		SampleRateHertz:           config.SampleRateHertz,
+		LanguageCode:              config.LanguageCode,
+		EnableAutomaticPunctuation: config.EnablePunctuation,
+		EnableWordTimeOffsets:      config.EnableWordTimestamp,
+		EnableWordConfidence:       config.EnableWordConfidence,
+		Model:                      config.Model,
+		UseEnhanced:               config.UseEnhanced,
+		AlternativeLanguageCodes:   config.AlternativeLanguageCodes,
+	}
+
+	// Configure speaker diarization if enabled
+	if config.EnableDiarization {
+		recognitionConfig.DiarizationConfig = &speechpb.SpeakerDiarizationConfig{
+			EnableSpeakerDiarization: true,
+			MinSpeakerCount:         config.MinSpeakerCount,
+			MaxSpeakerCount:         config.MaxSpeakerCount,
+		}
+	}
+
+	return recognitionConfig
+}
+
 // TranscribeBatch transcribes multiple audio files in batch
 func (ts *TranscriptionService) TranscribeBatch(ctx context.Context, requests []*TranscriptionRequest) ([]*TranscriptionResponse, error) {
 	if len(requests) == 0 {
@@ -208,9 +262,6 @@ func (ts *TranscriptionService) TranscribeBatch(ctx context.Context, requests []
 
 	responses := make([]*TranscriptionResponse, len(requests))
 
-	// Process requests concurrently with a semaphore to limit concurrency
-	semaphore := make(chan struct{}, 5) // Allow up to 5 concurrent transcriptions
-
 	type result struct {
 		index    int
 		response *TranscriptionResponse
@@ -221,8 +272,13 @@ func (ts *TranscriptionService) TranscribeBatch(ctx context.Context, requests []
 
 	for i, req := range requests {
 		go func(index int, request *TranscriptionRequest) {
-			semaphore <- struct{}{} // Acquire
-			defer func() { <-semaphore }() // Release
+			// Wait for a slot by priority and tenant share
+			release, err := ts.scheduler.Acquire(ctx, request.TenantID, scheduler.Priority(request.Priority))
+			if err != nil {
+				resultChan <- result{index: index, response: &TranscriptionResponse{Request: request, Error: err.Error()}, err: err}
+				return
+			}
+			defer release()
 
 			resp, err := ts.TranscribeAudio(ctx, request)
 			resultChan <- result{index: index, response: resp, err: err}
@@ -244,6 +300,63 @@ func (ts *TranscriptionService) TranscribeBatch(ctx context.Context, requests []
 
 // processTranscriptionResults processes Speech-to-Text API results
 func (ts *TranscriptionService) processTranscriptionResults(resp *speechpb.LongRunningRecognizeResponse, config *TranscriptionConfig) *models.TranscriptionResult {
+	results := resp.Results
+	if config.EnableDiarization {
+		results = diarizedResults(results)
+	}
+	return ts.buildTranscriptionResult(results, config)
+}
+
+// diarizedResults returns the results of a diarized recognition with each word once. With
+// diarization the final result repeats every word of the audio with its speaker tag, so its
+// words are handed back, in order, to the results they were recognized in and the final
+// result is dropped. Words left over once every result has its share stay on a result of
+// their own.
+func diarizedResults(results []*speechpb.SpeechRecognitionResult) []*speechpb.SpeechRecognitionResult {
+	if len(results) < 2 {
+		return results
+	}
+	final := results[len(results)-1]
+	if len(final.Alternatives) == 0 {
+		return results
+	}
+	tagged := final.Alternatives[0].Words
+
+	deduped := make([]*speechpb.SpeechRecognitionResult, 0, len(results))
+	for _, res := range results[:len(results)-1] {
+		segment := &speechpb.SpeechRecognitionResult{
+			ResultEndTime: res.ResultEndTime,
+			ChannelTag:    res.ChannelTag,
+			LanguageCode:  res.LanguageCode,
+		}
+		if len(res.Alternatives) > 0 {
+			alt := res.Alternatives[0]
+			n := len(alt.Words)
+			if n > len(tagged) {
+				n = len(tagged)
+			}
+			segment.Alternatives = []*speechpb.SpeechRecognitionAlternative{{
+				Transcript: alt.Transcript,
+				Confidence: alt.Confidence,
+				Words:      tagged[:n],
+			}}
+			tagged = tagged[n:]
+		}
+		deduped = append(deduped, segment)
+	}
+
+	if len(tagged) > 0 {
+		deduped = append(deduped, &speechpb.SpeechRecognitionResult{
+			Alternatives: []*speechpb.SpeechRecognitionAlternative{{Words: tagged}},
+			LanguageCode: final.LanguageCode,
+		})
+	}
+	return deduped
+}
+
+// buildTranscriptionResult builds a transcript from recognition results whose words each
+// appear once
+func (ts *TranscriptionService) buildTranscriptionResult(results []*speechpb.SpeechRecognitionResult, config *TranscriptionConfig) *models.TranscriptionResult {
 	result := &models.TranscriptionResult{
 		SpeakerDiarization: []models.SpeakerSegment{},
 		WordDetails:        []models.WordDetail{},
@@ -254,7 +367,7 @@ func (ts *TranscriptionService) processTranscriptionResults(resp *speechpb.LongR
 	var totalDuration float64
 	wordCount := 0
 
-	for _, res := range resp.Results {
+	for _, res := range results {
 		if len(res.Alternatives) == 0 {
 			continue
 		}
@@ -271,8 +384,7 @@ func (ts *TranscriptionService) processTranscriptionResults(resp *speechpb.LongR
 			firstWord := alt.Words[0]
 			lastWord := alt.Words[len(alt.Words)-1]
 			if firstWord.StartTime != nil && lastWord.EndTime != nil {
-				segmentDuration := lastWord.EndTime.Seconds + float64(lastWord.EndTime.Nanos)/1e9 -
-								 (firstWord.StartTime.Seconds + float64(firstWord.StartTime.Nanos)/1e9)
+				segmentDuration := (durationOf(lastWord.EndTime) - durationOf(firstWord.StartTime)).Seconds()
 				if segmentDuration > totalDuration {
 					totalDuration = segmentDuration
 				}
@@ -291,8 +403,8 @@ func (ts *TranscriptionService) processTranscriptionResults(resp *speechpb.LongR
 					// Save current segment
 					result.SpeakerDiarization = append(result.SpeakerDiarization, models.SpeakerSegment{
 						Speaker:   int(currentSpeaker),
-						StartTime: formatDuration(segmentStart),
-						EndTime:   formatDuration(word.StartTime),
+						StartTime: toOffset(segmentStart),
+						EndTime:   toOffset(word.StartTime),
 						Text:      strings.TrimSpace(segmentText.String()),
 					})
 
@@ -309,9 +421,10 @@ func (ts *TranscriptionService) processTranscriptionResults(resp *speechpb.LongR
 				if config.EnableWordTimestamp {
 					result.WordDetails = append(result.WordDetails, models.WordDetail{
 						Word:       word.Word,
-						StartTime:  formatDuration(word.StartTime),
-						EndTime:    formatDuration(word.EndTime),
+						StartTime:  toOffset(word.StartTime),
+						EndTime:    toOffset(word.EndTime),
 						Confidence: word.Confidence,
+						Speaker:    int(word.SpeakerTag),
 					})
 				}
 			}
@@ -321,8 +434,8 @@ func (ts *TranscriptionService) processTranscriptionResults(resp *speechpb.LongR
 				lastWord := alt.Words[len(alt.Words)-1]
 				result.SpeakerDiarization = append(result.SpeakerDiarization, models.SpeakerSegment{
 					Speaker:   int(currentSpeaker),
-					StartTime: formatDuration(segmentStart),
-					EndTime:   formatDuration(lastWord.EndTime),
+					StartTime: toOffset(segmentStart),
+					EndTime:   toOffset(lastWord.EndTime),
 					Text:      strings.TrimSpace(segmentText.String()),
 				})
 			}
@@ -332,6 +445,7 @@ func (ts *TranscriptionService) processTranscriptionResults(resp *speechpb.LongR
 	// Set final results
 	result.Transcript = strings.TrimSpace(transcriptBuilder.String())
 	result.Duration = totalDuration
+	result.Language = dominantLanguage(results, config.LanguageCode)
 
 	if wordCount > 0 {
 		result.Confidence = totalConfidence / float32(wordCount)
@@ -347,135 +461,11 @@ func (ts *TranscriptionService) processTranscriptionResults(resp *speechpb.LongR
 	return result
 }
 
-// formatDuration formats a protobuf duration to string
-func formatDuration(duration *durationpb.Duration) string {
+// toOffset converts a protobuf duration to a transcript offset
+func toOffset(duration *durationpb.Duration) models.Offset {
 	if duration == nil {
-		return "0.0s"
+		return 0
 	}
-
-	seconds := duration.Seconds
-	nanos := duration.Nanos
-
-	totalSeconds := float64(seconds) + float64(nanos)/1e9
-	return fmt.Sprintf("%.1fs", totalSeconds)
-}
-
-// TranscriptionStatus represents the status of a transcription job
-type TranscriptionStatus struct {
-	JobID       string                 `json:"job_id"`
-	Status      string                 `json:"status"` // pending, processing, completed, failed
-	Progress    float64               `json:"progress"` // 0.0 to 1.0
-	StartTime   time.Time             `json:"start_time"`
-	EndTime     *time.Time            `json:"end_time,omitempty"`
-	Error       string                `json:"error,omitempty"`
-	Result      *models.TranscriptionResult `json:"result,omitempty"`
-	Metadata    map[string]interface{} `json:"metadata"`
+	return models.Offset(duration.AsDuration())
 }
 
-// TranscriptionJob represents a transcription job for tracking
-type TranscriptionJob struct {
-	JobID    string                `json:"job_id"`
-	Request  *TranscriptionRequest `json:"request"`
-	Status   *TranscriptionStatus  `json:"status"`
-	Response *TranscriptionResponse `json:"response,omitempty"`
-}
-
-// JobManager manages transcription jobs
-type JobManager struct {
-	jobs map[string]*TranscriptionJob
-}
-
-// NewJobManager creates a new job manager
-func NewJobManager() *JobManager {
-	return &JobManager{
-		jobs: make(map[string]*TranscriptionJob),
-	}
-}
-
-// CreateJob creates a new transcription job
-func (jm *JobManager) CreateJob(request *TranscriptionRequest) *TranscriptionJob {
-	jobID := fmt.Sprintf("transcription_%s_%d", request.CallID, time.Now().UnixNano())
-
-	job := &TranscriptionJob{
-		JobID:   jobID,
-		Request: request,
-		Status: &TranscriptionStatus{
-			JobID:     jobID,
-			Status:    "pending",
-			Progress:  0.0,
-			StartTime: time.Now(),
-			Metadata:  make(map[string]interface{}),
-		},
-	}
-
-	jm.jobs[jobID] = job
-	return job
-}
-
-// UpdateJobStatus updates the status of a transcription job
-func (jm *JobManager) UpdateJobStatus(jobID string, status string, progress float64, error string) {
-	if job, exists := jm.jobs[jobID]; exists {
-		job.Status.Status = status
-		job.Status.Progress = progress
-		if error != "" {
-			job.Status.Error = error
-		}
-		if status == "completed" || status == "failed" {
-			now := time.Now()
-			job.Status.EndTime = &now
-		}
-	}
-}
-
-// CompleteJob marks a job as completed with results
-func (jm *JobManager) CompleteJob(jobID string, response *TranscriptionResponse) {
-	if job, exists := jm.jobs[jobID]; exists {
-		job.Response = response
-		job.Status.Status = "completed"
-		job.Status.Progress = 1.0
-		job.Status.Result = response.Result
-		now := time.Now()
-		job.Status.EndTime = &now
-	}
-}
-
-// GetJob retrieves a transcription job by ID
-func (jm *JobManager) GetJob(jobID string) (*TranscriptionJob, bool) {
-	job, exists := jm.jobs[jobID]
-	return job, exists
-}
-
-// GetJobStatus retrieves just the status of a transcription job
-func (jm *JobManager) GetJobStatus(jobID string) (*TranscriptionStatus, bool) {
-	if job, exists := jm.jobs[jobID]; exists {
-		return job.Status, true
-	}
-	return nil, false
-}
-
-// ListJobs returns all jobs (optionally filtered by status)
-func (jm *JobManager) ListJobs(statusFilter string) []*TranscriptionJob {
-	var jobs []*TranscriptionJob
-	for _, job := range jm.jobs {
-		if statusFilter == "" || job.Status.Status == statusFilter {
-			jobs = append(jobs, job)
-		}
-	}
-	return jobs
-}
-
-// CleanupCompletedJobs removes completed/failed jobs older than the specified duration
-func (jm *JobManager) CleanupCompletedJobs(olderThan time.Duration) int {
-	cutoff := time.Now().Add(-olderThan)
-	cleaned := 0
-
-	for jobID, job := range jm.jobs {
-		if (job.Status.Status == "completed" || job.Status.Status == "failed") &&
-		   job.Status.EndTime != nil && job.Status.EndTime.Before(cutoff) {
-			delete(jm.jobs, jobID)
-			cleaned++
-		}
-	}
-
-	return cleaned
-}
\ No newline at end of file
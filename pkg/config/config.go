@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
@@ -22,24 +23,62 @@ type Config struct {
 	// Cloud Spanner Configuration
 	SpannerInstance string `json:"spanner_instance"`
 	SpannerDatabase string `json:"spanner_database"`
+	SchemaCheck     bool   `json:"schema_check"` // refuse to start against a database behind the shipped migrations
 
 	// Vertex AI Configuration
 	VertexAIProject  string `json:"vertex_ai_project"`
 	VertexAILocation string `json:"vertex_ai_location"`
 	VertexAIModel    string `json:"vertex_ai_model"`
 
+	// LLM Provider Configuration
+	LLMProvider   string `json:"llm_provider"`    // vertex (default), openai or fake
+	LLMBaseURL    string `json:"llm_base_url"`    // OpenAI-compatible API root, e.g. http://localhost:11434/v1
+	LLMAPIKey     string `json:"-"`               // only if the OpenAI-compatible server requires one
+	LLMModel      string `json:"llm_model"`       // overrides VertexAIModel, e.g. llama3.1:8b for a local model
+	LLMFakeScript string `json:"llm_fake_script"` // scripted answers for the fake provider
+
 	// Speech-to-Text Configuration
 	SpeechToTextProject  string `json:"speech_to_text_project"`
 	SpeechToTextLocation string `json:"speech_to_text_location"`
 	SpeechToTextModel    string `json:"speech_to_text_model"`
 	SpeechLanguage       string `json:"speech_language"`
+	SpeechAlternativeLanguages []string `json:"speech_alternative_languages"` // used when a tenant has not configured languages
 	EnableDiarization    bool   `json:"enable_diarization"`
+	FFmpegPath           string `json:"ffmpeg_path"` // decodes MP3 recordings for screening and redaction
 
 	// Cloud Storage Configuration
 	StorageProject   string `json:"storage_project"`
 	AudioBucket      string `json:"audio_bucket"`
 	StorageLocation  string `json:"storage_location"`
-	RetentionDays    int    `json:"retention_days"`
+	RetentionDays    int    `json:"retention_days"` // default for tenants without a retention policy
+
+	// Storage Backend Configuration
+	StorageBackend    string `json:"storage_backend"` // gcs, local or s3
+	LocalStorageRoot  string `json:"local_storage_root"`
+	LocalStorageURL   string `json:"local_storage_url"` // base URL signed URLs point at
+	LocalSigningKey   string `json:"-"`
+	S3Endpoint        string `json:"s3_endpoint"`
+	S3Region          string `json:"s3_region"`
+	S3AccessKeyID     string `json:"-"`
+	S3SecretAccessKey string `json:"-"`
+	S3PathStyle       bool   `json:"s3_path_style"` // MinIO and most on-prem S3 use path-style URLs
+
+	// Encryption Configuration
+	EncryptionEnabled         bool   `json:"encryption_enabled"`
+	EncryptionKMSKey          string `json:"encryption_kms_key"` // projects/*/locations/*/keyRings/*/cryptoKeys/*
+	EncryptionLocalMasterKey  string `json:"-"`                  // base64 32-byte key, for development without KMS
+	EncryptionKeyCacheSeconds int    `json:"encryption_key_cache_seconds"`
+
+	// Retention Configuration
+	RetentionPurgeEnabled          bool `json:"retention_purge_enabled"`
+	RetentionPurgeIntervalMinutes  int  `json:"retention_purge_interval_minutes"`
+	RetentionPurgeBatchSize        int  `json:"retention_purge_batch_size"`
+	TranscriptionJobRetentionHours int  `json:"transcription_job_retention_hours"` // finished jobs are kept this long for redeliveries
+
+	// Spam Scoring and Caller History Configuration
+	SpamKnownNumbers      []string `json:"spam_known_numbers"`       // numbers whose calls are spam, any format
+	SpamRepeatWindowHours int      `json:"spam_repeat_window_hours"` // how far back a caller's calls to other businesses count
+	CallerHistoryLimit    int      `json:"caller_history_limit"`     // earlier calls from the caller shown to the analysis, 0 for none
 
 	// Webhook Configuration
 	CallRailWebhookSecret string `json:"callrail_webhook_secret"`
@@ -51,6 +90,11 @@ type Config struct {
 	// Cloud Tasks Configuration
 	CloudTasksProject  string `json:"cloud_tasks_project"`
 	CloudTasksLocation string `json:"cloud_tasks_location"`
+
+	// Scheduling Configuration
+	TranscriptionConcurrency int `json:"transcription_concurrency"`
+	AnalysisConcurrency      int `json:"analysis_concurrency"`
+	TenantConcurrency        int `json:"tenant_concurrency"`
 }
 
 // DefaultConfig returns configuration with default values from environment variables
@@ -66,18 +110,28 @@ func DefaultConfig() *Config {
 		// Cloud Spanner Configuration
 		SpannerInstance: getEnvOrDefault("SPANNER_INSTANCE", "upai-customers"),
 		SpannerDatabase: getEnvOrDefault("SPANNER_DATABASE", "agent_platform"),
+		SchemaCheck:     getEnvOrDefault("SPANNER_SCHEMA_CHECK", "true") == "true",
 
 		// Vertex AI Configuration
 		VertexAIProject:  getEnvOrDefault("VERTEX_AI_PROJECT", "account-strategy-464106"),
 		VertexAILocation: getEnvOrDefault("VERTEX_AI_LOCATION", "us-central1"),
 		VertexAIModel:    getEnvOrDefault("VERTEX_AI_MODEL", "gemini-2.5-flash"),
 
+		// LLM Provider
+		LLMProvider:   getEnvOrDefault("LLM_PROVIDER", "vertex"),
+		LLMBaseURL:    getEnvOrDefault("LLM_BASE_URL", "http://localhost:11434/v1"),
+		LLMAPIKey:     getEnvOrDefault("LLM_API_KEY", ""),
+		LLMModel:      getEnvOrDefault("LLM_MODEL", ""),
+		LLMFakeScript: getEnvOrDefault("LLM_FAKE_SCRIPT", ""),
+
 		// Speech-to-Text Configuration
 		SpeechToTextProject:  getEnvOrDefault("SPEECH_TO_TEXT_PROJECT", "account-strategy-464106"),
 		SpeechToTextLocation: getEnvOrDefault("SPEECH_TO_TEXT_LOCATION", "us-central1"),
 		SpeechToTextModel:    getEnvOrDefault("SPEECH_TO_TEXT_MODEL", "chirp-3"),
 		SpeechLanguage:       getEnvOrDefault("SPEECH_LANGUAGE", "en-US"),
+		SpeechAlternativeLanguages: getEnvListOrDefault("SPEECH_ALTERNATIVE_LANGUAGES", nil),
 		EnableDiarization:    getEnvOrDefault("ENABLE_DIARIZATION", "true") == "true",
+		FFmpegPath:           getEnvOrDefault("FFMPEG_PATH", "ffmpeg"),
 
 		// Cloud Storage Configuration
 		StorageProject:  getEnvOrDefault("STORAGE_PROJECT", "account-strategy-464106"),
@@ -85,6 +139,34 @@ func DefaultConfig() *Config {
 		StorageLocation: getEnvOrDefault("STORAGE_LOCATION", "us-central1"),
 		RetentionDays:   getEnvIntOrDefault("RETENTION_DAYS", 2555),
 
+		// Storage Backend
+		StorageBackend:    getEnvOrDefault("STORAGE_BACKEND", "gcs"),
+		LocalStorageRoot:  getEnvOrDefault("LOCAL_STORAGE_ROOT", "/var/lib/ingestion-pipeline/audio"),
+		LocalStorageURL:   getEnvOrDefault("LOCAL_STORAGE_URL", "http://localhost:8080/blobs"),
+		LocalSigningKey:   getEnvOrDefault("LOCAL_STORAGE_SIGNING_KEY", ""),
+		S3Endpoint:        getEnvOrDefault("S3_ENDPOINT", "http://localhost:9000"),
+		S3Region:          getEnvOrDefault("S3_REGION", "us-east-1"),
+		S3AccessKeyID:     getEnvOrDefault("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey: getEnvOrDefault("S3_SECRET_ACCESS_KEY", ""),
+		S3PathStyle:       getEnvOrDefault("S3_PATH_STYLE", "true") == "true",
+
+		// Encryption
+		EncryptionEnabled:         getEnvOrDefault("ENCRYPTION_ENABLED", "false") == "true",
+		EncryptionKMSKey:          getEnvOrDefault("ENCRYPTION_KMS_KEY", ""),
+		EncryptionLocalMasterKey:  getEnvOrDefault("ENCRYPTION_LOCAL_MASTER_KEY", ""),
+		EncryptionKeyCacheSeconds: getEnvIntOrDefault("ENCRYPTION_KEY_CACHE_SECONDS", 600),
+
+		// Retention
+		RetentionPurgeEnabled:          getEnvOrDefault("RETENTION_PURGE_ENABLED", "false") == "true",
+		RetentionPurgeIntervalMinutes:  getEnvIntOrDefault("RETENTION_PURGE_INTERVAL_MINUTES", 60),
+		RetentionPurgeBatchSize:        getEnvIntOrDefault("RETENTION_PURGE_BATCH_SIZE", 200),
+		TranscriptionJobRetentionHours: getEnvIntOrDefault("TRANSCRIPTION_JOB_RETENTION_HOURS", 168),
+
+		// Spam Scoring and Caller History
+		SpamKnownNumbers:      getEnvListOrDefault("SPAM_KNOWN_NUMBERS", nil),
+		SpamRepeatWindowHours: getEnvIntOrDefault("SPAM_REPEAT_WINDOW_HOURS", 24),
+		CallerHistoryLimit:    getEnvIntOrDefault("CALLER_HISTORY_LIMIT", 5),
+
 		// Webhook Configuration
 		CallRailWebhookSecret: getEnvOrDefault("CALLRAIL_WEBHOOK_SECRET_NAME", "callrail-webhook-secret"),
 
@@ -95,9 +177,19 @@ func DefaultConfig() *Config {
 		// Cloud Tasks Configuration
 		CloudTasksProject:  getEnvOrDefault("CLOUD_TASKS_PROJECT", "account-strategy-464106"),
 		CloudTasksLocation: getEnvOrDefault("CLOUD_TASKS_LOCATION", "us-central1"),
+
+		// Scheduling Configuration
+		TranscriptionConcurrency: getEnvIntOrDefault("TRANSCRIPTION_CONCURRENCY", 5),
+		AnalysisConcurrency:      getEnvIntOrDefault("ANALYSIS_CONCURRENCY", 10),
+		TenantConcurrency:        getEnvIntOrDefault("TENANT_CONCURRENCY", 3),
 	}
 }
 
+// SpannerDatabasePath returns the fully qualified Spanner database name
+func (c *Config) SpannerDatabasePath() string {
+	return fmt.Sprintf("projects/%s/instances/%s/databases/%s", c.ProjectID, c.SpannerInstance, c.SpannerDatabase)
+}
+
 // LoadSecrets loads sensitive configuration from Google Secret Manager
 func (c *Config) LoadSecrets(ctx context.Context) error {
 	if c.Environment == "development" {
@@ -151,4 +243,19 @@ func getEnvIntOrDefault(key string, defaultValue int) int {
 		}
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+// getEnvListOrDefault returns a comma-separated environment variable as a list or default
+func getEnvListOrDefault(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
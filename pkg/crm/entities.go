@@ -0,0 +1,88 @@
+package crm
+
+import (
+	"strings"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// MinEntityConfidence is the confidence an extracted entity needs before it is put on a lead
+const MinEntityConfidence = 0.6
+
+// NewLeadFromCall starts a lead from what CallRail knows about a call
+func NewLeadFromCall(tenantID string, call *models.CallDetails) *Lead {
+	lead := &Lead{
+		TenantID:     tenantID,
+		Source:       "callrail",
+		Phone:        call.CustomerPhoneNumber,
+		City:         call.CustomerCity,
+		State:        call.CustomerState,
+		Country:      call.CustomerCountry,
+		CallID:       call.ID,
+		CustomFields: make(map[string]interface{}),
+		CreatedAt:    call.StartTime,
+	}
+	lead.setName(call.CustomerName)
+	return lead
+}
+
+// ApplyEntities merges the contact details a caller gave during the call into the lead.
+//
+// The caller's own words beat CallRail's caller ID name and phone-number location, which
+// describe the line rather than the person, so a spoken name, city or state replaces them; the
+// caller ID name is kept in the custom fields. Email, street, ZIP code and callback time only
+// fill empty fields, since a value already there came from a form or the CRM. Entities below
+// MinEntityConfidence are ignored.
+func (l *Lead) ApplyEntities(entities *models.CallEntities) {
+	if entities == nil {
+		return
+	}
+
+	if name := confidentValue(entities.CustomerName); name != "" {
+		callerID := l.FullName
+		if callerID == "" {
+			callerID = strings.TrimSpace(l.FirstName + " " + l.LastName)
+		}
+		if callerID != "" && !strings.EqualFold(callerID, name) {
+			if l.CustomFields == nil {
+				l.CustomFields = make(map[string]interface{})
+			}
+			l.CustomFields["caller_id_name"] = callerID
+		}
+		l.setName(name)
+	}
+	if email := confidentValue(entities.Email); email != "" && l.Email == "" {
+		l.Email = email
+	}
+	if callback := confidentValue(entities.CallbackTime); callback != "" && l.CallbackTime == "" {
+		l.CallbackTime = callback
+	}
+
+	if address := entities.Address; address != nil && address.Confidence >= MinEntityConfidence {
+		if l.Address == "" {
+			l.Address = address.Street
+		}
+		if l.ZipCode == "" {
+			l.ZipCode = address.ZipCode
+		}
+		if address.City != "" {
+			l.City = address.City
+		}
+		if address.State != "" {
+			l.State = address.State
+		}
+	}
+}
+
+// setName sets the full name and splits it into a first name and the rest
+func (l *Lead) setName(name string) {
+	l.FullName = strings.Join(strings.Fields(name), " ")
+	l.FirstName, l.LastName, _ = strings.Cut(l.FullName, " ")
+}
+
+func confidentValue(entity *models.ExtractedEntity) string {
+	if entity == nil || entity.Confidence < MinEntityConfidence {
+		return ""
+	}
+	return entity.Value
+}
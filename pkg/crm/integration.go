@@ -4,12 +4,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
 )
 
 // CRMProvider defines the interface for CRM integrations
@@ -22,6 +25,15 @@ type CRMProvider interface {
 	TestConnection(ctx context.Context, config *Config) error
 }
 
+// ErrDeleteNotSupported is returned when a CRM provider can't delete leads through its API
+var ErrDeleteNotSupported = errors.New("CRM provider does not support lead deletion")
+
+// LeadDeleter is implemented by providers that can permanently delete a lead, as needed for data
+// subject erasure requests
+type LeadDeleter interface {
+	DeleteLead(ctx context.Context, leadID string, config *Config) error
+}
+
 // Config contains CRM integration configuration
 type Config struct {
 	Provider       string            `json:"provider"`
@@ -56,6 +68,7 @@ type Lead struct {
 	Email              string                 `json:"email,omitempty"`
 	Phone              string                 `json:"phone"`
 	MobilePhone        string                 `json:"mobile_phone,omitempty"`
+	CallbackTime       string                 `json:"callback_time,omitempty"` // when the caller asked to be called back
 
 	// Address Information
 	Address            string                 `json:"address,omitempty"`
@@ -82,6 +95,12 @@ type Lead struct {
 	Sentiment          string                 `json:"sentiment,omitempty"`
 	Intent             string                 `json:"intent,omitempty"`
 
+	// Call Summary, for the rep following up
+	Summary            string                 `json:"summary,omitempty"`
+	ActionItems        []string               `json:"action_items,omitempty"`
+	Commitments        []string               `json:"commitments,omitempty"`
+	OpenQuestions      []string               `json:"open_questions,omitempty"`
+
 	// Metadata
 	Notes              string                 `json:"notes,omitempty"`
 	Tags               []string               `json:"tags,omitempty"`
@@ -95,6 +114,75 @@ type Lead struct {
 	TeamID             string                 `json:"team_id,omitempty"`
 }
 
+// ApplySummary copies a call summary onto the lead
+func (l *Lead) ApplySummary(summary *models.CallSummary) {
+	if summary == nil {
+		return
+	}
+	l.Summary = summary.Summary
+	l.ActionItems = summary.ActionItems
+	l.Commitments = summary.Commitments
+	l.OpenQuestions = summary.OpenQuestions
+}
+
+// SummaryNotes returns the call summary as blocks of notes, for CRMs without fields for it
+func (l *Lead) SummaryNotes() string {
+	var notes []string
+	for _, section := range l.summarySections() {
+		if !section.empty() {
+			notes = append(notes, section.note())
+		}
+	}
+	return strings.Join(notes, "\n")
+}
+
+// summarySections are the call summary parts of a lead, in the order a rep reads them
+func (l *Lead) summarySections() []noteSection {
+	return []noteSection{
+		{field: "summary", title: "Summary", text: l.Summary},
+		{field: "action_items", title: "Action Items", items: l.ActionItems},
+		{field: "commitments", title: "Commitments", items: l.Commitments},
+		{field: "open_questions", title: "Open Questions", items: l.OpenQuestions},
+	}
+}
+
+// noteSection is a part of a lead that goes to its own CRM field when the field mapping names
+// one, and into the notes otherwise
+type noteSection struct {
+	field string // the lead field name used as the field mapping key
+	title string
+	text  string
+	items []string
+}
+
+func (n noteSection) empty() bool {
+	return n.text == "" && len(n.items) == 0
+}
+
+// value is the section as a CRM field value
+func (n noteSection) value() string {
+	if n.text != "" {
+		return n.text
+	}
+	return strings.Join(bulleted(n.items), "\n")
+}
+
+// note is the section as a block of the notes
+func (n noteSection) note() string {
+	if n.text != "" {
+		return fmt.Sprintf("%s: %s", n.title, n.text)
+	}
+	return n.title + ":\n" + strings.Join(bulleted(n.items), "\n")
+}
+
+func bulleted(items []string) []string {
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = "- " + item
+	}
+	return lines
+}
+
 // Response represents a standardized CRM response
 type Response struct {
 	Success     bool                   `json:"success"`
@@ -160,6 +248,21 @@ func (m *Manager) GetProvider(name string) (CRMProvider, error) {
 	return provider, nil
 }
 
+// DeleteLead permanently deletes a lead with the named provider, returning ErrDeleteNotSupported
+// if the provider can't
+func (m *Manager) DeleteLead(ctx context.Context, providerName, leadID string, config *Config) error {
+	provider, err := m.GetProvider(providerName)
+	if err != nil {
+		return err
+	}
+
+	deleter, ok := provider.(LeadDeleter)
+	if !ok {
+		return ErrDeleteNotSupported
+	}
+	return deleter.DeleteLead(ctx, leadID, config)
+}
+
 // ListProviders returns all registered provider names
 func (m *Manager) ListProviders() []string {
 	var names []string
@@ -181,6 +284,11 @@ func NewHubSpotProvider() *HubSpotProvider {
 	}
 }
 
+// NewHubSpotProviderWithHTTP creates a HubSpot provider that sends its requests with the given client
+func NewHubSpotProviderWithHTTP(client *http.Client) *HubSpotProvider {
+	return &HubSpotProvider{client: client}
+}
+
 func (h *HubSpotProvider) GetName() string {
 	return "hubspot"
 }
@@ -325,6 +433,21 @@ func (h *HubSpotProvider) SearchLeads(ctx context.Context, query *SearchQuery, c
 	return result, nil
 }
 
+// DeleteLead permanently deletes a contact with HubSpot's GDPR delete, which also blocks the
+// contact's email from being re-added
+func (h *HubSpotProvider) DeleteLead(ctx context.Context, leadID string, config *Config) error {
+	endpoint := "https://api.hubapi.com/crm/v3/objects/contacts/gdpr-delete"
+
+	payload := map[string]interface{}{
+		"objectId": leadID,
+	}
+
+	if _, err := h.makeRequest(ctx, "POST", endpoint, payload, config); err != nil {
+		return fmt.Errorf("failed to delete HubSpot contact: %w", err)
+	}
+	return nil
+}
+
 func (h *HubSpotProvider) TestConnection(ctx context.Context, config *Config) error {
 	endpoint := "https://api.hubapi.com/crm/v3/objects/contacts"
 	params := url.Values{}
@@ -385,6 +508,7 @@ func (h *HubSpotProvider) mapLeadToHubSpot(lead *Lead, fieldMapping map[string]s
 		"city":       "city",
 		"state":      "state",
 		"zip_code":   "zip",
+		"address":    "address",
 		"notes":      "notes",
 	}
 
@@ -419,14 +543,37 @@ func (h *HubSpotProvider) mapLeadToHubSpot(lead *Lead, fieldMapping map[string]s
 	if lead.ZipCode != "" && mappings["zip_code"] != "" {
 		properties[mappings["zip_code"]] = lead.ZipCode
 	}
+	if lead.Address != "" && mappings["address"] != "" {
+		properties[mappings["address"]] = lead.Address
+	}
+	if lead.CallbackTime != "" && mappings["callback_time"] != "" {
+		properties[mappings["callback_time"]] = lead.CallbackTime
+	}
 
 	// Add lead score if mapping exists
 	if mappings["lead_score"] != "" {
 		properties[mappings["lead_score"]] = lead.LeadScore
 	}
 
+	// Summary sections go to their mapped fields, or lead the notes
+	var summaryNotes []string
+	for _, section := range lead.summarySections() {
+		if section.empty() {
+			continue
+		}
+		if mappings[section.field] != "" {
+			properties[mappings[section.field]] = section.value()
+		} else {
+			summaryNotes = append(summaryNotes, section.note())
+		}
+	}
+
+	if lead.Notes != "" {
+		summaryNotes = append(summaryNotes, lead.Notes)
+	}
+	notes := strings.Join(summaryNotes, "\n")
+
 	// Add project type as a note if no specific field
-	notes := lead.Notes
 	if lead.ProjectType != "" {
 		if notes != "" {
 			notes += "\n"
@@ -439,6 +586,12 @@ func (h *HubSpotProvider) mapLeadToHubSpot(lead *Lead, fieldMapping map[string]s
 		}
 		notes += fmt.Sprintf("Timeline: %s", lead.Timeline)
 	}
+	if lead.CallbackTime != "" && mappings["callback_time"] == "" {
+		if notes != "" {
+			notes += "\n"
+		}
+		notes += fmt.Sprintf("Preferred Callback: %s", lead.CallbackTime)
+	}
 	if notes != "" && mappings["notes"] != "" {
 		properties[mappings["notes"]] = notes
 	}
@@ -478,6 +631,7 @@ func (h *HubSpotProvider) mapHubSpotToLead(hubspotData map[string]interface{}, f
 			"city":      "city",
 			"state":     "state",
 			"zip":       "zip_code",
+			"address":   "address",
 			"notes":     "notes",
 		}
 
@@ -519,10 +673,22 @@ func (h *HubSpotProvider) mapHubSpotToLead(hubspotData map[string]interface{}, f
 					if str, ok := value.(string); ok {
 						lead.ZipCode = str
 					}
+				case "address":
+					if str, ok := value.(string); ok {
+						lead.Address = str
+					}
+				case "callback_time":
+					if str, ok := value.(string); ok {
+						lead.CallbackTime = str
+					}
 				case "notes":
 					if str, ok := value.(string); ok {
 						lead.Notes = str
 					}
+				case "summary":
+					if str, ok := value.(string); ok {
+						lead.Summary = str
+					}
 				default:
 					lead.CustomFields[leadField] = value
 				}
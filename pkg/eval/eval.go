@@ -0,0 +1,359 @@
+// Package eval measures analysis quality against a labeled dataset of call transcripts, so a
+// prompt or model change can be compared with what it replaces before it ships.
+package eval
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/ai"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// Labeled fields and the correlations reported for them
+const (
+	FieldIntent        = "intent"
+	FieldProjectType   = "project_type"
+	FieldSpam          = "spam"
+	FieldLeadScoreBand = "lead_score_band"
+
+	CorrelationLeadScore = "lead_score~band"      // predicted lead score against the labeled band
+	CorrelationSpam      = "spam_likelihood~spam" // predicted spam likelihood against the spam label
+)
+
+// Lead score bands, from the 1-100 lead score
+const (
+	BandLow    = "low"    // 1-39
+	BandMedium = "medium" // 40-69
+	BandHigh   = "high"   // 70-100
+)
+
+// errorLabel is predicted for every field of an example whose analysis failed
+const errorLabel = "<error>"
+
+// Example is one labeled call of an evaluation dataset
+type Example struct {
+	ID           string                       `json:"id"`
+	Transcript   string                       `json:"transcript"`
+	Language     string                       `json:"language,omitempty"`
+	CallDetails  *models.CallDetails          `json:"call_details,omitempty"`
+	PromptConfig *models.AnalysisPromptConfig `json:"prompt_config,omitempty"` // the tenant configuration the call is analyzed with
+	Expected     Labels                       `json:"expected"`
+}
+
+// Labels are the expected analysis results of an example; empty labels are not scored
+type Labels struct {
+	Intent        string `json:"intent,omitempty"`
+	ProjectType   string `json:"project_type,omitempty"`
+	Spam          *bool  `json:"spam,omitempty"`
+	LeadScoreBand string `json:"lead_score_band,omitempty"` // low, medium or high
+}
+
+// Variant is a prompt and model combination to evaluate
+type Variant struct {
+	Name            string `json:"name"`
+	Model           string `json:"model"`
+	TemplateVersion int    `json:"template_version,omitempty"` // 0 uses the latest template
+	Combined        bool   `json:"combined,omitempty"`         // one combined analysis instead of separate content and spam calls
+}
+
+// Analyzer runs an analysis; *ai.AnalysisService is the production implementation
+type Analyzer interface {
+	AnalyzeContent(ctx context.Context, req *ai.AnalysisRequest) (*ai.AnalysisResponse, error)
+}
+
+// Prediction is what a variant produced for an example
+type Prediction struct {
+	ExampleID      string        `json:"example_id"`
+	Intent         string        `json:"intent,omitempty"`
+	ProjectType    string        `json:"project_type,omitempty"`
+	LeadScore      int           `json:"lead_score,omitempty"`
+	SpamLikelihood float64       `json:"spam_likelihood,omitempty"`
+	Usage          ai.TokenUsage `json:"usage"`
+	Error          string        `json:"error,omitempty"`
+}
+
+// Runner evaluates variants over a dataset
+type Runner struct {
+	Concurrency   int     // analyses in flight at once
+	SpamThreshold float64 // spam likelihood at which a call counts as spam
+}
+
+// DefaultRunner returns a runner with default settings
+func DefaultRunner() *Runner {
+	return &Runner{
+		Concurrency:   4,
+		SpamThreshold: 50,
+	}
+}
+
+// LoadDataset reads a JSONL dataset, one example per line. Blank lines and lines starting
+// with # are skipped.
+func LoadDataset(r io.Reader) ([]Example, error) {
+	var examples []Example
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		var example Example
+		if err := json.Unmarshal([]byte(text), &example); err != nil {
+			return nil, fmt.Errorf("line %d: failed to parse example: %w", line, err)
+		}
+		if err := validateExample(&example); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if seen[example.ID] {
+			return nil, fmt.Errorf("line %d: duplicate example id %q", line, example.ID)
+		}
+		seen[example.ID] = true
+		examples = append(examples, example)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+	if len(examples) == 0 {
+		return nil, fmt.Errorf("dataset has no examples")
+	}
+
+	return examples, nil
+}
+
+// LoadDatasetFile reads a JSONL dataset file
+func LoadDatasetFile(path string) ([]Example, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dataset: %w", err)
+	}
+	defer f.Close()
+
+	return LoadDataset(f)
+}
+
+func validateExample(example *Example) error {
+	if example.ID == "" {
+		return fmt.Errorf("example has no id")
+	}
+	if strings.TrimSpace(example.Transcript) == "" {
+		return fmt.Errorf("example %s has no transcript", example.ID)
+	}
+
+	labels := example.Expected
+	if labels.Intent == "" && labels.ProjectType == "" && labels.Spam == nil && labels.LeadScoreBand == "" {
+		return fmt.Errorf("example %s has no expected labels", example.ID)
+	}
+	switch labels.LeadScoreBand {
+	case "", BandLow, BandMedium, BandHigh:
+	default:
+		return fmt.Errorf("example %s has unknown lead score band %q", example.ID, labels.LeadScoreBand)
+	}
+
+	return nil
+}
+
+// LeadScoreBand returns the band of a 1-100 lead score
+func LeadScoreBand(score int) string {
+	switch {
+	case score >= 70:
+		return BandHigh
+	case score >= 40:
+		return BandMedium
+	default:
+		return BandLow
+	}
+}
+
+// Run analyzes every example with a variant and scores the predictions
+func (r *Runner) Run(ctx context.Context, analyzer Analyzer, variant Variant, examples []Example) (*Report, error) {
+	predictions := make([]Prediction, len(examples))
+
+	concurrency := r.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i := range examples {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			predictions[i] = r.predict(ctx, analyzer, variant, &examples[i])
+		}(i)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("evaluation of %s interrupted: %w", variant.Name, err)
+	}
+
+	return r.Score(variant, examples, predictions), nil
+}
+
+// predict runs the analyses an example's labels need
+func (r *Runner) predict(ctx context.Context, analyzer Analyzer, variant Variant, example *Example) Prediction {
+	prediction := Prediction{ExampleID: example.ID}
+	labels := example.Expected
+
+	request := func(analysisType ai.AnalysisType) *ai.AnalysisRequest {
+		return &ai.AnalysisRequest{
+			CallID:          example.ID,
+			Transcription:   example.Transcript,
+			CallDetails:     example.CallDetails,
+			AnalysisType:    analysisType,
+			Language:        example.Language,
+			PromptConfig:    example.PromptConfig,
+			TemplateVersion: variant.TemplateVersion,
+		}
+	}
+
+	if variant.Combined {
+		resp, err := analyzer.AnalyzeContent(ctx, request(ai.AnalysisTypeCombined))
+		addUsage(&prediction, resp)
+		if err != nil {
+			prediction.Error = fmt.Sprintf("combined analysis: %v", err)
+			return prediction
+		}
+		prediction.Intent = resp.CallAnalysis.Intent
+		prediction.ProjectType = resp.CallAnalysis.ProjectType
+		prediction.LeadScore = resp.CallAnalysis.LeadScore
+		prediction.SpamLikelihood = resp.SpamResult.SpamLikelihood
+		return prediction
+	}
+
+	if labels.Intent != "" || labels.ProjectType != "" || labels.LeadScoreBand != "" {
+		resp, err := analyzer.AnalyzeContent(ctx, request(ai.AnalysisTypeContent))
+		addUsage(&prediction, resp)
+		if err != nil {
+			prediction.Error = fmt.Sprintf("content analysis: %v", err)
+			return prediction
+		}
+		prediction.Intent = resp.CallAnalysis.Intent
+		prediction.ProjectType = resp.CallAnalysis.ProjectType
+		prediction.LeadScore = resp.CallAnalysis.LeadScore
+	}
+
+	if labels.Spam != nil {
+		resp, err := analyzer.AnalyzeContent(ctx, request(ai.AnalysisTypeSpam))
+		addUsage(&prediction, resp)
+		if err != nil {
+			prediction.Error = fmt.Sprintf("spam detection: %v", err)
+			return prediction
+		}
+		prediction.SpamLikelihood = resp.SpamResult.SpamLikelihood
+	}
+
+	return prediction
+}
+
+func addUsage(prediction *Prediction, resp *ai.AnalysisResponse) {
+	if resp == nil {
+		return
+	}
+	prediction.Usage.InputTokens += resp.Metadata.InputTokens
+	prediction.Usage.OutputTokens += resp.Metadata.OutputTokens
+}
+
+// Score computes a variant's metrics from its predictions, matched to examples by position
+func (r *Runner) Score(variant Variant, examples []Example, predictions []Prediction) *Report {
+	report := &Report{
+		Variant:      variant,
+		Examples:     len(examples),
+		Fields:       make(map[string]*FieldMetrics),
+		Correlations: make(map[string]float64),
+		Predictions:  predictions,
+	}
+
+	pairs := make(map[string][][2]string)
+	var leadScores, leadBands, spamLikelihoods, spamLabels []float64
+
+	for i, example := range examples {
+		prediction := predictions[i]
+		report.Usage.InputTokens += prediction.Usage.InputTokens
+		report.Usage.OutputTokens += prediction.Usage.OutputTokens
+		failed := prediction.Error != ""
+		if failed {
+			report.Failures++
+		}
+
+		labels := example.Expected
+		predicted := func(value string) string {
+			if failed {
+				return errorLabel
+			}
+			return value
+		}
+
+		if labels.Intent != "" {
+			pairs[FieldIntent] = append(pairs[FieldIntent], [2]string{labels.Intent, predicted(prediction.Intent)})
+		}
+		if labels.ProjectType != "" {
+			pairs[FieldProjectType] = append(pairs[FieldProjectType], [2]string{labels.ProjectType, predicted(prediction.ProjectType)})
+		}
+		if labels.LeadScoreBand != "" {
+			pairs[FieldLeadScoreBand] = append(pairs[FieldLeadScoreBand], [2]string{labels.LeadScoreBand, predicted(LeadScoreBand(prediction.LeadScore))})
+			if !failed {
+				leadScores = append(leadScores, float64(prediction.LeadScore))
+				leadBands = append(leadBands, bandRank(labels.LeadScoreBand))
+			}
+		}
+		if labels.Spam != nil {
+			pairs[FieldSpam] = append(pairs[FieldSpam], [2]string{spamLabel(*labels.Spam), predicted(spamLabel(prediction.SpamLikelihood >= r.SpamThreshold))})
+			if !failed {
+				spamLikelihoods = append(spamLikelihoods, prediction.SpamLikelihood)
+				spamLabels = append(spamLabels, boolValue(*labels.Spam))
+			}
+		}
+	}
+
+	for field, fieldPairs := range pairs {
+		report.Fields[field] = ScoreField(fieldPairs)
+	}
+	if len(leadScores) > 1 {
+		report.Correlations[CorrelationLeadScore] = Spearman(leadScores, leadBands)
+	}
+	if len(spamLikelihoods) > 1 {
+		report.Correlations[CorrelationSpam] = Spearman(spamLikelihoods, spamLabels)
+	}
+
+	return report
+}
+
+func bandRank(band string) float64 {
+	switch band {
+	case BandHigh:
+		return 3
+	case BandMedium:
+		return 2
+	default:
+		return 1
+	}
+}
+
+func spamLabel(spam bool) string {
+	if spam {
+		return "spam"
+	}
+	return "not_spam"
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,141 @@
+package eval
+
+import (
+	"math"
+	"sort"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/ai"
+)
+
+// Report is the evaluation of one variant
+type Report struct {
+	Variant      Variant                  `json:"variant"`
+	Examples     int                      `json:"examples"`
+	Failures     int                      `json:"failures"` // examples whose analysis failed; scored as wrong
+	Fields       map[string]*FieldMetrics `json:"fields"`
+	Correlations map[string]float64       `json:"correlations"` // Spearman rank correlation
+	Usage        ai.TokenUsage            `json:"usage"`
+	Predictions  []Prediction             `json:"predictions,omitempty"`
+}
+
+// FieldMetrics scores the predictions of one labeled field
+type FieldMetrics struct {
+	Support   int                       `json:"support"`
+	Accuracy  float64                   `json:"accuracy"`
+	MacroF1   float64                   `json:"macro_f1"` // mean F1 over the expected classes
+	Classes   map[string]ClassMetrics   `json:"classes"`
+	Confusion map[string]map[string]int `json:"confusion"` // expected -> predicted -> count
+}
+
+// ClassMetrics scores one class of a field
+type ClassMetrics struct {
+	Support   int     `json:"support"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+}
+
+// ScoreField computes accuracy, per-class precision, recall and F1, and the confusion matrix
+// of expected and predicted label pairs
+func ScoreField(pairs [][2]string) *FieldMetrics {
+	metrics := &FieldMetrics{
+		Support:   len(pairs),
+		Classes:   make(map[string]ClassMetrics),
+		Confusion: make(map[string]map[string]int),
+	}
+	if len(pairs) == 0 {
+		return metrics
+	}
+
+	correct := 0
+	predictedCount := make(map[string]int)
+	for _, pair := range pairs {
+		expected, predicted := pair[0], pair[1]
+		if metrics.Confusion[expected] == nil {
+			metrics.Confusion[expected] = make(map[string]int)
+		}
+		metrics.Confusion[expected][predicted]++
+		predictedCount[predicted]++
+		if expected == predicted {
+			correct++
+		}
+	}
+	metrics.Accuracy = float64(correct) / float64(len(pairs))
+
+	// Classes are the expected labels; predictions outside them only cost precision elsewhere
+	var f1Sum float64
+	for class, row := range metrics.Confusion {
+		support := 0
+		for _, count := range row {
+			support += count
+		}
+		truePositives := row[class]
+
+		scores := ClassMetrics{Support: support}
+		if predictedCount[class] > 0 {
+			scores.Precision = float64(truePositives) / float64(predictedCount[class])
+		}
+		scores.Recall = float64(truePositives) / float64(support)
+		if scores.Precision+scores.Recall > 0 {
+			scores.F1 = 2 * scores.Precision * scores.Recall / (scores.Precision + scores.Recall)
+		}
+		f1Sum += scores.F1
+		metrics.Classes[class] = scores
+	}
+	metrics.MacroF1 = f1Sum / float64(len(metrics.Classes))
+
+	return metrics
+}
+
+// Spearman returns the Spearman rank correlation of two equally long series, with ties
+// given their average rank. It is 0 when either series is constant.
+func Spearman(x, y []float64) float64 {
+	if len(x) != len(y) || len(x) < 2 {
+		return 0
+	}
+	return pearson(ranks(x), ranks(y))
+}
+
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return values[order[a]] < values[order[b]] })
+
+	ranked := make([]float64, len(values))
+	for start := 0; start < len(order); {
+		end := start
+		for end+1 < len(order) && values[order[end+1]] == values[order[start]] {
+			end++
+		}
+		average := float64(start+end)/2 + 1
+		for i := start; i <= end; i++ {
+			ranked[order[i]] = average
+		}
+		start = end + 1
+	}
+	return ranked
+}
+
+func pearson(x, y []float64) float64 {
+	n := float64(len(x))
+	var sumX, sumY float64
+	for i := range x {
+		sumX += x[i]
+		sumY += y[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var covariance, varianceX, varianceY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		covariance += dx * dy
+		varianceX += dx * dx
+		varianceY += dy * dy
+	}
+	if varianceX == 0 || varianceY == 0 {
+		return 0
+	}
+	return covariance / math.Sqrt(varianceX*varianceY)
+}
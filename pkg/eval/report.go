@@ -0,0 +1,165 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Comparison sets a candidate variant's metrics against a baseline's
+type Comparison struct {
+	Baseline      *Report         `json:"baseline"`
+	Candidate     *Report         `json:"candidate"`
+	MaxRegression float64         `json:"max_regression"`
+	Rows          []ComparisonRow `json:"rows"`
+}
+
+// ComparisonRow is one metric of a comparison
+type ComparisonRow struct {
+	Metric    string  `json:"metric"`
+	Baseline  float64 `json:"baseline"`
+	Candidate float64 `json:"candidate"`
+	Delta     float64 `json:"delta"`
+	Regressed bool    `json:"regressed"` // dropped by more than the allowed regression
+}
+
+// Compare compares every metric of the baseline with the candidate. A metric regresses when
+// the candidate is more than maxRegression below the baseline, e.g. 0.02 for two points.
+func Compare(baseline, candidate *Report, maxRegression float64) *Comparison {
+	comparison := &Comparison{
+		Baseline:      baseline,
+		Candidate:     candidate,
+		MaxRegression: maxRegression,
+	}
+
+	add := func(metric string, before, after float64) {
+		delta := after - before
+		comparison.Rows = append(comparison.Rows, ComparisonRow{
+			Metric:    metric,
+			Baseline:  before,
+			Candidate: after,
+			Delta:     delta,
+			Regressed: delta < -maxRegression-1e-9,
+		})
+	}
+
+	for _, field := range sortedKeys(baseline.Fields) {
+		before := baseline.Fields[field]
+		after := candidate.Fields[field]
+		if after == nil {
+			after = &FieldMetrics{}
+		}
+		add(field+" accuracy", before.Accuracy, after.Accuracy)
+		add(field+" macro_f1", before.MacroF1, after.MacroF1)
+	}
+	for _, name := range sortedKeys(baseline.Correlations) {
+		add(name+" correlation", baseline.Correlations[name], candidate.Correlations[name])
+	}
+
+	return comparison
+}
+
+// Regressions returns the metrics that regressed
+func (c *Comparison) Regressions() []ComparisonRow {
+	var regressed []ComparisonRow
+	for _, row := range c.Rows {
+		if row.Regressed {
+			regressed = append(regressed, row)
+		}
+	}
+	return regressed
+}
+
+// WriteReport writes a variant's metrics and confusion matrices as text
+func WriteReport(w io.Writer, report *Report) error {
+	mode := "separate"
+	if report.Variant.Combined {
+		mode = "combined"
+	}
+	fmt.Fprintf(w, "Variant %s (model %s, template %s, %s analyses)\n", report.Variant.Name, report.Variant.Model, templateLabel(report.Variant.TemplateVersion), mode)
+	fmt.Fprintf(w, "Examples: %d, failed: %d, tokens: %d in / %d out\n\n",
+		report.Examples, report.Failures, report.Usage.InputTokens, report.Usage.OutputTokens)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tSUPPORT\tACCURACY\tMACRO F1")
+	for _, field := range sortedKeys(report.Fields) {
+		metrics := report.Fields[field]
+		fmt.Fprintf(tw, "%s\t%d\t%.3f\t%.3f\n", field, metrics.Support, metrics.Accuracy, metrics.MacroF1)
+	}
+	for _, name := range sortedKeys(report.Correlations) {
+		fmt.Fprintf(tw, "%s\t\tspearman %.3f\t\n", name, report.Correlations[name])
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, field := range sortedKeys(report.Fields) {
+		fmt.Fprintf(w, "\nConfusion matrix for %s (rows expected, columns predicted)\n", field)
+		if err := writeConfusion(w, report.Fields[field]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeConfusion(w io.Writer, metrics *FieldMetrics) error {
+	expected := sortedKeys(metrics.Confusion)
+	columns := map[string]bool{}
+	for _, row := range metrics.Confusion {
+		for predicted := range row {
+			columns[predicted] = true
+		}
+	}
+	predicted := sortedKeys(columns)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "\t%s\tRECALL\t\n", strings.Join(predicted, "\t"))
+	for _, class := range expected {
+		cells := make([]string, len(predicted))
+		for i, column := range predicted {
+			cells[i] = fmt.Sprint(metrics.Confusion[class][column])
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.3f\t\n", class, strings.Join(cells, "\t"), metrics.Classes[class].Recall)
+	}
+	return tw.Flush()
+}
+
+// WriteComparison writes the baseline and candidate metrics side by side
+func WriteComparison(w io.Writer, comparison *Comparison) error {
+	fmt.Fprintf(w, "Baseline %s vs candidate %s (max regression %.3f)\n\n",
+		comparison.Baseline.Variant.Name, comparison.Candidate.Variant.Name, comparison.MaxRegression)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "METRIC\t%s\t%s\tDELTA\t\n", strings.ToUpper(comparison.Baseline.Variant.Name), strings.ToUpper(comparison.Candidate.Variant.Name))
+	for _, row := range comparison.Rows {
+		status := ""
+		if row.Regressed {
+			status = "REGRESSED"
+		}
+		fmt.Fprintf(tw, "%s\t%.3f\t%.3f\t%+.3f\t%s\n", row.Metric, row.Baseline, row.Candidate, row.Delta, status)
+	}
+	fmt.Fprintf(tw, "failures\t%d\t%d\t%+d\t\n", comparison.Baseline.Failures, comparison.Candidate.Failures,
+		comparison.Candidate.Failures-comparison.Baseline.Failures)
+	fmt.Fprintf(tw, "output tokens\t%d\t%d\t%+d\t\n", comparison.Baseline.Usage.OutputTokens, comparison.Candidate.Usage.OutputTokens,
+		comparison.Candidate.Usage.OutputTokens-comparison.Baseline.Usage.OutputTokens)
+	return tw.Flush()
+}
+
+func templateLabel(version int) string {
+	if version == 0 {
+		return "latest"
+	}
+	return fmt.Sprintf("v%d", version)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
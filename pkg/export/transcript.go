@@ -0,0 +1,329 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// Format is a transcript export format
+type Format string
+
+const (
+	FormatWebVTT Format = "vtt"
+	FormatSRT    Format = "srt"
+	FormatText   Format = "txt"
+	FormatJSON   Format = "json"
+)
+
+// Options controls how transcripts are split and labelled
+type Options struct {
+	SpeakerLabels  map[int]string `json:"speaker_labels,omitempty"` // overrides the Agent/Customer defaults
+	MaxCueDuration time.Duration  `json:"max_cue_duration"`
+	MaxCueChars    int            `json:"max_cue_chars"`
+	CueGap         time.Duration  `json:"cue_gap"`       // a pause this long starts a new caption
+	ParagraphGap   time.Duration  `json:"paragraph_gap"` // a pause this long starts a new paragraph
+}
+
+// DefaultOptions returns export defaults suited to call playback
+func DefaultOptions() *Options {
+	return &Options{
+		MaxCueDuration: 6 * time.Second,
+		MaxCueChars:    84, // two caption lines
+		CueGap:         time.Second,
+		ParagraphGap:   3 * time.Second,
+	}
+}
+
+// Cue is a single caption
+type Cue struct {
+	Index   int
+	Start   time.Duration
+	End     time.Duration
+	Speaker int
+	Label   string
+	Text    string
+}
+
+// Paragraph is a run of speech from one speaker
+type Paragraph struct {
+	Speaker int    `json:"speaker"`
+	Label   string `json:"label,omitempty"`
+	StartMs int64  `json:"start_ms"`
+	EndMs   int64  `json:"end_ms"`
+	Text    string `json:"text"`
+}
+
+// ParagraphDocument is the paragraph-level JSON export
+type ParagraphDocument struct {
+	DurationMs int64          `json:"duration_ms"`
+	Speakers   map[int]string `json:"speakers"`
+	Paragraphs []Paragraph    `json:"paragraphs"`
+}
+
+// token is a word with timing and speaker
+type token struct {
+	text    string
+	start   time.Duration
+	end     time.Duration
+	speaker int
+}
+
+// Render exports a transcript in the requested format, returning the body and its content type
+func Render(result *models.TranscriptionResult, format Format, opts *Options) ([]byte, string, error) {
+	switch format {
+	case FormatWebVTT:
+		return []byte(WebVTT(result, opts)), "text/vtt; charset=utf-8", nil
+	case FormatSRT:
+		return []byte(SRT(result, opts)), "application/x-subrip; charset=utf-8", nil
+	case FormatText:
+		return []byte(Text(result, opts)), "text/plain; charset=utf-8", nil
+	case FormatJSON:
+		data, err := json.Marshal(Paragraphs(result, opts))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to marshal paragraphs: %w", err)
+		}
+		return data, "application/json; charset=utf-8", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// WebVTT renders the transcript as WebVTT captions with voice spans for speakers
+func WebVTT(result *models.TranscriptionResult, opts *Options) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, cue := range Cues(result, opts) {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n", cue.Index, formatTimestamp(cue.Start, "."), formatTimestamp(cue.End, "."))
+		if cue.Label != "" {
+			fmt.Fprintf(&b, "<v %s>%s\n\n", cue.Label, cue.Text)
+		} else {
+			fmt.Fprintf(&b, "%s\n\n", cue.Text)
+		}
+	}
+	return b.String()
+}
+
+// SRT renders the transcript as SubRip captions prefixed with speaker labels
+func SRT(result *models.TranscriptionResult, opts *Options) string {
+	var b strings.Builder
+	for _, cue := range Cues(result, opts) {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n", cue.Index, formatTimestamp(cue.Start, ","), formatTimestamp(cue.End, ","))
+		if cue.Label != "" {
+			fmt.Fprintf(&b, "%s: %s\n\n", cue.Label, cue.Text)
+		} else {
+			fmt.Fprintf(&b, "%s\n\n", cue.Text)
+		}
+	}
+	return b.String()
+}
+
+// Text renders a readable transcript with one "Agent:"/"Customer:" paragraph per turn
+func Text(result *models.TranscriptionResult, opts *Options) string {
+	doc := Paragraphs(result, opts)
+	if len(doc.Paragraphs) == 0 {
+		return strings.TrimSpace(result.Transcript) + "\n"
+	}
+
+	lines := make([]string, 0, len(doc.Paragraphs))
+	for _, p := range doc.Paragraphs {
+		if p.Label != "" {
+			lines = append(lines, p.Label+": "+p.Text)
+		} else {
+			lines = append(lines, p.Text)
+		}
+	}
+	return strings.Join(lines, "\n\n") + "\n"
+}
+
+// Cues splits the transcript into captions at speaker changes, pauses and length limits
+func Cues(result *models.TranscriptionResult, opts *Options) []Cue {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	tokens := tokensOf(result)
+	labels := SpeakerLabels(result, opts)
+
+	var cues []Cue
+	var current []token
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		cues = append(cues, Cue{
+			Index:   len(cues) + 1,
+			Start:   current[0].start,
+			End:     current[len(current)-1].end,
+			Speaker: current[0].speaker,
+			Label:   labels[current[0].speaker],
+			Text:    joinTokens(current),
+		})
+		current = nil
+	}
+
+	for _, tok := range tokens {
+		if len(current) > 0 {
+			first, last := current[0], current[len(current)-1]
+			if tok.speaker != first.speaker ||
+				tok.start-last.end >= opts.CueGap ||
+				tok.end-first.start > opts.MaxCueDuration ||
+				len(joinTokens(current))+1+len(tok.text) > opts.MaxCueChars {
+				flush()
+			}
+		}
+		current = append(current, tok)
+	}
+	flush()
+
+	return cues
+}
+
+// Paragraphs groups the transcript into speaker turns, breaking long pauses into new paragraphs
+func Paragraphs(result *models.TranscriptionResult, opts *Options) *ParagraphDocument {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	labels := SpeakerLabels(result, opts)
+
+	doc := &ParagraphDocument{
+		DurationMs: int64(result.Duration * 1000),
+		Speakers:   labels,
+		Paragraphs: []Paragraph{},
+	}
+
+	var current []token
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		doc.Paragraphs = append(doc.Paragraphs, Paragraph{
+			Speaker: current[0].speaker,
+			Label:   labels[current[0].speaker],
+			StartMs: current[0].start.Milliseconds(),
+			EndMs:   current[len(current)-1].end.Milliseconds(),
+			Text:    joinTokens(current),
+		})
+		current = nil
+	}
+
+	for _, tok := range tokensOf(result) {
+		if len(current) > 0 {
+			last := current[len(current)-1]
+			if tok.speaker != last.speaker || tok.start-last.end >= opts.ParagraphGap {
+				flush()
+			}
+		}
+		current = append(current, tok)
+	}
+	flush()
+
+	if end := tokenEnd(doc.Paragraphs); end > doc.DurationMs {
+		doc.DurationMs = end
+	}
+	return doc
+}
+
+// SpeakerLabels names each diarized speaker. Without overrides the first speaker is the agent who
+// answered the call and the next is the customer; an untagged speaker 0 gets no label.
+func SpeakerLabels(result *models.TranscriptionResult, opts *Options) map[int]string {
+	labels := make(map[int]string)
+
+	var order []int
+	seen := make(map[int]bool)
+	for _, tok := range tokensOf(result) {
+		if !seen[tok.speaker] {
+			seen[tok.speaker] = true
+			order = append(order, tok.speaker)
+		}
+	}
+
+	defaults := []string{"Agent", "Customer"}
+	next := 0
+	for _, speaker := range order {
+		if opts != nil && opts.SpeakerLabels[speaker] != "" {
+			labels[speaker] = opts.SpeakerLabels[speaker]
+			continue
+		}
+		if speaker == 0 {
+			continue
+		}
+		if next < len(defaults) {
+			labels[speaker] = defaults[next]
+		} else {
+			labels[speaker] = fmt.Sprintf("Speaker %d", speaker)
+		}
+		next++
+	}
+	return labels
+}
+
+// tokensOf returns timed words, interpolating word times from speaker segments when the result
+// has no word-level details
+func tokensOf(result *models.TranscriptionResult) []token {
+	if result == nil {
+		return nil
+	}
+
+	if len(result.WordDetails) > 0 {
+		tokens := make([]token, 0, len(result.WordDetails))
+		for _, w := range result.WordDetails {
+			tokens = append(tokens, token{
+				text:    w.Word,
+				start:   w.StartTime.Duration(),
+				end:     w.EndTime.Duration(),
+				speaker: w.Speaker,
+			})
+		}
+		sort.SliceStable(tokens, func(i, j int) bool { return tokens[i].start < tokens[j].start })
+		return tokens
+	}
+
+	var tokens []token
+	for _, seg := range result.SpeakerDiarization {
+		words := strings.Fields(seg.Text)
+		if len(words) == 0 {
+			continue
+		}
+		start, end := seg.StartTime.Duration(), seg.EndTime.Duration()
+		step := (end - start) / time.Duration(len(words))
+		for i, w := range words {
+			tokens = append(tokens, token{
+				text:    w,
+				start:   start + time.Duration(i)*step,
+				end:     start + time.Duration(i+1)*step,
+				speaker: seg.Speaker,
+			})
+		}
+	}
+	return tokens
+}
+
+// joinTokens joins token text with single spaces
+func joinTokens(tokens []token) string {
+	words := make([]string, len(tokens))
+	for i, tok := range tokens {
+		words[i] = tok.text
+	}
+	return strings.Join(words, " ")
+}
+
+// tokenEnd returns the end of the last paragraph in milliseconds
+func tokenEnd(paragraphs []Paragraph) int64 {
+	if len(paragraphs) == 0 {
+		return 0
+	}
+	return paragraphs[len(paragraphs)-1].EndMs
+}
+
+// formatTimestamp formats an offset as HH:MM:SS.mmm using sep before the milliseconds
+func formatTimestamp(d time.Duration, sep string) string {
+	if d < 0 {
+		d = 0
+	}
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
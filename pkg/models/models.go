@@ -1,6 +1,10 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -68,6 +72,12 @@ type CallDetails struct {
 	Recording             string    `json:"recording"`
 }
 
+// HotLead reports whether the call is an answered first call, a new lead worth handling ahead of
+// routine and backfilled work
+func (c CallDetails) HotLead() bool {
+	return c.FirstCall && c.Answered
+}
+
 // RecordingDetails represents call recording information
 type RecordingDetails struct {
 	CallID       string    `json:"call_id"`
@@ -86,36 +96,231 @@ type TranscriptionResult struct {
 	WordDetails         []WordDetail             `json:"word_details"`
 	Duration            float64                  `json:"duration"`
 	SpeakerCount        int                      `json:"speaker_count"`
+	Language            string                   `json:"language,omitempty"` // dominant BCP-47 code detected by Speech-to-Text
+	Quality             *TranscriptQuality       `json:"quality,omitempty"`
+	Attempts            []TranscriptionAttempt   `json:"attempts,omitempty"` // every attempt when the first was re-transcribed
+}
+
+// TranscriptQuality scores how trustworthy a transcript is
+type TranscriptQuality struct {
+	Score                   float64  `json:"score"` // 0-1, higher is better
+	AverageConfidence       float64  `json:"average_confidence"`
+	LowPercentileConfidence float64  `json:"low_percentile_confidence"`
+	UnknownWordRatio        float64  `json:"unknown_word_ratio"`
+	WordCount               int      `json:"word_count"`
+	SpeakerCount            int      `json:"speaker_count"`
+	Issues                  []string `json:"issues,omitempty"`
+	Poor                    bool     `json:"poor"`
+}
+
+// TranscriptionAttempt records one transcription of a recording and how it scored
+type TranscriptionAttempt struct {
+	Name            string             `json:"name"`
+	JobID           string             `json:"job_id,omitempty"`
+	Model           string             `json:"model"`
+	UseEnhanced     bool               `json:"use_enhanced"`
+	SampleRateHertz int32              `json:"sample_rate_hertz,omitempty"`
+	Quality         *TranscriptQuality `json:"quality,omitempty"`
+	Selected        bool               `json:"selected"`
+	Error           string             `json:"error,omitempty"`
+}
+
+// languageNames maps the base of a BCP-47 code to the language name used in prompts
+var languageNames = map[string]string{
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"pt": "Portuguese",
+	"zh": "Chinese",
+	"vi": "Vietnamese",
+	"ko": "Korean",
+	"tl": "Tagalog",
+	"ar": "Arabic",
+	"ru": "Russian",
+}
+
+// LanguageName returns the English name of a language code such as "es-US", or the code itself if unknown
+func LanguageName(code string) string {
+	base := strings.ToLower(strings.SplitN(code, "-", 2)[0])
+	if name, ok := languageNames[base]; ok {
+		return name
+	}
+	return code
+}
+
+// IsEnglish reports whether a language code is English; an empty code is treated as English
+func IsEnglish(code string) bool {
+	return code == "" || strings.EqualFold(strings.SplitN(code, "-", 2)[0], "en")
 }
 
 // SpeakerSegment represents a segment of speech from one speaker
 type SpeakerSegment struct {
 	Speaker   int     `json:"speaker"`
-	StartTime string  `json:"start_time"`
-	EndTime   string  `json:"end_time"`
+	StartTime Offset  `json:"start_time"`
+	EndTime   Offset  `json:"end_time"`
 	Text      string  `json:"text"`
 }
 
+// MarshalJSON adds millisecond offsets alongside the legacy string times
+func (s SpeakerSegment) MarshalJSON() ([]byte, error) {
+	type segment SpeakerSegment
+	return json.Marshal(struct {
+		segment
+		StartMs int64 `json:"start_ms"`
+		EndMs   int64 `json:"end_ms"`
+	}{segment(s), s.StartTime.Milliseconds(), s.EndTime.Milliseconds()})
+}
+
 // WordDetail represents detailed information about a transcribed word
 type WordDetail struct {
 	Word       string  `json:"word"`
-	StartTime  string  `json:"start_time"`
-	EndTime    string  `json:"end_time"`
+	StartTime  Offset  `json:"start_time"`
+	EndTime    Offset  `json:"end_time"`
 	Confidence float32 `json:"confidence"`
+	Speaker    int     `json:"speaker,omitempty"`
+}
+
+// MarshalJSON adds millisecond offsets alongside the legacy string times
+func (w WordDetail) MarshalJSON() ([]byte, error) {
+	type word WordDetail
+	return json.Marshal(struct {
+		word
+		StartMs int64 `json:"start_ms"`
+		EndMs   int64 `json:"end_ms"`
+	}{word(w), w.StartTime.Milliseconds(), w.EndTime.Milliseconds()})
+}
+
+// Offset is a time offset into a recording. It marshals to the legacy "1.234s" string form and
+// unmarshals from either that form or a number of milliseconds.
+type Offset time.Duration
+
+// Duration returns the offset as a time.Duration
+func (o Offset) Duration() time.Duration {
+	return time.Duration(o)
+}
+
+// Milliseconds returns the offset in whole milliseconds
+func (o Offset) Milliseconds() int64 {
+	return time.Duration(o).Milliseconds()
+}
+
+// Seconds returns the offset in seconds
+func (o Offset) Seconds() float64 {
+	return time.Duration(o).Seconds()
+}
+
+// MarshalJSON encodes the offset as seconds with millisecond precision, e.g. "1.234s"
+func (o Offset) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatFloat(o.Seconds(), 'f', 3, 64) + "s")
+}
+
+// UnmarshalJSON accepts "1.2s" strings written by older versions or numeric milliseconds
+func (o *Offset) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		var ms float64
+		if err := json.Unmarshal(data, &ms); err != nil {
+			return fmt.Errorf("invalid offset %s", string(data))
+		}
+		*o = Offset(time.Duration(ms * float64(time.Millisecond)))
+		return nil
+	}
+
+	if text == "" {
+		*o = 0
+		return nil
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSuffix(text, "s"), 64)
+	if err != nil {
+		return fmt.Errorf("invalid offset %q: %w", text, err)
+	}
+	*o = Offset(time.Duration(seconds * float64(time.Second)).Round(time.Millisecond))
+	return nil
 }
 
 // CallAnalysis represents AI-powered analysis of the call content
 type CallAnalysis struct {
-	Intent              string   `json:"intent"`
-	ProjectType         string   `json:"project_type"`
-	Timeline            string   `json:"timeline"`
-	BudgetIndicator     string   `json:"budget_indicator"`
-	Sentiment           string   `json:"sentiment"`
-	LeadScore           int      `json:"lead_score"`
-	Urgency             string   `json:"urgency"`
+	Intent              string   `json:"intent" schema:"enum=quote_request|information_seeking|appointment_booking|complaint|follow_up|other"`
+	ProjectType         string   `json:"project_type" schema:"enum=kitchen|bathroom|whole_home|addition|flooring|roofing|windows|doors|other"`
+	Timeline            string   `json:"timeline" schema:"enum=immediate|1-3_months|3-6_months|6+_months|unknown"`
+	BudgetIndicator     string   `json:"budget_indicator" schema:"enum=high|medium|low|unknown"`
+	Sentiment           string   `json:"sentiment" schema:"enum=positive|neutral|negative"`
+	LeadScore           int      `json:"lead_score" schema:"min=1,max=100"`
+	Urgency             string   `json:"urgency" schema:"enum=high|medium|low"`
 	AppointmentRequested bool    `json:"appointment_requested"`
 	FollowUpRequired    bool     `json:"follow_up_required"`
 	KeyDetails          []string `json:"key_details"`
+
+	// Summary is filled by the call summary or combined analysis, never by the content analysis
+	Summary *CallSummary `json:"summary,omitempty" schema:"-"`
+
+	// Continuity relates the call to the caller's earlier calls; a caller without any is a new project
+	Continuity *CallContinuity `json:"continuity,omitempty"`
+}
+
+// How a call relates to the caller's earlier calls to the same business
+const (
+	CallRelationshipNewProject = "new_project"
+	CallRelationshipFollowUp   = "follow_up"
+	CallRelationshipComplaint  = "complaint" // about work already done
+)
+
+// CallContinuity says whether a call starts a new project or continues one from an earlier call
+type CallContinuity struct {
+	Relationship     string `json:"relationship" schema:"enum=new_project|follow_up|complaint"`
+	RelatedRequestID string `json:"related_request_id"` // the earlier call's request, "" for a new project
+	Reasoning        string `json:"reasoning"`
+}
+
+// PriorInteraction is one of the caller's earlier calls, as the analysis prompt shows it
+type PriorInteraction struct {
+	RequestID   string    `json:"request_id"`
+	Date        time.Time `json:"date"`
+	Intent      string    `json:"intent,omitempty"`
+	ProjectType string    `json:"project_type,omitempty"`
+	Summary     string    `json:"summary,omitempty"`
+	CRMStage    string    `json:"crm_stage,omitempty"` // the lead's status in the CRM, or how pushing it went
+}
+
+// CallSummary is what a sales rep needs to follow up on a call
+type CallSummary struct {
+	Summary       string   `json:"summary"`        // 2-3 sentences
+	ActionItems   []string `json:"action_items"`   // concrete next steps, e.g. "Call back Tuesday"
+	Commitments   []string `json:"commitments"`    // what either side promised during the call
+	OpenQuestions []string `json:"open_questions"` // what the caller asked that is still unanswered
+}
+
+// CallEntities are the contact details a caller gave during a call; what the caller didn't say is null
+type CallEntities struct {
+	CustomerName *ExtractedEntity  `json:"customer_name"`
+	Email        *ExtractedEntity  `json:"email"`
+	Address      *ExtractedAddress `json:"address"`
+	CallbackTime *ExtractedEntity  `json:"callback_time"` // as the caller put it, e.g. "weekdays after 5pm"
+}
+
+// ExtractedEntity is a normalized value taken from a transcript with the words it came from
+type ExtractedEntity struct {
+	Value      string          `json:"value"`
+	Confidence float64         `json:"confidence" schema:"min=0,max=1"`
+	Quote      string          `json:"quote"`                     // the transcript words, verbatim
+	Span       *TranscriptSpan `json:"span,omitempty" schema:"-"` // where the quote is, nil when it isn't in the transcript
+}
+
+// ExtractedAddress is a postal address taken from a transcript; unknown parts are empty
+type ExtractedAddress struct {
+	Street     string          `json:"street"`
+	City       string          `json:"city"`
+	State      string          `json:"state"` // two-letter code
+	ZipCode    string          `json:"zip_code"`
+	Confidence float64         `json:"confidence" schema:"min=0,max=1"`
+	Quote      string          `json:"quote"`
+	Span       *TranscriptSpan `json:"span,omitempty" schema:"-"`
+}
+
+// TranscriptSpan is the byte range [Start, End) of a quote in a transcript
+type TranscriptSpan struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
 }
 
 // EnhancedPayload represents the final structured data for workflow processing
@@ -172,6 +377,8 @@ type WorkflowConfig struct {
 	ServiceArea           ServiceAreaConfig            `json:"service_area"`
 	CRMIntegration        CRMIntegrationConfig         `json:"crm_integration"`
 	EmailNotifications    EmailNotificationsConfig     `json:"email_notifications"`
+	Retention             RetentionConfig              `json:"retention"`
+	Analysis              AnalysisPromptConfig         `json:"analysis"`
 }
 
 // CommunicationDetectionConfig configures how communications are processed
@@ -186,6 +393,272 @@ type PhoneProcessingConfig struct {
 	ExtractDetails      bool `json:"extract_details"`
 	SentimentAnalysis   bool `json:"sentiment_analysis"`
 	SpeakerDiarization  bool `json:"speaker_diarization"`
+	CallScreening       CallScreeningConfig `json:"call_screening"`
+	Redaction           RedactionConfig     `json:"redaction"`
+	Languages           []string            `json:"languages,omitempty"` // expected caller languages, primary first, e.g. ["en-US", "es-US"]
+}
+
+// RedactionConfig configures which PII is removed from transcripts and stored audio
+type RedactionConfig struct {
+	Enabled     bool      `json:"enabled"`
+	Entities    []PIIType `json:"entities,omitempty"` // defaults to card numbers and SSNs
+	RedactAudio bool      `json:"redact_audio"`       // bleep redacted words in the stored audio copy
+	UseNER      bool      `json:"use_ner"`            // also run the entity recognizer for names and addresses
+}
+
+// PIIType identifies a kind of personal data found in a transcript
+type PIIType string
+
+const (
+	PIICardNumber PIIType = "card_number"
+	PIISSN        PIIType = "ssn"
+	PIIPhone      PIIType = "phone"
+	PIIEmail      PIIType = "email"
+	PIIAddress    PIIType = "address"
+	PIIPerson     PIIType = "person"
+)
+
+// CallScreeningConfig configures voicemail, IVR and no-speech screening before transcription and AI
+type CallScreeningConfig struct {
+	Enabled                      bool         `json:"enabled"`
+	SkipTranscriptionForNoSpeech bool         `json:"skip_transcription_for_no_speech"`
+	VoicemailAnalysis            AnalysisMode `json:"voicemail_analysis"` // full, summary, skip
+	IVRAnalysis                  AnalysisMode `json:"ivr_analysis"`       // full, summary, skip
+}
+
+// CallClass classifies what kind of audio a call recording contains
+type CallClass string
+
+const (
+	CallClassConversation CallClass = "conversation"
+	CallClassVoicemail    CallClass = "voicemail"
+	CallClassNoSpeech     CallClass = "no_speech"
+	CallClassIVROnly      CallClass = "ivr_only"
+)
+
+// AnalysisMode controls how much AI analysis a call receives
+type AnalysisMode string
+
+const (
+	AnalysisModeFull    AnalysisMode = "full"
+	AnalysisModeSummary AnalysisMode = "summary"
+	AnalysisModeSkip    AnalysisMode = "skip"
+)
+
+// VoicemailLead is the short lead record produced for voicemails and unanswered calls
+type VoicemailLead struct {
+	RequestID         string    `json:"request_id"`
+	CallID            string    `json:"call_id"`
+	CallerName        string    `json:"caller_name,omitempty"`
+	CallerPhone       string    `json:"caller_phone,omitempty"`
+	Classification    CallClass `json:"classification"`
+	Message           string    `json:"message"`
+	CallbackRequested bool      `json:"callback_requested"`
+	Duration          float64   `json:"duration"`
+	ReceivedAt        time.Time `json:"received_at"`
+}
+
+// RecordingFingerprint is the acoustic fingerprint stored for a call recording
+type RecordingFingerprint struct {
+	RecordingID string    `json:"recording_id"`
+	TenantID    string    `json:"tenant_id"`
+	CallID      string    `json:"call_id"`
+	Digest      string    `json:"digest"`      // identical for byte-identical audio
+	Fingerprint string    `json:"fingerprint"` // base64 sub-fingerprints
+	HopMs       int64     `json:"hop_ms"`
+	DurationMs  int64     `json:"duration_ms"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// RobocallFingerprint is a known pre-recorded spam message, shared across tenants
+type RobocallFingerprint struct {
+	FingerprintID     string    `json:"fingerprint_id"`
+	Label             string    `json:"label"`
+	Digest            string    `json:"digest"`
+	Fingerprint       string    `json:"fingerprint"`
+	HopMs             int64     `json:"hop_ms"`
+	DurationMs        int64     `json:"duration_ms"`
+	SourceTenantID    string    `json:"source_tenant_id"`
+	SourceRecordingID string    `json:"source_recording_id"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// FingerprintMatch is the result of checking a recording against earlier recordings and robocalls
+type FingerprintMatch struct {
+	Duplicate           bool    `json:"duplicate"`
+	Exact               bool    `json:"exact"`
+	DuplicateOf         string  `json:"duplicate_of,omitempty"` // recording ID
+	DuplicateCallID     string  `json:"duplicate_call_id,omitempty"`
+	DuplicateSimilarity float64 `json:"duplicate_similarity,omitempty"`
+	Robocall            bool    `json:"robocall"`
+	RobocallID          string  `json:"robocall_id,omitempty"`
+	RobocallLabel       string  `json:"robocall_label,omitempty"`
+	RobocallSimilarity  float64 `json:"robocall_similarity,omitempty"`
+	SpamLikelihood      float64 `json:"spam_likelihood"` // 0-100, on the same scale as spam detection
+}
+
+// Tenant data key states
+const (
+	DataKeyStateActive    = "active"    // encrypts new data
+	DataKeyStateRetired   = "retired"   // superseded by a rotation, still decrypts old data
+	DataKeyStateDestroyed = "destroyed" // crypto-shredded, the wrapped key is gone
+)
+
+// TenantDataKey is a version of a tenant's data encryption key, wrapped by the key encryption key
+type TenantDataKey struct {
+	TenantID    string     `json:"tenant_id"`
+	KeyVersion  int64      `json:"key_version"`
+	WrappedKey  []byte     `json:"-"`
+	KEKName     string     `json:"kek_name"` // KMS key or local master key that wrapped it
+	State       string     `json:"state"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DestroyedAt *time.Time `json:"destroyed_at,omitempty"`
+}
+
+// DataClass is a kind of stored call data with its own retention period
+type DataClass string
+
+const (
+	DataClassAudio      DataClass = "audio"       // recordings in object storage
+	DataClassTranscript DataClass = "transcript"  // transcripts and transcription jobs
+	DataClassAIOutput   DataClass = "ai_output"   // AI analysis, extraction and processing logs
+	DataClassWebhook    DataClass = "raw_webhook" // raw webhook payloads and events
+)
+
+// DataClasses lists every data class in purge order
+var DataClasses = []DataClass{DataClassAudio, DataClassTranscript, DataClassAIOutput, DataClassWebhook}
+
+// AnalysisPromptConfig tailors the AI analysis prompts to a tenant's business. Empty fields keep
+// the platform defaults.
+type AnalysisPromptConfig struct {
+	BusinessType     string         `json:"business_type,omitempty"`     // e.g. "roofing company"
+	ProjectTypes     []string       `json:"project_types,omitempty"`     // project_type taxonomy; "other" is always allowed
+	ScoringCriteria  []string       `json:"scoring_criteria,omitempty"`  // what lead scoring weighs, replacing the defaults
+	TemplateVersions map[string]int `json:"template_versions,omitempty"` // prompt template version pinned per analysis type
+}
+
+// RetentionConfig sets how long each class of a tenant's call data is kept. Zero keeps the
+// platform default.
+type RetentionConfig struct {
+	AudioDays      int `json:"audio_days,omitempty"`
+	TranscriptDays int `json:"transcript_days,omitempty"`
+	AIOutputDays   int `json:"ai_output_days,omitempty"`
+	WebhookDays    int `json:"webhook_days,omitempty"`
+}
+
+// Days returns the retention period of a data class, falling back to defaultDays
+func (c RetentionConfig) Days(class DataClass, defaultDays int) int {
+	var days int
+	switch class {
+	case DataClassAudio:
+		days = c.AudioDays
+	case DataClassTranscript:
+		days = c.TranscriptDays
+	case DataClassAIOutput:
+		days = c.AIOutputDays
+	case DataClassWebhook:
+		days = c.WebhookDays
+	}
+	if days <= 0 {
+		return defaultDays
+	}
+	return days
+}
+
+// LegalHold exempts a call, or a whole tenant when CallID is empty, from retention purges
+type LegalHold struct {
+	HoldID     string     `json:"hold_id"`
+	TenantID   string     `json:"tenant_id"`
+	CallID     string     `json:"call_id,omitempty"`
+	Reason     string     `json:"reason"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+}
+
+// Active reports whether the hold is still in force
+func (h *LegalHold) Active() bool {
+	return h.ReleasedAt == nil
+}
+
+// PurgeAuditRecord records data removed by the retention purge
+type PurgeAuditRecord struct {
+	AuditID        string    `json:"audit_id"`
+	TenantID       string    `json:"tenant_id"`
+	CallID         string    `json:"call_id,omitempty"`
+	RequestID      string    `json:"request_id,omitempty"`
+	RecordingID    string    `json:"recording_id,omitempty"`
+	DataClass      DataClass `json:"data_class"`
+	RetentionDays  int       `json:"retention_days"`
+	Cutoff         time.Time `json:"cutoff"` // data created before this was purged
+	ObjectsDeleted int64     `json:"objects_deleted"`
+	RowsAffected   int64     `json:"rows_affected"`
+	PurgedAt       time.Time `json:"purged_at"`
+}
+
+// NewHoldID generates a new legal hold ID
+func NewHoldID() string {
+	return "hold_" + uuid.New().String()
+}
+
+// NewAuditID generates a new audit record ID
+func NewAuditID() string {
+	return "audit_" + uuid.New().String()
+}
+
+// Data subject request types
+const (
+	DataSubjectExport = "export"
+	DataSubjectDelete = "delete"
+)
+
+// Data subject request outcomes
+const (
+	DataSubjectCompleted = "completed"
+	DataSubjectPartial   = "partial" // some data is retained or needs follow-up, see the log details
+	DataSubjectFailed    = "failed"
+)
+
+// DataSubjectCounts counts the artifacts found for a data subject
+type DataSubjectCounts struct {
+	Requests          int `json:"requests"`
+	CallRecordings    int `json:"call_recordings"`
+	ProcessingLogs    int `json:"processing_logs"`
+	CRMIntegrations   int `json:"crm_integrations"`
+	WebhookEvents     int `json:"webhook_events"`
+	TranscriptionJobs int `json:"transcription_jobs"`
+	Objects           int `json:"objects"`
+}
+
+// CRMErasure records the deletion of a lead in the tenant's CRM
+type CRMErasure struct {
+	Provider   string `json:"provider"`
+	ExternalID string `json:"external_id"`
+	Status     string `json:"status"` // deleted, unsupported or failed
+	Error      string `json:"error,omitempty"`
+}
+
+// DataSubjectLog is the compliance record of a data subject export or deletion. The subject is
+// recorded as a hash so the log doesn't keep the personal data it documents removing.
+type DataSubjectLog struct {
+	LogID         string            `json:"log_id"`
+	TenantID      string            `json:"tenant_id"`
+	RequestType   string            `json:"request_type"`
+	SubjectHash   string            `json:"subject_hash"`
+	RequestedBy   string            `json:"requested_by,omitempty"`
+	Status        string            `json:"status"`
+	Counts        DataSubjectCounts `json:"counts"`
+	RetainedCalls []string          `json:"retained_calls,omitempty"` // on legal hold
+	CRMErasures   []CRMErasure      `json:"crm_erasures,omitempty"`
+	Verified      bool              `json:"verified"` // a search after deletion found nothing left
+	Error         string            `json:"error,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	CompletedAt   time.Time         `json:"completed_at"`
+}
+
+// NewDataSubjectLogID generates a new data subject log ID
+func NewDataSubjectLogID() string {
+	return "dsr_" + uuid.New().String()
 }
 
 // ValidationConfig configures request validation
@@ -195,9 +668,16 @@ type ValidationConfig struct {
 
 // SpamDetectionConfig configures spam detection
 type SpamDetectionConfig struct {
-	Enabled             bool   `json:"enabled"`
-	ConfidenceThreshold int    `json:"confidence_threshold"`
-	MLModel             string `json:"ml_model"`
+	Enabled             bool               `json:"enabled"`
+	ConfidenceThreshold int                `json:"confidence_threshold"` // spam likelihood (0-100) at which a lead is kept out of the CRM
+	MLModel             string             `json:"ml_model"`
+	Weights             map[string]float64 `json:"weights,omitempty"` // overrides the spam scoring weight of a factor, e.g. {"llm": 0.3}
+}
+
+// CallerActivity counts the requests from one phone number within a window, across every tenant
+type CallerActivity struct {
+	Requests int `json:"requests"`
+	Tenants  int `json:"tenants"` // distinct tenants called
 }
 
 // ServiceAreaConfig configures service area validation
@@ -212,6 +692,7 @@ type ServiceAreaConfig struct {
 type CRMIntegrationConfig struct {
 	Enabled         bool              `json:"enabled"`
 	Provider        string            `json:"provider"`
+	APIKey          string            `json:"api_key,omitempty"`
 	FieldMapping    map[string]string `json:"field_mapping"`
 	PushImmediately bool              `json:"push_immediately"`
 }
@@ -245,6 +726,7 @@ type Request struct {
 	LeadScore          *int      `json:"lead_score" spanner:"lead_score"`
 	CommunicationMode  string    `json:"communication_mode" spanner:"communication_mode"`
 	SpamLikelihood     *float64  `json:"spam_likelihood" spanner:"spam_likelihood"`
+	CallClassification *string   `json:"call_classification" spanner:"call_classification"`
 	CreatedAt          time.Time `json:"created_at" spanner:"created_at"`
 	UpdatedAt          time.Time `json:"updated_at" spanner:"updated_at"`
 }
@@ -256,6 +738,7 @@ type CallRecording struct {
 	CallID              string    `json:"call_id" spanner:"call_id"`
 	StorageURL          string    `json:"storage_url" spanner:"storage_url"`
 	TranscriptionStatus string    `json:"transcription_status" spanner:"transcription_status"`
+	TranscriptionData   *string   `json:"transcription_data,omitempty" spanner:"transcription_data"` // JSON string, only loaded for data subject exports
 	CreatedAt           time.Time `json:"created_at" spanner:"created_at"`
 }
 
@@ -0,0 +1,135 @@
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// exportManifest describes the contents of an export bundle
+type exportManifest struct {
+	TenantID    string                   `json:"tenant_id"`
+	Subject     Subject                  `json:"subject"`
+	GeneratedAt time.Time                `json:"generated_at"`
+	Counts      models.DataSubjectCounts `json:"counts"`
+	Files       []string                 `json:"files"`
+}
+
+// Export writes a zip bundle of everything held on the subject: a manifest, one JSON file per
+// record and the call recordings. The export is written to the compliance log.
+func (s *Service) Export(ctx context.Context, tenantID string, subject Subject, requestedBy string, w io.Writer) (*models.DataSubjectLog, error) {
+	log := s.newLog(tenantID, models.DataSubjectExport, subject, requestedBy)
+
+	err := s.writeExport(ctx, tenantID, subject, w, log)
+	log.Status = models.DataSubjectCompleted
+	if err != nil {
+		log.Status = models.DataSubjectFailed
+		log.Error = err.Error()
+	}
+	log.CompletedAt = s.now().UTC()
+
+	if saveErr := s.store.SaveDataSubjectLog(ctx, log); saveErr != nil {
+		return log, fmt.Errorf("failed to save compliance log: %w", saveErr)
+	}
+	return log, err
+}
+
+// writeExport finds the subject's artifacts and writes them as a zip bundle
+func (s *Service) writeExport(ctx context.Context, tenantID string, subject Subject, w io.Writer, log *models.DataSubjectLog) error {
+	artifacts, err := s.Find(ctx, tenantID, subject)
+	if err != nil {
+		return err
+	}
+	log.Counts = artifacts.Counts()
+
+	bundle := zip.NewWriter(w)
+	manifest := &exportManifest{
+		TenantID:    tenantID,
+		Subject:     subject.Normalized(),
+		GeneratedAt: s.now().UTC(),
+		Counts:      log.Counts,
+	}
+
+	records := make(map[string]interface{})
+	for _, req := range artifacts.Requests {
+		records["requests/"+req.RequestID+".json"] = req
+	}
+	for _, recording := range artifacts.CallRecordings {
+		records["call_recordings/"+recording.RecordingID+".json"] = recording
+	}
+	for _, processingLog := range artifacts.ProcessingLogs {
+		records["ai_processing_logs/"+processingLog.LogID+".json"] = processingLog
+	}
+	for _, integration := range artifacts.CRMIntegrations {
+		records["crm_integrations/"+integration.IntegrationID+".json"] = integration
+	}
+	for _, event := range artifacts.WebhookEvents {
+		records["webhook_events/"+event.EventID+".json"] = event
+	}
+	for _, job := range artifacts.TranscriptionJobs {
+		records["transcription_jobs/"+job.JobID+".json"] = job
+	}
+
+	for _, name := range sortedKeys(records) {
+		if err := writeJSONFile(bundle, name, records[name]); err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, name)
+	}
+
+	for _, key := range artifacts.Objects {
+		data, err := s.objects.ReadObject(ctx, tenantID, key)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", key, err)
+		}
+		name := "recordings/" + path.Base(key)
+		file, err := bundle.Create(name)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to export: %w", name, err)
+		}
+		if _, err := file.Write(data); err != nil {
+			return fmt.Errorf("failed to write %s to export: %w", name, err)
+		}
+		manifest.Files = append(manifest.Files, name)
+	}
+
+	if err := writeJSONFile(bundle, "manifest.json", manifest); err != nil {
+		return err
+	}
+	if err := bundle.Close(); err != nil {
+		return fmt.Errorf("failed to finish export: %w", err)
+	}
+	return nil
+}
+
+// writeJSONFile adds an indented JSON file to the bundle
+func writeJSONFile(bundle *zip.Writer, name string, v interface{}) error {
+	file, err := bundle.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to export: %w", name, err)
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to write %s to export: %w", name, err)
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of a map in order so bundles are reproducible
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,406 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/audio"
+	"github.com/home-renovators/ingestion-pipeline/pkg/crm"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// ErrTenantOnHold is returned when a deletion is refused because the whole tenant is on legal hold
+var ErrTenantOnHold = errors.New("tenant is on legal hold")
+
+// Store finds and deletes a data subject's records
+type Store interface {
+	// FindRequests returns a tenant's requests whose data contains any of the lower-case terms
+	FindRequests(ctx context.Context, tenantID string, terms []string) ([]*models.Request, error)
+	// FindCRMIntegrations returns a tenant's CRM integration records whose config contains any of
+	// the lower-case terms
+	FindCRMIntegrations(ctx context.Context, tenantID string, terms []string) ([]*models.CRMIntegration, error)
+	ListCallRecordingsByCall(ctx context.Context, tenantID, callID string) ([]*models.CallRecording, error)
+	ListAIProcessingLogsByRequest(ctx context.Context, tenantID, requestID string) ([]*models.AIProcessingLog, error)
+	ListWebhookEventsByCall(ctx context.Context, callID string) ([]*models.WebhookEvent, error)
+	ListTranscriptionJobs(ctx context.Context, filter audio.JobFilter) ([]*audio.TranscriptionJob, error)
+	ListLegalHolds(ctx context.Context, tenantID string) ([]*models.LegalHold, error)
+	// DeleteSubjectRows deletes every row of the artifacts in one transaction
+	DeleteSubjectRows(ctx context.Context, tenantID string, artifacts *Artifacts) (int64, error)
+	SaveDataSubjectLog(ctx context.Context, log *models.DataSubjectLog) error
+}
+
+// ObjectStore reads and deletes the stored recordings of a call
+type ObjectStore interface {
+	ListCallObjects(ctx context.Context, tenantID, callID string) ([]string, error)
+	ReadObject(ctx context.Context, tenantID, key string) ([]byte, error)
+	DeleteCallObjects(ctx context.Context, tenantID, callID string) (int, error)
+}
+
+// CRMEraser deletes leads from a tenant's CRM
+type CRMEraser interface {
+	// DeleteLead permanently deletes a lead, returning crm.ErrDeleteNotSupported if the provider can't
+	DeleteLead(ctx context.Context, tenantID, provider, leadID string) error
+}
+
+// Artifacts is everything held on a data subject within a tenant
+type Artifacts struct {
+	Requests          []*models.Request         `json:"requests"`
+	CallRecordings    []*models.CallRecording   `json:"call_recordings"`
+	ProcessingLogs    []*models.AIProcessingLog `json:"processing_logs"`
+	CRMIntegrations   []*models.CRMIntegration  `json:"crm_integrations"`
+	WebhookEvents     []*models.WebhookEvent    `json:"webhook_events"`
+	TranscriptionJobs []*audio.TranscriptionJob `json:"transcription_jobs"`
+	Objects           []string                  `json:"objects"`
+}
+
+// Counts counts the artifacts of each kind
+func (a *Artifacts) Counts() models.DataSubjectCounts {
+	return models.DataSubjectCounts{
+		Requests:          len(a.Requests),
+		CallRecordings:    len(a.CallRecordings),
+		ProcessingLogs:    len(a.ProcessingLogs),
+		CRMIntegrations:   len(a.CRMIntegrations),
+		WebhookEvents:     len(a.WebhookEvents),
+		TranscriptionJobs: len(a.TranscriptionJobs),
+		Objects:           len(a.Objects),
+	}
+}
+
+// Empty reports whether nothing was found
+func (a *Artifacts) Empty() bool {
+	return a.Counts() == models.DataSubjectCounts{}
+}
+
+// CallIDs returns the IDs of the subject's calls
+func (a *Artifacts) CallIDs() []string {
+	seen := make(map[string]bool)
+	var callIDs []string
+	for _, req := range a.Requests {
+		if req.CallID != nil && *req.CallID != "" && !seen[*req.CallID] {
+			seen[*req.CallID] = true
+			callIDs = append(callIDs, *req.CallID)
+		}
+	}
+	sort.Strings(callIDs)
+	return callIDs
+}
+
+// CRMLead is the CRM record a CRM integration created
+type CRMLead struct {
+	Provider   string
+	ExternalID string
+	RequestID  string
+}
+
+// ParseCRMLead reads the request and CRM lead ID from an integration record's config
+func ParseCRMLead(integration *models.CRMIntegration) CRMLead {
+	var config struct {
+		RequestID  string `json:"request_id"`
+		ExternalID string `json:"external_id"`
+	}
+	_ = json.Unmarshal([]byte(integration.Config), &config)
+
+	return CRMLead{
+		Provider:   integration.CRMType,
+		ExternalID: config.ExternalID,
+		RequestID:  config.RequestID,
+	}
+}
+
+// Service finds, exports and deletes everything held on a data subject
+type Service struct {
+	store   Store
+	objects ObjectStore
+	crm     CRMEraser
+	now     func() time.Time
+}
+
+// NewService creates a data subject request service. A nil CRM eraser leaves CRM leads for manual
+// deletion.
+func NewService(store Store, objects ObjectStore, eraser CRMEraser) *Service {
+	return &Service{
+		store:   store,
+		objects: objects,
+		crm:     eraser,
+		now:     time.Now,
+	}
+}
+
+// Find enumerates every artifact held on the subject within a tenant
+func (s *Service) Find(ctx context.Context, tenantID string, subject Subject) (*Artifacts, error) {
+	if !subject.Valid() {
+		return nil, fmt.Errorf("a phone number or email is required")
+	}
+	artifacts := &Artifacts{}
+
+	candidates, err := s.store.FindRequests(ctx, tenantID, subject.SearchTerms())
+	if err != nil {
+		return nil, fmt.Errorf("failed to search requests: %w", err)
+	}
+	requestIDs := make(map[string]bool)
+	for _, req := range candidates {
+		if requestMatches(subject, req) {
+			artifacts.Requests = append(artifacts.Requests, req)
+			requestIDs[req.RequestID] = true
+		}
+	}
+
+	for _, req := range artifacts.Requests {
+		logs, err := s.store.ListAIProcessingLogsByRequest(ctx, tenantID, req.RequestID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list processing logs: %w", err)
+		}
+		artifacts.ProcessingLogs = append(artifacts.ProcessingLogs, logs...)
+	}
+
+	for _, callID := range artifacts.CallIDs() {
+		recordings, err := s.store.ListCallRecordingsByCall(ctx, tenantID, callID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list call recordings: %w", err)
+		}
+		artifacts.CallRecordings = append(artifacts.CallRecordings, recordings...)
+
+		events, err := s.store.ListWebhookEventsByCall(ctx, callID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list webhook events: %w", err)
+		}
+		artifacts.WebhookEvents = append(artifacts.WebhookEvents, events...)
+
+		jobs, err := s.store.ListTranscriptionJobs(ctx, audio.JobFilter{TenantID: tenantID, CallID: callID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list transcription jobs: %w", err)
+		}
+		artifacts.TranscriptionJobs = append(artifacts.TranscriptionJobs, jobs...)
+
+		if s.objects != nil {
+			keys, err := s.objects.ListCallObjects(ctx, tenantID, callID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list recordings: %w", err)
+			}
+			artifacts.Objects = append(artifacts.Objects, keys...)
+		}
+	}
+
+	// CRM records are found by the subject's details or by the requests they were created from
+	terms := subject.SearchTerms()
+	for requestID := range requestIDs {
+		terms = append(terms, requestID)
+	}
+	integrations, err := s.store.FindCRMIntegrations(ctx, tenantID, terms)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search CRM integrations: %w", err)
+	}
+	for _, integration := range integrations {
+		if requestIDs[ParseCRMLead(integration).RequestID] || subject.Matches(integration.Config) {
+			artifacts.CRMIntegrations = append(artifacts.CRMIntegrations, integration)
+		}
+	}
+
+	return artifacts, nil
+}
+
+// requestMatches reports whether a request carries the subject's phone number or email
+func requestMatches(subject Subject, req *models.Request) bool {
+	if subject.Matches(req.Data) || subject.Matches(req.AIExtracted) || subject.Matches(req.AINormalized) {
+		return true
+	}
+	return req.AIAnalysis != nil && subject.Matches(*req.AIAnalysis)
+}
+
+// Delete erases everything held on the subject: CRM leads first while their IDs are still known,
+// then recordings, then rows. Calls on legal hold are kept. A second search verifies nothing else
+// is left, and the outcome is written to the compliance log whether or not deletion succeeds.
+func (s *Service) Delete(ctx context.Context, tenantID string, subject Subject, requestedBy string) (*models.DataSubjectLog, error) {
+	log := s.newLog(tenantID, models.DataSubjectDelete, subject, requestedBy)
+
+	err := s.erase(ctx, tenantID, subject, log)
+	if err != nil {
+		log.Status = models.DataSubjectFailed
+		log.Error = err.Error()
+	}
+	log.CompletedAt = s.now().UTC()
+
+	if saveErr := s.store.SaveDataSubjectLog(ctx, log); saveErr != nil {
+		return log, fmt.Errorf("failed to save compliance log: %w", saveErr)
+	}
+	return log, err
+}
+
+// erase performs a deletion, filling in the log
+func (s *Service) erase(ctx context.Context, tenantID string, subject Subject, log *models.DataSubjectLog) error {
+	artifacts, err := s.Find(ctx, tenantID, subject)
+	if err != nil {
+		return err
+	}
+
+	held, err := s.heldCalls(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	if held == nil {
+		log.Counts = artifacts.Counts()
+		return ErrTenantOnHold
+	}
+	artifacts, log.RetainedCalls = withoutHeldCalls(artifacts, held)
+	log.Counts = artifacts.Counts()
+
+	complete := true
+	for _, integration := range artifacts.CRMIntegrations {
+		lead := ParseCRMLead(integration)
+		if lead.ExternalID == "" {
+			continue
+		}
+		erasure := models.CRMErasure{Provider: lead.Provider, ExternalID: lead.ExternalID, Status: "deleted"}
+		if s.crm == nil {
+			erasure.Status = "unsupported"
+		} else if err := s.crm.DeleteLead(ctx, tenantID, lead.Provider, lead.ExternalID); err != nil {
+			erasure.Status = "failed"
+			erasure.Error = err.Error()
+			if errors.Is(err, crm.ErrDeleteNotSupported) {
+				erasure.Status = "unsupported"
+			}
+		}
+		if erasure.Status != "deleted" {
+			complete = false
+		}
+		log.CRMErasures = append(log.CRMErasures, erasure)
+	}
+
+	if s.objects != nil {
+		for _, callID := range artifacts.CallIDs() {
+			if _, err := s.objects.DeleteCallObjects(ctx, tenantID, callID); err != nil {
+				return fmt.Errorf("failed to delete recordings of call %s: %w", callID, err)
+			}
+		}
+	}
+
+	if _, err := s.store.DeleteSubjectRows(ctx, tenantID, artifacts); err != nil {
+		return fmt.Errorf("failed to delete records: %w", err)
+	}
+
+	remaining, err := s.Find(ctx, tenantID, subject)
+	if err != nil {
+		return fmt.Errorf("failed to verify deletion: %w", err)
+	}
+	remaining, _ = withoutHeldCalls(remaining, held)
+	if !remaining.Empty() {
+		return fmt.Errorf("verification found data left after deletion: %+v", remaining.Counts())
+	}
+	log.Verified = true
+
+	log.Status = models.DataSubjectCompleted
+	if !complete || len(log.RetainedCalls) > 0 {
+		log.Status = models.DataSubjectPartial
+	}
+	return nil
+}
+
+// heldCalls returns the tenant's calls on legal hold, or nil if the whole tenant is held
+func (s *Service) heldCalls(ctx context.Context, tenantID string) (map[string]bool, error) {
+	holds, err := s.store.ListLegalHolds(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load legal holds: %w", err)
+	}
+
+	held := make(map[string]bool)
+	for _, hold := range holds {
+		if !hold.Active() {
+			continue
+		}
+		if hold.CallID == "" {
+			return nil, nil
+		}
+		held[hold.CallID] = true
+	}
+	return held, nil
+}
+
+// withoutHeldCalls splits off the artifacts of calls on legal hold, returning the rest and the
+// held call IDs found
+func withoutHeldCalls(artifacts *Artifacts, held map[string]bool) (*Artifacts, []string) {
+	if len(held) == 0 {
+		return artifacts, nil
+	}
+
+	var retained []string
+	for _, callID := range artifacts.CallIDs() {
+		if held[callID] {
+			retained = append(retained, callID)
+		}
+	}
+	if len(retained) == 0 {
+		return artifacts, nil
+	}
+
+	heldRequests := make(map[string]bool)
+	rest := &Artifacts{}
+	for _, req := range artifacts.Requests {
+		if req.CallID != nil && held[*req.CallID] {
+			heldRequests[req.RequestID] = true
+			continue
+		}
+		rest.Requests = append(rest.Requests, req)
+	}
+	for _, recording := range artifacts.CallRecordings {
+		if !held[recording.CallID] {
+			rest.CallRecordings = append(rest.CallRecordings, recording)
+		}
+	}
+	for _, log := range artifacts.ProcessingLogs {
+		if !heldRequests[log.RequestID] {
+			rest.ProcessingLogs = append(rest.ProcessingLogs, log)
+		}
+	}
+	for _, integration := range artifacts.CRMIntegrations {
+		if !heldRequests[ParseCRMLead(integration).RequestID] {
+			rest.CRMIntegrations = append(rest.CRMIntegrations, integration)
+		}
+	}
+	for _, event := range artifacts.WebhookEvents {
+		if event.CallID == nil || !held[*event.CallID] {
+			rest.WebhookEvents = append(rest.WebhookEvents, event)
+		}
+	}
+	for _, job := range artifacts.TranscriptionJobs {
+		if job.Request == nil || !held[job.Request.CallID] {
+			rest.TranscriptionJobs = append(rest.TranscriptionJobs, job)
+		}
+	}
+	for _, key := range artifacts.Objects {
+		keep := true
+		for callID := range held {
+			if objectBelongsToCall(key, callID) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			rest.Objects = append(rest.Objects, key)
+		}
+	}
+
+	return rest, retained
+}
+
+// objectBelongsToCall reports whether a stored object is one of a call's recordings
+func objectBelongsToCall(key, callID string) bool {
+	return strings.HasPrefix(path.Base(key), callID+".")
+}
+
+// newLog starts a compliance log record
+func (s *Service) newLog(tenantID, requestType string, subject Subject, requestedBy string) *models.DataSubjectLog {
+	return &models.DataSubjectLog{
+		LogID:       models.NewDataSubjectLogID(),
+		TenantID:    tenantID,
+		RequestType: requestType,
+		SubjectHash: subject.Hash(tenantID),
+		RequestedBy: requestedBy,
+		CreatedAt:   s.now().UTC(),
+	}
+}
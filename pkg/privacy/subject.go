@@ -0,0 +1,122 @@
+package privacy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Subject identifies the consumer a data subject request is about, by phone number, email or both
+type Subject struct {
+	Phone string `json:"phone,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// NormalizePhone reduces a phone number to its digits, dropping the North American country code so
+// "+1 (555) 123-4567" and "555.123.4567" compare equal. Values too short to be a phone number
+// normalize to "".
+func NormalizePhone(phone string) string {
+	var digits strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+
+	normalized := digits.String()
+	if len(normalized) == 11 && normalized[0] == '1' {
+		normalized = normalized[1:]
+	}
+	if len(normalized) < 7 {
+		return ""
+	}
+	return normalized
+}
+
+// NormalizeEmail lower-cases an email address, returning "" if it isn't one
+func NormalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !strings.Contains(email, "@") {
+		return ""
+	}
+	return email
+}
+
+// Normalized returns the subject with its phone and email normalized
+func (s Subject) Normalized() Subject {
+	return Subject{
+		Phone: NormalizePhone(s.Phone),
+		Email: NormalizeEmail(s.Email),
+	}
+}
+
+// Valid reports whether the subject has a usable phone number or email
+func (s Subject) Valid() bool {
+	n := s.Normalized()
+	return n.Phone != "" || n.Email != ""
+}
+
+// Hash identifies the subject within a tenant without revealing the phone number or email
+func (s Subject) Hash(tenantID string) string {
+	n := s.Normalized()
+	sum := sha256.Sum256([]byte(tenantID + "\x00phone:" + n.Phone + "\x00email:" + n.Email))
+	return hex.EncodeToString(sum[:])
+}
+
+// SearchTerms returns lower-case substrings any record mentioning the subject must contain. Phone
+// numbers are stored in many formats, so only the last four digits are certain to be contiguous;
+// matches are confirmed with Matches.
+func (s Subject) SearchTerms() []string {
+	n := s.Normalized()
+	var terms []string
+	if n.Phone != "" {
+		terms = append(terms, n.Phone[len(n.Phone)-4:])
+	}
+	if n.Email != "" {
+		terms = append(terms, n.Email)
+	}
+	return terms
+}
+
+// Matches reports whether a JSON document contains the subject's phone number or email as one of
+// its values. A document that isn't JSON is compared as a single value.
+func (s Subject) Matches(document string) bool {
+	n := s.Normalized()
+	if document == "" || (n.Phone == "" && n.Email == "") {
+		return false
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(document), &value); err != nil {
+		value = document
+	}
+	return n.matchesValue(value)
+}
+
+// matchesValue walks a decoded JSON value looking for the normalized subject
+func (s Subject) matchesValue(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		if s.Email != "" && NormalizeEmail(v) == s.Email {
+			return true
+		}
+		return s.Phone != "" && NormalizePhone(v) == s.Phone
+	case float64:
+		return s.Phone != "" && NormalizePhone(strconv.FormatFloat(v, 'f', 0, 64)) == s.Phone
+	case map[string]interface{}:
+		for _, item := range v {
+			if s.matchesValue(item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if s.matchesValue(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,262 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// Candidate is a call whose data of one class is older than the tenant's retention period
+type Candidate struct {
+	TenantID    string    `json:"tenant_id"`
+	CallID      string    `json:"call_id,omitempty"`
+	RequestID   string    `json:"request_id,omitempty"`
+	RecordingID string    `json:"recording_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Store finds and removes expired call data
+type Store interface {
+	// ListPurgeCandidates returns up to limit calls of a tenant that still hold data of the class
+	// and were created before the cutoff, oldest first. A call's transcription jobs count as
+	// transcript data, since a finished job keeps its redacted result.
+	ListPurgeCandidates(ctx context.Context, tenantID string, class models.DataClass, before time.Time, limit int) ([]Candidate, error)
+	// PurgeCallData removes a call's data of the class and writes the audit record in the same
+	// transaction, returning the number of rows changed or deleted
+	PurgeCallData(ctx context.Context, candidate Candidate, class models.DataClass, audit *models.PurgeAuditRecord) (int64, error)
+	// ListLegalHolds returns a tenant's legal holds that have not been released
+	ListLegalHolds(ctx context.Context, tenantID string) ([]*models.LegalHold, error)
+}
+
+// ObjectStore deletes the stored recordings of a call
+type ObjectStore interface {
+	// DeleteCallObjects deletes every object stored for a call, returning how many existed
+	DeleteCallObjects(ctx context.Context, tenantID, callID string) (int, error)
+}
+
+// Config contains purge settings
+type Config struct {
+	DefaultDays int  `json:"default_days"` // retention for classes a tenant doesn't configure
+	BatchSize   int  `json:"batch_size"`   // candidates fetched per query
+	MaxPerRun   int  `json:"max_per_run"`  // calls purged per tenant and class in one run, 0 for no limit
+	DryRun      bool `json:"dry_run"`      // report what would be purged without deleting
+}
+
+// DefaultConfig returns purge defaults
+func DefaultConfig() *Config {
+	return &Config{
+		DefaultDays: 2555,
+		BatchSize:   200,
+		MaxPerRun:   5000,
+	}
+}
+
+// ClassReport summarises the purge of one data class
+type ClassReport struct {
+	RetentionDays  int       `json:"retention_days"`
+	Cutoff         time.Time `json:"cutoff"`
+	Purged         int       `json:"purged"`
+	Held           int       `json:"held"` // skipped because of a call-level legal hold
+	ObjectsDeleted int64     `json:"objects_deleted"`
+	RowsAffected   int64     `json:"rows_affected"`
+}
+
+// Report summarises a tenant's purge
+type Report struct {
+	TenantID string                            `json:"tenant_id"`
+	DryRun   bool                              `json:"dry_run"`
+	Held     bool                              `json:"held"` // the whole tenant is on legal hold
+	Classes  map[models.DataClass]*ClassReport `json:"classes"`
+	Errors   []string                          `json:"errors,omitempty"`
+}
+
+// Purger deletes call data past each tenant's retention period, skipping data on legal hold
+type Purger struct {
+	store   Store
+	objects ObjectStore
+	config  *Config
+	now     func() time.Time
+}
+
+// NewPurger creates a retention purger
+func NewPurger(store Store, objects ObjectStore, config *Config) *Purger {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Purger{
+		store:   store,
+		objects: objects,
+		config:  config,
+		now:     time.Now,
+	}
+}
+
+// WithClock returns a copy of the purger using now as the current time
+func (p *Purger) WithClock(now func() time.Time) *Purger {
+	clone := *p
+	clone.now = now
+	return &clone
+}
+
+// WithDryRun returns a copy of the purger that only reports what it would purge
+func (p *Purger) WithDryRun() *Purger {
+	config := *p.config
+	config.DryRun = true
+	clone := *p
+	clone.config = &config
+	return &clone
+}
+
+// PurgeTenant purges every data class of a tenant according to its retention policy. Failures on
+// single calls are collected in the report and the purge carries on; they are retried next run.
+func (p *Purger) PurgeTenant(ctx context.Context, tenantID string, policy models.RetentionConfig) (*Report, error) {
+	report := &Report{
+		TenantID: tenantID,
+		DryRun:   p.config.DryRun,
+		Classes:  make(map[models.DataClass]*ClassReport),
+	}
+
+	holds, err := p.store.ListLegalHolds(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load legal holds: %w", err)
+	}
+	heldCalls := make(map[string]bool)
+	for _, hold := range holds {
+		if !hold.Active() {
+			continue
+		}
+		if hold.CallID == "" {
+			report.Held = true
+			return report, nil
+		}
+		heldCalls[hold.CallID] = true
+	}
+
+	for _, class := range models.DataClasses {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		classReport, err := p.purgeClass(ctx, tenantID, class, policy.Days(class, p.config.DefaultDays), heldCalls, report)
+		report.Classes[class] = classReport
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// purgeClass purges one data class of a tenant
+func (p *Purger) purgeClass(ctx context.Context, tenantID string, class models.DataClass, days int, heldCalls map[string]bool, report *Report) (*ClassReport, error) {
+	cutoff := p.now().UTC().AddDate(0, 0, -days)
+	classReport := &ClassReport{RetentionDays: days, Cutoff: cutoff}
+
+	// Purged calls drop out of the candidate list but held, failed and dry-run ones stay, so the
+	// query limit grows by the number skipped to reach the next unseen batch
+	seen := make(map[string]bool)
+	skipped := 0
+	for p.config.MaxPerRun == 0 || classReport.Purged < p.config.MaxPerRun {
+		limit := p.config.BatchSize + skipped
+		candidates, err := p.store.ListPurgeCandidates(ctx, tenantID, class, cutoff, limit)
+		if err != nil {
+			return classReport, fmt.Errorf("failed to list %s purge candidates: %w", class, err)
+		}
+
+		fresh := 0
+		for _, candidate := range candidates {
+			id := candidateID(candidate)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			fresh++
+
+			switch {
+			case candidate.CallID != "" && heldCalls[candidate.CallID]:
+				classReport.Held++
+				skipped++
+			case p.config.DryRun:
+				classReport.Purged++
+				skipped++
+			default:
+				if err := p.purgeCandidate(ctx, candidate, class, days, cutoff, classReport); err != nil {
+					report.Errors = append(report.Errors, fmt.Sprintf("%s %s: %v", class, id, err))
+					skipped++
+				}
+			}
+			if p.config.MaxPerRun > 0 && classReport.Purged >= p.config.MaxPerRun {
+				break
+			}
+		}
+
+		if fresh == 0 || len(candidates) < limit {
+			break
+		}
+	}
+
+	return classReport, nil
+}
+
+// purgeCandidate deletes a call's objects, then its rows together with the audit record. If the
+// row purge fails the objects are already gone and the next run finishes the job.
+func (p *Purger) purgeCandidate(ctx context.Context, candidate Candidate, class models.DataClass, days int, cutoff time.Time, classReport *ClassReport) error {
+	audit := &models.PurgeAuditRecord{
+		AuditID:       models.NewAuditID(),
+		TenantID:      candidate.TenantID,
+		CallID:        candidate.CallID,
+		RequestID:     candidate.RequestID,
+		RecordingID:   candidate.RecordingID,
+		DataClass:     class,
+		RetentionDays: days,
+		Cutoff:        cutoff,
+	}
+
+	if class == models.DataClassAudio && candidate.CallID != "" && p.objects != nil {
+		deleted, err := p.objects.DeleteCallObjects(ctx, candidate.TenantID, candidate.CallID)
+		if err != nil {
+			return fmt.Errorf("failed to delete recordings: %w", err)
+		}
+		audit.ObjectsDeleted = int64(deleted)
+	}
+
+	audit.PurgedAt = p.now().UTC()
+	rows, err := p.store.PurgeCallData(ctx, candidate, class, audit)
+	if err != nil {
+		return fmt.Errorf("failed to purge rows: %w", err)
+	}
+
+	classReport.Purged++
+	classReport.ObjectsDeleted += audit.ObjectsDeleted
+	classReport.RowsAffected += rows
+	return nil
+}
+
+// MergePolicies combines the retention policies of a tenant's offices, keeping the longest period
+// of each class so no office loses data earlier than it agreed to
+func MergePolicies(policies []models.RetentionConfig, defaultDays int) models.RetentionConfig {
+	if len(policies) == 0 {
+		return models.RetentionConfig{}
+	}
+
+	merged := models.RetentionConfig{}
+	for _, policy := range policies {
+		merged.AudioDays = max(merged.AudioDays, policy.Days(models.DataClassAudio, defaultDays))
+		merged.TranscriptDays = max(merged.TranscriptDays, policy.Days(models.DataClassTranscript, defaultDays))
+		merged.AIOutputDays = max(merged.AIOutputDays, policy.Days(models.DataClassAIOutput, defaultDays))
+		merged.WebhookDays = max(merged.WebhookDays, policy.Days(models.DataClassWebhook, defaultDays))
+	}
+	return merged
+}
+
+// candidateID identifies a candidate within one class
+func candidateID(c Candidate) string {
+	if c.RecordingID != "" {
+		return c.RecordingID
+	}
+	if c.RequestID != "" {
+		return c.RequestID
+	}
+	return c.CallID
+}
@@ -0,0 +1,309 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Priority is the urgency of a scheduled unit of work
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityNormal Priority = "normal"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// priorities lists the levels from most to least urgent
+var priorities = []Priority{PriorityUrgent, PriorityHigh, PriorityNormal, PriorityLow}
+
+// ParsePriority normalises a priority string, treating unknown or empty values as normal
+func ParsePriority(value string) Priority {
+	switch Priority(value) {
+	case PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent:
+		return Priority(value)
+	default:
+		return PriorityNormal
+	}
+}
+
+// Config contains scheduler limits and weights
+type Config struct {
+	MaxConcurrent int              `json:"max_concurrent"` // global concurrency budget
+	MaxPerTenant  int              `json:"max_per_tenant"` // 0 means a tenant may use the whole budget; urgent work is not capped
+	Weights       map[Priority]int `json:"weights"`        // relative share of slots per priority
+}
+
+// DefaultConfig returns scheduler defaults
+func DefaultConfig() *Config {
+	return &Config{
+		MaxConcurrent: 5,
+		MaxPerTenant:  3,
+		Weights: map[Priority]int{
+			PriorityUrgent: 16,
+			PriorityHigh:   8,
+			PriorityNormal: 4,
+			PriorityLow:    1,
+		},
+	}
+}
+
+// Stats is a snapshot of scheduler state
+type Stats struct {
+	Running  int              `json:"running"`
+	Queued   map[Priority]int `json:"queued"`
+	ByTenant map[string]int   `json:"running_by_tenant"`
+}
+
+// waiter is a caller blocked in Acquire
+type waiter struct {
+	tenantID string
+	priority Priority
+	ready    chan struct{}
+	granted  bool
+}
+
+// level holds the per-tenant FIFO queues for one priority
+type level struct {
+	priority Priority
+	weight   int
+	current  int // smooth weighted round-robin state
+	tenants  []string
+	queues   map[string][]*waiter
+	cursor   int
+}
+
+// Scheduler admits work by priority with per-tenant fair share and a global concurrency budget.
+// Priorities are served by smooth weighted round-robin so low priority work is slowed, not starved;
+// within a priority, tenants take turns so one tenant's backfill cannot block another's calls.
+type Scheduler struct {
+	config *Config
+
+	mu       sync.Mutex
+	running  int
+	byTenant map[string]int
+	levels   map[Priority]*level
+}
+
+// New creates a new scheduler
+func New(config *Config) *Scheduler {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = 1
+	}
+
+	s := &Scheduler{
+		config:   config,
+		byTenant: make(map[string]int),
+		levels:   make(map[Priority]*level),
+	}
+	for _, p := range priorities {
+		weight := config.Weights[p]
+		if weight <= 0 {
+			weight = 1
+		}
+		s.levels[p] = &level{priority: p, weight: weight, queues: make(map[string][]*waiter)}
+	}
+	return s
+}
+
+// Acquire blocks until work for the tenant may run at the given priority. The returned release
+// function must be called exactly once when the work finishes.
+func (s *Scheduler) Acquire(ctx context.Context, tenantID string, priority Priority) (func(), error) {
+	w := &waiter{
+		tenantID: tenantID,
+		priority: ParsePriority(string(priority)),
+		ready:    make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	s.enqueue(w)
+	s.dispatch()
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return s.releaseFunc(tenantID), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		if w.granted {
+			// Granted while we were giving up; hand the slot straight back
+			s.release(tenantID)
+		} else {
+			s.remove(w)
+		}
+		s.mu.Unlock()
+		return nil, fmt.Errorf("scheduler wait cancelled: %w", ctx.Err())
+	}
+}
+
+// Stats returns a snapshot of running and queued work
+func (s *Scheduler) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := Stats{
+		Running:  s.running,
+		Queued:   make(map[Priority]int),
+		ByTenant: make(map[string]int),
+	}
+	for p, l := range s.levels {
+		for _, q := range l.queues {
+			stats.Queued[p] += len(q)
+		}
+	}
+	for tenantID, n := range s.byTenant {
+		stats.ByTenant[tenantID] = n
+	}
+	return stats
+}
+
+// releaseFunc returns an idempotent release for one granted slot
+func (s *Scheduler) releaseFunc(tenantID string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.release(tenantID)
+		})
+	}
+}
+
+// release frees a slot and admits the next waiters; callers hold s.mu
+func (s *Scheduler) release(tenantID string) {
+	s.running--
+	s.byTenant[tenantID]--
+	if s.byTenant[tenantID] <= 0 {
+		delete(s.byTenant, tenantID)
+	}
+	s.dispatch()
+}
+
+// enqueue adds a waiter to its priority level; callers hold s.mu
+func (s *Scheduler) enqueue(w *waiter) {
+	l := s.levels[w.priority]
+	if _, exists := l.queues[w.tenantID]; !exists {
+		l.tenants = append(l.tenants, w.tenantID)
+	}
+	l.queues[w.tenantID] = append(l.queues[w.tenantID], w)
+}
+
+// remove drops a waiter that gave up before being admitted; callers hold s.mu
+func (s *Scheduler) remove(w *waiter) {
+	l := s.levels[w.priority]
+	queue := l.queues[w.tenantID]
+	for i, queued := range queue {
+		if queued == w {
+			l.queues[w.tenantID] = append(queue[:i], queue[i+1:]...)
+			break
+		}
+	}
+	if len(l.queues[w.tenantID]) == 0 {
+		l.dropTenant(w.tenantID)
+	}
+}
+
+// dispatch admits waiters while the global budget allows; callers hold s.mu
+func (s *Scheduler) dispatch() {
+	for s.running < s.config.MaxConcurrent {
+		w := s.next()
+		if w == nil {
+			return
+		}
+		w.granted = true
+		s.running++
+		s.byTenant[w.tenantID]++
+		close(w.ready)
+	}
+}
+
+// next picks the next waiter by weighted round-robin across priorities; callers hold s.mu
+func (s *Scheduler) next() *waiter {
+	total := 0
+	var best *level
+	for _, p := range priorities {
+		l := s.levels[p]
+		if !l.hasEligible(s) {
+			continue
+		}
+		l.current += l.weight
+		total += l.weight
+		if best == nil || l.current > best.current {
+			best = l
+		}
+	}
+	if best == nil {
+		return nil
+	}
+
+	best.current -= total
+	return best.pop(s)
+}
+
+// tenantAtCap reports whether a tenant has used its share of the budget for work of a priority.
+// Urgent work is exempt so a hot lead isn't held behind its own tenant's backfill; it still waits
+// for the global budget.
+func (s *Scheduler) tenantAtCap(tenantID string, priority Priority) bool {
+	if priority == PriorityUrgent {
+		return false
+	}
+	return s.config.MaxPerTenant > 0 && s.byTenant[tenantID] >= s.config.MaxPerTenant
+}
+
+// hasEligible reports whether any queued tenant in the level may start work now
+func (l *level) hasEligible(s *Scheduler) bool {
+	for _, tenantID := range l.tenants {
+		if !s.tenantAtCap(tenantID, l.priority) {
+			return true
+		}
+	}
+	return false
+}
+
+// pop takes the head waiter of the next eligible tenant in round-robin order
+func (l *level) pop(s *Scheduler) *waiter {
+	for i := 0; i < len(l.tenants); i++ {
+		idx := (l.cursor + i) % len(l.tenants)
+		tenantID := l.tenants[idx]
+		if s.tenantAtCap(tenantID, l.priority) {
+			continue
+		}
+
+		queue := l.queues[tenantID]
+		w := queue[0]
+		l.queues[tenantID] = queue[1:]
+
+		if len(l.queues[tenantID]) == 0 {
+			l.dropTenant(tenantID)
+			l.cursor = idx
+		} else {
+			l.cursor = idx + 1
+		}
+		if len(l.tenants) > 0 {
+			l.cursor %= len(l.tenants)
+		} else {
+			l.cursor = 0
+		}
+		return w
+	}
+	return nil
+}
+
+// dropTenant removes a tenant with an empty queue from the rotation
+func (l *level) dropTenant(tenantID string) {
+	delete(l.queues, tenantID)
+	for i, t := range l.tenants {
+		if t == tenantID {
+			l.tenants = append(l.tenants[:i], l.tenants[i+1:]...)
+			if l.cursor > i {
+				l.cursor--
+			}
+			break
+		}
+	}
+}
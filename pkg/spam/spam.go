@@ -0,0 +1,313 @@
+// Package spam scores calls for spam by blending deterministic signals about the call and the
+// caller with the LLM's reading of the transcript.
+package spam
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+	"github.com/home-renovators/ingestion-pipeline/pkg/privacy"
+)
+
+// Factors a spam likelihood is built from, also the keys of a weight override
+const (
+	FactorLLM           = "llm"
+	FactorDuration      = "call_duration"
+	FactorUnanswered    = "unanswered"
+	FactorRepeatCaller  = "repeat_caller"
+	FactorNumberPattern = "number_pattern"
+	FactorKnownSpam     = "known_spam_number"
+	FactorFingerprint   = "robocall_fingerprint"
+)
+
+// KnownSpamLikelihood is the least spam likelihood of a call from a number on the known-spam list
+const KnownSpamLikelihood = 95
+
+// tollFreeAreaCodes are North American toll-free prefixes, which call centers dial out from
+var tollFreeAreaCodes = map[string]bool{
+	"800": true, "833": true, "844": true, "855": true, "866": true, "877": true, "888": true,
+}
+
+// Config contains spam scoring settings
+type Config struct {
+	Weights          map[string]float64 `json:"weights"`            // by factor; a factor without a weight is ignored
+	ShortCall        time.Duration      `json:"short_call"`         // calls shorter than this are suspicious
+	BriefCall        time.Duration      `json:"brief_call"`         // calls shorter than this are somewhat suspicious
+	RepeatWindow     time.Duration      `json:"repeat_window"`      // how far back to count a caller's other calls
+	RepeatCalls      int                `json:"repeat_calls"`       // calls within the window that make a repeat caller
+	RepeatTenants    int                `json:"repeat_tenants"`     // businesses called within the window that make a dialer
+	KnownSpamNumbers []string           `json:"known_spam_numbers"` // any format, compared normalized
+}
+
+// DefaultConfig returns spam scoring settings that lean on the LLM and let hard evidence, a known
+// spam number or a robocall recording, decide on its own
+func DefaultConfig() *Config {
+	return &Config{
+		Weights: map[string]float64{
+			FactorLLM:           0.5,
+			FactorDuration:      0.1,
+			FactorUnanswered:    0.05,
+			FactorRepeatCaller:  0.15,
+			FactorNumberPattern: 0.1,
+			FactorKnownSpam:     0.3,
+			FactorFingerprint:   0.3,
+		},
+		ShortCall:     10 * time.Second,
+		BriefCall:     30 * time.Second,
+		RepeatWindow:  24 * time.Hour,
+		RepeatCalls:   5,
+		RepeatTenants: 3,
+	}
+}
+
+// Signals are what is known about a call when it is scored; nil means unknown
+type Signals struct {
+	Duration       *time.Duration
+	Answered       *bool
+	CallerPhone    *string                  // as CallRail reports it, "" when caller ID was withheld
+	CallerActivity *models.CallerActivity   // the caller's calls within the repeat window, this one included
+	Fingerprint    *models.FingerprintMatch // the recording's match against known robocalls
+	LLM            *LLMVerdict
+}
+
+// LLMVerdict is the LLM's spam assessment of the transcript
+type LLMVerdict struct {
+	Likelihood float64 // 0-100
+	Confidence float64 // 0-1, scales the LLM's weight; 0 when the model didn't say
+	Reasoning  string
+}
+
+// Verdict is a blended spam likelihood with the factors behind it
+type Verdict struct {
+	Likelihood  float64  `json:"spam_likelihood"` // 0-100
+	Factors     []Factor `json:"factors"`         // largest contribution first
+	Explanation string   `json:"explanation"`
+	Suppressed  bool     `json:"suppressed"` // at or above the tenant's threshold, so the lead is kept out of the CRM
+}
+
+// Factor is one signal's part in a verdict
+type Factor struct {
+	Name         string  `json:"name"`
+	Score        float64 `json:"score"` // 0-100
+	Weight       float64 `json:"weight"`
+	Contribution float64 `json:"contribution"` // points of the blended likelihood
+	Detail       string  `json:"detail"`
+}
+
+// Scorer blends spam signals into a verdict
+type Scorer struct {
+	config    *Config
+	knownSpam map[string]bool
+}
+
+// NewScorer creates a spam scorer
+func NewScorer(config *Config) *Scorer {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	knownSpam := make(map[string]bool)
+	for _, number := range config.KnownSpamNumbers {
+		if normalized := privacy.NormalizePhone(number); normalized != "" {
+			knownSpam[normalized] = true
+		}
+	}
+	return &Scorer{config: config, knownSpam: knownSpam}
+}
+
+// RepeatWindow returns how far back a caller's other calls count
+func (s *Scorer) RepeatWindow() time.Duration {
+	return s.config.RepeatWindow
+}
+
+// WithWeights returns a scorer whose weights are overridden by a tenant's, e.g. to trust the
+// LLM less or ignore the number pattern with a weight of 0
+func (s *Scorer) WithWeights(overrides map[string]float64) *Scorer {
+	if len(overrides) == 0 {
+		return s
+	}
+
+	config := *s.config
+	config.Weights = make(map[string]float64, len(s.config.Weights))
+	for name, weight := range s.config.Weights {
+		config.Weights[name] = weight
+	}
+	for name, weight := range overrides {
+		config.Weights[name] = weight
+	}
+	return &Scorer{config: &config, knownSpam: s.knownSpam}
+}
+
+// Score blends the known signals into a spam likelihood, a weighted average of the factors'
+// scores. A known spam number or a robocall recording also sets a floor the average can't
+// pull the likelihood under, unless the factor is weighted 0.
+func (s *Scorer) Score(signals *Signals, policy models.SpamDetectionConfig) *Verdict {
+	factors, floor, floorFactor := s.factors(signals)
+
+	var total, weighted float64
+	for i := range factors {
+		total += factors[i].Weight
+		weighted += factors[i].Weight * factors[i].Score
+	}
+
+	verdict := &Verdict{}
+	if total > 0 {
+		for i := range factors {
+			factors[i].Contribution = round(factors[i].Weight * factors[i].Score / total)
+		}
+		verdict.Likelihood = weighted / total
+	}
+	sort.SliceStable(factors, func(i, j int) bool { return factors[i].Contribution > factors[j].Contribution })
+	verdict.Factors = factors
+
+	raised := floor > verdict.Likelihood
+	if raised {
+		verdict.Likelihood = floor
+	}
+	verdict.Likelihood = round(verdict.Likelihood)
+	verdict.Suppressed = Suppresses(policy, verdict.Likelihood)
+	verdict.Explanation = explain(verdict, raised, floorFactor)
+	return verdict
+}
+
+// Suppresses reports whether a tenant's spam policy keeps a call with this likelihood out of the CRM
+func Suppresses(policy models.SpamDetectionConfig, likelihood float64) bool {
+	return policy.Enabled && policy.ConfidenceThreshold > 0 && likelihood >= float64(policy.ConfidenceThreshold)
+}
+
+// factors scores each known signal, returning the floor set by hard evidence and its factor
+func (s *Scorer) factors(signals *Signals) ([]Factor, float64, string) {
+	var factors []Factor
+	add := func(name string, score float64, detail string) {
+		if weight := s.config.Weights[name]; weight > 0 {
+			factors = append(factors, Factor{Name: name, Score: score, Weight: weight, Detail: detail})
+		}
+	}
+	var floor float64
+	var floorFactor string
+
+	if llm := signals.LLM; llm != nil {
+		detail := fmt.Sprintf("transcript rated %.0f by the LLM", llm.Likelihood)
+		if llm.Reasoning != "" {
+			detail += ": " + llm.Reasoning
+		}
+		add(FactorLLM, llm.Likelihood, detail)
+
+		// An unsure model counts for less
+		if last := len(factors) - 1; last >= 0 && factors[last].Name == FactorLLM && llm.Confidence > 0 && llm.Confidence < 1 {
+			factors[last].Weight *= llm.Confidence
+		}
+	}
+
+	if signals.Duration != nil {
+		duration := *signals.Duration
+		switch {
+		case duration < s.config.ShortCall:
+			add(FactorDuration, 70, fmt.Sprintf("call lasted only %s", duration.Round(time.Second)))
+		case duration < s.config.BriefCall:
+			add(FactorDuration, 35, fmt.Sprintf("brief call of %s", duration.Round(time.Second)))
+		default:
+			add(FactorDuration, 0, fmt.Sprintf("call lasted %s", duration.Round(time.Second)))
+		}
+	}
+
+	if signals.Answered != nil {
+		if *signals.Answered {
+			add(FactorUnanswered, 0, "call was answered")
+		} else {
+			add(FactorUnanswered, 40, "call went unanswered")
+		}
+	}
+
+	if activity := signals.CallerActivity; activity != nil {
+		window := describeWindow(s.config.RepeatWindow)
+		switch {
+		case activity.Tenants >= s.config.RepeatTenants:
+			add(FactorRepeatCaller, 90, fmt.Sprintf("caller reached %d businesses within %s", activity.Tenants, window))
+		case activity.Tenants > 1:
+			add(FactorRepeatCaller, 50, fmt.Sprintf("caller reached %d businesses within %s", activity.Tenants, window))
+		case activity.Requests >= s.config.RepeatCalls:
+			add(FactorRepeatCaller, 60, fmt.Sprintf("caller called %d times within %s", activity.Requests, window))
+		default:
+			add(FactorRepeatCaller, 0, "no other recent calls from this number")
+		}
+	}
+
+	if signals.CallerPhone != nil {
+		score, detail := numberPattern(*signals.CallerPhone)
+		add(FactorNumberPattern, score, detail)
+
+		if normalized := privacy.NormalizePhone(*signals.CallerPhone); normalized != "" && s.knownSpam[normalized] {
+			add(FactorKnownSpam, 100, "number is on the known spam list")
+			if s.config.Weights[FactorKnownSpam] > 0 {
+				floor, floorFactor = KnownSpamLikelihood, FactorKnownSpam
+			}
+		}
+	}
+
+	if match := signals.Fingerprint; match != nil && match.Robocall {
+		add(FactorFingerprint, match.SpamLikelihood, fmt.Sprintf("recording matches known robocall %q", match.RobocallLabel))
+		if s.config.Weights[FactorFingerprint] > 0 && match.SpamLikelihood > floor {
+			floor, floorFactor = match.SpamLikelihood, FactorFingerprint
+		}
+	}
+
+	return factors, floor, floorFactor
+}
+
+// numberPattern scores how unlike a local customer's phone number a caller ID is
+func numberPattern(phone string) (float64, string) {
+	normalized := privacy.NormalizePhone(phone)
+	switch {
+	case normalized == "":
+		return 70, "caller ID withheld or invalid"
+	case len(normalized) != 10:
+		return 40, "international or nonstandard number"
+	case normalized[0] == '0' || normalized[0] == '1':
+		return 80, fmt.Sprintf("invalid area code %s", normalized[:3])
+	case tollFreeAreaCodes[normalized[:3]]:
+		return 50, fmt.Sprintf("toll-free %s number, typical of call centers", normalized[:3])
+	default:
+		return 0, fmt.Sprintf("ordinary %s number", normalized[:3])
+	}
+}
+
+// explain summarizes a verdict with the factors that raised it most
+func explain(verdict *Verdict, raised bool, floorFactor string) string {
+	var parts []string
+	for _, factor := range verdict.Factors {
+		if factor.Contribution >= 1 {
+			parts = append(parts, fmt.Sprintf("%s (+%.0f)", factor.Detail, factor.Contribution))
+		}
+	}
+
+	explanation := fmt.Sprintf("Spam likelihood %.0f", verdict.Likelihood)
+	if len(parts) > 0 {
+		explanation += ": " + strings.Join(parts, "; ")
+	} else if len(verdict.Factors) > 0 {
+		explanation += ": no spam signals"
+	}
+	if raised {
+		explanation += fmt.Sprintf(", raised by %s", strings.ReplaceAll(floorFactor, "_", " "))
+	}
+	if verdict.Suppressed {
+		explanation += ". Kept out of the CRM"
+	}
+	return explanation
+}
+
+// describeWindow renders a repeat window as "24 hours" rather than "24h0m0s"
+func describeWindow(window time.Duration) string {
+	if window >= time.Hour && window%time.Hour == 0 {
+		return fmt.Sprintf("%d hours", window/time.Hour)
+	}
+	return window.String()
+}
+
+func round(value float64) float64 {
+	return math.Round(value*10) / 10
+}
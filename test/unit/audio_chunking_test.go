@@ -0,0 +1,385 @@
+package unit
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/speech/apiv1/speechpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/audio"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// synthesizeSpeech builds PCM audio alternating tone bursts and pauses
+func synthesizeSpeech(sampleRate int, segments []struct {
+	d     time.Duration
+	voice bool
+}) *audio.PCMAudio {
+	var samples []int16
+	for _, seg := range segments {
+		n := int(seg.d.Seconds() * float64(sampleRate))
+		for i := 0; i < n; i++ {
+			if seg.voice {
+				samples = append(samples, int16(8000*math.Sin(2*math.Pi*300*float64(i)/float64(sampleRate))))
+			} else {
+				samples = append(samples, 0)
+			}
+		}
+	}
+	return &audio.PCMAudio{Samples: samples, SampleRate: sampleRate}
+}
+
+func word(text string, start, end time.Duration, speaker int32) *speechpb.WordInfo {
+	return &speechpb.WordInfo{
+		Word:       text,
+		StartTime:  durationpb.New(start),
+		EndTime:    durationpb.New(end),
+		SpeakerTag: speaker,
+		Confidence: 0.9,
+	}
+}
+
+func TestChunkerPlansCutsAtSilence(t *testing.T) {
+	pcm := synthesizeSpeech(8000, []struct {
+		d     time.Duration
+		voice bool
+	}{
+		{50 * time.Second, true},
+		{1 * time.Second, false},
+		{20 * time.Second, true},
+		{1 * time.Second, false},
+		{40 * time.Second, true},
+	})
+
+	config := audio.DefaultChunkingConfig()
+	config.MaxChunkDuration = 60 * time.Second
+	config.Overlap = 2 * time.Second
+	config.SearchWindow = 40 * time.Second
+
+	windows := audio.NewChunker(config).Plan(pcm)
+	require.Len(t, windows, 3)
+
+	// Cuts land inside the pauses at 50-51s and 71-72s rather than at the hard limit
+	assert.True(t, windows[0].CutAtSilence)
+	assert.InDelta(t, 50.5, windows[0].End.Seconds(), 0.1)
+	assert.True(t, windows[1].CutAtSilence)
+	assert.InDelta(t, 71.5, windows[1].End.Seconds(), 0.1)
+
+	// Each window starts one overlap before the previous cut and the last runs to the end
+	assert.InDelta(t, windows[0].End.Seconds()-2, windows[1].Start.Seconds(), 0.01)
+	assert.InDelta(t, windows[1].End.Seconds()-2, windows[2].Start.Seconds(), 0.01)
+	assert.Equal(t, pcm.Duration(), windows[2].End)
+}
+
+func TestChunkerSingleWindowForShortAudio(t *testing.T) {
+	pcm := synthesizeSpeech(8000, []struct {
+		d     time.Duration
+		voice bool
+	}{{30 * time.Second, true}})
+
+	windows := audio.NewChunker(nil).Plan(pcm)
+	require.Len(t, windows, 1)
+	assert.Equal(t, time.Duration(0), windows[0].Start)
+	assert.Equal(t, pcm.Duration(), windows[0].End)
+}
+
+func TestStitchChunksDeduplicatesOverlapAndKeepsSpeakers(t *testing.T) {
+	chunks := []audio.ChunkTranscript{
+		{
+			Window: audio.ChunkWindow{Index: 0, Start: 0, End: 10 * time.Second},
+			Words: []*speechpb.WordInfo{
+				word("thanks", 1*time.Second, 2*time.Second, 1),
+				word("for", 2*time.Second, 3*time.Second, 1),
+				word("calling", 3*time.Second, 4*time.Second, 1),
+				word("hi", 7*time.Second, 8*time.Second, 2),
+				word("kitchen", 8500*time.Millisecond, 9500*time.Millisecond, 2),
+			},
+		},
+		{
+			// Chunk-relative times; speaker tags are numbered independently and swapped
+			Window: audio.ChunkWindow{Index: 1, Start: 7 * time.Second, End: 15 * time.Second},
+			Words: []*speechpb.WordInfo{
+				word("hi", 0, 1*time.Second, 1),
+				word("kitchen", 1500*time.Millisecond, 2500*time.Millisecond, 1),
+				word("remodel", 3*time.Second, 4*time.Second, 1),
+				word("great", 5*time.Second, 6*time.Second, 2),
+			},
+		},
+	}
+
+	words := audio.StitchChunks(chunks)
+
+	var texts []string
+	for _, w := range words {
+		texts = append(texts, w.Word)
+	}
+	assert.Equal(t, []string{"thanks", "for", "calling", "hi", "kitchen", "remodel", "great"}, texts)
+
+	// Timestamps from the second chunk are shifted by its start offset
+	assert.Equal(t, 10*time.Second, words[5].StartTime.AsDuration())
+
+	// The customer keeps label 2 across chunks and the agent keeps label 1
+	assert.Equal(t, int32(2), words[5].SpeakerTag)
+	assert.Equal(t, int32(1), words[6].SpeakerTag)
+}
+
+func TestChunkerCapsChunksAtInlineLimit(t *testing.T) {
+	config := audio.DefaultChunkingConfig()
+	config.MaxChunkDuration = 5 * time.Minute
+	pcm := &audio.PCMAudio{Samples: make([]int16, 48000*4*60), SampleRate: 48000}
+
+	windows := audio.NewChunker(config).Plan(pcm)
+	require.Greater(t, len(windows), 1)
+	for _, w := range windows {
+		assert.LessOrEqual(t, len(pcm.Slice(w.Start, w.End).LinearBytes()), audio.MaxInlineAudioBytes)
+	}
+}
+
+func TestResampleDownsamplesOnly(t *testing.T) {
+	pcm := synthesizeSpeech(44100, []struct {
+		d     time.Duration
+		voice bool
+	}{{2 * time.Second, true}})
+
+	resampled := pcm.Resample(16000)
+	assert.Equal(t, 16000, resampled.SampleRate)
+	assert.Len(t, resampled.Samples, 32000)
+	assert.InDelta(t, pcm.Duration().Seconds(), resampled.Duration().Seconds(), 0.001)
+
+	narrow := synthesizeSpeech(8000, []struct {
+		d     time.Duration
+		voice bool
+	}{{time.Second, true}})
+	assert.Same(t, narrow, narrow.Resample(16000), "audio is never upsampled")
+}
+
+func TestWAVRoundTrip(t *testing.T) {
+	pcm := &audio.PCMAudio{Samples: []int16{0, 1000, -1000, 32767, -32768}, SampleRate: 8000}
+
+	decoded, err := audio.DecodeWAV(audio.EncodeWAV(pcm))
+	require.NoError(t, err)
+	assert.Equal(t, pcm.SampleRate, decoded.SampleRate)
+	assert.Equal(t, pcm.Samples, decoded.Samples)
+}
+
+func TestTranscribeWithJobChunksLongRecordings(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeSpeech{responses: map[string]*speechpb.LongRunningRecognizeResponse{
+		"chirp-3": recognized(0.9, "the", "deck", "needs", "sealing"),
+	}}
+	config := audio.DefaultTranscriptionConfig()
+	config.Chunking.MinDuration = 30 * time.Second
+	config.Chunking.MaxChunkDuration = 20 * time.Second
+	config.Chunking.SearchWindow = 5 * time.Second
+	ts := newFakeSpeechService(t, fake, config)
+	jm := audio.NewJobManager(nil)
+
+	long := synthesizeSpeech(8000, []struct {
+		d     time.Duration
+		voice bool
+	}{{18 * time.Second, true}, {time.Second, false}, {18 * time.Second, true}, {time.Second, false}, {12 * time.Second, true}})
+	response, err := ts.TranscribeWithJob(ctx, jm, &audio.TranscriptionRequest{
+		AudioURI: "gs://recordings/tenant_1/calls/call_1.mp3", TenantID: "tenant_1", CallID: "call_1", PCM: long,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, response.Metadata["chunk_count"])
+	assert.Equal(t, 3, fake.submitted())
+	for _, req := range fake.requests {
+		assert.Equal(t, speechpb.RecognitionConfig_LINEAR16, req.Config.Encoding)
+		assert.Equal(t, int32(8000), req.Config.SampleRateHertz)
+		assert.NotEmpty(t, req.Audio.GetContent(), "chunks are sent inline")
+	}
+	assert.Contains(t, response.Result.Transcript, "deck needs sealing")
+	assert.InDelta(t, 50, response.Result.Duration, 0.01)
+
+	job, err := jm.GetJob(ctx, response.Metadata["job_id"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, audio.JobStatusCompleted, job.Status.Status)
+
+	// Recordings under the threshold are still read from storage in one operation
+	short := synthesizeSpeech(8000, []struct {
+		d     time.Duration
+		voice bool
+	}{{10 * time.Second, true}})
+	_, err = ts.TranscribeWithJob(ctx, jm, &audio.TranscriptionRequest{
+		AudioURI: "gs://recordings/tenant_1/calls/call_2.mp3", TenantID: "tenant_1", CallID: "call_2", PCM: short,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 4, fake.submitted())
+	assert.Equal(t, "gs://recordings/tenant_1/calls/call_2.mp3", fake.requests[3].Audio.GetUri())
+}
+
+func TestChunkedTranscriptionSendsHighRateAudioWithinInlineLimit(t *testing.T) {
+	fake := &fakeSpeech{responses: map[string]*speechpb.LongRunningRecognizeResponse{
+		"chirp-3": recognized(0.9, "the", "deck", "needs", "sealing"),
+	}}
+	config := audio.DefaultTranscriptionConfig()
+	config.Chunking.MinDuration = time.Minute
+	ts := newFakeSpeechService(t, fake, config)
+
+	// A default-length chunk of 44.1kHz audio would be about 21MB of LINEAR16
+	pcm := &audio.PCMAudio{Samples: make([]int16, 44100*5*60), SampleRate: 44100}
+	response, err := ts.TranscribeWithJob(context.Background(), audio.NewJobManager(nil), &audio.TranscriptionRequest{
+		AudioURI: "gs://recordings/tenant_1/calls/call_1.wav", TenantID: "tenant_1", CallID: "call_1", PCM: pcm,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, response.Metadata["chunk_count"])
+	assert.InDelta(t, 300, response.Result.Duration, 0.01)
+	for _, req := range fake.requests {
+		assert.Equal(t, int32(audio.ChunkSampleRate), req.Config.SampleRateHertz)
+		assert.LessOrEqual(t, len(req.Audio.GetContent()), audio.MaxInlineAudioBytes)
+	}
+}
+
+// diarized builds the response Speech-to-Text gives a diarized recognition of segments: a
+// result per segment with untagged words, then a final result repeating every word with its
+// speaker tag
+func diarized(segments ...[]*speechpb.WordInfo) *speechpb.LongRunningRecognizeResponse {
+	resp := &speechpb.LongRunningRecognizeResponse{}
+	final := &speechpb.SpeechRecognitionAlternative{}
+	for _, segment := range segments {
+		alt := &speechpb.SpeechRecognitionAlternative{Confidence: 0.9}
+		var text []string
+		for _, w := range segment {
+			alt.Words = append(alt.Words, &speechpb.WordInfo{
+				Word: w.Word, StartTime: w.StartTime, EndTime: w.EndTime, Confidence: w.Confidence,
+			})
+			text = append(text, w.Word)
+		}
+		alt.Transcript = strings.Join(text, " ")
+		resp.Results = append(resp.Results, &speechpb.SpeechRecognitionResult{Alternatives: []*speechpb.SpeechRecognitionAlternative{alt}})
+		final.Words = append(final.Words, segment...)
+	}
+	resp.Results = append(resp.Results, &speechpb.SpeechRecognitionResult{Alternatives: []*speechpb.SpeechRecognitionAlternative{final}})
+	return resp
+}
+
+// diarizedRecognition is a diarized recognition of two speakers saying a word pair each
+func diarizedRecognition() *speechpb.LongRunningRecognizeResponse {
+	return diarized(
+		[]*speechpb.WordInfo{word("my", 0, 300*time.Millisecond, 1), word("deck", 400*time.Millisecond, 700*time.Millisecond, 1)},
+		[]*speechpb.WordInfo{word("has", 2*time.Second, 2300*time.Millisecond, 2), word("rot", 2400*time.Millisecond, 2700*time.Millisecond, 2)},
+	)
+}
+
+// transcribeDiarized runs a short, diarized recording through the single-operation path
+// against a scripted response and returns its transcript
+func transcribeDiarized(t *testing.T, response *speechpb.LongRunningRecognizeResponse) *models.TranscriptionResult {
+	t.Helper()
+	fake := &fakeSpeech{responses: map[string]*speechpb.LongRunningRecognizeResponse{"chirp-3": response}}
+	config := audio.DefaultTranscriptionConfig()
+	config.EnableDiarization = true
+	ts := newFakeSpeechService(t, fake, config)
+
+	transcription, err := ts.TranscribeWithJob(context.Background(), audio.NewJobManager(nil), &audio.TranscriptionRequest{
+		AudioURI: "gs://recordings/tenant_1/calls/call_1.mp3", TenantID: "tenant_1", CallID: "call_1",
+	})
+	require.NoError(t, err)
+	require.True(t, transcription.Success)
+	return transcription.Result
+}
+
+func TestTranscriptionKeepsDiarizedWordsOnce(t *testing.T) {
+	result := transcribeDiarized(t, diarizedRecognition())
+
+	words := result.WordDetails
+	require.Len(t, words, 4, "each word is kept once")
+	for _, w := range words {
+		assert.NotZero(t, w.Speaker, "%q keeps its speaker tag", w.Word)
+	}
+	assert.Equal(t, "my", words[0].Word)
+	assert.Equal(t, 1, words[0].Speaker)
+	assert.Equal(t, "rot", words[3].Word)
+	assert.Equal(t, 2, words[3].Speaker)
+
+	require.Len(t, result.SpeakerDiarization, 2)
+	assert.Equal(t, "my deck", result.SpeakerDiarization[0].Text)
+	assert.Equal(t, "has rot", result.SpeakerDiarization[1].Text)
+	assert.Equal(t, 2, result.SpeakerCount)
+	assert.Equal(t, "my deck has rot", result.Transcript)
+	assert.InDelta(t, 0.9, result.Confidence, 0.001)
+}
+
+func TestChunkedTranscriptionKeepsDiarizedWordsOnce(t *testing.T) {
+	fake := &fakeSpeech{responses: map[string]*speechpb.LongRunningRecognizeResponse{"chirp-3": diarizedRecognition()}}
+	config := audio.DefaultTranscriptionConfig()
+	config.EnableDiarization = true
+	config.Chunking.MinDuration = 5 * time.Second
+	ts := newFakeSpeechService(t, fake, config)
+
+	pcm := synthesizeSpeech(8000, []struct {
+		d     time.Duration
+		voice bool
+	}{{10 * time.Second, true}})
+	response, err := ts.TranscribeWithJob(context.Background(), audio.NewJobManager(nil), &audio.TranscriptionRequest{
+		AudioURI: "gs://recordings/tenant_1/calls/call_1.mp3", TenantID: "tenant_1", CallID: "call_1", PCM: pcm,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, response.Metadata["chunk_count"])
+
+	words := response.Result.WordDetails
+	require.Len(t, words, 4, "each word is kept once")
+	assert.Equal(t, "my", words[0].Word)
+	assert.Equal(t, 1, words[0].Speaker)
+	assert.Equal(t, "rot", words[3].Word)
+	assert.Equal(t, 2, words[3].Speaker)
+}
+
+func TestChunkedTranscriptionStaysWithinSchedulerBudget(t *testing.T) {
+	ctx := context.Background()
+	config := audio.DefaultTranscriptionConfig()
+	config.Chunking.MinDuration = 30 * time.Second
+	config.Chunking.MaxChunkDuration = 10 * time.Second
+	config.Chunking.Concurrency = 4
+	config.Scheduling.MaxConcurrent = 2
+	config.Scheduling.MaxPerTenant = 0
+
+	var (
+		ts          *audio.TranscriptionService
+		mu          sync.Mutex
+		inFlight    int
+		maxInFlight int
+		maxRunning  int
+	)
+	fake := &fakeSpeech{responses: map[string]*speechpb.LongRunningRecognizeResponse{
+		"chirp-3": recognized(0.9, "the", "deck", "needs", "sealing"),
+	}}
+	fake.recognizing = func() {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		if running := ts.SchedulerStats().Running; running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}
+	ts = newFakeSpeechService(t, fake, config)
+
+	long := synthesizeSpeech(8000, []struct {
+		d     time.Duration
+		voice bool
+	}{{50 * time.Second, true}})
+	response, err := ts.TranscribeWithJob(ctx, audio.NewJobManager(nil), &audio.TranscriptionRequest{
+		AudioURI: "gs://recordings/tenant_1/calls/call_1.mp3", TenantID: "tenant_1", CallID: "call_1", PCM: long,
+	})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, response.Metadata["chunk_count"], 5)
+	assert.LessOrEqual(t, maxRunning, 2, "chunks never run past the global budget")
+	assert.LessOrEqual(t, maxInFlight, 2)
+	assert.Positive(t, maxRunning, "every chunk runs under a scheduler slot")
+	assert.Zero(t, ts.SchedulerStats().Running, "every slot is released")
+}
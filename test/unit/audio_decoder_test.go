@@ -0,0 +1,68 @@
+package unit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/audio"
+)
+
+// mp3Frame is the start of an MPEG-1 Layer III file, enough for the decoder to hand it to ffmpeg
+var mp3Frame = []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 0, 0xFF, 0xFB, 0x90, 0x64}
+
+// fakeFFmpeg writes a shell script standing in for ffmpeg and returns its path
+func fakeFFmpeg(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg is a shell script")
+	}
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755))
+	return path
+}
+
+func TestDecoderDecodesWAVWithoutFFmpeg(t *testing.T) {
+	original := &audio.PCMAudio{Samples: []int16{0, 1200, -1200, 32767}, SampleRate: 8000}
+	decoder := audio.NewDecoder(&audio.DecoderConfig{})
+
+	pcm, err := decoder.Decode(context.Background(), audio.EncodeWAV(original))
+	require.NoError(t, err)
+	assert.Equal(t, original.Samples, pcm.Samples)
+	assert.Equal(t, 8000, pcm.SampleRate)
+
+	_, err = decoder.Decode(context.Background(), mp3Frame)
+	assert.ErrorIs(t, err, audio.ErrUnsupportedFormat)
+}
+
+func TestDecoderDecodesMP3WithFFmpeg(t *testing.T) {
+	// Answers with two samples, 4096 and -4096, when asked for mono 16 kHz LINEAR16
+	ffmpeg := fakeFFmpeg(t, `
+cat > /dev/null
+case "$*" in
+  *"-i pipe:0 -f s16le -acodec pcm_s16le -ac 1 -ar 16000 pipe:1"*) printf '\000\020\000\360' ;;
+  *) echo "unexpected arguments: $*" >&2; exit 1 ;;
+esac
+`)
+	decoder := audio.NewDecoder(&audio.DecoderConfig{FFmpegPath: ffmpeg, SampleRate: 16000})
+
+	pcm, err := decoder.Decode(context.Background(), mp3Frame)
+	require.NoError(t, err)
+	assert.Equal(t, []int16{4096, -4096}, pcm.Samples)
+	assert.Equal(t, 16000, pcm.SampleRate)
+}
+
+func TestDecoderReportsFFmpegFailures(t *testing.T) {
+	broken := fakeFFmpeg(t, "cat > /dev/null\necho 'Invalid data found when processing input' >&2\nexit 1\n")
+	_, err := audio.NewDecoder(&audio.DecoderConfig{FFmpegPath: broken, SampleRate: 16000}).Decode(context.Background(), mp3Frame)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid data found")
+
+	_, err = audio.NewDecoder(&audio.DecoderConfig{FFmpegPath: "ffmpeg-not-installed", SampleRate: 16000}).Decode(context.Background(), mp3Frame)
+	assert.ErrorIs(t, err, audio.ErrUnsupportedFormat)
+}
@@ -0,0 +1,283 @@
+package unit
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/internal/storage"
+)
+
+func TestObjectKeyLayout(t *testing.T) {
+	assert.Equal(t, "tenant-1/calls/call-1.mp3", storage.RecordingKey("tenant-1", "call-1"))
+	assert.Equal(t, "tenant-1/calls/call-1.redacted.wav", storage.RedactedRecordingKey("tenant-1", "call-1"))
+	assert.Equal(t, "tenant-1/calls/", storage.CallsPrefix("tenant-1"))
+	assert.True(t, strings.HasPrefix(storage.RecordingKey("tenant-1", "call-1"), storage.TenantPrefix("tenant-1")))
+}
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewLocalStore(t.TempDir(), "http://localhost/blobs", "secret")
+	require.NoError(t, err)
+	svc := storage.NewServiceWithStore(store)
+
+	uri, err := svc.StoreAudioFile(ctx, "tenant-1", "call-1", []byte("audio"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(uri, "file://"))
+	_, err = svc.StoreRedactedAudioFile(ctx, "tenant-1", "call-1", []byte("bleeped"))
+	require.NoError(t, err)
+	_, err = svc.StoreAudioFile(ctx, "tenant-2", "call-9", []byte("other tenant"))
+	require.NoError(t, err)
+
+	data, err := svc.GetAudioFile(ctx, "tenant-1", "call-1")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("audio"), data)
+
+	metadata, err := svc.GetAudioFileMetadata(ctx, "tenant-1", "call-1")
+	require.NoError(t, err)
+	assert.Equal(t, "call-1", metadata["call_id"])
+
+	files, err := svc.ListAudioFiles(ctx, "tenant-1")
+	require.NoError(t, err)
+	sort.Strings(files)
+	assert.Equal(t, []string{"tenant-1/calls/call-1.mp3", "tenant-1/calls/call-1.redacted.wav"}, files)
+
+	stats, err := svc.GetStorageStatistics(ctx, "tenant-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.FileCount)
+	assert.Equal(t, int64(len("audio")+len("bleeped")), stats.TotalSizeBytes)
+
+	require.NoError(t, svc.CopyFile(ctx, storage.RecordingKey("tenant-1", "call-1"), storage.RecordingKey("tenant-1", "call-2")))
+	copied, err := store.Stat(ctx, storage.RecordingKey("tenant-1", "call-2"))
+	require.NoError(t, err)
+	assert.Equal(t, "audio/mpeg", copied.ContentType)
+
+	require.NoError(t, svc.DeleteAudioFile(ctx, "tenant-1", "call-1"))
+	_, err = svc.GetAudioFile(ctx, "tenant-1", "call-1")
+	assert.ErrorIs(t, err, storage.ErrObjectNotFound)
+	assert.ErrorIs(t, svc.DeleteAudioFile(ctx, "tenant-1", "call-1"), storage.ErrObjectNotFound)
+
+	// Bucket management is GCS only
+	assert.Error(t, svc.SetLifecyclePolicy(ctx, 30))
+}
+
+func TestLocalStoreRejectsEscapingKeys(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewLocalStore(t.TempDir(), "http://localhost/blobs", "secret")
+	require.NoError(t, err)
+
+	assert.Error(t, store.Put(ctx, "../outside", []byte("x"), nil))
+	assert.Error(t, store.Put(ctx, "tenant/../../outside", []byte("x"), nil))
+	assert.Error(t, store.Put(ctx, "/absolute", []byte("x"), nil))
+	_, err = store.Get(ctx, "tenant/../../etc/passwd")
+	assert.Error(t, err)
+}
+
+func TestLocalStoreSignedURL(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewLocalStore(t.TempDir(), "http://localhost/blobs", "secret")
+	require.NoError(t, err)
+	require.NoError(t, store.Put(ctx, "tenant-1/calls/call-1.mp3", []byte("audio"), &storage.PutOptions{ContentType: "audio/mpeg"}))
+
+	server := httptest.NewServer(http.StripPrefix("/blobs", store.Handler()))
+	defer server.Close()
+
+	signed, err := store.SignedURL(ctx, "tenant-1/calls/call-1.mp3", "GET", time.Minute)
+	require.NoError(t, err)
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+
+	resp, err := http.Get(server.URL + u.RequestURI())
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "audio", string(body))
+	assert.Equal(t, "audio/mpeg", resp.Header.Get("Content-Type"))
+
+	// A URL signed for one key doesn't open another
+	tampered := strings.Replace(u.RequestURI(), "call-1", "call-2", 1)
+	resp, err = http.Get(server.URL + tampered)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	expired, err := store.SignedURL(ctx, "tenant-1/calls/call-1.mp3", "GET", -time.Minute)
+	require.NoError(t, err)
+	u, err = url.Parse(expired)
+	require.NoError(t, err)
+	resp, err = http.Get(server.URL + u.RequestURI())
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestSignedURLServesRedactedCopyOnceStored(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewLocalStore(t.TempDir(), "http://localhost/blobs", "secret")
+	require.NoError(t, err)
+	svc := storage.NewServiceWithStore(store)
+	_, err = svc.StoreAudioFile(ctx, "tenant-1", "call-1", []byte("original"))
+	require.NoError(t, err)
+
+	key, err := svc.PlaybackKey(ctx, "tenant-1", "call-1")
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-1/calls/call-1.mp3", key)
+
+	_, err = svc.StoreRedactedAudioFile(ctx, "tenant-1", "call-1", []byte("bleeped"))
+	require.NoError(t, err)
+	key, err = svc.PlaybackKey(ctx, "tenant-1", "call-1")
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-1/calls/call-1.redacted.wav", key)
+
+	server := httptest.NewServer(http.StripPrefix("/blobs", store.Handler()))
+	defer server.Close()
+	signed, err := svc.GenerateSignedURL(ctx, "tenant-1", "call-1", time.Minute)
+	require.NoError(t, err)
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+	resp, err := http.Get(server.URL + u.RequestURI())
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "bleeped", string(body))
+	assert.Equal(t, "audio/wav", resp.Header.Get("Content-Type"))
+
+	// The original is kept until the call is deleted
+	original, err := svc.GetAudioFile(ctx, "tenant-1", "call-1")
+	require.NoError(t, err)
+	assert.Equal(t, "original", string(original))
+}
+
+// fakeS3 is an in-memory S3 endpoint serving path-style requests for one bucket
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeS3Object
+}
+
+type fakeS3Object struct {
+	data   []byte
+	header http.Header
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=minio/") || r.Header.Get("x-amz-content-sha256") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/audio/")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/audio/":
+		type content struct {
+			Key  string `xml:"Key"`
+			Size int64  `xml:"Size"`
+		}
+		result := struct {
+			XMLName     xml.Name  `xml:"ListBucketResult"`
+			Contents    []content `xml:"Contents"`
+			IsTruncated bool      `xml:"IsTruncated"`
+		}{}
+		prefix := r.URL.Query().Get("prefix")
+		for k, obj := range f.objects {
+			if strings.HasPrefix(k, prefix) {
+				result.Contents = append(result.Contents, content{Key: k, Size: int64(len(obj.data))})
+			}
+		}
+		xml.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodPut && r.Header.Get("x-amz-copy-source") != "":
+		src, ok := f.objects[strings.TrimPrefix(r.Header.Get("x-amz-copy-source"), "/audio/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		f.objects[key] = src
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = fakeS3Object{data: data, header: r.Header.Clone()}
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		obj, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		for name, values := range obj.header {
+			if strings.HasPrefix(strings.ToLower(name), "x-amz-meta-") || name == "Content-Type" {
+				w.Header()[name] = values
+			}
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		if r.Method == http.MethodGet {
+			w.Write(obj.data)
+		}
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestS3StoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeS3{objects: make(map[string]fakeS3Object)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store, err := storage.NewS3Store(storage.S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "audio",
+		AccessKeyID:     "minio",
+		SecretAccessKey: "minio-secret",
+		PathStyle:       true,
+	})
+	require.NoError(t, err)
+	svc := storage.NewServiceWithStore(store)
+
+	uri, err := svc.StoreAudioFile(ctx, "tenant-1", "call-1", []byte("audio"))
+	require.NoError(t, err)
+	assert.Equal(t, "s3://audio/tenant-1/calls/call-1.mp3", uri)
+
+	data, err := svc.GetAudioFile(ctx, "tenant-1", "call-1")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("audio"), data)
+
+	metadata, err := svc.GetAudioFileMetadata(ctx, "tenant-1", "call-1")
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-1", metadata["tenant_id"])
+
+	require.NoError(t, svc.CopyFile(ctx, storage.RecordingKey("tenant-1", "call-1"), storage.RecordingKey("tenant-1", "call-2")))
+	files, err := svc.ListAudioFiles(ctx, "tenant-1")
+	require.NoError(t, err)
+	sort.Strings(files)
+	assert.Equal(t, []string{"tenant-1/calls/call-1.mp3", "tenant-1/calls/call-2.mp3"}, files)
+
+	require.NoError(t, svc.DeleteAudioFile(ctx, "tenant-1", "call-1"))
+	_, err = svc.GetAudioFile(ctx, "tenant-1", "call-1")
+	assert.ErrorIs(t, err, storage.ErrObjectNotFound)
+	assert.ErrorIs(t, svc.DeleteAudioFile(ctx, "tenant-1", "call-1"), storage.ErrObjectNotFound)
+
+	signed, err := svc.GenerateSignedURL(ctx, "tenant-1", "call-2", 15*time.Minute)
+	require.NoError(t, err)
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.Equal(t, "/audio/tenant-1/calls/call-2.mp3", u.Path)
+	assert.Equal(t, "900", u.Query().Get("X-Amz-Expires"))
+	assert.NotEmpty(t, u.Query().Get("X-Amz-Signature"))
+}
@@ -0,0 +1,139 @@
+package unit
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/audio"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// synthesizeTone builds PCM audio of a pure tone at freq
+func synthesizeTone(sampleRate int, freq float64, d time.Duration) []int16 {
+	n := int(d.Seconds() * float64(sampleRate))
+	samples := make([]int16, n)
+	for i := range samples {
+		samples[i] = int16(10000 * math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate)))
+	}
+	return samples
+}
+
+// answeredCall and unansweredCall are CallRail's answered flag when it is known
+var (
+	answeredCall   = func() *bool { v := true; return &v }()
+	unansweredCall = func() *bool { v := false; return &v }()
+)
+
+func TestClassifyAudioSilentRecordingIsNoSpeech(t *testing.T) {
+	pcm := &audio.PCMAudio{Samples: make([]int16, 8000*20), SampleRate: 8000}
+
+	result := audio.NewCallClassifier(nil).ClassifyAudio(pcm, answeredCall, 20*time.Second)
+
+	assert.Equal(t, models.CallClassNoSpeech, result.Class)
+	assert.InDelta(t, 1.0, result.SilenceRatio, 0.001)
+	assert.False(t, result.BeepDetected)
+}
+
+func TestClassifyAudioUnansweredWithBeepIsVoicemail(t *testing.T) {
+	greeting := synthesizeSpeech(8000, []struct {
+		d     time.Duration
+		voice bool
+	}{
+		{5 * time.Second, true},
+		{500 * time.Millisecond, false},
+	})
+	samples := append(greeting.Samples, synthesizeTone(8000, 1000, 400*time.Millisecond)...)
+	samples = append(samples, synthesizeSpeech(8000, []struct {
+		d     time.Duration
+		voice bool
+	}{{8 * time.Second, true}}).Samples...)
+	pcm := &audio.PCMAudio{Samples: samples, SampleRate: 8000}
+
+	result := audio.NewCallClassifier(nil).ClassifyAudio(pcm, unansweredCall, 14*time.Second)
+
+	assert.Equal(t, models.CallClassVoicemail, result.Class)
+	assert.True(t, result.BeepDetected)
+	assert.InDelta(t, 5.5, result.BeepOffset, 0.05)
+}
+
+func TestClassifyAudioFallsBackToMetadata(t *testing.T) {
+	classifier := audio.NewCallClassifier(nil)
+
+	assert.Equal(t, models.CallClassConversation, classifier.ClassifyAudio(nil, answeredCall, 5*time.Minute).Class)
+	assert.Equal(t, models.CallClassVoicemail, classifier.ClassifyAudio(nil, unansweredCall, 40*time.Second).Class)
+	assert.Equal(t, models.CallClassNoSpeech, classifier.ClassifyAudio(nil, unansweredCall, time.Second).Class)
+}
+
+func TestClassifyAudioWithUnknownMetadata(t *testing.T) {
+	classifier := audio.NewCallClassifier(nil)
+	speech := synthesizeSpeech(8000, []struct {
+		d     time.Duration
+		voice bool
+	}{{10 * time.Second, true}})
+
+	// Producers that don't report whether the call was answered, or for how long, get conversations
+	assert.Equal(t, models.CallClassConversation, classifier.ClassifyAudio(nil, nil, 0).Class)
+	assert.Equal(t, models.CallClassConversation, classifier.ClassifyAudio(speech, nil, 0).Class)
+	assert.Equal(t, models.CallClassConversation, classifier.ClassifyAudio(speech, nil, 40*time.Second).Class)
+}
+
+func TestClassifyTranscript(t *testing.T) {
+	classifier := audio.NewCallClassifier(nil)
+
+	ivr := classifier.ClassifyTranscript(nil, "Thank you for calling. Your call is important to us. Press 1 for sales, press 2 for service.", 1)
+	assert.Equal(t, models.CallClassIVROnly, ivr.Class)
+
+	voicemail := classifier.ClassifyTranscript(nil, "You've reached the voicemail of Acme Remodeling, please leave a message.", 1)
+	assert.Equal(t, models.CallClassVoicemail, voicemail.Class)
+
+	// A beep flagged by audio analysis is overridden when two people clearly talked
+	prior := &audio.CallClassification{Class: models.CallClassVoicemail, BeepDetected: true}
+	conversation := classifier.ClassifyTranscript(prior, "Hi, I need a quote for a bathroom. Sure, what is your address?", 2)
+	assert.Equal(t, models.CallClassConversation, conversation.Class)
+
+	empty := classifier.ClassifyTranscript(nil, "  ", 0)
+	assert.Equal(t, models.CallClassNoSpeech, empty.Class)
+}
+
+func TestDecideAppliesTenantPolicy(t *testing.T) {
+	policy := models.CallScreeningConfig{
+		Enabled:                      true,
+		SkipTranscriptionForNoSpeech: true,
+		VoicemailAnalysis:            models.AnalysisModeSummary,
+		IVRAnalysis:                  models.AnalysisModeSkip,
+	}
+
+	noSpeech := audio.Decide(&audio.CallClassification{Class: models.CallClassNoSpeech}, policy)
+	assert.False(t, noSpeech.Transcribe)
+	assert.Equal(t, models.AnalysisModeSkip, noSpeech.AnalysisMode)
+
+	voicemail := audio.Decide(&audio.CallClassification{Class: models.CallClassVoicemail}, policy)
+	assert.True(t, voicemail.Transcribe)
+	assert.Equal(t, models.AnalysisModeSummary, voicemail.AnalysisMode)
+
+	ivr := audio.Decide(&audio.CallClassification{Class: models.CallClassIVROnly}, policy)
+	assert.Equal(t, models.AnalysisModeSkip, ivr.AnalysisMode)
+
+	conversation := audio.Decide(&audio.CallClassification{Class: models.CallClassConversation}, policy)
+	assert.Equal(t, models.AnalysisModeFull, conversation.AnalysisMode)
+
+	// Screening disabled leaves every call on the full pipeline
+	disabled := audio.Decide(&audio.CallClassification{Class: models.CallClassNoSpeech}, models.CallScreeningConfig{})
+	assert.True(t, disabled.Transcribe)
+	assert.Equal(t, models.AnalysisModeFull, disabled.AnalysisMode)
+}
+
+func TestBuildVoicemailLeadStripsGreeting(t *testing.T) {
+	classification := &audio.CallClassification{Class: models.CallClassVoicemail, BeepDetected: true, TotalDuration: 32}
+	transcript := "Please leave your name and number after the tone. Hi this is Dana, I need a roof estimate, please call me back."
+
+	lead := audio.BuildVoicemailLead(classification, transcript, "Dana", "+15551234567")
+
+	assert.Equal(t, "Hi this is Dana, I need a roof estimate, please call me back.", lead.Message)
+	assert.True(t, lead.CallbackRequested)
+	assert.Equal(t, "+15551234567", lead.CallerPhone)
+	assert.Equal(t, models.CallClassVoicemail, lead.Classification)
+}
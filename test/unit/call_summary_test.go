@@ -0,0 +1,111 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/ai"
+	"github.com/home-renovators/ingestion-pipeline/pkg/crm"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// capturedHubSpot records the properties of the contacts sent to HubSpot
+type capturedHubSpot struct {
+	properties map[string]interface{}
+}
+
+func (c *capturedHubSpot) RoundTrip(r *http.Request) (*http.Response, error) {
+	var payload struct {
+		Properties map[string]interface{} `json:"properties"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		return nil, err
+	}
+	c.properties = payload.Properties
+
+	return &http.Response{
+		StatusCode: http.StatusCreated,
+		Body:       io.NopCloser(strings.NewReader(`{"id":"501"}`)),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+	}, nil
+}
+
+func bathroomLead() *crm.Lead {
+	lead := &crm.Lead{FirstName: "Jane", Phone: "+15125550100", ProjectType: "bathroom", Timeline: "1-3_months"}
+	lead.ApplySummary(&models.CallSummary{
+		Summary:       "Jane wants both upstairs bathrooms redone before her in-laws visit in June.",
+		ActionItems:   []string{"Call back Tuesday after 3pm", "Send quote for 2 bathrooms"},
+		Commitments:   []string{"Rep promised tile samples by Friday"},
+		OpenQuestions: []string{"Can the tub stay during the remodel?"},
+	})
+	return lead
+}
+
+func TestCallSummaryAnalysis(t *testing.T) {
+	fake := ai.NewFakeProvider()
+	fake.SetDefault(ai.FakeResponse{Text: `{"summary":"Caller wants two bathrooms redone.",
+		"action_items":"Send quote for 2 bathrooms","commitments":null,"open_questions":[]}`})
+	service := ai.NewAnalysisServiceWithProvider(fake, nil)
+
+	resp, err := service.AnalyzeContent(context.Background(), &ai.AnalysisRequest{
+		Transcription: "We'd like both bathrooms upstairs redone. Can you send a quote?",
+		AnalysisType:  ai.AnalysisTypeSummary,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp.SummaryResult)
+	assert.Equal(t, "Caller wants two bathrooms redone.", resp.SummaryResult.Summary)
+	assert.Equal(t, []string{"Send quote for 2 bathrooms"}, resp.SummaryResult.ActionItems)
+	assert.Empty(t, resp.SummaryResult.Commitments)
+	assert.Equal(t, "call_summary", resp.Metadata.TemplateID)
+	assert.Contains(t, fake.Calls()[0].Prompt, "for the sales rep who will follow up")
+
+	assert.Nil(t, ai.ResponseSchema(ai.AnalysisTypeContent).Properties["summary"],
+		"the content analysis is not asked for a summary")
+}
+
+func TestHubSpotNotesLeadWithCallSummary(t *testing.T) {
+	capture := &capturedHubSpot{}
+	provider := crm.NewHubSpotProviderWithHTTP(&http.Client{Transport: capture})
+
+	_, err := provider.CreateLead(context.Background(), bathroomLead(), &crm.Config{APIKey: "key"})
+	require.NoError(t, err)
+
+	assert.Equal(t, strings.Join([]string{
+		"Summary: Jane wants both upstairs bathrooms redone before her in-laws visit in June.",
+		"Action Items:",
+		"- Call back Tuesday after 3pm",
+		"- Send quote for 2 bathrooms",
+		"Commitments:",
+		"- Rep promised tile samples by Friday",
+		"Open Questions:",
+		"- Can the tub stay during the remodel?",
+		"Project Type: bathroom",
+		"Timeline: 1-3_months",
+	}, "\n"), capture.properties["notes"])
+}
+
+func TestHubSpotMapsCallSummaryToConfiguredFields(t *testing.T) {
+	capture := &capturedHubSpot{}
+	provider := crm.NewHubSpotProviderWithHTTP(&http.Client{Transport: capture})
+
+	_, err := provider.CreateLead(context.Background(), bathroomLead(), &crm.Config{
+		APIKey:       "key",
+		FieldMapping: map[string]string{"summary": "call_summary", "action_items": "next_steps"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Jane wants both upstairs bathrooms redone before her in-laws visit in June.", capture.properties["call_summary"])
+	assert.Equal(t, "- Call back Tuesday after 3pm\n- Send quote for 2 bathrooms", capture.properties["next_steps"])
+
+	notes := capture.properties["notes"].(string)
+	assert.NotContains(t, notes, "Summary:")
+	assert.NotContains(t, notes, "Action Items:")
+	assert.True(t, strings.HasPrefix(notes, "Commitments:\n- Rep promised tile samples by Friday"))
+}
@@ -0,0 +1,119 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/internal/repository"
+	"github.com/home-renovators/ingestion-pipeline/pkg/ai"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// kitchenHistory is a caller who asked for a kitchen quote, then called again without being analyzed
+func kitchenHistory() []models.PriorInteraction {
+	return []models.PriorInteraction{
+		{RequestID: "req_2", Date: time.Date(2026, 9, 14, 15, 0, 0, 0, time.UTC)},
+		{RequestID: "req_1", Date: time.Date(2026, 8, 2, 10, 0, 0, 0, time.UTC), Intent: "quote_request",
+			ProjectType: "kitchen", Summary: "Wants new kitchen cabinets.", CRMStage: "quote sent"},
+	}
+}
+
+func TestPriorInteractionsCarryAnalysisAndCRMStage(t *testing.T) {
+	analysis := `{"intent":"quote_request","project_type":"kitchen","summary":{"summary":"Wants new kitchen cabinets."}}`
+	requests := []*models.Request{
+		{RequestID: "req_2", CreatedAt: time.Date(2026, 9, 14, 15, 0, 0, 0, time.UTC)},
+		{RequestID: "req_1", CreatedAt: time.Date(2026, 8, 2, 10, 0, 0, 0, time.UTC), AIAnalysis: &analysis},
+	}
+	integrations := []*models.CRMIntegration{
+		{Status: "completed", Config: `{"request_id":"req_1","lead_data":{"lead_status":"quote sent"}}`,
+			UpdatedAt: time.Date(2026, 8, 5, 0, 0, 0, 0, time.UTC)},
+		{Status: "completed", Config: `{"request_id":"req_1","lead_data":{"lead_status":"new"}}`,
+			UpdatedAt: time.Date(2026, 8, 2, 0, 0, 0, 0, time.UTC)},
+		{Status: "failed", Config: `{"request_id":"req_2"}`, UpdatedAt: time.Date(2026, 9, 14, 0, 0, 0, 0, time.UTC)},
+		{Status: "completed", Config: `not json`},
+	}
+
+	history := ai.NewPriorInteractions(requests, integrations)
+
+	require.Len(t, history, 2)
+	assert.Equal(t, models.PriorInteraction{RequestID: "req_2", Date: requests[0].CreatedAt, CRMStage: "failed"}, history[0],
+		"without a lead status the integration's status is the stage")
+	assert.Equal(t, kitchenHistory()[1], history[1], "the latest integration wins")
+}
+
+func TestContinuityPointsAtAnEarlierCall(t *testing.T) {
+	analysis := &models.CallAnalysis{Intent: "follow_up", Continuity: &models.CallContinuity{
+		Relationship: models.CallRelationshipFollowUp, RelatedRequestID: "req_1",
+	}}
+	ai.ResolveContinuity(analysis, kitchenHistory())
+	assert.Equal(t, "req_1", analysis.Continuity.RelatedRequestID, "a request from the history is kept")
+
+	analysis.Continuity.RelatedRequestID = "req_made_up"
+	ai.ResolveContinuity(analysis, kitchenHistory())
+	assert.Equal(t, "req_2", analysis.Continuity.RelatedRequestID, "anything else becomes the latest call")
+
+	analysis.Continuity = &models.CallContinuity{Relationship: models.CallRelationshipNewProject, RelatedRequestID: "req_1"}
+	ai.ResolveContinuity(analysis, kitchenHistory())
+	assert.Empty(t, analysis.Continuity.RelatedRequestID, "a new project relates to nothing")
+}
+
+func TestContinuityWithoutAnAnswer(t *testing.T) {
+	analysis := &models.CallAnalysis{Intent: "complaint"}
+	ai.ResolveContinuity(analysis, kitchenHistory())
+	require.NotNil(t, analysis.Continuity)
+	assert.Equal(t, models.CallRelationshipComplaint, analysis.Continuity.Relationship, "falls back to the intent")
+	assert.Equal(t, "req_2", analysis.Continuity.RelatedRequestID)
+
+	analysis = &models.CallAnalysis{Intent: "follow_up", Continuity: &models.CallContinuity{Relationship: models.CallRelationshipFollowUp}}
+	ai.ResolveContinuity(analysis, nil)
+	assert.Equal(t, models.CallRelationshipNewProject, analysis.Continuity.Relationship, "a first-time caller has nothing to follow up")
+}
+
+func TestPromptsShowCallerHistory(t *testing.T) {
+	library := ai.DefaultPromptLibrary()
+	for _, analysisType := range []ai.AnalysisType{ai.AnalysisTypeContent, ai.AnalysisTypeCombined} {
+		rendered, err := library.RenderWithHistory(analysisType, 0, nil, "Any news on my cabinets?", nil, kitchenHistory())
+		require.NoError(t, err)
+		assert.Contains(t, rendered.Text, "CALLER HISTORY (earlier calls from this number, newest first):\n"+
+			"- 2026-09-14 [req_2]: not analyzed\n"+
+			"- 2026-08-02 [req_1]: quote_request, kitchen, CRM stage: quote sent\n  Wants new kitchen cabinets.\n", analysisType)
+		assert.Contains(t, rendered.Text, `"continuity": {`, analysisType)
+
+		firstCall, err := library.Render(analysisType, 0, nil, "Any news on my cabinets?", nil)
+		require.NoError(t, err)
+		assert.NotContains(t, firstCall.Text, "CALLER HISTORY", analysisType)
+		assert.NotContains(t, firstCall.Text, "continuity", "a first-time caller is never asked about continuity")
+	}
+}
+
+func TestMemoryListsCallerRequestsWithinTenant(t *testing.T) {
+	store := repository.NewMemoryStore()
+	ctx := context.Background()
+	for i, call := range []struct {
+		tenantID, requestID, phone string
+	}{
+		{"tenant_a", "req_a1", "+15125550142"},
+		{"tenant_a", "req_a2", "512.555.0142"},
+		{"tenant_b", "req_b1", "+15125550142"},
+		{"tenant_a", "req_a3", "+15125550100"},
+		{"tenant_a", "req_a4", "(512) 555-0142"},
+	} {
+		req := memoryRequest(call.tenantID, call.requestID, "", i)
+		req.Data = `{"customer_phone_number":"` + call.phone + `"}`
+		require.NoError(t, store.CreateRequest(ctx, req))
+	}
+
+	requests, err := store.ListRequestsByCallerPhone(ctx, "tenant_a", "+1 512 555 0142", 2)
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	assert.Equal(t, "req_a4", requests[0].RequestID, "newest first")
+	assert.Equal(t, "req_a2", requests[1].RequestID)
+
+	requests, err = store.ListRequestsByCallerPhone(ctx, "tenant_a", "", 5)
+	require.NoError(t, err)
+	assert.Empty(t, requests, "withheld numbers have no history")
+}
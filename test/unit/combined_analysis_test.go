@@ -0,0 +1,92 @@
+package unit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/ai"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// combinedKitchenAnswer is a combined analysis answer whose sections disagree on purpose
+const combinedKitchenAnswer = `{
+	"call_analysis":{"intent":"quote_request","project_type":"kitchen","timeline":"1-3_months","budget_indicator":"high",
+		"sentiment":"neutral","lead_score":70,"urgency":"medium","appointment_requested":true,"follow_up_required":true,
+		"key_details":["island","quartz"]},
+	"spam":{"spam_likelihood":"2%","confidence":0.97,"indicators":[],"reasoning":"specific project details"},
+	"sentiment":{"sentiment":"Positive","confidence":0.9,"emotional_tone":"excited","customer_satisfaction":"high","key_emotions":["excitement"]},
+	"intent":{"primary_intent":"emergency","confidence":0.4,"secondary_intents":[],"reasoning":"misread"},
+	"lead_score":{"lead_score":91,"confidence":0.8,"scoring_factors":{"project_complexity":8,"buying_readiness":9,
+		"timeline_urgency":7,"budget_capability":9,"engagement_quality":9},"reasoning":"ready to buy"},
+	"summary":{"summary":"Homeowner wants a kitchen remodel with an island and quartz counters this spring.",
+		"action_items":["Send quote for kitchen island"],"commitments":[],"open_questions":["Lead time on quartz?"]},
+	"entities":{"customer_name":null,"email":null,"address":null,"callback_time":null}
+}`
+
+func TestCombinedAnalysisFillsEveryResult(t *testing.T) {
+	fake := ai.NewFakeProvider()
+	fake.SetDefault(ai.FakeResponse{Text: combinedKitchenAnswer, Usage: ai.TokenUsage{InputTokens: 900, OutputTokens: 300}})
+	service := ai.NewAnalysisServiceWithProvider(fake, nil)
+
+	resp, err := service.AnalyzeContent(context.Background(), &ai.AnalysisRequest{
+		CallID:        "CAL1",
+		Transcription: "We want a kitchen island with quartz counters this spring.",
+		AnalysisType:  ai.AnalysisTypeCombined,
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, resp.CallAnalysis)
+	require.NotNil(t, resp.SpamResult)
+	require.NotNil(t, resp.SentimentResult)
+	require.NotNil(t, resp.IntentResult)
+	require.NotNil(t, resp.LeadScoreResult)
+
+	assert.Equal(t, "kitchen", resp.CallAnalysis.ProjectType)
+	assert.Equal(t, 2.0, resp.SpamResult.SpamLikelihood)
+	assert.Equal(t, 9, resp.LeadScoreResult.ScoringFactors.BuyingReadiness)
+
+	// The dedicated sections win where they overlap with the call analysis
+	assert.Equal(t, 91, resp.CallAnalysis.LeadScore)
+	assert.Equal(t, "positive", resp.CallAnalysis.Sentiment)
+	assert.Equal(t, "quote_request", resp.CallAnalysis.Intent, "emergency has no call analysis value")
+	require.NotNil(t, resp.SummaryResult)
+	assert.Equal(t, []string{"Send quote for kitchen island"}, resp.SummaryResult.ActionItems)
+	assert.Same(t, resp.SummaryResult, resp.CallAnalysis.Summary)
+
+	calls := fake.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, ai.CombinedMaxOutputTokens, calls[0].MaxOutputTokens)
+	assert.Equal(t, "combined_analysis", resp.Metadata.TemplateID)
+	assert.Equal(t, int64(300), resp.Metadata.OutputTokens)
+}
+
+func TestCombinedAnalysisReportsMissingSections(t *testing.T) {
+	fake := ai.NewFakeProvider()
+	fake.SetDefault(ai.FakeResponse{Text: `{"call_analysis":{"intent":"other"}}`})
+	service := ai.NewAnalysisServiceWithProvider(fake, nil)
+
+	resp, err := service.AnalyzeContent(context.Background(), &ai.AnalysisRequest{
+		Transcription: "Hello?",
+		AnalysisType:  ai.AnalysisTypeCombined,
+	})
+	require.Error(t, err)
+	assert.True(t, ai.IsValidationError(err))
+	assert.False(t, resp.Success)
+	assert.Len(t, fake.Calls(), 2, "one repair attempt")
+	assert.Contains(t, fake.Calls()[1].Prompt, "spam is missing")
+}
+
+func TestTenantResponseSchemaReachesNestedProjectType(t *testing.T) {
+	cfg := &models.AnalysisPromptConfig{ProjectTypes: []string{"Roof Repair", "gutters"}}
+
+	schema := ai.TenantResponseSchema(ai.AnalysisTypeCombined, cfg)
+	assert.Equal(t, []string{"roof_repair", "gutters", "other"}, schema.Properties["call_analysis"].Properties["project_type"].Enum)
+
+	shared := ai.ResponseSchema(ai.AnalysisTypeCombined)
+	assert.Contains(t, shared.Properties["call_analysis"].Properties["project_type"].Enum, "kitchen",
+		"the shared schema is left untouched")
+	assert.Same(t, shared.Properties["spam"], schema.Properties["spam"], "sections without a project type are shared")
+}
@@ -0,0 +1,370 @@
+package unit
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/internal/storage"
+	"github.com/home-renovators/ingestion-pipeline/pkg/audio"
+	"github.com/home-renovators/ingestion-pipeline/pkg/crm"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+	"github.com/home-renovators/ingestion-pipeline/pkg/privacy"
+)
+
+// fakeSubjectStore keeps a tenant's records in memory
+type fakeSubjectStore struct {
+	requests     []*models.Request
+	recordings   []*models.CallRecording
+	logs         []*models.AIProcessingLog
+	integrations []*models.CRMIntegration
+	events       []*models.WebhookEvent
+	jobs         []*audio.TranscriptionJob
+	holds        []*models.LegalHold
+	saved        []*models.DataSubjectLog
+}
+
+func containsAny(terms []string, values ...string) bool {
+	for _, term := range terms {
+		for _, value := range values {
+			if strings.Contains(strings.ToLower(value), term) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (f *fakeSubjectStore) FindRequests(ctx context.Context, tenantID string, terms []string) ([]*models.Request, error) {
+	var found []*models.Request
+	for _, req := range f.requests {
+		if req.TenantID == tenantID && containsAny(terms, req.Data, req.AIExtracted, req.AINormalized) {
+			found = append(found, req)
+		}
+	}
+	return found, nil
+}
+
+func (f *fakeSubjectStore) FindCRMIntegrations(ctx context.Context, tenantID string, terms []string) ([]*models.CRMIntegration, error) {
+	var found []*models.CRMIntegration
+	for _, integration := range f.integrations {
+		if integration.TenantID == tenantID && containsAny(terms, integration.Config) {
+			found = append(found, integration)
+		}
+	}
+	return found, nil
+}
+
+func (f *fakeSubjectStore) ListCallRecordingsByCall(ctx context.Context, tenantID, callID string) ([]*models.CallRecording, error) {
+	var found []*models.CallRecording
+	for _, recording := range f.recordings {
+		if recording.TenantID == tenantID && recording.CallID == callID {
+			found = append(found, recording)
+		}
+	}
+	return found, nil
+}
+
+func (f *fakeSubjectStore) ListAIProcessingLogsByRequest(ctx context.Context, tenantID, requestID string) ([]*models.AIProcessingLog, error) {
+	var found []*models.AIProcessingLog
+	for _, log := range f.logs {
+		if log.TenantID == tenantID && log.RequestID == requestID {
+			found = append(found, log)
+		}
+	}
+	return found, nil
+}
+
+func (f *fakeSubjectStore) ListWebhookEventsByCall(ctx context.Context, callID string) ([]*models.WebhookEvent, error) {
+	var found []*models.WebhookEvent
+	for _, event := range f.events {
+		if event.CallID != nil && *event.CallID == callID {
+			found = append(found, event)
+		}
+	}
+	return found, nil
+}
+
+func (f *fakeSubjectStore) ListTranscriptionJobs(ctx context.Context, filter audio.JobFilter) ([]*audio.TranscriptionJob, error) {
+	var found []*audio.TranscriptionJob
+	for _, job := range f.jobs {
+		if filter.Matches(job) {
+			found = append(found, job)
+		}
+	}
+	return found, nil
+}
+
+func (f *fakeSubjectStore) ListLegalHolds(ctx context.Context, tenantID string) ([]*models.LegalHold, error) {
+	return f.holds, nil
+}
+
+func (f *fakeSubjectStore) DeleteSubjectRows(ctx context.Context, tenantID string, artifacts *privacy.Artifacts) (int64, error) {
+	deleted := make(map[interface{}]bool)
+	for _, req := range artifacts.Requests {
+		deleted[req] = true
+	}
+	for _, recording := range artifacts.CallRecordings {
+		deleted[recording] = true
+	}
+	for _, log := range artifacts.ProcessingLogs {
+		deleted[log] = true
+	}
+	for _, integration := range artifacts.CRMIntegrations {
+		deleted[integration] = true
+	}
+	for _, event := range artifacts.WebhookEvents {
+		deleted[event] = true
+	}
+	for _, job := range artifacts.TranscriptionJobs {
+		deleted[job] = true
+	}
+
+	f.requests = keep(f.requests, deleted)
+	f.recordings = keep(f.recordings, deleted)
+	f.logs = keep(f.logs, deleted)
+	f.integrations = keep(f.integrations, deleted)
+	f.events = keep(f.events, deleted)
+	f.jobs = keep(f.jobs, deleted)
+	return int64(len(deleted)), nil
+}
+
+func keep[T comparable](items []T, deleted map[interface{}]bool) []T {
+	var kept []T
+	for _, item := range items {
+		if !deleted[item] {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+func (f *fakeSubjectStore) SaveDataSubjectLog(ctx context.Context, log *models.DataSubjectLog) error {
+	f.saved = append(f.saved, log)
+	return nil
+}
+
+// fakeCRMEraser records deleted leads and refuses providers without delete support
+type fakeCRMEraser struct {
+	deleted []string
+}
+
+func (f *fakeCRMEraser) DeleteLead(ctx context.Context, tenantID, provider, leadID string) error {
+	if provider != "hubspot" {
+		return crm.ErrDeleteNotSupported
+	}
+	f.deleted = append(f.deleted, leadID)
+	return nil
+}
+
+// newSubjectFixture stores two calls from the subject, in different phone formats, and one from
+// another caller whose number shares the last four digits
+func newSubjectFixture(t *testing.T) (*fakeSubjectStore, *storage.Service) {
+	t.Helper()
+	ctx := context.Background()
+	blobs, err := storage.NewLocalStore(t.TempDir(), "http://localhost/blobs", "secret")
+	require.NoError(t, err)
+	objects := storage.NewServiceWithStore(blobs)
+
+	call1, call2, call3 := "CAL1", "CAL2", "CAL3"
+	store := &fakeSubjectStore{
+		requests: []*models.Request{
+			{RequestID: "req-1", TenantID: "tenant-1", CallID: &call1, Data: `{"customer_phone_number":"+1 (555) 123-4567"}`, AIExtracted: `{}`},
+			{RequestID: "req-2", TenantID: "tenant-1", CallID: &call2, Data: `{"customer_phone_number":"+19998887777"}`, AIExtracted: `{"email":"Jane@Example.com"}`},
+			{RequestID: "req-3", TenantID: "tenant-1", CallID: &call3, Data: `{"customer_phone_number":"555-000-4567"}`, AIExtracted: `{}`},
+		},
+		recordings: []*models.CallRecording{
+			{RecordingID: "rec-1", TenantID: "tenant-1", CallID: call1},
+			{RecordingID: "rec-3", TenantID: "tenant-1", CallID: call3},
+		},
+		logs: []*models.AIProcessingLog{
+			{LogID: "log-1", TenantID: "tenant-1", RequestID: "req-1"},
+			{LogID: "log-3", TenantID: "tenant-1", RequestID: "req-3"},
+		},
+		integrations: []*models.CRMIntegration{
+			{IntegrationID: "integ-1", TenantID: "tenant-1", CRMType: "hubspot", Config: `{"request_id":"req-1","external_id":"hs-1"}`},
+			{IntegrationID: "integ-2", TenantID: "tenant-1", CRMType: "pipedrive", Config: `{"request_id":"req-2","external_id":"pd-2"}`},
+		},
+		events: []*models.WebhookEvent{
+			{EventID: "evt-1", CallID: &call1},
+			{EventID: "evt-3", CallID: &call3},
+		},
+		jobs: []*audio.TranscriptionJob{
+			{
+				JobID:   "job-1",
+				Request: &audio.TranscriptionRequest{TenantID: "tenant-1", CallID: call1},
+				Status:  &audio.TranscriptionStatus{JobID: "job-1", Status: audio.JobStatusCompleted},
+				Result:  &models.TranscriptionResult{Transcript: "Hi, this is Jane, call me on 555 123 4567"},
+			},
+			{
+				JobID:   "job-3",
+				Request: &audio.TranscriptionRequest{TenantID: "tenant-1", CallID: call3},
+				Status:  &audio.TranscriptionStatus{JobID: "job-3", Status: audio.JobStatusCompleted},
+			},
+		},
+	}
+
+	_, err = objects.StoreAudioFile(ctx, "tenant-1", call1, []byte("RIFF call one"))
+	require.NoError(t, err)
+	_, err = objects.StoreRedactedAudioFile(ctx, "tenant-1", call1, []byte("RIFF call one redacted"))
+	require.NoError(t, err)
+	_, err = objects.StoreAudioFile(ctx, "tenant-1", call3, []byte("RIFF call three"))
+	require.NoError(t, err)
+
+	return store, objects
+}
+
+var janeDoe = privacy.Subject{Phone: "555.123.4567", Email: "jane@example.com"}
+
+func TestSubjectMatching(t *testing.T) {
+	assert.Equal(t, "5551234567", privacy.NormalizePhone("+1 (555) 123-4567"))
+	assert.Equal(t, "5551234567", privacy.NormalizePhone("555-123-4567"))
+	assert.Empty(t, privacy.NormalizePhone("4567"))
+
+	assert.True(t, janeDoe.Matches(`{"caller":{"phone":"15551234567"}}`))
+	assert.True(t, janeDoe.Matches(`{"contacts":[{"email":" JANE@example.com "}]}`))
+	assert.True(t, janeDoe.Matches(`{"phone":5551234567}`))
+	assert.False(t, janeDoe.Matches(`{"phone":"555-000-4567"}`))
+	assert.False(t, janeDoe.Matches(`{"notes":"call jane@example.com back"}`))
+
+	assert.Equal(t, []string{"4567", "jane@example.com"}, janeDoe.SearchTerms())
+	assert.Equal(t, janeDoe.Hash("tenant-1"), privacy.Subject{Phone: "+15551234567", Email: "Jane@Example.com"}.Hash("tenant-1"))
+	assert.NotEqual(t, janeDoe.Hash("tenant-1"), janeDoe.Hash("tenant-2"))
+	assert.False(t, privacy.Subject{Phone: "12"}.Valid())
+}
+
+func TestFindDataSubject(t *testing.T) {
+	store, objects := newSubjectFixture(t)
+	service := privacy.NewService(store, objects, nil)
+
+	artifacts, err := service.Find(context.Background(), "tenant-1", janeDoe)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"CAL1", "CAL2"}, artifacts.CallIDs())
+	assert.Equal(t, models.DataSubjectCounts{
+		Requests:          2,
+		CallRecordings:    1,
+		ProcessingLogs:    1,
+		CRMIntegrations:   2,
+		WebhookEvents:     1,
+		TranscriptionJobs: 1,
+		Objects:           2,
+	}, artifacts.Counts())
+}
+
+func TestDeleteDataSubject(t *testing.T) {
+	ctx := context.Background()
+	store, objects := newSubjectFixture(t)
+	eraser := &fakeCRMEraser{}
+	service := privacy.NewService(store, objects, eraser)
+
+	record, err := service.Delete(ctx, "tenant-1", janeDoe, "privacy@example.com")
+	require.NoError(t, err)
+
+	assert.True(t, record.Verified)
+	// Pipedrive can't delete through its API, so the lead is left for follow-up
+	assert.Equal(t, models.DataSubjectPartial, record.Status)
+	assert.Equal(t, []string{"hs-1"}, eraser.deleted)
+	assert.ElementsMatch(t, []models.CRMErasure{
+		{Provider: "hubspot", ExternalID: "hs-1", Status: "deleted"},
+		{Provider: "pipedrive", ExternalID: "pd-2", Status: "unsupported", Error: crm.ErrDeleteNotSupported.Error()},
+	}, record.CRMErasures)
+	assert.Equal(t, 2, record.Counts.Requests)
+	assert.Equal(t, janeDoe.Hash("tenant-1"), record.SubjectHash)
+	assert.Equal(t, "privacy@example.com", record.RequestedBy)
+
+	// The other caller is untouched
+	require.Len(t, store.requests, 1)
+	assert.Equal(t, "req-3", store.requests[0].RequestID)
+	assert.Len(t, store.recordings, 1)
+	assert.Len(t, store.events, 1)
+	// The transcript kept on the subject's transcription job goes with their call
+	require.Len(t, store.jobs, 1)
+	assert.Equal(t, "job-3", store.jobs[0].JobID)
+	keys, err := objects.ListCallObjects(ctx, "tenant-1", "CAL1")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	keys, err = objects.ListCallObjects(ctx, "tenant-1", "CAL3")
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	require.Len(t, store.saved, 1)
+	assert.Equal(t, record, store.saved[0])
+}
+
+func TestDeleteDataSubjectRespectsLegalHolds(t *testing.T) {
+	ctx := context.Background()
+	store, objects := newSubjectFixture(t)
+	store.holds = []*models.LegalHold{{HoldID: "hold-1", TenantID: "tenant-1", CallID: "CAL1"}}
+	service := privacy.NewService(store, objects, &fakeCRMEraser{})
+
+	record, err := service.Delete(ctx, "tenant-1", janeDoe, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, models.DataSubjectPartial, record.Status)
+	assert.Equal(t, []string{"CAL1"}, record.RetainedCalls)
+	assert.True(t, record.Verified)
+	assert.Equal(t, 1, record.Counts.Requests)
+	assert.Len(t, store.jobs, 2)
+
+	keys, err := objects.ListCallObjects(ctx, "tenant-1", "CAL1")
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	// A tenant-wide hold refuses the deletion but still logs it
+	store.holds = []*models.LegalHold{{HoldID: "hold-2", TenantID: "tenant-1"}}
+	record, err = service.Delete(ctx, "tenant-1", janeDoe, "")
+	assert.ErrorIs(t, err, privacy.ErrTenantOnHold)
+	assert.Equal(t, models.DataSubjectFailed, record.Status)
+	assert.Len(t, store.saved, 2)
+}
+
+func TestExportDataSubject(t *testing.T) {
+	store, objects := newSubjectFixture(t)
+	service := privacy.NewService(store, objects, nil)
+
+	var bundle bytes.Buffer
+	record, err := service.Export(context.Background(), "tenant-1", janeDoe, "privacy@example.com", &bundle)
+	require.NoError(t, err)
+	assert.Equal(t, models.DataSubjectExport, record.RequestType)
+	assert.Equal(t, models.DataSubjectCompleted, record.Status)
+	require.Len(t, store.saved, 1)
+
+	reader, err := zip.NewReader(bytes.NewReader(bundle.Bytes()), int64(bundle.Len()))
+	require.NoError(t, err)
+	files := make(map[string][]byte)
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[file.Name] = data
+	}
+
+	assert.Contains(t, files, "requests/req-1.json")
+	assert.Contains(t, files, "requests/req-2.json")
+	assert.NotContains(t, files, "requests/req-3.json")
+	assert.Contains(t, files, "crm_integrations/integ-1.json")
+	assert.Contains(t, files, "transcription_jobs/job-1.json")
+	assert.NotContains(t, files, "transcription_jobs/job-3.json")
+	assert.Equal(t, "RIFF call one", string(files["recordings/CAL1.mp3"]))
+	assert.Equal(t, "RIFF call one redacted", string(files["recordings/CAL1.redacted.wav"]))
+
+	var manifest struct {
+		Files  []string                 `json:"files"`
+		Counts models.DataSubjectCounts `json:"counts"`
+		At     time.Time                `json:"generated_at"`
+	}
+	require.NoError(t, json.Unmarshal(files["manifest.json"], &manifest))
+	assert.Len(t, manifest.Files, len(files)-1)
+	assert.Equal(t, 2, manifest.Counts.Requests)
+}
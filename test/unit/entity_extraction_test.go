@@ -0,0 +1,158 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/ai"
+	"github.com/home-renovators/ingestion-pipeline/pkg/crm"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+const deckTranscript = "Hi, my name is Marcus O'Neil. I'm at 418 Birch Lane in Round Rock, 78664. " +
+	"You can email me at marcus.oneil@example.com, or call me back tomorrow morning."
+
+// deckCall is what CallRail knows about the caller in deckTranscript
+func deckCall() *models.CallDetails {
+	return &models.CallDetails{
+		ID:                  "CAL9",
+		CustomerName:        "WIRELESS CALLER",
+		CustomerPhoneNumber: "+15125550199",
+		CustomerCity:        "Austin",
+		CustomerState:       "TX",
+		CustomerCountry:     "US",
+		StartTime:           time.Date(2026, 5, 4, 9, 30, 0, 0, time.UTC),
+	}
+}
+
+func TestEntityExtractionNormalizesAndLocatesQuotes(t *testing.T) {
+	fake := ai.NewFakeProvider()
+	fake.SetDefault(ai.FakeResponse{Text: `{
+		"customer_name":{"value":"MARCUS O'NEIL","confidence":0.95,"quote":"my name is Marcus O'Neil"},
+		"email":{"value":" Marcus.ONeil@Example.com ","confidence":0.9,"quote":"marcus.oneil@example.com"},
+		"address":{"street":"418 Birch Lane","city":"round rock","state":"tx","zip_code":"78664","confidence":0.85,
+			"quote":"418 Birch Lane in Round Rock, 78664"},
+		"callback_time":{"value":"tomorrow morning","confidence":0.8,"quote":"call me back tomorrow morning"}}`})
+	service := ai.NewAnalysisServiceWithProvider(fake, nil)
+
+	resp, err := service.AnalyzeContent(context.Background(), &ai.AnalysisRequest{
+		Transcription: deckTranscript,
+		CallDetails:   deckCall(),
+		AnalysisType:  ai.AnalysisTypeEntities,
+	})
+	require.NoError(t, err)
+	entities := resp.EntitiesResult
+	require.NotNil(t, entities)
+	assert.Equal(t, "entity_extraction", resp.Metadata.TemplateID)
+	assert.Contains(t, fake.Calls()[0].Prompt, "CALLER ID NAME: WIRELESS CALLER")
+
+	require.NotNil(t, entities.CustomerName)
+	assert.Equal(t, "Marcus O'Neil", entities.CustomerName.Value)
+	require.NotNil(t, entities.CustomerName.Span)
+	span := entities.CustomerName.Span
+	assert.Equal(t, "my name is Marcus O'Neil", deckTranscript[span.Start:span.End])
+
+	require.NotNil(t, entities.Email)
+	assert.Equal(t, "marcus.oneil@example.com", entities.Email.Value)
+
+	require.NotNil(t, entities.Address)
+	assert.Equal(t, "Round Rock", entities.Address.City)
+	assert.Equal(t, "TX", entities.Address.State)
+	require.NotNil(t, entities.Address.Span)
+	assert.Equal(t, 0.85, entities.Address.Confidence)
+
+	require.NotNil(t, entities.CallbackTime)
+	assert.Equal(t, 0.8, entities.CallbackTime.Confidence)
+}
+
+func TestResolveEntitiesDropsWhatTheTranscriptDoesNotSupport(t *testing.T) {
+	entities := &models.CallEntities{
+		CustomerName: &models.ExtractedEntity{Value: "Marcus O'Neil", Confidence: 0.9, Quote: "MY NAME IS MARCUS O'NEIL"},
+		Email:        &models.ExtractedEntity{Value: "marcus at example", Confidence: 0.9, Quote: "email me"},
+		Address:      &models.ExtractedAddress{Street: "418 Birch Lane", ZipCode: "7866", Confidence: 0.9, Quote: "418 Birch Road"},
+		CallbackTime: &models.ExtractedEntity{Value: "  ", Confidence: 0.9},
+	}
+
+	ai.ResolveEntities(entities, deckTranscript)
+
+	require.NotNil(t, entities.CustomerName)
+	assert.NotNil(t, entities.CustomerName.Span, "quotes are matched ignoring case")
+	assert.Equal(t, "Marcus O'Neil", entities.CustomerName.Value, "mixed-case names are kept as given")
+	assert.Nil(t, entities.Email, "not an email address")
+	assert.Nil(t, entities.CallbackTime, "no value")
+
+	require.NotNil(t, entities.Address)
+	assert.Empty(t, entities.Address.ZipCode, "not a ZIP code")
+	assert.Nil(t, entities.Address.Span)
+	assert.Equal(t, ai.UnverifiedEntityConfidence, entities.Address.Confidence, "the quote is not in the transcript")
+}
+
+func TestLeadPrefersSpokenDetailsOverCallerID(t *testing.T) {
+	lead := crm.NewLeadFromCall("tenant_a", deckCall())
+	require.Equal(t, "WIRELESS", lead.FirstName)
+	require.Equal(t, "Austin", lead.City)
+
+	lead.Email = "marcus@old-form.example.com"
+	lead.ApplyEntities(&models.CallEntities{
+		CustomerName: &models.ExtractedEntity{Value: "Marcus O'Neil", Confidence: 0.95},
+		Email:        &models.ExtractedEntity{Value: "marcus.oneil@example.com", Confidence: 0.9},
+		Address: &models.ExtractedAddress{Street: "418 Birch Lane", City: "Round Rock", State: "TX", ZipCode: "78664",
+			Confidence: 0.85},
+		CallbackTime: &models.ExtractedEntity{Value: "tomorrow morning", Confidence: crm.MinEntityConfidence - 0.1},
+	})
+
+	assert.Equal(t, "Marcus", lead.FirstName)
+	assert.Equal(t, "O'Neil", lead.LastName)
+	assert.Equal(t, "Marcus O'Neil", lead.FullName)
+	assert.Equal(t, "WIRELESS CALLER", lead.CustomFields["caller_id_name"])
+	assert.Equal(t, "marcus@old-form.example.com", lead.Email, "an email already on the lead wins")
+	assert.Equal(t, "418 Birch Lane", lead.Address)
+	assert.Equal(t, "Round Rock", lead.City, "the spoken address beats the phone number's location")
+	assert.Equal(t, "78664", lead.ZipCode)
+	assert.Empty(t, lead.CallbackTime, "below the confidence threshold")
+	assert.Equal(t, "+15125550199", lead.Phone)
+}
+
+func TestLeadKeepsCallerIDWhenNothingConfidentWasSaid(t *testing.T) {
+	lead := crm.NewLeadFromCall("tenant_a", &models.CallDetails{CustomerName: "Priya Raman", CustomerCity: "Austin"})
+	lead.ApplyEntities(&models.CallEntities{
+		CustomerName: &models.ExtractedEntity{Value: "Maya Ramen", Confidence: ai.UnverifiedEntityConfidence},
+		Address:      &models.ExtractedAddress{City: "Dallas", Confidence: 0.4},
+	})
+
+	assert.Equal(t, "Priya Raman", lead.FullName)
+	assert.Equal(t, "Austin", lead.City)
+	assert.NotContains(t, lead.CustomFields, "caller_id_name")
+}
+
+func TestHubSpotSendsExtractedContactDetails(t *testing.T) {
+	lead := crm.NewLeadFromCall("tenant_a", deckCall())
+	lead.ApplyEntities(&models.CallEntities{
+		Address:      &models.ExtractedAddress{Street: "418 Birch Lane", City: "Round Rock", State: "TX", ZipCode: "78664", Confidence: 0.9},
+		CallbackTime: &models.ExtractedEntity{Value: "tomorrow morning", Confidence: 0.9},
+	})
+
+	capture := &capturedHubSpot{}
+	provider := crm.NewHubSpotProviderWithHTTP(&http.Client{Transport: capture})
+	_, err := provider.CreateLead(context.Background(), lead, &crm.Config{APIKey: "key"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "418 Birch Lane", capture.properties["address"])
+	assert.Equal(t, "78664", capture.properties["zip"])
+	assert.Equal(t, "Round Rock", capture.properties["city"])
+	assert.True(t, strings.HasSuffix(capture.properties["notes"].(string), "Preferred Callback: tomorrow morning"))
+
+	_, err = provider.CreateLead(context.Background(), lead, &crm.Config{
+		APIKey:       "key",
+		FieldMapping: map[string]string{"callback_time": "best_time_to_call"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "tomorrow morning", capture.properties["best_time_to_call"])
+	assert.Nil(t, capture.properties["notes"], "nothing else to note")
+}
@@ -0,0 +1,251 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/speech/apiv1/speechpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/internal/encryption"
+	"github.com/home-renovators/ingestion-pipeline/internal/repository"
+	"github.com/home-renovators/ingestion-pipeline/internal/storage"
+	"github.com/home-renovators/ingestion-pipeline/pkg/audio"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+func newTestEnvelope(t *testing.T, store encryption.KeyStore) *encryption.Envelope {
+	t.Helper()
+	wrapper, err := encryption.NewLocalKeyWrapper(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32)))
+	require.NoError(t, err)
+	return encryption.NewEnvelope(store, wrapper, nil)
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnvelope(t, encryption.NewMemoryKeyStore())
+
+	sealed, err := env.EncryptField(ctx, "tenant-1", encryption.PurposeTranscription, `{"transcript":"hello"}`)
+	require.NoError(t, err)
+	assert.True(t, encryption.IsEncryptedField(sealed))
+	assert.NotContains(t, sealed, "hello")
+
+	opened, err := env.DecryptField(ctx, "tenant-1", encryption.PurposeTranscription, sealed)
+	require.NoError(t, err)
+	assert.Equal(t, `{"transcript":"hello"}`, opened)
+
+	// Rows written before encryption was enabled read as plaintext
+	legacy, err := env.DecryptField(ctx, "tenant-1", encryption.PurposeTranscription, `{"transcript":"old"}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"transcript":"old"}`, legacy)
+
+	// A ciphertext can't be read as another tenant's or moved to another column
+	_, err = env.DecryptField(ctx, "tenant-2", encryption.PurposeTranscription, sealed)
+	assert.Error(t, err)
+	_, err = env.DecryptField(ctx, "tenant-1", encryption.PurposeAIAnalysis, sealed)
+	assert.Error(t, err)
+}
+
+func TestEnvelopeRotationKeepsOldDataReadable(t *testing.T) {
+	ctx := context.Background()
+	store := encryption.NewMemoryKeyStore()
+	env := newTestEnvelope(t, store)
+
+	before, err := env.Encrypt(ctx, "tenant-1", encryption.PurposeAudio, []byte("first"))
+	require.NoError(t, err)
+
+	key, err := env.RotateTenantKey(ctx, "tenant-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), key.KeyVersion)
+
+	after, err := env.Encrypt(ctx, "tenant-1", encryption.PurposeAudio, []byte("second"))
+	require.NoError(t, err)
+
+	plaintext, err := env.Decrypt(ctx, "tenant-1", encryption.PurposeAudio, before)
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(plaintext))
+	plaintext, err = env.Decrypt(ctx, "tenant-1", encryption.PurposeAudio, after)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(plaintext))
+
+	keys, err := store.ListTenantKeys(ctx, "tenant-1")
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, models.DataKeyStateRetired, keys[0].State)
+	assert.Equal(t, models.DataKeyStateActive, keys[1].State)
+
+	// Another instance sharing the key store reads data written after the rotation
+	other := newTestEnvelope(t, store)
+	plaintext, err = other.Decrypt(ctx, "tenant-1", encryption.PurposeAudio, after)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(plaintext))
+}
+
+func TestEnvelopeShredding(t *testing.T) {
+	ctx := context.Background()
+	store := encryption.NewMemoryKeyStore()
+	env := newTestEnvelope(t, store)
+
+	sealed, err := env.Encrypt(ctx, "tenant-1", encryption.PurposeAudio, []byte("recording"))
+	require.NoError(t, err)
+	other, err := env.Encrypt(ctx, "tenant-2", encryption.PurposeAudio, []byte("recording"))
+	require.NoError(t, err)
+
+	require.NoError(t, env.ShredTenant(ctx, "tenant-1"))
+
+	_, err = env.Decrypt(ctx, "tenant-1", encryption.PurposeAudio, sealed)
+	assert.ErrorIs(t, err, encryption.ErrTenantShredded)
+	_, err = env.Encrypt(ctx, "tenant-1", encryption.PurposeAudio, []byte("new"))
+	assert.ErrorIs(t, err, encryption.ErrTenantShredded)
+	_, err = env.RotateTenantKey(ctx, "tenant-1")
+	assert.ErrorIs(t, err, encryption.ErrTenantShredded)
+
+	keys, err := store.ListTenantKeys(ctx, "tenant-1")
+	require.NoError(t, err)
+	for _, key := range keys {
+		assert.Empty(t, key.WrappedKey)
+		assert.NotNil(t, key.DestroyedAt)
+	}
+
+	// Other tenants are unaffected
+	plaintext, err := env.Decrypt(ctx, "tenant-2", encryption.PurposeAudio, other)
+	require.NoError(t, err)
+	assert.Equal(t, "recording", string(plaintext))
+
+	// A tenant shredded before it ever stored data can't start again either
+	require.NoError(t, env.ShredTenant(ctx, "tenant-3"))
+	_, err = env.Encrypt(ctx, "tenant-3", encryption.PurposeAudio, []byte("new"))
+	assert.ErrorIs(t, err, encryption.ErrTenantShredded)
+}
+
+func TestEnvelopeRejectsForeignMasterKey(t *testing.T) {
+	ctx := context.Background()
+	store := encryption.NewMemoryKeyStore()
+	env := newTestEnvelope(t, store)
+	sealed, err := env.Encrypt(ctx, "tenant-1", encryption.PurposeAudio, []byte("recording"))
+	require.NoError(t, err)
+
+	wrapper, err := encryption.NewLocalKeyWrapper(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{9}, 32)))
+	require.NoError(t, err)
+	_, err = encryption.NewEnvelope(store, wrapper, nil).Decrypt(ctx, "tenant-1", encryption.PurposeAudio, sealed)
+	assert.Error(t, err)
+
+	_, err = encryption.NewLocalKeyWrapper(base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.Error(t, err)
+}
+
+func TestStorageEncryptsRecordings(t *testing.T) {
+	ctx := context.Background()
+	blobs, err := storage.NewLocalStore(t.TempDir(), "http://localhost/blobs", "secret")
+	require.NoError(t, err)
+
+	// A recording stored before encryption was enabled
+	plain := storage.NewServiceWithStore(blobs)
+	_, err = plain.StoreAudioFile(ctx, "tenant-1", "legacy", []byte("RIFF legacy audio"))
+	require.NoError(t, err)
+
+	svc := storage.NewServiceWithStore(blobs)
+	svc.SetEnvelope(newTestEnvelope(t, encryption.NewMemoryKeyStore()))
+
+	_, err = svc.StoreAudioFile(ctx, "tenant-1", "call-1", []byte("RIFF call audio"))
+	require.NoError(t, err)
+
+	raw, err := blobs.Get(ctx, storage.RecordingKey("tenant-1", "call-1"))
+	require.NoError(t, err)
+	assert.True(t, encryption.IsEncrypted(raw))
+	assert.NotContains(t, string(raw), "call audio")
+
+	info, err := blobs.Stat(ctx, storage.RecordingKey("tenant-1", "call-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "true", info.Metadata["encrypted"])
+	assert.Equal(t, "audio/mpeg", info.Metadata["original_content_type"])
+
+	data, err := svc.GetAudioFile(ctx, "tenant-1", "call-1")
+	require.NoError(t, err)
+	assert.Equal(t, "RIFF call audio", string(data))
+
+	data, err = svc.GetAudioFile(ctx, "tenant-1", "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, "RIFF legacy audio", string(data))
+
+	// Without the envelope the ciphertext is refused rather than handed out as audio
+	_, err = plain.GetAudioFile(ctx, "tenant-1", "call-1")
+	assert.Error(t, err)
+
+	_, err = svc.GenerateSignedURL(ctx, "tenant-1", "call-1", time.Minute)
+	assert.Error(t, err)
+}
+
+func TestMemoryStoreSealsExtractedContactDetails(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	store.SetEnvelope(newTestEnvelope(t, store))
+
+	req := memoryRequest("tenant_a", "req_1", "CAL1", 0)
+	require.NoError(t, store.CreateRequest(ctx, req))
+	require.NoError(t, store.UpdateRequestExtraction(ctx, "tenant_a", "req_1", `{"email":{"value":"jane@example.com","confidence":0.9}}`))
+
+	stored, err := store.GetRequest(ctx, "tenant_a", "req_1")
+	require.NoError(t, err)
+	assert.Contains(t, stored.AIExtracted, "jane@example.com")
+
+	found, err := store.FindRequests(ctx, "tenant_a", []string{"jane@example.com"})
+	require.NoError(t, err)
+	require.Len(t, found, 1, "sealed contact details are searched once decrypted")
+
+	// Without the keys the sealed column can't be read back
+	store.SetEnvelope(nil)
+	_, err = store.GetRequest(ctx, "tenant_a", "req_1")
+	assert.ErrorContains(t, err, "encryption is not configured")
+}
+
+func TestEncryptedRecordingsAreOnlySentInline(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeSpeech{responses: map[string]*speechpb.LongRunningRecognizeResponse{
+		"chirp-3":    recognized(0.4, "uh", "deck", "mumble", "rot"),
+		"phone_call": recognized(0.93, "my", "deck", "has", "rot"),
+	}}
+	ts := newFakeSpeechService(t, fake, nil)
+	jm := audio.NewJobManager(nil)
+	encrypted := func(callID string) *audio.TranscriptionRequest {
+		return &audio.TranscriptionRequest{
+			AudioURI: "gs://recordings/tenant_1/calls/" + callID + ".mp3", TenantID: "tenant_1", CallID: callID, Encrypted: true,
+		}
+	}
+
+	// Without the decrypted audio nothing is sent, rather than the ciphertext's URI
+	_, err := ts.TranscribeWithJob(ctx, jm, encrypted("call_1"))
+	assert.Error(t, err)
+	assert.Zero(t, fake.submitted())
+
+	// Quality retries send the decrypted audio again
+	req := encrypted("call_2")
+	req.AudioContent = []byte("decrypted mp3")
+	response, err := ts.TranscribeWithQuality(ctx, jm, req)
+	require.NoError(t, err)
+	require.Len(t, response.Result.Attempts, 2)
+	require.Equal(t, 2, fake.submitted())
+	for _, sent := range fake.requests {
+		assert.Equal(t, []byte("decrypted mp3"), sent.Audio.GetContent())
+		assert.Empty(t, sent.Audio.GetUri())
+	}
+
+	// Recordings over the inline limit are sent in chunks from the decoded audio
+	big := encrypted("call_3")
+	big.AudioContent = make([]byte, audio.MaxInlineAudioBytes+1)
+	_, err = ts.TranscribeWithJob(ctx, jm, big)
+	assert.Error(t, err, "too big to send inline and not decoded")
+	assert.Equal(t, 2, fake.submitted())
+
+	big.PCM = &audio.PCMAudio{Samples: make([]int16, 8000*30), SampleRate: 8000}
+	response, err = ts.TranscribeWithJob(ctx, jm, big)
+	require.NoError(t, err)
+	assert.Equal(t, 1, response.Metadata["chunk_count"])
+	require.Equal(t, 3, fake.submitted())
+	assert.Equal(t, speechpb.RecognitionConfig_LINEAR16, fake.requests[2].Config.Encoding)
+	assert.Len(t, fake.requests[2].Audio.GetContent(), 8000*30*2)
+}
@@ -0,0 +1,274 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/ai"
+	"github.com/home-renovators/ingestion-pipeline/pkg/eval"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// scriptedAnalyzer answers content analyses and spam detections per example ID
+type scriptedAnalyzer struct {
+	mu       sync.Mutex
+	content  map[string]*models.CallAnalysis
+	spam     map[string]float64
+	failing  map[string]bool
+	requests []*ai.AnalysisRequest
+}
+
+func (s *scriptedAnalyzer) AnalyzeContent(ctx context.Context, req *ai.AnalysisRequest) (*ai.AnalysisResponse, error) {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+
+	resp := &ai.AnalysisResponse{Request: req}
+	resp.Metadata.InputTokens = 100
+	resp.Metadata.OutputTokens = 10
+	if s.failing[req.CallID] {
+		return resp, errors.New("model output unusable")
+	}
+	// A combined analysis answers both
+	if req.AnalysisType != ai.AnalysisTypeSpam {
+		resp.CallAnalysis = s.content[req.CallID]
+		if resp.CallAnalysis == nil {
+			resp.CallAnalysis = &models.CallAnalysis{}
+		}
+	}
+	if req.AnalysisType != ai.AnalysisTypeContent {
+		resp.SpamResult = &ai.SpamAnalysisResult{SpamLikelihood: s.spam[req.CallID]}
+	}
+	resp.Success = true
+	return resp, nil
+}
+
+const evalDataset = `# labeled calls
+{"id":"c1","transcript":"I want a new kitchen","expected":{"intent":"quote_request","project_type":"kitchen","lead_score_band":"high"}}
+{"id":"c2","transcript":"Your warranty is expiring","expected":{"spam":true}}
+
+{"id":"c3","transcript":"When is my bathroom crew coming?","expected":{"intent":"status_check","project_type":"bathroom","lead_score_band":"low","spam":false}}
+{"id":"c4","transcript":"Looking at a deck, maybe next year","expected":{"intent":"quote_request","project_type":"other","lead_score_band":"medium"}}
+`
+
+func TestLoadDatasetReadsLabeledExamples(t *testing.T) {
+	examples, err := eval.LoadDataset(strings.NewReader(evalDataset))
+	require.NoError(t, err)
+	require.Len(t, examples, 4)
+
+	assert.Equal(t, "c1", examples[0].ID)
+	assert.Equal(t, "kitchen", examples[0].Expected.ProjectType)
+	require.NotNil(t, examples[1].Expected.Spam)
+	assert.True(t, *examples[1].Expected.Spam)
+	assert.Nil(t, examples[0].Expected.Spam)
+}
+
+func TestLoadDatasetRejectsInvalidExamples(t *testing.T) {
+	cases := map[string]string{
+		"duplicate id": `{"id":"a","transcript":"hi","expected":{"intent":"other"}}
+{"id":"a","transcript":"hi again","expected":{"intent":"other"}}`,
+		"no labels":     `{"id":"a","transcript":"hi","expected":{}}`,
+		"unknown band":  `{"id":"a","transcript":"hi","expected":{"lead_score_band":"huge"}}`,
+		"no transcript": `{"id":"a","transcript":" ","expected":{"intent":"other"}}`,
+		"bad json":      `{"id":`,
+		"empty":         "# nothing here\n",
+	}
+	for name, dataset := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := eval.LoadDataset(strings.NewReader(dataset))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLeadScoreBand(t *testing.T) {
+	assert.Equal(t, eval.BandLow, eval.LeadScoreBand(1))
+	assert.Equal(t, eval.BandLow, eval.LeadScoreBand(39))
+	assert.Equal(t, eval.BandMedium, eval.LeadScoreBand(40))
+	assert.Equal(t, eval.BandMedium, eval.LeadScoreBand(69))
+	assert.Equal(t, eval.BandHigh, eval.LeadScoreBand(70))
+	assert.Equal(t, eval.BandHigh, eval.LeadScoreBand(100))
+}
+
+func TestScoreFieldComputesPerClassMetrics(t *testing.T) {
+	metrics := eval.ScoreField([][2]string{
+		{"kitchen", "kitchen"},
+		{"kitchen", "bathroom"},
+		{"bathroom", "bathroom"},
+		{"roofing", "roofing"},
+	})
+
+	assert.Equal(t, 4, metrics.Support)
+	assert.InDelta(t, 0.75, metrics.Accuracy, 1e-9)
+
+	kitchen := metrics.Classes["kitchen"]
+	assert.Equal(t, 2, kitchen.Support)
+	assert.InDelta(t, 1.0, kitchen.Precision, 1e-9)
+	assert.InDelta(t, 0.5, kitchen.Recall, 1e-9)
+	assert.InDelta(t, 2.0/3, kitchen.F1, 1e-9)
+
+	bathroom := metrics.Classes["bathroom"]
+	assert.InDelta(t, 0.5, bathroom.Precision, 1e-9)
+	assert.InDelta(t, 1.0, bathroom.Recall, 1e-9)
+
+	assert.InDelta(t, (2.0/3+2.0/3+1)/3, metrics.MacroF1, 1e-9)
+	assert.Equal(t, 1, metrics.Confusion["kitchen"]["bathroom"])
+	assert.Equal(t, 1, metrics.Confusion["kitchen"]["kitchen"])
+}
+
+func TestSpearman(t *testing.T) {
+	assert.InDelta(t, 1.0, eval.Spearman([]float64{10, 20, 30, 40}, []float64{1, 2, 3, 4}), 1e-9)
+	assert.InDelta(t, -1.0, eval.Spearman([]float64{10, 20, 30, 40}, []float64{4, 3, 2, 1}), 1e-9)
+	// Ties take their average rank
+	assert.InDelta(t, 0.8944, eval.Spearman([]float64{1, 2, 3, 4}, []float64{1, 1, 2, 2}), 1e-4)
+	assert.Equal(t, 0.0, eval.Spearman([]float64{5, 5, 5}, []float64{1, 2, 3}))
+	assert.Equal(t, 0.0, eval.Spearman([]float64{1}, []float64{1}))
+}
+
+func TestRunnerScoresVariantAgainstLabels(t *testing.T) {
+	examples, err := eval.LoadDataset(strings.NewReader(evalDataset))
+	require.NoError(t, err)
+
+	analyzer := &scriptedAnalyzer{
+		content: map[string]*models.CallAnalysis{
+			"c1": {Intent: "quote_request", ProjectType: "kitchen", LeadScore: 85},
+			"c3": {Intent: "status_check", ProjectType: "kitchen", LeadScore: 20},
+			"c4": {Intent: "quote_request", ProjectType: "other", LeadScore: 55},
+		},
+		spam: map[string]float64{"c2": 92, "c3": 8},
+	}
+	runner := eval.DefaultRunner()
+	variant := eval.Variant{Name: "baseline", Model: "gemini-1.5-pro", TemplateVersion: 1}
+
+	report, err := runner.Run(context.Background(), analyzer, variant, examples)
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, report.Examples)
+	assert.Zero(t, report.Failures)
+	assert.InDelta(t, 1.0, report.Fields[eval.FieldIntent].Accuracy, 1e-9)
+	assert.InDelta(t, 2.0/3, report.Fields[eval.FieldProjectType].Accuracy, 1e-9)
+	assert.InDelta(t, 1.0, report.Fields[eval.FieldLeadScoreBand].Accuracy, 1e-9)
+	assert.InDelta(t, 1.0, report.Fields[eval.FieldSpam].Accuracy, 1e-9)
+	assert.InDelta(t, 1.0, report.Correlations[eval.CorrelationLeadScore], 1e-9)
+	assert.InDelta(t, 1.0, report.Correlations[eval.CorrelationSpam], 1e-9)
+
+	// c3 carries content and spam labels, so it is analyzed twice
+	assert.Len(t, analyzer.requests, 5)
+	for _, req := range analyzer.requests {
+		assert.Equal(t, 1, req.TemplateVersion)
+	}
+	assert.Equal(t, ai.TokenUsage{InputTokens: 500, OutputTokens: 50}, report.Usage)
+}
+
+func TestRunnerScoresFailedAnalysesAsWrong(t *testing.T) {
+	examples, err := eval.LoadDataset(strings.NewReader(evalDataset))
+	require.NoError(t, err)
+
+	analyzer := &scriptedAnalyzer{
+		content: map[string]*models.CallAnalysis{
+			"c1": {Intent: "quote_request", ProjectType: "kitchen", LeadScore: 85},
+			"c4": {Intent: "quote_request", ProjectType: "other", LeadScore: 55},
+		},
+		spam:    map[string]float64{"c2": 92},
+		failing: map[string]bool{"c3": true},
+	}
+
+	report, err := eval.DefaultRunner().Run(context.Background(), analyzer, eval.Variant{Name: "baseline"}, examples)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, report.Failures)
+	intent := report.Fields[eval.FieldIntent]
+	assert.InDelta(t, 2.0/3, intent.Accuracy, 1e-9)
+	assert.Equal(t, 1, intent.Confusion["status_check"]["<error>"])
+	assert.Contains(t, report.Predictions[2].Error, "content analysis")
+}
+
+func TestCompareFlagsRegressionsBeyondThreshold(t *testing.T) {
+	baseline := &eval.Report{
+		Variant: eval.Variant{Name: "baseline"},
+		Fields: map[string]*eval.FieldMetrics{
+			eval.FieldIntent:      {Accuracy: 0.90, MacroF1: 0.80},
+			eval.FieldProjectType: {Accuracy: 0.70, MacroF1: 0.60},
+		},
+		Correlations: map[string]float64{eval.CorrelationLeadScore: 0.50},
+	}
+	candidate := &eval.Report{
+		Variant: eval.Variant{Name: "candidate"},
+		Fields: map[string]*eval.FieldMetrics{
+			eval.FieldIntent:      {Accuracy: 0.88, MacroF1: 0.85},
+			eval.FieldProjectType: {Accuracy: 0.60, MacroF1: 0.60},
+		},
+		Correlations: map[string]float64{eval.CorrelationLeadScore: 0.55},
+	}
+
+	comparison := eval.Compare(baseline, candidate, 0.02)
+	require.Len(t, comparison.Rows, 5)
+
+	regressions := comparison.Regressions()
+	require.Len(t, regressions, 1)
+	assert.Equal(t, "project_type accuracy", regressions[0].Metric)
+	assert.InDelta(t, -0.10, regressions[0].Delta, 1e-9)
+
+	var out bytes.Buffer
+	require.NoError(t, eval.WriteComparison(&out, comparison))
+	assert.Contains(t, out.String(), "REGRESSED")
+	assert.Contains(t, out.String(), "intent accuracy")
+
+	assert.Empty(t, eval.Compare(baseline, candidate, 0.2).Regressions())
+}
+
+func TestWriteReportIncludesConfusionMatrices(t *testing.T) {
+	report := (&eval.Runner{SpamThreshold: 50}).Score(
+		eval.Variant{Name: "baseline", Model: "gemini-1.5-pro"},
+		[]eval.Example{
+			{ID: "a", Expected: eval.Labels{ProjectType: "kitchen"}},
+			{ID: "b", Expected: eval.Labels{ProjectType: "bathroom"}},
+		},
+		[]eval.Prediction{
+			{ExampleID: "a", ProjectType: "kitchen"},
+			{ExampleID: "b", ProjectType: "kitchen"},
+		},
+	)
+
+	var out bytes.Buffer
+	require.NoError(t, eval.WriteReport(&out, report))
+	text := out.String()
+	assert.Contains(t, text, "Variant baseline (model gemini-1.5-pro, template latest, separate analyses)")
+	assert.Contains(t, text, "Confusion matrix for project_type")
+	assert.Contains(t, text, "0.500")
+}
+
+func TestRunnerCombinedVariantAnalyzesEachCallOnce(t *testing.T) {
+	examples, err := eval.LoadDataset(strings.NewReader(evalDataset))
+	require.NoError(t, err)
+
+	analyzer := &scriptedAnalyzer{
+		content: map[string]*models.CallAnalysis{
+			"c1": {Intent: "quote_request", ProjectType: "kitchen", LeadScore: 85},
+			"c3": {Intent: "status_check", ProjectType: "bathroom", LeadScore: 20},
+			"c4": {Intent: "quote_request", ProjectType: "other", LeadScore: 55},
+		},
+		spam: map[string]float64{"c2": 92, "c3": 8},
+	}
+
+	report, err := eval.DefaultRunner().Run(context.Background(), analyzer, eval.Variant{Name: "combined", Combined: true}, examples)
+	require.NoError(t, err)
+
+	require.Len(t, analyzer.requests, 4)
+	for _, req := range analyzer.requests {
+		assert.Equal(t, ai.AnalysisTypeCombined, req.AnalysisType)
+	}
+	assert.InDelta(t, 1.0, report.Fields[eval.FieldProjectType].Accuracy, 1e-9)
+	assert.InDelta(t, 1.0, report.Fields[eval.FieldSpam].Accuracy, 1e-9)
+
+	var out bytes.Buffer
+	require.NoError(t, eval.WriteReport(&out, report))
+	assert.Contains(t, out.String(), "combined analyses")
+}
@@ -0,0 +1,112 @@
+package unit
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/audio"
+)
+
+// synthesizeMessage builds 8 kHz audio whose pitch changes every 100ms, like a spoken message
+func synthesizeMessage(seed int64, d time.Duration) []int16 {
+	rng := rand.New(rand.NewSource(seed))
+	samples := make([]int16, int(d.Seconds()*8000))
+	freq := 0.0
+	for i := range samples {
+		if i%800 == 0 {
+			freq = 200 + rng.Float64()*1800
+		}
+		t := float64(i) / 8000
+		samples[i] = int16(6000*math.Sin(2*math.Pi*freq*t) + 3000*math.Sin(2*math.Pi*2.3*freq*t))
+	}
+	return samples
+}
+
+// degrade scales the audio and adds line noise, as a second delivery of the same message would
+func degrade(samples []int16) []int16 {
+	rng := rand.New(rand.NewSource(99))
+	out := make([]int16, len(samples))
+	for i, s := range samples {
+		out[i] = int16(0.7*float64(s) + rng.NormFloat64()*150)
+	}
+	return out
+}
+
+func TestFingerprintSimilarity(t *testing.T) {
+	message := synthesizeMessage(1, 10*time.Second)
+	original := audio.ComputeFingerprint(&audio.PCMAudio{Samples: message, SampleRate: 8000}, nil)
+	require.NotEmpty(t, original.Hashes)
+
+	same := audio.ComputeFingerprint(&audio.PCMAudio{Samples: message, SampleRate: 8000}, nil)
+	assert.Equal(t, original.Digest(), same.Digest())
+
+	noisy := audio.ComputeFingerprint(&audio.PCMAudio{Samples: degrade(message), SampleRate: 8000}, nil)
+	assert.NotEqual(t, original.Digest(), noisy.Digest())
+	assert.Greater(t, audio.Similarity(original, noisy, 10), 0.85)
+
+	other := audio.ComputeFingerprint(&audio.PCMAudio{Samples: synthesizeMessage(2, 10*time.Second), SampleRate: 8000}, nil)
+	assert.Less(t, audio.Similarity(original, other, 10), 0.7)
+
+	decoded, err := audio.DecodeFingerprint(original.Encode(), original.Hop)
+	require.NoError(t, err)
+	assert.Equal(t, original.Hashes, decoded.Hashes)
+}
+
+func TestFingerprintMatcherFlagsDuplicatesWithinTenant(t *testing.T) {
+	ctx := context.Background()
+	matcher := audio.NewFingerprintMatcher(nil, nil)
+	message := synthesizeMessage(3, 8*time.Second)
+
+	first := matcher.Fingerprint(&audio.PCMAudio{Samples: message, SampleRate: 8000}, "tenant_a", "rec_1", "call_1")
+	require.NotNil(t, first)
+	match, err := matcher.Check(ctx, first)
+	require.NoError(t, err)
+	assert.False(t, match.Duplicate)
+	require.NoError(t, matcher.Register(ctx, first))
+
+	// Same recording delivered again under another call ID
+	again := matcher.Fingerprint(&audio.PCMAudio{Samples: message, SampleRate: 8000}, "tenant_a", "rec_2", "call_2")
+	match, err = matcher.Check(ctx, again)
+	require.NoError(t, err)
+	assert.True(t, match.Duplicate)
+	assert.True(t, match.Exact)
+	assert.Equal(t, "call_1", match.DuplicateCallID)
+	assert.Zero(t, match.SpamLikelihood)
+
+	// Other tenants' recordings are not duplicates
+	elsewhere := matcher.Fingerprint(&audio.PCMAudio{Samples: degrade(message), SampleRate: 8000}, "tenant_b", "rec_3", "call_3")
+	match, err = matcher.Check(ctx, elsewhere)
+	require.NoError(t, err)
+	assert.False(t, match.Duplicate)
+}
+
+func TestFingerprintMatcherDetectsKnownRobocall(t *testing.T) {
+	ctx := context.Background()
+	matcher := audio.NewFingerprintMatcher(nil, nil)
+	message := synthesizeMessage(4, 6*time.Second)
+
+	reported := matcher.Fingerprint(&audio.PCMAudio{Samples: message, SampleRate: 8000}, "tenant_a", "rec_1", "call_1")
+	require.NoError(t, matcher.Register(ctx, reported))
+	_, err := matcher.MarkRobocall(ctx, "tenant_b", "rec_1", "extended warranty")
+	assert.ErrorIs(t, err, audio.ErrFingerprintNotFound, "only the recording's tenant can report it")
+	_, err = matcher.MarkRobocall(ctx, "tenant_a", "rec_1", "extended warranty")
+	require.NoError(t, err)
+
+	// Another tenant receives the message after a few seconds of ringing
+	call := append(make([]int16, 3*8000), degrade(message)...)
+	received := matcher.Fingerprint(&audio.PCMAudio{Samples: call, SampleRate: 8000}, "tenant_b", "rec_2", "call_2")
+	match, err := matcher.Check(ctx, received)
+	require.NoError(t, err)
+	assert.True(t, match.Robocall)
+	assert.Equal(t, "extended warranty", match.RobocallLabel)
+	assert.GreaterOrEqual(t, match.SpamLikelihood, 90.0)
+
+	_, err = matcher.MarkRobocall(ctx, "tenant_a", "missing", "x")
+	assert.ErrorIs(t, err, audio.ErrFingerprintNotFound)
+}
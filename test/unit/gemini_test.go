@@ -0,0 +1,210 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/ai"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// geminiServer answers generateContent calls with a fixed body and records the last request
+func geminiServer(t *testing.T, status int, body string) (*httptest.Server, *map[string]interface{}, *string) {
+	t.Helper()
+	var lastRequest map[string]interface{}
+	var lastPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastPath = r.URL.Path
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&lastRequest))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &lastRequest, &lastPath
+}
+
+// geminiAnswer wraps model text in a generateContent response with usage metadata
+func geminiAnswer(text string) string {
+	data, _ := json.Marshal(map[string]interface{}{
+		"candidates": []map[string]interface{}{{
+			"content":      map[string]interface{}{"role": "model", "parts": []map[string]string{{"text": text}}},
+			"finishReason": "STOP",
+		}},
+		"usageMetadata": map[string]int{"promptTokenCount": 412, "candidatesTokenCount": 57, "thoughtsTokenCount": 100},
+	})
+	return string(data)
+}
+
+func TestSchemaForCallAnalysis(t *testing.T) {
+	schema := ai.SchemaFor(models.CallAnalysis{})
+
+	assert.Equal(t, ai.SchemaTypeObject, schema.Type)
+	assert.ElementsMatch(t, []string{
+		"intent", "project_type", "timeline", "budget_indicator", "sentiment", "lead_score",
+		"urgency", "appointment_requested", "follow_up_required", "key_details",
+	}, schema.Required)
+
+	assert.Contains(t, schema.Properties["project_type"].Enum, "kitchen")
+	assert.Equal(t, ai.SchemaTypeInteger, schema.Properties["lead_score"].Type)
+	require.NotNil(t, schema.Properties["lead_score"].Minimum)
+	assert.Equal(t, 1.0, *schema.Properties["lead_score"].Minimum)
+	assert.Equal(t, 100.0, *schema.Properties["lead_score"].Maximum)
+	assert.Equal(t, ai.SchemaTypeBoolean, schema.Properties["appointment_requested"].Type)
+	assert.Equal(t, ai.SchemaTypeArray, schema.Properties["key_details"].Type)
+	assert.Equal(t, ai.SchemaTypeString, schema.Properties["key_details"].Items.Type)
+}
+
+func TestSchemaForTagsAndOptionalFields(t *testing.T) {
+	type result struct {
+		Labels   []string               `json:"labels" schema:"enum=a|b"`
+		Note     *string                `json:"note"`
+		Extra    string                 `json:"extra,omitempty"`
+		Details  map[string]interface{} `json:"details" schema:"-"`
+		Internal string                 `json:"-"`
+	}
+	schema := ai.SchemaFor(result{})
+
+	assert.Equal(t, []string{"labels"}, schema.Required)
+	assert.Equal(t, []string{"a", "b"}, schema.Properties["labels"].Items.Enum, "constraints on a slice apply to its items")
+	assert.True(t, schema.Properties["note"].Nullable)
+	assert.Contains(t, schema.Properties, "extra")
+	assert.NotContains(t, schema.Properties, "details")
+	assert.NotContains(t, schema.Properties, "Internal")
+
+	spam := ai.ResponseSchema(ai.AnalysisTypeSpam)
+	require.NotNil(t, spam)
+	assert.NotContains(t, spam.Properties, "details")
+	assert.Nil(t, ai.ResponseSchema(ai.AnalysisTypeCustom), "custom prompts are free-form")
+}
+
+func TestGeminiClientSendsResponseSchemaAndReportsUsage(t *testing.T) {
+	server, lastRequest, lastPath := geminiServer(t, http.StatusOK, geminiAnswer(`{"sentiment":"positive"}`))
+	client := ai.NewGeminiClientWithHTTP(server.Client(), server.URL, "proj", "us-central1")
+
+	result, err := client.GenerateContent(context.Background(), &ai.GenerateRequest{
+		Model:           "gemini-2.5-flash",
+		Prompt:          "How did the caller feel?",
+		Temperature:     0.2,
+		MaxOutputTokens: 1024,
+		ResponseSchema:  ai.ResponseSchema(ai.AnalysisTypeSentiment),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "/v1/projects/proj/locations/us-central1/publishers/google/models/gemini-2.5-flash:generateContent", *lastPath)
+	config := (*lastRequest)["generationConfig"].(map[string]interface{})
+	assert.Equal(t, "application/json", config["responseMimeType"])
+	schema := config["responseSchema"].(map[string]interface{})
+	assert.Equal(t, "OBJECT", schema["type"])
+	assert.Contains(t, schema["properties"], "sentiment")
+
+	assert.Equal(t, `{"sentiment":"positive"}`, result.Text)
+	assert.Equal(t, "STOP", result.FinishReason)
+	assert.Equal(t, int64(412), result.Usage.InputTokens)
+	assert.Equal(t, int64(157), result.Usage.OutputTokens, "thinking tokens count as output")
+}
+
+func TestGeminiClientFreeTextOmitsResponseSchema(t *testing.T) {
+	server, lastRequest, _ := geminiServer(t, http.StatusOK, geminiAnswer("hello"))
+	client := ai.NewGeminiClientWithHTTP(server.Client(), server.URL, "proj", "us-central1")
+
+	_, err := client.GenerateContent(context.Background(), &ai.GenerateRequest{Prompt: "hi"})
+	require.NoError(t, err)
+
+	config := (*lastRequest)["generationConfig"].(map[string]interface{})
+	assert.NotContains(t, config, "responseMimeType")
+	assert.NotContains(t, config, "responseSchema")
+}
+
+func TestGeminiClientErrors(t *testing.T) {
+	cases := map[string]struct {
+		status int
+		body   string
+		want   string
+	}{
+		"api error":  {http.StatusBadRequest, `{"error":{"code":400,"message":"bad schema","status":"INVALID_ARGUMENT"}}`, "INVALID_ARGUMENT: bad schema"},
+		"blocked":    {http.StatusOK, `{"promptFeedback":{"blockReason":"SAFETY"}}`, "prompt blocked: SAFETY"},
+		"no text":    {http.StatusOK, `{"candidates":[{"content":{"parts":[]},"finishReason":"MAX_TOKENS"}]}`, "MAX_TOKENS"},
+		"no answer":  {http.StatusOK, `{"candidates":[]}`, "no candidates"},
+		"plain http": {http.StatusBadGateway, `upstream unavailable`, "HTTP 502"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			server, _, _ := geminiServer(t, tc.status, tc.body)
+			client := ai.NewGeminiClientWithHTTP(server.Client(), server.URL, "proj", "us-central1")
+
+			_, err := client.GenerateContent(context.Background(), &ai.GenerateRequest{Prompt: "hi"})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.want)
+		})
+	}
+}
+
+func TestAnalyzeContentReturnsTypedResultsWithTokenUsage(t *testing.T) {
+	cases := []struct {
+		analysisType ai.AnalysisType
+		answer       string
+		check        func(t *testing.T, resp *ai.AnalysisResponse)
+	}{
+		{ai.AnalysisTypeIntent, `{"primary_intent":"appointment_booking","confidence":0.9,"secondary_intents":["quote_request"],"reasoning":"wants a visit"}`,
+			func(t *testing.T, resp *ai.AnalysisResponse) {
+				require.NotNil(t, resp.IntentResult)
+				assert.Equal(t, "appointment_booking", resp.IntentResult.PrimaryIntent)
+				assert.Equal(t, []string{"quote_request"}, resp.IntentResult.SecondaryIntents)
+			}},
+		{ai.AnalysisTypeLeadScore, `{"lead_score":82,"confidence":0.7,"scoring_factors":{"project_complexity":8,"buying_readiness":9,"timeline_urgency":7,"budget_capability":6,"engagement_quality":9},"reasoning":"ready to buy"}`,
+			func(t *testing.T, resp *ai.AnalysisResponse) {
+				require.NotNil(t, resp.LeadScoreResult)
+				assert.Equal(t, 82, resp.LeadScoreResult.LeadScore)
+				assert.Equal(t, 9, resp.LeadScoreResult.ScoringFactors.BuyingReadiness)
+			}},
+		{ai.AnalysisTypeContent, `{"intent":"quote_request","project_type":"kitchen","timeline":"1-3_months","budget_indicator":"high","sentiment":"positive","lead_score":75,"urgency":"medium","appointment_requested":true,"follow_up_required":false,"key_details":["island"]}`,
+			func(t *testing.T, resp *ai.AnalysisResponse) {
+				require.NotNil(t, resp.CallAnalysis)
+				assert.Equal(t, "kitchen", resp.CallAnalysis.ProjectType)
+			}},
+	}
+
+	for _, tc := range cases {
+		t.Run(string(tc.analysisType), func(t *testing.T) {
+			server, lastRequest, _ := geminiServer(t, http.StatusOK, geminiAnswer(tc.answer))
+			service := ai.NewAnalysisServiceWithProvider(ai.NewGeminiClientWithHTTP(server.Client(), server.URL, "proj", "us-central1"), nil)
+
+			resp, err := service.AnalyzeContent(context.Background(), &ai.AnalysisRequest{
+				CallID:        "CAL1",
+				TenantID:      "tenant_a",
+				Transcription: "Hi, I'd like someone to come out and quote a kitchen remodel.",
+				AnalysisType:  tc.analysisType,
+			})
+			require.NoError(t, err)
+			assert.True(t, resp.Success)
+			assert.Equal(t, int64(412), resp.Metadata.InputTokens)
+			assert.Equal(t, int64(157), resp.Metadata.OutputTokens)
+			assert.Empty(t, resp.CustomResult, "structured types no longer fall through to raw_response")
+			tc.check(t, resp)
+
+			config := (*lastRequest)["generationConfig"].(map[string]interface{})
+			assert.Equal(t, "application/json", config["responseMimeType"])
+		})
+	}
+}
+
+func TestAnalyzeContentReportsGenerationFailure(t *testing.T) {
+	server, _, _ := geminiServer(t, http.StatusTooManyRequests, `{"error":{"code":429,"message":"quota exceeded","status":"RESOURCE_EXHAUSTED"}}`)
+	service := ai.NewAnalysisServiceWithProvider(ai.NewGeminiClientWithHTTP(server.Client(), server.URL, "proj", "us-central1"), nil)
+
+	resp, err := service.AnalyzeContent(context.Background(), &ai.AnalysisRequest{
+		Transcription: "hello",
+		AnalysisType:  ai.AnalysisTypeSpam,
+	})
+	require.Error(t, err)
+	assert.False(t, resp.Success)
+	assert.True(t, strings.Contains(resp.Error, "RESOURCE_EXHAUSTED"))
+}
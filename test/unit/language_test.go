@@ -0,0 +1,45 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/ai"
+	"github.com/home-renovators/ingestion-pipeline/pkg/audio"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+func TestWithLanguagesSetsPrimaryAndAlternatives(t *testing.T) {
+	base := audio.DefaultTranscriptionConfig()
+
+	config := base.WithLanguages([]string{"es-us", "en-US", "ES-US", "fr-CA", "pt-BR", "vi-VN"})
+	assert.Equal(t, "es-US", config.LanguageCode)
+	assert.Equal(t, []string{"en-US", "fr-CA", "pt-BR"}, config.AlternativeLanguageCodes)
+
+	// The service default is not modified
+	assert.Equal(t, "en-US", base.LanguageCode)
+	assert.Empty(t, base.AlternativeLanguageCodes)
+
+	// No tenant languages keeps the default config
+	assert.Same(t, base, base.WithLanguages(nil))
+}
+
+func TestNormalizeLanguageCode(t *testing.T) {
+	assert.Equal(t, "es-US", audio.NormalizeLanguageCode(" ES-us "))
+	assert.Equal(t, "zh-Hant-TW", audio.NormalizeLanguageCode("zh-Hant-tw"))
+	assert.Equal(t, "", audio.NormalizeLanguageCode(""))
+}
+
+func TestLanguageInstructionOnlyForNonEnglish(t *testing.T) {
+	assert.Empty(t, ai.LanguageInstruction(""))
+	assert.Empty(t, ai.LanguageInstruction("en-GB"))
+
+	instruction := ai.LanguageInstruction("es-US")
+	assert.Contains(t, instruction, "Spanish (es-US)")
+	assert.Contains(t, instruction, "in English")
+
+	assert.Equal(t, "Spanish", models.LanguageName("es-MX"))
+	assert.Equal(t, "xx-YY", models.LanguageName("xx-YY"))
+	assert.False(t, models.IsEnglish("es-US"))
+}
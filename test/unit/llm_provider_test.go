@@ -0,0 +1,168 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/ai"
+)
+
+func TestOpenAIClientRequestsJSONSchema(t *testing.T) {
+	var body map[string]interface{}
+	var authorization, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		authorization = r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{\"sentiment\":\"neutral\"}"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":300,"completion_tokens":20}}`))
+	}))
+	defer server.Close()
+
+	client := ai.NewOpenAIClientWithHTTP(server.Client(), server.URL+"/v1/", "local-key")
+	result, err := client.GenerateContent(context.Background(), &ai.GenerateRequest{
+		Model:           "llama3.1:8b",
+		Prompt:          "How did the caller feel?",
+		Temperature:     0.2,
+		MaxOutputTokens: 512,
+		ResponseSchema:  ai.ResponseSchema(ai.AnalysisTypeSentiment),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "/v1/chat/completions", path)
+	assert.Equal(t, "Bearer local-key", authorization)
+	assert.Equal(t, "llama3.1:8b", body["model"])
+	format := body["response_format"].(map[string]interface{})
+	assert.Equal(t, "json_schema", format["type"])
+	schema := format["json_schema"].(map[string]interface{})["schema"].(map[string]interface{})
+	assert.Equal(t, "object", schema["type"])
+	sentiment := schema["properties"].(map[string]interface{})["sentiment"].(map[string]interface{})
+	assert.Equal(t, "string", sentiment["type"])
+	assert.ElementsMatch(t, []interface{}{"positive", "neutral", "negative"}, sentiment["enum"])
+
+	assert.Equal(t, `{"sentiment":"neutral"}`, result.Text)
+	assert.Equal(t, ai.TokenUsage{InputTokens: 300, OutputTokens: 20}, result.Usage)
+}
+
+func TestOpenAIClientReportsAPIErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"message":"model \"llama9\" not found","type":"invalid_request_error"}}`))
+	}))
+	defer server.Close()
+
+	client := ai.NewOpenAIClientWithHTTP(server.Client(), server.URL, "")
+	_, err := client.GenerateContent(context.Background(), &ai.GenerateRequest{Model: "llama9", Prompt: "hi"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `model "llama9" not found`)
+}
+
+func TestSchemaJSONSchemaMarksNullableFields(t *testing.T) {
+	type result struct {
+		Score *int `json:"score" schema:"min=1,max=10"`
+	}
+	schema := ai.SchemaFor(result{}).JSONSchema()
+
+	score := schema["properties"].(map[string]interface{})["score"].(map[string]interface{})
+	assert.Equal(t, []string{"integer", "null"}, score["type"])
+	assert.Equal(t, 1.0, score["minimum"])
+	assert.Equal(t, false, schema["additionalProperties"])
+}
+
+func TestFakeProviderAnswersByPromptHash(t *testing.T) {
+	ctx := context.Background()
+	fake := ai.NewFakeProvider()
+	fake.Respond("first prompt", ai.FakeResponse{Text: `{"a":1}`, Usage: ai.TokenUsage{InputTokens: 10, OutputTokens: 2}})
+	fake.RespondToHash(ai.PromptHash("second prompt"), ai.FakeResponse{Error: "quota exceeded"})
+
+	result, err := fake.GenerateContent(ctx, &ai.GenerateRequest{Prompt: "first prompt"})
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, result.Text)
+	assert.Equal(t, "STOP", result.FinishReason)
+	assert.Equal(t, int64(10), result.Usage.InputTokens)
+
+	_, err = fake.GenerateContent(ctx, &ai.GenerateRequest{Prompt: "second prompt"})
+	assert.EqualError(t, err, "quota exceeded")
+
+	_, err = fake.GenerateContent(ctx, &ai.GenerateRequest{Prompt: "unscripted"})
+	assert.True(t, errors.Is(err, ai.ErrNoScriptedResponse))
+
+	fake.SetDefault(ai.FakeResponse{Text: "fallback"})
+	result, err = fake.GenerateContent(ctx, &ai.GenerateRequest{Prompt: "unscripted"})
+	require.NoError(t, err)
+	assert.Equal(t, "fallback", result.Text)
+	assert.Equal(t, int64(3), result.Usage.InputTokens, "usage is estimated when the script has none")
+
+	calls := fake.Calls()
+	require.Len(t, calls, 4)
+	assert.Equal(t, "first prompt", calls[0].Prompt)
+}
+
+func TestFakeProviderIsDeterministic(t *testing.T) {
+	fake := ai.NewFakeProvider()
+	fake.SetDefault(ai.FakeResponse{Text: `{"lead_score":50}`})
+
+	req := &ai.GenerateRequest{Prompt: "Score this lead"}
+	first, err := fake.GenerateContent(context.Background(), req)
+	require.NoError(t, err)
+	second, err := fake.GenerateContent(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Len(t, ai.PromptHash("Score this lead"), 64)
+	assert.Equal(t, ai.PromptHash("Score this lead"), ai.PromptHash("Score this lead"))
+}
+
+func TestNewProviderLoadsFakeScript(t *testing.T) {
+	script := ai.FakeScript{
+		Responses: map[string]ai.FakeResponse{ai.PromptHash("known"): {Text: "scripted"}},
+		Default:   &ai.FakeResponse{Text: "default"},
+	}
+	data, err := json.Marshal(script)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "script.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	provider, err := ai.NewProvider(context.Background(), &ai.ProviderConfig{Provider: ai.ProviderFake, FakeScript: path})
+	require.NoError(t, err)
+	defer provider.Close()
+
+	result, err := provider.GenerateContent(context.Background(), &ai.GenerateRequest{Prompt: "known"})
+	require.NoError(t, err)
+	assert.Equal(t, "scripted", result.Text)
+	result, err = provider.GenerateContent(context.Background(), &ai.GenerateRequest{Prompt: "other"})
+	require.NoError(t, err)
+	assert.Equal(t, "default", result.Text)
+
+	_, err = ai.NewProvider(context.Background(), &ai.ProviderConfig{Provider: "bard"})
+	assert.Error(t, err)
+	_, err = ai.NewProvider(context.Background(), &ai.ProviderConfig{Provider: ai.ProviderOpenAI})
+	assert.Error(t, err, "the openai provider needs a base URL")
+}
+
+func TestAnalysisServiceRunsOnFakeProvider(t *testing.T) {
+	fake := ai.NewFakeProvider()
+	fake.SetDefault(ai.FakeResponse{Text: `{"spam_likelihood":91,"confidence":0.8,"indicators":["robotic"],"reasoning":"recorded pitch"}`})
+	service := ai.NewAnalysisServiceWithProvider(fake, nil)
+
+	resp, err := service.AnalyzeContent(context.Background(), &ai.AnalysisRequest{
+		Transcription: "Press one to lower your interest rate",
+		AnalysisType:  ai.AnalysisTypeSpam,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp.SpamResult)
+	assert.Equal(t, 91.0, resp.SpamResult.SpamLikelihood)
+
+	calls := fake.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "gemini-2.5-flash", calls[0].Model)
+	assert.Equal(t, ai.ResponseSchema(ai.AnalysisTypeSpam), calls[0].ResponseSchema)
+}
@@ -0,0 +1,320 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/internal/repository"
+	"github.com/home-renovators/ingestion-pipeline/internal/storage"
+	"github.com/home-renovators/ingestion-pipeline/pkg/audio"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+	"github.com/home-renovators/ingestion-pipeline/pkg/privacy"
+	"github.com/home-renovators/ingestion-pipeline/pkg/retention"
+)
+
+var memoryEpoch = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// memoryRequest builds a request created the given number of minutes after memoryEpoch
+func memoryRequest(tenantID, requestID, callID string, minutes int) *models.Request {
+	return &models.Request{
+		RequestID:         requestID,
+		TenantID:          tenantID,
+		Source:            "callrail",
+		RequestType:       "call",
+		Status:            "pending",
+		Data:              `{"customer_phone_number":"+15551234567"}`,
+		AINormalized:      "{}",
+		AIExtracted:       "{}",
+		CallID:            &callID,
+		CommunicationMode: "phone_call",
+		CreatedAt:         memoryEpoch.Add(time.Duration(minutes) * time.Minute),
+		UpdatedAt:         memoryEpoch.Add(time.Duration(minutes) * time.Minute),
+	}
+}
+
+// memoryRecording builds a call recording created the given number of minutes after memoryEpoch
+func memoryRecording(tenantID, recordingID, callID string, minutes int) *models.CallRecording {
+	return &models.CallRecording{
+		RecordingID:         recordingID,
+		TenantID:            tenantID,
+		CallID:              callID,
+		StorageURL:          fmt.Sprintf("gs://bucket/%s/%s.mp3", tenantID, callID),
+		TranscriptionStatus: "pending",
+		CreatedAt:           memoryEpoch.Add(time.Duration(minutes) * time.Minute),
+	}
+}
+
+// memoryJob builds a completed transcription job of a call, keeping its redacted transcript
+func memoryJob(tenantID, jobID, callID string) *audio.TranscriptionJob {
+	return &audio.TranscriptionJob{
+		JobID:   jobID,
+		Request: &audio.TranscriptionRequest{TenantID: tenantID, CallID: callID},
+		Status:  &audio.TranscriptionStatus{JobID: jobID, Status: audio.JobStatusCompleted},
+		Result:  &models.TranscriptionResult{Transcript: "call me back on [PHONE]"},
+	}
+}
+
+func TestMemoryStoreScopesReadsAndUpdatesToTenant(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	require.NoError(t, store.CreateCallRecording(ctx, memoryRecording("tenant_a", "rec_1", "CAL1", 0)))
+	require.NoError(t, store.CreateRequest(ctx, memoryRequest("tenant_a", "req_1", "CAL1", 0)))
+
+	_, err := store.GetCallRecording(ctx, "tenant_b", "rec_1")
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+	_, err = store.GetRequest(ctx, "tenant_b", "req_1")
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+
+	err = store.UpdateCallRecordingStatus(ctx, "tenant_b", "rec_1", "completed")
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+	err = store.UpdateRequestClassification(ctx, "tenant_b", "req_1", "voicemail", "screened")
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+
+	require.NoError(t, store.UpdateCallRecordingStatus(ctx, "tenant_a", "rec_1", "completed"))
+	recording, err := store.GetCallRecording(ctx, "tenant_a", "rec_1")
+	require.NoError(t, err)
+	assert.Equal(t, "completed", recording.TranscriptionStatus)
+
+	require.NoError(t, store.UpdateRequestClassification(ctx, "tenant_a", "req_1", "voicemail", "screened"))
+	req, err := store.GetRequest(ctx, "tenant_a", "req_1")
+	require.NoError(t, err)
+	assert.Equal(t, "screened", req.Status)
+	require.NotNil(t, req.CallClassification)
+	assert.Equal(t, "voicemail", *req.CallClassification)
+
+	score, spam := 72, 4.5
+	err = store.UpdateRequestAnalysis(ctx, "tenant_b", "req_1", `{"lead_score":72}`, &score, &spam)
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+	require.NoError(t, store.UpdateRequestAnalysis(ctx, "tenant_a", "req_1", `{"lead_score":72}`, &score, &spam))
+	req, err = store.GetRequest(ctx, "tenant_a", "req_1")
+	require.NoError(t, err)
+	require.NotNil(t, req.AIAnalysis)
+	assert.JSONEq(t, `{"lead_score":72}`, *req.AIAnalysis)
+	require.NotNil(t, req.LeadScore)
+	assert.Equal(t, 72, *req.LeadScore)
+	require.NotNil(t, req.SpamLikelihood)
+	assert.Equal(t, 4.5, *req.SpamLikelihood)
+
+	err = store.UpdateRequestExtraction(ctx, "tenant_b", "req_1", `{"email":null}`)
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+	require.NoError(t, store.UpdateRequestExtraction(ctx, "tenant_a", "req_1", `{"email":null}`))
+	req, err = store.GetRequest(ctx, "tenant_a", "req_1")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"email":null}`, req.AIExtracted)
+}
+
+func TestMemoryStoreRejectsDuplicateInserts(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	require.NoError(t, store.CreateRequest(ctx, memoryRequest("tenant_a", "req_1", "CAL1", 0)))
+	assert.Error(t, store.CreateRequest(ctx, memoryRequest("tenant_a", "req_1", "CAL1", 0)))
+
+	// A failed combined insert writes neither row
+	require.NoError(t, store.CreateCallRecording(ctx, memoryRecording("tenant_a", "rec_1", "CAL1", 0)))
+	err := store.CreateRequestWithRecording(ctx, memoryRequest("tenant_a", "req_2", "CAL2", 1), memoryRecording("tenant_a", "rec_1", "CAL2", 1))
+	assert.Error(t, err)
+	_, err = store.GetRequest(ctx, "tenant_a", "req_2")
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+
+	require.NoError(t, store.CreateRequestWithRecording(ctx, memoryRequest("tenant_a", "req_2", "CAL2", 1), memoryRecording("tenant_a", "rec_2", "CAL2", 1)))
+	_, err = store.GetCallRecording(ctx, "tenant_a", "rec_2")
+	assert.NoError(t, err)
+}
+
+func TestMemoryStoreNotFoundSemantics(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+
+	// Lookups the auth and webhook paths branch on return nil rather than an error
+	office, err := store.GetOfficeByCallRailCompanyID(ctx, "COM1", "tenant_a")
+	assert.NoError(t, err)
+	assert.Nil(t, office)
+	req, err := store.GetRequestByCallID(ctx, "CAL1")
+	assert.NoError(t, err)
+	assert.Nil(t, req)
+
+	_, err = store.GetOfficeByTenantID(ctx, "tenant_a")
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+	_, err = store.GetAIProcessingLog(ctx, "tenant_a", "proc_1")
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+	_, err = store.GetCRMIntegration(ctx, "tenant_a", "int_1")
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+	assert.True(t, errors.Is(store.UpdateWebhookEventStatus(ctx, "evt_1", "processed"), repository.ErrNotFound))
+	assert.True(t, errors.Is(store.ReleaseLegalHold(ctx, "tenant_a", "hold_1"), repository.ErrNotFound))
+	_, err = store.GetRecordingFingerprint(ctx, "rec_1")
+	assert.Equal(t, audio.ErrFingerprintNotFound, err)
+	_, err = store.GetTranscriptionJob(ctx, "job_1")
+	assert.Equal(t, audio.ErrJobNotFound, err)
+}
+
+func TestMemoryStoreOffices(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	store.PutOffice(&models.Office{TenantID: "tenant_b", OfficeID: "office_1", CallRailCompanyID: "COM2", Status: "active"})
+	store.PutOffice(&models.Office{TenantID: "tenant_a", OfficeID: "office_1", CallRailCompanyID: "COM1", Status: "active"})
+	store.PutOffice(&models.Office{TenantID: "tenant_c", OfficeID: "office_1", CallRailCompanyID: "COM3", Status: "inactive"})
+	store.PutTenant("tenant_d", "suspended")
+
+	offices, err := store.ListActiveOffices(ctx)
+	require.NoError(t, err)
+	require.Len(t, offices, 2)
+	assert.Equal(t, "tenant_a", offices[0].TenantID)
+	assert.Equal(t, "tenant_b", offices[1].TenantID)
+
+	office, err := store.GetOfficeByCallRailCompanyID(ctx, "COM1", "tenant_a")
+	require.NoError(t, err)
+	require.NotNil(t, office)
+	office, err = store.GetOfficeByCallRailCompanyID(ctx, "COM1", "tenant_b")
+	require.NoError(t, err)
+	assert.Nil(t, office, "company IDs only match within their tenant")
+
+	for tenantID, want := range map[string]bool{"tenant_a": true, "tenant_d": false, "tenant_x": false} {
+		exists, err := store.TenantExists(ctx, tenantID)
+		require.NoError(t, err)
+		assert.Equal(t, want, exists, tenantID)
+	}
+}
+
+func TestMemoryStoreRequestQueries(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	for i, score := range []int{40, 60, 80} {
+		score := score
+		req := memoryRequest("tenant_a", fmt.Sprintf("req_%d", i), fmt.Sprintf("CAL%d", i), i*60)
+		req.LeadScore = &score
+		if i == 2 {
+			req.CommunicationMode = "form"
+		}
+		require.NoError(t, store.CreateRequest(ctx, req))
+	}
+	require.NoError(t, store.CreateRequest(ctx, memoryRequest("tenant_b", "req_other", "CALX", 0)))
+
+	page, err := store.GetRequestsByTenant(ctx, "tenant_a", 2, 0)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, "req_2", page[0].RequestID, "newest first")
+	assert.Equal(t, "req_1", page[1].RequestID)
+	page, err = store.GetRequestsByTenant(ctx, "tenant_a", 2, 2)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "req_0", page[0].RequestID)
+
+	counts, err := store.GetRequestCountsByTenant(ctx, "tenant_a", memoryEpoch.Add(30*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"phone_call": 1, "form": 1}, counts)
+
+	avg, err := store.GetAverageLeadScoreByTenant(ctx, "tenant_a", memoryEpoch)
+	require.NoError(t, err)
+	assert.InDelta(t, 60.0, avg, 0.001)
+	avg, err = store.GetAverageLeadScoreByTenant(ctx, "tenant_b", memoryEpoch)
+	require.NoError(t, err)
+	assert.Zero(t, avg, "unscored tenants average 0")
+
+	req, err := store.GetRequestByCallID(ctx, "CAL1")
+	require.NoError(t, err)
+	require.NotNil(t, req)
+	assert.Equal(t, "req_1", req.RequestID)
+}
+
+func TestMemoryStoreTranscriptions(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	require.NoError(t, store.CreateCallRecording(ctx, memoryRecording("tenant_a", "rec_1", "CAL1", 0)))
+
+	_, err := store.GetCallRecordingTranscription(ctx, "tenant_a", "rec_1")
+	assert.Error(t, err, "no transcription stored yet")
+
+	assert.True(t, errors.Is(store.UpdateCallRecordingTranscription(ctx, "tenant_b", "rec_1", `{"transcript":"x"}`), repository.ErrNotFound))
+	require.NoError(t, store.UpdateCallRecordingTranscription(ctx, "tenant_a", "rec_1", `{"transcript":"hello there","confidence":0.9}`))
+
+	result, err := store.GetCallRecordingTranscription(ctx, "tenant_a", "rec_1")
+	require.NoError(t, err)
+	assert.Equal(t, "hello there", result.Transcript)
+}
+
+func TestMemoryStoreBacksRetentionPurge(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	old := memoryRequest("tenant_a", "req_old", "CAL_OLD", -60*24*400)
+	fresh := memoryRequest("tenant_a", "req_new", "CAL_NEW", 0)
+	require.NoError(t, store.CreateRequest(ctx, old))
+	require.NoError(t, store.CreateRequest(ctx, fresh))
+	require.NoError(t, store.CreateCallRecording(ctx, memoryRecording("tenant_a", "rec_old", "CAL_OLD", -60*24*400)))
+	require.NoError(t, store.CreateCallRecording(ctx, memoryRecording("tenant_a", "rec_new", "CAL_NEW", 0)))
+	// The old call's transcript survives only on its transcription job
+	require.NoError(t, store.SaveTranscriptionJob(ctx, memoryJob("tenant_a", "job_old", "CAL_OLD")))
+	require.NoError(t, store.SaveTranscriptionJob(ctx, memoryJob("tenant_a", "job_new", "CAL_NEW")))
+
+	purger := retention.NewPurger(store, &fakeObjectStore{}, &retention.Config{DefaultDays: 365, BatchSize: 10}).
+		WithClock(func() time.Time { return memoryEpoch.Add(time.Hour) })
+	_, err := purger.PurgeTenant(ctx, "tenant_a", models.RetentionConfig{})
+	require.NoError(t, err)
+
+	req, err := store.GetRequest(ctx, "tenant_a", "req_old")
+	require.NoError(t, err)
+	assert.Equal(t, "{}", req.Data, "expired webhook payload is purged")
+	req, err = store.GetRequest(ctx, "tenant_a", "req_new")
+	require.NoError(t, err)
+	assert.NotEqual(t, "{}", req.Data)
+	_, err = store.GetTranscriptionJob(ctx, "job_old")
+	assert.Equal(t, audio.ErrJobNotFound, err, "expired transcription job is purged")
+	_, err = store.GetTranscriptionJob(ctx, "job_new")
+	assert.NoError(t, err)
+
+	// Like the caller_phone column, the purged request's phone number is gone with its payload
+	activity, err := store.GetCallerActivity(ctx, "+15551234567", memoryEpoch.Add(-24*time.Hour*500))
+	require.NoError(t, err)
+	assert.Equal(t, 1, activity.Requests)
+	history, err := store.ListRequestsByCallerPhone(ctx, "tenant_a", "+15551234567", 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "req_new", history[0].RequestID)
+
+	candidates, err := store.ListPurgeCandidates(ctx, "tenant_a", models.DataClassAudio, memoryEpoch, 10)
+	require.NoError(t, err)
+	assert.Empty(t, candidates, "purged audio is no longer a candidate")
+	assert.NotEmpty(t, store.PurgeAudits("tenant_a"))
+	assert.Empty(t, store.PurgeAudits("tenant_b"))
+}
+
+func TestMemoryStoreBacksDataSubjectErasure(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	require.NoError(t, store.CreateRequest(ctx, memoryRequest("tenant_a", "req_1", "CAL1", 0)))
+	require.NoError(t, store.CreateRequest(ctx, memoryRequest("tenant_b", "req_2", "CAL2", 0)))
+	require.NoError(t, store.CreateCallRecording(ctx, memoryRecording("tenant_a", "rec_1", "CAL1", 0)))
+	require.NoError(t, store.SaveRecordingFingerprint(ctx, &models.RecordingFingerprint{RecordingID: "rec_1", TenantID: "tenant_a", CallID: "CAL1"}))
+	require.NoError(t, store.SaveTranscriptionJob(ctx, memoryJob("tenant_a", "job_1", "CAL1")))
+
+	blobs, err := storage.NewLocalStore(t.TempDir(), "http://localhost/blobs", "test-signing-key")
+	require.NoError(t, err)
+	service := privacy.NewService(store, storage.NewServiceWithStore(blobs), nil)
+	subject := privacy.Subject{Phone: "(555) 123-4567"}
+	var log *models.DataSubjectLog
+	log, err = service.Delete(ctx, "tenant_a", subject, "dpo@example.com")
+	require.NoError(t, err)
+	assert.True(t, log.Verified)
+	assert.Equal(t, 1, log.Counts.Requests)
+	assert.Equal(t, 1, log.Counts.CallRecordings)
+	assert.Equal(t, 1, log.Counts.TranscriptionJobs)
+
+	_, err = store.GetRequest(ctx, "tenant_a", "req_1")
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+	_, err = store.GetRecordingFingerprint(ctx, "rec_1")
+	assert.Equal(t, audio.ErrFingerprintNotFound, err)
+	_, err = store.GetTranscriptionJob(ctx, "job_1")
+	assert.Equal(t, audio.ErrJobNotFound, err)
+	_, err = store.GetRequest(ctx, "tenant_b", "req_2")
+	assert.NoError(t, err, "other tenants keep their records")
+
+	logs, err := store.ListDataSubjectLogs(ctx, "tenant_a", subject.Hash("tenant_a"))
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, models.DataSubjectDelete, logs[0].RequestType)
+}
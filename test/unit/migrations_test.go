@@ -0,0 +1,118 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/internal/migrations"
+)
+
+// fixedSchemaVersion reports a fixed schema version, or an error
+type fixedSchemaVersion struct {
+	version int
+	err     error
+}
+
+func (f fixedSchemaVersion) SchemaVersion(ctx context.Context) (int, error) {
+	return f.version, f.err
+}
+
+func TestShippedMigrationsAreContiguous(t *testing.T) {
+	all, err := migrations.All()
+	require.NoError(t, err)
+	require.NotEmpty(t, all)
+
+	for i, migration := range all {
+		assert.Equal(t, i+1, migration.Version)
+		assert.NotEmpty(t, migration.Statements, "migration %d", migration.Version)
+		for _, statement := range migration.Statements {
+			assert.NotContains(t, statement, ";")
+			assert.NotContains(t, statement, "--")
+		}
+	}
+
+	latest, err := migrations.Latest()
+	require.NoError(t, err)
+	assert.Equal(t, all[len(all)-1].Version, latest)
+}
+
+func TestLoadOrdersAndSplitsMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_notes.sql": {Data: []byte("-- notes column\nALTER TABLE things ADD COLUMN notes STRING(MAX);\n")},
+		"0001_create_things.sql": {Data: []byte(`-- things and their index
+CREATE TABLE things (
+  thing_id STRING(64) NOT NULL, -- the key
+) PRIMARY KEY (thing_id);
+
+CREATE INDEX things_by_id ON things(thing_id);
+`)},
+		"README.md": {Data: []byte("not a migration")},
+	}
+
+	all, err := migrations.Load(fsys)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+
+	assert.Equal(t, 1, all[0].Version)
+	assert.Equal(t, "create_things", all[0].Name)
+	assert.Equal(t, []string{
+		"CREATE TABLE things (\n  thing_id STRING(64) NOT NULL,\n) PRIMARY KEY (thing_id)",
+		"CREATE INDEX things_by_id ON things(thing_id)",
+	}, all[0].Statements)
+
+	assert.Equal(t, 2, all[1].Version)
+	assert.Equal(t, []string{"ALTER TABLE things ADD COLUMN notes STRING(MAX)"}, all[1].Statements)
+}
+
+func TestLoadRejectsBadMigrationSets(t *testing.T) {
+	stmt := &fstest.MapFile{Data: []byte("CREATE TABLE t (id INT64) PRIMARY KEY (id);")}
+
+	cases := map[string]fstest.MapFS{
+		"gap":          {"0001_a.sql": stmt, "0003_c.sql": stmt},
+		"duplicate":    {"0001_a.sql": stmt, "001_b.sql": stmt},
+		"not at one":   {"0002_b.sql": stmt},
+		"no version":   {"initial.sql": stmt},
+		"no name":      {"0001.sql": stmt},
+		"no statement": {"0001_a.sql": {Data: []byte("-- nothing yet\n")}},
+	}
+	for name, fsys := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := migrations.Load(fsys)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPendingAfter(t *testing.T) {
+	all := []migrations.Migration{{Version: 1, Name: "a"}, {Version: 2, Name: "b"}, {Version: 3, Name: "c"}}
+
+	assert.Len(t, migrations.PendingAfter(all, 0), 3)
+	pending := migrations.PendingAfter(all, 1)
+	require.Len(t, pending, 2)
+	assert.Equal(t, "b", pending[0].Name)
+	assert.Empty(t, migrations.PendingAfter(all, 3))
+}
+
+func TestVerifyRefusesOlderSchema(t *testing.T) {
+	ctx := context.Background()
+	latest, err := migrations.Latest()
+	require.NoError(t, err)
+
+	err = migrations.Verify(ctx, fixedSchemaVersion{version: latest - 1})
+	assert.ErrorIs(t, err, migrations.ErrSchemaOutdated)
+
+	err = migrations.Verify(ctx, fixedSchemaVersion{version: 0})
+	assert.ErrorIs(t, err, migrations.ErrSchemaOutdated, "an untracked database is outdated")
+
+	assert.NoError(t, migrations.Verify(ctx, fixedSchemaVersion{version: latest}))
+	assert.NoError(t, migrations.Verify(ctx, fixedSchemaVersion{version: latest + 1}), "a newer schema allows rolling back the code")
+
+	err = migrations.Verify(ctx, fixedSchemaVersion{err: errors.New("connection refused")})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, migrations.ErrSchemaOutdated)
+}
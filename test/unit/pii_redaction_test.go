@@ -0,0 +1,157 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/speech/apiv1/speechpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/audio"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+func TestRedactCardNumbers(t *testing.T) {
+	ctx := context.Background()
+	redactor := audio.NewRedactor(models.RedactionConfig{Enabled: true}, nil)
+
+	text, entities, err := redactor.RedactText(ctx, "my card is 4111 1111 1111 1111 expiring 0427.")
+	require.NoError(t, err)
+	assert.Equal(t, "my card is [CARD_NUMBER] expiring 0427.", text)
+	require.Len(t, entities, 1)
+	assert.Equal(t, models.PIICardNumber, entities[0].Type)
+
+	// Digits read out as words are caught too
+	text, _, err = redactor.RedactText(ctx, "it's four one one one one one one one one one one one one one one one okay")
+	require.NoError(t, err)
+	assert.Equal(t, "it's [CARD_NUMBER] okay", text)
+
+	// A number failing the Luhn check is left alone
+	text, entities, err = redactor.RedactText(ctx, "order 4111 1111 1111 1112 shipped")
+	require.NoError(t, err)
+	assert.Empty(t, entities)
+	assert.Equal(t, "order 4111 1111 1111 1112 shipped", text)
+}
+
+func TestRedactSSNNeedsContextOrDashes(t *testing.T) {
+	ctx := context.Background()
+	redactor := audio.NewRedactor(models.RedactionConfig{Enabled: true}, nil)
+
+	text, _, err := redactor.RedactText(ctx, "my social security number is 123 45 6789")
+	require.NoError(t, err)
+	assert.Equal(t, "my social security number is [SSN]", text)
+
+	text, _, err = redactor.RedactText(ctx, "it is 123-45-6789, thanks")
+	require.NoError(t, err)
+	assert.Equal(t, "it is [SSN], thanks", text)
+
+	// Nine digits without context could be anything
+	text, _, err = redactor.RedactText(ctx, "the permit is 123 45 6789")
+	require.NoError(t, err)
+	assert.Equal(t, "the permit is 123 45 6789", text)
+}
+
+func TestRedactionPolicySelectsEntities(t *testing.T) {
+	ctx := context.Background()
+	input := "call me at 555 867 5309 or email jane.doe@example.com, I'm at 42 Maple Street"
+
+	// Contact details are kept by default since they are the lead
+	text, _, err := audio.NewRedactor(models.RedactionConfig{Enabled: true}, nil).RedactText(ctx, input)
+	require.NoError(t, err)
+	assert.Equal(t, input, text)
+
+	redactor := audio.NewRedactor(models.RedactionConfig{
+		Enabled:  true,
+		Entities: []models.PIIType{models.PIIPhone, models.PIIEmail, models.PIIAddress},
+	}, nil)
+	text, entities, err := redactor.RedactText(ctx, input)
+	require.NoError(t, err)
+	assert.Equal(t, "call me at [PHONE] or email [EMAIL], I'm at [ADDRESS]", text)
+	assert.Len(t, entities, 3)
+
+	text, _, err = redactor.RedactText(ctx, "it's jane dot doe at example dot com")
+	require.NoError(t, err)
+	assert.Equal(t, "it's [EMAIL]", text)
+}
+
+func TestRedactTranscriptionReturnsAudioRanges(t *testing.T) {
+	ctx := context.Background()
+	result := &models.TranscriptionResult{
+		Transcript: "card 4111 1111 1111 1111 thanks",
+		WordDetails: []models.WordDetail{
+			wordDetail("card", 0, 400, 2),
+			wordDetail("4111", 500, 900, 2),
+			wordDetail("1111", 1000, 1400, 2),
+			wordDetail("1111", 1500, 1900, 2),
+			wordDetail("1111", 2000, 2400, 2),
+			wordDetail("thanks", 2600, 3000, 2),
+		},
+	}
+
+	redacted, err := audio.NewRedactor(models.RedactionConfig{Enabled: true}, nil).RedactTranscription(ctx, result)
+	require.NoError(t, err)
+
+	assert.Equal(t, "card [CARD_NUMBER] thanks", redacted.Transcription.Transcript)
+	require.Len(t, redacted.Transcription.WordDetails, 3)
+	assert.Equal(t, "[CARD_NUMBER]", redacted.Transcription.WordDetails[1].Word)
+	assert.Equal(t, int64(500), redacted.Transcription.WordDetails[1].StartTime.Milliseconds())
+	assert.Equal(t, int64(2400), redacted.Transcription.WordDetails[1].EndTime.Milliseconds())
+
+	require.Len(t, redacted.AudioRanges, 1)
+	assert.Equal(t, audio.TimeRange{Start: 500 * time.Millisecond, End: 2400 * time.Millisecond}, redacted.AudioRanges[0])
+	assert.Equal(t, 1, redacted.Counts()[models.PIICardNumber])
+
+	// The original is untouched
+	assert.Equal(t, "4111", result.WordDetails[1].Word)
+	assert.Len(t, result.WordDetails, 6)
+}
+
+func TestBleepAudioReplacesRanges(t *testing.T) {
+	pcm := &audio.PCMAudio{Samples: make([]int16, 16000), SampleRate: 8000}
+
+	bleeped := audio.BleepAudio(pcm, []audio.TimeRange{{Start: 500 * time.Millisecond, End: time.Second}}, 0)
+
+	inRange := 0
+	for _, s := range bleeped.Samples[4000:8000] {
+		if s != 0 {
+			inRange++
+		}
+	}
+	assert.Greater(t, inRange, 2000)
+	for _, s := range bleeped.Samples[8000:] {
+		require.Zero(t, s)
+	}
+	// The source audio is not modified
+	for _, s := range pcm.Samples {
+		require.Zero(t, s)
+	}
+}
+
+func TestRedactDiarizedTranscriptionReportsEachEntityOnce(t *testing.T) {
+	ctx := context.Background()
+	result := transcribeDiarized(t, diarized(
+		[]*speechpb.WordInfo{
+			word("social", 0, 300*time.Millisecond, 2),
+			word("security", 400*time.Millisecond, 700*time.Millisecond, 2),
+			word("number", 800*time.Millisecond, 1100*time.Millisecond, 2),
+			word("123-45-6789", 1200*time.Millisecond, 2400*time.Millisecond, 2),
+		},
+		[]*speechpb.WordInfo{word("thanks", 3*time.Second, 3400*time.Millisecond, 1)},
+	))
+
+	redacted, err := audio.NewRedactor(models.RedactionConfig{Enabled: true}, nil).RedactTranscription(ctx, result)
+	require.NoError(t, err)
+
+	require.Len(t, redacted.Entities, 1, "the SSN is reported once")
+	assert.Equal(t, 1, redacted.Counts()[models.PIISSN])
+	require.Len(t, redacted.AudioRanges, 1, "the SSN is bleeped once")
+	assert.Equal(t, audio.TimeRange{Start: 1200 * time.Millisecond, End: 2400 * time.Millisecond}, redacted.AudioRanges[0])
+
+	words := redacted.Transcription.WordDetails
+	require.Len(t, words, 5)
+	assert.Equal(t, "[SSN]", words[3].Word)
+	assert.Equal(t, 2, words[3].Speaker)
+	assert.Equal(t, "social security number [SSN] thanks", redacted.Transcription.Transcript)
+}
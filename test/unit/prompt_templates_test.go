@@ -0,0 +1,127 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/ai"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+func TestShippedPromptTemplatesRenderWithDefaults(t *testing.T) {
+	library := ai.DefaultPromptLibrary()
+	details := &models.CallDetails{CustomerName: "Jane Doe", CustomerCity: "Austin", CustomerState: "TX", Duration: 240}
+
+	for _, analysisType := range []ai.AnalysisType{
+		ai.AnalysisTypeContent, ai.AnalysisTypeSpam, ai.AnalysisTypeSentiment, ai.AnalysisTypeIntent, ai.AnalysisTypeLeadScore, ai.AnalysisTypeSummary,
+		ai.AnalysisTypeEntities, ai.AnalysisTypeCombined,
+	} {
+		t.Run(string(analysisType), func(t *testing.T) {
+			rendered, err := library.Render(analysisType, 0, nil, "I need a new kitchen.", details)
+			require.NoError(t, err)
+			assert.Equal(t, string(analysisType), rendered.TemplateID)
+			assert.Equal(t, 1, rendered.TemplateVersion)
+			assert.Contains(t, rendered.Text, "home remodeling company")
+			assert.Contains(t, rendered.Text, "TRANSCRIPT: I need a new kitchen.")
+			assert.NotContains(t, rendered.Text, "<no value>")
+		})
+	}
+
+	content, err := library.Render(ai.AnalysisTypeContent, 0, nil, "hi", details)
+	require.NoError(t, err)
+	assert.Contains(t, content.Text, `"project_type": "kitchen|bathroom|whole_home|addition|flooring|roofing|windows|doors|other"`)
+	assert.Contains(t, content.Text, "- Customer Location: Austin, TX")
+	assert.Contains(t, content.Text, "- Quality of conversation")
+
+	withoutCall, err := library.Render(ai.AnalysisTypeContent, 0, nil, "hi", nil)
+	require.NoError(t, err)
+	assert.NotContains(t, withoutCall.Text, "CALL METADATA")
+}
+
+func TestPromptTemplatesApplyTenantOverrides(t *testing.T) {
+	cfg := &models.AnalysisPromptConfig{
+		BusinessType:    "HVAC company",
+		ProjectTypes:    []string{"AC Install", "furnace-repair", "ac install", "Other"},
+		ScoringCriteria: []string{"No heat in winter is an emergency"},
+	}
+
+	assert.Equal(t, []string{"ac_install", "furnace_repair", "other"}, ai.ProjectTaxonomy(cfg))
+
+	rendered, err := ai.DefaultPromptLibrary().Render(ai.AnalysisTypeLeadScore, 0, cfg, "The furnace died.", nil)
+	require.NoError(t, err)
+	assert.Contains(t, rendered.Text, "for a HVAC company")
+	assert.Contains(t, rendered.Text, "project types: ac install, furnace repair.")
+	assert.Contains(t, rendered.Text, "- No heat in winter is an emergency")
+	assert.NotContains(t, rendered.Text, "kitchen/bathroom")
+
+	schema := ai.TenantResponseSchema(ai.AnalysisTypeContent, cfg)
+	assert.Equal(t, []string{"ac_install", "furnace_repair", "other"}, schema.Properties["project_type"].Enum)
+	assert.Contains(t, ai.ResponseSchema(ai.AnalysisTypeContent).Properties["project_type"].Enum, "kitchen",
+		"the shared schema is left untouched")
+	assert.Same(t, ai.ResponseSchema(ai.AnalysisTypeSpam), ai.TenantResponseSchema(ai.AnalysisTypeSpam, cfg))
+}
+
+func TestPromptLibraryVersions(t *testing.T) {
+	library, err := ai.LoadPromptLibrary(fstest.MapFS{
+		"prompts/spam_detection.v1.tmpl": {Data: []byte("v1 for a {{.BusinessType}}: {{.Transcript}}")},
+		"prompts/spam_detection.v2.tmpl": {Data: []byte("v2 for a {{.BusinessType}}: {{.Transcript}}")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, library.Versions("spam_detection"))
+
+	latest, err := library.Render(ai.AnalysisTypeSpam, 0, nil, "hello", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "v2 for a home remodeling company: hello", latest.Text)
+
+	pinned := &models.AnalysisPromptConfig{TemplateVersions: map[string]int{"spam_detection": 1}}
+	rendered, err := library.Render(ai.AnalysisTypeSpam, 0, pinned, "hello", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rendered.TemplateVersion)
+	assert.Equal(t, "v1 for a home remodeling company: hello", rendered.Text)
+
+	rendered, err = library.Render(ai.AnalysisTypeSpam, 2, pinned, "hello", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rendered.TemplateVersion, "an explicit version overrides the tenant pin")
+
+	_, err = library.Render(ai.AnalysisTypeSpam, 3, nil, "hello", nil)
+	assert.ErrorContains(t, err, "has no version 3")
+	_, err = library.Render(ai.AnalysisTypeSentiment, 0, nil, "hello", nil)
+	assert.ErrorContains(t, err, `no prompt template "sentiment_analysis"`)
+}
+
+func TestLoadPromptLibraryRejectsBadTemplates(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"version gap":  {"spam_detection.v1.tmpl": {Data: []byte("a")}, "spam_detection.v3.tmpl": {Data: []byte("b")}},
+		"bad name":     {"spam_detection.tmpl": {Data: []byte("a")}},
+		"bad template": {"spam_detection.v1.tmpl": {Data: []byte("{{.Transcript")}},
+	}
+	for name, fsys := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ai.LoadPromptLibrary(fsys)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAnalyzeContentRecordsTemplateInMetadata(t *testing.T) {
+	fake := ai.NewFakeProvider()
+	fake.SetDefault(ai.FakeResponse{Text: `{"intent":"quote_request","project_type":"gutters","timeline":"unknown","budget_indicator":"unknown",
+		"sentiment":"neutral","lead_score":40,"urgency":"low","appointment_requested":false,"follow_up_required":true,"key_details":[]}`})
+	service := ai.NewAnalysisServiceWithProvider(fake, nil)
+
+	resp, err := service.AnalyzeContent(context.Background(), &ai.AnalysisRequest{
+		Transcription: "Do you clean gutters?",
+		AnalysisType:  ai.AnalysisTypeContent,
+		PromptConfig:  &models.AnalysisPromptConfig{BusinessType: "roofing company", ProjectTypes: []string{"roofing", "gutters"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "gutters", resp.CallAnalysis.ProjectType)
+	assert.Equal(t, "content_analysis", resp.Metadata.TemplateID)
+	assert.Equal(t, 1, resp.Metadata.TemplateVersion)
+	assert.Contains(t, resp.Metadata.PromptTemplate, "roofing company")
+	assert.Equal(t, []string{"roofing", "gutters", "other"}, fake.Calls()[0].ResponseSchema.Properties["project_type"].Enum)
+}
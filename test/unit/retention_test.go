@@ -0,0 +1,173 @@
+package unit
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+	"github.com/home-renovators/ingestion-pipeline/pkg/retention"
+)
+
+// fakeRetentionStore keeps calls in memory; a call holds data of a class until it is purged
+type fakeRetentionStore struct {
+	calls  []retention.Candidate
+	purged map[models.DataClass]map[string]bool
+	holds  []*models.LegalHold
+	audits []*models.PurgeAuditRecord
+}
+
+func newFakeRetentionStore(now time.Time, ages map[string]int) *fakeRetentionStore {
+	store := &fakeRetentionStore{purged: make(map[models.DataClass]map[string]bool)}
+	for callID, days := range ages {
+		store.calls = append(store.calls, retention.Candidate{
+			TenantID:    "tenant-1",
+			CallID:      callID,
+			RequestID:   "req-" + callID,
+			RecordingID: "rec-" + callID,
+			CreatedAt:   now.AddDate(0, 0, -days),
+		})
+	}
+	sort.Slice(store.calls, func(i, j int) bool { return store.calls[i].CreatedAt.Before(store.calls[j].CreatedAt) })
+	return store
+}
+
+func (f *fakeRetentionStore) ListPurgeCandidates(ctx context.Context, tenantID string, class models.DataClass, before time.Time, limit int) ([]retention.Candidate, error) {
+	var candidates []retention.Candidate
+	for _, call := range f.calls {
+		if call.TenantID == tenantID && call.CreatedAt.Before(before) && !f.purged[class][call.CallID] {
+			candidates = append(candidates, call)
+		}
+		if len(candidates) == limit {
+			break
+		}
+	}
+	return candidates, nil
+}
+
+func (f *fakeRetentionStore) PurgeCallData(ctx context.Context, candidate retention.Candidate, class models.DataClass, audit *models.PurgeAuditRecord) (int64, error) {
+	if f.purged[class] == nil {
+		f.purged[class] = make(map[string]bool)
+	}
+	f.purged[class][candidate.CallID] = true
+	audit.RowsAffected = 1
+	f.audits = append(f.audits, audit)
+	return 1, nil
+}
+
+func (f *fakeRetentionStore) ListLegalHolds(ctx context.Context, tenantID string) ([]*models.LegalHold, error) {
+	return f.holds, nil
+}
+
+type fakeObjectStore struct {
+	deleted []string
+}
+
+func (f *fakeObjectStore) DeleteCallObjects(ctx context.Context, tenantID, callID string) (int, error) {
+	f.deleted = append(f.deleted, callID)
+	return 2, nil
+}
+
+var retentionNow = time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestPurger(store retention.Store, objects retention.ObjectStore, batchSize int) *retention.Purger {
+	config := retention.DefaultConfig()
+	config.DefaultDays = 730
+	config.BatchSize = batchSize
+	return retention.NewPurger(store, objects, config).WithClock(func() time.Time { return retentionNow })
+}
+
+func TestPurgeAppliesPerClassRetention(t *testing.T) {
+	store := newFakeRetentionStore(retentionNow, map[string]int{"new": 10, "mid": 200, "old": 800})
+	objects := &fakeObjectStore{}
+
+	report, err := newTestPurger(store, objects, 1).PurgeTenant(context.Background(), "tenant-1", models.RetentionConfig{AudioDays: 90})
+	require.NoError(t, err)
+
+	// Audio is kept 90 days, everything else falls back to the two-year default
+	assert.Equal(t, 2, report.Classes[models.DataClassAudio].Purged)
+	assert.Equal(t, 1, report.Classes[models.DataClassTranscript].Purged)
+	assert.Equal(t, 1, report.Classes[models.DataClassAIOutput].Purged)
+	assert.Equal(t, 1, report.Classes[models.DataClassWebhook].Purged)
+	assert.ElementsMatch(t, []string{"mid", "old"}, objects.deleted)
+	assert.Equal(t, int64(4), report.Classes[models.DataClassAudio].ObjectsDeleted)
+	assert.Equal(t, 730, report.Classes[models.DataClassTranscript].RetentionDays)
+
+	require.Len(t, store.audits, 5)
+	audit := store.audits[0]
+	assert.Equal(t, models.DataClassAudio, audit.DataClass)
+	assert.Equal(t, "old", audit.CallID)
+	assert.Equal(t, "rec-old", audit.RecordingID)
+	assert.Equal(t, 90, audit.RetentionDays)
+	assert.Equal(t, retentionNow.AddDate(0, 0, -90), audit.Cutoff)
+	assert.Equal(t, int64(2), audit.ObjectsDeleted)
+	assert.Equal(t, int64(1), audit.RowsAffected)
+	assert.Equal(t, retentionNow, audit.PurgedAt)
+	assert.NotEmpty(t, audit.AuditID)
+}
+
+func TestPurgeSkipsCallsOnLegalHold(t *testing.T) {
+	store := newFakeRetentionStore(retentionNow, map[string]int{"a": 100, "b": 110, "c": 120, "d": 130})
+	released := retentionNow
+	store.holds = []*models.LegalHold{
+		{HoldID: "hold-1", TenantID: "tenant-1", CallID: "b"},
+		{HoldID: "hold-2", TenantID: "tenant-1", CallID: "c"},
+		{HoldID: "hold-3", TenantID: "tenant-1", CallID: "d", ReleasedAt: &released},
+	}
+	objects := &fakeObjectStore{}
+
+	// A batch size of one makes the purger page past the held calls
+	report, err := newTestPurger(store, objects, 1).PurgeTenant(context.Background(), "tenant-1", models.RetentionConfig{AudioDays: 90})
+	require.NoError(t, err)
+
+	audio := report.Classes[models.DataClassAudio]
+	assert.Equal(t, 2, audio.Purged)
+	assert.Equal(t, 2, audio.Held)
+	assert.ElementsMatch(t, []string{"a", "d"}, objects.deleted)
+}
+
+func TestPurgeSkipsTenantOnLegalHold(t *testing.T) {
+	store := newFakeRetentionStore(retentionNow, map[string]int{"old": 800})
+	store.holds = []*models.LegalHold{{HoldID: "hold-1", TenantID: "tenant-1", Reason: "litigation"}}
+	objects := &fakeObjectStore{}
+
+	report, err := newTestPurger(store, objects, 10).PurgeTenant(context.Background(), "tenant-1", models.RetentionConfig{AudioDays: 1})
+	require.NoError(t, err)
+
+	assert.True(t, report.Held)
+	assert.Empty(t, report.Classes)
+	assert.Empty(t, objects.deleted)
+	assert.Empty(t, store.audits)
+}
+
+func TestPurgeDryRun(t *testing.T) {
+	store := newFakeRetentionStore(retentionNow, map[string]int{"a": 100, "b": 200, "c": 300})
+	objects := &fakeObjectStore{}
+
+	report, err := newTestPurger(store, objects, 1).WithDryRun().PurgeTenant(context.Background(), "tenant-1", models.RetentionConfig{AudioDays: 90})
+	require.NoError(t, err)
+
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 3, report.Classes[models.DataClassAudio].Purged)
+	assert.Empty(t, objects.deleted)
+	assert.Empty(t, store.audits)
+}
+
+func TestMergeRetentionPolicies(t *testing.T) {
+	merged := retention.MergePolicies([]models.RetentionConfig{
+		{AudioDays: 90, TranscriptDays: 730},
+		{AudioDays: 30, TranscriptDays: 1000, AIOutputDays: 60},
+	}, 365)
+
+	assert.Equal(t, 90, merged.AudioDays)
+	assert.Equal(t, 1000, merged.TranscriptDays)
+	// An office without a setting keeps the platform default, which is longer here
+	assert.Equal(t, 365, merged.AIOutputDays)
+	assert.Equal(t, 365, merged.WebhookDays)
+
+	assert.Equal(t, models.RetentionConfig{}, retention.MergePolicies(nil, 365))
+}
@@ -0,0 +1,187 @@
+package unit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/scheduler"
+)
+
+// queuedJob is work submitted to the scheduler while its only slot is held
+type queuedJob struct {
+	tenant   string
+	priority scheduler.Priority
+	label    string
+}
+
+// admissionOrder queues jobs behind a held slot, then records the order they are admitted in
+func admissionOrder(t *testing.T, s *scheduler.Scheduler, jobs []queuedJob) []string {
+	ctx := context.Background()
+
+	hold, err := s.Acquire(ctx, "holder", scheduler.PriorityNormal)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		wg.Add(1)
+		go func(job queuedJob) {
+			defer wg.Done()
+			release, err := s.Acquire(ctx, job.tenant, job.priority)
+			if !assert.NoError(t, err) {
+				return
+			}
+			mu.Lock()
+			order = append(order, job.label)
+			mu.Unlock()
+			release()
+		}(job)
+
+		// Wait for each job to queue so submission order is deterministic
+		require.Eventually(t, func() bool {
+			queued := 0
+			for _, n := range s.Stats().Queued {
+				queued += n
+			}
+			return queued == i+1
+		}, time.Second, time.Millisecond)
+	}
+
+	hold()
+	wg.Wait()
+	return order
+}
+
+func TestSchedulerUrgentJumpsBackfill(t *testing.T) {
+	s := scheduler.New(&scheduler.Config{MaxConcurrent: 1, Weights: scheduler.DefaultConfig().Weights})
+
+	order := admissionOrder(t, s, []queuedJob{
+		{"tenant_a", scheduler.PriorityLow, "backfill-1"},
+		{"tenant_a", scheduler.PriorityLow, "backfill-2"},
+		{"tenant_a", scheduler.PriorityLow, "backfill-3"},
+		{"tenant_b", scheduler.PriorityUrgent, "hot-lead"},
+	})
+
+	require.Len(t, order, 4)
+	assert.Equal(t, "hot-lead", order[0])
+}
+
+func TestSchedulerWeightedDequeueDoesNotStarveLowPriority(t *testing.T) {
+	s := scheduler.New(&scheduler.Config{
+		MaxConcurrent: 1,
+		Weights: map[scheduler.Priority]int{
+			scheduler.PriorityHigh: 2,
+			scheduler.PriorityLow:  1,
+		},
+	})
+
+	order := admissionOrder(t, s, []queuedJob{
+		{"tenant_a", scheduler.PriorityHigh, "h1"},
+		{"tenant_a", scheduler.PriorityHigh, "h2"},
+		{"tenant_a", scheduler.PriorityHigh, "h3"},
+		{"tenant_a", scheduler.PriorityHigh, "h4"},
+		{"tenant_a", scheduler.PriorityLow, "l1"},
+	})
+
+	// With a 2:1 weighting the low priority job runs within the first three admissions
+	require.Len(t, order, 5)
+	assert.Contains(t, order[:3], "l1")
+}
+
+func TestSchedulerRoundRobinsTenantsWithinPriority(t *testing.T) {
+	s := scheduler.New(&scheduler.Config{MaxConcurrent: 1})
+
+	order := admissionOrder(t, s, []queuedJob{
+		{"tenant_a", scheduler.PriorityNormal, "a1"},
+		{"tenant_a", scheduler.PriorityNormal, "a2"},
+		{"tenant_a", scheduler.PriorityNormal, "a3"},
+		{"tenant_b", scheduler.PriorityNormal, "b1"},
+	})
+
+	require.Len(t, order, 4)
+	assert.Equal(t, []string{"a1", "b1", "a2", "a3"}, order)
+}
+
+func TestSchedulerEnforcesBudgetAndTenantCap(t *testing.T) {
+	ctx := context.Background()
+	s := scheduler.New(&scheduler.Config{MaxConcurrent: 3, MaxPerTenant: 2})
+
+	r1, err := s.Acquire(ctx, "tenant_a", scheduler.PriorityNormal)
+	require.NoError(t, err)
+	r2, err := s.Acquire(ctx, "tenant_a", scheduler.PriorityNormal)
+	require.NoError(t, err)
+
+	// A third slot for tenant_a must wait even though the global budget has room
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	_, err = s.Acquire(waitCtx, "tenant_a", scheduler.PriorityHigh)
+	cancel()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	r3, err := s.Acquire(ctx, "tenant_b", scheduler.PriorityNormal)
+	require.NoError(t, err)
+	assert.Equal(t, 3, s.Stats().Running)
+
+	// Cancelled waiters leave the queue
+	assert.Equal(t, 0, s.Stats().Queued[scheduler.PriorityHigh])
+
+	r1()
+	r1() // release is idempotent
+	assert.Equal(t, 2, s.Stats().Running)
+	r2()
+	r3()
+	assert.Equal(t, 0, s.Stats().Running)
+}
+
+func TestSchedulerAdmitsUrgentWorkOfTenantAtCap(t *testing.T) {
+	ctx := context.Background()
+	s := scheduler.New(&scheduler.Config{MaxConcurrent: 3, MaxPerTenant: 2})
+
+	// tenant_a's backfill fills its share and queues more behind it
+	r1, err := s.Acquire(ctx, "tenant_a", scheduler.PriorityLow)
+	require.NoError(t, err)
+	r2, err := s.Acquire(ctx, "tenant_a", scheduler.PriorityLow)
+	require.NoError(t, err)
+	backfill := make(chan func(), 1)
+	go func() {
+		release, err := s.Acquire(ctx, "tenant_a", scheduler.PriorityLow)
+		if assert.NoError(t, err) {
+			backfill <- release
+		}
+	}()
+	require.Eventually(t, func() bool { return s.Stats().Queued[scheduler.PriorityLow] == 1 }, time.Second, time.Millisecond)
+
+	// The same tenant's hot lead takes the free global slot straight away
+	waitCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	hot, err := s.Acquire(waitCtx, "tenant_a", scheduler.PriorityUrgent)
+	require.NoError(t, err)
+	assert.Equal(t, 3, s.Stats().Running)
+	assert.Equal(t, 1, s.Stats().Queued[scheduler.PriorityLow], "backfill stays capped")
+
+	// Urgent work is still bound by the global budget
+	fullCtx, cancelFull := context.WithTimeout(ctx, 20*time.Millisecond)
+	_, err = s.Acquire(fullCtx, "tenant_a", scheduler.PriorityUrgent)
+	cancelFull()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Finishing the hot lead leaves tenant_a at its cap; finishing backfill admits the next
+	hot()
+	assert.Equal(t, 1, s.Stats().Queued[scheduler.PriorityLow])
+	r1()
+	release := <-backfill
+	release()
+	r2()
+	assert.Equal(t, 0, s.Stats().Running)
+}
+
+func TestParsePriorityDefaultsToNormal(t *testing.T) {
+	assert.Equal(t, scheduler.PriorityUrgent, scheduler.ParsePriority("urgent"))
+	assert.Equal(t, scheduler.PriorityNormal, scheduler.ParsePriority(""))
+	assert.Equal(t, scheduler.PriorityNormal, scheduler.ParsePriority("asap"))
+}
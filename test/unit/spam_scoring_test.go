@@ -0,0 +1,173 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/internal/repository"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+	"github.com/home-renovators/ingestion-pipeline/pkg/spam"
+)
+
+// spamPolicy is a tenant spam policy suppressing leads at the threshold
+func spamPolicy(threshold int) models.SpamDetectionConfig {
+	return models.SpamDetectionConfig{Enabled: true, ConfidenceThreshold: threshold}
+}
+
+func TestSpamScoreBlendsSignalsByWeight(t *testing.T) {
+	duration := 5 * time.Second
+	answered := false
+	phone := "+15125550142"
+	verdict := spam.NewScorer(nil).Score(&spam.Signals{
+		Duration:       &duration,
+		Answered:       &answered,
+		CallerPhone:    &phone,
+		CallerActivity: &models.CallerActivity{Requests: 1, Tenants: 1},
+		LLM:            &spam.LLMVerdict{Likelihood: 20, Confidence: 1},
+	}, models.SpamDetectionConfig{})
+
+	// (0.5*20 + 0.1*70 + 0.05*40 + 0.15*0 + 0.1*0) / 0.9
+	assert.Equal(t, 21.1, verdict.Likelihood)
+	require.Len(t, verdict.Factors, 5)
+	assert.Equal(t, spam.FactorLLM, verdict.Factors[0].Name, "largest contribution first")
+	assert.Equal(t, spam.FactorDuration, verdict.Factors[1].Name)
+	assert.False(t, verdict.Suppressed)
+	assert.Equal(t, "Spam likelihood 21: transcript rated 20 by the LLM (+11); call lasted only 5s (+8); call went unanswered (+2)",
+		verdict.Explanation)
+}
+
+func TestSpamScoreOnlyWeighsKnownSignals(t *testing.T) {
+	verdict := spam.NewScorer(nil).Score(&spam.Signals{LLM: &spam.LLMVerdict{Likelihood: 3}}, models.SpamDetectionConfig{})
+	assert.Equal(t, 3.0, verdict.Likelihood, "the LLM alone decides when nothing else is known")
+
+	verdict = spam.NewScorer(nil).Score(&spam.Signals{}, models.SpamDetectionConfig{})
+	assert.Zero(t, verdict.Likelihood)
+	assert.Equal(t, "Spam likelihood 0", verdict.Explanation)
+}
+
+func TestUnsureLLMCountsForLess(t *testing.T) {
+	duration := time.Minute
+	score := func(confidence float64) float64 {
+		return spam.NewScorer(nil).Score(&spam.Signals{
+			Duration: &duration,
+			LLM:      &spam.LLMVerdict{Likelihood: 90, Confidence: confidence},
+		}, models.SpamDetectionConfig{}).Likelihood
+	}
+
+	assert.Equal(t, 75.0, score(1))
+	assert.Equal(t, 64.3, score(0.5))
+	assert.Equal(t, 75.0, score(0), "a model that didn't say is trusted fully")
+}
+
+func TestKnownSpamNumberSetsAFloor(t *testing.T) {
+	config := spam.DefaultConfig()
+	config.KnownSpamNumbers = []string{"(512) 555-0199"}
+	scorer := spam.NewScorer(config)
+	phone := "+1 512 555 0199"
+	signals := &spam.Signals{CallerPhone: &phone, LLM: &spam.LLMVerdict{Likelihood: 5, Confidence: 1}}
+
+	verdict := scorer.Score(signals, spamPolicy(90))
+	assert.Equal(t, float64(spam.KnownSpamLikelihood), verdict.Likelihood)
+	assert.True(t, verdict.Suppressed)
+	assert.Contains(t, verdict.Explanation, "number is on the known spam list")
+	assert.Contains(t, verdict.Explanation, ", raised by known spam number. Kept out of the CRM")
+
+	verdict = scorer.WithWeights(map[string]float64{spam.FactorKnownSpam: 0}).Score(signals, spamPolicy(90))
+	assert.Less(t, verdict.Likelihood, 10.0, "a factor weighted 0 is ignored")
+	assert.False(t, verdict.Suppressed)
+}
+
+func TestRobocallFingerprintSetsAFloor(t *testing.T) {
+	verdict := spam.NewScorer(nil).Score(&spam.Signals{
+		Fingerprint: &models.FingerprintMatch{Robocall: true, RobocallLabel: "warranty", SpamLikelihood: 98},
+		LLM:         &spam.LLMVerdict{Likelihood: 10},
+	}, models.SpamDetectionConfig{})
+
+	assert.Equal(t, 98.0, verdict.Likelihood)
+	assert.Contains(t, verdict.Explanation, `recording matches known robocall "warranty"`)
+	assert.Contains(t, verdict.Explanation, "raised by robocall fingerprint")
+}
+
+func TestTenantWeightsOverrideDefaults(t *testing.T) {
+	scorer := spam.NewScorer(nil)
+	duration := 3 * time.Second
+	signals := &spam.Signals{Duration: &duration, LLM: &spam.LLMVerdict{Likelihood: 0}}
+
+	assert.Equal(t, 11.7, scorer.Score(signals, models.SpamDetectionConfig{}).Likelihood)
+	assert.Equal(t, 35.0, scorer.WithWeights(map[string]float64{spam.FactorLLM: 0.1}).Score(signals, models.SpamDetectionConfig{}).Likelihood)
+	assert.Equal(t, 11.7, scorer.Score(signals, models.SpamDetectionConfig{}).Likelihood, "the defaults are unchanged")
+}
+
+func TestSpamNumberPatterns(t *testing.T) {
+	scorer := spam.NewScorer(&spam.Config{Weights: map[string]float64{spam.FactorNumberPattern: 1}})
+	for phone, want := range map[string]float64{
+		"":                 70,
+		"restricted":       70,
+		"+44 20 7946 0018": 40,
+		"+1 012 555 0142":  80,
+		"+1 888 555 0142":  50,
+		"+1 512 555 0142":  0,
+	} {
+		phone := phone
+		verdict := scorer.Score(&spam.Signals{CallerPhone: &phone}, models.SpamDetectionConfig{})
+		assert.Equal(t, want, verdict.Likelihood, phone)
+	}
+}
+
+func TestRepeatCallersAcrossBusinesses(t *testing.T) {
+	scorer := spam.NewScorer(&spam.Config{
+		Weights:      map[string]float64{spam.FactorRepeatCaller: 1},
+		RepeatWindow: 24 * time.Hour, RepeatCalls: 5, RepeatTenants: 3,
+	})
+	score := func(activity models.CallerActivity) *spam.Verdict {
+		return scorer.Score(&spam.Signals{CallerActivity: &activity}, models.SpamDetectionConfig{})
+	}
+
+	assert.Equal(t, 0.0, score(models.CallerActivity{Requests: 2, Tenants: 1}).Likelihood)
+	assert.Equal(t, 60.0, score(models.CallerActivity{Requests: 6, Tenants: 1}).Likelihood)
+	assert.Equal(t, 50.0, score(models.CallerActivity{Requests: 2, Tenants: 2}).Likelihood)
+	verdict := score(models.CallerActivity{Requests: 4, Tenants: 4})
+	assert.Equal(t, 90.0, verdict.Likelihood)
+	assert.Contains(t, verdict.Explanation, "caller reached 4 businesses within 24 hours")
+}
+
+func TestSpamSuppressionFollowsTenantPolicy(t *testing.T) {
+	assert.True(t, spam.Suppresses(spamPolicy(80), 80))
+	assert.False(t, spam.Suppresses(spamPolicy(80), 79.9))
+	assert.False(t, spam.Suppresses(spamPolicy(0), 100), "no threshold, nothing suppressed")
+	assert.False(t, spam.Suppresses(models.SpamDetectionConfig{ConfidenceThreshold: 50}, 100), "spam detection disabled")
+}
+
+func TestMemoryCallerActivitySpansTenants(t *testing.T) {
+	store := repository.NewMemoryStore()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	for i, call := range []struct {
+		tenantID, phone string
+		age             time.Duration
+	}{
+		{"tenant_a", "+15125550142", time.Hour},
+		{"tenant_a", "512-555-0142", 2 * time.Hour},
+		{"tenant_b", "(512) 555-0142", 3 * time.Hour},
+		{"tenant_c", "+15125550142", 48 * time.Hour},
+		{"tenant_c", "+15125550100", time.Hour},
+	} {
+		req := memoryRequest(call.tenantID, fmt.Sprintf("req_%d", i), "", 0)
+		req.Data = `{"customer_phone_number":"` + call.phone + `"}`
+		req.CreatedAt = now.Add(-call.age)
+		require.NoError(t, store.CreateRequest(ctx, req))
+	}
+
+	activity, err := store.GetCallerActivity(ctx, "+1 512 555 0142", now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, models.CallerActivity{Requests: 3, Tenants: 2}, *activity)
+
+	activity, err = store.GetCallerActivity(ctx, "", now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, *activity, "withheld numbers are never counted together")
+}
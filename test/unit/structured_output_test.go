@@ -0,0 +1,162 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/ai"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+const validLeadScore = `{"lead_score":82,"confidence":0.7,"scoring_factors":{"project_complexity":8,"buying_readiness":9,"timeline_urgency":7,"budget_capability":6,"engagement_quality":9},"reasoning":"ready to buy"}`
+
+func TestDecodeJSONStripsFencesAndProse(t *testing.T) {
+	schema := ai.ResponseSchema(ai.AnalysisTypeLeadScore)
+	for name, text := range map[string]string{
+		"fenced":     "```json\n" + validLeadScore + "\n```",
+		"bare fence": "```\n" + validLeadScore + "\n```",
+		"with prose": "Here is the score:\n" + validLeadScore + "\nLet me know if you need more.",
+		"whitespace": "\n\n  " + validLeadScore + "  \n",
+		"plain":      validLeadScore,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var result ai.LeadScoreResult
+			require.NoError(t, ai.DecodeJSON(text, schema, &result))
+			assert.Equal(t, 82, result.LeadScore)
+			assert.Equal(t, 9, result.ScoringFactors.EngagementQuality)
+		})
+	}
+}
+
+func TestDecodeJSONCoercesNearMisses(t *testing.T) {
+	answer := `{"intent":"Quote Request","project_type":"Kitchen","timeline":"1-3 months","budget_indicator":"HIGH",
+		"sentiment":" positive ","lead_score":"75","urgency":"medium","appointment_requested":"yes",
+		"follow_up_required":false,"key_details":"wants an island"}`
+
+	var analysis models.CallAnalysis
+	require.NoError(t, ai.DecodeJSON(answer, ai.ResponseSchema(ai.AnalysisTypeContent), &analysis))
+
+	assert.Equal(t, "quote_request", analysis.Intent)
+	assert.Equal(t, "kitchen", analysis.ProjectType)
+	assert.Equal(t, "1-3_months", analysis.Timeline)
+	assert.Equal(t, "high", analysis.BudgetIndicator)
+	assert.Equal(t, "positive", analysis.Sentiment)
+	assert.Equal(t, 75, analysis.LeadScore)
+	assert.True(t, analysis.AppointmentRequested)
+	assert.Equal(t, []string{"wants an island"}, analysis.KeyDetails, "a lone value becomes a list")
+}
+
+func TestDecodeJSONScalesPercentageConfidence(t *testing.T) {
+	var intent ai.IntentClassificationResult
+	answer := `{"primary_intent":"emergency","confidence":85,"secondary_intents":null,"reasoning":"burst pipe"}`
+	require.NoError(t, ai.DecodeJSON(answer, ai.ResponseSchema(ai.AnalysisTypeIntent), &intent))
+
+	assert.Equal(t, "emergency", intent.PrimaryIntent)
+	assert.InDelta(t, 0.85, intent.Confidence, 1e-9)
+	assert.Empty(t, intent.SecondaryIntents)
+}
+
+func TestDecodeJSONRejectsValuesOutsideTheSchema(t *testing.T) {
+	answer := `{"lead_score":140,"confidence":0.7,"scoring_factors":{"project_complexity":8,"buying_readiness":0,"timeline_urgency":7,"budget_capability":6},"reasoning":"?"}`
+
+	var result ai.LeadScoreResult
+	err := ai.DecodeJSON(answer, ai.ResponseSchema(ai.AnalysisTypeLeadScore), &result)
+	require.Error(t, err)
+	require.True(t, ai.IsValidationError(err))
+
+	var validationErr *ai.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []string{
+		"lead_score 140 is outside 1-100",
+		"scoring_factors.buying_readiness 0 is outside 1-10",
+		"scoring_factors.engagement_quality is missing",
+	}, validationErr.Problems)
+
+	err = ai.DecodeJSON(`{"sentiment":"ecstatic","confidence":0.9,"emotional_tone":"","customer_satisfaction":"high","key_emotions":[]}`,
+		ai.ResponseSchema(ai.AnalysisTypeSentiment), &ai.SentimentAnalysisResult{})
+	assert.ErrorContains(t, err, `sentiment "ecstatic" is not one of positive, neutral, negative`)
+
+	err = ai.DecodeJSON("I could not analyze this call.", ai.ResponseSchema(ai.AnalysisTypeSentiment), &ai.SentimentAnalysisResult{})
+	assert.ErrorContains(t, err, "no JSON object")
+}
+
+func TestGenerateJSONRepairsOnce(t *testing.T) {
+	fake := ai.NewFakeProvider()
+	req := &ai.GenerateRequest{Prompt: "Score this lead", ResponseSchema: ai.ResponseSchema(ai.AnalysisTypeLeadScore)}
+	fake.Respond(req.Prompt, ai.FakeResponse{Text: `{"lead_score":0}`, Usage: ai.TokenUsage{InputTokens: 100, OutputTokens: 10}})
+	fake.SetDefault(ai.FakeResponse{Text: validLeadScore, Usage: ai.TokenUsage{InputTokens: 300, OutputTokens: 40}})
+
+	var result ai.LeadScoreResult
+	structured, err := ai.GenerateJSON(context.Background(), fake, req, &result)
+	require.NoError(t, err)
+
+	assert.True(t, structured.Repaired)
+	assert.Equal(t, ai.TokenUsage{InputTokens: 400, OutputTokens: 50}, structured.Usage)
+	assert.Equal(t, 82, result.LeadScore)
+
+	calls := fake.Calls()
+	require.Len(t, calls, 2)
+	repair := calls[1].Prompt
+	assert.True(t, strings.HasPrefix(repair, "Score this lead"), "the repair prompt keeps the original request")
+	assert.Contains(t, repair, `{"lead_score":0}`)
+	assert.Contains(t, repair, "lead_score 0 is outside 1-100")
+	assert.Contains(t, repair, `"additionalProperties": false`)
+	assert.Equal(t, req.ResponseSchema, calls[1].ResponseSchema)
+}
+
+func TestGenerateJSONFailsWhenRepairIsStillInvalid(t *testing.T) {
+	fake := ai.NewFakeProvider()
+	fake.SetDefault(ai.FakeResponse{Text: `{"spam_likelihood":"very"}`})
+
+	structured, err := ai.GenerateJSON(context.Background(), fake, &ai.GenerateRequest{
+		Prompt:         "Is this spam?",
+		ResponseSchema: ai.ResponseSchema(ai.AnalysisTypeSpam),
+	}, &ai.SpamAnalysisResult{})
+	require.Error(t, err)
+	assert.True(t, ai.IsValidationError(err))
+	assert.True(t, structured.Repaired)
+	assert.Len(t, fake.Calls(), 2, "exactly one repair attempt")
+}
+
+func TestGenerateJSONDoesNotRepairProviderErrors(t *testing.T) {
+	fake := ai.NewFakeProvider()
+	fake.SetDefault(ai.FakeResponse{Error: "quota exceeded"})
+
+	structured, err := ai.GenerateJSON(context.Background(), fake, &ai.GenerateRequest{
+		Prompt:         "Is this spam?",
+		ResponseSchema: ai.ResponseSchema(ai.AnalysisTypeSpam),
+	}, &ai.SpamAnalysisResult{})
+	assert.EqualError(t, err, "quota exceeded")
+	assert.False(t, structured.Repaired)
+	assert.Len(t, fake.Calls(), 1)
+}
+
+func TestAnalyzeContentMarksRepairedAnalyses(t *testing.T) {
+	fake := ai.NewFakeProvider()
+	fake.SetDefault(ai.FakeResponse{Text: "```json\n" + `{"primary_intent":"Appointment Booking","confidence":0.9,"secondary_intents":[],"reasoning":"wants a visit"}` + "\n```"})
+	service := ai.NewAnalysisServiceWithProvider(fake, nil)
+
+	resp, err := service.AnalyzeContent(context.Background(), &ai.AnalysisRequest{
+		Transcription: "Can someone come out Tuesday?",
+		AnalysisType:  ai.AnalysisTypeIntent,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp.IntentResult)
+	assert.Equal(t, "appointment_booking", resp.IntentResult.PrimaryIntent)
+	assert.False(t, resp.Metadata.Repaired, "coercion alone needs no repair prompt")
+
+	fake.SetDefault(ai.FakeResponse{Text: `{"primary_intent":"buy a boat","confidence":2,"secondary_intents":[],"reasoning":""}`})
+	resp, err = service.AnalyzeContent(context.Background(), &ai.AnalysisRequest{
+		Transcription: "Can someone come out Tuesday?",
+		AnalysisType:  ai.AnalysisTypeIntent,
+	})
+	require.Error(t, err)
+	assert.False(t, resp.Success)
+	assert.True(t, resp.Metadata.Repaired)
+	assert.Nil(t, resp.IntentResult)
+	assert.Contains(t, resp.Error, `primary_intent "buy a boat"`)
+}
@@ -0,0 +1,151 @@
+package unit
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/export"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+func wordDetail(text string, startMs, endMs int64, speaker int) models.WordDetail {
+	return models.WordDetail{
+		Word:       text,
+		StartTime:  models.Offset(time.Duration(startMs) * time.Millisecond),
+		EndTime:    models.Offset(time.Duration(endMs) * time.Millisecond),
+		Confidence: 0.9,
+		Speaker:    speaker,
+	}
+}
+
+func sampleTranscription() *models.TranscriptionResult {
+	return &models.TranscriptionResult{
+		Transcript: "Thanks for calling Acme. Hi I need a kitchen remodel.",
+		Duration:   6.5,
+		WordDetails: []models.WordDetail{
+			wordDetail("Thanks", 0, 400, 1),
+			wordDetail("for", 400, 600, 1),
+			wordDetail("calling", 600, 1000, 1),
+			wordDetail("Acme.", 1000, 1500, 1),
+			wordDetail("Hi", 2000, 2200, 2),
+			wordDetail("I", 2300, 2400, 2),
+			wordDetail("need", 2400, 2700, 2),
+			wordDetail("a", 2700, 2800, 2),
+			wordDetail("kitchen", 2800, 3300, 2),
+			wordDetail("remodel.", 3300, 3900, 2),
+		},
+	}
+}
+
+func TestOffsetJSONIsBackwardCompatible(t *testing.T) {
+	var legacy models.WordDetail
+	require.NoError(t, json.Unmarshal([]byte(`{"word":"hi","start_time":"1.2s","end_time":"1.5s","confidence":0.9}`), &legacy))
+	assert.Equal(t, 1200*time.Millisecond, legacy.StartTime.Duration())
+	assert.Equal(t, int64(1500), legacy.EndTime.Milliseconds())
+
+	var numeric models.SpeakerSegment
+	require.NoError(t, json.Unmarshal([]byte(`{"speaker":1,"start_time":1234,"end_time":2500,"text":"hi"}`), &numeric))
+	assert.Equal(t, int64(1234), numeric.StartTime.Milliseconds())
+
+	data, err := json.Marshal(wordDetail("hi", 1234, 1500, 2))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"word":"hi","start_time":"1.234s","end_time":"1.500s","confidence":0.9,"speaker":2,"start_ms":1234,"end_ms":1500}`, string(data))
+
+	// Round trip keeps millisecond precision
+	var decoded models.WordDetail
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, int64(1234), decoded.StartTime.Milliseconds())
+}
+
+func TestExportWebVTTAndSRT(t *testing.T) {
+	result := sampleTranscription()
+
+	vtt := export.WebVTT(result, nil)
+	assert.True(t, strings.HasPrefix(vtt, "WEBVTT\n\n"))
+	assert.Contains(t, vtt, "1\n00:00:00.000 --> 00:00:01.500\n<v Agent>Thanks for calling Acme.\n")
+	assert.Contains(t, vtt, "2\n00:00:02.000 --> 00:00:03.900\n<v Customer>Hi I need a kitchen remodel.\n")
+
+	srt := export.SRT(result, nil)
+	assert.Contains(t, srt, "1\n00:00:00,000 --> 00:00:01,500\nAgent: Thanks for calling Acme.\n")
+	assert.Contains(t, srt, "2\n00:00:02,000 --> 00:00:03,900\nCustomer: Hi I need a kitchen remodel.\n")
+}
+
+func TestExportCuesSplitLongSpeech(t *testing.T) {
+	result := &models.TranscriptionResult{}
+	for i := int64(0); i < 30; i++ {
+		result.WordDetails = append(result.WordDetails, wordDetail("word", i*500, i*500+400, 1))
+	}
+
+	opts := export.DefaultOptions()
+	cues := export.Cues(result, opts)
+	require.Greater(t, len(cues), 2)
+	for _, cue := range cues {
+		assert.LessOrEqual(t, cue.End-cue.Start, opts.MaxCueDuration)
+		assert.LessOrEqual(t, len(cue.Text), opts.MaxCueChars)
+	}
+}
+
+func TestExportTextAndParagraphs(t *testing.T) {
+	result := sampleTranscription()
+
+	assert.Equal(t, "Agent: Thanks for calling Acme.\n\nCustomer: Hi I need a kitchen remodel.\n", export.Text(result, nil))
+
+	// Labels can be swapped for outbound calls
+	opts := export.DefaultOptions()
+	opts.SpeakerLabels = map[int]string{1: "Customer"}
+	assert.True(t, strings.HasPrefix(export.Text(result, opts), "Customer: Thanks"))
+
+	body, contentType, err := export.Render(result, export.FormatJSON, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "application/json; charset=utf-8", contentType)
+
+	var doc export.ParagraphDocument
+	require.NoError(t, json.Unmarshal(body, &doc))
+	require.Len(t, doc.Paragraphs, 2)
+	assert.Equal(t, int64(2000), doc.Paragraphs[1].StartMs)
+	assert.Equal(t, int64(3900), doc.Paragraphs[1].EndMs)
+	assert.Equal(t, int64(6500), doc.DurationMs)
+
+	_, _, err = export.Render(result, export.Format("docx"), nil)
+	assert.Error(t, err)
+}
+
+func TestExportFallsBackToSpeakerSegments(t *testing.T) {
+	result := &models.TranscriptionResult{
+		SpeakerDiarization: []models.SpeakerSegment{
+			{Speaker: 1, StartTime: 0, EndTime: models.Offset(2 * time.Second), Text: "Thanks for calling"},
+			{Speaker: 2, StartTime: models.Offset(2 * time.Second), EndTime: models.Offset(4 * time.Second), Text: "Hello there"},
+		},
+	}
+
+	assert.Equal(t, "Agent: Thanks for calling\n\nCustomer: Hello there\n", export.Text(result, nil))
+	assert.Contains(t, export.SRT(result, nil), "00:00:02,000 --> 00:00:04,000\nCustomer: Hello there")
+}
+
+func TestExportDiarizedRecognition(t *testing.T) {
+	result := transcribeDiarized(t, diarizedRecognition())
+
+	assert.Equal(t, "Agent: my deck\n\nCustomer: has rot\n", export.Text(result, nil))
+	assert.Equal(t, map[int]string{1: "Agent", 2: "Customer"}, export.SpeakerLabels(result, nil))
+
+	vtt := export.WebVTT(result, nil)
+	assert.Equal(t, 1, strings.Count(vtt, "my deck"), "the conversation is exported once")
+	assert.Contains(t, vtt, "1\n00:00:00.000 --> 00:00:00.700\n<v Agent>my deck\n")
+	assert.Contains(t, vtt, "2\n00:00:02.000 --> 00:00:02.700\n<v Customer>has rot\n")
+
+	srt := export.SRT(result, nil)
+	assert.Equal(t, 1, strings.Count(srt, "has rot"))
+	assert.NotContains(t, srt, "\n3\n")
+
+	doc := export.Paragraphs(result, nil)
+	require.Len(t, doc.Paragraphs, 2)
+	assert.Equal(t, "Agent", doc.Paragraphs[0].Label)
+	assert.Equal(t, "Customer", doc.Paragraphs[1].Label)
+	assert.Equal(t, map[int]string{1: "Agent", 2: "Customer"}, doc.Speakers)
+	assert.Equal(t, 2, result.SpeakerCount, "no untagged speaker is counted")
+}
@@ -0,0 +1,249 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/longrunning/autogen/longrunningpb"
+	speech "cloud.google.com/go/speech/apiv1"
+	"cloud.google.com/go/speech/apiv1/speechpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/audio"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+// fakeSpeech is an in-process Speech-to-Text server answering each recognition model with a
+// scripted response. Operations finish immediately; models without a response fail.
+type fakeSpeech struct {
+	speechpb.UnimplementedSpeechServer
+
+	recognizing func() // called, unlocked, as each recognition starts
+
+	mu        sync.Mutex
+	responses map[string]*speechpb.LongRunningRecognizeResponse
+	requests  []*speechpb.LongRunningRecognizeRequest
+}
+
+func (f *fakeSpeech) LongRunningRecognize(ctx context.Context, req *speechpb.LongRunningRecognizeRequest) (*longrunningpb.Operation, error) {
+	if f.recognizing != nil {
+		f.recognizing()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, req)
+
+	response, ok := f.responses[req.Config.Model]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "model %s is not available", req.Config.Model)
+	}
+	result, err := anypb.New(response)
+	if err != nil {
+		return nil, err
+	}
+	return &longrunningpb.Operation{
+		Name:   fmt.Sprintf("operations/%d", len(f.requests)),
+		Done:   true,
+		Result: &longrunningpb.Operation_Response{Response: result},
+	}, nil
+}
+
+// submitted returns how many recognitions were started
+func (f *fakeSpeech) submitted() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+// newFakeSpeechService starts fake and returns a transcription service talking to it
+func newFakeSpeechService(t *testing.T, fake *fakeSpeech, config *audio.TranscriptionConfig) *audio.TranscriptionService {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(grpc.MaxRecvMsgSize(4 * audio.MaxInlineAudioBytes)) // room for oversized chunks to reach assertions
+	speechpb.RegisterSpeechServer(server, fake)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	client, err := speech.NewClient(context.Background(), option.WithGRPCConn(conn))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return audio.NewTranscriptionServiceWithClient(client, config)
+}
+
+// recognized builds a Speech-to-Text response of one speaker saying words with the given confidence
+func recognized(confidence float32, words ...string) *speechpb.LongRunningRecognizeResponse {
+	alternative := &speechpb.SpeechRecognitionAlternative{Confidence: confidence}
+	for i, w := range words {
+		alternative.Transcript += w + " "
+		alternative.Words = append(alternative.Words, &speechpb.WordInfo{
+			Word:       w,
+			Confidence: confidence,
+			StartTime:  durationpb.New(time.Duration(i) * 400 * time.Millisecond),
+			EndTime:    durationpb.New(time.Duration(i)*400*time.Millisecond + 300*time.Millisecond),
+			SpeakerTag: 1,
+		})
+	}
+	return &speechpb.LongRunningRecognizeResponse{
+		Results: []*speechpb.SpeechRecognitionResult{{Alternatives: []*speechpb.SpeechRecognitionAlternative{alternative}}},
+	}
+}
+
+// resultWithConfidences builds a transcript whose words have the given confidences
+func resultWithConfidences(confidences ...float32) *models.TranscriptionResult {
+	result := &models.TranscriptionResult{Transcript: "call", SpeakerCount: 2}
+	for i, c := range confidences {
+		w := wordDetail("word", int64(i)*300, int64(i)*300+250, 1+i%2)
+		w.Confidence = c
+		result.WordDetails = append(result.WordDetails, w)
+	}
+	return result
+}
+
+func TestEvaluateQualityAcceptsClearTranscript(t *testing.T) {
+	quality := audio.EvaluateQuality(resultWithConfidences(0.95, 0.9, 0.92, 0.88, 0.97, 0.91, 0.93, 0.9, 0.89, 0.94), nil)
+
+	assert.False(t, quality.Poor)
+	assert.Empty(t, quality.Issues)
+	assert.Equal(t, 10, quality.WordCount)
+	assert.InDelta(t, 0.919, quality.AverageConfidence, 0.001)
+	assert.Greater(t, quality.Score, 0.85)
+}
+
+func TestEvaluateQualityFlagsLowConfidenceTail(t *testing.T) {
+	// A good average can hide a run of garbled words
+	quality := audio.EvaluateQuality(resultWithConfidences(0.99, 0.99, 0.99, 0.99, 0.99, 0.99, 0.99, 0.99, 0.2, 0.25), nil)
+
+	assert.True(t, quality.Poor)
+	assert.NotContains(t, quality.Issues, audio.QualityIssueLowConfidence)
+	assert.Contains(t, quality.Issues, audio.QualityIssueLowPercentile)
+	assert.Contains(t, quality.Issues, audio.QualityIssueUnknownWords)
+	assert.InDelta(t, 0.2, quality.UnknownWordRatio, 0.001)
+}
+
+func TestEvaluateQualityChecksSpeakersAndEmptyTranscripts(t *testing.T) {
+	result := resultWithConfidences(0.9, 0.9, 0.9, 0.9)
+	result.SpeakerCount = 6
+	quality := audio.EvaluateQuality(result, nil)
+	assert.Equal(t, []string{audio.QualityIssueSpeakerCount}, quality.Issues)
+
+	empty := audio.EvaluateQuality(&models.TranscriptionResult{}, nil)
+	assert.True(t, empty.Poor)
+	assert.Equal(t, []string{audio.QualityIssueEmpty}, empty.Issues)
+	assert.Zero(t, empty.Score)
+
+	// Without word confidences the result average is used
+	noWords := audio.EvaluateQuality(&models.TranscriptionResult{Transcript: "hello", Confidence: 0.5}, nil)
+	assert.Contains(t, noWords.Issues, audio.QualityIssueLowConfidence)
+}
+
+func TestEvaluateQualityOfDiarizedRecognition(t *testing.T) {
+	mumble := word("uh", 3*time.Second, 3200*time.Millisecond, 2)
+	mumble.Confidence = 0.2
+	result := transcribeDiarized(t, diarized(
+		[]*speechpb.WordInfo{word("my", 0, 300*time.Millisecond, 1), word("deck", 400*time.Millisecond, 700*time.Millisecond, 1)},
+		[]*speechpb.WordInfo{word("has", 2*time.Second, 2300*time.Millisecond, 2), word("rot", 2400*time.Millisecond, 2700*time.Millisecond, 2), mumble},
+	))
+
+	quality := audio.EvaluateQuality(result, nil)
+	assert.Equal(t, 5, quality.WordCount, "each word is scored once")
+	assert.Equal(t, 2, quality.SpeakerCount)
+	assert.InDelta(t, 0.2, quality.UnknownWordRatio, 0.001)
+	assert.InDelta(t, 0.76, quality.AverageConfidence, 0.001)
+}
+
+func TestImproveTranscriptionKeepsTheBetterAttempt(t *testing.T) {
+	fake := &fakeSpeech{responses: map[string]*speechpb.LongRunningRecognizeResponse{
+		"chirp-3":    recognized(0.4, "uh", "deck", "mumble", "rot"),
+		"phone_call": recognized(0.93, "my", "deck", "has", "rot"),
+	}}
+	ts := newFakeSpeechService(t, fake, nil)
+	jm := audio.NewJobManager(audio.NewMemoryJobStore())
+
+	response, err := ts.TranscribeWithQuality(context.Background(), jm, &audio.TranscriptionRequest{
+		AudioURI: "gs://recordings/tenant_1/calls/call_1.mp3", TenantID: "tenant_1", CallID: "call_1", AudioDuration: time.Minute,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, fake.submitted())
+	assert.Equal(t, "my deck has rot", response.Result.Transcript)
+	assert.Equal(t, fake.requests[0].Config.SampleRateHertz, fake.requests[1].Config.SampleRateHertz, "the retry describes the recording as it is")
+
+	attempts := response.Result.Attempts
+	require.Len(t, attempts, 2)
+	assert.Equal(t, "primary", attempts[0].Name)
+	assert.Equal(t, "chirp-3", attempts[0].Model)
+	assert.True(t, attempts[0].Quality.Poor)
+	assert.False(t, attempts[0].Selected)
+	assert.Equal(t, "phone_call_enhanced", attempts[1].Name)
+	assert.False(t, attempts[1].Quality.Poor)
+	assert.True(t, attempts[1].Selected)
+	assert.NotEqual(t, attempts[0].JobID, attempts[1].JobID)
+	assert.Equal(t, 2, response.Metadata["transcription_attempts"])
+
+	for _, attempt := range attempts {
+		job, err := jm.GetJob(context.Background(), attempt.JobID)
+		require.NoError(t, err)
+		assert.Equal(t, audio.JobStatusCompleted, job.Status.Status)
+	}
+}
+
+func TestImproveTranscriptionRecordsFailedRetries(t *testing.T) {
+	fake := &fakeSpeech{responses: map[string]*speechpb.LongRunningRecognizeResponse{
+		"chirp-3": recognized(0.4, "uh", "deck", "mumble", "rot"),
+	}}
+	ts := newFakeSpeechService(t, fake, nil)
+	jm := audio.NewJobManager(audio.NewMemoryJobStore())
+
+	response, err := ts.TranscribeWithQuality(context.Background(), jm, &audio.TranscriptionRequest{
+		AudioURI: "gs://recordings/tenant_1/calls/call_1.mp3", TenantID: "tenant_1", CallID: "call_1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "uh deck mumble rot", response.Result.Transcript, "the primary is kept when the retry fails")
+
+	attempts := response.Result.Attempts
+	require.Len(t, attempts, 2)
+	assert.True(t, attempts[0].Selected)
+	assert.False(t, attempts[1].Selected)
+	assert.Empty(t, attempts[1].JobID)
+	assert.Contains(t, attempts[1].Error, "not available")
+}
+
+func TestImproveTranscriptionSkipsRecordingsWithoutSpeech(t *testing.T) {
+	ctx := context.Background()
+	for name, req := range map[string]*audio.TranscriptionRequest{
+		"classified as no speech": {NoSpeech: true, AudioDuration: time.Minute},
+		"too short":               {AudioDuration: 3 * time.Second},
+	} {
+		t.Run(name, func(t *testing.T) {
+			fake := &fakeSpeech{responses: map[string]*speechpb.LongRunningRecognizeResponse{
+				"chirp-3":    {},
+				"phone_call": {},
+			}}
+			ts := newFakeSpeechService(t, fake, nil)
+			req.AudioURI, req.TenantID, req.CallID = "gs://recordings/tenant_1/calls/call_1.mp3", "tenant_1", "call_1"
+
+			response, err := ts.TranscribeWithQuality(ctx, audio.NewJobManager(nil), req)
+			require.NoError(t, err)
+			assert.Equal(t, 1, fake.submitted(), "no paid retry")
+			assert.True(t, response.Result.Quality.Poor)
+			assert.Empty(t, response.Result.Attempts)
+		})
+	}
+}
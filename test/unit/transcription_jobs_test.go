@@ -0,0 +1,403 @@
+package unit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/speech/apiv1/speechpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/home-renovators/ingestion-pipeline/pkg/audio"
+	"github.com/home-renovators/ingestion-pipeline/pkg/models"
+)
+
+func TestJobManagerLifecycle(t *testing.T) {
+	ctx := context.Background()
+	jm := audio.NewJobManager(audio.NewMemoryJobStore())
+
+	job, err := jm.CreateJob(ctx, &audio.TranscriptionRequest{TenantID: "tenant_1", CallID: "call_1"})
+	require.NoError(t, err)
+	assert.Equal(t, audio.JobStatusPending, job.Status.Status)
+
+	require.NoError(t, jm.SetOperation(ctx, job.JobID, "operations/123"))
+	require.NoError(t, jm.UpdateJobStatus(ctx, job.JobID, audio.JobStatusProcessing, 0.4, ""))
+
+	status, err := jm.GetJobStatus(ctx, job.JobID)
+	require.NoError(t, err)
+	assert.Equal(t, audio.JobStatusProcessing, status.Status)
+	assert.Equal(t, "operations/123", status.OperationName)
+	assert.InDelta(t, 0.4, status.Progress, 0.001)
+
+	resumable, err := jm.ResumableJobs(ctx)
+	require.NoError(t, err)
+	require.Len(t, resumable, 1)
+	assert.Equal(t, job.JobID, resumable[0].JobID)
+
+	require.NoError(t, jm.CompleteJob(ctx, job.JobID, &audio.TranscriptionResponse{
+		Success: true,
+		Result: &models.TranscriptionResult{
+			Transcript:  "hello",
+			WordDetails: []models.WordDetail{{Word: "hello"}},
+			Language:    "en-US",
+		},
+	}))
+
+	completed, err := jm.GetJob(ctx, job.JobID)
+	require.NoError(t, err)
+	assert.True(t, completed.Done())
+	assert.Equal(t, 1.0, completed.Status.Progress)
+	require.NotNil(t, completed.Status.EndTime)
+	assert.EqualValues(t, 1, completed.Status.Metadata["word_count"])
+	assert.Equal(t, "en-US", completed.Status.Metadata["language"])
+
+	// The transcript is only kept once the caller has redacted it
+	assert.Nil(t, completed.Result)
+	require.NoError(t, jm.StoreResult(ctx, "tenant_1", "call_1", &models.TranscriptionResult{Transcript: "hello [SSN]"}))
+	completed, err = jm.GetJob(ctx, job.JobID)
+	require.NoError(t, err)
+	require.NotNil(t, completed.Result)
+	assert.Equal(t, "hello [SSN]", completed.Result.Transcript)
+
+	resumable, err = jm.ResumableJobs(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, resumable)
+}
+
+func TestJobManagerUnknownJob(t *testing.T) {
+	ctx := context.Background()
+	jm := audio.NewJobManager(nil)
+
+	_, err := jm.GetJob(ctx, "missing")
+	assert.ErrorIs(t, err, audio.ErrJobNotFound)
+	assert.ErrorIs(t, jm.UpdateJobStatus(ctx, "missing", audio.JobStatusFailed, 0, "boom"), audio.ErrJobNotFound)
+}
+
+func TestJobManagerFindLatestJobForCall(t *testing.T) {
+	ctx := context.Background()
+	jm := audio.NewJobManager(nil)
+
+	first, err := jm.CreateJob(ctx, &audio.TranscriptionRequest{TenantID: "tenant_1", CallID: "call_1"})
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	second, err := jm.CreateJob(ctx, &audio.TranscriptionRequest{TenantID: "tenant_1", CallID: "call_1"})
+	require.NoError(t, err)
+	_, err = jm.CreateJob(ctx, &audio.TranscriptionRequest{TenantID: "tenant_2", CallID: "call_1"})
+	require.NoError(t, err)
+
+	latest, err := jm.FindLatestJob(ctx, "tenant_1", "call_1")
+	require.NoError(t, err)
+	assert.NotEqual(t, first.JobID, latest.JobID)
+	assert.Equal(t, second.JobID, latest.JobID)
+
+	_, err = jm.FindLatestJob(ctx, "tenant_1", "call_2")
+	assert.ErrorIs(t, err, audio.ErrJobNotFound)
+}
+
+func TestJobManagerConcurrentUpdates(t *testing.T) {
+	ctx := context.Background()
+	jm := audio.NewJobManager(nil)
+
+	var wg sync.WaitGroup
+	jobIDs := make([]string, 20)
+	for i := range jobIDs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			job, err := jm.CreateJob(ctx, &audio.TranscriptionRequest{TenantID: "tenant_1", CallID: "call"})
+			if !assert.NoError(t, err) {
+				return
+			}
+			jobIDs[i] = job.JobID
+
+			for p := 1; p <= 10; p++ {
+				assert.NoError(t, jm.UpdateJobStatus(ctx, job.JobID, audio.JobStatusProcessing, float64(p)/10, ""))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	jobs, err := jm.ListJobs(ctx, audio.JobStatusProcessing)
+	require.NoError(t, err)
+	assert.Len(t, jobs, len(jobIDs))
+	for _, job := range jobs {
+		assert.Equal(t, 1.0, job.Status.Progress)
+	}
+}
+
+func TestMemoryJobStoreReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	store := audio.NewMemoryJobStore()
+	jm := audio.NewJobManager(store)
+
+	job, err := jm.CreateJob(ctx, &audio.TranscriptionRequest{TenantID: "tenant_1", CallID: "call_1"})
+	require.NoError(t, err)
+
+	// Mutating a returned job must not change what is stored
+	fetched, err := store.GetTranscriptionJob(ctx, job.JobID)
+	require.NoError(t, err)
+	fetched.Status.Status = audio.JobStatusFailed
+
+	stored, err := store.GetTranscriptionJob(ctx, job.JobID)
+	require.NoError(t, err)
+	assert.Equal(t, audio.JobStatusPending, stored.Status.Status)
+}
+
+func TestJobManagerCleanupCompletedJobs(t *testing.T) {
+	ctx := context.Background()
+	jm := audio.NewJobManager(nil)
+
+	done, err := jm.CreateJob(ctx, &audio.TranscriptionRequest{TenantID: "tenant_1", CallID: "call_1"})
+	require.NoError(t, err)
+	require.NoError(t, jm.UpdateJobStatus(ctx, done.JobID, audio.JobStatusFailed, 0, "boom"))
+
+	active, err := jm.CreateJob(ctx, &audio.TranscriptionRequest{TenantID: "tenant_1", CallID: "call_2"})
+	require.NoError(t, err)
+
+	time.Sleep(5 * time.Millisecond)
+	cleaned, err := jm.CleanupCompletedJobs(ctx, time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 1, cleaned)
+
+	_, err = jm.GetJob(ctx, done.JobID)
+	assert.ErrorIs(t, err, audio.ErrJobNotFound)
+	_, err = jm.GetJob(ctx, active.JobID)
+	assert.NoError(t, err)
+}
+
+func TestMemoryJobStoreClaims(t *testing.T) {
+	ctx := context.Background()
+	store := audio.NewMemoryJobStore()
+	job, err := audio.NewJobManager(store).CreateJob(ctx, &audio.TranscriptionRequest{TenantID: "tenant_1", CallID: "call_1"})
+	require.NoError(t, err)
+
+	claimed, err := store.ClaimTranscriptionJob(ctx, job.JobID, "instance_a", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = store.ClaimTranscriptionJob(ctx, job.JobID, "instance_b", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, claimed, "held by another instance")
+
+	claimed, err = store.ClaimTranscriptionJob(ctx, job.JobID, "instance_a", time.Now().Add(-time.Second))
+	require.NoError(t, err)
+	assert.True(t, claimed, "the holder renews its own claim")
+
+	claimed, err = store.ClaimTranscriptionJob(ctx, job.JobID, "instance_b", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, claimed, "an expired claim can be taken over")
+
+	require.NoError(t, store.ReleaseTranscriptionJob(ctx, job.JobID, "instance_a"))
+	claimed, err = store.ClaimTranscriptionJob(ctx, job.JobID, "instance_a", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, claimed, "only the holder can release a claim")
+
+	require.NoError(t, store.ReleaseTranscriptionJob(ctx, job.JobID, "instance_b"))
+	claimed, err = store.ClaimTranscriptionJob(ctx, job.JobID, "instance_a", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	_, err = store.ClaimTranscriptionJob(ctx, "missing", "instance_a", time.Now().Add(time.Minute))
+	assert.ErrorIs(t, err, audio.ErrJobNotFound)
+}
+
+func TestTranscribeWithJobReusesCompletedResult(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeSpeech{responses: map[string]*speechpb.LongRunningRecognizeResponse{
+		"chirp-3": recognized(0.9, "my", "deck", "has", "rot"),
+	}}
+	ts := newFakeSpeechService(t, fake, nil)
+	jm := audio.NewJobManager(nil)
+	req := &audio.TranscriptionRequest{AudioURI: "gs://recordings/tenant_1/calls/call_1.mp3", TenantID: "tenant_1", CallID: "call_1"}
+
+	first, err := ts.TranscribeWithJob(ctx, jm, req)
+	require.NoError(t, err)
+	require.NoError(t, jm.StoreResult(ctx, "tenant_1", "call_1", &models.TranscriptionResult{Transcript: "my deck has [ADDRESS]"}))
+
+	// A redelivery of the call gets the stored, redacted result without another paid recognition
+	again, err := ts.TranscribeWithJob(ctx, jm, req)
+	require.NoError(t, err)
+	assert.Equal(t, 1, fake.submitted())
+	assert.Equal(t, first.Metadata["job_id"], again.Metadata["job_id"])
+	assert.Equal(t, true, again.Metadata["reused"])
+	assert.Equal(t, "my deck has [ADDRESS]", again.Result.Transcript)
+	assert.Same(t, req, again.Request)
+}
+
+func TestTranscribeWithJobWaitsForResultToBeStored(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeSpeech{responses: map[string]*speechpb.LongRunningRecognizeResponse{
+		"chirp-3": recognized(0.9, "my", "deck", "has", "rot"),
+	}}
+	ts := newFakeSpeechService(t, fake, nil)
+	jm := audio.NewJobManager(nil).WithPollInterval(5 * time.Millisecond)
+	req := &audio.TranscriptionRequest{AudioURI: "gs://recordings/tenant_1/calls/call_1.mp3", TenantID: "tenant_1", CallID: "call_1"}
+
+	first, err := ts.TranscribeWithJob(ctx, jm, req)
+	require.NoError(t, err)
+
+	// A redelivery while the first delivery is still redacting waits for its result
+	done := make(chan *audio.TranscriptionResponse, 1)
+	go func() {
+		response, err := ts.TranscribeWithJob(ctx, jm, req)
+		assert.NoError(t, err)
+		done <- response
+	}()
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, jm.StoreResult(ctx, "tenant_1", "call_1", &models.TranscriptionResult{Transcript: "my deck has [ADDRESS]"}))
+
+	select {
+	case response := <-done:
+		assert.Equal(t, first.Metadata["job_id"], response.Metadata["job_id"])
+		assert.Equal(t, "my deck has [ADDRESS]", response.Result.Transcript)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the redelivery never picked up the stored result")
+	}
+	assert.Equal(t, 1, fake.submitted(), "the call is transcribed once")
+}
+
+func TestTranscribeWithJobRedoesJobWhoseResultWasNeverStored(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeSpeech{responses: map[string]*speechpb.LongRunningRecognizeResponse{
+		"chirp-3": recognized(0.9, "my", "deck", "has", "rot"),
+	}}
+	ts := newFakeSpeechService(t, fake, nil)
+	jm := audio.NewJobManager(nil).WithPollInterval(5 * time.Millisecond).WithClaimLease(30 * time.Millisecond)
+	req := &audio.TranscriptionRequest{AudioURI: "gs://recordings/tenant_1/calls/call_1.mp3", TenantID: "tenant_1", CallID: "call_1"}
+
+	// The first delivery failed after transcribing, before its result was redacted and stored
+	first, err := ts.TranscribeWithJob(ctx, jm, req)
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+
+	again, err := ts.TranscribeWithJob(ctx, jm, req)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Metadata["job_id"], again.Metadata["job_id"])
+	assert.Equal(t, "my deck has rot", again.Result.Transcript)
+	assert.Equal(t, 2, fake.submitted())
+}
+
+func TestTranscribeWithJobWaitsForJobHeldByAnotherInstance(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeSpeech{responses: map[string]*speechpb.LongRunningRecognizeResponse{
+		"chirp-3": recognized(0.9, "wrong", "call"),
+	}}
+	ts := newFakeSpeechService(t, fake, nil)
+	store := audio.NewMemoryJobStore()
+	req := &audio.TranscriptionRequest{AudioURI: "gs://recordings/tenant_1/calls/call_1.mp3", TenantID: "tenant_1", CallID: "call_1"}
+
+	// Another instance started the job and still holds it
+	other := audio.NewJobManager(store)
+	job, err := other.CreateJob(ctx, req)
+	require.NoError(t, err)
+	claimed, err := store.ClaimTranscriptionJob(ctx, job.JobID, "other_instance", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	type outcome struct {
+		response *audio.TranscriptionResponse
+		err      error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		response, err := ts.TranscribeWithJob(ctx, audio.NewJobManager(store).WithPollInterval(5*time.Millisecond), req)
+		done <- outcome{response, err}
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, other.CompleteJob(ctx, job.JobID, &audio.TranscriptionResponse{
+		Success: true,
+		Result:  &models.TranscriptionResult{Transcript: "my deck has rot"},
+	}))
+	require.NoError(t, other.StoreResult(ctx, "tenant_1", "call_1", &models.TranscriptionResult{Transcript: "my deck has rot"}))
+
+	select {
+	case result := <-done:
+		require.NoError(t, result.err)
+		assert.Equal(t, "my deck has rot", result.response.Result.Transcript)
+		assert.Equal(t, job.JobID, result.response.Metadata["job_id"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("the redelivery never picked up the other instance's result")
+	}
+	assert.Zero(t, fake.submitted(), "the call is transcribed once")
+}
+
+func TestTranscribeWithJobTakesOverLapsedClaim(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeSpeech{responses: map[string]*speechpb.LongRunningRecognizeResponse{
+		"chirp-3": recognized(0.9, "my", "deck", "has", "rot"),
+	}}
+	ts := newFakeSpeechService(t, fake, nil)
+	store := audio.NewMemoryJobStore()
+	req := &audio.TranscriptionRequest{AudioURI: "gs://recordings/tenant_1/calls/call_1.mp3", TenantID: "tenant_1", CallID: "call_1"}
+
+	// The instance that started the job stopped renewing its claim before submitting it
+	job, err := audio.NewJobManager(store).CreateJob(ctx, req)
+	require.NoError(t, err)
+	_, err = store.ClaimTranscriptionJob(ctx, job.JobID, "crashed_instance", time.Now().Add(-time.Second))
+	require.NoError(t, err)
+
+	response, err := ts.TranscribeWithJob(ctx, audio.NewJobManager(store), req)
+	require.NoError(t, err)
+	assert.Equal(t, job.JobID, response.Metadata["job_id"])
+	assert.Equal(t, "my deck has rot", response.Result.Transcript)
+	assert.Equal(t, 1, fake.submitted())
+}
+
+// stolenClaimStore is a job store where another instance takes every job over after its first claim
+type stolenClaimStore struct {
+	*audio.MemoryJobStore
+
+	mu      sync.Mutex
+	claimed map[string]bool
+}
+
+func (s *stolenClaimStore) ClaimTranscriptionJob(ctx context.Context, jobID, owner string, until time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.claimed[jobID] {
+		return false, nil
+	}
+	s.claimed[jobID] = true
+	return s.MemoryJobStore.ClaimTranscriptionJob(ctx, jobID, owner, until)
+}
+
+func TestTranscribeWithJobStopsWhenClaimIsLost(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeSpeech{responses: map[string]*speechpb.LongRunningRecognizeResponse{
+		"chirp-3": recognized(0.9, "my", "deck", "has", "rot"),
+	}}
+	fake.recognizing = func() { time.Sleep(200 * time.Millisecond) }
+	ts := newFakeSpeechService(t, fake, nil)
+	store := &stolenClaimStore{MemoryJobStore: audio.NewMemoryJobStore(), claimed: make(map[string]bool)}
+	jm := audio.NewJobManager(store).WithClaimLease(30 * time.Millisecond)
+	req := &audio.TranscriptionRequest{AudioURI: "gs://recordings/tenant_1/calls/call_1.mp3", TenantID: "tenant_1", CallID: "call_1"}
+
+	_, err := ts.TranscribeWithJob(ctx, jm, req)
+	assert.ErrorIs(t, err, audio.ErrJobClaimLost)
+
+	// The job is left for the instance that took it over
+	jobs, err := jm.ListJobs(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.NotEqual(t, audio.JobStatusFailed, jobs[0].Status.Status)
+}
+
+func TestResumeJobsSkipsJobsHeldByAnotherInstance(t *testing.T) {
+	ctx := context.Background()
+	ts := newFakeSpeechService(t, &fakeSpeech{}, nil)
+	store := audio.NewMemoryJobStore()
+	jm := audio.NewJobManager(store)
+
+	job, err := jm.CreateJob(ctx, &audio.TranscriptionRequest{TenantID: "tenant_1", CallID: "call_1"})
+	require.NoError(t, err)
+	require.NoError(t, jm.SetOperation(ctx, job.JobID, "operations/1"))
+	_, err = store.ClaimTranscriptionJob(ctx, job.JobID, "other_instance", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+
+	resumed, err := ts.ResumeJobs(ctx, jm, nil)
+	require.NoError(t, err)
+	assert.Zero(t, resumed)
+}